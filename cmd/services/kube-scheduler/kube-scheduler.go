@@ -0,0 +1,9 @@
+package main
+
+import (
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-scheduler/cmd"
+)
+
+func main() {
+	cmd.Execute()
+}
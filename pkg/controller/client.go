@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	kubeapi_rest "github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
+)
+
+// SendRequest sends the body as json to the kube api and returns an error with the response body when the
+// request did not succeed
+func SendRequest(method string, url string, body interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		bodyBytes, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("error parsing request body: %v", err)
+		}
+
+		reader = bytes.NewBuffer(bodyBytes)
+	}
+
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		return fmt.Errorf("error creating request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("error reading response body: %v", err)
+		}
+
+		return fmt.Errorf("request failed with status code: %d %s", resp.StatusCode, string(respBody))
+	}
+
+	return nil
+}
+
+func CreatePod(kubeAPIEndpoint string, pod *kubeapi_rest.Pod) error {
+	return SendRequest(http.MethodPost, fmt.Sprintf("%s/namespaces/%s/pods", kubeAPIEndpoint, pod.Metadata.Namespace), pod)
+}
+
+// DeletePod removes the pod from the api, the first delete only marks the pod as terminating for its kubelet
+// to stop the containers. A pod with no kubelet to stop it, not bound to a node or on a node that is gone or not
+// ready, is removed with force by deleting it again
+func DeletePod(kubeAPIEndpoint string, pod *kubeapi_rest.Pod, force bool) error {
+	podURL := fmt.Sprintf("%s/namespaces/%s/pods/%s", kubeAPIEndpoint, pod.Metadata.Namespace, pod.Metadata.Name)
+
+	if pod.Status.Phase != kubeapi_rest.PodTerminatingPhase {
+		if err := SendRequest(http.MethodDelete, podURL, nil); err != nil {
+			return err
+		}
+	}
+
+	if !force {
+		return nil
+	}
+
+	return SendRequest(http.MethodDelete, podURL, nil)
+}
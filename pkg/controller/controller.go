@@ -0,0 +1,104 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	kubeapi_rest "github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
+	"github.com/jonatan5524/own-kubernetes/pkg/record"
+)
+
+// MaxRetries is how many times a key is retried before a controller drops it, the next change of the object
+// or the next resync adds it again
+const MaxRetries = 15
+
+// Controller is a cluster level control loop run by kube-controller-manager
+type Controller interface {
+	// Run blocks until the stop channel is closed
+	Run(stopCh <-chan struct{})
+}
+
+// ControllerContext is what the controllers are created with, the informers are shared by all of them
+type ControllerContext struct {
+	KubeAPIEndpoint string
+	InformerFactory *InformerFactory
+}
+
+// EventRecorder returns a recorder for the events of the controller
+func (ctx ControllerContext) EventRecorder(component string) record.EventRecorder {
+	return record.NewRecorder(ctx.KubeAPIEndpoint, kubeapi_rest.EventSource{
+		Component: component,
+	})
+}
+
+// InitFunc creates a controller, its informers must be requested from the factory before it returns
+type InitFunc func(ctx ControllerContext) (Controller, error)
+
+// MetaKey returns the key of an object in the informers and queues, namespace/name or name for cluster scoped objects
+func MetaKey(metadata kubeapi_rest.ResourceMetadata) string {
+	if metadata.Namespace == "" {
+		return metadata.Name
+	}
+
+	return fmt.Sprintf("%s/%s", metadata.Namespace, metadata.Name)
+}
+
+// SplitMetaKey returns the namespace and name of a key, the namespace is empty for cluster scoped objects
+func SplitMetaKey(key string) (string, string) {
+	namespace, name, found := strings.Cut(key, "/")
+	if !found {
+		return "", key
+	}
+
+	return namespace, name
+}
+
+// RunWorkers processes the keys of the queue with the sync func in workers goroutines until the stop channel
+// is closed. A key whose sync failed is retried with backoff up to MaxRetries times
+func RunWorkers(name string, queue *RateLimitingQueue, workers int, sync func(key string) error, stopCh <-chan struct{}) {
+	defer queue.ShutDown()
+
+	log.Printf("starting %d workers of %s controller", workers, name)
+
+	for worker := 0; worker < max(workers, 1); worker++ {
+		go func() {
+			for processNextItem(name, queue, sync) {
+			}
+		}()
+	}
+
+	<-stopCh
+
+	log.Printf("stopping %s controller", name)
+}
+
+func processNextItem(name string, queue *RateLimitingQueue, sync func(key string) error) bool {
+	key, shutdown := queue.Get()
+	if shutdown {
+		return false
+	}
+	defer queue.Done(key)
+
+	start := time.Now()
+
+	err := sync(key)
+	if err == nil {
+		queue.Forget(key)
+
+		return true
+	}
+
+	if queue.NumRequeues(key) < MaxRetries {
+		log.Printf("error syncing %s %s after %s, retrying: %v", name, key, time.Since(start), err)
+		queue.AddRateLimited(key)
+
+		return true
+	}
+
+	log.Printf("dropping %s %s out of the queue: %v", name, key, err)
+	queue.Forget(key)
+
+	return true
+}
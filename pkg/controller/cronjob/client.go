@@ -0,0 +1,29 @@
+package cronjob
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/jonatan5524/own-kubernetes/pkg/controller"
+	kubeapi_rest "github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
+)
+
+func createJob(kubeAPIEndpoint string, job *kubeapi_rest.Job) error {
+	return controller.SendRequest(http.MethodPost, fmt.Sprintf("%s/namespaces/%s/jobs", kubeAPIEndpoint, job.Metadata.Namespace), job)
+}
+
+func deleteJob(kubeAPIEndpoint string, job *kubeapi_rest.Job) error {
+	return controller.SendRequest(
+		http.MethodDelete,
+		fmt.Sprintf("%s/namespaces/%s/jobs/%s", kubeAPIEndpoint, job.Metadata.Namespace, job.Metadata.Name),
+		nil,
+	)
+}
+
+func updateCronJobStatus(kubeAPIEndpoint string, cronJob *kubeapi_rest.CronJob) error {
+	return controller.SendRequest(
+		http.MethodPatch,
+		fmt.Sprintf("%s/namespaces/%s/cronjobs/%s/status", kubeAPIEndpoint, cronJob.Metadata.Namespace, cronJob.Metadata.Name),
+		cronJob.Status,
+	)
+}
@@ -0,0 +1,402 @@
+package cronjob
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/jonatan5524/own-kubernetes/pkg/controller"
+	"github.com/jonatan5524/own-kubernetes/pkg/cron"
+	kubeapi_rest "github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
+	"github.com/jonatan5524/own-kubernetes/pkg/record"
+)
+
+const (
+	DefaultConcurrentSyncs = 5
+
+	// more missed runs than this since the last run is reported, the controller was probably down
+	tooManyMissedTimes = 100
+
+	// ScheduledTimestampAnnotation is the time a job of a cronjob was scheduled at
+	ScheduledTimestampAnnotation = "batch.kubernetes.io/cronjob-scheduled-timestamp"
+
+	controllerKind = "CronJob"
+	jobKind        = "Job"
+)
+
+type Options struct {
+	// ConcurrentSyncs is the number of cronjobs synced in parallel
+	ConcurrentSyncs int
+}
+
+// Controller creates the jobs of every cronjob at the times of its schedule and deletes its finished jobs
+// beyond the history limits. The jobs of a cronjob are the jobs it is the controller of
+type Controller struct {
+	kubeAPIEndpoint string
+	options         Options
+	eventRecorder   record.EventRecorder
+
+	cronJobInformer *controller.Informer[kubeapi_rest.CronJob]
+	jobInformer     *controller.Informer[kubeapi_rest.Job]
+
+	queue *controller.RateLimitingQueue
+}
+
+func NewController(ctx controller.ControllerContext, options Options) *Controller {
+	cronJobController := &Controller{
+		kubeAPIEndpoint: ctx.KubeAPIEndpoint,
+		options:         options,
+		eventRecorder:   ctx.EventRecorder("cronjob-controller"),
+		cronJobInformer: ctx.InformerFactory.CronJobs(),
+		jobInformer:     ctx.InformerFactory.Jobs(),
+		queue:           controller.NewRateLimitingQueue(),
+	}
+
+	cronJobController.cronJobInformer.AddEventHandler(controller.ResourceEventHandler[kubeapi_rest.CronJob]{
+		OnAdd: cronJobController.enqueueCronJob,
+		OnUpdate: func(_ *kubeapi_rest.CronJob, newCronJob *kubeapi_rest.CronJob) {
+			cronJobController.enqueueCronJob(newCronJob)
+		},
+		OnDelete: cronJobController.enqueueCronJob,
+	})
+
+	cronJobController.jobInformer.AddEventHandler(controller.ResourceEventHandler[kubeapi_rest.Job]{
+		OnAdd: cronJobController.enqueueJobOwner,
+		OnUpdate: func(_ *kubeapi_rest.Job, newJob *kubeapi_rest.Job) {
+			cronJobController.enqueueJobOwner(newJob)
+		},
+		OnDelete: cronJobController.enqueueJobOwner,
+	})
+
+	return cronJobController
+}
+
+func (cronJobController *Controller) Run(stopCh <-chan struct{}) {
+	controller.RunWorkers("cronjob", cronJobController.queue, cronJobController.options.ConcurrentSyncs,
+		cronJobController.syncCronJob, stopCh)
+}
+
+func (cronJobController *Controller) enqueueCronJob(cronJob *kubeapi_rest.CronJob) {
+	cronJobController.queue.Add(controller.MetaKey(cronJob.Metadata))
+}
+
+func (cronJobController *Controller) enqueueJobOwner(job *kubeapi_rest.Job) {
+	controllerRef := job.Metadata.ControllerRef()
+	if controllerRef == nil || controllerRef.Kind != controllerKind {
+		return
+	}
+
+	cronJobController.queue.Add(controller.MetaKey(kubeapi_rest.ResourceMetadata{
+		Namespace: job.Metadata.Namespace,
+		Name:      controllerRef.Name,
+	}))
+}
+
+func (cronJobController *Controller) syncCronJob(key string) error {
+	namespace, name := controller.SplitMetaKey(key)
+	jobs := cronJobController.listCronJobJobs(namespace, name)
+
+	sharedCronJob, ok := cronJobController.cronJobInformer.Get(key)
+	if !ok {
+		// the jobs of a deleted cronjob are deleted with it
+		return cronJobController.deleteJobs(jobs)
+	}
+
+	cronJob, err := controller.DeepCopy(sharedCronJob)
+	if err != nil {
+		return err
+	}
+
+	var orphanJobs []*kubeapi_rest.Job
+	var ownJobs []*kubeapi_rest.Job
+
+	for _, job := range jobs {
+		if job.Metadata.ControllerRef().UID != cronJob.Metadata.UID {
+			// a job of a previous cronjob with the same name
+			orphanJobs = append(orphanJobs, job)
+		} else {
+			ownJobs = append(ownJobs, job)
+		}
+	}
+
+	if err = cronJobController.deleteJobs(orphanJobs); err != nil {
+		return err
+	}
+
+	oldStatus := cronJob.Status
+	now := time.Now()
+
+	activeJobs := syncActiveAndLastSuccessful(cronJob, ownJobs)
+
+	if err = cronJobController.cleanupFinishedJobs(cronJob, ownJobs); err != nil {
+		return err
+	}
+
+	schedule, err := cron.Parse(cronJob.Spec.Schedule)
+	if err != nil {
+		// the api validates the schedule, retrying would not fix it
+		cronJobController.eventRecorder.Eventf(cronJobReference(cronJob), kubeapi_rest.EventTypeWarning,
+			"UnparseableSchedule", "unparseable schedule: %q : %v", cronJob.Spec.Schedule, err)
+
+		return nil
+	}
+
+	var runErr error
+	if !cronJob.Spec.Suspend {
+		runErr = cronJobController.runScheduledJob(cronJob, schedule, activeJobs, now)
+	}
+
+	if !reflect.DeepEqual(oldStatus, cronJob.Status) {
+		if err = updateCronJobStatus(cronJobController.kubeAPIEndpoint, cronJob); err != nil {
+			return fmt.Errorf("error updating status of cronjob %s: %v", key, err)
+		}
+	}
+
+	if runErr != nil {
+		return runErr
+	}
+
+	// the next run has no event, the cronjob is synced again at its time
+	if next := schedule.Next(now); !next.IsZero() {
+		cronJobController.queue.AddAfter(key, next.Sub(now)+100*time.Millisecond)
+	}
+
+	return nil
+}
+
+// runScheduledJob creates the job of the most recent scheduled time that did not run yet, following the
+// concurrency policy and the starting deadline of the cronjob
+func (cronJobController *Controller) runScheduledJob(
+	cronJob *kubeapi_rest.CronJob,
+	schedule *cron.Schedule,
+	activeJobs []*kubeapi_rest.Job,
+	now time.Time,
+) error {
+	scheduledTime, missed := mostRecentScheduleTime(cronJob, schedule, now)
+	if scheduledTime.IsZero() {
+		return nil
+	}
+
+	if missed > tooManyMissedTimes {
+		cronJobController.eventRecorder.Eventf(cronJobReference(cronJob), kubeapi_rest.EventTypeWarning,
+			"TooManyMissedTimes", "too many missed start times: %d, the last one is started", missed)
+	}
+
+	if cronJob.Spec.ConcurrencyPolicy == kubeapi_rest.ForbidConcurrent && len(activeJobs) > 0 {
+		log.Printf("not starting job of cronjob %s/%s for %s, a previous job is running and the concurrency policy is %s",
+			cronJob.Metadata.Namespace, cronJob.Metadata.Name, scheduledTime.Format(time.RFC3339), kubeapi_rest.ForbidConcurrent)
+
+		return nil
+	}
+
+	if cronJob.Spec.ConcurrencyPolicy == kubeapi_rest.ReplaceConcurrent {
+		for _, job := range activeJobs {
+			if err := deleteJob(cronJobController.kubeAPIEndpoint, job); err != nil {
+				cronJobController.eventRecorder.Eventf(cronJobReference(cronJob), kubeapi_rest.EventTypeWarning,
+					"FailedDelete", "Error deleting job: %v", err)
+
+				return fmt.Errorf("error deleting job %s/%s: %v", job.Metadata.Namespace, job.Metadata.Name, err)
+			}
+
+			cronJobController.eventRecorder.Eventf(cronJobReference(cronJob), kubeapi_rest.EventTypeNormal,
+				"SuccessfulDelete", "Deleted job %s", job.Metadata.Name)
+		}
+
+		cronJob.Status.Active = nil
+	}
+
+	job := newJobForSchedule(cronJob, scheduledTime)
+
+	// the job was created by a previous sync whose status update failed
+	_, exists := cronJobController.jobInformer.Get(controller.MetaKey(job.Metadata))
+	if !exists {
+		if err := createJob(cronJobController.kubeAPIEndpoint, job); err != nil {
+			cronJobController.eventRecorder.Eventf(cronJobReference(cronJob), kubeapi_rest.EventTypeWarning,
+				"FailedCreate", "Error creating job: %v", err)
+
+			return fmt.Errorf("error creating job for cronjob %s/%s: %v", cronJob.Metadata.Namespace, cronJob.Metadata.Name, err)
+		}
+
+		cronJobController.eventRecorder.Eventf(cronJobReference(cronJob), kubeapi_rest.EventTypeNormal,
+			"SuccessfulCreate", "Created job %s", job.Metadata.Name)
+
+		cronJob.Status.Active = append(cronJob.Status.Active, kubeapi_rest.ObjectReference{
+			Kind:      jobKind,
+			Namespace: job.Metadata.Namespace,
+			Name:      job.Metadata.Name,
+		})
+	}
+
+	cronJob.Status.LastScheduleTime = scheduledTime.Format(time.RFC3339)
+
+	return nil
+}
+
+// mostRecentScheduleTime returns the latest scheduled time up to now that has no job yet and the number of
+// scheduled times since the last run, the zero time when there is none. With a starting deadline the times
+// older than the deadline are missed
+func mostRecentScheduleTime(cronJob *kubeapi_rest.CronJob, schedule *cron.Schedule, now time.Time) (time.Time, int) {
+	earliest, err := time.Parse(time.RFC3339, cronJob.Status.LastScheduleTime)
+	if err != nil {
+		earliest, err = time.Parse(time.RFC3339, cronJob.Metadata.CreationTimestamp)
+		if err != nil {
+			earliest = now
+		}
+	}
+
+	if cronJob.Spec.StartingDeadlineSeconds != nil {
+		deadline := now.Add(-time.Duration(*cronJob.Spec.StartingDeadlineSeconds) * time.Second)
+		if deadline.After(earliest) {
+			earliest = deadline
+		}
+	}
+
+	var mostRecent time.Time
+	missed := 0
+
+	for next := schedule.Next(earliest); !next.IsZero() && !next.After(now); next = schedule.Next(next) {
+		mostRecent = next
+		missed++
+	}
+
+	return mostRecent, missed
+}
+
+// syncActiveAndLastSuccessful sets the active jobs and the last successful time of the status by the jobs of the
+// cronjob and returns the active jobs
+func syncActiveAndLastSuccessful(cronJob *kubeapi_rest.CronJob, jobs []*kubeapi_rest.Job) []*kubeapi_rest.Job {
+	var activeJobs []*kubeapi_rest.Job
+	var active []kubeapi_rest.ObjectReference
+
+	for _, job := range jobs {
+		if !job.IsFinished() {
+			activeJobs = append(activeJobs, job)
+			active = append(active, kubeapi_rest.ObjectReference{
+				Kind:      jobKind,
+				Namespace: job.Metadata.Namespace,
+				Name:      job.Metadata.Name,
+				UID:       job.Metadata.UID,
+			})
+
+			continue
+		}
+
+		if job.IsComplete() && job.Status.CompletionTime > cronJob.Status.LastSuccessfulTime {
+			cronJob.Status.LastSuccessfulTime = job.Status.CompletionTime
+		}
+	}
+
+	sort.Slice(active, func(i, j int) bool {
+		return active[i].Name < active[j].Name
+	})
+
+	cronJob.Status.Active = active
+
+	return activeJobs
+}
+
+// cleanupFinishedJobs deletes the oldest finished jobs beyond the successful and failed jobs history limits
+func (cronJobController *Controller) cleanupFinishedJobs(cronJob *kubeapi_rest.CronJob, jobs []*kubeapi_rest.Job) error {
+	var successfulJobs []*kubeapi_rest.Job
+	var failedJobs []*kubeapi_rest.Job
+
+	for _, job := range jobs {
+		if job.IsComplete() {
+			successfulJobs = append(successfulJobs, job)
+		} else if job.IsFinished() {
+			failedJobs = append(failedJobs, job)
+		}
+	}
+
+	if err := cronJobController.removeOldestJobs(cronJob, successfulJobs, *cronJob.Spec.SuccessfulJobsHistoryLimit); err != nil {
+		return err
+	}
+
+	return cronJobController.removeOldestJobs(cronJob, failedJobs, *cronJob.Spec.FailedJobsHistoryLimit)
+}
+
+func (cronJobController *Controller) removeOldestJobs(cronJob *kubeapi_rest.CronJob, jobs []*kubeapi_rest.Job, limit int) error {
+	if len(jobs) <= limit {
+		return nil
+	}
+
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return jobs[i].Status.StartTime < jobs[j].Status.StartTime
+	})
+
+	for _, job := range jobs[:len(jobs)-limit] {
+		if err := deleteJob(cronJobController.kubeAPIEndpoint, job); err != nil {
+			return fmt.Errorf("error deleting job %s/%s: %v", job.Metadata.Namespace, job.Metadata.Name, err)
+		}
+
+		cronJobController.eventRecorder.Eventf(cronJobReference(cronJob), kubeapi_rest.EventTypeNormal,
+			"SuccessfulDelete", "Deleted job %s", job.Metadata.Name)
+	}
+
+	return nil
+}
+
+func (cronJobController *Controller) deleteJobs(jobs []*kubeapi_rest.Job) error {
+	for _, job := range jobs {
+		log.Printf("deleting job %s/%s, its cronjob was deleted", job.Metadata.Namespace, job.Metadata.Name)
+
+		if err := deleteJob(cronJobController.kubeAPIEndpoint, job); err != nil {
+			return fmt.Errorf("error deleting job %s/%s: %v", job.Metadata.Namespace, job.Metadata.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// listCronJobJobs returns the jobs controlled by a cronjob of the name, including the jobs of a previous cronjob
+// with the same name
+func (cronJobController *Controller) listCronJobJobs(namespace string, name string) []*kubeapi_rest.Job {
+	var jobs []*kubeapi_rest.Job
+
+	for _, job := range cronJobController.jobInformer.List() {
+		if job.Metadata.Namespace == namespace && controller.IsControlledBy(job.Metadata, controllerKind, name) {
+			jobs = append(jobs, job)
+		}
+	}
+
+	return jobs
+}
+
+// newJobForSchedule returns the job of the template for the scheduled time, its name is derived from the time so
+// a scheduled time runs once
+func newJobForSchedule(cronJob *kubeapi_rest.CronJob, scheduledTime time.Time) *kubeapi_rest.Job {
+	job := &kubeapi_rest.Job{
+		TypeMeta: kubeapi_rest.TypeMeta{Kind: jobKind},
+		Metadata: kubeapi_rest.ResourceMetadata{
+			Name:            fmt.Sprintf("%s-%d", cronJob.Metadata.Name, scheduledTime.Unix()/60),
+			Namespace:       cronJob.Metadata.Namespace,
+			Labels:          make(map[string]string, len(cronJob.Spec.JobTemplate.Metadata.Labels)),
+			Annotations:     make(map[string]string, len(cronJob.Spec.JobTemplate.Metadata.Annotations)+1),
+			OwnerReferences: []kubeapi_rest.OwnerReference{controller.NewControllerRef(controllerKind, cronJob.Metadata)},
+		},
+		Spec: cronJob.Spec.JobTemplate.Spec,
+	}
+
+	for key, value := range cronJob.Spec.JobTemplate.Metadata.Labels {
+		job.Metadata.Labels[key] = value
+	}
+
+	for key, value := range cronJob.Spec.JobTemplate.Metadata.Annotations {
+		job.Metadata.Annotations[key] = value
+	}
+
+	job.Metadata.Annotations[ScheduledTimestampAnnotation] = scheduledTime.Format(time.RFC3339)
+
+	return job
+}
+
+func cronJobReference(cronJob *kubeapi_rest.CronJob) kubeapi_rest.ObjectReference {
+	return kubeapi_rest.ObjectReference{
+		Kind:      controllerKind,
+		Namespace: cronJob.Metadata.Namespace,
+		Name:      cronJob.Metadata.Name,
+		UID:       cronJob.Metadata.UID,
+	}
+}
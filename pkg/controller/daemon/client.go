@@ -0,0 +1,17 @@
+package daemon
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/jonatan5524/own-kubernetes/pkg/controller"
+	kubeapi_rest "github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
+)
+
+func updateDaemonSetStatus(kubeAPIEndpoint string, daemonSet *kubeapi_rest.DaemonSet, status kubeapi_rest.DaemonSetStatus) error {
+	return controller.SendRequest(
+		http.MethodPatch,
+		fmt.Sprintf("%s/namespaces/%s/daemonsets/%s/status", kubeAPIEndpoint, daemonSet.Metadata.Namespace, daemonSet.Metadata.Name),
+		status,
+	)
+}
@@ -0,0 +1,495 @@
+package daemon
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/jonatan5524/own-kubernetes/pkg/controller"
+	kubeapi_rest "github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
+	"github.com/jonatan5524/own-kubernetes/pkg/record"
+)
+
+const (
+	DefaultConcurrentSyncs = 2
+
+	// burstReplicas bounds the pods created or deleted in a single sync of a daemonset
+	burstReplicas = 250
+
+	controllerKind = "DaemonSet"
+)
+
+type Options struct {
+	// ConcurrentSyncs is the number of daemonsets synced in parallel
+	ConcurrentSyncs int
+}
+
+// Controller runs one pod of every daemonset on each node the pod should run on. The pods are created bound to
+// their node, so they are not scheduled, and are deleted from the nodes that leave the cluster or stop matching
+// the node selector, affinity or tolerations of the template
+type Controller struct {
+	kubeAPIEndpoint string
+	options         Options
+	eventRecorder   record.EventRecorder
+
+	daemonSetInformer *controller.Informer[kubeapi_rest.DaemonSet]
+	podInformer       *controller.Informer[kubeapi_rest.Pod]
+	nodeInformer      *controller.Informer[kubeapi_rest.Node]
+
+	queue        *controller.RateLimitingQueue
+	expectations *controller.ControllerExpectations
+}
+
+func NewController(ctx controller.ControllerContext, options Options) *Controller {
+	daemonSetController := &Controller{
+		kubeAPIEndpoint:   ctx.KubeAPIEndpoint,
+		options:           options,
+		eventRecorder:     ctx.EventRecorder("daemonset-controller"),
+		daemonSetInformer: ctx.InformerFactory.DaemonSets(),
+		podInformer:       ctx.InformerFactory.Pods(),
+		nodeInformer:      ctx.InformerFactory.Nodes(),
+		queue:             controller.NewRateLimitingQueue(),
+		expectations:      controller.NewControllerExpectations(),
+	}
+
+	daemonSetController.daemonSetInformer.AddEventHandler(controller.ResourceEventHandler[kubeapi_rest.DaemonSet]{
+		OnAdd: daemonSetController.enqueueDaemonSet,
+		OnUpdate: func(_ *kubeapi_rest.DaemonSet, newDaemonSet *kubeapi_rest.DaemonSet) {
+			daemonSetController.enqueueDaemonSet(newDaemonSet)
+		},
+		OnDelete: func(daemonSet *kubeapi_rest.DaemonSet) {
+			daemonSetController.expectations.DeleteExpectations(controller.MetaKey(daemonSet.Metadata))
+			daemonSetController.enqueueDaemonSet(daemonSet)
+		},
+	})
+
+	daemonSetController.podInformer.AddEventHandler(controller.ResourceEventHandler[kubeapi_rest.Pod]{
+		OnAdd:    daemonSetController.addPod,
+		OnUpdate: daemonSetController.updatePod,
+		OnDelete: daemonSetController.deletePod,
+	})
+
+	daemonSetController.nodeInformer.AddEventHandler(controller.ResourceEventHandler[kubeapi_rest.Node]{
+		OnAdd:    func(_ *kubeapi_rest.Node) { daemonSetController.enqueueAllDaemonSets() },
+		OnUpdate: daemonSetController.updateNode,
+		OnDelete: func(_ *kubeapi_rest.Node) { daemonSetController.enqueueAllDaemonSets() },
+	})
+
+	return daemonSetController
+}
+
+func (daemonSetController *Controller) Run(stopCh <-chan struct{}) {
+	controller.RunWorkers("daemonset", daemonSetController.queue, daemonSetController.options.ConcurrentSyncs,
+		daemonSetController.syncDaemonSet, stopCh)
+}
+
+func (daemonSetController *Controller) enqueueDaemonSet(daemonSet *kubeapi_rest.DaemonSet) {
+	daemonSetController.queue.Add(controller.MetaKey(daemonSet.Metadata))
+}
+
+func (daemonSetController *Controller) enqueueAllDaemonSets() {
+	for _, daemonSet := range daemonSetController.daemonSetInformer.List() {
+		daemonSetController.enqueueDaemonSet(daemonSet)
+	}
+}
+
+// updateNode syncs the daemonsets only when the node changed in a way that decides which pods run on it,
+// the status of the node changes with every heartbeat
+func (daemonSetController *Controller) updateNode(oldNode *kubeapi_rest.Node, newNode *kubeapi_rest.Node) {
+	if reflect.DeepEqual(oldNode.Metadata.Labels, newNode.Metadata.Labels) &&
+		reflect.DeepEqual(oldNode.Spec, newNode.Spec) {
+		return
+	}
+
+	daemonSetController.enqueueAllDaemonSets()
+}
+
+// daemonSetKeyOfPod returns the key of the daemonset controlling the pod, empty when a daemonset does not
+// control it
+func daemonSetKeyOfPod(pod *kubeapi_rest.Pod) string {
+	controllerRef := pod.Metadata.ControllerRef()
+	if controllerRef == nil || controllerRef.Kind != controllerKind {
+		return ""
+	}
+
+	return controller.MetaKey(kubeapi_rest.ResourceMetadata{Namespace: pod.Metadata.Namespace, Name: controllerRef.Name})
+}
+
+func (daemonSetController *Controller) addPod(pod *kubeapi_rest.Pod) {
+	key := daemonSetKeyOfPod(pod)
+	if key == "" {
+		return
+	}
+
+	if pod.IsActive() {
+		daemonSetController.expectations.CreationObserved(key)
+	} else {
+		daemonSetController.expectations.DeletionObserved(key, controller.MetaKey(pod.Metadata))
+	}
+
+	daemonSetController.queue.Add(key)
+}
+
+func (daemonSetController *Controller) updatePod(oldPod *kubeapi_rest.Pod, newPod *kubeapi_rest.Pod) {
+	key := daemonSetKeyOfPod(newPod)
+	if key == "" {
+		return
+	}
+
+	if oldPod.IsActive() && !newPod.IsActive() {
+		daemonSetController.expectations.DeletionObserved(key, controller.MetaKey(newPod.Metadata))
+	}
+
+	daemonSetController.queue.Add(key)
+}
+
+func (daemonSetController *Controller) deletePod(pod *kubeapi_rest.Pod) {
+	key := daemonSetKeyOfPod(pod)
+	if key == "" {
+		return
+	}
+
+	daemonSetController.expectations.DeletionObserved(key, controller.MetaKey(pod.Metadata))
+	daemonSetController.queue.Add(key)
+}
+
+func (daemonSetController *Controller) syncDaemonSet(key string) error {
+	namespace, name := controller.SplitMetaKey(key)
+	pods := daemonSetController.listDaemonSetPods(namespace, name)
+
+	nodes := make(map[string]*kubeapi_rest.Node)
+	for _, node := range daemonSetController.nodeInformer.List() {
+		nodes[node.Metadata.Name] = node
+	}
+
+	daemonSet, ok := daemonSetController.daemonSetInformer.Get(key)
+	if !ok {
+		// the pods of a deleted daemonset are deleted with it
+		return daemonSetController.deleteOrphanPods(pods, nodes)
+	}
+
+	var orphanPods []*kubeapi_rest.Pod
+	nodeToPods := make(map[string][]*kubeapi_rest.Pod)
+
+	for _, pod := range pods {
+		if pod.Metadata.ControllerRef().UID != daemonSet.Metadata.UID {
+			// a pod of a previous daemonset with the same name
+			orphanPods = append(orphanPods, pod)
+		} else if pod.Status.Phase != kubeapi_rest.PodTerminatingPhase && pod.Status.Phase != kubeapi_rest.PodSucceededPhase {
+			nodeToPods[pod.Spec.NodeName] = append(nodeToPods[pod.Spec.NodeName], pod)
+		}
+	}
+
+	if err := daemonSetController.deleteOrphanPods(orphanPods, nodes); err != nil {
+		return err
+	}
+
+	hash := controller.ComputeHash(daemonSet.Spec.Template)
+	now := time.Now()
+
+	var manageErr error
+	if daemonSetController.expectations.SatisfiedExpectations(key) {
+		manageErr = daemonSetController.manage(key, daemonSet, nodes, nodeToPods, hash, now)
+	}
+
+	status := calculateStatus(daemonSet, nodes, nodeToPods, hash, now)
+	if status != daemonSet.Status {
+		if err := updateDaemonSetStatus(daemonSetController.kubeAPIEndpoint, daemonSet, status); err != nil {
+			return fmt.Errorf("error updating status of daemonset %s: %v", key, err)
+		}
+	}
+
+	// a ready pod becomes available after min ready seconds without an event, the daemonset is synced again then
+	if daemonSet.Spec.MinReadySeconds > 0 && status.NumberReady != status.NumberAvailable {
+		daemonSetController.queue.AddAfter(key, time.Duration(daemonSet.Spec.MinReadySeconds)*time.Second)
+	}
+
+	return manageErr
+}
+
+// manage creates the pod on the nodes that should run it and have none, and deletes the pods of the nodes that
+// should not run it, the extra pods of a node, the failed pods and the pods replaced by a rolling update
+func (daemonSetController *Controller) manage(
+	key string,
+	daemonSet *kubeapi_rest.DaemonSet,
+	nodes map[string]*kubeapi_rest.Node,
+	nodeToPods map[string][]*kubeapi_rest.Pod,
+	hash string,
+	now time.Time,
+) error {
+	var nodesNeedingPods []string
+	var podsToDelete []*kubeapi_rest.Pod
+
+	for nodeName, node := range nodes {
+		shouldRun, shouldContinueRunning := nodeShouldRunDaemonPod(node, daemonSet, hash)
+		nodePods := nodeToPods[nodeName]
+
+		var activePods []*kubeapi_rest.Pod
+		for _, pod := range nodePods {
+			if pod.Status.Phase == kubeapi_rest.PodFailedPhase {
+				// a failed pod is replaced by a new one once it is deleted
+				podsToDelete = append(podsToDelete, pod)
+
+				continue
+			}
+
+			activePods = append(activePods, pod)
+		}
+
+		switch {
+		case shouldRun && len(nodePods) == 0:
+			nodesNeedingPods = append(nodesNeedingPods, nodeName)
+		case shouldContinueRunning && len(activePods) > 1:
+			// keep the pod of the current template that was created first
+			sort.SliceStable(activePods, func(i, j int) bool {
+				firstUpdated := activePods[i].Metadata.Labels[kubeapi_rest.ControllerRevisionHashLabelKey] == hash
+				secondUpdated := activePods[j].Metadata.Labels[kubeapi_rest.ControllerRevisionHashLabelKey] == hash
+				if firstUpdated != secondUpdated {
+					return firstUpdated
+				}
+
+				return activePods[i].Metadata.CreationTimestamp < activePods[j].Metadata.CreationTimestamp
+			})
+
+			podsToDelete = append(podsToDelete, activePods[1:]...)
+		case !shouldContinueRunning:
+			podsToDelete = append(podsToDelete, activePods...)
+		}
+	}
+
+	// the pods of the nodes that left the cluster
+	for nodeName, nodePods := range nodeToPods {
+		if _, ok := nodes[nodeName]; !ok {
+			podsToDelete = append(podsToDelete, nodePods...)
+		}
+	}
+
+	if daemonSet.Spec.UpdateStrategy.Type == kubeapi_rest.RollingUpdateDaemonSetStrategyType &&
+		len(nodesNeedingPods) == 0 && len(podsToDelete) == 0 {
+		oldPodsToDelete, err := rollingUpdate(daemonSet, nodes, nodeToPods, hash, now)
+		if err != nil {
+			return fmt.Errorf("error updating daemonset %s: %v", key, err)
+		}
+
+		podsToDelete = oldPodsToDelete
+	}
+
+	return daemonSetController.syncNodes(key, daemonSet, nodesNeedingPods, podsToDelete, nodes, hash)
+}
+
+// syncNodes creates and deletes the pods, at most burstReplicas of each at a time
+func (daemonSetController *Controller) syncNodes(
+	key string,
+	daemonSet *kubeapi_rest.DaemonSet,
+	nodesNeedingPods []string,
+	podsToDelete []*kubeapi_rest.Pod,
+	nodes map[string]*kubeapi_rest.Node,
+	hash string,
+) error {
+	nodesNeedingPods = nodesNeedingPods[:min(len(nodesNeedingPods), burstReplicas)]
+	podsToDelete = podsToDelete[:min(len(podsToDelete), burstReplicas)]
+
+	podKeys := make([]string, 0, len(podsToDelete))
+	for _, pod := range podsToDelete {
+		podKeys = append(podKeys, controller.MetaKey(pod.Metadata))
+	}
+
+	daemonSetController.expectations.SetExpectations(key, len(nodesNeedingPods), podKeys)
+
+	var syncErr error
+
+	for index, nodeName := range nodesNeedingPods {
+		pod := newDaemonPod(daemonSet, nodeName, hash)
+
+		if err := controller.CreatePod(daemonSetController.kubeAPIEndpoint, pod); err != nil {
+			// the creations that did not happen will not be observed, stop at the first error so a broken
+			// template does not flood the api
+			for skipped := index; skipped < len(nodesNeedingPods); skipped++ {
+				daemonSetController.expectations.CreationObserved(key)
+			}
+
+			daemonSetController.eventRecorder.Eventf(daemonSetReference(daemonSet), kubeapi_rest.EventTypeWarning,
+				"FailedCreate", "Error creating: %v", err)
+
+			syncErr = fmt.Errorf("error creating pod for daemonset %s on node %s: %v", key, nodeName, err)
+
+			break
+		}
+
+		daemonSetController.eventRecorder.Eventf(daemonSetReference(daemonSet), kubeapi_rest.EventTypeNormal,
+			"SuccessfulCreate", "Created pod: %s", pod.Metadata.Name)
+	}
+
+	for _, pod := range podsToDelete {
+		_, nodeExists := nodes[pod.Spec.NodeName]
+
+		if err := controller.DeletePod(daemonSetController.kubeAPIEndpoint, pod, !nodeExists); err != nil {
+			daemonSetController.expectations.DeletionObserved(key, controller.MetaKey(pod.Metadata))
+			daemonSetController.eventRecorder.Eventf(daemonSetReference(daemonSet), kubeapi_rest.EventTypeWarning,
+				"FailedDelete", "Error deleting: %v", err)
+
+			syncErr = fmt.Errorf("error deleting pod %s of daemonset %s: %v", pod.Metadata.Name, key, err)
+
+			continue
+		}
+
+		daemonSetController.eventRecorder.Eventf(daemonSetReference(daemonSet), kubeapi_rest.EventTypeNormal,
+			"SuccessfulDelete", "Deleted pod: %s", pod.Metadata.Name)
+	}
+
+	return syncErr
+}
+
+func (daemonSetController *Controller) deleteOrphanPods(pods []*kubeapi_rest.Pod, nodes map[string]*kubeapi_rest.Node) error {
+	for _, pod := range pods {
+		_, nodeExists := nodes[pod.Spec.NodeName]
+
+		if pod.Status.Phase == kubeapi_rest.PodTerminatingPhase && nodeExists {
+			// its kubelet is already stopping it
+			continue
+		}
+
+		log.Printf("deleting pod %s/%s, its daemonset was deleted", pod.Metadata.Namespace, pod.Metadata.Name)
+
+		if err := controller.DeletePod(daemonSetController.kubeAPIEndpoint, pod, !nodeExists); err != nil {
+			return fmt.Errorf("error deleting pod %s/%s: %v", pod.Metadata.Namespace, pod.Metadata.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// listDaemonSetPods returns the pods controlled by a daemonset of the name, including the pods of a previous
+// daemonset with the same name
+func (daemonSetController *Controller) listDaemonSetPods(namespace string, name string) []*kubeapi_rest.Pod {
+	var pods []*kubeapi_rest.Pod
+
+	for _, pod := range daemonSetController.podInformer.List() {
+		if pod.Metadata.Namespace == namespace && controller.IsControlledBy(pod.Metadata, controllerKind, name) {
+			pods = append(pods, pod)
+		}
+	}
+
+	return pods
+}
+
+// newDaemonPod returns the pod of the daemonset for the node, it tolerates the unschedulable taint so
+// cordoning a node does not remove its system workloads
+func newDaemonPod(daemonSet *kubeapi_rest.DaemonSet, nodeName string, hash string) *kubeapi_rest.Pod {
+	pod := controller.NewPodFromTemplate(daemonSet.Spec.Template, daemonSet.Metadata.Namespace,
+		controller.NewControllerRef(controllerKind, daemonSet.Metadata))
+
+	pod.Metadata.Labels[kubeapi_rest.ControllerRevisionHashLabelKey] = hash
+	pod.Spec.NodeName = nodeName
+
+	// the tolerations of the template must not be appended to in place
+	tolerations := make([]kubeapi_rest.Toleration, 0, len(daemonSet.Spec.Template.Spec.Tolerations)+1)
+	tolerations = append(tolerations, daemonSet.Spec.Template.Spec.Tolerations...)
+	pod.Spec.Tolerations = append(tolerations, kubeapi_rest.Toleration{
+		Key:      kubeapi_rest.TaintNodeUnschedulable,
+		Operator: kubeapi_rest.TolerationOpExists,
+		Effect:   kubeapi_rest.TaintEffectNoSchedule,
+	})
+
+	return pod
+}
+
+// nodeShouldRunDaemonPod tells if a new pod of the daemonset should be created on the node, and if a pod already
+// on it should keep running. A NoSchedule taint keeps new pods off the node but leaves the running pod, a NoExecute
+// taint or a node selector or affinity that stopped matching removes it
+func nodeShouldRunDaemonPod(node *kubeapi_rest.Node, daemonSet *kubeapi_rest.DaemonSet, hash string) (bool, bool) {
+	pod := newDaemonPod(daemonSet, node.Metadata.Name, hash)
+
+	if !pod.MatchesNodeSelectorAndAffinity(node) {
+		return false, false
+	}
+
+	shouldRun := true
+
+	for _, taint := range node.Spec.Taints {
+		if pod.ToleratesTaint(taint) {
+			continue
+		}
+
+		switch taint.Effect {
+		case kubeapi_rest.TaintEffectNoExecute:
+			return false, false
+		case kubeapi_rest.TaintEffectNoSchedule:
+			shouldRun = false
+		}
+	}
+
+	return shouldRun, true
+}
+
+func calculateStatus(
+	daemonSet *kubeapi_rest.DaemonSet,
+	nodes map[string]*kubeapi_rest.Node,
+	nodeToPods map[string][]*kubeapi_rest.Pod,
+	hash string,
+	now time.Time,
+) kubeapi_rest.DaemonSetStatus {
+	status := kubeapi_rest.DaemonSetStatus{}
+
+	for nodeName, node := range nodes {
+		shouldRun, _ := nodeShouldRunDaemonPod(node, daemonSet, hash)
+		pods := activeDaemonPods(nodeToPods[nodeName])
+
+		if !shouldRun {
+			if len(pods) > 0 {
+				status.NumberMisscheduled++
+			}
+
+			continue
+		}
+
+		status.DesiredNumberScheduled++
+
+		if len(pods) == 0 {
+			continue
+		}
+
+		status.CurrentNumberScheduled++
+
+		pod := pods[0]
+
+		if pod.IsReady() {
+			status.NumberReady++
+		}
+
+		if pod.IsAvailable(daemonSet.Spec.MinReadySeconds, now) {
+			status.NumberAvailable++
+		}
+
+		if pod.Metadata.Labels[kubeapi_rest.ControllerRevisionHashLabelKey] == hash {
+			status.UpdatedNumberScheduled++
+		}
+	}
+
+	status.NumberUnavailable = status.DesiredNumberScheduled - status.NumberAvailable
+
+	return status
+}
+
+func activeDaemonPods(pods []*kubeapi_rest.Pod) []*kubeapi_rest.Pod {
+	var activePods []*kubeapi_rest.Pod
+
+	for _, pod := range pods {
+		if pod.IsActive() {
+			activePods = append(activePods, pod)
+		}
+	}
+
+	return activePods
+}
+
+func daemonSetReference(daemonSet *kubeapi_rest.DaemonSet) kubeapi_rest.ObjectReference {
+	return kubeapi_rest.ObjectReference{
+		Kind:      controllerKind,
+		Namespace: daemonSet.Metadata.Namespace,
+		Name:      daemonSet.Metadata.Name,
+		UID:       daemonSet.Metadata.UID,
+	}
+}
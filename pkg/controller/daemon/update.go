@@ -0,0 +1,77 @@
+package daemon
+
+import (
+	"time"
+
+	kubeapi_rest "github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
+)
+
+// rollingUpdate returns the pods of an older template to delete, the node of a deleted pod gets a pod of the
+// current template on the next sync. The old pods that are not available are deleted first since they do not
+// lower the availability, then the available ones while fewer than maxUnavailable nodes are without an available pod
+func rollingUpdate(
+	daemonSet *kubeapi_rest.DaemonSet,
+	nodes map[string]*kubeapi_rest.Node,
+	nodeToPods map[string][]*kubeapi_rest.Pod,
+	hash string,
+	now time.Time,
+) ([]*kubeapi_rest.Pod, error) {
+	var oldAvailablePods []*kubeapi_rest.Pod
+	var podsToDelete []*kubeapi_rest.Pod
+
+	desired := 0
+	numUnavailable := 0
+
+	for nodeName, node := range nodes {
+		shouldRun, shouldContinueRunning := nodeShouldRunDaemonPod(node, daemonSet, hash)
+		if shouldRun {
+			desired++
+		}
+
+		if !shouldContinueRunning {
+			continue
+		}
+
+		pods := activeDaemonPods(nodeToPods[nodeName])
+		if len(pods) == 0 {
+			if shouldRun {
+				numUnavailable++
+			}
+
+			continue
+		}
+
+		pod := pods[0]
+		available := pod.IsAvailable(daemonSet.Spec.MinReadySeconds, now)
+
+		if !available {
+			numUnavailable++
+		}
+
+		if pod.Metadata.Labels[kubeapi_rest.ControllerRevisionHashLabelKey] == hash {
+			continue
+		}
+
+		if available {
+			oldAvailablePods = append(oldAvailablePods, pod)
+		} else {
+			podsToDelete = append(podsToDelete, pod)
+		}
+	}
+
+	maxUnavailable, err := daemonSet.MaxUnavailable(desired)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pod := range oldAvailablePods {
+		if numUnavailable >= maxUnavailable {
+			break
+		}
+
+		podsToDelete = append(podsToDelete, pod)
+		numUnavailable++
+	}
+
+	return podsToDelete, nil
+}
@@ -0,0 +1,34 @@
+package deployment
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/jonatan5524/own-kubernetes/pkg/controller"
+	kubeapi_rest "github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
+)
+
+// updateReplicaSet creates the replicaset or replaces its spec
+func updateReplicaSet(kubeAPIEndpoint string, replicaSet *kubeapi_rest.ReplicaSet) error {
+	return controller.SendRequest(http.MethodPost, fmt.Sprintf("%s/namespaces/%s/replicasets", kubeAPIEndpoint, replicaSet.Metadata.Namespace), replicaSet)
+}
+
+func deleteReplicaSet(kubeAPIEndpoint string, replicaSet *kubeapi_rest.ReplicaSet) error {
+	return controller.SendRequest(
+		http.MethodDelete,
+		fmt.Sprintf("%s/namespaces/%s/replicasets/%s", kubeAPIEndpoint, replicaSet.Metadata.Namespace, replicaSet.Metadata.Name),
+		nil,
+	)
+}
+
+func updateDeployment(kubeAPIEndpoint string, deployment *kubeapi_rest.Deployment) error {
+	return controller.SendRequest(http.MethodPost, fmt.Sprintf("%s/namespaces/%s/deployments", kubeAPIEndpoint, deployment.Metadata.Namespace), deployment)
+}
+
+func updateDeploymentStatus(kubeAPIEndpoint string, deployment *kubeapi_rest.Deployment) error {
+	return controller.SendRequest(
+		http.MethodPatch,
+		fmt.Sprintf("%s/namespaces/%s/deployments/%s/status", kubeAPIEndpoint, deployment.Metadata.Namespace, deployment.Metadata.Name),
+		deployment.Status,
+	)
+}
@@ -0,0 +1,337 @@
+package deployment
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+
+	"github.com/jonatan5524/own-kubernetes/pkg/controller"
+	kubeapi_rest "github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
+	"github.com/jonatan5524/own-kubernetes/pkg/record"
+)
+
+const (
+	DefaultConcurrentSyncs = 5
+
+	controllerKind = "Deployment"
+	replicaSetKind = "ReplicaSet"
+)
+
+type Options struct {
+	// ConcurrentSyncs is the number of deployments synced in parallel
+	ConcurrentSyncs int
+}
+
+// Controller rolls out the template of every deployment. The template has a replicaset named after its hash,
+// it is scaled up to the replicas of the deployment while the replicasets of the previous templates are scaled
+// down, with the RollingUpdate strategy a few pods at a time and with the Recreate strategy all at once
+type Controller struct {
+	kubeAPIEndpoint string
+	options         Options
+	eventRecorder   record.EventRecorder
+
+	deploymentInformer *controller.Informer[kubeapi_rest.Deployment]
+	replicaSetInformer *controller.Informer[kubeapi_rest.ReplicaSet]
+	podInformer        *controller.Informer[kubeapi_rest.Pod]
+
+	queue *controller.RateLimitingQueue
+}
+
+func NewController(ctx controller.ControllerContext, options Options) *Controller {
+	deploymentController := &Controller{
+		kubeAPIEndpoint:    ctx.KubeAPIEndpoint,
+		options:            options,
+		eventRecorder:      ctx.EventRecorder("deployment-controller"),
+		deploymentInformer: ctx.InformerFactory.Deployments(),
+		replicaSetInformer: ctx.InformerFactory.ReplicaSets(),
+		podInformer:        ctx.InformerFactory.Pods(),
+		queue:              controller.NewRateLimitingQueue(),
+	}
+
+	deploymentController.deploymentInformer.AddEventHandler(controller.ResourceEventHandler[kubeapi_rest.Deployment]{
+		OnAdd: deploymentController.enqueueDeployment,
+		OnUpdate: func(_ *kubeapi_rest.Deployment, newDeployment *kubeapi_rest.Deployment) {
+			deploymentController.enqueueDeployment(newDeployment)
+		},
+		OnDelete: deploymentController.enqueueDeployment,
+	})
+
+	deploymentController.replicaSetInformer.AddEventHandler(controller.ResourceEventHandler[kubeapi_rest.ReplicaSet]{
+		OnAdd: deploymentController.enqueueReplicaSetOwner,
+		OnUpdate: func(_ *kubeapi_rest.ReplicaSet, newReplicaSet *kubeapi_rest.ReplicaSet) {
+			deploymentController.enqueueReplicaSetOwner(newReplicaSet)
+		},
+		OnDelete: deploymentController.enqueueReplicaSetOwner,
+	})
+
+	// a Recreate rollout waits for all the old pods to be gone before it creates the new ones
+	deploymentController.podInformer.AddEventHandler(controller.ResourceEventHandler[kubeapi_rest.Pod]{
+		OnDelete: deploymentController.deletePod,
+	})
+
+	return deploymentController
+}
+
+func (deploymentController *Controller) Run(stopCh <-chan struct{}) {
+	controller.RunWorkers("deployment", deploymentController.queue, deploymentController.options.ConcurrentSyncs,
+		deploymentController.syncDeployment, stopCh)
+}
+
+func (deploymentController *Controller) enqueueDeployment(deployment *kubeapi_rest.Deployment) {
+	deploymentController.queue.Add(controller.MetaKey(deployment.Metadata))
+}
+
+func (deploymentController *Controller) enqueueReplicaSetOwner(replicaSet *kubeapi_rest.ReplicaSet) {
+	controllerRef := replicaSet.Metadata.ControllerRef()
+	if controllerRef == nil || controllerRef.Kind != controllerKind {
+		return
+	}
+
+	deploymentController.queue.Add(controller.MetaKey(kubeapi_rest.ResourceMetadata{
+		Namespace: replicaSet.Metadata.Namespace,
+		Name:      controllerRef.Name,
+	}))
+}
+
+func (deploymentController *Controller) deletePod(pod *kubeapi_rest.Pod) {
+	controllerRef := pod.Metadata.ControllerRef()
+	if controllerRef == nil || controllerRef.Kind != replicaSetKind {
+		return
+	}
+
+	replicaSet, ok := deploymentController.replicaSetInformer.Get(controller.MetaKey(kubeapi_rest.ResourceMetadata{
+		Namespace: pod.Metadata.Namespace,
+		Name:      controllerRef.Name,
+	}))
+	if !ok {
+		return
+	}
+
+	deploymentRef := replicaSet.Metadata.ControllerRef()
+	if deploymentRef == nil || deploymentRef.Kind != controllerKind {
+		return
+	}
+
+	deploymentKey := controller.MetaKey(kubeapi_rest.ResourceMetadata{Namespace: pod.Metadata.Namespace, Name: deploymentRef.Name})
+
+	deployment, ok := deploymentController.deploymentInformer.Get(deploymentKey)
+	if ok && deployment.Spec.Strategy.Type == kubeapi_rest.RecreateDeploymentStrategyType {
+		deploymentController.queue.Add(deploymentKey)
+	}
+}
+
+func (deploymentController *Controller) syncDeployment(key string) error {
+	namespace, name := controller.SplitMetaKey(key)
+	replicaSets := deploymentController.listDeploymentReplicaSets(namespace, name)
+
+	cachedDeployment, ok := deploymentController.deploymentInformer.Get(key)
+	if !ok {
+		// the replicasets of a deleted deployment are deleted with it, their pods are deleted by the
+		// replicaset controller
+		return deploymentController.deleteReplicaSets(replicaSets)
+	}
+
+	deployment, err := controller.DeepCopy(cachedDeployment)
+	if err != nil {
+		return err
+	}
+
+	var ownedReplicaSets []*kubeapi_rest.ReplicaSet
+	var orphanReplicaSets []*kubeapi_rest.ReplicaSet
+
+	for _, replicaSet := range replicaSets {
+		if replicaSet.Metadata.ControllerRef().UID == deployment.Metadata.UID {
+			ownedReplicaSets = append(ownedReplicaSets, replicaSet)
+		} else {
+			// a replicaset of a previous deployment with the same name
+			orphanReplicaSets = append(orphanReplicaSets, replicaSet)
+		}
+	}
+
+	if err = deploymentController.deleteReplicaSets(orphanReplicaSets); err != nil {
+		return err
+	}
+
+	if deployment.Spec.RollbackTo != nil {
+		return deploymentController.rollback(deployment, ownedReplicaSets)
+	}
+
+	if deployment.Spec.Strategy.Type == kubeapi_rest.RecreateDeploymentStrategyType {
+		return deploymentController.rolloutRecreate(deployment, ownedReplicaSets)
+	}
+
+	return deploymentController.rolloutRolling(deployment, ownedReplicaSets)
+}
+
+// rollback replaces the template of the deployment with the template of the revision it is rolled back to,
+// the replicaset of the revision is then scaled up by the rollout as the new replicaset
+func (deploymentController *Controller) rollback(deployment *kubeapi_rest.Deployment, replicaSets []*kubeapi_rest.ReplicaSet) error {
+	toRevision := deployment.Spec.RollbackTo.Revision
+	if toRevision == 0 {
+		toRevision = lastRevision(replicaSets)
+		if toRevision == 0 {
+			deploymentController.eventRecorder.Event(deploymentReference(deployment), kubeapi_rest.EventTypeWarning,
+				"DeploymentRollbackRevisionNotFound", "Unable to find last revision.")
+
+			return deploymentController.clearRollbackTo(deployment)
+		}
+	}
+
+	for _, replicaSet := range replicaSets {
+		if getRevision(replicaSet.Metadata) != toRevision {
+			continue
+		}
+
+		template, err := controller.DeepCopy(&replicaSet.Spec.Template)
+		if err != nil {
+			return err
+		}
+
+		delete(template.Metadata.Labels, kubeapi_rest.PodTemplateHashLabelKey)
+
+		if controller.ComputeHash(*template) == controller.ComputeHash(deployment.Spec.Template) {
+			deploymentController.eventRecorder.Eventf(deploymentReference(deployment), kubeapi_rest.EventTypeWarning,
+				"DeploymentRollbackTemplateUnchanged", "The rollback revision contains the same template as current deployment %q",
+				deployment.Metadata.Name)
+		} else {
+			deployment.Spec.Template = *template
+
+			deploymentController.eventRecorder.Eventf(deploymentReference(deployment), kubeapi_rest.EventTypeNormal,
+				"DeploymentRollback", "Rolled back deployment %q to revision %d", deployment.Metadata.Name, toRevision)
+		}
+
+		return deploymentController.clearRollbackTo(deployment)
+	}
+
+	deploymentController.eventRecorder.Event(deploymentReference(deployment), kubeapi_rest.EventTypeWarning,
+		"DeploymentRollbackRevisionNotFound", "Unable to find the revision to rollback to.")
+
+	return deploymentController.clearRollbackTo(deployment)
+}
+
+func (deploymentController *Controller) clearRollbackTo(deployment *kubeapi_rest.Deployment) error {
+	deployment.Spec.RollbackTo = nil
+
+	if err := updateDeployment(deploymentController.kubeAPIEndpoint, deployment); err != nil {
+		return fmt.Errorf("error updating deployment %s/%s: %v", deployment.Metadata.Namespace, deployment.Metadata.Name, err)
+	}
+
+	return nil
+}
+
+// cleanupDeployment deletes the oldest scaled down replicasets above the revision history limit
+func (deploymentController *Controller) cleanupDeployment(oldReplicaSets []*kubeapi_rest.ReplicaSet, deployment *kubeapi_rest.Deployment) error {
+	var cleanable []*kubeapi_rest.ReplicaSet
+	for _, replicaSet := range oldReplicaSets {
+		if *replicaSet.Spec.Replicas == 0 && replicaSet.Status.Replicas == 0 {
+			cleanable = append(cleanable, replicaSet)
+		}
+	}
+
+	diff := len(cleanable) - *deployment.Spec.RevisionHistoryLimit
+	if diff <= 0 {
+		return nil
+	}
+
+	sort.SliceStable(cleanable, func(i, j int) bool {
+		return getRevision(cleanable[i].Metadata) < getRevision(cleanable[j].Metadata)
+	})
+
+	return deploymentController.deleteReplicaSets(cleanable[:diff])
+}
+
+func (deploymentController *Controller) deleteReplicaSets(replicaSets []*kubeapi_rest.ReplicaSet) error {
+	for _, replicaSet := range replicaSets {
+		log.Printf("deleting replicaset %s/%s", replicaSet.Metadata.Namespace, replicaSet.Metadata.Name)
+
+		if err := deleteReplicaSet(deploymentController.kubeAPIEndpoint, replicaSet); err != nil {
+			return fmt.Errorf("error deleting replicaset %s/%s: %v", replicaSet.Metadata.Namespace, replicaSet.Metadata.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// listDeploymentReplicaSets returns the replicasets controlled by a deployment of the name, including the
+// replicasets of a previous deployment with the same name
+func (deploymentController *Controller) listDeploymentReplicaSets(namespace string, name string) []*kubeapi_rest.ReplicaSet {
+	var replicaSets []*kubeapi_rest.ReplicaSet
+
+	for _, replicaSet := range deploymentController.replicaSetInformer.List() {
+		if replicaSet.Metadata.Namespace == namespace && controller.IsControlledBy(replicaSet.Metadata, controllerKind, name) {
+			replicaSets = append(replicaSets, replicaSet)
+		}
+	}
+
+	return replicaSets
+}
+
+// oldPodsRunning tells if a pod of the old replicasets is still running, including the pods being terminated
+func (deploymentController *Controller) oldPodsRunning(oldReplicaSets []*kubeapi_rest.ReplicaSet) bool {
+	oldReplicaSetUIDs := make(map[string]bool, len(oldReplicaSets))
+	for _, replicaSet := range oldReplicaSets {
+		if replicaSet.Status.Replicas > 0 {
+			return true
+		}
+
+		oldReplicaSetUIDs[replicaSet.Metadata.UID] = true
+	}
+
+	for _, pod := range deploymentController.podInformer.List() {
+		controllerRef := pod.Metadata.ControllerRef()
+		if controllerRef == nil || !oldReplicaSetUIDs[controllerRef.UID] {
+			continue
+		}
+
+		if pod.Status.Phase != kubeapi_rest.PodSucceededPhase && pod.Status.Phase != kubeapi_rest.PodFailedPhase {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getRevision returns the revision annotation of a deployment or a replicaset, 0 when it has none
+func getRevision(metadata kubeapi_rest.ResourceMetadata) int {
+	revision, err := strconv.Atoi(metadata.Annotations[kubeapi_rest.RevisionAnnotation])
+	if err != nil {
+		return 0
+	}
+
+	return revision
+}
+
+func maxRevision(replicaSets []*kubeapi_rest.ReplicaSet) int {
+	maxRevision := 0
+	for _, replicaSet := range replicaSets {
+		maxRevision = max(maxRevision, getRevision(replicaSet.Metadata))
+	}
+
+	return maxRevision
+}
+
+// lastRevision returns the revision before the current one, 0 when there is none
+func lastRevision(replicaSets []*kubeapi_rest.ReplicaSet) int {
+	current, last := 0, 0
+
+	for _, replicaSet := range replicaSets {
+		revision := getRevision(replicaSet.Metadata)
+		if revision > current {
+			current, last = revision, current
+		} else if revision > last && revision < current {
+			last = revision
+		}
+	}
+
+	return last
+}
+
+func deploymentReference(deployment *kubeapi_rest.Deployment) kubeapi_rest.ObjectReference {
+	return kubeapi_rest.ObjectReference{
+		Kind:      controllerKind,
+		Namespace: deployment.Metadata.Namespace,
+		Name:      deployment.Metadata.Name,
+		UID:       deployment.Metadata.UID,
+	}
+}
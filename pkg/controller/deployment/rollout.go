@@ -0,0 +1,432 @@
+package deployment
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/jonatan5524/own-kubernetes/pkg/controller"
+	kubeapi_rest "github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
+)
+
+// rolloutRolling scales the new replicaset up by at most maxSurge pods above the replicas, and the old replicasets
+// down while at most maxUnavailable pods are unavailable below the replicas
+func (deploymentController *Controller) rolloutRolling(deployment *kubeapi_rest.Deployment, replicaSets []*kubeapi_rest.ReplicaSet) error {
+	newReplicaSet, oldReplicaSets, created, err := deploymentController.getAllReplicaSetsAndSyncRevision(deployment, replicaSets, true)
+	if err != nil {
+		return err
+	}
+
+	scaledUp, newReplicaSet, err := deploymentController.reconcileNewReplicaSet(
+		append(oldReplicaSets, newReplicaSet), newReplicaSet, deployment)
+	if err != nil {
+		return err
+	}
+
+	if scaledUp {
+		return deploymentController.syncRolloutStatus(append(oldReplicaSets, newReplicaSet), newReplicaSet, deployment, created)
+	}
+
+	scaledDown, err := deploymentController.reconcileOldReplicaSets(
+		append(oldReplicaSets, newReplicaSet), oldReplicaSets, newReplicaSet, deployment)
+	if err != nil {
+		return err
+	}
+
+	allReplicaSets := append(oldReplicaSets, newReplicaSet)
+
+	if !scaledDown && deploymentComplete(deployment, calculateStatus(allReplicaSets, newReplicaSet, deployment)) {
+		if err = deploymentController.cleanupDeployment(oldReplicaSets, deployment); err != nil {
+			return err
+		}
+	}
+
+	return deploymentController.syncRolloutStatus(allReplicaSets, newReplicaSet, deployment, created)
+}
+
+// rolloutRecreate scales all the old replicasets down, waits for their pods to be gone and only then creates
+// the new replicaset with all the replicas
+func (deploymentController *Controller) rolloutRecreate(deployment *kubeapi_rest.Deployment, replicaSets []*kubeapi_rest.ReplicaSet) error {
+	newReplicaSet, oldReplicaSets, _, err := deploymentController.getAllReplicaSetsAndSyncRevision(deployment, replicaSets, false)
+	if err != nil {
+		return err
+	}
+
+	allReplicaSets := oldReplicaSets
+	if newReplicaSet != nil {
+		allReplicaSets = append(allReplicaSets, newReplicaSet)
+	}
+
+	scaledDown := false
+	for index, replicaSet := range oldReplicaSets {
+		scaled, updatedReplicaSet, err := deploymentController.scaleReplicaSet(replicaSet, 0, deployment)
+		if err != nil {
+			return err
+		}
+
+		oldReplicaSets[index] = updatedReplicaSet
+		scaledDown = scaledDown || scaled
+	}
+
+	if scaledDown || deploymentController.oldPodsRunning(oldReplicaSets) {
+		return deploymentController.syncRolloutStatus(allReplicaSets, newReplicaSet, deployment, false)
+	}
+
+	created := false
+	if newReplicaSet == nil {
+		newReplicaSet, oldReplicaSets, created, err = deploymentController.getAllReplicaSetsAndSyncRevision(deployment, replicaSets, true)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, newReplicaSet, err = deploymentController.reconcileNewReplicaSet(append(oldReplicaSets, newReplicaSet), newReplicaSet, deployment)
+	if err != nil {
+		return err
+	}
+
+	allReplicaSets = append(oldReplicaSets, newReplicaSet)
+
+	if deploymentComplete(deployment, calculateStatus(allReplicaSets, newReplicaSet, deployment)) {
+		if err = deploymentController.cleanupDeployment(oldReplicaSets, deployment); err != nil {
+			return err
+		}
+	}
+
+	return deploymentController.syncRolloutStatus(allReplicaSets, newReplicaSet, deployment, created)
+}
+
+// getAllReplicaSetsAndSyncRevision splits the replicasets to the replicaset of the current template and the old
+// ones. The new replicaset gets the revision after the newest old replicaset, it is created when it does not
+// exist and createIfNotExisted is set, and the deployment gets the revision of its new replicaset
+func (deploymentController *Controller) getAllReplicaSetsAndSyncRevision(
+	deployment *kubeapi_rest.Deployment,
+	replicaSets []*kubeapi_rest.ReplicaSet,
+	createIfNotExisted bool,
+) (*kubeapi_rest.ReplicaSet, []*kubeapi_rest.ReplicaSet, bool, error) {
+	hash := controller.ComputeHash(deployment.Spec.Template)
+
+	var newReplicaSet *kubeapi_rest.ReplicaSet
+	oldReplicaSets := []*kubeapi_rest.ReplicaSet{}
+
+	for _, replicaSet := range replicaSets {
+		if newReplicaSet == nil && replicaSet.Spec.Template.Metadata.Labels[kubeapi_rest.PodTemplateHashLabelKey] == hash {
+			newReplicaSet = replicaSet
+		} else {
+			oldReplicaSets = append(oldReplicaSets, replicaSet)
+		}
+	}
+
+	newRevision := maxRevision(oldReplicaSets) + 1
+	created := false
+
+	if newReplicaSet != nil {
+		// the replicaset of a template rolled back to becomes the newest revision again
+		if getRevision(newReplicaSet.Metadata) < newRevision || newReplicaSet.Spec.MinReadySeconds != deployment.Spec.MinReadySeconds {
+			updatedReplicaSet, err := controller.DeepCopy(newReplicaSet)
+			if err != nil {
+				return nil, nil, false, err
+			}
+
+			if updatedReplicaSet.Metadata.Annotations == nil {
+				updatedReplicaSet.Metadata.Annotations = make(map[string]string)
+			}
+
+			updatedReplicaSet.Metadata.Annotations[kubeapi_rest.RevisionAnnotation] = strconv.Itoa(max(newRevision, getRevision(newReplicaSet.Metadata)))
+			updatedReplicaSet.Spec.MinReadySeconds = deployment.Spec.MinReadySeconds
+
+			if err = updateReplicaSet(deploymentController.kubeAPIEndpoint, updatedReplicaSet); err != nil {
+				return nil, nil, false, fmt.Errorf("error updating replicaset %s: %v", updatedReplicaSet.Metadata.Name, err)
+			}
+
+			newReplicaSet = updatedReplicaSet
+		}
+	} else if createIfNotExisted {
+		newReplicaSet = newReplicaSetForTemplate(deployment, hash, newRevision)
+		*newReplicaSet.Spec.Replicas = newReplicaSetReplicas(deployment, oldReplicaSets, nil)
+
+		if err := updateReplicaSet(deploymentController.kubeAPIEndpoint, newReplicaSet); err != nil {
+			return nil, nil, false, fmt.Errorf("error creating replicaset %s: %v", newReplicaSet.Metadata.Name, err)
+		}
+
+		created = true
+
+		deploymentController.eventRecorder.Eventf(deploymentReference(deployment), kubeapi_rest.EventTypeNormal,
+			"ScalingReplicaSet", "Scaled up replica set %s to %d", newReplicaSet.Metadata.Name, *newReplicaSet.Spec.Replicas)
+	}
+
+	if newReplicaSet != nil && getRevision(deployment.Metadata) != getRevision(newReplicaSet.Metadata) {
+		if deployment.Metadata.Annotations == nil {
+			deployment.Metadata.Annotations = make(map[string]string)
+		}
+
+		deployment.Metadata.Annotations[kubeapi_rest.RevisionAnnotation] = newReplicaSet.Metadata.Annotations[kubeapi_rest.RevisionAnnotation]
+
+		if err := updateDeployment(deploymentController.kubeAPIEndpoint, deployment); err != nil {
+			return nil, nil, false, fmt.Errorf("error updating revision of deployment %s: %v", deployment.Metadata.Name, err)
+		}
+	}
+
+	return newReplicaSet, oldReplicaSets, created, nil
+}
+
+// newReplicaSetForTemplate returns the replicaset of the template of the deployment, the pod-template-hash label
+// is added to its selector and template so it does not match the pods of the other replicasets
+func newReplicaSetForTemplate(deployment *kubeapi_rest.Deployment, hash string, revision int) *kubeapi_rest.ReplicaSet {
+	template := deployment.Spec.Template
+	template.Metadata.Labels = make(map[string]string, len(deployment.Spec.Template.Metadata.Labels)+1)
+
+	for key, value := range deployment.Spec.Template.Metadata.Labels {
+		template.Metadata.Labels[key] = value
+	}
+
+	template.Metadata.Labels[kubeapi_rest.PodTemplateHashLabelKey] = hash
+
+	selector := kubeapi_rest.LabelSelector{
+		MatchLabels: make(map[string]string, len(deployment.Spec.Selector.MatchLabels)+1),
+	}
+
+	for key, value := range deployment.Spec.Selector.MatchLabels {
+		selector.MatchLabels[key] = value
+	}
+
+	selector.MatchLabels[kubeapi_rest.PodTemplateHashLabelKey] = hash
+
+	replicas := 0
+
+	return &kubeapi_rest.ReplicaSet{
+		TypeMeta: kubeapi_rest.TypeMeta{Kind: replicaSetKind},
+		Metadata: kubeapi_rest.ResourceMetadata{
+			Name:      fmt.Sprintf("%s-%s", deployment.Metadata.Name, hash),
+			Namespace: deployment.Metadata.Namespace,
+			Labels:    template.Metadata.Labels,
+			Annotations: map[string]string{
+				kubeapi_rest.RevisionAnnotation: strconv.Itoa(revision),
+			},
+			OwnerReferences: []kubeapi_rest.OwnerReference{controller.NewControllerRef(controllerKind, deployment.Metadata)},
+		},
+		Spec: kubeapi_rest.ReplicaSetSpec{
+			Replicas:        &replicas,
+			MinReadySeconds: deployment.Spec.MinReadySeconds,
+			Selector:        selector,
+			Template:        template,
+		},
+	}
+}
+
+// newReplicaSetReplicas returns the replicas of the new replicaset, with RollingUpdate it grows while all the
+// replicasets together stay below the replicas plus maxSurge
+func newReplicaSetReplicas(deployment *kubeapi_rest.Deployment, allReplicaSets []*kubeapi_rest.ReplicaSet, newReplicaSet *kubeapi_rest.ReplicaSet) int {
+	replicas := *deployment.Spec.Replicas
+
+	if deployment.Spec.Strategy.Type == kubeapi_rest.RecreateDeploymentStrategyType {
+		return replicas
+	}
+
+	newReplicaSetReplicas := 0
+	if newReplicaSet != nil {
+		newReplicaSetReplicas = *newReplicaSet.Spec.Replicas
+	}
+
+	maxSurge, _, _ := deployment.MaxSurgeAndUnavailable()
+
+	maxTotalPods := replicas + maxSurge
+	currentPodCount := specReplicas(allReplicaSets)
+
+	if currentPodCount >= maxTotalPods {
+		return newReplicaSetReplicas
+	}
+
+	scaleUpCount := min(maxTotalPods-currentPodCount, replicas-newReplicaSetReplicas)
+
+	return newReplicaSetReplicas + scaleUpCount
+}
+
+func (deploymentController *Controller) reconcileNewReplicaSet(
+	allReplicaSets []*kubeapi_rest.ReplicaSet,
+	newReplicaSet *kubeapi_rest.ReplicaSet,
+	deployment *kubeapi_rest.Deployment,
+) (bool, *kubeapi_rest.ReplicaSet, error) {
+	replicas := *deployment.Spec.Replicas
+
+	if *newReplicaSet.Spec.Replicas == replicas {
+		return false, newReplicaSet, nil
+	}
+
+	if *newReplicaSet.Spec.Replicas > replicas {
+		return deploymentController.scaleReplicaSet(newReplicaSet, replicas, deployment)
+	}
+
+	return deploymentController.scaleReplicaSet(newReplicaSet, newReplicaSetReplicas(deployment, allReplicaSets, newReplicaSet), deployment)
+}
+
+// reconcileOldReplicaSets scales the old replicasets down as long as the deployment keeps the replicas minus
+// maxUnavailable available. The old pods that are not available are removed first, they do not lower the
+// availability
+func (deploymentController *Controller) reconcileOldReplicaSets(
+	allReplicaSets []*kubeapi_rest.ReplicaSet,
+	oldReplicaSets []*kubeapi_rest.ReplicaSet,
+	newReplicaSet *kubeapi_rest.ReplicaSet,
+	deployment *kubeapi_rest.Deployment,
+) (bool, error) {
+	if specReplicas(oldReplicaSets) == 0 {
+		return false, nil
+	}
+
+	_, maxUnavailable, err := deployment.MaxSurgeAndUnavailable()
+	if err != nil {
+		return false, err
+	}
+
+	minAvailable := *deployment.Spec.Replicas - maxUnavailable
+	newReplicaSetUnavailable := *newReplicaSet.Spec.Replicas - newReplicaSet.Status.AvailableReplicas
+	maxScaledDown := specReplicas(allReplicaSets) - minAvailable - newReplicaSetUnavailable
+
+	if maxScaledDown <= 0 {
+		return false, nil
+	}
+
+	sortByCreation(oldReplicaSets)
+
+	cleanupCount, err := deploymentController.cleanupUnhealthyReplicas(oldReplicaSets, deployment, maxScaledDown)
+	if err != nil {
+		return false, err
+	}
+
+	scaledDownCount, err := deploymentController.scaleDownOldReplicaSets(append(oldReplicaSets, newReplicaSet), oldReplicaSets, deployment, minAvailable)
+	if err != nil {
+		return false, err
+	}
+
+	return cleanupCount+scaledDownCount > 0, nil
+}
+
+// cleanupUnhealthyReplicas scales the old replicasets down by their unavailable pods, oldest replicaset first
+func (deploymentController *Controller) cleanupUnhealthyReplicas(
+	oldReplicaSets []*kubeapi_rest.ReplicaSet,
+	deployment *kubeapi_rest.Deployment,
+	maxCleanupCount int,
+) (int, error) {
+	totalScaledDown := 0
+
+	for index, replicaSet := range oldReplicaSets {
+		if totalScaledDown >= maxCleanupCount {
+			break
+		}
+
+		replicas := *replicaSet.Spec.Replicas
+		if replicas == 0 || replicas == replicaSet.Status.AvailableReplicas {
+			continue
+		}
+
+		scaledDownCount := min(maxCleanupCount-totalScaledDown, replicas-replicaSet.Status.AvailableReplicas)
+
+		_, updatedReplicaSet, err := deploymentController.scaleReplicaSet(replicaSet, replicas-scaledDownCount, deployment)
+		if err != nil {
+			return totalScaledDown, err
+		}
+
+		oldReplicaSets[index] = updatedReplicaSet
+		totalScaledDown += scaledDownCount
+	}
+
+	return totalScaledDown, nil
+}
+
+// scaleDownOldReplicaSets scales the old replicasets down by the available pods above minAvailable, oldest
+// replicaset first
+func (deploymentController *Controller) scaleDownOldReplicaSets(
+	allReplicaSets []*kubeapi_rest.ReplicaSet,
+	oldReplicaSets []*kubeapi_rest.ReplicaSet,
+	deployment *kubeapi_rest.Deployment,
+	minAvailable int,
+) (int, error) {
+	availablePodCount := 0
+	for _, replicaSet := range allReplicaSets {
+		availablePodCount += replicaSet.Status.AvailableReplicas
+	}
+
+	if availablePodCount <= minAvailable {
+		return 0, nil
+	}
+
+	totalScaleDownCount := availablePodCount - minAvailable
+	totalScaledDown := 0
+
+	for index, replicaSet := range oldReplicaSets {
+		if totalScaledDown >= totalScaleDownCount {
+			break
+		}
+
+		replicas := *replicaSet.Spec.Replicas
+		if replicas == 0 {
+			continue
+		}
+
+		scaleDownCount := min(replicas, totalScaleDownCount-totalScaledDown)
+
+		_, updatedReplicaSet, err := deploymentController.scaleReplicaSet(replicaSet, replicas-scaleDownCount, deployment)
+		if err != nil {
+			return totalScaledDown, err
+		}
+
+		oldReplicaSets[index] = updatedReplicaSet
+		totalScaledDown += scaleDownCount
+	}
+
+	return totalScaledDown, nil
+}
+
+// scaleReplicaSet sets the replicas of the replicaset, it returns if it was scaled and the updated replicaset
+func (deploymentController *Controller) scaleReplicaSet(
+	replicaSet *kubeapi_rest.ReplicaSet,
+	replicas int,
+	deployment *kubeapi_rest.Deployment,
+) (bool, *kubeapi_rest.ReplicaSet, error) {
+	if *replicaSet.Spec.Replicas == replicas {
+		return false, replicaSet, nil
+	}
+
+	scalingOperation := "up"
+	if *replicaSet.Spec.Replicas > replicas {
+		scalingOperation = "down"
+	}
+
+	updatedReplicaSet, err := controller.DeepCopy(replicaSet)
+	if err != nil {
+		return false, replicaSet, err
+	}
+
+	updatedReplicaSet.Spec.Replicas = &replicas
+
+	if err = updateReplicaSet(deploymentController.kubeAPIEndpoint, updatedReplicaSet); err != nil {
+		return false, replicaSet, fmt.Errorf("error scaling replicaset %s: %v", replicaSet.Metadata.Name, err)
+	}
+
+	deploymentController.eventRecorder.Eventf(deploymentReference(deployment), kubeapi_rest.EventTypeNormal,
+		"ScalingReplicaSet", "Scaled %s replica set %s to %d", scalingOperation, replicaSet.Metadata.Name, replicas)
+
+	return true, updatedReplicaSet, nil
+}
+
+func specReplicas(replicaSets []*kubeapi_rest.ReplicaSet) int {
+	replicas := 0
+	for _, replicaSet := range replicaSets {
+		if replicaSet != nil {
+			replicas += *replicaSet.Spec.Replicas
+		}
+	}
+
+	return replicas
+}
+
+// sortByCreation orders the replicasets from the oldest to the newest
+func sortByCreation(replicaSets []*kubeapi_rest.ReplicaSet) {
+	sort.SliceStable(replicaSets, func(i, j int) bool {
+		if replicaSets[i].Metadata.CreationTimestamp == replicaSets[j].Metadata.CreationTimestamp {
+			return replicaSets[i].Metadata.Name < replicaSets[j].Metadata.Name
+		}
+
+		return replicaSets[i].Metadata.CreationTimestamp < replicaSets[j].Metadata.CreationTimestamp
+	})
+}
@@ -0,0 +1,204 @@
+package deployment
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/jonatan5524/own-kubernetes/pkg/controller"
+	kubeapi_rest "github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
+)
+
+const (
+	// same reasons as the kubernetes deployment conditions
+	minimumReplicasAvailable   = "MinimumReplicasAvailable"
+	minimumReplicasUnavailable = "MinimumReplicasUnavailable"
+	newReplicaSetCreatedReason = "NewReplicaSetCreated"
+	replicaSetUpdatedReason    = "ReplicaSetUpdated"
+	newRSAvailableReason       = "NewReplicaSetAvailable"
+	timedOutReason             = "ProgressDeadlineExceeded"
+)
+
+func calculateStatus(
+	allReplicaSets []*kubeapi_rest.ReplicaSet,
+	newReplicaSet *kubeapi_rest.ReplicaSet,
+	deployment *kubeapi_rest.Deployment,
+) kubeapi_rest.DeploymentStatus {
+	status := kubeapi_rest.DeploymentStatus{
+		Conditions: append([]kubeapi_rest.DeploymentCondition{}, deployment.Status.Conditions...),
+	}
+
+	for _, replicaSet := range allReplicaSets {
+		if replicaSet == nil {
+			continue
+		}
+
+		status.Replicas += replicaSet.Status.Replicas
+		status.ReadyReplicas += replicaSet.Status.ReadyReplicas
+		status.AvailableReplicas += replicaSet.Status.AvailableReplicas
+	}
+
+	if newReplicaSet != nil {
+		status.UpdatedReplicas = newReplicaSet.Status.Replicas
+	}
+
+	status.UnavailableReplicas = max(specReplicas(allReplicaSets)-status.AvailableReplicas, 0)
+
+	return status
+}
+
+// deploymentComplete tells if all the replicas run the current template and are available
+func deploymentComplete(deployment *kubeapi_rest.Deployment, status kubeapi_rest.DeploymentStatus) bool {
+	replicas := *deployment.Spec.Replicas
+
+	return status.UpdatedReplicas == replicas && status.Replicas == replicas && status.AvailableReplicas == replicas
+}
+
+// deploymentProgressing tells if the rollout moved since the last status, more pods of the current template or
+// less old pods or more ready or available pods
+func deploymentProgressing(oldStatus kubeapi_rest.DeploymentStatus, newStatus kubeapi_rest.DeploymentStatus) bool {
+	oldStatusOldReplicas := oldStatus.Replicas - oldStatus.UpdatedReplicas
+	newStatusOldReplicas := newStatus.Replicas - newStatus.UpdatedReplicas
+
+	return newStatus.UpdatedReplicas > oldStatus.UpdatedReplicas ||
+		newStatusOldReplicas < oldStatusOldReplicas ||
+		newStatus.ReadyReplicas > oldStatus.ReadyReplicas ||
+		newStatus.AvailableReplicas > oldStatus.AvailableReplicas
+}
+
+// syncRolloutStatus updates the replica counts and the Available and Progressing conditions of the deployment.
+// The rollout is failed when it did not progress for the progress deadline, the deployment is synced again at
+// the deadline to report it
+func (deploymentController *Controller) syncRolloutStatus(
+	allReplicaSets []*kubeapi_rest.ReplicaSet,
+	newReplicaSet *kubeapi_rest.ReplicaSet,
+	deployment *kubeapi_rest.Deployment,
+	created bool,
+) error {
+	newStatus := calculateStatus(allReplicaSets, newReplicaSet, deployment)
+	now := time.Now()
+
+	_, maxUnavailable, err := deployment.MaxSurgeAndUnavailable()
+	if err != nil {
+		return err
+	}
+
+	if newStatus.AvailableReplicas >= *deployment.Spec.Replicas-maxUnavailable {
+		setCondition(&newStatus, newCondition(kubeapi_rest.DeploymentAvailable, kubeapi_rest.ConditionTrue,
+			minimumReplicasAvailable, "Deployment has minimum availability.", now), false)
+	} else {
+		setCondition(&newStatus, newCondition(kubeapi_rest.DeploymentAvailable, kubeapi_rest.ConditionFalse,
+			minimumReplicasUnavailable, "Deployment does not have minimum availability.", now), false)
+	}
+
+	// the new replicaset of a Recreate rollout is created only once the old pods are gone
+	subject := fmt.Sprintf("Deployment %q", deployment.Metadata.Name)
+	if newReplicaSet != nil {
+		subject = fmt.Sprintf("ReplicaSet %q", newReplicaSet.Metadata.Name)
+	}
+
+	progressing := deployment.Condition(kubeapi_rest.DeploymentProgressing)
+	complete := newReplicaSet != nil && deploymentComplete(deployment, newStatus)
+
+	switch {
+	case complete:
+		setCondition(&newStatus, newCondition(kubeapi_rest.DeploymentProgressing, kubeapi_rest.ConditionTrue,
+			newRSAvailableReason, fmt.Sprintf("%s has successfully progressed.", subject), now), false)
+	case created:
+		setCondition(&newStatus, newCondition(kubeapi_rest.DeploymentProgressing, kubeapi_rest.ConditionTrue,
+			newReplicaSetCreatedReason, fmt.Sprintf("Created new replica set %q", newReplicaSet.Metadata.Name), now), true)
+	case progressing == nil || progressing.Reason == newRSAvailableReason || deploymentProgressing(deployment.Status, newStatus):
+		// a rollout that was complete and is not anymore started again
+		setCondition(&newStatus, newCondition(kubeapi_rest.DeploymentProgressing, kubeapi_rest.ConditionTrue,
+			replicaSetUpdatedReason, fmt.Sprintf("%s is progressing.", subject), now), true)
+	case progressTimedOut(progressing, deployment, now):
+		setCondition(&newStatus, newCondition(kubeapi_rest.DeploymentProgressing, kubeapi_rest.ConditionFalse,
+			timedOutReason, fmt.Sprintf("%s has timed out progressing.", subject), now), false)
+	}
+
+	if !reflect.DeepEqual(newStatus, deployment.Status) {
+		deployment.Status = newStatus
+
+		if err := updateDeploymentStatus(deploymentController.kubeAPIEndpoint, deployment); err != nil {
+			return fmt.Errorf("error updating status of deployment %s/%s: %v", deployment.Metadata.Namespace, deployment.Metadata.Name, err)
+		}
+	}
+
+	if !complete {
+		deploymentController.requeueAtProgressDeadline(deployment, now)
+	}
+
+	return nil
+}
+
+func progressTimedOut(progressing *kubeapi_rest.DeploymentCondition, deployment *kubeapi_rest.Deployment, now time.Time) bool {
+	if progressing == nil || progressing.Reason == newRSAvailableReason {
+		return false
+	}
+
+	if progressing.Reason == timedOutReason {
+		return true
+	}
+
+	lastUpdateTime, err := time.Parse(time.RFC3339, progressing.LastUpdateTime)
+	if err != nil {
+		return false
+	}
+
+	return lastUpdateTime.Add(time.Duration(*deployment.Spec.ProgressDeadlineSeconds) * time.Second).Before(now)
+}
+
+// requeueAtProgressDeadline syncs the deployment again when its progress deadline passes, the rollout is reported
+// as failed then if it made no progress
+func (deploymentController *Controller) requeueAtProgressDeadline(deployment *kubeapi_rest.Deployment, now time.Time) {
+	progressing := deployment.Condition(kubeapi_rest.DeploymentProgressing)
+	if progressing == nil || progressing.Status != kubeapi_rest.ConditionTrue || progressing.Reason == newRSAvailableReason {
+		return
+	}
+
+	lastUpdateTime, err := time.Parse(time.RFC3339, progressing.LastUpdateTime)
+	if err != nil {
+		return
+	}
+
+	deadline := lastUpdateTime.Add(time.Duration(*deployment.Spec.ProgressDeadlineSeconds) * time.Second)
+
+	// one second after the deadline so the deadline passed by then
+	deploymentController.queue.AddAfter(controller.MetaKey(deployment.Metadata), deadline.Sub(now)+time.Second)
+}
+
+func newCondition(conditionType string, status string, reason string, message string, now time.Time) kubeapi_rest.DeploymentCondition {
+	return kubeapi_rest.DeploymentCondition{
+		Type:               conditionType,
+		Status:             status,
+		LastUpdateTime:     now.Format(time.RFC3339),
+		LastTransitionTime: now.Format(time.RFC3339),
+		Reason:             reason,
+		Message:            message,
+	}
+}
+
+// setCondition adds the condition or replaces the one of the same type. A condition with the same status and
+// reason is kept as is unless progress is set, then only its update time and message change
+func setCondition(status *kubeapi_rest.DeploymentStatus, condition kubeapi_rest.DeploymentCondition, progress bool) {
+	for index := range status.Conditions {
+		existing := &status.Conditions[index]
+		if existing.Type != condition.Type {
+			continue
+		}
+
+		if existing.Status == condition.Status && existing.Reason == condition.Reason && !progress {
+			return
+		}
+
+		if existing.Status == condition.Status {
+			condition.LastTransitionTime = existing.LastTransitionTime
+		}
+
+		*existing = condition
+
+		return
+	}
+
+	status.Conditions = append(status.Conditions, condition)
+}
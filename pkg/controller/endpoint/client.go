@@ -0,0 +1,23 @@
+package endpoint
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/jonatan5524/own-kubernetes/pkg/controller"
+	kubeapi_rest "github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
+)
+
+func createEndpoint(kubeAPIEndpoint string, endpoint *kubeapi_rest.Endpoint) error {
+	return controller.SendRequest(http.MethodPost, fmt.Sprintf("%s/namespaces/%s/endpoints", kubeAPIEndpoint, endpoint.Metadata.Namespace), endpoint)
+}
+
+func deleteEndpoint(kubeAPIEndpoint string, namespace string, name string) error {
+	err := controller.SendRequest(http.MethodDelete, fmt.Sprintf("%s/namespaces/%s/endpoints/%s", kubeAPIEndpoint, namespace, name), nil)
+	if err != nil && strings.Contains(err.Error(), "key not found") {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,269 @@
+package endpoint
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jonatan5524/own-kubernetes/pkg/controller"
+	kubeapi_rest "github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
+	"github.com/jonatan5524/own-kubernetes/pkg/record"
+)
+
+const (
+	DefaultConcurrentSyncs = 5
+)
+
+type Options struct {
+	// ConcurrentSyncs is the number of services synced in parallel
+	ConcurrentSyncs int
+}
+
+// Controller writes the Endpoint of every service with a selector, the addresses of the pods matching the selector
+// with the target ports of the service resolved for each pod. It is the only writer of these endpoints, the
+// kube-proxies only read them. Services without a selector keep the endpoints written by the user
+type Controller struct {
+	kubeAPIEndpoint string
+	options         Options
+	eventRecorder   record.EventRecorder
+
+	serviceInformer  *controller.Informer[kubeapi_rest.Service]
+	podInformer      *controller.Informer[kubeapi_rest.Pod]
+	endpointInformer *controller.Informer[kubeapi_rest.Endpoint]
+
+	queue *controller.RateLimitingQueue
+}
+
+func NewController(ctx controller.ControllerContext, options Options) *Controller {
+	endpointController := &Controller{
+		kubeAPIEndpoint:  ctx.KubeAPIEndpoint,
+		options:          options,
+		eventRecorder:    ctx.EventRecorder("endpoint-controller"),
+		serviceInformer:  ctx.InformerFactory.Services(),
+		podInformer:      ctx.InformerFactory.Pods(),
+		endpointInformer: ctx.InformerFactory.Endpoints(),
+		queue:            controller.NewRateLimitingQueue(),
+	}
+
+	endpointController.serviceInformer.AddEventHandler(controller.ResourceEventHandler[kubeapi_rest.Service]{
+		OnAdd: endpointController.enqueueService,
+		OnUpdate: func(_ *kubeapi_rest.Service, newService *kubeapi_rest.Service) {
+			endpointController.enqueueService(newService)
+		},
+		OnDelete: endpointController.enqueueService,
+	})
+
+	endpointController.podInformer.AddEventHandler(controller.ResourceEventHandler[kubeapi_rest.Pod]{
+		OnAdd: endpointController.enqueuePodServices,
+		OnUpdate: func(oldPod *kubeapi_rest.Pod, newPod *kubeapi_rest.Pod) {
+			// a pod whose labels changed may leave the services of its old labels
+			if !reflect.DeepEqual(oldPod.Metadata.Labels, newPod.Metadata.Labels) {
+				endpointController.enqueuePodServices(oldPod)
+			}
+
+			endpointController.enqueuePodServices(newPod)
+		},
+		OnDelete: endpointController.enqueuePodServices,
+	})
+
+	// endpoints changed or deleted by someone else are written again
+	endpointController.endpointInformer.AddEventHandler(controller.ResourceEventHandler[kubeapi_rest.Endpoint]{
+		OnUpdate: func(_ *kubeapi_rest.Endpoint, newEndpoint *kubeapi_rest.Endpoint) {
+			endpointController.queue.Add(controller.MetaKey(newEndpoint.Metadata))
+		},
+		OnDelete: func(endpoint *kubeapi_rest.Endpoint) {
+			endpointController.queue.Add(controller.MetaKey(endpoint.Metadata))
+		},
+	})
+
+	return endpointController
+}
+
+func (endpointController *Controller) Run(stopCh <-chan struct{}) {
+	controller.RunWorkers("endpoint", endpointController.queue, endpointController.options.ConcurrentSyncs,
+		endpointController.syncService, stopCh)
+}
+
+func (endpointController *Controller) enqueueService(service *kubeapi_rest.Service) {
+	endpointController.queue.Add(controller.MetaKey(service.Metadata))
+}
+
+// enqueuePodServices enqueues the services in the namespace of the pod whose selector matches the pod
+func (endpointController *Controller) enqueuePodServices(pod *kubeapi_rest.Pod) {
+	for _, service := range endpointController.serviceInformer.List() {
+		if service.Metadata.Namespace == pod.Metadata.Namespace && serviceSelects(service, pod) {
+			endpointController.enqueueService(service)
+		}
+	}
+}
+
+func serviceSelects(service *kubeapi_rest.Service, pod *kubeapi_rest.Pod) bool {
+	return kubeapi_rest.LabelSelector{MatchLabels: service.Spec.Selector}.Matches(pod.Metadata.Labels)
+}
+
+func (endpointController *Controller) syncService(key string) error {
+	service, ok := endpointController.serviceInformer.Get(key)
+	if !ok {
+		// the endpoint of a deleted service is deleted with it
+		if _, ok := endpointController.endpointInformer.Get(key); !ok {
+			return nil
+		}
+
+		namespace, name := controller.SplitMetaKey(key)
+
+		return deleteEndpoint(endpointController.kubeAPIEndpoint, namespace, name)
+	}
+
+	if len(service.Spec.Selector) == 0 {
+		return nil
+	}
+
+	subsets := endpointController.computeSubsets(service)
+
+	currentEndpoint, ok := endpointController.endpointInformer.Get(key)
+	if ok && reflect.DeepEqual(currentEndpoint.Subsets, subsets) &&
+		reflect.DeepEqual(currentEndpoint.Metadata.Labels, service.Metadata.Labels) {
+		return nil
+	}
+
+	endpoint := &kubeapi_rest.Endpoint{
+		Metadata: kubeapi_rest.ResourceMetadata{
+			Name:      service.Metadata.Name,
+			Namespace: service.Metadata.Namespace,
+			Labels:    service.Metadata.Labels,
+		},
+		TypeMeta: kubeapi_rest.TypeMeta{Kind: "Endpoint"},
+		Subsets:  subsets,
+	}
+
+	if ok {
+		endpoint.Metadata.UID = currentEndpoint.Metadata.UID
+	}
+
+	if err := createEndpoint(endpointController.kubeAPIEndpoint, endpoint); err != nil {
+		endpointController.eventRecorder.Eventf(serviceReference(service), kubeapi_rest.EventTypeWarning,
+			"FailedToUpdateEndpoint", "Failed to update endpoint %s: %v", key, err)
+
+		return fmt.Errorf("error updating endpoint %s: %v", key, err)
+	}
+
+	return nil
+}
+
+// computeSubsets returns the addresses of the active pods of the service with an ip, grouped by the ports
+// resolved for them. A pod without a container port named by a target port does not serve that port
+func (endpointController *Controller) computeSubsets(service *kubeapi_rest.Service) []kubeapi_rest.EndpointSubset {
+	subsetsByPorts := map[string]*kubeapi_rest.EndpointSubset{}
+
+	for _, pod := range endpointController.podInformer.List() {
+		if pod.Metadata.Namespace != service.Metadata.Namespace || !serviceSelects(service, pod) ||
+			!pod.IsActive() || pod.Status.PodIP == "" {
+			continue
+		}
+
+		ports := []kubeapi_rest.EndpointPort{}
+		for _, servicePort := range service.Spec.Ports {
+			port, ok := resolveTargetPort(servicePort, pod)
+			if !ok {
+				continue
+			}
+
+			ports = append(ports, kubeapi_rest.EndpointPort{
+				Name:     servicePort.Name,
+				Port:     port,
+				Protocol: servicePort.Protocol,
+			})
+		}
+
+		if len(ports) == 0 {
+			continue
+		}
+
+		portsKey := portsKey(ports)
+		subset, ok := subsetsByPorts[portsKey]
+		if !ok {
+			subset = &kubeapi_rest.EndpointSubset{Addresses: []kubeapi_rest.EndpointAddress{}, Ports: ports}
+			subsetsByPorts[portsKey] = subset
+		}
+
+		address := kubeapi_rest.EndpointAddress{
+			IP:       pod.Status.PodIP,
+			NodeName: pod.Spec.NodeName,
+			TargetRef: kubeapi_rest.TargetRef{
+				Kind:      "Pod",
+				Name:      pod.Metadata.Name,
+				Namespace: pod.Metadata.Namespace,
+				UID:       pod.Metadata.UID,
+			},
+		}
+
+		if pod.IsReady() {
+			subset.Addresses = append(subset.Addresses, address)
+		} else {
+			subset.NotReadyAddresses = append(subset.NotReadyAddresses, address)
+		}
+	}
+
+	// the subsets are sorted so the same pods always give the same endpoint, and it is written only when it changed
+	subsets := []kubeapi_rest.EndpointSubset{}
+	for _, subset := range subsetsByPorts {
+		sortAddresses(subset.Addresses)
+		sortAddresses(subset.NotReadyAddresses)
+		subsets = append(subsets, *subset)
+	}
+
+	sort.Slice(subsets, func(i, j int) bool {
+		return portsKey(subsets[i].Ports) < portsKey(subsets[j].Ports)
+	})
+
+	return subsets
+}
+
+// resolveTargetPort returns the port of the pod the service port sends to, the target port is a number, the name
+// of a container port of the pod, or the port of the service when not set
+func resolveTargetPort(servicePort kubeapi_rest.ServicePorts, pod *kubeapi_rest.Pod) (int, bool) {
+	targetPort := string(servicePort.TargetPort)
+	if targetPort == "" {
+		return servicePort.Port, true
+	}
+
+	if port, err := strconv.Atoi(targetPort); err == nil {
+		return port, true
+	}
+
+	for _, container := range pod.Spec.Containers {
+		for _, containerPort := range container.Ports {
+			if containerPort.Name == targetPort {
+				return containerPort.ContainerPort, true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+func portsKey(ports []kubeapi_rest.EndpointPort) string {
+	keys := make([]string, 0, len(ports))
+	for _, port := range ports {
+		keys = append(keys, fmt.Sprintf("%s/%d/%s", port.Name, port.Port, port.Protocol))
+	}
+
+	return strings.Join(keys, ",")
+}
+
+func sortAddresses(addresses []kubeapi_rest.EndpointAddress) {
+	sort.Slice(addresses, func(i, j int) bool {
+		return addresses[i].TargetRef.Name < addresses[j].TargetRef.Name
+	})
+}
+
+func serviceReference(service *kubeapi_rest.Service) kubeapi_rest.ObjectReference {
+	return kubeapi_rest.ObjectReference{
+		Kind:      "Service",
+		Namespace: service.Metadata.Namespace,
+		Name:      service.Metadata.Name,
+		UID:       service.Metadata.UID,
+	}
+}
@@ -0,0 +1,104 @@
+package controller
+
+import (
+	"sync"
+	"time"
+)
+
+// ExpectationsTimeout is how long a controller waits for its informers to see its creations and deletions,
+// after it the controller acts again in case a watch event was lost
+const ExpectationsTimeout = 5 * time.Minute
+
+// ControllerExpectations records the pods a controller created and deleted and its informer did not see yet.
+// The informer lags behind the api, so a controller that acts before it sees its own changes would create or
+// delete the pods again
+type ControllerExpectations struct {
+	mu           sync.Mutex
+	expectations map[string]*expectation
+}
+
+type expectation struct {
+	creations int
+	// deletions are the keys of the pods expected to be deleted, a pod seen deleted twice is counted once
+	deletions map[string]bool
+	timestamp time.Time
+}
+
+func NewControllerExpectations() *ControllerExpectations {
+	return &ControllerExpectations{
+		expectations: make(map[string]*expectation),
+	}
+}
+
+// SatisfiedExpectations tells if the controller of the key may act, all its changes were seen or they expired
+func (expectations *ControllerExpectations) SatisfiedExpectations(key string) bool {
+	expectations.mu.Lock()
+	defer expectations.mu.Unlock()
+
+	exp, ok := expectations.expectations[key]
+	if !ok {
+		return true
+	}
+
+	if exp.creations <= 0 && len(exp.deletions) == 0 {
+		return true
+	}
+
+	return time.Since(exp.timestamp) > ExpectationsTimeout
+}
+
+// ExpectCreations replaces the expectations of the key with the number of pods about to be created
+func (expectations *ControllerExpectations) ExpectCreations(key string, creations int) {
+	expectations.SetExpectations(key, creations, nil)
+}
+
+// ExpectDeletions replaces the expectations of the key with the keys of the pods about to be deleted
+func (expectations *ControllerExpectations) ExpectDeletions(key string, podKeys []string) {
+	expectations.SetExpectations(key, 0, podKeys)
+}
+
+// SetExpectations replaces the expectations of the key with both the pods about to be created and deleted, for
+// controllers that create and delete pods in the same sync
+func (expectations *ControllerExpectations) SetExpectations(key string, creations int, podKeys []string) {
+	expectations.mu.Lock()
+	defer expectations.mu.Unlock()
+
+	deletions := make(map[string]bool, len(podKeys))
+	for _, podKey := range podKeys {
+		deletions[podKey] = true
+	}
+
+	expectations.expectations[key] = &expectation{
+		creations: creations,
+		deletions: deletions,
+		timestamp: time.Now(),
+	}
+}
+
+// CreationObserved lowers the expected creations, it is also called for a creation that failed
+func (expectations *ControllerExpectations) CreationObserved(key string) {
+	expectations.mu.Lock()
+	defer expectations.mu.Unlock()
+
+	if exp, ok := expectations.expectations[key]; ok {
+		exp.creations--
+	}
+}
+
+// DeletionObserved removes the pod from the expected deletions, it is also called for a deletion that failed
+func (expectations *ControllerExpectations) DeletionObserved(key string, podKey string) {
+	expectations.mu.Lock()
+	defer expectations.mu.Unlock()
+
+	if exp, ok := expectations.expectations[key]; ok {
+		delete(exp.deletions, podKey)
+	}
+}
+
+// DeleteExpectations forgets the key, it is called when the controller object is deleted
+func (expectations *ControllerExpectations) DeleteExpectations(key string) {
+	expectations.mu.Lock()
+	defer expectations.mu.Unlock()
+
+	delete(expectations.expectations, key)
+}
@@ -0,0 +1,488 @@
+package controller
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	kubeapi_rest "github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
+	"github.com/jonatan5524/own-kubernetes/pkg/utils"
+)
+
+const (
+	DefaultResyncPeriod = 10 * time.Minute
+
+	watchRetryInterval    = 5 * time.Second
+	cacheSyncPollInterval = 100 * time.Millisecond
+)
+
+// ResourceEventHandler is notified of the changes seen by an informer, every func is optional.
+// The objects are shared with the cache of the informer and must not be changed
+type ResourceEventHandler[T any] struct {
+	OnAdd    func(obj *T)
+	OnUpdate func(oldObj *T, newObj *T)
+	OnDelete func(obj *T)
+}
+
+// Informer keeps a cache of all the objects of a resource with a list and a watch from the revision of the list,
+// when the watch ends the resource is listed again. Every resync period all the objects are sent again to
+// OnUpdate so the controllers fix what they missed
+type Informer[T any] struct {
+	kubeAPIEndpoint string
+	resource        string
+	resyncPeriod    time.Duration
+
+	mu       sync.RWMutex
+	items    map[string]*T
+	handlers []ResourceEventHandler[T]
+	synced   bool
+}
+
+// objectMeta reads only the metadata of an object to get its key
+type objectMeta struct {
+	Metadata kubeapi_rest.ResourceMetadata `json:"metadata"`
+}
+
+func newInformer[T any](kubeAPIEndpoint string, resource string, resyncPeriod time.Duration) *Informer[T] {
+	return &Informer[T]{
+		kubeAPIEndpoint: kubeAPIEndpoint,
+		resource:        resource,
+		resyncPeriod:    resyncPeriod,
+		items:           make(map[string]*T),
+	}
+}
+
+// AddEventHandler registers the handler, the objects already in the cache are sent to its OnAdd
+func (informer *Informer[T]) AddEventHandler(handler ResourceEventHandler[T]) {
+	informer.mu.Lock()
+	informer.handlers = append(informer.handlers, handler)
+	informer.mu.Unlock()
+
+	if handler.OnAdd == nil {
+		return
+	}
+
+	for _, obj := range informer.List() {
+		handler.OnAdd(obj)
+	}
+}
+
+// Get returns the object by its key, namespace/name or name for cluster scoped resources
+func (informer *Informer[T]) Get(key string) (*T, bool) {
+	informer.mu.RLock()
+	defer informer.mu.RUnlock()
+
+	obj, ok := informer.items[key]
+
+	return obj, ok
+}
+
+func (informer *Informer[T]) List() []*T {
+	informer.mu.RLock()
+	defer informer.mu.RUnlock()
+
+	objs := make([]*T, 0, len(informer.items))
+	for _, obj := range informer.items {
+		objs = append(objs, obj)
+	}
+
+	return objs
+}
+
+// HasSynced tells if the first list of the resource is in the cache
+func (informer *Informer[T]) HasSynced() bool {
+	informer.mu.RLock()
+	defer informer.mu.RUnlock()
+
+	return informer.synced
+}
+
+// Run keeps the cache up to date until the stop channel is closed
+func (informer *Informer[T]) Run(stopCh <-chan struct{}) {
+	log.Printf("informer for %s running", informer.resource)
+
+	go informer.resync(stopCh)
+
+	for {
+		if err := informer.listAndWatch(stopCh); err != nil {
+			log.Printf("watch on %s stopped: %v", informer.resource, err)
+		}
+
+		select {
+		case <-stopCh:
+			return
+		case <-time.After(watchRetryInterval):
+		}
+	}
+}
+
+func (informer *Informer[T]) resync(stopCh <-chan struct{}) {
+	if informer.resyncPeriod <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(informer.resyncPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		}
+
+		informer.mu.RLock()
+		handlers := informer.handlers
+		informer.mu.RUnlock()
+
+		for _, obj := range informer.List() {
+			for _, handler := range handlers {
+				if handler.OnUpdate != nil {
+					handler.OnUpdate(obj, obj)
+				}
+			}
+		}
+	}
+}
+
+func (informer *Informer[T]) listAndWatch(stopCh <-chan struct{}) error {
+	resourceVersion, err := informer.list()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		select {
+		case <-stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		fmt.Sprintf("%s/%s/?watch=true&resourceVersion=%s", informer.kubeAPIEndpoint, informer.resource, resourceVersion), nil)
+	if err != nil {
+		return fmt.Errorf("error creating request: %v", err)
+	}
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("error reading response body: %v", err)
+		}
+
+		return fmt.Errorf("request failed with status code: %d %s", resp.StatusCode, string(body))
+	}
+
+	reader := bufio.NewReader(resp.Body)
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			if errors.Is(err, io.EOF) || ctx.Err() != nil {
+				return fmt.Errorf("watch stream closed")
+			}
+
+			return fmt.Errorf("error reading watch stream: %v", err)
+		}
+
+		line = strings.TrimSpace(line)
+
+		if len(line) == 0 {
+			continue
+		}
+
+		typeEvent, value, err := utils.GetTypeAndValueFromEvent(line)
+		if err != nil {
+			log.Printf("error getting type and value from event: %v", err)
+
+			continue
+		}
+
+		if typeEvent == kubeapi_rest.WatchErrorEventType {
+			return fmt.Errorf("watch ended by api: %s", value)
+		}
+
+		key, obj, err := decodeObject[T]([]byte(value))
+		if err != nil {
+			log.Printf("error parsing %s from event: %v", informer.resource, err)
+
+			continue
+		}
+
+		if typeEvent == "PUT" {
+			informer.set(key, obj)
+		} else {
+			informer.delete(key)
+		}
+	}
+}
+
+// list replaces the cache with a list of the resource, the handlers get the differences from the cache
+func (informer *Informer[T]) list() (string, error) {
+	resp, err := http.Get(fmt.Sprintf("%s/%s", informer.kubeAPIEndpoint, informer.resource))
+	if err != nil {
+		return "", fmt.Errorf("error sending request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("error reading response body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("request failed with status code: %d %s", resp.StatusCode, string(body))
+	}
+
+	var values []json.RawMessage
+	if err = json.Unmarshal(body, &values); err != nil {
+		return "", fmt.Errorf("error unmarshalling JSON: %v", err)
+	}
+
+	listed := make(map[string]*T, len(values))
+	for _, value := range values {
+		key, obj, err := decodeObject[T](value)
+		if err != nil {
+			return "", fmt.Errorf("error parsing %s: %v", informer.resource, err)
+		}
+
+		listed[key] = obj
+	}
+
+	informer.mu.Lock()
+	previous := informer.items
+	informer.items = listed
+	informer.synced = true
+	handlers := informer.handlers
+	informer.mu.Unlock()
+
+	for key, obj := range listed {
+		oldObj, existed := previous[key]
+		for _, handler := range handlers {
+			if existed && handler.OnUpdate != nil {
+				handler.OnUpdate(oldObj, obj)
+			} else if !existed && handler.OnAdd != nil {
+				handler.OnAdd(obj)
+			}
+		}
+	}
+
+	for key, oldObj := range previous {
+		if _, ok := listed[key]; ok {
+			continue
+		}
+
+		for _, handler := range handlers {
+			if handler.OnDelete != nil {
+				handler.OnDelete(oldObj)
+			}
+		}
+	}
+
+	return resp.Header.Get(kubeapi_rest.ResourceVersionHeader), nil
+}
+
+func (informer *Informer[T]) set(key string, obj *T) {
+	informer.mu.Lock()
+	oldObj, existed := informer.items[key]
+	informer.items[key] = obj
+	handlers := informer.handlers
+	informer.mu.Unlock()
+
+	for _, handler := range handlers {
+		if existed && handler.OnUpdate != nil {
+			handler.OnUpdate(oldObj, obj)
+		} else if !existed && handler.OnAdd != nil {
+			handler.OnAdd(obj)
+		}
+	}
+}
+
+func (informer *Informer[T]) delete(key string) {
+	informer.mu.Lock()
+	oldObj, existed := informer.items[key]
+	delete(informer.items, key)
+	handlers := informer.handlers
+	informer.mu.Unlock()
+
+	if !existed {
+		return
+	}
+
+	for _, handler := range handlers {
+		if handler.OnDelete != nil {
+			handler.OnDelete(oldObj)
+		}
+	}
+}
+
+func decodeObject[T any](value []byte) (string, *T, error) {
+	var meta objectMeta
+	if err := json.Unmarshal(value, &meta); err != nil {
+		return "", nil, err
+	}
+
+	obj := new(T)
+	if err := json.Unmarshal(value, obj); err != nil {
+		return "", nil, err
+	}
+
+	return MetaKey(meta.Metadata), obj, nil
+}
+
+type informerRunner interface {
+	Run(stopCh <-chan struct{})
+	HasSynced() bool
+}
+
+// InformerFactory hands out one informer per resource, so all the controllers watching a resource share its cache
+type InformerFactory struct {
+	kubeAPIEndpoint string
+	resyncPeriod    time.Duration
+
+	mu        sync.Mutex
+	informers map[string]informerRunner
+	started   map[string]bool
+}
+
+func NewInformerFactory(kubeAPIEndpoint string, resyncPeriod time.Duration) *InformerFactory {
+	return &InformerFactory{
+		kubeAPIEndpoint: kubeAPIEndpoint,
+		resyncPeriod:    resyncPeriod,
+		informers:       make(map[string]informerRunner),
+		started:         make(map[string]bool),
+	}
+}
+
+// ForResource returns the informer of the resource, resource is the path of its list in the api, for example pods
+func ForResource[T any](factory *InformerFactory, resource string) *Informer[T] {
+	factory.mu.Lock()
+	defer factory.mu.Unlock()
+
+	if informer, ok := factory.informers[resource]; ok {
+		return informer.(*Informer[T])
+	}
+
+	informer := newInformer[T](factory.kubeAPIEndpoint, resource, factory.resyncPeriod)
+	factory.informers[resource] = informer
+
+	return informer
+}
+
+func (factory *InformerFactory) Pods() *Informer[kubeapi_rest.Pod] {
+	return ForResource[kubeapi_rest.Pod](factory, "pods")
+}
+
+func (factory *InformerFactory) Nodes() *Informer[kubeapi_rest.Node] {
+	return ForResource[kubeapi_rest.Node](factory, "nodes")
+}
+
+func (factory *InformerFactory) Leases() *Informer[kubeapi_rest.Lease] {
+	return ForResource[kubeapi_rest.Lease](factory, "leases")
+}
+
+func (factory *InformerFactory) Services() *Informer[kubeapi_rest.Service] {
+	return ForResource[kubeapi_rest.Service](factory, "services")
+}
+
+func (factory *InformerFactory) Endpoints() *Informer[kubeapi_rest.Endpoint] {
+	return ForResource[kubeapi_rest.Endpoint](factory, "endpoints")
+}
+
+func (factory *InformerFactory) ReplicaSets() *Informer[kubeapi_rest.ReplicaSet] {
+	return ForResource[kubeapi_rest.ReplicaSet](factory, "replicasets")
+}
+
+func (factory *InformerFactory) Deployments() *Informer[kubeapi_rest.Deployment] {
+	return ForResource[kubeapi_rest.Deployment](factory, "deployments")
+}
+
+func (factory *InformerFactory) DaemonSets() *Informer[kubeapi_rest.DaemonSet] {
+	return ForResource[kubeapi_rest.DaemonSet](factory, "daemonsets")
+}
+
+func (factory *InformerFactory) Jobs() *Informer[kubeapi_rest.Job] {
+	return ForResource[kubeapi_rest.Job](factory, "jobs")
+}
+
+func (factory *InformerFactory) CronJobs() *Informer[kubeapi_rest.CronJob] {
+	return ForResource[kubeapi_rest.CronJob](factory, "cronjobs")
+}
+
+func (factory *InformerFactory) StatefulSets() *Informer[kubeapi_rest.StatefulSet] {
+	return ForResource[kubeapi_rest.StatefulSet](factory, "statefulsets")
+}
+
+func (factory *InformerFactory) HorizontalPodAutoscalers() *Informer[kubeapi_rest.HorizontalPodAutoscaler] {
+	return ForResource[kubeapi_rest.HorizontalPodAutoscaler](factory, "horizontalpodautoscalers")
+}
+
+func (factory *InformerFactory) PodMetrics() *Informer[kubeapi_rest.PodMetrics] {
+	return ForResource[kubeapi_rest.PodMetrics](factory, "podmetrics")
+}
+
+// Start runs the informers that were requested and are not running yet
+func (factory *InformerFactory) Start(stopCh <-chan struct{}) {
+	factory.mu.Lock()
+	defer factory.mu.Unlock()
+
+	for resource, informer := range factory.informers {
+		if factory.started[resource] {
+			continue
+		}
+
+		factory.started[resource] = true
+
+		go informer.Run(stopCh)
+	}
+}
+
+// WaitForCacheSync blocks until all the informers listed their resource, it returns false when stopped before
+func (factory *InformerFactory) WaitForCacheSync(stopCh <-chan struct{}) bool {
+	ticker := time.NewTicker(cacheSyncPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if factory.hasSynced() {
+			return true
+		}
+
+		select {
+		case <-stopCh:
+			return false
+		case <-ticker.C:
+		}
+	}
+}
+
+func (factory *InformerFactory) hasSynced() bool {
+	factory.mu.Lock()
+	defer factory.mu.Unlock()
+
+	for _, informer := range factory.informers {
+		if !informer.HasSynced() {
+			return false
+		}
+	}
+
+	return true
+}
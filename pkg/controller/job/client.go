@@ -0,0 +1,17 @@
+package job
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/jonatan5524/own-kubernetes/pkg/controller"
+	kubeapi_rest "github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
+)
+
+func updateJobStatus(kubeAPIEndpoint string, job *kubeapi_rest.Job) error {
+	return controller.SendRequest(
+		http.MethodPatch,
+		fmt.Sprintf("%s/namespaces/%s/jobs/%s/status", kubeAPIEndpoint, job.Metadata.Namespace, job.Metadata.Name),
+		job.Status,
+	)
+}
@@ -0,0 +1,440 @@
+package job
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"time"
+
+	"github.com/jonatan5524/own-kubernetes/pkg/controller"
+	kubeapi_rest "github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
+	"github.com/jonatan5524/own-kubernetes/pkg/record"
+)
+
+const (
+	DefaultConcurrentSyncs = 5
+
+	// burstReplicas bounds the pods created or deleted in a single sync of a job
+	burstReplicas = 500
+
+	// a failed pod is replaced after a delay that doubles with every failure, same as kubernetes
+	defaultBackoff = 10 * time.Second
+	maxBackoff     = 6 * time.Minute
+
+	controllerKind = "Job"
+)
+
+type Options struct {
+	// ConcurrentSyncs is the number of jobs synced in parallel
+	ConcurrentSyncs int
+}
+
+// Controller runs the pods of every job until completions of them succeeded. A pod succeeds when all its
+// containers exited with a zero exit code and fails when one of them exited with another code, a failed pod is
+// replaced by a new one until the job has backoffLimit failed pods
+type Controller struct {
+	kubeAPIEndpoint string
+	options         Options
+	eventRecorder   record.EventRecorder
+
+	jobInformer *controller.Informer[kubeapi_rest.Job]
+	podInformer *controller.Informer[kubeapi_rest.Pod]
+
+	queue        *controller.RateLimitingQueue
+	expectations *controller.ControllerExpectations
+}
+
+func NewController(ctx controller.ControllerContext, options Options) *Controller {
+	jobController := &Controller{
+		kubeAPIEndpoint: ctx.KubeAPIEndpoint,
+		options:         options,
+		eventRecorder:   ctx.EventRecorder("job-controller"),
+		jobInformer:     ctx.InformerFactory.Jobs(),
+		podInformer:     ctx.InformerFactory.Pods(),
+		queue:           controller.NewRateLimitingQueue(),
+		expectations:    controller.NewControllerExpectations(),
+	}
+
+	jobController.jobInformer.AddEventHandler(controller.ResourceEventHandler[kubeapi_rest.Job]{
+		OnAdd: jobController.enqueueJob,
+		OnUpdate: func(_ *kubeapi_rest.Job, newJob *kubeapi_rest.Job) {
+			jobController.enqueueJob(newJob)
+		},
+		OnDelete: func(job *kubeapi_rest.Job) {
+			jobController.expectations.DeleteExpectations(controller.MetaKey(job.Metadata))
+			jobController.enqueueJob(job)
+		},
+	})
+
+	jobController.podInformer.AddEventHandler(controller.ResourceEventHandler[kubeapi_rest.Pod]{
+		OnAdd:    jobController.addPod,
+		OnUpdate: jobController.updatePod,
+		OnDelete: jobController.deletePod,
+	})
+
+	return jobController
+}
+
+func (jobController *Controller) Run(stopCh <-chan struct{}) {
+	controller.RunWorkers("job", jobController.queue, jobController.options.ConcurrentSyncs, jobController.syncJob, stopCh)
+}
+
+func (jobController *Controller) enqueueJob(job *kubeapi_rest.Job) {
+	jobController.queue.Add(controller.MetaKey(job.Metadata))
+}
+
+// jobKeyOfPod returns the key of the job controlling the pod, empty when a job does not control it
+func jobKeyOfPod(pod *kubeapi_rest.Pod) string {
+	controllerRef := pod.Metadata.ControllerRef()
+	if controllerRef == nil || controllerRef.Kind != controllerKind {
+		return ""
+	}
+
+	return controller.MetaKey(kubeapi_rest.ResourceMetadata{Namespace: pod.Metadata.Namespace, Name: controllerRef.Name})
+}
+
+func (jobController *Controller) addPod(pod *kubeapi_rest.Pod) {
+	key := jobKeyOfPod(pod)
+	if key == "" {
+		return
+	}
+
+	if pod.IsActive() {
+		jobController.expectations.CreationObserved(key)
+	} else {
+		jobController.expectations.DeletionObserved(key, controller.MetaKey(pod.Metadata))
+	}
+
+	jobController.queue.Add(key)
+}
+
+func (jobController *Controller) updatePod(oldPod *kubeapi_rest.Pod, newPod *kubeapi_rest.Pod) {
+	key := jobKeyOfPod(newPod)
+	if key == "" {
+		return
+	}
+
+	if oldPod.IsActive() && !newPod.IsActive() {
+		jobController.expectations.DeletionObserved(key, controller.MetaKey(newPod.Metadata))
+	}
+
+	jobController.queue.Add(key)
+}
+
+func (jobController *Controller) deletePod(pod *kubeapi_rest.Pod) {
+	key := jobKeyOfPod(pod)
+	if key == "" {
+		return
+	}
+
+	jobController.expectations.DeletionObserved(key, controller.MetaKey(pod.Metadata))
+	jobController.queue.Add(key)
+}
+
+func (jobController *Controller) syncJob(key string) error {
+	namespace, name := controller.SplitMetaKey(key)
+	pods := jobController.listJobPods(namespace, name)
+
+	sharedJob, ok := jobController.jobInformer.Get(key)
+	if !ok {
+		// the pods of a deleted job are deleted with it
+		return jobController.deleteOrphanPods(pods)
+	}
+
+	job, err := controller.DeepCopy(sharedJob)
+	if err != nil {
+		return err
+	}
+
+	var orphanPods []*kubeapi_rest.Pod
+	var activePods []*kubeapi_rest.Pod
+	var failedPods []*kubeapi_rest.Pod
+	succeeded := 0
+
+	for _, pod := range pods {
+		switch {
+		case pod.Metadata.ControllerRef().UID != job.Metadata.UID:
+			// a pod of a previous job with the same name
+			orphanPods = append(orphanPods, pod)
+		case pod.Status.Phase == kubeapi_rest.PodSucceededPhase:
+			succeeded++
+		case pod.Status.Phase == kubeapi_rest.PodFailedPhase:
+			failedPods = append(failedPods, pod)
+		case pod.IsActive():
+			activePods = append(activePods, pod)
+		}
+	}
+
+	if err = jobController.deleteOrphanPods(orphanPods); err != nil {
+		return err
+	}
+
+	if job.IsFinished() {
+		return nil
+	}
+
+	now := time.Now()
+	oldStatus := job.Status
+
+	if job.Status.StartTime == "" {
+		job.Status.StartTime = now.Format(time.RFC3339)
+	}
+
+	job.Status.Succeeded = succeeded
+	job.Status.Failed = len(failedPods)
+	job.Status.Active = len(activePods)
+
+	var manageErr error
+
+	if reason, message := jobFailure(job, now); reason != "" {
+		manageErr = jobController.deleteJobPods(job, activePods)
+		job.Status.Active = 0
+
+		setCondition(&job.Status, newCondition(kubeapi_rest.JobFailed, reason, message, now))
+		jobController.eventRecorder.Event(jobReference(job), kubeapi_rest.EventTypeWarning, reason, message)
+	} else if succeeded >= *job.Spec.Completions {
+		manageErr = jobController.deleteJobPods(job, activePods)
+		job.Status.Active = 0
+		job.Status.CompletionTime = now.Format(time.RFC3339)
+
+		setCondition(&job.Status, newCondition(kubeapi_rest.JobComplete, "", "", now))
+		jobController.eventRecorder.Event(jobReference(job), kubeapi_rest.EventTypeNormal, "Completed", "Job completed")
+	} else if jobController.expectations.SatisfiedExpectations(key) {
+		job.Status.Active, manageErr = jobController.manageJob(key, job, activePods, failedPods, now)
+	}
+
+	if !reflect.DeepEqual(oldStatus, job.Status) {
+		if err = updateJobStatus(jobController.kubeAPIEndpoint, job); err != nil {
+			return fmt.Errorf("error updating status of job %s: %v", key, err)
+		}
+	}
+
+	// the job fails at its deadline without an event, it is synced again then
+	if !job.IsFinished() && job.Spec.ActiveDeadlineSeconds != nil {
+		startTime, err := time.Parse(time.RFC3339, job.Status.StartTime)
+		if err == nil {
+			jobController.queue.AddAfter(key, startTime.Add(time.Duration(*job.Spec.ActiveDeadlineSeconds)*time.Second).Sub(now))
+		}
+	}
+
+	return manageErr
+}
+
+// manageJob creates or deletes pods until the job runs parallelism pods, or fewer when fewer completions are left,
+// and returns the number of active pods. After a failed pod the new pods wait for the backoff of the failures
+func (jobController *Controller) manageJob(
+	key string,
+	job *kubeapi_rest.Job,
+	activePods []*kubeapi_rest.Pod,
+	failedPods []*kubeapi_rest.Pod,
+	now time.Time,
+) (int, error) {
+	active := len(activePods)
+	wantActive := max(min(*job.Spec.Parallelism, *job.Spec.Completions-job.Status.Succeeded), 0)
+
+	if active > wantActive {
+		podsToDelete := make([]*kubeapi_rest.Pod, len(activePods))
+		copy(podsToDelete, activePods)
+
+		controller.SortPodsForDeletion(podsToDelete)
+		podsToDelete = podsToDelete[:min(active-wantActive, burstReplicas)]
+
+		podKeys := make([]string, 0, len(podsToDelete))
+		for _, pod := range podsToDelete {
+			podKeys = append(podKeys, controller.MetaKey(pod.Metadata))
+		}
+
+		jobController.expectations.ExpectDeletions(key, podKeys)
+
+		var deleteErr error
+
+		for _, pod := range podsToDelete {
+			if err := controller.DeletePod(jobController.kubeAPIEndpoint, pod, pod.Spec.NodeName == ""); err != nil {
+				jobController.expectations.DeletionObserved(key, controller.MetaKey(pod.Metadata))
+				jobController.eventRecorder.Eventf(jobReference(job), kubeapi_rest.EventTypeWarning,
+					"FailedDelete", "Error deleting: %v", err)
+
+				deleteErr = fmt.Errorf("error deleting pod %s of job %s: %v", pod.Metadata.Name, key, err)
+
+				continue
+			}
+
+			active--
+
+			jobController.eventRecorder.Eventf(jobReference(job), kubeapi_rest.EventTypeNormal,
+				"SuccessfulDelete", "Deleted pod: %s", pod.Metadata.Name)
+		}
+
+		return active, deleteErr
+	}
+
+	if active == wantActive {
+		return active, nil
+	}
+
+	if len(failedPods) > 0 {
+		backoff := min(defaultBackoff*time.Duration(1<<min(len(failedPods)-1, 16)), maxBackoff)
+
+		if remaining := lastFailureTime(failedPods).Add(backoff).Sub(now); remaining > 0 {
+			jobController.queue.AddAfter(key, remaining)
+
+			return active, nil
+		}
+	}
+
+	creations := min(wantActive-active, burstReplicas)
+	jobController.expectations.ExpectCreations(key, creations)
+
+	controllerRef := controller.NewControllerRef(controllerKind, job.Metadata)
+
+	for created := 0; created < creations; created++ {
+		pod := controller.NewPodFromTemplate(job.Spec.Template, job.Metadata.Namespace, controllerRef)
+
+		if err := controller.CreatePod(jobController.kubeAPIEndpoint, pod); err != nil {
+			// the creations that did not happen will not be observed, stop at the first error so a broken
+			// template does not flood the api
+			for skipped := created; skipped < creations; skipped++ {
+				jobController.expectations.CreationObserved(key)
+			}
+
+			jobController.eventRecorder.Eventf(jobReference(job), kubeapi_rest.EventTypeWarning,
+				"FailedCreate", "Error creating: %v", err)
+
+			return active, fmt.Errorf("error creating pod for job %s: %v", key, err)
+		}
+
+		active++
+
+		jobController.eventRecorder.Eventf(jobReference(job), kubeapi_rest.EventTypeNormal,
+			"SuccessfulCreate", "Created pod: %s", pod.Metadata.Name)
+	}
+
+	return active, nil
+}
+
+// jobFailure returns the reason and message of the failure of the job, an empty reason when it did not fail
+func jobFailure(job *kubeapi_rest.Job, now time.Time) (string, string) {
+	if job.Status.Failed > *job.Spec.BackoffLimit {
+		return "BackoffLimitExceeded", "Job has reached the specified backoff limit"
+	}
+
+	if job.Spec.ActiveDeadlineSeconds == nil {
+		return "", ""
+	}
+
+	startTime, err := time.Parse(time.RFC3339, job.Status.StartTime)
+	if err != nil {
+		return "", ""
+	}
+
+	if !now.Before(startTime.Add(time.Duration(*job.Spec.ActiveDeadlineSeconds) * time.Second)) {
+		return "DeadlineExceeded", "Job was active longer than specified deadline"
+	}
+
+	return "", ""
+}
+
+// lastFailureTime returns the latest time a container of the failed pods exited
+func lastFailureTime(failedPods []*kubeapi_rest.Pod) time.Time {
+	var lastFailure time.Time
+
+	for _, pod := range failedPods {
+		for _, containerStatus := range pod.Status.ContainerStatuses {
+			if containerStatus.State.Terminated == nil {
+				continue
+			}
+
+			finishedAt, err := time.Parse(time.RFC3339, containerStatus.State.Terminated.FinishedAt)
+			if err == nil && finishedAt.After(lastFailure) {
+				lastFailure = finishedAt
+			}
+		}
+	}
+
+	return lastFailure
+}
+
+// deleteJobPods stops the active pods of a finished job, the finished pods are kept for their logs
+func (jobController *Controller) deleteJobPods(job *kubeapi_rest.Job, activePods []*kubeapi_rest.Pod) error {
+	var deleteErr error
+
+	for _, pod := range activePods {
+		if err := controller.DeletePod(jobController.kubeAPIEndpoint, pod, pod.Spec.NodeName == ""); err != nil {
+			jobController.eventRecorder.Eventf(jobReference(job), kubeapi_rest.EventTypeWarning,
+				"FailedDelete", "Error deleting: %v", err)
+
+			deleteErr = fmt.Errorf("error deleting pod %s of job %s/%s: %v", pod.Metadata.Name,
+				job.Metadata.Namespace, job.Metadata.Name, err)
+
+			continue
+		}
+
+		jobController.eventRecorder.Eventf(jobReference(job), kubeapi_rest.EventTypeNormal,
+			"SuccessfulDelete", "Deleted pod: %s", pod.Metadata.Name)
+	}
+
+	return deleteErr
+}
+
+func (jobController *Controller) deleteOrphanPods(pods []*kubeapi_rest.Pod) error {
+	for _, pod := range pods {
+		if pod.Status.Phase == kubeapi_rest.PodTerminatingPhase && pod.Spec.NodeName != "" {
+			// its kubelet is already stopping it
+			continue
+		}
+
+		log.Printf("deleting pod %s/%s, its job was deleted", pod.Metadata.Namespace, pod.Metadata.Name)
+
+		if err := controller.DeletePod(jobController.kubeAPIEndpoint, pod, pod.Spec.NodeName == ""); err != nil {
+			return fmt.Errorf("error deleting pod %s/%s: %v", pod.Metadata.Namespace, pod.Metadata.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// listJobPods returns the pods controlled by a job of the name, including the pods of a previous job with the
+// same name
+func (jobController *Controller) listJobPods(namespace string, name string) []*kubeapi_rest.Pod {
+	var pods []*kubeapi_rest.Pod
+
+	for _, pod := range jobController.podInformer.List() {
+		if pod.Metadata.Namespace == namespace && controller.IsControlledBy(pod.Metadata, controllerKind, name) {
+			pods = append(pods, pod)
+		}
+	}
+
+	return pods
+}
+
+func newCondition(conditionType string, reason string, message string, now time.Time) kubeapi_rest.JobCondition {
+	return kubeapi_rest.JobCondition{
+		Type:               conditionType,
+		Status:             kubeapi_rest.ConditionTrue,
+		LastProbeTime:      now.Format(time.RFC3339),
+		LastTransitionTime: now.Format(time.RFC3339),
+		Reason:             reason,
+		Message:            message,
+	}
+}
+
+func setCondition(status *kubeapi_rest.JobStatus, condition kubeapi_rest.JobCondition) {
+	for index := range status.Conditions {
+		if status.Conditions[index].Type == condition.Type {
+			status.Conditions[index] = condition
+
+			return
+		}
+	}
+
+	status.Conditions = append(status.Conditions, condition)
+}
+
+func jobReference(job *kubeapi_rest.Job) kubeapi_rest.ObjectReference {
+	return kubeapi_rest.ObjectReference{
+		Kind:      controllerKind,
+		Namespace: job.Metadata.Namespace,
+		Name:      job.Metadata.Name,
+		UID:       job.Metadata.UID,
+	}
+}
@@ -0,0 +1,27 @@
+package nodelifecycle
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/jonatan5524/own-kubernetes/pkg/controller"
+	kubeapi_rest "github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
+)
+
+func updateNodeStatus(kubeAPIEndpoint string, nodeName string, nodeStatus kubeapi_rest.NodeStatus) error {
+	return controller.SendRequest(http.MethodPatch, fmt.Sprintf("%s/nodes/%s/status", kubeAPIEndpoint, nodeName), nodeStatus)
+}
+
+func updatePodStatus(kubeAPIEndpoint string, pod *kubeapi_rest.Pod) error {
+	return controller.SendRequest(
+		http.MethodPatch,
+		fmt.Sprintf("%s/namespaces/%s/pods/%s/status", kubeAPIEndpoint, pod.Metadata.Namespace, pod.Metadata.Name),
+		pod.Status,
+	)
+}
+
+// deletePod removes the pod with force, the kubelet of a not ready node can not stop its containers. The receiver
+// of the controller methods shadows the controller package, so the evictions go through this function
+func deletePod(kubeAPIEndpoint string, pod *kubeapi_rest.Pod) error {
+	return controller.DeletePod(kubeAPIEndpoint, pod, true)
+}
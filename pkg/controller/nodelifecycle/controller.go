@@ -0,0 +1,430 @@
+package nodelifecycle
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/jonatan5524/own-kubernetes/pkg/controller"
+	kubeapi_rest "github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
+	"github.com/jonatan5524/own-kubernetes/pkg/record"
+	"github.com/jonatan5524/own-kubernetes/pkg/utils"
+)
+
+const (
+	// same defaults as the kubernetes node lifecycle controller
+	DefaultNodeMonitorPeriod         = 5 * time.Second
+	DefaultNodeMonitorGracePeriod    = 40 * time.Second
+	DefaultPodEvictionTimeout        = 5 * time.Minute
+	DefaultEvictionRate              = 0.1
+	DefaultSecondaryEvictionRate     = 0.01
+	DefaultUnhealthyZoneThreshold    = 0.55
+	DefaultLargeClusterSizeThreshold = 50
+
+	nodeStatusUnknownReason  = "NodeStatusUnknown"
+	nodeStatusUnknownMessage = "Kubelet stopped posting node status."
+)
+
+type Options struct {
+	// NodeMonitorPeriod is how often the nodes are checked
+	NodeMonitorPeriod time.Duration
+	// NodeMonitorGracePeriod is how long a node may go without a heartbeat before it is marked as not ready
+	NodeMonitorGracePeriod time.Duration
+	// PodEvictionTimeout is how long a node may be not ready before its pods are deleted
+	PodEvictionTimeout time.Duration
+	// EvictionRate is the number of nodes per second whose pods are deleted
+	EvictionRate float64
+	// SecondaryEvictionRate is used instead of EvictionRate when a large part of the cluster is not ready,
+	// it is zero for clusters smaller than LargeClusterSizeThreshold
+	SecondaryEvictionRate     float64
+	UnhealthyZoneThreshold    float64
+	LargeClusterSizeThreshold int
+}
+
+// nodeHealth is what the controller observed of a node, the times are of the controller clock so a node
+// with a skewed clock is not marked as not ready
+type nodeHealth struct {
+	// probeTimestamp is when a heartbeat of the node was last seen
+	probeTimestamp time.Time
+	// readyTransitionTimestamp is when the node stopped being ready, zero while it is ready
+	readyTransitionTimestamp time.Time
+
+	lastLeaseRenewTime string
+	lastHeartbeatTime  string
+
+	evicted bool
+}
+
+type Controller struct {
+	kubeAPIEndpoint string
+	options         Options
+	eventRecorder   record.EventRecorder
+
+	nodeInformer  *controller.Informer[kubeapi_rest.Node]
+	leaseInformer *controller.Informer[kubeapi_rest.Lease]
+	podInformer   *controller.Informer[kubeapi_rest.Pod]
+
+	nodeHealths map[string]*nodeHealth
+
+	evictionLimiter     *utils.TokenBucket
+	evictionLimiterRate float64
+}
+
+func NewController(ctx controller.ControllerContext, options Options) *Controller {
+	return &Controller{
+		kubeAPIEndpoint: ctx.KubeAPIEndpoint,
+		options:         options,
+		eventRecorder:   ctx.EventRecorder("node-controller"),
+		nodeInformer:    ctx.InformerFactory.Nodes(),
+		leaseInformer:   ctx.InformerFactory.Leases(),
+		podInformer:     ctx.InformerFactory.Pods(),
+		nodeHealths:     make(map[string]*nodeHealth),
+	}
+}
+
+// Run checks the nodes every monitor period until the stop channel is closed
+func (controller *Controller) Run(stopCh <-chan struct{}) {
+	log.Printf("node lifecycle controller running")
+
+	ticker := time.NewTicker(controller.options.NodeMonitorPeriod)
+	defer ticker.Stop()
+
+	for {
+		if err := controller.monitorNodeHealth(); err != nil {
+			log.Printf("error monitoring node health: %v", err)
+		}
+
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (controller *Controller) monitorNodeHealth() error {
+	nodes := controller.listNodes()
+	leases := controller.listNodeLeases()
+
+	now := time.Now()
+	seen := make(map[string]bool, len(nodes))
+
+	for index := range nodes {
+		node := &nodes[index]
+		seen[node.Metadata.Name] = true
+
+		controller.updateNodeHealth(node, leases[node.Metadata.Name], now)
+
+		if err := controller.processNode(node, now); err != nil {
+			log.Printf("error processing node %s: %v", node.Metadata.Name, err)
+		}
+	}
+
+	for name := range controller.nodeHealths {
+		if !seen[name] {
+			delete(controller.nodeHealths, name)
+		}
+	}
+
+	controller.updateEvictionRate(nodes)
+
+	return controller.evictPods(nodes, now)
+}
+
+// updateNodeHealth records a heartbeat when the lease was renewed or the kubelet posted a new status
+func (controller *Controller) updateNodeHealth(node *kubeapi_rest.Node, lease *kubeapi_rest.Lease, now time.Time) {
+	health, ok := controller.nodeHealths[node.Metadata.Name]
+	if !ok {
+		// a node seen for the first time gets a full grace period, the controller may have just started
+		health = &nodeHealth{probeTimestamp: now}
+		controller.nodeHealths[node.Metadata.Name] = health
+	}
+
+	if lease != nil && lease.Spec.RenewTime != health.lastLeaseRenewTime {
+		health.lastLeaseRenewTime = lease.Spec.RenewTime
+		health.probeTimestamp = now
+	}
+
+	if ready := node.Condition(kubeapi_rest.NodeReady); ready != nil &&
+		ready.Status != kubeapi_rest.ConditionUnknown && ready.LastHeartbeatTime != health.lastHeartbeatTime {
+		health.lastHeartbeatTime = ready.LastHeartbeatTime
+		health.probeTimestamp = now
+	}
+}
+
+func (controller *Controller) processNode(node *kubeapi_rest.Node, now time.Time) error {
+	health := controller.nodeHealths[node.Metadata.Name]
+
+	if now.Sub(health.probeTimestamp) > controller.options.NodeMonitorGracePeriod {
+		if err := controller.markNodeUnknown(node); err != nil {
+			return err
+		}
+	}
+
+	if isNodeReady(node) {
+		if !health.readyTransitionTimestamp.IsZero() {
+			log.Printf("node %s is ready again", node.Metadata.Name)
+			controller.eventRecorder.Eventf(nodeReference(node), kubeapi_rest.EventTypeNormal, "NodeReady",
+				"Node %s status is now: NodeReady", node.Metadata.Name)
+		}
+
+		health.readyTransitionTimestamp = time.Time{}
+		health.evicted = false
+
+		return nil
+	}
+
+	if health.readyTransitionTimestamp.IsZero() {
+		health.readyTransitionTimestamp = now
+
+		log.Printf("node %s is not ready", node.Metadata.Name)
+		controller.eventRecorder.Eventf(nodeReference(node), kubeapi_rest.EventTypeNormal, "NodeNotReady",
+			"Node %s status is now: NodeNotReady", node.Metadata.Name)
+	}
+
+	return controller.markPodsNotReady(node)
+}
+
+// markNodeUnknown sets all the conditions of a node that stopped sending heartbeats to Unknown,
+// the kubelet posts them again when it is back
+func (controller *Controller) markNodeUnknown(node *kubeapi_rest.Node) error {
+	changed := false
+	now := time.Now().Format(time.RFC3339)
+
+	for index := range node.Status.Conditions {
+		condition := &node.Status.Conditions[index]
+		if condition.Status == kubeapi_rest.ConditionUnknown {
+			continue
+		}
+
+		condition.Status = kubeapi_rest.ConditionUnknown
+		condition.Reason = nodeStatusUnknownReason
+		condition.Message = nodeStatusUnknownMessage
+		condition.LastTransitionTime = now
+		changed = true
+	}
+
+	if node.Condition(kubeapi_rest.NodeReady) == nil {
+		node.Status.Conditions = append(node.Status.Conditions, kubeapi_rest.NodeCondition{
+			Type:               kubeapi_rest.NodeReady,
+			Status:             kubeapi_rest.ConditionUnknown,
+			Reason:             nodeStatusUnknownReason,
+			Message:            nodeStatusUnknownMessage,
+			LastTransitionTime: now,
+		})
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+
+	log.Printf("node %s stopped sending heartbeats, marking its status as unknown", node.Metadata.Name)
+
+	return updateNodeStatus(controller.kubeAPIEndpoint, node.Metadata.Name, node.Status)
+}
+
+// markPodsNotReady sets the ready condition of the pods of the node to False, so they are taken out of the endpoints
+func (controller *Controller) markPodsNotReady(node *kubeapi_rest.Node) error {
+	pods := controller.listNodePods(node.Metadata.Name)
+
+	for index := range pods {
+		pod := &pods[index]
+
+		if ready := pod.Condition(kubeapi_rest.PodReady); ready != nil && ready.Status == kubeapi_rest.ConditionFalse {
+			continue
+		}
+
+		pod.SetCondition(kubeapi_rest.PodCondition{
+			Type:               kubeapi_rest.PodReady,
+			Status:             kubeapi_rest.ConditionFalse,
+			LastTransitionTime: time.Now().Format(time.RFC3339),
+			Reason:             "NodeNotReady",
+			Message:            fmt.Sprintf("node %s is not ready", node.Metadata.Name),
+		})
+
+		log.Printf("marking pod %s/%s as not ready", pod.Metadata.Namespace, pod.Metadata.Name)
+
+		if err := updatePodStatus(controller.kubeAPIEndpoint, pod); err != nil {
+			log.Printf("error marking pod %s/%s as not ready: %v", pod.Metadata.Namespace, pod.Metadata.Name, err)
+
+			continue
+		}
+
+		controller.eventRecorder.Eventf(podReference(pod), kubeapi_rest.EventTypeWarning, "NodeNotReady",
+			"Node is not ready")
+	}
+
+	return nil
+}
+
+// updateEvictionRate slows down or stops the evictions when a large part of the cluster is not ready,
+// it is more likely that the controller lost the network to the nodes than that all of them died
+func (controller *Controller) updateEvictionRate(nodes []kubeapi_rest.Node) {
+	notReady := 0
+	for index := range nodes {
+		if !isNodeReady(&nodes[index]) {
+			notReady++
+		}
+	}
+
+	rate := controller.options.EvictionRate
+
+	switch {
+	case len(nodes) > 0 && notReady == len(nodes):
+		rate = 0
+	case notReady > 2 && float64(notReady)/float64(len(nodes)) >= controller.options.UnhealthyZoneThreshold:
+		if len(nodes) > controller.options.LargeClusterSizeThreshold {
+			rate = controller.options.SecondaryEvictionRate
+		} else {
+			rate = 0
+		}
+	}
+
+	if controller.evictionLimiter != nil && rate == controller.evictionLimiterRate {
+		return
+	}
+
+	if controller.evictionLimiter != nil {
+		log.Printf("%d of %d nodes are not ready, eviction rate changed to %v nodes per second", notReady, len(nodes), rate)
+	}
+
+	controller.evictionLimiterRate = rate
+	controller.evictionLimiter = nil
+
+	if rate > 0 {
+		controller.evictionLimiter = utils.NewTokenBucket(rate, 1)
+	}
+}
+
+// evictPods deletes the pods of the nodes that are not ready for longer than the eviction timeout,
+// the nodes that are not ready for the longest are evicted first
+func (controller *Controller) evictPods(nodes []kubeapi_rest.Node, now time.Time) error {
+	toEvict := []string{}
+
+	for index := range nodes {
+		health := controller.nodeHealths[nodes[index].Metadata.Name]
+
+		if health.evicted || health.readyTransitionTimestamp.IsZero() ||
+			now.Sub(health.readyTransitionTimestamp) < controller.options.PodEvictionTimeout {
+			continue
+		}
+
+		toEvict = append(toEvict, nodes[index].Metadata.Name)
+	}
+
+	sort.Slice(toEvict, func(i, j int) bool {
+		return controller.nodeHealths[toEvict[i]].readyTransitionTimestamp.Before(controller.nodeHealths[toEvict[j]].readyTransitionTimestamp)
+	})
+
+	// evictions are stopped while the whole cluster is not ready, the change of the rate is already logged
+	if controller.evictionLimiter == nil {
+		return nil
+	}
+
+	for _, nodeName := range toEvict {
+		if !controller.evictionLimiter.TryAccept() {
+			log.Printf("eviction of %d nodes is rate limited", len(toEvict))
+
+			return nil
+		}
+
+		if err := controller.evictNodePods(nodeName); err != nil {
+			log.Printf("error evicting pods of node %s: %v", nodeName, err)
+
+			continue
+		}
+
+		controller.nodeHealths[nodeName].evicted = true
+	}
+
+	return nil
+}
+
+func (controller *Controller) evictNodePods(nodeName string) error {
+	pods := controller.listNodePods(nodeName)
+
+	log.Printf("evicting %d pods of node %s", len(pods), nodeName)
+
+	for index := range pods {
+		pod := &pods[index]
+
+		if err := deletePod(controller.kubeAPIEndpoint, pod); err != nil {
+			return err
+		}
+
+		controller.eventRecorder.Eventf(podReference(pod), kubeapi_rest.EventTypeNormal, "NodeControllerEviction",
+			"Deleting pod of not ready node %s", nodeName)
+	}
+
+	return nil
+}
+
+// listNodes returns copies of the nodes in the informer, the controller changes their status before posting it
+func (controller *Controller) listNodes() []kubeapi_rest.Node {
+	nodes := []kubeapi_rest.Node{}
+
+	for _, cachedNode := range controller.nodeInformer.List() {
+		node := *cachedNode
+		node.Status.Conditions = append([]kubeapi_rest.NodeCondition{}, cachedNode.Status.Conditions...)
+
+		nodes = append(nodes, node)
+	}
+
+	return nodes
+}
+
+// listNodeLeases returns the leases of the nodes by node name
+func (controller *Controller) listNodeLeases() map[string]*kubeapi_rest.Lease {
+	leasesByNode := make(map[string]*kubeapi_rest.Lease)
+
+	for _, lease := range controller.leaseInformer.List() {
+		if lease.Metadata.Namespace == kubeapi_rest.NodeLeaseNamespace {
+			leasesByNode[lease.Metadata.Name] = lease
+		}
+	}
+
+	return leasesByNode
+}
+
+// listNodePods returns copies of the pods of the node in the informer
+func (controller *Controller) listNodePods(nodeName string) []kubeapi_rest.Pod {
+	pods := []kubeapi_rest.Pod{}
+
+	for _, cachedPod := range controller.podInformer.List() {
+		if cachedPod.Spec.NodeName != nodeName {
+			continue
+		}
+
+		pod := *cachedPod
+		pod.Status.Conditions = append([]kubeapi_rest.PodCondition{}, cachedPod.Status.Conditions...)
+
+		pods = append(pods, pod)
+	}
+
+	return pods
+}
+
+func isNodeReady(node *kubeapi_rest.Node) bool {
+	ready := node.Condition(kubeapi_rest.NodeReady)
+
+	return ready != nil && ready.Status == kubeapi_rest.ConditionTrue
+}
+
+func nodeReference(node *kubeapi_rest.Node) kubeapi_rest.ObjectReference {
+	return kubeapi_rest.ObjectReference{
+		Kind: "Node",
+		Name: node.Metadata.Name,
+		UID:  node.Metadata.UID,
+	}
+}
+
+func podReference(pod *kubeapi_rest.Pod) kubeapi_rest.ObjectReference {
+	return kubeapi_rest.ObjectReference{
+		Kind:      "Pod",
+		Namespace: pod.Metadata.Namespace,
+		Name:      pod.Metadata.Name,
+		UID:       pod.Metadata.UID,
+	}
+}
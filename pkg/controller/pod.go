@@ -0,0 +1,152 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"math/rand"
+	"sort"
+	"time"
+
+	kubeapi_rest "github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
+)
+
+const (
+	// same alphabet as the kubernetes generated names, without vowels and look alike characters
+	nameSuffixAlphabet = "bcdfghjklmnpqrstvwxz2456789"
+	nameSuffixLength   = 5
+)
+
+// GenerateName returns the prefix with a random suffix, for the pods a controller creates
+func GenerateName(prefix string) string {
+	suffix := make([]byte, nameSuffixLength)
+	for index := range suffix {
+		suffix[index] = nameSuffixAlphabet[rand.Intn(len(nameSuffixAlphabet))]
+	}
+
+	return fmt.Sprintf("%s-%s", prefix, string(suffix))
+}
+
+// NewControllerRef returns the owner reference a controller sets on the objects it manages
+func NewControllerRef(kind string, metadata kubeapi_rest.ResourceMetadata) kubeapi_rest.OwnerReference {
+	return kubeapi_rest.OwnerReference{
+		Kind:       kind,
+		Name:       metadata.Name,
+		UID:        metadata.UID,
+		Controller: true,
+	}
+}
+
+// NewPodFromTemplate returns a pod of the template with a generated name owned by the controller
+func NewPodFromTemplate(template kubeapi_rest.PodTemplateSpec, namespace string, controllerRef kubeapi_rest.OwnerReference) *kubeapi_rest.Pod {
+	pod := &kubeapi_rest.Pod{
+		TypeMeta: kubeapi_rest.TypeMeta{Kind: "Pod"},
+		Metadata: kubeapi_rest.ResourceMetadata{
+			Name:            GenerateName(controllerRef.Name),
+			Namespace:       namespace,
+			Labels:          make(map[string]string, len(template.Metadata.Labels)),
+			Annotations:     make(map[string]string, len(template.Metadata.Annotations)),
+			OwnerReferences: []kubeapi_rest.OwnerReference{controllerRef},
+		},
+		Spec: template.Spec,
+	}
+
+	for key, value := range template.Metadata.Labels {
+		pod.Metadata.Labels[key] = value
+	}
+
+	for key, value := range template.Metadata.Annotations {
+		pod.Metadata.Annotations[key] = value
+	}
+
+	return pod
+}
+
+// IsControlledBy tells if the controller of the object is the given kind and name, whatever its UID is
+func IsControlledBy(metadata kubeapi_rest.ResourceMetadata, kind string, name string) bool {
+	controllerRef := metadata.ControllerRef()
+
+	return controllerRef != nil && controllerRef.Kind == kind && controllerRef.Name == name
+}
+
+// SortPodsForDeletion orders the pods from the first to delete to the last: the pods not bound to a node, then
+// pending pods, then not ready pods, then the pods ready for the shortest time, then the newest pods
+func SortPodsForDeletion(pods []*kubeapi_rest.Pod) {
+	phaseRank := map[string]int{
+		kubeapi_rest.PodPendingPhase: 0,
+		"":                           1,
+		kubeapi_rest.PodRunningPhase: 2,
+	}
+
+	sort.SliceStable(pods, func(i, j int) bool {
+		first, second := pods[i], pods[j]
+
+		if (first.Spec.NodeName == "") != (second.Spec.NodeName == "") {
+			return first.Spec.NodeName == ""
+		}
+
+		if phaseRank[first.Status.Phase] != phaseRank[second.Status.Phase] {
+			return phaseRank[first.Status.Phase] < phaseRank[second.Status.Phase]
+		}
+
+		if first.IsReady() != second.IsReady() {
+			return !first.IsReady()
+		}
+
+		if first.IsReady() {
+			firstReadyTime, secondReadyTime := readyTime(first), readyTime(second)
+			if !firstReadyTime.Equal(secondReadyTime) {
+				return firstReadyTime.After(secondReadyTime)
+			}
+		}
+
+		return first.Metadata.CreationTimestamp > second.Metadata.CreationTimestamp
+	})
+}
+
+func readyTime(pod *kubeapi_rest.Pod) time.Time {
+	ready := pod.Condition(kubeapi_rest.PodReady)
+	if ready == nil {
+		return time.Time{}
+	}
+
+	readyTime, err := time.Parse(time.RFC3339, ready.LastTransitionTime)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return readyTime
+}
+
+// DeepCopy returns a copy of an object of an informer, the objects of the informers are shared and must be copied
+// before they are changed
+func DeepCopy[T any](obj *T) (*T, error) {
+	objBytes, err := json.Marshal(obj)
+	if err != nil {
+		return nil, fmt.Errorf("error copying object: %v", err)
+	}
+
+	objCopy := new(T)
+	if err = json.Unmarshal(objBytes, objCopy); err != nil {
+		return nil, fmt.Errorf("error copying object: %v", err)
+	}
+
+	return objCopy, nil
+}
+
+// ComputeHash returns the hash of a pod template, the workload controllers label their pods with it to tell the pods
+// of the current template from the pods of an older one
+func ComputeHash(template kubeapi_rest.PodTemplateSpec) string {
+	hasher := fnv.New32a()
+
+	// the template is hashed in its json form, the json of a struct is always in the same order
+	templateBytes, err := json.Marshal(template)
+	if err != nil {
+		log.Printf("error hashing pod template: %v", err)
+	}
+
+	hasher.Write(templateBytes)
+
+	return fmt.Sprintf("%x", hasher.Sum32())
+}
@@ -0,0 +1,56 @@
+package podautoscaler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/jonatan5524/own-kubernetes/pkg/controller"
+	kubeapi_rest "github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
+)
+
+// scaleURL returns the url of the scale subresource of the target of the autoscaler
+func scaleURL(kubeAPIEndpoint string, hpa *kubeapi_rest.HorizontalPodAutoscaler) string {
+	return fmt.Sprintf("%s/namespaces/%s/%ss/%s/scale", kubeAPIEndpoint, hpa.Metadata.Namespace,
+		strings.ToLower(hpa.Spec.ScaleTargetRef.Kind), hpa.Spec.ScaleTargetRef.Name)
+}
+
+func getScale(kubeAPIEndpoint string, hpa *kubeapi_rest.HorizontalPodAutoscaler) (*kubeapi_rest.Scale, error) {
+	resp, err := http.Get(scaleURL(kubeAPIEndpoint, hpa))
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("request failed with status code: %d %s", resp.StatusCode, string(body))
+	}
+
+	scale := &kubeapi_rest.Scale{}
+	if err = json.Unmarshal(body, scale); err != nil {
+		return nil, fmt.Errorf("error unmarshalling JSON: %v", err)
+	}
+
+	return scale, nil
+}
+
+func updateScale(kubeAPIEndpoint string, hpa *kubeapi_rest.HorizontalPodAutoscaler, replicas int) error {
+	return controller.SendRequest(http.MethodPatch, scaleURL(kubeAPIEndpoint, hpa), kubeapi_rest.Scale{
+		Spec: kubeapi_rest.ScaleSpec{Replicas: replicas},
+	})
+}
+
+func updateHorizontalPodAutoscalerStatus(kubeAPIEndpoint string, hpa *kubeapi_rest.HorizontalPodAutoscaler) error {
+	return controller.SendRequest(
+		http.MethodPatch,
+		fmt.Sprintf("%s/namespaces/%s/horizontalpodautoscalers/%s/status", kubeAPIEndpoint, hpa.Metadata.Namespace, hpa.Metadata.Name),
+		hpa.Status,
+	)
+}
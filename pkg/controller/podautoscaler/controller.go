@@ -0,0 +1,381 @@
+package podautoscaler
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/jonatan5524/own-kubernetes/pkg/controller"
+	kubeapi_rest "github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
+	"github.com/jonatan5524/own-kubernetes/pkg/record"
+)
+
+const (
+	DefaultConcurrentSyncs = 5
+	// same defaults as kubernetes
+	DefaultSyncPeriod = 15 * time.Second
+	DefaultTolerance  = 0.1
+
+	controllerKind = "HorizontalPodAutoscaler"
+)
+
+type Options struct {
+	// ConcurrentSyncs is the number of autoscalers synced in parallel
+	ConcurrentSyncs int
+	// SyncPeriod is how often the metrics of every autoscaler are checked
+	SyncPeriod time.Duration
+	// Tolerance is how far the ratio of the current to the target metric may be from 1 without scaling
+	Tolerance float64
+}
+
+type recommendation struct {
+	replicas  int
+	timestamp time.Time
+}
+
+// Controller scales the target of every horizontal pod autoscaler through its scale subresource, so the average
+// usage of the pods of the target, reported by the kubelets as pod metrics, is close to the targets of the
+// autoscaler metrics. The replicas are the most replicas any metric asks for, stabilized over the scaling windows
+// of the autoscaler and bounded by its min and max replicas
+type Controller struct {
+	kubeAPIEndpoint string
+	options         Options
+	eventRecorder   record.EventRecorder
+
+	hpaInformer        *controller.Informer[kubeapi_rest.HorizontalPodAutoscaler]
+	podInformer        *controller.Informer[kubeapi_rest.Pod]
+	podMetricsInformer *controller.Informer[kubeapi_rest.PodMetrics]
+
+	queue *controller.RateLimitingQueue
+
+	mu sync.Mutex
+	// recommendations are the replicas computed by the recent syncs of every autoscaler for its stabilization
+	// windows, and scheduled the autoscalers with a pending periodic sync
+	recommendations map[string][]recommendation
+	scheduled       map[string]bool
+}
+
+func NewController(ctx controller.ControllerContext, options Options) *Controller {
+	hpaController := &Controller{
+		kubeAPIEndpoint:    ctx.KubeAPIEndpoint,
+		options:            options,
+		eventRecorder:      ctx.EventRecorder("horizontal-pod-autoscaler"),
+		hpaInformer:        ctx.InformerFactory.HorizontalPodAutoscalers(),
+		podInformer:        ctx.InformerFactory.Pods(),
+		podMetricsInformer: ctx.InformerFactory.PodMetrics(),
+		queue:              controller.NewRateLimitingQueue(),
+		recommendations:    map[string][]recommendation{},
+		scheduled:          map[string]bool{},
+	}
+
+	hpaController.hpaInformer.AddEventHandler(controller.ResourceEventHandler[kubeapi_rest.HorizontalPodAutoscaler]{
+		OnAdd: hpaController.enqueueHorizontalPodAutoscaler,
+		OnUpdate: func(oldHPA *kubeapi_rest.HorizontalPodAutoscaler, newHPA *kubeapi_rest.HorizontalPodAutoscaler) {
+			// the status is written by the controller itself, the periodic sync is enough for it
+			if reflect.DeepEqual(oldHPA.Spec, newHPA.Spec) {
+				return
+			}
+
+			hpaController.enqueueHorizontalPodAutoscaler(newHPA)
+		},
+		OnDelete: hpaController.enqueueHorizontalPodAutoscaler,
+	})
+
+	return hpaController
+}
+
+func (hpaController *Controller) Run(stopCh <-chan struct{}) {
+	controller.RunWorkers("horizontalpodautoscaler", hpaController.queue, hpaController.options.ConcurrentSyncs,
+		hpaController.syncHorizontalPodAutoscaler, stopCh)
+}
+
+func (hpaController *Controller) enqueueHorizontalPodAutoscaler(hpa *kubeapi_rest.HorizontalPodAutoscaler) {
+	hpaController.queue.Add(controller.MetaKey(hpa.Metadata))
+}
+
+// scheduleSync syncs the autoscaler again after the sync period, an autoscaler has at most one pending periodic
+// sync however many times it was synced in between
+func (hpaController *Controller) scheduleSync(key string) {
+	hpaController.mu.Lock()
+	defer hpaController.mu.Unlock()
+
+	if hpaController.scheduled[key] {
+		return
+	}
+
+	hpaController.scheduled[key] = true
+
+	time.AfterFunc(hpaController.options.SyncPeriod, func() {
+		hpaController.mu.Lock()
+		delete(hpaController.scheduled, key)
+		hpaController.mu.Unlock()
+
+		hpaController.queue.Add(key)
+	})
+}
+
+func (hpaController *Controller) syncHorizontalPodAutoscaler(key string) error {
+	sharedHPA, ok := hpaController.hpaInformer.Get(key)
+	if !ok {
+		hpaController.mu.Lock()
+		delete(hpaController.recommendations, key)
+		hpaController.mu.Unlock()
+
+		return nil
+	}
+
+	hpaController.scheduleSync(key)
+
+	hpa, err := controller.DeepCopy(sharedHPA)
+	if err != nil {
+		return fmt.Errorf("error copying horizontalpodautoscaler %s: %v", key, err)
+	}
+
+	now := time.Now()
+
+	scale, err := getScale(hpaController.kubeAPIEndpoint, hpa)
+	if err != nil {
+		hpa.SetCondition(newCondition(kubeapi_rest.AbleToScale, kubeapi_rest.ConditionFalse, "FailedGetScale",
+			fmt.Sprintf("the HPA controller was unable to get the target's current scale: %v", err), now))
+		hpaController.eventRecorder.Eventf(hpaReference(hpa), kubeapi_rest.EventTypeWarning,
+			"FailedGetScale", "%v", err)
+
+		if statusErr := hpaController.updateStatusIfChanged(sharedHPA, hpa); statusErr != nil {
+			log.Printf("%v", statusErr)
+		}
+
+		return fmt.Errorf("error getting scale of %s %s: %v", hpa.Spec.ScaleTargetRef.Kind, hpa.Spec.ScaleTargetRef.Name, err)
+	}
+
+	currentReplicas := scale.Spec.Replicas
+	hpa.Status.CurrentReplicas = scale.Status.Replicas
+	desiredReplicas := currentReplicas
+	rescaleReason := ""
+
+	if currentReplicas == 0 {
+		// kubernetes does not scale a target scaled to zero, it was scaled down on purpose
+		hpa.SetCondition(newCondition(kubeapi_rest.ScalingActive, kubeapi_rest.ConditionFalse, "ScalingDisabled",
+			"scaling is disabled since the replica count of the target is zero", now))
+	} else {
+		metricReplicas, metricName, err := hpaController.computeReplicasForMetrics(hpa, scale, now)
+		if err != nil {
+			hpa.SetCondition(newCondition(kubeapi_rest.ScalingActive, kubeapi_rest.ConditionFalse, "FailedGetResourceMetric",
+				fmt.Sprintf("the HPA was unable to compute the replica count: %v", err), now))
+			hpaController.eventRecorder.Eventf(hpaReference(hpa), kubeapi_rest.EventTypeWarning,
+				"FailedComputeMetricsReplicas", "invalid metrics (%d invalid out of %d), first error is: %v",
+				len(hpa.Spec.Metrics), len(hpa.Spec.Metrics), err)
+
+			// the pods may not have metrics yet, they are checked again on the next periodic sync
+			return hpaController.updateStatusIfChanged(sharedHPA, hpa)
+		}
+
+		hpa.SetCondition(newCondition(kubeapi_rest.ScalingActive, kubeapi_rest.ConditionTrue, "ValidMetricFound",
+			fmt.Sprintf("the HPA was able to successfully calculate a replica count from %s", metricName), now))
+
+		rescaleReason = "All metrics below target"
+		if metricReplicas > currentReplicas {
+			rescaleReason = fmt.Sprintf("%s above target", metricName)
+		}
+
+		desiredReplicas = hpaController.stabilizeRecommendation(key, hpa, currentReplicas, metricReplicas, now)
+		desiredReplicas = boundReplicas(hpa, desiredReplicas, now)
+	}
+
+	hpa.Status.DesiredReplicas = desiredReplicas
+
+	if desiredReplicas != currentReplicas {
+		if err = updateScale(hpaController.kubeAPIEndpoint, hpa, desiredReplicas); err != nil {
+			hpa.SetCondition(newCondition(kubeapi_rest.AbleToScale, kubeapi_rest.ConditionFalse, "FailedUpdateScale",
+				fmt.Sprintf("the HPA controller was unable to update the target scale: %v", err), now))
+			hpaController.eventRecorder.Eventf(hpaReference(hpa), kubeapi_rest.EventTypeWarning,
+				"FailedRescale", "New size: %d; reason: %s; error: %v", desiredReplicas, rescaleReason, err)
+
+			if statusErr := hpaController.updateStatusIfChanged(sharedHPA, hpa); statusErr != nil {
+				log.Printf("%v", statusErr)
+			}
+
+			return fmt.Errorf("error scaling %s %s: %v", hpa.Spec.ScaleTargetRef.Kind, hpa.Spec.ScaleTargetRef.Name, err)
+		}
+
+		hpa.SetCondition(newCondition(kubeapi_rest.AbleToScale, kubeapi_rest.ConditionTrue, "SucceededRescale",
+			fmt.Sprintf("the HPA controller was able to update the target scale to %d", desiredReplicas), now))
+		hpaController.eventRecorder.Eventf(hpaReference(hpa), kubeapi_rest.EventTypeNormal,
+			"SuccessfulRescale", "New size: %d; reason: %s", desiredReplicas, rescaleReason)
+
+		hpa.Status.LastScaleTime = now.Format(time.RFC3339)
+	} else {
+		hpa.SetCondition(newCondition(kubeapi_rest.AbleToScale, kubeapi_rest.ConditionTrue, "ReadyForNewScale",
+			"recommended size matches current size", now))
+	}
+
+	return hpaController.updateStatusIfChanged(sharedHPA, hpa)
+}
+
+// computeReplicasForMetrics returns the most replicas any metric of the autoscaler asks for and the name of
+// that metric, the metrics that failed are skipped unless all of them failed
+func (hpaController *Controller) computeReplicasForMetrics(
+	hpa *kubeapi_rest.HorizontalPodAutoscaler,
+	scale *kubeapi_rest.Scale,
+	now time.Time,
+) (int, string, error) {
+	selector, err := kubeapi_rest.ParseLabelSelector(scale.Status.Selector)
+	if err != nil || len(selector.MatchLabels) == 0 {
+		return 0, "", fmt.Errorf("the target has an invalid selector %q", scale.Status.Selector)
+	}
+
+	pods := []*kubeapi_rest.Pod{}
+	for _, pod := range hpaController.podInformer.List() {
+		if pod.Metadata.Namespace == hpa.Metadata.Namespace && pod.IsActive() && selector.Matches(pod.Metadata.Labels) {
+			pods = append(pods, pod)
+		}
+	}
+
+	if len(pods) == 0 {
+		return 0, "", fmt.Errorf("no pods match the selector %q of the target", scale.Status.Selector)
+	}
+
+	replicas := 0
+	metricName := ""
+	statuses := []kubeapi_rest.MetricStatus{}
+	var firstErr error
+
+	for _, metric := range hpa.Spec.Metrics {
+		metricReplicas, status, err := hpaController.calculateReplicas(metric, pods, scale.Spec.Replicas, now)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to get %s utilization: %v", metric.Resource.Name, err)
+			}
+
+			continue
+		}
+
+		statuses = append(statuses, status)
+
+		if metricName == "" || metricReplicas > replicas {
+			replicas = metricReplicas
+			metricName = fmt.Sprintf("%s resource", metric.Resource.Name)
+			if metric.Resource.Target.Type == kubeapi_rest.UtilizationMetricType {
+				metricName = fmt.Sprintf("%s resource utilization (percentage of request)", metric.Resource.Name)
+			}
+		}
+	}
+
+	if metricName == "" {
+		return 0, "", firstErr
+	}
+
+	hpa.Status.CurrentMetrics = statuses
+
+	return replicas, metricName, nil
+}
+
+// stabilizeRecommendation records the replicas the metrics ask for and returns the replicas to scale to, scaling
+// up to the lowest and scaling down to the highest recommendation of their windows, so a short spike or dip of
+// the metrics does not change the replicas
+func (hpaController *Controller) stabilizeRecommendation(
+	key string,
+	hpa *kubeapi_rest.HorizontalPodAutoscaler,
+	currentReplicas int,
+	replicas int,
+	now time.Time,
+) int {
+	upWindow := time.Duration(hpa.ScaleUpStabilizationWindowSeconds()) * time.Second
+	downWindow := time.Duration(hpa.ScaleDownStabilizationWindowSeconds()) * time.Second
+	longestWindow := max(upWindow, downWindow)
+
+	hpaController.mu.Lock()
+	defer hpaController.mu.Unlock()
+
+	recommendations := []recommendation{{replicas: replicas, timestamp: now}}
+	for _, previous := range hpaController.recommendations[key] {
+		if now.Sub(previous.timestamp) <= longestWindow {
+			recommendations = append(recommendations, previous)
+		}
+	}
+
+	hpaController.recommendations[key] = recommendations
+
+	upRecommendation := replicas
+	downRecommendation := replicas
+
+	for _, previous := range recommendations {
+		if now.Sub(previous.timestamp) <= upWindow {
+			upRecommendation = min(upRecommendation, previous.replicas)
+		}
+
+		if now.Sub(previous.timestamp) <= downWindow {
+			downRecommendation = max(downRecommendation, previous.replicas)
+		}
+	}
+
+	stabilized := currentReplicas
+	if stabilized < upRecommendation {
+		stabilized = upRecommendation
+	}
+
+	if stabilized > downRecommendation {
+		stabilized = downRecommendation
+	}
+
+	return stabilized
+}
+
+// boundReplicas keeps the replicas between the min and max replicas of the autoscaler and sets the
+// ScalingLimited condition
+func boundReplicas(hpa *kubeapi_rest.HorizontalPodAutoscaler, replicas int, now time.Time) int {
+	minReplicas := 1
+	if hpa.Spec.MinReplicas != nil {
+		minReplicas = *hpa.Spec.MinReplicas
+	}
+
+	switch {
+	case replicas > hpa.Spec.MaxReplicas:
+		hpa.SetCondition(newCondition(kubeapi_rest.ScalingLimited, kubeapi_rest.ConditionTrue, "TooManyReplicas",
+			"the desired replica count is more than the maximum replica count", now))
+
+		return hpa.Spec.MaxReplicas
+	case replicas < minReplicas:
+		hpa.SetCondition(newCondition(kubeapi_rest.ScalingLimited, kubeapi_rest.ConditionTrue, "TooFewReplicas",
+			"the desired replica count is less than the minimum replica count", now))
+
+		return minReplicas
+	default:
+		hpa.SetCondition(newCondition(kubeapi_rest.ScalingLimited, kubeapi_rest.ConditionFalse, "DesiredWithinRange",
+			"the desired count is within the acceptable range", now))
+
+		return replicas
+	}
+}
+
+func (hpaController *Controller) updateStatusIfChanged(sharedHPA *kubeapi_rest.HorizontalPodAutoscaler, hpa *kubeapi_rest.HorizontalPodAutoscaler) error {
+	if reflect.DeepEqual(sharedHPA.Status, hpa.Status) {
+		return nil
+	}
+
+	if err := updateHorizontalPodAutoscalerStatus(hpaController.kubeAPIEndpoint, hpa); err != nil {
+		return fmt.Errorf("error updating status of horizontalpodautoscaler %s: %v", controller.MetaKey(hpa.Metadata), err)
+	}
+
+	return nil
+}
+
+func newCondition(conditionType string, status string, reason string, message string, now time.Time) kubeapi_rest.HorizontalPodAutoscalerCondition {
+	return kubeapi_rest.HorizontalPodAutoscalerCondition{
+		Type:               conditionType,
+		Status:             status,
+		LastTransitionTime: now.Format(time.RFC3339),
+		Reason:             reason,
+		Message:            message,
+	}
+}
+
+func hpaReference(hpa *kubeapi_rest.HorizontalPodAutoscaler) kubeapi_rest.ObjectReference {
+	return kubeapi_rest.ObjectReference{
+		Kind:      controllerKind,
+		Namespace: hpa.Metadata.Namespace,
+		Name:      hpa.Metadata.Name,
+		UID:       hpa.Metadata.UID,
+	}
+}
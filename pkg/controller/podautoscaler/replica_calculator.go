@@ -0,0 +1,227 @@
+package podautoscaler
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jonatan5524/own-kubernetes/pkg/controller"
+	kubeapi_rest "github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
+	"github.com/jonatan5524/own-kubernetes/pkg/utils"
+)
+
+// maxMetricsAge is how old the metrics of a pod may be before the pod is treated as having no metrics, the
+// kubelet of a pod reports its metrics every 15 seconds
+const maxMetricsAge = time.Minute
+
+// podUsage is the usage of a resource by a pod and the sum of the requests of its containers for the resource
+type podUsage struct {
+	usage   float64
+	request float64
+}
+
+// calculateReplicas returns the replicas for the resource metric and the current value of the metric. Pods
+// without metrics are counted as using the target when scaling down and nothing when scaling up, not ready pods
+// are counted as using nothing when scaling up, so neither makes the autoscaler scale further than the ready
+// pods with metrics ask for
+func (hpaController *Controller) calculateReplicas(
+	metric kubeapi_rest.MetricSpec,
+	pods []*kubeapi_rest.Pod,
+	currentReplicas int,
+	now time.Time,
+) (int, kubeapi_rest.MetricStatus, error) {
+	resource := metric.Resource.Name
+	target := metric.Resource.Target
+	status := kubeapi_rest.MetricStatus{
+		Type:     kubeapi_rest.ResourceMetricSourceType,
+		Resource: &kubeapi_rest.ResourceMetricStatus{Name: resource},
+	}
+
+	var targetValue float64
+	if target.Type == kubeapi_rest.AverageValueMetricType {
+		var err error
+
+		targetValue, err = utils.ParseQuantity(target.AverageValue)
+		if err != nil {
+			return 0, status, fmt.Errorf("invalid target average value: %v", err)
+		}
+	}
+
+	var ready, missing, unready []podUsage
+
+	for _, pod := range pods {
+		request := 0.0
+
+		if target.Type == kubeapi_rest.UtilizationMetricType {
+			var err error
+
+			request, err = podRequest(pod, resource)
+			if err != nil {
+				return 0, status, err
+			}
+		}
+
+		if !pod.IsReady() {
+			unready = append(unready, podUsage{request: request})
+
+			continue
+		}
+
+		usage, ok := hpaController.podUsage(pod, resource, now)
+		if !ok {
+			missing = append(missing, podUsage{request: request})
+
+			continue
+		}
+
+		ready = append(ready, podUsage{usage: usage, request: request})
+	}
+
+	if len(ready) == 0 {
+		return 0, status, fmt.Errorf("did not receive metrics for any ready pods")
+	}
+
+	usageRatio := func(pods []podUsage) (float64, error) {
+		usage, request := 0.0, 0.0
+		for _, pod := range pods {
+			usage += pod.usage
+			request += pod.request
+		}
+
+		if target.Type == kubeapi_rest.AverageValueMetricType {
+			return usage / float64(len(pods)) / targetValue, nil
+		}
+
+		if request == 0 {
+			return 0, fmt.Errorf("no %s requests for the pods", resource)
+		}
+
+		return usage / request * 100 / float64(*target.AverageUtilization), nil
+	}
+
+	ratio, err := usageRatio(ready)
+	if err != nil {
+		return 0, status, err
+	}
+
+	status.Resource.Current = currentMetricValue(resource, target, ready)
+
+	if len(missing) == 0 && len(unready) == 0 {
+		if math.Abs(ratio-1) <= hpaController.options.Tolerance {
+			return currentReplicas, status, nil
+		}
+
+		return int(math.Ceil(ratio * float64(len(ready)))), status, nil
+	}
+
+	rebalanced := append([]podUsage{}, ready...)
+
+	if ratio < 1 {
+		for _, pod := range missing {
+			if target.Type == kubeapi_rest.AverageValueMetricType {
+				pod.usage = targetValue
+			} else {
+				pod.usage = pod.request * float64(*target.AverageUtilization) / 100
+			}
+
+			rebalanced = append(rebalanced, pod)
+		}
+	} else {
+		rebalanced = append(rebalanced, missing...)
+		rebalanced = append(rebalanced, unready...)
+	}
+
+	newRatio, err := usageRatio(rebalanced)
+	if err != nil {
+		return 0, status, err
+	}
+
+	// the pods without metrics may change the direction of the scaling, then the replicas stay as they are
+	if math.Abs(newRatio-1) <= hpaController.options.Tolerance || (ratio < 1 && newRatio > 1) || (ratio > 1 && newRatio < 1) {
+		return currentReplicas, status, nil
+	}
+
+	replicas := int(math.Ceil(newRatio * float64(len(rebalanced))))
+	if (newRatio < 1 && replicas > currentReplicas) || (newRatio > 1 && replicas < currentReplicas) {
+		return currentReplicas, status, nil
+	}
+
+	return replicas, status, nil
+}
+
+// podUsage returns the usage of the resource by the containers of the pod, false when the pod has no fresh
+// metrics for all of its containers
+func (hpaController *Controller) podUsage(pod *kubeapi_rest.Pod, resource string, now time.Time) (float64, bool) {
+	podMetrics, ok := hpaController.podMetricsInformer.Get(controller.MetaKey(pod.Metadata))
+	if !ok || len(podMetrics.Containers) < len(pod.Spec.Containers) {
+		return 0, false
+	}
+
+	timestamp, err := time.Parse(time.RFC3339, podMetrics.Timestamp)
+	if err != nil || now.Sub(timestamp) > maxMetricsAge {
+		return 0, false
+	}
+
+	usage := 0.0
+	for _, container := range podMetrics.Containers {
+		value, err := utils.ParseQuantity(container.Usage[resource])
+		if err != nil {
+			return 0, false
+		}
+
+		usage += value
+	}
+
+	return usage, true
+}
+
+// podRequest returns the sum of the requests of the containers of the pod for the resource, utilization is
+// relative to the requests so every container must request the resource
+func podRequest(pod *kubeapi_rest.Pod, resource string) (float64, error) {
+	request := 0.0
+
+	for _, container := range pod.Spec.Containers {
+		quantity, ok := container.Resources.Requests[resource]
+		if !ok {
+			return 0, fmt.Errorf("missing request for %s in container %s of pod %s", resource, container.Name, pod.Metadata.Name)
+		}
+
+		value, err := utils.ParseQuantity(quantity)
+		if err != nil {
+			return 0, fmt.Errorf("invalid request for %s in container %s of pod %s: %v", resource, container.Name, pod.Metadata.Name, err)
+		}
+
+		request += value
+	}
+
+	return request, nil
+}
+
+// currentMetricValue returns the average usage of the ready pods, and their utilization for a utilization target
+func currentMetricValue(resource string, target kubeapi_rest.MetricTarget, pods []podUsage) kubeapi_rest.MetricValueStatus {
+	usage, request := 0.0, 0.0
+	for _, pod := range pods {
+		usage += pod.usage
+		request += pod.request
+	}
+
+	current := kubeapi_rest.MetricValueStatus{
+		AverageValue: formatQuantity(resource, usage/float64(len(pods))),
+	}
+
+	if target.Type == kubeapi_rest.UtilizationMetricType && request > 0 {
+		utilization := int(math.Round(usage / request * 100))
+		current.AverageUtilization = &utilization
+	}
+
+	return current
+}
+
+// formatQuantity formats cpu in millicores and memory in kibibytes, the units the kubelets report
+func formatQuantity(resource string, value float64) string {
+	if resource == kubeapi_rest.ResourceCPU {
+		return fmt.Sprintf("%dm", int64(math.Round(value*1000)))
+	}
+
+	return fmt.Sprintf("%dKi", int64(math.Round(value/1024)))
+}
@@ -0,0 +1,17 @@
+package replicaset
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/jonatan5524/own-kubernetes/pkg/controller"
+	kubeapi_rest "github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
+)
+
+func updateReplicaSetStatus(kubeAPIEndpoint string, replicaSet *kubeapi_rest.ReplicaSet, status kubeapi_rest.ReplicaSetStatus) error {
+	return controller.SendRequest(
+		http.MethodPatch,
+		fmt.Sprintf("%s/namespaces/%s/replicasets/%s/status", kubeAPIEndpoint, replicaSet.Metadata.Namespace, replicaSet.Metadata.Name),
+		status,
+	)
+}
@@ -0,0 +1,302 @@
+package replicaset
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jonatan5524/own-kubernetes/pkg/controller"
+	kubeapi_rest "github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
+	"github.com/jonatan5524/own-kubernetes/pkg/record"
+)
+
+const (
+	DefaultConcurrentSyncs = 5
+
+	// burstReplicas bounds the pods created or deleted in a single sync of a replicaset
+	burstReplicas = 500
+
+	controllerKind = "ReplicaSet"
+)
+
+type Options struct {
+	// ConcurrentSyncs is the number of replicasets synced in parallel
+	ConcurrentSyncs int
+}
+
+// Controller keeps the number of active pods of every replicaset at its replicas, it creates the missing pods from
+// the template and deletes the extra pods. The pods of a replicaset are the pods it is the controller of
+type Controller struct {
+	kubeAPIEndpoint string
+	options         Options
+	eventRecorder   record.EventRecorder
+
+	replicaSetInformer *controller.Informer[kubeapi_rest.ReplicaSet]
+	podInformer        *controller.Informer[kubeapi_rest.Pod]
+
+	queue        *controller.RateLimitingQueue
+	expectations *controller.ControllerExpectations
+}
+
+func NewController(ctx controller.ControllerContext, options Options) *Controller {
+	replicaSetController := &Controller{
+		kubeAPIEndpoint:    ctx.KubeAPIEndpoint,
+		options:            options,
+		eventRecorder:      ctx.EventRecorder("replicaset-controller"),
+		replicaSetInformer: ctx.InformerFactory.ReplicaSets(),
+		podInformer:        ctx.InformerFactory.Pods(),
+		queue:              controller.NewRateLimitingQueue(),
+		expectations:       controller.NewControllerExpectations(),
+	}
+
+	replicaSetController.replicaSetInformer.AddEventHandler(controller.ResourceEventHandler[kubeapi_rest.ReplicaSet]{
+		OnAdd: replicaSetController.enqueueReplicaSet,
+		OnUpdate: func(_ *kubeapi_rest.ReplicaSet, newReplicaSet *kubeapi_rest.ReplicaSet) {
+			replicaSetController.enqueueReplicaSet(newReplicaSet)
+		},
+		OnDelete: func(replicaSet *kubeapi_rest.ReplicaSet) {
+			replicaSetController.expectations.DeleteExpectations(controller.MetaKey(replicaSet.Metadata))
+			replicaSetController.enqueueReplicaSet(replicaSet)
+		},
+	})
+
+	replicaSetController.podInformer.AddEventHandler(controller.ResourceEventHandler[kubeapi_rest.Pod]{
+		OnAdd:    replicaSetController.addPod,
+		OnUpdate: replicaSetController.updatePod,
+		OnDelete: replicaSetController.deletePod,
+	})
+
+	return replicaSetController
+}
+
+func (replicaSetController *Controller) Run(stopCh <-chan struct{}) {
+	controller.RunWorkers("replicaset", replicaSetController.queue, replicaSetController.options.ConcurrentSyncs,
+		replicaSetController.syncReplicaSet, stopCh)
+}
+
+func (replicaSetController *Controller) enqueueReplicaSet(replicaSet *kubeapi_rest.ReplicaSet) {
+	replicaSetController.queue.Add(controller.MetaKey(replicaSet.Metadata))
+}
+
+// replicaSetKeyOfPod returns the key of the replicaset controlling the pod, empty when a replicaset does not
+// control it
+func replicaSetKeyOfPod(pod *kubeapi_rest.Pod) string {
+	controllerRef := pod.Metadata.ControllerRef()
+	if controllerRef == nil || controllerRef.Kind != controllerKind {
+		return ""
+	}
+
+	return controller.MetaKey(kubeapi_rest.ResourceMetadata{Namespace: pod.Metadata.Namespace, Name: controllerRef.Name})
+}
+
+func (replicaSetController *Controller) addPod(pod *kubeapi_rest.Pod) {
+	key := replicaSetKeyOfPod(pod)
+	if key == "" {
+		return
+	}
+
+	if pod.IsActive() {
+		replicaSetController.expectations.CreationObserved(key)
+	} else {
+		replicaSetController.expectations.DeletionObserved(key, controller.MetaKey(pod.Metadata))
+	}
+
+	replicaSetController.queue.Add(key)
+}
+
+func (replicaSetController *Controller) updatePod(oldPod *kubeapi_rest.Pod, newPod *kubeapi_rest.Pod) {
+	key := replicaSetKeyOfPod(newPod)
+	if key == "" {
+		return
+	}
+
+	if oldPod.IsActive() && !newPod.IsActive() {
+		replicaSetController.expectations.DeletionObserved(key, controller.MetaKey(newPod.Metadata))
+	}
+
+	replicaSetController.queue.Add(key)
+}
+
+func (replicaSetController *Controller) deletePod(pod *kubeapi_rest.Pod) {
+	key := replicaSetKeyOfPod(pod)
+	if key == "" {
+		return
+	}
+
+	replicaSetController.expectations.DeletionObserved(key, controller.MetaKey(pod.Metadata))
+	replicaSetController.queue.Add(key)
+}
+
+func (replicaSetController *Controller) syncReplicaSet(key string) error {
+	namespace, name := controller.SplitMetaKey(key)
+	pods := replicaSetController.listReplicaSetPods(namespace, name)
+
+	replicaSet, ok := replicaSetController.replicaSetInformer.Get(key)
+	if !ok {
+		// the pods of a deleted replicaset are deleted with it
+		return replicaSetController.deleteOrphanPods(pods)
+	}
+
+	var activePods []*kubeapi_rest.Pod
+	var orphanPods []*kubeapi_rest.Pod
+
+	for _, pod := range pods {
+		if pod.Metadata.ControllerRef().UID != replicaSet.Metadata.UID {
+			// a pod of a previous replicaset with the same name
+			orphanPods = append(orphanPods, pod)
+		} else if pod.IsActive() {
+			activePods = append(activePods, pod)
+		}
+	}
+
+	if err := replicaSetController.deleteOrphanPods(orphanPods); err != nil {
+		return err
+	}
+
+	var manageErr error
+	if replicaSetController.expectations.SatisfiedExpectations(key) {
+		manageErr = replicaSetController.manageReplicas(key, replicaSet, activePods)
+	}
+
+	status := calculateStatus(replicaSet, activePods, time.Now())
+	if status != replicaSet.Status {
+		if err := updateReplicaSetStatus(replicaSetController.kubeAPIEndpoint, replicaSet, status); err != nil {
+			return fmt.Errorf("error updating status of replicaset %s: %v", key, err)
+		}
+	}
+
+	// a ready pod becomes available after min ready seconds without an event, the replicaset is synced again then
+	if replicaSet.Spec.MinReadySeconds > 0 && status.ReadyReplicas != status.AvailableReplicas {
+		replicaSetController.queue.AddAfter(key, time.Duration(replicaSet.Spec.MinReadySeconds)*time.Second)
+	}
+
+	return manageErr
+}
+
+// manageReplicas creates or deletes pods until the replicaset has its replicas, at most burstReplicas at a time
+func (replicaSetController *Controller) manageReplicas(key string, replicaSet *kubeapi_rest.ReplicaSet, activePods []*kubeapi_rest.Pod) error {
+	diff := len(activePods) - *replicaSet.Spec.Replicas
+
+	if diff < 0 {
+		creations := min(-diff, burstReplicas)
+		replicaSetController.expectations.ExpectCreations(key, creations)
+
+		controllerRef := controller.NewControllerRef(controllerKind, replicaSet.Metadata)
+
+		for created := 0; created < creations; created++ {
+			pod := controller.NewPodFromTemplate(replicaSet.Spec.Template, replicaSet.Metadata.Namespace, controllerRef)
+
+			if err := controller.CreatePod(replicaSetController.kubeAPIEndpoint, pod); err != nil {
+				// the creations that did not happen will not be observed, stop at the first error so a broken
+				// template does not flood the api
+				for skipped := created; skipped < creations; skipped++ {
+					replicaSetController.expectations.CreationObserved(key)
+				}
+
+				replicaSetController.eventRecorder.Eventf(replicaSetReference(replicaSet), kubeapi_rest.EventTypeWarning,
+					"FailedCreate", "Error creating: %v", err)
+
+				return fmt.Errorf("error creating pod for replicaset %s: %v", key, err)
+			}
+
+			replicaSetController.eventRecorder.Eventf(replicaSetReference(replicaSet), kubeapi_rest.EventTypeNormal,
+				"SuccessfulCreate", "Created pod: %s", pod.Metadata.Name)
+		}
+
+		return nil
+	}
+
+	if diff > 0 {
+		podsToDelete := make([]*kubeapi_rest.Pod, len(activePods))
+		copy(podsToDelete, activePods)
+
+		controller.SortPodsForDeletion(podsToDelete)
+		podsToDelete = podsToDelete[:min(diff, burstReplicas)]
+
+		podKeys := make([]string, 0, len(podsToDelete))
+		for _, pod := range podsToDelete {
+			podKeys = append(podKeys, controller.MetaKey(pod.Metadata))
+		}
+
+		replicaSetController.expectations.ExpectDeletions(key, podKeys)
+
+		var deleteErr error
+
+		for _, pod := range podsToDelete {
+			if err := controller.DeletePod(replicaSetController.kubeAPIEndpoint, pod, pod.Spec.NodeName == ""); err != nil {
+				replicaSetController.expectations.DeletionObserved(key, controller.MetaKey(pod.Metadata))
+				replicaSetController.eventRecorder.Eventf(replicaSetReference(replicaSet), kubeapi_rest.EventTypeWarning,
+					"FailedDelete", "Error deleting: %v", err)
+
+				deleteErr = fmt.Errorf("error deleting pod %s of replicaset %s: %v", pod.Metadata.Name, key, err)
+
+				continue
+			}
+
+			replicaSetController.eventRecorder.Eventf(replicaSetReference(replicaSet), kubeapi_rest.EventTypeNormal,
+				"SuccessfulDelete", "Deleted pod: %s", pod.Metadata.Name)
+		}
+
+		return deleteErr
+	}
+
+	return nil
+}
+
+func (replicaSetController *Controller) deleteOrphanPods(pods []*kubeapi_rest.Pod) error {
+	for _, pod := range pods {
+		if pod.Status.Phase == kubeapi_rest.PodTerminatingPhase && pod.Spec.NodeName != "" {
+			// its kubelet is already stopping it
+			continue
+		}
+
+		log.Printf("deleting pod %s/%s, its replicaset was deleted", pod.Metadata.Namespace, pod.Metadata.Name)
+
+		if err := controller.DeletePod(replicaSetController.kubeAPIEndpoint, pod, pod.Spec.NodeName == ""); err != nil {
+			return fmt.Errorf("error deleting pod %s/%s: %v", pod.Metadata.Namespace, pod.Metadata.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// listReplicaSetPods returns the pods controlled by a replicaset of the name, including the pods of a previous
+// replicaset with the same name
+func (replicaSetController *Controller) listReplicaSetPods(namespace string, name string) []*kubeapi_rest.Pod {
+	var pods []*kubeapi_rest.Pod
+
+	for _, pod := range replicaSetController.podInformer.List() {
+		if pod.Metadata.Namespace == namespace && controller.IsControlledBy(pod.Metadata, controllerKind, name) {
+			pods = append(pods, pod)
+		}
+	}
+
+	return pods
+}
+
+func calculateStatus(replicaSet *kubeapi_rest.ReplicaSet, activePods []*kubeapi_rest.Pod, now time.Time) kubeapi_rest.ReplicaSetStatus {
+	status := kubeapi_rest.ReplicaSetStatus{
+		Replicas: len(activePods),
+	}
+
+	for _, pod := range activePods {
+		if pod.IsReady() {
+			status.ReadyReplicas++
+		}
+
+		if pod.IsAvailable(replicaSet.Spec.MinReadySeconds, now) {
+			status.AvailableReplicas++
+		}
+	}
+
+	return status
+}
+
+func replicaSetReference(replicaSet *kubeapi_rest.ReplicaSet) kubeapi_rest.ObjectReference {
+	return kubeapi_rest.ObjectReference{
+		Kind:      controllerKind,
+		Namespace: replicaSet.Metadata.Namespace,
+		Name:      replicaSet.Metadata.Name,
+		UID:       replicaSet.Metadata.UID,
+	}
+}
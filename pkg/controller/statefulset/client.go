@@ -0,0 +1,17 @@
+package statefulset
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/jonatan5524/own-kubernetes/pkg/controller"
+	kubeapi_rest "github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
+)
+
+func updateStatefulSetStatus(kubeAPIEndpoint string, statefulSet *kubeapi_rest.StatefulSet, status kubeapi_rest.StatefulSetStatus) error {
+	return controller.SendRequest(
+		http.MethodPatch,
+		fmt.Sprintf("%s/namespaces/%s/statefulsets/%s/status", kubeAPIEndpoint, statefulSet.Metadata.Namespace, statefulSet.Metadata.Name),
+		status,
+	)
+}
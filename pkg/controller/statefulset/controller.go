@@ -0,0 +1,447 @@
+package statefulset
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jonatan5524/own-kubernetes/pkg/controller"
+	kubeapi_rest "github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
+	"github.com/jonatan5524/own-kubernetes/pkg/record"
+)
+
+const (
+	DefaultConcurrentSyncs = 5
+
+	controllerKind = "StatefulSet"
+)
+
+type Options struct {
+	// ConcurrentSyncs is the number of statefulsets synced in parallel
+	ConcurrentSyncs int
+}
+
+// Controller runs the pods of every statefulset with stable identities, the pod of ordinal i is named <name>-i,
+// its hostname is its name and its subdomain is the service of the statefulset. With the OrderedReady policy a
+// pod is created only once the pods of the lower ordinals are running and ready and the pods are removed from
+// the highest ordinal, with the Parallel policy they are all created and removed at once
+type Controller struct {
+	kubeAPIEndpoint string
+	options         Options
+	eventRecorder   record.EventRecorder
+
+	statefulSetInformer *controller.Informer[kubeapi_rest.StatefulSet]
+	podInformer         *controller.Informer[kubeapi_rest.Pod]
+
+	queue        *controller.RateLimitingQueue
+	expectations *controller.ControllerExpectations
+}
+
+func NewController(ctx controller.ControllerContext, options Options) *Controller {
+	statefulSetController := &Controller{
+		kubeAPIEndpoint:     ctx.KubeAPIEndpoint,
+		options:             options,
+		eventRecorder:       ctx.EventRecorder("statefulset-controller"),
+		statefulSetInformer: ctx.InformerFactory.StatefulSets(),
+		podInformer:         ctx.InformerFactory.Pods(),
+		queue:               controller.NewRateLimitingQueue(),
+		expectations:        controller.NewControllerExpectations(),
+	}
+
+	statefulSetController.statefulSetInformer.AddEventHandler(controller.ResourceEventHandler[kubeapi_rest.StatefulSet]{
+		OnAdd: statefulSetController.enqueueStatefulSet,
+		OnUpdate: func(_ *kubeapi_rest.StatefulSet, newStatefulSet *kubeapi_rest.StatefulSet) {
+			statefulSetController.enqueueStatefulSet(newStatefulSet)
+		},
+		OnDelete: func(statefulSet *kubeapi_rest.StatefulSet) {
+			statefulSetController.expectations.DeleteExpectations(controller.MetaKey(statefulSet.Metadata))
+			statefulSetController.enqueueStatefulSet(statefulSet)
+		},
+	})
+
+	statefulSetController.podInformer.AddEventHandler(controller.ResourceEventHandler[kubeapi_rest.Pod]{
+		OnAdd:    statefulSetController.addPod,
+		OnUpdate: statefulSetController.updatePod,
+		OnDelete: statefulSetController.deletePod,
+	})
+
+	return statefulSetController
+}
+
+func (statefulSetController *Controller) Run(stopCh <-chan struct{}) {
+	controller.RunWorkers("statefulset", statefulSetController.queue, statefulSetController.options.ConcurrentSyncs,
+		statefulSetController.syncStatefulSet, stopCh)
+}
+
+func (statefulSetController *Controller) enqueueStatefulSet(statefulSet *kubeapi_rest.StatefulSet) {
+	statefulSetController.queue.Add(controller.MetaKey(statefulSet.Metadata))
+}
+
+// statefulSetKeyOfPod returns the key of the statefulset controlling the pod, empty when a statefulset does not
+// control it
+func statefulSetKeyOfPod(pod *kubeapi_rest.Pod) string {
+	controllerRef := pod.Metadata.ControllerRef()
+	if controllerRef == nil || controllerRef.Kind != controllerKind {
+		return ""
+	}
+
+	return controller.MetaKey(kubeapi_rest.ResourceMetadata{Namespace: pod.Metadata.Namespace, Name: controllerRef.Name})
+}
+
+func (statefulSetController *Controller) addPod(pod *kubeapi_rest.Pod) {
+	key := statefulSetKeyOfPod(pod)
+	if key == "" {
+		return
+	}
+
+	if pod.IsActive() {
+		statefulSetController.expectations.CreationObserved(key)
+	} else {
+		statefulSetController.expectations.DeletionObserved(key, controller.MetaKey(pod.Metadata))
+	}
+
+	statefulSetController.queue.Add(key)
+}
+
+func (statefulSetController *Controller) updatePod(oldPod *kubeapi_rest.Pod, newPod *kubeapi_rest.Pod) {
+	key := statefulSetKeyOfPod(newPod)
+	if key == "" {
+		return
+	}
+
+	if oldPod.IsActive() && !newPod.IsActive() {
+		statefulSetController.expectations.DeletionObserved(key, controller.MetaKey(newPod.Metadata))
+	}
+
+	statefulSetController.queue.Add(key)
+}
+
+func (statefulSetController *Controller) deletePod(pod *kubeapi_rest.Pod) {
+	key := statefulSetKeyOfPod(pod)
+	if key == "" {
+		return
+	}
+
+	statefulSetController.expectations.DeletionObserved(key, controller.MetaKey(pod.Metadata))
+	statefulSetController.queue.Add(key)
+}
+
+func (statefulSetController *Controller) syncStatefulSet(key string) error {
+	namespace, name := controller.SplitMetaKey(key)
+	pods := statefulSetController.listStatefulSetPods(namespace, name)
+
+	statefulSet, ok := statefulSetController.statefulSetInformer.Get(key)
+	if !ok {
+		// the pods of a deleted statefulset are deleted with it
+		return statefulSetController.deleteOrphanPods(pods)
+	}
+
+	var orphanPods []*kubeapi_rest.Pod
+	var ownedPods []*kubeapi_rest.Pod
+
+	for _, pod := range pods {
+		if pod.Metadata.ControllerRef().UID != statefulSet.Metadata.UID {
+			// a pod of a previous statefulset with the same name, it holds the name of a pod of this one
+			orphanPods = append(orphanPods, pod)
+		} else {
+			ownedPods = append(ownedPods, pod)
+		}
+	}
+
+	if len(orphanPods) > 0 {
+		// a pod is created with a fixed name, so the pods of this statefulset wait until the orphans are gone,
+		// their deletion syncs the statefulset again
+		return statefulSetController.deleteOrphanPods(orphanPods)
+	}
+
+	updateRevision := controller.ComputeHash(statefulSet.Spec.Template)
+
+	var manageErr error
+	if statefulSetController.expectations.SatisfiedExpectations(key) {
+		manageErr = statefulSetController.manage(key, statefulSet, ownedPods, updateRevision)
+	}
+
+	status := calculateStatus(statefulSet, ownedPods, updateRevision)
+	if status != statefulSet.Status {
+		if err := updateStatefulSetStatus(statefulSetController.kubeAPIEndpoint, statefulSet, status); err != nil {
+			return fmt.Errorf("error updating status of statefulset %s: %v", key, err)
+		}
+	}
+
+	return manageErr
+}
+
+// manage creates the missing pods, replaces the failed and finished pods, deletes the pods of ordinals beyond
+// the replicas and replaces the pods of an older template during a rolling update. With the OrderedReady policy
+// only the first of these actions is taken and nothing is done while a pod of a lower ordinal is not running
+// and ready
+func (statefulSetController *Controller) manage(
+	key string,
+	statefulSet *kubeapi_rest.StatefulSet,
+	pods []*kubeapi_rest.Pod,
+	updateRevision string,
+) error {
+	monotonic := statefulSet.Spec.PodManagementPolicy == kubeapi_rest.OrderedReadyPodManagement
+
+	replicas := make([]*kubeapi_rest.Pod, *statefulSet.Spec.Replicas)
+	var condemned []*kubeapi_rest.Pod
+
+	for _, pod := range pods {
+		ordinal := getOrdinal(statefulSet, pod)
+		if ordinal >= 0 && ordinal < len(replicas) {
+			replicas[ordinal] = pod
+		} else {
+			condemned = append(condemned, pod)
+		}
+	}
+
+	// the pods beyond the replicas are removed from the highest ordinal
+	sort.SliceStable(condemned, func(i, j int) bool {
+		return getOrdinal(statefulSet, condemned[i]) > getOrdinal(statefulSet, condemned[j])
+	})
+
+	var ordinalsToCreate []int
+	var podsToDelete []*kubeapi_rest.Pod
+
+	allHealthy := true
+
+	for ordinal, pod := range replicas {
+		switch {
+		case pod == nil:
+			ordinalsToCreate = append(ordinalsToCreate, ordinal)
+		case pod.Status.Phase == kubeapi_rest.PodFailedPhase || pod.Status.Phase == kubeapi_rest.PodSucceededPhase:
+			// the pod is created again with the same name once it is deleted
+			podsToDelete = append(podsToDelete, pod)
+		case !pod.IsReady():
+			// pending or terminating, waiting for it
+		default:
+			continue
+		}
+
+		allHealthy = false
+
+		if monotonic {
+			break
+		}
+	}
+
+	if allHealthy || !monotonic {
+		for _, pod := range condemned {
+			if pod.Status.Phase == kubeapi_rest.PodTerminatingPhase {
+				if monotonic {
+					// the next pod is removed once this one is gone
+					break
+				}
+
+				continue
+			}
+
+			podsToDelete = append(podsToDelete, pod)
+
+			if monotonic {
+				break
+			}
+		}
+	}
+
+	if statefulSet.Spec.UpdateStrategy.Type == kubeapi_rest.RollingUpdateStatefulSetStrategyType &&
+		allHealthy && len(condemned) == 0 {
+		if pod := podToUpdate(statefulSet, replicas, updateRevision); pod != nil {
+			podsToDelete = append(podsToDelete, pod)
+		}
+	}
+
+	return statefulSetController.syncPods(key, statefulSet, ordinalsToCreate, podsToDelete, updateRevision)
+}
+
+// podToUpdate returns the pod of the highest ordinal from the partition that is of an older template, the rolling
+// update replaces one pod at a time and the pod is created again from the current template once it is deleted
+func podToUpdate(statefulSet *kubeapi_rest.StatefulSet, replicas []*kubeapi_rest.Pod, updateRevision string) *kubeapi_rest.Pod {
+	partition := 0
+	if statefulSet.Spec.UpdateStrategy.RollingUpdate != nil {
+		partition = statefulSet.Spec.UpdateStrategy.RollingUpdate.Partition
+	}
+
+	for ordinal := len(replicas) - 1; ordinal >= partition; ordinal-- {
+		if replicas[ordinal].Metadata.Labels[kubeapi_rest.ControllerRevisionHashLabelKey] != updateRevision {
+			return replicas[ordinal]
+		}
+	}
+
+	return nil
+}
+
+// syncPods creates the pods of the ordinals and deletes the pods, it stops creating at the first error so the
+// pods of the higher ordinals are not created before a lower one
+func (statefulSetController *Controller) syncPods(
+	key string,
+	statefulSet *kubeapi_rest.StatefulSet,
+	ordinalsToCreate []int,
+	podsToDelete []*kubeapi_rest.Pod,
+	updateRevision string,
+) error {
+	podKeys := make([]string, 0, len(podsToDelete))
+	for _, pod := range podsToDelete {
+		podKeys = append(podKeys, controller.MetaKey(pod.Metadata))
+	}
+
+	statefulSetController.expectations.SetExpectations(key, len(ordinalsToCreate), podKeys)
+
+	var syncErr error
+
+	for index, ordinal := range ordinalsToCreate {
+		pod := newStatefulSetPod(statefulSet, ordinal, updateRevision)
+
+		if err := controller.CreatePod(statefulSetController.kubeAPIEndpoint, pod); err != nil {
+			// the creations that did not happen will not be observed
+			for skipped := index; skipped < len(ordinalsToCreate); skipped++ {
+				statefulSetController.expectations.CreationObserved(key)
+			}
+
+			statefulSetController.eventRecorder.Eventf(statefulSetReference(statefulSet), kubeapi_rest.EventTypeWarning,
+				"FailedCreate", "create Pod %s in StatefulSet %s failed error: %v", pod.Metadata.Name, statefulSet.Metadata.Name, err)
+
+			syncErr = fmt.Errorf("error creating pod %s of statefulset %s: %v", pod.Metadata.Name, key, err)
+
+			break
+		}
+
+		statefulSetController.eventRecorder.Eventf(statefulSetReference(statefulSet), kubeapi_rest.EventTypeNormal,
+			"SuccessfulCreate", "create Pod %s in StatefulSet %s successful", pod.Metadata.Name, statefulSet.Metadata.Name)
+	}
+
+	for _, pod := range podsToDelete {
+		if err := controller.DeletePod(statefulSetController.kubeAPIEndpoint, pod, pod.Spec.NodeName == ""); err != nil {
+			statefulSetController.expectations.DeletionObserved(key, controller.MetaKey(pod.Metadata))
+			statefulSetController.eventRecorder.Eventf(statefulSetReference(statefulSet), kubeapi_rest.EventTypeWarning,
+				"FailedDelete", "delete Pod %s in StatefulSet %s failed error: %v", pod.Metadata.Name, statefulSet.Metadata.Name, err)
+
+			syncErr = fmt.Errorf("error deleting pod %s of statefulset %s: %v", pod.Metadata.Name, key, err)
+
+			continue
+		}
+
+		statefulSetController.eventRecorder.Eventf(statefulSetReference(statefulSet), kubeapi_rest.EventTypeNormal,
+			"SuccessfulDelete", "delete Pod %s in StatefulSet %s successful", pod.Metadata.Name, statefulSet.Metadata.Name)
+	}
+
+	return syncErr
+}
+
+func (statefulSetController *Controller) deleteOrphanPods(pods []*kubeapi_rest.Pod) error {
+	for _, pod := range pods {
+		if pod.Status.Phase == kubeapi_rest.PodTerminatingPhase && pod.Spec.NodeName != "" {
+			// its kubelet is already stopping it
+			continue
+		}
+
+		log.Printf("deleting pod %s/%s, its statefulset was deleted", pod.Metadata.Namespace, pod.Metadata.Name)
+
+		if err := controller.DeletePod(statefulSetController.kubeAPIEndpoint, pod, pod.Spec.NodeName == ""); err != nil {
+			return fmt.Errorf("error deleting pod %s/%s: %v", pod.Metadata.Namespace, pod.Metadata.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// listStatefulSetPods returns the pods controlled by a statefulset of the name, including the pods of a previous
+// statefulset with the same name
+func (statefulSetController *Controller) listStatefulSetPods(namespace string, name string) []*kubeapi_rest.Pod {
+	var pods []*kubeapi_rest.Pod
+
+	for _, pod := range statefulSetController.podInformer.List() {
+		if pod.Metadata.Namespace == namespace && controller.IsControlledBy(pod.Metadata, controllerKind, name) {
+			pods = append(pods, pod)
+		}
+	}
+
+	return pods
+}
+
+// getOrdinal returns the ordinal of the pod from its name, -1 when the name is not of a pod of the statefulset
+func getOrdinal(statefulSet *kubeapi_rest.StatefulSet, pod *kubeapi_rest.Pod) int {
+	suffix, ok := strings.CutPrefix(pod.Metadata.Name, statefulSet.Metadata.Name+"-")
+	if !ok {
+		return -1
+	}
+
+	ordinal, err := strconv.Atoi(suffix)
+	if err != nil || ordinal < 0 || strconv.Itoa(ordinal) != suffix {
+		return -1
+	}
+
+	return ordinal
+}
+
+// newStatefulSetPod returns the pod of the ordinal, its name and hostname are <name>-<ordinal> and its subdomain
+// is the service of the statefulset
+func newStatefulSetPod(statefulSet *kubeapi_rest.StatefulSet, ordinal int, revision string) *kubeapi_rest.Pod {
+	pod := controller.NewPodFromTemplate(statefulSet.Spec.Template, statefulSet.Metadata.Namespace,
+		controller.NewControllerRef(controllerKind, statefulSet.Metadata))
+
+	pod.Metadata.Name = fmt.Sprintf("%s-%d", statefulSet.Metadata.Name, ordinal)
+	pod.Metadata.Labels[kubeapi_rest.ControllerRevisionHashLabelKey] = revision
+	pod.Metadata.Labels[kubeapi_rest.StatefulSetPodNameLabelKey] = pod.Metadata.Name
+	pod.Spec.Hostname = pod.Metadata.Name
+	pod.Spec.Subdomain = statefulSet.Spec.ServiceName
+
+	return pod
+}
+
+// calculateStatus counts the active pods by revision, the current revision becomes the update revision once
+// all the replicas are of the update revision
+func calculateStatus(
+	statefulSet *kubeapi_rest.StatefulSet,
+	pods []*kubeapi_rest.Pod,
+	updateRevision string,
+) kubeapi_rest.StatefulSetStatus {
+	status := kubeapi_rest.StatefulSetStatus{
+		CurrentRevision: statefulSet.Status.CurrentRevision,
+		UpdateRevision:  updateRevision,
+	}
+
+	if status.CurrentRevision == "" {
+		status.CurrentRevision = updateRevision
+	}
+
+	for _, pod := range pods {
+		if !pod.IsActive() {
+			continue
+		}
+
+		status.Replicas++
+
+		if pod.IsReady() {
+			status.ReadyReplicas++
+		}
+
+		revision := pod.Metadata.Labels[kubeapi_rest.ControllerRevisionHashLabelKey]
+
+		if revision == status.CurrentRevision {
+			status.CurrentReplicas++
+		}
+
+		if revision == updateRevision {
+			status.UpdatedReplicas++
+		}
+	}
+
+	if status.UpdatedReplicas == *statefulSet.Spec.Replicas && status.Replicas == *statefulSet.Spec.Replicas {
+		status.CurrentRevision = updateRevision
+		status.CurrentReplicas = status.UpdatedReplicas
+	}
+
+	return status
+}
+
+func statefulSetReference(statefulSet *kubeapi_rest.StatefulSet) kubeapi_rest.ObjectReference {
+	return kubeapi_rest.ObjectReference{
+		Kind:      controllerKind,
+		Namespace: statefulSet.Metadata.Namespace,
+		Name:      statefulSet.Metadata.Name,
+		UID:       statefulSet.Metadata.UID,
+	}
+}
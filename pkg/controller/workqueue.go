@@ -0,0 +1,167 @@
+package controller
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jonatan5524/own-kubernetes/pkg/utils"
+)
+
+const (
+	// same defaults as the kubernetes controller rate limiter
+	defaultBaseRetryDelay = 5 * time.Millisecond
+	defaultMaxRetryDelay  = 1000 * time.Second
+	defaultQueueQPS       = 10
+	defaultQueueBurst     = 100
+)
+
+// RateLimitingQueue holds the keys of the objects a controller has to sync.
+// A key is in the queue at most once and is handed to one worker at a time, a key added while it is processed
+// is handed out again after Done
+type RateLimitingQueue struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	queue []string
+	// dirty are the keys waiting to be processed
+	dirty map[string]bool
+	// processing are the keys handed to a worker and not done yet
+	processing map[string]bool
+
+	failures       map[string]int
+	baseRetryDelay time.Duration
+	maxRetryDelay  time.Duration
+	// limiter bounds the retries of all the keys together
+	limiter *utils.TokenBucket
+
+	shuttingDown bool
+}
+
+func NewRateLimitingQueue() *RateLimitingQueue {
+	queue := &RateLimitingQueue{
+		dirty:          make(map[string]bool),
+		processing:     make(map[string]bool),
+		failures:       make(map[string]int),
+		baseRetryDelay: defaultBaseRetryDelay,
+		maxRetryDelay:  defaultMaxRetryDelay,
+		limiter:        utils.NewTokenBucket(defaultQueueQPS, defaultQueueBurst),
+	}
+	queue.cond = sync.NewCond(&queue.mu)
+
+	return queue
+}
+
+func (queue *RateLimitingQueue) Add(key string) {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	if queue.shuttingDown || queue.dirty[key] {
+		return
+	}
+
+	queue.dirty[key] = true
+
+	if queue.processing[key] {
+		return
+	}
+
+	queue.queue = append(queue.queue, key)
+	queue.cond.Signal()
+}
+
+// AddAfter adds the key once the delay passed
+func (queue *RateLimitingQueue) AddAfter(key string, delay time.Duration) {
+	if delay <= 0 {
+		queue.Add(key)
+
+		return
+	}
+
+	time.AfterFunc(delay, func() {
+		queue.Add(key)
+	})
+}
+
+// AddRateLimited adds the key after a delay that doubles on every failure of the key, and that is longer
+// when many keys are retried together
+func (queue *RateLimitingQueue) AddRateLimited(key string) {
+	queue.mu.Lock()
+	failures := queue.failures[key]
+	queue.failures[key] = failures + 1
+	queue.mu.Unlock()
+
+	delay := queue.maxRetryDelay
+	if failures < 30 {
+		delay = min(queue.baseRetryDelay*time.Duration(1<<failures), queue.maxRetryDelay)
+	}
+
+	queue.AddAfter(key, max(delay, queue.limiter.Reserve()))
+}
+
+// Forget resets the failures of the key, it is called once the key was synced
+func (queue *RateLimitingQueue) Forget(key string) {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	delete(queue.failures, key)
+}
+
+// NumRequeues returns how many times the key failed since it was last forgotten
+func (queue *RateLimitingQueue) NumRequeues(key string) int {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	return queue.failures[key]
+}
+
+// Get blocks until there is a key to process, shutdown is true when the queue was shut down.
+// Done must be called with the key when it is processed
+func (queue *RateLimitingQueue) Get() (key string, shutdown bool) {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	for len(queue.queue) == 0 && !queue.shuttingDown {
+		queue.cond.Wait()
+	}
+
+	if len(queue.queue) == 0 {
+		return "", true
+	}
+
+	key = queue.queue[0]
+	queue.queue = queue.queue[1:]
+
+	queue.processing[key] = true
+	delete(queue.dirty, key)
+
+	return key, false
+}
+
+// Done marks the key as processed, a key that was added while it was processed goes back to the queue
+func (queue *RateLimitingQueue) Done(key string) {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	delete(queue.processing, key)
+
+	if queue.dirty[key] {
+		queue.queue = append(queue.queue, key)
+		queue.cond.Signal()
+	}
+}
+
+func (queue *RateLimitingQueue) Len() int {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	return len(queue.queue)
+}
+
+// ShutDown stops the queue from taking new keys, the workers waiting in Get return
+func (queue *RateLimitingQueue) ShutDown() {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	queue.shuttingDown = true
+	queue.cond.Broadcast()
+}
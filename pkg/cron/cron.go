@@ -0,0 +1,242 @@
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// searchLimit is how far Next looks for a matching time, a schedule such as "0 0 30 2 *" never matches
+const searchLimit = 5 * 365 * 24 * time.Hour
+
+// Schedule is a parsed cron schedule of the standard five fields: minute, hour, day of month, month and day of week
+type Schedule struct {
+	minute     uint64
+	hour       uint64
+	dayOfMonth uint64
+	month      uint64
+	dayOfWeek  uint64
+
+	// when both day fields are restricted a day matching either of them matches, like in cron
+	dayOfMonthStar bool
+	dayOfWeekStar  bool
+}
+
+type field struct {
+	name  string
+	min   int
+	max   int
+	names map[string]int
+}
+
+var (
+	minuteField     = field{name: "minute", min: 0, max: 59}
+	hourField       = field{name: "hour", min: 0, max: 23}
+	dayOfMonthField = field{name: "day of month", min: 1, max: 31}
+	monthField      = field{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	dayOfWeekField = field{name: "day of week", min: 0, max: 6, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+
+	macros = map[string]string{
+		"@yearly":   "0 0 1 1 *",
+		"@annually": "0 0 1 1 *",
+		"@monthly":  "0 0 1 * *",
+		"@weekly":   "0 0 * * 0",
+		"@daily":    "0 0 * * *",
+		"@midnight": "0 0 * * *",
+		"@hourly":   "0 * * * *",
+	}
+)
+
+// Parse parses a schedule such as "*/5 * * * *", "0 3 * * mon-fri" or "@hourly". Every field is a comma separated
+// list of values, ranges and steps, a day of week of 7 is sunday like 0
+func Parse(spec string) (*Schedule, error) {
+	spec = strings.TrimSpace(spec)
+
+	if macro, ok := macros[spec]; ok {
+		spec = macro
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields, found %d: %q", len(fields), spec)
+	}
+
+	schedule := &Schedule{}
+
+	var err error
+
+	if schedule.minute, err = parseField(fields[0], minuteField); err != nil {
+		return nil, err
+	}
+
+	if schedule.hour, err = parseField(fields[1], hourField); err != nil {
+		return nil, err
+	}
+
+	if schedule.dayOfMonth, err = parseField(fields[2], dayOfMonthField); err != nil {
+		return nil, err
+	}
+
+	if schedule.month, err = parseField(fields[3], monthField); err != nil {
+		return nil, err
+	}
+
+	// 7 is an alias of sunday
+	dayOfWeek := dayOfWeekField
+	dayOfWeek.max = 7
+
+	if schedule.dayOfWeek, err = parseField(fields[4], dayOfWeek); err != nil {
+		return nil, err
+	}
+
+	if schedule.dayOfWeek&(1<<7) != 0 {
+		schedule.dayOfWeek |= 1
+	}
+
+	schedule.dayOfMonthStar = isStar(fields[2])
+	schedule.dayOfWeekStar = isStar(fields[4])
+
+	return schedule, nil
+}
+
+func isStar(value string) bool {
+	return value == "*" || value == "?"
+}
+
+// parseField returns the bits of the values of the field, bit n is set when the value n matches
+func parseField(value string, f field) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(value, ",") {
+		partBits, err := parsePart(part, f)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s %q: %v", f.name, value, err)
+		}
+
+		bits |= partBits
+	}
+
+	return bits, nil
+}
+
+func parsePart(part string, f field) (uint64, error) {
+	rangeAndStep := strings.Split(part, "/")
+	if len(rangeAndStep) > 2 {
+		return 0, fmt.Errorf("too many slashes in %q", part)
+	}
+
+	start, end := f.min, f.max
+
+	if !isStar(rangeAndStep[0]) {
+		bounds := strings.Split(rangeAndStep[0], "-")
+		if len(bounds) > 2 {
+			return 0, fmt.Errorf("too many hyphens in %q", part)
+		}
+
+		var err error
+		if start, err = parseValue(bounds[0], f); err != nil {
+			return 0, err
+		}
+
+		end = start
+		if len(bounds) == 2 {
+			if end, err = parseValue(bounds[1], f); err != nil {
+				return 0, err
+			}
+		} else if len(rangeAndStep) == 2 {
+			// "5/15" is from 5 to the end of the field every 15
+			end = f.max
+		}
+	}
+
+	step := 1
+	if len(rangeAndStep) == 2 {
+		var err error
+		if step, err = strconv.Atoi(rangeAndStep[1]); err != nil || step <= 0 {
+			return 0, fmt.Errorf("invalid step %q", rangeAndStep[1])
+		}
+	}
+
+	if start > end {
+		return 0, fmt.Errorf("range start %d is beyond its end %d", start, end)
+	}
+
+	var bits uint64
+	for current := start; current <= end; current += step {
+		bits |= 1 << uint(current)
+	}
+
+	return bits, nil
+}
+
+func parseValue(value string, f field) (int, error) {
+	if number, ok := f.names[strings.ToLower(value)]; ok {
+		return number, nil
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", value)
+	}
+
+	if number < f.min || number > f.max {
+		return 0, fmt.Errorf("value %d out of range [%d, %d]", number, f.min, f.max)
+	}
+
+	return number, nil
+}
+
+// Next returns the first time after t that matches the schedule, in the location of t. It returns the zero time
+// when nothing matches in the next five years
+func (schedule *Schedule) Next(t time.Time) time.Time {
+	// the schedule has a minute resolution, the next time is at least the next minute
+	t = t.Add(time.Minute - time.Duration(t.Second())*time.Second - time.Duration(t.Nanosecond()))
+	limit := t.Add(searchLimit)
+
+	for t.Before(limit) {
+		if schedule.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+
+			continue
+		}
+
+		if !schedule.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+
+			continue
+		}
+
+		if schedule.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+
+			continue
+		}
+
+		if schedule.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+func (schedule *Schedule) dayMatches(t time.Time) bool {
+	dayOfMonthMatches := schedule.dayOfMonth&(1<<uint(t.Day())) != 0
+	dayOfWeekMatches := schedule.dayOfWeek&(1<<uint(t.Weekday())) != 0
+
+	if schedule.dayOfMonthStar || schedule.dayOfWeekStar {
+		return dayOfMonthMatches && dayOfWeekMatches
+	}
+
+	return dayOfMonthMatches || dayOfWeekMatches
+}
@@ -0,0 +1,139 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+	}{
+		{name: "empty", spec: ""},
+		{name: "too few fields", spec: "* * * *"},
+		{name: "too many fields", spec: "* * * * * *"},
+		{name: "unknown macro", spec: "@every"},
+		{name: "minute out of range", spec: "60 * * * *"},
+		{name: "hour out of range", spec: "* 24 * * *"},
+		{name: "day of month zero", spec: "* * 0 * *"},
+		{name: "month out of range", spec: "* * * 13 *"},
+		{name: "day of week out of range", spec: "* * * * 8"},
+		{name: "negative value", spec: "-1 * * * *"},
+		{name: "not a number", spec: "a * * * *"},
+		{name: "unknown month name", spec: "* * * foo *"},
+		{name: "day name in month", spec: "* * * mon *"},
+		{name: "reversed range", spec: "5-1 * * * *"},
+		{name: "zero step", spec: "*/0 * * * *"},
+		{name: "invalid step", spec: "*/x * * * *"},
+		{name: "too many slashes", spec: "1/2/3 * * * *"},
+		{name: "too many hyphens", spec: "1-2-3 * * * *"},
+		{name: "empty list item", spec: "1,,2 * * * *"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := Parse(test.spec); err == nil {
+				t.Errorf("Parse(%q) expected an error", test.spec)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	// a monday
+	now := time.Date(2026, time.October, 19, 10, 30, 15, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{name: "every minute", spec: "* * * * *", want: time.Date(2026, time.October, 19, 10, 31, 0, 0, time.UTC)},
+		{name: "step", spec: "*/5 * * * *", want: time.Date(2026, time.October, 19, 10, 35, 0, 0, time.UTC)},
+		{name: "step from a start", spec: "5/15 * * * *", want: time.Date(2026, time.October, 19, 10, 35, 0, 0, time.UTC)},
+		{name: "range with step", spec: "0 9-17/4 * * *", want: time.Date(2026, time.October, 19, 13, 0, 0, 0, time.UTC)},
+		{name: "list", spec: "10,45 * * * *", want: time.Date(2026, time.October, 19, 10, 45, 0, 0, time.UTC)},
+		{name: "question mark is a star", spec: "0 0 ? * *", want: time.Date(2026, time.October, 20, 0, 0, 0, 0, time.UTC)},
+		{
+			name: "matching minute is in the past",
+			spec: "30 10 * * *",
+			from: time.Date(2026, time.October, 19, 10, 30, 0, 0, time.UTC),
+			want: time.Date(2026, time.October, 20, 10, 30, 0, 0, time.UTC),
+		},
+		{name: "hourly", spec: "@hourly", want: time.Date(2026, time.October, 19, 11, 0, 0, 0, time.UTC)},
+		{name: "daily", spec: "@daily", want: time.Date(2026, time.October, 20, 0, 0, 0, 0, time.UTC)},
+		{name: "midnight", spec: "@midnight", want: time.Date(2026, time.October, 20, 0, 0, 0, 0, time.UTC)},
+		{name: "weekly", spec: "@weekly", want: time.Date(2026, time.October, 25, 0, 0, 0, 0, time.UTC)},
+		{name: "monthly", spec: "@monthly", want: time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "yearly", spec: "@yearly", want: time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "annually", spec: "@annually", want: time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "day names", spec: "0 3 * * mon-fri", want: time.Date(2026, time.October, 20, 3, 0, 0, 0, time.UTC)},
+		{name: "upper case names", spec: "0 0 1 JAN,JUL *", want: time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "day of week 7 is sunday", spec: "0 0 * * 7", want: time.Date(2026, time.October, 25, 0, 0, 0, 0, time.UTC)},
+		{name: "day of week 0 is sunday", spec: "0 0 * * 0", want: time.Date(2026, time.October, 25, 0, 0, 0, 0, time.UTC)},
+		{name: "day of week range to 7", spec: "0 0 * * 6-7", want: time.Date(2026, time.October, 24, 0, 0, 0, 0, time.UTC)},
+		{
+			name: "day of week range to 7 includes sunday",
+			spec: "0 0 * * 6-7",
+			from: time.Date(2026, time.October, 24, 0, 0, 0, 0, time.UTC),
+			want: time.Date(2026, time.October, 25, 0, 0, 0, 0, time.UTC),
+		},
+		{name: "only day of month restricted", spec: "0 0 13 * *", want: time.Date(2026, time.November, 13, 0, 0, 0, 0, time.UTC)},
+		{name: "only day of week restricted", spec: "0 0 * * fri", want: time.Date(2026, time.October, 23, 0, 0, 0, 0, time.UTC)},
+		{
+			name: "both days restricted matches the day of week",
+			spec: "0 0 13 * fri",
+			want: time.Date(2026, time.October, 23, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "both days restricted matches the day of month",
+			spec: "0 0 1 * mon",
+			from: time.Date(2026, time.October, 27, 0, 0, 0, 0, time.UTC),
+			want: time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "day of month restricted with day of week star",
+			spec: "0 0 1 * *",
+			from: time.Date(2026, time.October, 27, 0, 0, 0, 0, time.UTC),
+			want: time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{name: "leap day", spec: "0 0 29 2 *", want: time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{name: "never matches", spec: "0 0 30 2 *", want: time.Time{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schedule, err := Parse(test.spec)
+			if err != nil {
+				t.Fatalf("Parse(%q) error: %v", test.spec, err)
+			}
+
+			from := test.from
+			if from.IsZero() {
+				from = now
+			}
+
+			if next := schedule.Next(from); !next.Equal(test.want) {
+				t.Errorf("Parse(%q).Next(%v) = %v, want %v", test.spec, from, next, test.want)
+			}
+		})
+	}
+}
+
+func TestNextKeepsLocation(t *testing.T) {
+	location := time.FixedZone("UTC+3", 3*60*60)
+
+	schedule, err := Parse("0 3 * * *")
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+
+	next := schedule.Next(time.Date(2026, time.October, 19, 10, 30, 0, 0, location))
+
+	want := time.Date(2026, time.October, 20, 3, 0, 0, 0, location)
+	if !next.Equal(want) || next.Location() != location {
+		t.Errorf("Next = %v, want %v", next, want)
+	}
+}
@@ -1,13 +1,25 @@
 package kubeapi
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/emicklei/go-restful/v3"
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/conversion"
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/encryption"
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/etcd"
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/flowcontrol"
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/health"
 	kubeapi_logger "github.com/jonatan5524/own-kubernetes/pkg/kube-api/logger"
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/metrics"
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
 )
 
 type KubeAPI interface {
@@ -17,41 +29,104 @@ type KubeAPI interface {
 }
 
 type Rest interface {
-	Register(etcdServers string)
+	Register(container *restful.Container, etcdService etcd.EtcdService)
 }
 
 type KubeAPIApp struct {
 	server        *http.Server
+	container     *restful.Container
+	etcdService   etcd.EtcdService
+	shuttingDown  atomic.Bool
+	stopWatches   chan struct{}
+	stopOnce      sync.Once
 	Host          string
 	restEndpoints []Rest
 	Port          int
 	EtcdServers   string
+	options       Options
+}
+
+type Options struct {
+	EtcdServers string
+	// ShutdownDelay is the time readyz reports failure before the server stops accepting connections
+	ShutdownDelay time.Duration
+	// ShutdownTimeout is the deadline for in-flight requests to finish on shutdown
+	ShutdownTimeout time.Duration
+	// WatchCacheSize is the number of recent events kept per resource for resuming watches
+	WatchCacheSize int
+	// MaxRequestsInflight and MaxMutatingRequestsInflight limit the non watch requests served at once,
+	// zero or less disables the limit
+	MaxRequestsInflight         int
+	MaxMutatingRequestsInflight int
+	// EncryptionProviderConfig is the path of the config for encrypting resources at rest, empty stores them as is
+	EncryptionProviderConfig string
+	// ServiceClusterIPRange is the cidr the clusterIPs of the services are allocated from
+	ServiceClusterIPRange string
+	// ServiceNodePortRange is the range of ports the node ports of the services are allocated from, as first-last
+	ServiceNodePortRange string
 }
 
 const (
-	defaultPort    = 8080
-	defaultHost    = "0.0.0.0"
-	defaultTimeout = 3 * time.Second
+	defaultPort              = 8080
+	defaultHost              = "0.0.0.0"
+	defaultTimeout           = 3 * time.Second
+	defaultEtcdHealthTimeout = 2 * time.Second
+	defaultShutdownTimeout   = 30 * time.Second
+	DefaultWatchCacheSize    = 100
 )
 
-func NewKubeAPI(etcdServers string, restEndpoints []Rest) KubeAPI {
+func NewKubeAPI(options Options, restEndpoints []Rest) KubeAPI {
 	app := &KubeAPIApp{}
 
 	app.restEndpoints = restEndpoints
 	app.Port = defaultPort
 	app.Host = defaultHost
 
+	if options.ShutdownTimeout == 0 {
+		options.ShutdownTimeout = defaultShutdownTimeout
+	}
+
+	if options.WatchCacheSize == 0 {
+		options.WatchCacheSize = DefaultWatchCacheSize
+	}
+
+	if options.ServiceClusterIPRange == "" {
+		options.ServiceClusterIPRange = rest.DefaultServiceClusterIPRange
+	}
+
+	if options.ServiceNodePortRange == "" {
+		options.ServiceNodePortRange = rest.DefaultServiceNodePortRange
+	}
+	app.options = options
+
+	rest.RegisterEntityAccessorYAML()
+
+	app.container = restful.NewContainer()
+	app.container.Filter(flowcontrol.NewController(flowcontrol.Options{
+		MaxRequestsInflight:         options.MaxRequestsInflight,
+		MaxMutatingRequestsInflight: options.MaxMutatingRequestsInflight,
+	}).Filter)
+	app.stopWatches = make(chan struct{})
+
 	app.server = &http.Server{
-		Addr:              fmt.Sprintf("%s:%d", app.Host, app.Port),
+		Handler:           rest.VersionedPaths(app.container),
 		ReadHeaderTimeout: defaultTimeout,
+		BaseContext: func(net.Listener) context.Context {
+			return rest.WithWatchStop(context.Background(), app.stopWatches)
+		},
 	}
+	// watch streams never become idle by themselves, they are ended when the shutdown starts
+	app.server.RegisterOnShutdown(func() {
+		close(app.stopWatches)
+	})
 
-	app.EtcdServers = etcdServers
+	app.EtcdServers = options.EtcdServers
+	app.etcdService = etcd.NewEtcdService(options.EtcdServers)
 
 	return app
 }
 
-func setupHealth() {
+func (app *KubeAPIApp) setupHealth() {
 	log.Println("setup health check endpoint")
 
 	ws := new(restful.WebService)
@@ -59,26 +134,87 @@ func setupHealth() {
 	ws.Filter(kubeapi_logger.LoggerMiddleware)
 
 	ws.Path("/").
-		Consumes(restful.MIME_XML, restful.MIME_JSON).
-		Produces(restful.MIME_JSON, restful.MIME_XML)
+		Consumes(restful.MIME_JSON, rest.MimeYAML).
+		Produces(restful.MIME_JSON, rest.MimeYAML)
 
-	ws.Route(ws.GET("/health").To(func(_ *restful.Request, res *restful.Response) {
-		err := res.WriteEntity("all good")
-		if err != nil {
-			res.WriteError(http.StatusInternalServerError, err)
+	etcdCheck := health.NamedCheck("etcd", func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), defaultEtcdHealthTimeout)
+		defer cancel()
+
+		return app.etcdService.Ping(ctx)
+	})
+
+	namespacesCheck := health.NamedCheck("default-namespaces", rest.CheckDefaultNamespaces)
+
+	watchCacheCheck := health.NamedCheck("watch-cache", rest.CheckWatchCachesReady)
+
+	shutdownCheck := health.NamedCheck("shutdown", func() error {
+		if app.shuttingDown.Load() {
+			return fmt.Errorf("server is shutting down")
+		}
+
+		return nil
+	})
+
+	health.InstallHandler(ws, "livez", health.PingCheck, etcdCheck)
+	health.InstallHandler(ws, "readyz", health.PingCheck, etcdCheck, namespacesCheck, watchCacheCheck, shutdownCheck)
+	// kept for older clients, same as livez
+	health.InstallHandler(ws, "health", health.PingCheck, etcdCheck)
+
+	app.container.Add(ws)
+}
+
+func (app *KubeAPIApp) setupMetrics() {
+	log.Println("setup metrics endpoint")
+
+	ws := new(restful.WebService)
+
+	ws.Path("/metrics")
+
+	metrics.InstallHandler(ws)
+
+	metrics.OnScrape(func() {
+		for _, etcdKey := range rest.StoredResourcesEtcdKeys {
+			count, err := app.etcdService.CountResource(etcdKey)
+			if err != nil {
+				log.Printf("error counting stored objects of %s: %v", etcdKey, err)
+
+				continue
+			}
+
+			metrics.SetStorageObjects(metrics.ResourceFromEtcdKey(etcdKey), count)
 		}
-	}))
+	})
 
-	restful.Add(ws)
+	app.container.Add(ws)
 }
 
 func (app *KubeAPIApp) Setup() error {
 	log.Println("KubeApi setup")
 
-	setupHealth()
+	if app.options.EncryptionProviderConfig != "" {
+		transformers, err := encryption.LoadConfig(app.options.EncryptionProviderConfig)
+		if err != nil {
+			return err
+		}
+
+		app.etcdService = encryption.NewEtcdService(app.etcdService, transformers)
+	}
+
+	// objects are converted to the storage version of their resource above the encryption, it only sees bytes
+	app.etcdService = conversion.NewEtcdService(app.etcdService, rest.Scheme)
+
+	app.setupHealth()
+	app.setupMetrics()
+
+	rest.StartWatchCaches(app.etcdService, app.options.WatchCacheSize)
 
 	for _, restEndpoint := range app.restEndpoints {
-		restEndpoint.Register(app.EtcdServers)
+		restEndpoint.Register(app.container, app.etcdService)
+	}
+
+	if err := rest.SetupServiceAllocators(app.options.ServiceClusterIPRange, app.options.ServiceNodePortRange); err != nil {
+		return err
 	}
 
 	return nil
@@ -87,8 +223,10 @@ func (app *KubeAPIApp) Setup() error {
 func (app *KubeAPIApp) Run() error {
 	log.Printf("Kube api listening on %s:%d", app.Host, app.Port)
 
+	app.server.Addr = fmt.Sprintf("%s:%d", app.Host, app.Port)
+
 	err := app.server.ListenAndServe()
-	if err != nil {
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		return err
 	}
 
@@ -96,5 +234,42 @@ func (app *KubeAPIApp) Run() error {
 }
 
 func (app *KubeAPIApp) Stop() error {
-	return nil
+	var err error
+
+	app.stopOnce.Do(func() {
+		err = app.shutdown()
+	})
+
+	return err
+}
+
+func (app *KubeAPIApp) shutdown() error {
+	log.Println("KubeApi shutting down")
+
+	app.shuttingDown.Store(true)
+
+	if app.options.ShutdownDelay > 0 {
+		log.Printf("waiting %s before closing listeners", app.options.ShutdownDelay)
+		time.Sleep(app.options.ShutdownDelay)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), app.options.ShutdownTimeout)
+	defer cancel()
+
+	var shutdownErr error
+	if err := app.server.Shutdown(ctx); err != nil {
+		log.Printf("in-flight requests did not finish in %s, closing connections: %v", app.options.ShutdownTimeout, err)
+
+		shutdownErr = app.server.Close()
+	}
+
+	rest.StopWatchCaches()
+
+	if err := app.etcdService.Close(); err != nil {
+		return fmt.Errorf("error closing etcd client: %v", err)
+	}
+
+	log.Println("KubeApi stopped")
+
+	return shutdownErr
 }
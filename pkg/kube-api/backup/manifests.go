@@ -0,0 +1,112 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/etcd"
+	"github.com/jonatan5524/own-kubernetes/pkg/utils"
+)
+
+const manifestExtension = ".yaml"
+
+// DefaultExportPrefixes are the etcd prefixes exported when none are given
+var DefaultExportPrefixes = []string{"/namespaces", "/pods", "/services"}
+
+// Export writes every object under the etcd prefixes as a YAML manifest in the directory, the path of the manifest
+// is its etcd key, for example /pods/default/nginx is written to <dir>/pods/default/nginx.yaml. etcdService is
+// expected to decrypt the encrypted resources, the manifests are plain
+func Export(etcdService etcd.EtcdService, prefixes []string, dir string) (int, error) {
+	exported := 0
+
+	for _, prefix := range prefixes {
+		// the prefix is listed with a trailing slash so /pods does not list /podmetrics
+		values, _, err := etcdService.ListResource(strings.TrimSuffix(prefix, "/") + "/")
+		if err != nil {
+			return exported, fmt.Errorf("error listing %s: %v", prefix, err)
+		}
+
+		for key, value := range values {
+			manifest, err := utils.JSONToYAML(value)
+			if err != nil {
+				return exported, fmt.Errorf("error converting %s to yaml: %v", key, err)
+			}
+
+			file := filepath.Join(dir, filepath.FromSlash(key)+manifestExtension)
+			if err = os.MkdirAll(filepath.Dir(file), 0o700); err != nil {
+				return exported, fmt.Errorf("error creating directory of %s: %v", key, err)
+			}
+
+			if err = os.WriteFile(file, manifest, 0o600); err != nil {
+				return exported, fmt.Errorf("error writing %s: %v", file, err)
+			}
+
+			exported++
+		}
+
+		log.Printf("exported %d objects of %s", len(values), prefix)
+	}
+
+	return exported, nil
+}
+
+// Import writes the manifests of the directory written by Export back to their etcd keys. Keys that already exist
+// are skipped unless overwrite is set, so importing into a running cluster does not replace its objects
+func Import(etcdService etcd.EtcdService, dir string, overwrite bool) (int, int, error) {
+	imported, skipped := 0, 0
+
+	err := filepath.WalkDir(dir, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() || filepath.Ext(file) != manifestExtension {
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+
+		key := "/" + filepath.ToSlash(strings.TrimSuffix(relativePath, manifestExtension))
+
+		manifest, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("error reading %s: %v", file, err)
+		}
+
+		value, err := utils.YAMLToJSON(manifest)
+		if err != nil {
+			return fmt.Errorf("error converting %s to json: %v", file, err)
+		}
+
+		if overwrite {
+			err = etcdService.PutResource(key, string(value))
+		} else {
+			// a revision of 0 writes the key only if it does not exist
+			err = etcdService.PutResourceIfRevision(key, string(value), 0)
+			if errors.Is(err, etcd.ErrRevisionConflict) {
+				log.Printf("%s already exists, skipping", key)
+				skipped++
+
+				return nil
+			}
+		}
+
+		if err != nil {
+			return fmt.Errorf("error writing %s: %v", key, err)
+		}
+
+		imported++
+
+		return nil
+	})
+
+	return imported, skipped, err
+}
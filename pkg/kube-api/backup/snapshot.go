@@ -0,0 +1,158 @@
+package backup
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/etcd"
+)
+
+// SnapshotMetadata is written next to every snapshot, restore checks the snapshot against it before using it
+type SnapshotMetadata struct {
+	CreationTimestamp string `json:"creationTimestamp" yaml:"creationTimestamp"`
+	EtcdServers       string `json:"etcdServers" yaml:"etcdServers"`
+	EtcdVersion       string `json:"etcdVersion" yaml:"etcdVersion"`
+	// Revision is the etcd revision when the snapshot started, the snapshot holds at least this revision
+	Revision int64  `json:"revision" yaml:"revision"`
+	Size     int64  `json:"size" yaml:"size"`
+	SHA256   string `json:"sha256" yaml:"sha256"`
+}
+
+// MetadataFile returns the file of the metadata of the snapshot file
+func MetadataFile(file string) string {
+	return file + ".metadata.json"
+}
+
+// Snapshot streams a snapshot of etcd to the file and writes its metadata. The snapshot is written to a temporary
+// file first, so the file is either a complete snapshot or is not changed
+func Snapshot(ctx context.Context, etcdService etcd.EtcdService, etcdServers string, file string) (*SnapshotMetadata, error) {
+	status, err := etcdService.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := &SnapshotMetadata{
+		CreationTimestamp: time.Now().Format(time.RFC3339),
+		EtcdServers:       etcdServers,
+		EtcdVersion:       status.Version,
+		Revision:          status.Header.Revision,
+	}
+
+	log.Printf("saving snapshot of etcd %s at revision %d to %s", metadata.EtcdVersion, metadata.Revision, file)
+
+	snapshot, err := etcdService.Snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer snapshot.Close()
+
+	partFile := file + ".part"
+	out, err := os.OpenFile(partFile, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("error creating snapshot file: %v", err)
+	}
+	defer os.Remove(partFile)
+
+	hash := sha256.New()
+	metadata.Size, err = io.Copy(io.MultiWriter(out, hash), snapshot)
+	if err == nil {
+		err = out.Sync()
+	}
+
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error writing snapshot: %v", err)
+	}
+
+	metadata.SHA256 = hex.EncodeToString(hash.Sum(nil))
+
+	if err = os.Rename(partFile, file); err != nil {
+		return nil, fmt.Errorf("error moving snapshot to %s: %v", file, err)
+	}
+
+	metadataBytes, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error parsing snapshot metadata: %v", err)
+	}
+
+	if err = os.WriteFile(MetadataFile(file), metadataBytes, 0o600); err != nil {
+		return nil, fmt.Errorf("error writing snapshot metadata: %v", err)
+	}
+
+	log.Printf("saved snapshot of %d bytes with sha256 %s", metadata.Size, metadata.SHA256)
+
+	return metadata, nil
+}
+
+// Verify checks the size and the checksum of the snapshot file against its metadata
+func Verify(file string) (*SnapshotMetadata, error) {
+	metadataBytes, err := os.ReadFile(MetadataFile(file))
+	if err != nil {
+		return nil, fmt.Errorf("error reading snapshot metadata: %v", err)
+	}
+
+	metadata := &SnapshotMetadata{}
+	if err = json.Unmarshal(metadataBytes, metadata); err != nil {
+		return nil, fmt.Errorf("error parsing snapshot metadata: %v", err)
+	}
+
+	snapshot, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("error opening snapshot: %v", err)
+	}
+	defer snapshot.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("error reading snapshot: %v", err)
+	}
+
+	if size != metadata.Size {
+		return nil, fmt.Errorf("snapshot %s is %d bytes, expected %d", file, size, metadata.Size)
+	}
+
+	if checksum := hex.EncodeToString(hash.Sum(nil)); checksum != metadata.SHA256 {
+		return nil, fmt.Errorf("snapshot %s has sha256 %s, expected %s", file, checksum, metadata.SHA256)
+	}
+
+	return metadata, nil
+}
+
+// Restore verifies the snapshot file and rebuilds an etcd data directory from it with etcdutl, etcd is then started
+// on the data directory. The data directory must not exist, an existing cluster state is never overwritten
+func Restore(file string, dataDir string, etcdutl string) error {
+	metadata, err := Verify(file)
+	if err != nil {
+		return err
+	}
+
+	if _, err = os.Stat(dataDir); err == nil {
+		return fmt.Errorf("data directory %s already exists", dataDir)
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("error checking data directory %s: %v", dataDir, err)
+	}
+
+	log.Printf("restoring snapshot of etcd %s at revision %d from %s to %s",
+		metadata.EtcdVersion, metadata.Revision, file, dataDir)
+
+	output, err := exec.Command(etcdutl, "snapshot", "restore", file, "--data-dir", dataDir).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("error running %s: %v output: %s", etcdutl, err, string(output))
+	}
+
+	log.Printf("restored snapshot to %s", dataDir)
+
+	return nil
+}
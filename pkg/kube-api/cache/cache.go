@@ -0,0 +1,396 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/etcd"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+const (
+	EventTypePut    = "PUT"
+	EventTypeDelete = "DELETE"
+
+	defaultWatcherBufferSize = 100
+	relistInterval           = time.Second
+	defaultReadyTimeout      = 5 * time.Second
+)
+
+// ErrResourceVersionTooOld is returned when a watch asks for events that were already dropped from the cache
+var ErrResourceVersionTooOld = errors.New("too old resource version")
+
+type Event struct {
+	Type string
+	Key  string
+	// Value is the object after the change, for DELETE it is the last value before the deletion
+	Value    []byte
+	Revision int64
+
+	// prevValue is the object before a PUT, nil when the PUT created it
+	prevValue []byte
+}
+
+// Predicate filters the resources of a cache, empty fields match everything
+type Predicate struct {
+	Namespace string
+	Label     LabelSelector
+	Field     FieldSelector
+}
+
+// WatchCache keeps the current state of all the resources under an etcd prefix and a ring buffer of their
+// recent events, it is filled by a single etcd watch and serves list and watch requests from memory
+type WatchCache struct {
+	etcdService etcd.EtcdService
+	prefix      string
+	capacity    int
+
+	mu       sync.RWMutex
+	store    map[string][]byte
+	revision int64
+
+	// events is a ring buffer, oldest is the index of the oldest event
+	events []Event
+	oldest int
+	size   int
+	// replayableRevision is the lowest revision a watch can be resumed from
+	replayableRevision int64
+
+	watchers      map[int]*Watcher
+	nextWatcherID int
+
+	ready     chan struct{}
+	readyOnce sync.Once
+	cancel    context.CancelFunc
+	done      chan struct{}
+}
+
+func NewWatchCache(etcdService etcd.EtcdService, prefix string, capacity int) *WatchCache {
+	return &WatchCache{
+		etcdService: etcdService,
+		prefix:      strings.TrimSuffix(prefix, "/"),
+		capacity:    capacity,
+		store:       make(map[string][]byte),
+		events:      make([]Event, capacity),
+		watchers:    make(map[int]*Watcher),
+		ready:       make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+}
+
+func (cache *WatchCache) Start() {
+	log.Printf("starting watch cache for %s", cache.prefix)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cache.cancel = cancel
+
+	go cache.run(ctx)
+}
+
+func (cache *WatchCache) Stop() {
+	if cache.cancel == nil {
+		return
+	}
+
+	log.Printf("stopping watch cache for %s", cache.prefix)
+
+	cache.cancel()
+	<-cache.done
+}
+
+func (cache *WatchCache) Ready() bool {
+	select {
+	case <-cache.ready:
+		return true
+	default:
+		return false
+	}
+}
+
+func (cache *WatchCache) waitUntilReady(timeout time.Duration) error {
+	select {
+	case <-cache.ready:
+		return nil
+	case <-time.After(timeout):
+		return fmt.Errorf("watch cache for %s is not ready", cache.prefix)
+	}
+}
+
+func (cache *WatchCache) run(ctx context.Context) {
+	defer close(cache.done)
+
+	for {
+		if err := cache.listAndWatch(ctx); err != nil {
+			log.Printf("watch cache for %s failed, relisting: %v", cache.prefix, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			cache.terminateWatchers()
+
+			return
+		case <-time.After(relistInterval):
+		}
+	}
+}
+
+func (cache *WatchCache) listAndWatch(ctx context.Context) error {
+	values, revision, err := cache.etcdService.ListResource(cache.prefix + "/")
+	if err != nil {
+		return err
+	}
+
+	cache.replace(values, revision)
+
+	watchChan, closeWatch, err := cache.etcdService.GetWatchChannel(cache.prefix+"/", revision+1)
+	if err != nil {
+		return err
+	}
+	defer closeWatch()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case watchResp, ok := <-watchChan:
+			if !ok {
+				return fmt.Errorf("etcd watch channel closed")
+			}
+
+			if err := watchResp.Err(); err != nil {
+				return fmt.Errorf("etcd watch error: %v", err)
+			}
+
+			for _, event := range watchResp.Events {
+				cache.processEvent(event)
+			}
+		}
+	}
+}
+
+// replace resets the cache to a fresh list, watchers are terminated since they may have missed events
+// while the etcd watch was down and have to start over
+func (cache *WatchCache) replace(values map[string][]byte, revision int64) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	cache.store = values
+	cache.revision = revision
+	cache.oldest = 0
+	cache.size = 0
+	cache.replayableRevision = revision
+
+	cache.terminateWatchersLocked()
+
+	cache.readyOnce.Do(func() {
+		close(cache.ready)
+	})
+
+	log.Printf("watch cache for %s synced %d objects at revision %d", cache.prefix, len(values), revision)
+}
+
+func (cache *WatchCache) processEvent(etcdEvent *clientv3.Event) {
+	key := string(etcdEvent.Kv.Key)
+
+	event := Event{
+		Key:      key,
+		Revision: etcdEvent.Kv.ModRevision,
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	switch etcdEvent.Type {
+	case mvccpb.PUT:
+		event.Type = EventTypePut
+		event.Value = etcdEvent.Kv.Value
+		event.prevValue = cache.store[key]
+		cache.store[key] = etcdEvent.Kv.Value
+	case mvccpb.DELETE:
+		event.Type = EventTypeDelete
+		event.Value = cache.store[key]
+		delete(cache.store, key)
+	}
+
+	cache.revision = event.Revision
+	cache.appendEventLocked(event)
+
+	for id, watcher := range cache.watchers {
+		watcherEvent, ok := cache.eventFor(watcher.predicate, event)
+		if !ok {
+			continue
+		}
+
+		select {
+		case watcher.result <- watcherEvent:
+		default:
+			// the client does not keep up, it is cheaper for it to start a new watch than to buffer forever
+			log.Printf("watcher %d of %s is too slow, terminating it", id, cache.prefix)
+			cache.removeWatcherLocked(id)
+		}
+	}
+}
+
+func (cache *WatchCache) appendEventLocked(event Event) {
+	if cache.capacity == 0 {
+		cache.replayableRevision = event.Revision
+
+		return
+	}
+
+	if cache.size == cache.capacity {
+		cache.replayableRevision = cache.events[cache.oldest].Revision
+		cache.oldest = (cache.oldest + 1) % cache.capacity
+		cache.size--
+	}
+
+	cache.events[(cache.oldest+cache.size)%cache.capacity] = event
+	cache.size++
+}
+
+// eventFor returns the event as seen by a watcher of the predicate. A PUT that moves the object out of the predicate
+// is a DELETE of its previous value for the watcher, so the watcher does not keep an object it no longer selects
+func (cache *WatchCache) eventFor(predicate Predicate, event Event) (Event, bool) {
+	if cache.matches(predicate, event.Key, event.Value) {
+		return event, true
+	}
+
+	if event.Type == EventTypePut && event.prevValue != nil && cache.matches(predicate, event.Key, event.prevValue) {
+		return Event{
+			Type:     EventTypeDelete,
+			Key:      event.Key,
+			Value:    event.prevValue,
+			Revision: event.Revision,
+		}, true
+	}
+
+	return Event{}, false
+}
+
+func (cache *WatchCache) matches(predicate Predicate, key string, value []byte) bool {
+	if predicate.Namespace != "" && !strings.HasPrefix(key, fmt.Sprintf("%s/%s/", cache.prefix, predicate.Namespace)) {
+		return false
+	}
+
+	return predicate.Label.Matches(value) && predicate.Field.Matches(value)
+}
+
+// List returns the values matching the predicate sorted by key, and the revision of the cache
+func (cache *WatchCache) List(predicate Predicate) ([][]byte, int64, error) {
+	if err := cache.waitUntilReady(defaultReadyTimeout); err != nil {
+		return nil, 0, err
+	}
+
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+
+	keys := make([]string, 0, len(cache.store))
+	for key, value := range cache.store {
+		if cache.matches(predicate, key, value) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	values := make([][]byte, len(keys))
+	for index, key := range keys {
+		values[index] = cache.store[key]
+	}
+
+	return values, cache.revision, nil
+}
+
+// Watch returns a watcher for the events matching the predicate, when resourceVersion is not 0
+// the events after it that are still in the cache are sent first
+func (cache *WatchCache) Watch(predicate Predicate, resourceVersion int64) (*Watcher, error) {
+	if err := cache.waitUntilReady(defaultReadyTimeout); err != nil {
+		return nil, err
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	var replay []Event
+	if resourceVersion != 0 && resourceVersion < cache.revision {
+		if resourceVersion < cache.replayableRevision {
+			return nil, fmt.Errorf("%w: %d, oldest available is %d", ErrResourceVersionTooOld, resourceVersion, cache.replayableRevision)
+		}
+
+		for index := 0; index < cache.size; index++ {
+			event := cache.events[(cache.oldest+index)%cache.capacity]
+			if event.Revision <= resourceVersion {
+				continue
+			}
+
+			if watcherEvent, ok := cache.eventFor(predicate, event); ok {
+				replay = append(replay, watcherEvent)
+			}
+		}
+	}
+
+	watcher := &Watcher{
+		cache:     cache,
+		id:        cache.nextWatcherID,
+		predicate: predicate,
+		result:    make(chan Event, defaultWatcherBufferSize+len(replay)),
+	}
+	cache.nextWatcherID++
+
+	for _, event := range replay {
+		watcher.result <- event
+	}
+
+	cache.watchers[watcher.id] = watcher
+
+	return watcher, nil
+}
+
+func (cache *WatchCache) removeWatcherLocked(id int) {
+	watcher, ok := cache.watchers[id]
+	if !ok {
+		return
+	}
+
+	delete(cache.watchers, id)
+	close(watcher.result)
+}
+
+func (cache *WatchCache) terminateWatchersLocked() {
+	for id := range cache.watchers {
+		cache.removeWatcherLocked(id)
+	}
+}
+
+func (cache *WatchCache) terminateWatchers() {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	cache.terminateWatchersLocked()
+}
+
+// Watcher receives the events of a cache, its channel is closed when the cache terminates it
+type Watcher struct {
+	cache     *WatchCache
+	id        int
+	predicate Predicate
+	result    chan Event
+}
+
+func (watcher *Watcher) ResultChan() <-chan Event {
+	return watcher.result
+}
+
+func (watcher *Watcher) Stop() {
+	watcher.cache.mu.Lock()
+	defer watcher.cache.mu.Unlock()
+
+	watcher.cache.removeWatcherLocked(watcher.id)
+}
@@ -0,0 +1,229 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+type selectorOperator string
+
+const (
+	operatorEquals       selectorOperator = "="
+	operatorNotEquals    selectorOperator = "!="
+	operatorIn           selectorOperator = "in"
+	operatorNotIn        selectorOperator = "notin"
+	operatorExists       selectorOperator = "exists"
+	operatorDoesNotExist selectorOperator = "!"
+)
+
+type requirement struct {
+	key      string
+	operator selectorOperator
+	values   []string
+}
+
+func (req requirement) matches(value string, exists bool) bool {
+	switch req.operator {
+	case operatorEquals:
+		return exists && value == req.values[0]
+	case operatorNotEquals:
+		return !exists || value != req.values[0]
+	case operatorIn:
+		return exists && contains(req.values, value)
+	case operatorNotIn:
+		return !exists || !contains(req.values, value)
+	case operatorExists:
+		return exists
+	case operatorDoesNotExist:
+		return !exists
+	}
+
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, current := range values {
+		if current == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// LabelSelector matches the metadata.labels of a resource,
+// supports key=value, key==value, key!=value, key, !key, key in (a,b) and key notin (a,b) separated by commas
+type LabelSelector struct {
+	requirements []requirement
+}
+
+func ParseLabelSelector(selector string) (LabelSelector, error) {
+	var labelSelector LabelSelector
+
+	for _, term := range splitSelector(selector) {
+		req, err := parseLabelRequirement(term)
+		if err != nil {
+			return LabelSelector{}, fmt.Errorf("invalid label selector %q: %v", selector, err)
+		}
+
+		labelSelector.requirements = append(labelSelector.requirements, req)
+	}
+
+	return labelSelector, nil
+}
+
+func parseLabelRequirement(term string) (requirement, error) {
+	if strings.HasPrefix(term, "!") && !strings.Contains(term, "=") {
+		return newRequirement(strings.TrimPrefix(term, "!"), operatorDoesNotExist, nil)
+	}
+
+	for _, operator := range []selectorOperator{operatorIn, operatorNotIn} {
+		if key, values, found := strings.Cut(term, fmt.Sprintf(" %s ", operator)); found {
+			values = strings.TrimSpace(values)
+			if !strings.HasPrefix(values, "(") || !strings.HasSuffix(values, ")") {
+				return requirement{}, fmt.Errorf("values of %s must be in parentheses", operator)
+			}
+
+			var parsedValues []string
+			for _, value := range strings.Split(strings.Trim(values, "()"), ",") {
+				parsedValues = append(parsedValues, strings.TrimSpace(value))
+			}
+
+			return newRequirement(key, operator, parsedValues)
+		}
+	}
+
+	if strings.Contains(term, "=") {
+		return parseEqualityRequirement(term)
+	}
+
+	return newRequirement(term, operatorExists, nil)
+}
+
+// FieldSelector matches fields of a resource by their json path, for example spec.nodeName=node1,
+// supports key=value, key==value and key!=value separated by commas
+type FieldSelector struct {
+	requirements []requirement
+}
+
+func ParseFieldSelector(selector string) (FieldSelector, error) {
+	var fieldSelector FieldSelector
+
+	for _, term := range splitSelector(selector) {
+		req, err := parseEqualityRequirement(term)
+		if err != nil {
+			return FieldSelector{}, fmt.Errorf("invalid field selector %q: %v", selector, err)
+		}
+
+		fieldSelector.requirements = append(fieldSelector.requirements, req)
+	}
+
+	return fieldSelector, nil
+}
+
+func parseEqualityRequirement(term string) (requirement, error) {
+	if key, value, found := strings.Cut(term, "!="); found {
+		return newRequirement(key, operatorNotEquals, []string{strings.TrimSpace(value)})
+	}
+
+	if key, value, found := strings.Cut(term, "=="); found {
+		return newRequirement(key, operatorEquals, []string{strings.TrimSpace(value)})
+	}
+
+	if key, value, found := strings.Cut(term, "="); found {
+		return newRequirement(key, operatorEquals, []string{strings.TrimSpace(value)})
+	}
+
+	return requirement{}, fmt.Errorf("%q is missing an operator", term)
+}
+
+func newRequirement(key string, operator selectorOperator, values []string) (requirement, error) {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return requirement{}, fmt.Errorf("empty key for operator %s", operator)
+	}
+
+	return requirement{
+		key:      key,
+		operator: operator,
+		values:   values,
+	}, nil
+}
+
+// splitSelector splits the selector on commas that are not inside the parentheses of in and notin
+func splitSelector(selector string) []string {
+	var terms []string
+
+	depth := 0
+	start := 0
+	for index, char := range selector {
+		switch char {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				terms = append(terms, selector[start:index])
+				start = index + 1
+			}
+		}
+	}
+	terms = append(terms, selector[start:])
+
+	var nonEmptyTerms []string
+	for _, term := range terms {
+		if term = strings.TrimSpace(term); term != "" {
+			nonEmptyTerms = append(nonEmptyTerms, term)
+		}
+	}
+
+	return nonEmptyTerms
+}
+
+func (selector LabelSelector) Empty() bool {
+	return len(selector.requirements) == 0
+}
+
+func (selector LabelSelector) Matches(value []byte) bool {
+	if selector.Empty() {
+		return true
+	}
+
+	var resource struct {
+		Metadata struct {
+			Labels map[string]string `json:"labels"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal(value, &resource); err != nil {
+		return false
+	}
+
+	for _, req := range selector.requirements {
+		labelValue, exists := resource.Metadata.Labels[req.key]
+		if !req.matches(labelValue, exists) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (selector FieldSelector) Empty() bool {
+	return len(selector.requirements) == 0
+}
+
+func (selector FieldSelector) Matches(value []byte) bool {
+	for _, req := range selector.requirements {
+		// a missing field is the same as an empty one, so spec.nodeName= matches unscheduled pods
+		field := gjson.GetBytes(value, req.key)
+		if !req.matches(field.String(), true) {
+			return false
+		}
+	}
+
+	return true
+}
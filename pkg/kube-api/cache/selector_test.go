@@ -0,0 +1,127 @@
+package cache
+
+import "testing"
+
+const selectorTestPod = `{
+	"metadata": {"name": "web-1", "labels": {"app": "web", "tier": "frontend"}},
+	"spec": {"nodeName": "node1"},
+	"status": {"phase": "Running"}
+}`
+
+func TestLabelSelectorMatches(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector string
+		value    string
+		matches  bool
+	}{
+		{name: "empty selector", selector: "", value: selectorTestPod, matches: true},
+		{name: "equals", selector: "app=web", value: selectorTestPod, matches: true},
+		{name: "double equals", selector: "app==web", value: selectorTestPod, matches: true},
+		{name: "equals other value", selector: "app=db", value: selectorTestPod, matches: false},
+		{name: "equals missing label", selector: "env=prod", value: selectorTestPod, matches: false},
+		{name: "not equals", selector: "app!=db", value: selectorTestPod, matches: true},
+		{name: "not equals same value", selector: "app!=web", value: selectorTestPod, matches: false},
+		{name: "not equals missing label", selector: "env!=prod", value: selectorTestPod, matches: true},
+		{name: "exists", selector: "tier", value: selectorTestPod, matches: true},
+		{name: "exists missing label", selector: "env", value: selectorTestPod, matches: false},
+		{name: "does not exist", selector: "!env", value: selectorTestPod, matches: true},
+		{name: "does not exist present label", selector: "!app", value: selectorTestPod, matches: false},
+		{name: "in", selector: "app in (db, web)", value: selectorTestPod, matches: true},
+		{name: "in other values", selector: "app in (db,cache)", value: selectorTestPod, matches: false},
+		{name: "in missing label", selector: "env in (prod)", value: selectorTestPod, matches: false},
+		{name: "notin", selector: "app notin (db,cache)", value: selectorTestPod, matches: true},
+		{name: "notin same value", selector: "app notin (db,web)", value: selectorTestPod, matches: false},
+		{name: "notin missing label", selector: "env notin (prod)", value: selectorTestPod, matches: true},
+		{name: "all requirements match", selector: "app=web, tier in (frontend,backend), !env", value: selectorTestPod, matches: true},
+		{name: "one requirement does not match", selector: "app=web,tier=backend", value: selectorTestPod, matches: false},
+		{name: "no labels", selector: "app=web", value: `{"metadata": {"name": "web-1"}}`, matches: false},
+		{name: "invalid json", selector: "app=web", value: `{`, matches: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			selector, err := ParseLabelSelector(test.selector)
+			if err != nil {
+				t.Fatalf("ParseLabelSelector(%q) error: %v", test.selector, err)
+			}
+
+			if matches := selector.Matches([]byte(test.value)); matches != test.matches {
+				t.Errorf("ParseLabelSelector(%q).Matches() = %v, want %v", test.selector, matches, test.matches)
+			}
+		})
+	}
+}
+
+func TestParseLabelSelectorErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector string
+	}{
+		{name: "empty key", selector: "=web"},
+		{name: "empty does not exist key", selector: "!"},
+		{name: "in without parentheses", selector: "app in web,db"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := ParseLabelSelector(test.selector); err == nil {
+				t.Errorf("ParseLabelSelector(%q) expected an error", test.selector)
+			}
+		})
+	}
+}
+
+func TestFieldSelectorMatches(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector string
+		value    string
+		matches  bool
+	}{
+		{name: "empty selector", selector: "", value: selectorTestPod, matches: true},
+		{name: "equals", selector: "spec.nodeName=node1", value: selectorTestPod, matches: true},
+		{name: "double equals", selector: "spec.nodeName==node1", value: selectorTestPod, matches: true},
+		{name: "equals other value", selector: "spec.nodeName=node2", value: selectorTestPod, matches: false},
+		{name: "not equals", selector: "status.phase!=Pending", value: selectorTestPod, matches: true},
+		{name: "not equals same value", selector: "status.phase!=Running", value: selectorTestPod, matches: false},
+		{name: "metadata field", selector: "metadata.name=web-1", value: selectorTestPod, matches: true},
+		{name: "missing field equals empty", selector: "spec.nodeName=", value: `{"spec": {}}`, matches: true},
+		{name: "present field equals empty", selector: "spec.nodeName=", value: selectorTestPod, matches: false},
+		{name: "missing field not equals empty", selector: "spec.nodeName!=", value: `{"spec": {}}`, matches: false},
+		{name: "all requirements match", selector: "spec.nodeName=node1,status.phase=Running", value: selectorTestPod, matches: true},
+		{name: "one requirement does not match", selector: "spec.nodeName=node1,status.phase=Pending", value: selectorTestPod, matches: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			selector, err := ParseFieldSelector(test.selector)
+			if err != nil {
+				t.Fatalf("ParseFieldSelector(%q) error: %v", test.selector, err)
+			}
+
+			if matches := selector.Matches([]byte(test.value)); matches != test.matches {
+				t.Errorf("ParseFieldSelector(%q).Matches() = %v, want %v", test.selector, matches, test.matches)
+			}
+		})
+	}
+}
+
+func TestParseFieldSelectorErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector string
+	}{
+		{name: "missing operator", selector: "spec.nodeName"},
+		{name: "empty key", selector: "=node1"},
+		{name: "one term missing operator", selector: "spec.nodeName=node1,status.phase"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := ParseFieldSelector(test.selector); err == nil {
+				t.Errorf("ParseFieldSelector(%q) expected an error", test.selector)
+			}
+		})
+	}
+}
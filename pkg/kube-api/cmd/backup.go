@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/backup"
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/etcd"
+	"github.com/spf13/cobra"
+)
+
+var (
+	snapshotFile string
+	dataDir      string
+	etcdutlPath  string
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "save a snapshot of etcd to a file, with its checksum and metadata in <file>.metadata.json",
+	RunE: func(_ *cobra.Command, _ []string) error {
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+		defer stop()
+
+		etcdService := etcd.NewEtcdService(etcdServers)
+		defer etcdService.Close()
+
+		_, err := backup.Snapshot(ctx, etcdService, etcdServers, snapshotFile)
+
+		return err
+	},
+}
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "verify a snapshot saved by backup and rebuild a new etcd data directory from it with etcdutl",
+	RunE: func(_ *cobra.Command, _ []string) error {
+		if err := backup.Restore(snapshotFile, dataDir, etcdutlPath); err != nil {
+			return err
+		}
+
+		log.Printf("start etcd with --data-dir %s to serve the restored state", dataDir)
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(backupCmd)
+	rootCmd.AddCommand(restoreCmd)
+
+	backupCmd.Flags().StringVar(&etcdServers, "etcd-servers", "", "etcd servers endpoints")
+	backupCmd.Flags().StringVar(&snapshotFile, "file", "", "file the snapshot is saved to")
+
+	for _, flag := range []string{"etcd-servers", "file"} {
+		if err := backupCmd.MarkFlagRequired(flag); err != nil {
+			panic(err)
+		}
+	}
+
+	restoreCmd.Flags().StringVar(&snapshotFile, "file", "", "snapshot file saved by backup")
+	restoreCmd.Flags().StringVar(&dataDir, "data-dir", "", "etcd data directory to create, must not exist")
+	restoreCmd.Flags().StringVar(&etcdutlPath, "etcdutl", "etcdutl", "path of the etcdutl binary of the etcd release")
+
+	for _, flag := range []string{"file", "data-dir"} {
+		if err := restoreCmd.MarkFlagRequired(flag); err != nil {
+			panic(err)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"log"
+
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/backup"
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/encryption"
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/etcd"
+	"github.com/spf13/cobra"
+)
+
+var (
+	manifestsDir    string
+	exportPrefixes  []string
+	importOverwrite bool
+)
+
+// manifestsEtcdService returns the etcd service for export and import, encrypting and decrypting the encrypted
+// resources when an encryption config is given so the manifests are plain
+func manifestsEtcdService() (etcd.EtcdService, error) {
+	etcdService := etcd.NewEtcdService(etcdServers)
+	if encryptionProviderConfig == "" {
+		return etcdService, nil
+	}
+
+	transformers, err := encryption.LoadConfig(encryptionProviderConfig)
+	if err != nil {
+		etcdService.Close()
+
+		return nil, err
+	}
+
+	return encryption.NewEtcdService(etcdService, transformers), nil
+}
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "write the objects under etcd prefixes as YAML manifests in a directory, one file per etcd key",
+	RunE: func(_ *cobra.Command, _ []string) error {
+		etcdService, err := manifestsEtcdService()
+		if err != nil {
+			return err
+		}
+		defer etcdService.Close()
+
+		exported, err := backup.Export(etcdService, exportPrefixes, manifestsDir)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("exported %d objects to %s", exported, manifestsDir)
+
+		return nil
+	},
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "write the YAML manifests of a directory written by export back to their etcd keys",
+	RunE: func(_ *cobra.Command, _ []string) error {
+		etcdService, err := manifestsEtcdService()
+		if err != nil {
+			return err
+		}
+		defer etcdService.Close()
+
+		imported, skipped, err := backup.Import(etcdService, manifestsDir, importOverwrite)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("imported %d objects from %s, skipped %d existing objects", imported, manifestsDir, skipped)
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(importCmd)
+
+	for _, command := range []*cobra.Command{exportCmd, importCmd} {
+		command.Flags().StringVar(&etcdServers, "etcd-servers", "", "etcd servers endpoints")
+		command.Flags().StringVar(&manifestsDir, "dir", "", "directory of the manifests")
+		command.Flags().StringVar(&encryptionProviderConfig, "encryption-provider-config", "",
+			"file with the configuration for encrypting resources at rest in etcd")
+
+		for _, flag := range []string{"etcd-servers", "dir"} {
+			if err := command.MarkFlagRequired(flag); err != nil {
+				panic(err)
+			}
+		}
+	}
+
+	exportCmd.Flags().StringSliceVar(&exportPrefixes, "prefixes", backup.DefaultExportPrefixes,
+		"etcd prefixes of the exported objects")
+	importCmd.Flags().BoolVar(&importOverwrite, "overwrite", false, "replace the objects that already exist")
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/conversion"
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
+	"github.com/spf13/cobra"
+)
+
+var migrateStorageCmd = &cobra.Command{
+	Use:   "migrate-storage",
+	Short: "rewrite the objects stored with an older version than the storage version of their resource",
+	RunE: func(_ *cobra.Command, _ []string) error {
+		etcdService, err := manifestsEtcdService()
+		if err != nil {
+			return err
+		}
+		defer etcdService.Close()
+
+		return conversion.Migrate(etcdService, rest.Scheme)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(migrateStorageCmd)
+
+	migrateStorageCmd.Flags().StringVar(&etcdServers, "etcd-servers", "", "etcd servers endpoints")
+	migrateStorageCmd.Flags().StringVar(&encryptionProviderConfig, "encryption-provider-config", "",
+		"file with the configuration for encrypting resources at rest in etcd, required if resources are encrypted")
+
+	if err := migrateStorageCmd.MarkFlagRequired("etcd-servers"); err != nil {
+		panic(err)
+	}
+}
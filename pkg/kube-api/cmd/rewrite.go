@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/encryption"
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/etcd"
+	"github.com/spf13/cobra"
+)
+
+var rewriteEncryptedCmd = &cobra.Command{
+	Use:   "rewrite-encrypted",
+	Short: "rewrite the encrypted resources that are not stored with the current write key, run after rotating a key",
+	RunE: func(_ *cobra.Command, _ []string) error {
+		transformers, err := encryption.LoadConfig(encryptionProviderConfig)
+		if err != nil {
+			return err
+		}
+
+		etcdService := etcd.NewEtcdService(etcdServers)
+		defer etcdService.Close()
+
+		return encryption.Rewrite(etcdService, transformers)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(rewriteEncryptedCmd)
+
+	rewriteEncryptedCmd.Flags().StringVar(&etcdServers, "etcd-servers", "", "etcd servers endpoints")
+	rewriteEncryptedCmd.Flags().StringVar(&encryptionProviderConfig, "encryption-provider-config", "",
+		"file with the configuration for encrypting resources at rest in etcd")
+
+	for _, flag := range []string{"etcd-servers", "encryption-provider-config"} {
+		if err := rewriteEncryptedCmd.MarkFlagRequired(flag); err != nil {
+			panic(err)
+		}
+	}
+}
@@ -1,38 +1,102 @@
 package cmd
 
 import (
+	"context"
+	"log"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	kubeapi "github.com/jonatan5524/own-kubernetes/pkg/kube-api"
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/flowcontrol"
 	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
 	"github.com/spf13/cobra"
 )
 
-var etcdServers string
+var (
+	etcdServers           string
+	shutdownDelayDuration time.Duration
+	shutdownTimeout       time.Duration
+	watchCacheSize        int
+
+	maxRequestsInflight         int
+	maxMutatingRequestsInflight int
+
+	encryptionProviderConfig string
+
+	serviceClusterIPRange string
+	serviceNodePortRange  string
+)
 
 var rootCmd = &cobra.Command{
 	Use:   "kube-api",
 	Short: "CLI util for running kubernetes api program",
 	RunE: func(_ *cobra.Command, _ []string) error {
 		app := kubeapi.NewKubeAPI(
-			etcdServers,
+			kubeapi.Options{
+				EtcdServers:     etcdServers,
+				ShutdownDelay:   shutdownDelayDuration,
+				ShutdownTimeout: shutdownTimeout,
+				WatchCacheSize:  watchCacheSize,
+
+				MaxRequestsInflight:         maxRequestsInflight,
+				MaxMutatingRequestsInflight: maxMutatingRequestsInflight,
+
+				EncryptionProviderConfig: encryptionProviderConfig,
+
+				ServiceClusterIPRange: serviceClusterIPRange,
+				ServiceNodePortRange:  serviceNodePortRange,
+			},
 			[]kubeapi.Rest{
+				&rest.Discovery{},
 				&rest.Pod{},
 				&rest.Namespace{},
 				&rest.Service{},
 				&rest.Endpoint{},
+				&rest.Event{},
+				&rest.ConfigMap{},
+				&rest.Secret{},
+				&rest.Node{},
+				&rest.Lease{},
+				&rest.ReplicaSet{},
+				&rest.Deployment{},
+				&rest.DaemonSet{},
+				&rest.Job{},
+				&rest.CronJob{},
+				&rest.StatefulSet{},
+				&rest.PodMetrics{},
+				&rest.HorizontalPodAutoscaler{},
 			})
-		defer app.Stop()
 
 		if err := app.Setup(); err != nil {
 			return err
 		}
 
-		if err := app.Run(); err != nil {
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+		defer stop()
+
+		errChan := make(chan error, 1)
+		go func() {
+			errChan <- app.Run()
+		}()
+
+		select {
+		case err := <-errChan:
+			if stopErr := app.Stop(); stopErr != nil {
+				log.Printf("error stopping kube api: %v", stopErr)
+			}
+
 			return err
-		}
+		case <-ctx.Done():
+			log.Println("received termination signal")
+
+			if err := app.Stop(); err != nil {
+				return err
+			}
 
-		return nil
+			return <-errChan
+		}
 	},
 }
 
@@ -45,6 +109,22 @@ func Execute() {
 
 func init() {
 	rootCmd.Flags().StringVar(&etcdServers, "etcd-servers", "", "etcd servers endpoints")
+	rootCmd.Flags().DurationVar(&shutdownDelayDuration, "shutdown-delay-duration", 0,
+		"time to keep serving with readyz failing before closing the listeners on termination")
+	rootCmd.Flags().DurationVar(&shutdownTimeout, "shutdown-timeout", 30*time.Second,
+		"deadline for in-flight requests to finish on termination")
+	rootCmd.Flags().IntVar(&watchCacheSize, "default-watch-cache-size", kubeapi.DefaultWatchCacheSize,
+		"number of recent events kept in the watch cache of every resource")
+	rootCmd.Flags().IntVar(&maxRequestsInflight, "max-requests-inflight", flowcontrol.DefaultMaxRequestsInflight,
+		"maximum number of non-mutating requests in flight at a given time, zero for no limit")
+	rootCmd.Flags().IntVar(&maxMutatingRequestsInflight, "max-mutating-requests-inflight", flowcontrol.DefaultMaxMutatingRequestsInflight,
+		"maximum number of mutating requests in flight at a given time, zero for no limit")
+	rootCmd.Flags().StringVar(&encryptionProviderConfig, "encryption-provider-config", "",
+		"file with the configuration for encrypting resources at rest in etcd")
+	rootCmd.Flags().StringVar(&serviceClusterIPRange, "service-cluster-ip-range", rest.DefaultServiceClusterIPRange,
+		"cidr the clusterIPs of the services are allocated from")
+	rootCmd.Flags().StringVar(&serviceNodePortRange, "service-node-port-range", rest.DefaultServiceNodePortRange,
+		"range of ports the node ports of the services are allocated from, as first-last")
 	err := rootCmd.MarkFlagRequired("etcd-servers")
 	if err != nil {
 		panic(err)
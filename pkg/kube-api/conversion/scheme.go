@@ -0,0 +1,185 @@
+package conversion
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Object is a stored object decoded as generic json, conversions change it in place. Numbers are kept as
+// json.Number so they are written back as they were read
+type Object map[string]interface{}
+
+// Func converts an object from a version of its resource to the next one
+type Func func(object Object) error
+
+type conversion struct {
+	toVersion string
+	convert   Func
+}
+
+type resource struct {
+	etcdKey        string
+	storageVersion string
+	kind           string
+	// conversions are by the version they convert from
+	conversions map[string]conversion
+}
+
+// Scheme has the storage version of the resources by their etcd prefix, and the conversions of the objects stored
+// with older versions to the storage version
+type Scheme struct {
+	resources []*resource
+}
+
+func NewScheme() *Scheme {
+	return &Scheme{}
+}
+
+// AddResource sets the storage version and the kind of the objects under the etcd prefix. Objects stored before the
+// resource had versions have no apiVersion, they are read as the storage version unless a conversion from the empty
+// version is added
+func (scheme *Scheme) AddResource(etcdKey string, storageVersion string, kind string) {
+	scheme.resources = append(scheme.resources, &resource{
+		etcdKey:        etcdKey,
+		storageVersion: storageVersion,
+		kind:           kind,
+		conversions: map[string]conversion{
+			"": {toVersion: storageVersion, convert: func(Object) error { return nil }},
+		},
+	})
+}
+
+// AddConversion adds the conversion of the objects under the etcd prefix from a version to the next one, the
+// conversions of a resource are chained until the object is in the storage version
+func (scheme *Scheme) AddConversion(etcdKey string, fromVersion string, toVersion string, convert Func) {
+	resource := scheme.resourceFor(etcdKey)
+	if resource == nil {
+		panic(fmt.Sprintf("conversion for unknown resource %s", etcdKey))
+	}
+
+	resource.conversions[fromVersion] = conversion{toVersion: toVersion, convert: convert}
+}
+
+// StorageVersion returns the storage version of the objects under the etcd prefix
+func (scheme *Scheme) StorageVersion(etcdKey string) (string, bool) {
+	resource := scheme.resourceFor(etcdKey)
+	if resource == nil {
+		return "", false
+	}
+
+	return resource.storageVersion, true
+}
+
+// EtcdKeys returns the etcd prefixes of the resources of the scheme
+func (scheme *Scheme) EtcdKeys() []string {
+	keys := make([]string, 0, len(scheme.resources))
+	for _, resource := range scheme.resources {
+		keys = append(keys, resource.etcdKey)
+	}
+
+	return keys
+}
+
+func (scheme *Scheme) resourceFor(key string) *resource {
+	for _, resource := range scheme.resources {
+		if key == resource.etcdKey || strings.HasPrefix(key, resource.etcdKey+"/") {
+			return resource
+		}
+	}
+
+	return nil
+}
+
+type typeMeta struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+}
+
+func decode(value []byte) (Object, error) {
+	decoder := json.NewDecoder(bytes.NewReader(value))
+	decoder.UseNumber()
+
+	object := Object{}
+	if err := decoder.Decode(&object); err != nil {
+		return nil, err
+	}
+
+	return object, nil
+}
+
+// ToStorage sets the storage version and the kind of the resource of the key on the object, the objects written by
+// the api are always of the types of the storage version
+func (scheme *Scheme) ToStorage(key string, value []byte) ([]byte, error) {
+	resource := scheme.resourceFor(key)
+	if resource == nil {
+		return value, nil
+	}
+
+	meta := typeMeta{}
+	if err := json.Unmarshal(value, &meta); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %v", key, err)
+	}
+
+	if meta.APIVersion == resource.storageVersion && meta.Kind == resource.kind {
+		return value, nil
+	}
+
+	object, err := decode(value)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s: %v", key, err)
+	}
+
+	object["apiVersion"] = resource.storageVersion
+	object["kind"] = resource.kind
+
+	return json.Marshal(object)
+}
+
+// FromStorage converts the stored object of the key to the storage version of its resource, and reports whether
+// the stored object was in an older version
+func (scheme *Scheme) FromStorage(key string, value []byte) ([]byte, bool, error) {
+	resource := scheme.resourceFor(key)
+	if resource == nil {
+		return value, false, nil
+	}
+
+	meta := typeMeta{}
+	if err := json.Unmarshal(value, &meta); err != nil {
+		return nil, false, fmt.Errorf("error parsing %s: %v", key, err)
+	}
+
+	if meta.APIVersion == resource.storageVersion {
+		return value, false, nil
+	}
+
+	object, err := decode(value)
+	if err != nil {
+		return nil, false, fmt.Errorf("error parsing %s: %v", key, err)
+	}
+
+	version := meta.APIVersion
+	for steps := 0; version != resource.storageVersion; steps++ {
+		conversion, ok := resource.conversions[version]
+		if !ok || steps == len(resource.conversions) {
+			return nil, false, fmt.Errorf("no conversion of %s from version %q to %s", key, version, resource.storageVersion)
+		}
+
+		if err = conversion.convert(object); err != nil {
+			return nil, false, fmt.Errorf("error converting %s from version %q to %s: %v", key, version, conversion.toVersion, err)
+		}
+
+		version = conversion.toVersion
+	}
+
+	object["apiVersion"] = resource.storageVersion
+	object["kind"] = resource.kind
+
+	converted, err := json.Marshal(object)
+	if err != nil {
+		return nil, false, fmt.Errorf("error parsing %s: %v", key, err)
+	}
+
+	return converted, true, nil
+}
@@ -0,0 +1,214 @@
+package conversion
+
+import (
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/etcd"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// etcdService writes the objects with the storage version of their resource and converts the objects stored with
+// older versions when they are read, everything above it only sees objects of the storage version
+type etcdService struct {
+	etcd.EtcdService
+
+	scheme *Scheme
+}
+
+func NewEtcdService(service etcd.EtcdService, scheme *Scheme) etcd.EtcdService {
+	return &etcdService{
+		EtcdService: service,
+		scheme:      scheme,
+	}
+}
+
+func (service *etcdService) fromStorage(key string, value []byte) ([]byte, error) {
+	converted, _, err := service.scheme.FromStorage(key, value)
+
+	return converted, err
+}
+
+func (service *etcdService) GetResource(key string) ([]byte, error) {
+	value, err := service.EtcdService.GetResource(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return service.fromStorage(key, value)
+}
+
+func (service *etcdService) GetResourceWithRevision(key string) ([]byte, int64, error) {
+	value, revision, err := service.EtcdService.GetResourceWithRevision(key)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	converted, err := service.fromStorage(key, value)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return converted, revision, nil
+}
+
+// GetAllFromResource goes through ListResource since the etcd key of every value is needed to convert it
+func (service *etcdService) GetAllFromResource(key string) ([][]byte, error) {
+	values, _, err := service.ListResource(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(values) == 0 {
+		return nil, fmt.Errorf("key not found for: %s", key)
+	}
+
+	keys := make([]string, 0, len(values))
+	for valueKey := range values {
+		keys = append(keys, valueKey)
+	}
+	sort.Strings(keys)
+
+	result := make([][]byte, 0, len(keys))
+	for _, valueKey := range keys {
+		result = append(result, values[valueKey])
+	}
+
+	return result, nil
+}
+
+func (service *etcdService) PutResource(key string, value string) error {
+	stored, err := service.scheme.ToStorage(key, []byte(value))
+	if err != nil {
+		return err
+	}
+
+	return service.EtcdService.PutResource(key, string(stored))
+}
+
+func (service *etcdService) PutResourceIfRevision(key string, value string, revision int64) error {
+	stored, err := service.scheme.ToStorage(key, []byte(value))
+	if err != nil {
+		return err
+	}
+
+	return service.EtcdService.PutResourceIfRevision(key, string(stored), revision)
+}
+
+func (service *etcdService) ListResource(key string) (map[string][]byte, int64, error) {
+	values, revision, err := service.EtcdService.ListResource(key)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for valueKey, value := range values {
+		converted, err := service.fromStorage(valueKey, value)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		values[valueKey] = converted
+	}
+
+	return values, revision, nil
+}
+
+// GetWatchChannel converts the objects of the events of the watch, an object that can not be converted ends the
+// watch so the watcher relists and gets the error
+func (service *etcdService) GetWatchChannel(key string, revision int64) (clientv3.WatchChan, func(), error) {
+	watchChan, closeWatch, err := service.EtcdService.GetWatchChannel(key, revision)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	convertedChan := make(chan clientv3.WatchResponse)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(convertedChan)
+
+		for watchResp := range watchChan {
+			convertedResp, err := service.convertWatchResponse(watchResp)
+			if err != nil {
+				log.Printf("ending watch on %s: %v", key, err)
+
+				return
+			}
+
+			select {
+			case convertedChan <- convertedResp:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	closeChan := func() {
+		close(done)
+		closeWatch()
+	}
+
+	return convertedChan, closeChan, nil
+}
+
+func (service *etcdService) convertWatchResponse(watchResp clientv3.WatchResponse) (clientv3.WatchResponse, error) {
+	events := make([]*clientv3.Event, 0, len(watchResp.Events))
+
+	for _, event := range watchResp.Events {
+		convertedEvent := *event
+
+		if event.Kv != nil && len(event.Kv.Value) > 0 {
+			kv := *event.Kv
+
+			value, err := service.fromStorage(string(kv.Key), kv.Value)
+			if err != nil {
+				return watchResp, err
+			}
+
+			kv.Value = value
+			convertedEvent.Kv = &kv
+		}
+
+		events = append(events, &convertedEvent)
+	}
+
+	watchResp.Events = events
+
+	return watchResp, nil
+}
+
+// Migrate rewrites every object stored with an older version than the storage version of its resource, after a
+// resource changed version this moves all its objects to the new version so the old conversions can be removed.
+// service is the etcd service below the conversion, the stored objects are read as is
+func Migrate(service etcd.EtcdService, scheme *Scheme) error {
+	for _, etcdKey := range scheme.EtcdKeys() {
+		values, _, err := service.ListResource(etcdKey + "/")
+		if err != nil {
+			return fmt.Errorf("error listing %s: %v", etcdKey, err)
+		}
+
+		migrated := 0
+		for key, value := range values {
+			converted, stale, err := scheme.FromStorage(key, value)
+			if err != nil {
+				return err
+			}
+
+			if !stale {
+				continue
+			}
+
+			if err := service.PutResource(key, string(converted)); err != nil {
+				return fmt.Errorf("error rewriting %s: %v", key, err)
+			}
+
+			migrated++
+		}
+
+		storageVersion, _ := scheme.StorageVersion(etcdKey)
+		log.Printf("migrated %d of %d objects of %s to %s", migrated, len(values), etcdKey, storageVersion)
+	}
+
+	return nil
+}
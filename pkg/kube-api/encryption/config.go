@@ -0,0 +1,168 @@
+package encryption
+
+import (
+	"encoding/base64"
+	"fmt"
+	"log"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+const configurationKind = "EncryptionConfiguration"
+
+// Configuration is the encryption provider config file, the same format as the kubernetes EncryptionConfiguration:
+//
+//	kind: EncryptionConfiguration
+//	resources:
+//	  - resources: [secrets]
+//	    providers:
+//	      - aesgcm:
+//	          keys:
+//	            - name: key1
+//	              secret: <base64 encoded 16, 24 or 32 byte key>
+//	      - identity: {}
+type Configuration struct {
+	Kind       string                  `json:"kind" yaml:"kind"`
+	APIVersion string                  `json:"apiVersion" yaml:"apiVersion"`
+	Resources  []ResourceConfiguration `json:"resources" yaml:"resources"`
+}
+
+type ResourceConfiguration struct {
+	// Resources are the plural names of the resources, for example secrets
+	Resources []string `json:"resources" yaml:"resources"`
+	// Providers are in order, the first one encrypts new writes and all of them are tried on reads
+	Providers []ProviderConfiguration `json:"providers" yaml:"providers"`
+}
+
+type ProviderConfiguration struct {
+	AESGCM   *KeysConfiguration `json:"aesgcm" yaml:"aesgcm"`
+	AESCBC   *KeysConfiguration `json:"aescbc" yaml:"aescbc"`
+	Identity *struct{}          `json:"identity" yaml:"identity"`
+}
+
+type KeysConfiguration struct {
+	// Keys are in order, the first one encrypts new writes
+	Keys []Key `json:"keys" yaml:"keys"`
+}
+
+type Key struct {
+	Name   string `json:"name" yaml:"name"`
+	Secret string `json:"secret" yaml:"secret"`
+}
+
+// LoadConfig reads the encryption config file and returns the transformer of every resource by its etcd prefix
+func LoadConfig(path string) (map[string]Transformer, error) {
+	log.Printf("loading encryption provider config %s", path)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading encryption provider config: %v", err)
+	}
+
+	var config Configuration
+	if err = yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("error parsing encryption provider config: %v", err)
+	}
+
+	return buildTransformers(config)
+}
+
+func buildTransformers(config Configuration) (map[string]Transformer, error) {
+	if config.Kind != configurationKind {
+		return nil, fmt.Errorf("encryption provider config kind must be %s, got %q", configurationKind, config.Kind)
+	}
+
+	transformers := make(map[string]Transformer)
+
+	for _, resourceConfig := range config.Resources {
+		if len(resourceConfig.Providers) == 0 {
+			return nil, fmt.Errorf("no providers for resources %v", resourceConfig.Resources)
+		}
+
+		var chain []prefixTransformer
+		for _, providerConfig := range resourceConfig.Providers {
+			providerTransformers, err := buildProvider(providerConfig)
+			if err != nil {
+				return nil, fmt.Errorf("error in providers of resources %v: %v", resourceConfig.Resources, err)
+			}
+
+			chain = append(chain, providerTransformers...)
+		}
+
+		for _, resource := range resourceConfig.Resources {
+			etcdKey := fmt.Sprintf("/%s", resource)
+
+			if _, ok := transformers[etcdKey]; ok {
+				return nil, fmt.Errorf("resource %s is configured more than once", resource)
+			}
+
+			transformers[etcdKey] = &prefixTransformers{transformers: chain}
+		}
+	}
+
+	return transformers, nil
+}
+
+func buildProvider(providerConfig ProviderConfiguration) ([]prefixTransformer, error) {
+	configured := 0
+	for _, provider := range []bool{providerConfig.AESGCM != nil, providerConfig.AESCBC != nil, providerConfig.Identity != nil} {
+		if provider {
+			configured++
+		}
+	}
+
+	if configured != 1 {
+		return nil, fmt.Errorf("every provider must have exactly one of %s, %s or %s", aesGCMProviderName, aesCBCProviderName, identityProviderName)
+	}
+
+	switch {
+	case providerConfig.AESGCM != nil:
+		return buildKeys(aesGCMProviderName, providerConfig.AESGCM.Keys, newAESGCMTransformer)
+	case providerConfig.AESCBC != nil:
+		return buildKeys(aesCBCProviderName, providerConfig.AESCBC.Keys, newAESCBCTransformer)
+	default:
+		return []prefixTransformer{{prefix: []byte{}, transformer: identityTransformer{}}}, nil
+	}
+}
+
+func buildKeys(providerName string, keys []Key, newTransformer func([]byte) (Transformer, error)) ([]prefixTransformer, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("provider %s has no keys", providerName)
+	}
+
+	names := make(map[string]bool)
+	transformers := make([]prefixTransformer, 0, len(keys))
+
+	for _, key := range keys {
+		if key.Name == "" {
+			return nil, fmt.Errorf("provider %s has a key without a name", providerName)
+		}
+
+		if names[key.Name] {
+			return nil, fmt.Errorf("provider %s has key %s more than once", providerName, key.Name)
+		}
+		names[key.Name] = true
+
+		secret, err := base64.StdEncoding.DecodeString(key.Secret)
+		if err != nil {
+			return nil, fmt.Errorf("key %s of provider %s is not base64: %v", key.Name, providerName, err)
+		}
+
+		if len(secret) != 16 && len(secret) != 24 && len(secret) != 32 {
+			return nil, fmt.Errorf("key %s of provider %s must be 16, 24 or 32 bytes, got %d", key.Name, providerName, len(secret))
+		}
+
+		transformer, err := newTransformer(secret)
+		if err != nil {
+			return nil, fmt.Errorf("error creating key %s of provider %s: %v", key.Name, providerName, err)
+		}
+
+		transformers = append(transformers, prefixTransformer{
+			prefix:      []byte(fmt.Sprintf(encryptedValuePrefix, providerName, key.Name)),
+			transformer: transformer,
+		})
+	}
+
+	return transformers, nil
+}
@@ -0,0 +1,248 @@
+package encryption
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/etcd"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// etcdService encrypts the values of the configured resources before they reach etcd and decrypts them on the way
+// back, everything above it only sees plain values
+type etcdService struct {
+	etcd.EtcdService
+
+	// transformers are by the etcd prefix of the resource
+	transformers map[string]Transformer
+}
+
+func NewEtcdService(service etcd.EtcdService, transformers map[string]Transformer) etcd.EtcdService {
+	return &etcdService{
+		EtcdService:  service,
+		transformers: transformers,
+	}
+}
+
+func (service *etcdService) transformerFor(key string) Transformer {
+	for prefix, transformer := range service.transformers {
+		if key == prefix || strings.HasPrefix(key, prefix+"/") {
+			return transformer
+		}
+	}
+
+	return nil
+}
+
+func (service *etcdService) fromStorage(key string, value []byte) ([]byte, error) {
+	transformer := service.transformerFor(key)
+	if transformer == nil {
+		return value, nil
+	}
+
+	plain, _, err := transformer.TransformFromStorage(value, key)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting %s: %v", key, err)
+	}
+
+	return plain, nil
+}
+
+func (service *etcdService) GetResource(key string) ([]byte, error) {
+	value, err := service.EtcdService.GetResource(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return service.fromStorage(key, value)
+}
+
+func (service *etcdService) GetResourceWithRevision(key string) ([]byte, int64, error) {
+	value, revision, err := service.EtcdService.GetResourceWithRevision(key)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	plain, err := service.fromStorage(key, value)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return plain, revision, nil
+}
+
+// GetAllFromResource goes through ListResource since the etcd key of every value is needed to decrypt it
+func (service *etcdService) GetAllFromResource(key string) ([][]byte, error) {
+	values, _, err := service.ListResource(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(values) == 0 {
+		return nil, fmt.Errorf("key not found for: %s", key)
+	}
+
+	keys := make([]string, 0, len(values))
+	for valueKey := range values {
+		keys = append(keys, valueKey)
+	}
+	sort.Strings(keys)
+
+	result := make([][]byte, 0, len(keys))
+	for _, valueKey := range keys {
+		result = append(result, values[valueKey])
+	}
+
+	return result, nil
+}
+
+func (service *etcdService) PutResource(key string, value string) error {
+	transformer := service.transformerFor(key)
+	if transformer == nil {
+		return service.EtcdService.PutResource(key, value)
+	}
+
+	stored, err := transformer.TransformToStorage([]byte(value), key)
+	if err != nil {
+		return fmt.Errorf("error encrypting %s: %v", key, err)
+	}
+
+	return service.EtcdService.PutResource(key, string(stored))
+}
+
+func (service *etcdService) PutResourceIfRevision(key string, value string, revision int64) error {
+	transformer := service.transformerFor(key)
+	if transformer == nil {
+		return service.EtcdService.PutResourceIfRevision(key, value, revision)
+	}
+
+	stored, err := transformer.TransformToStorage([]byte(value), key)
+	if err != nil {
+		return fmt.Errorf("error encrypting %s: %v", key, err)
+	}
+
+	return service.EtcdService.PutResourceIfRevision(key, string(stored), revision)
+}
+
+func (service *etcdService) ListResource(key string) (map[string][]byte, int64, error) {
+	values, revision, err := service.EtcdService.ListResource(key)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for valueKey, value := range values {
+		plain, err := service.fromStorage(valueKey, value)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		values[valueKey] = plain
+	}
+
+	return values, revision, nil
+}
+
+// GetWatchChannel decrypts the events of the watch, a value that can not be decrypted ends the watch
+// so the watcher relists and gets the error
+func (service *etcdService) GetWatchChannel(key string, revision int64) (clientv3.WatchChan, func(), error) {
+	watchChan, closeWatch, err := service.EtcdService.GetWatchChannel(key, revision)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	decryptedChan := make(chan clientv3.WatchResponse)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(decryptedChan)
+
+		for watchResp := range watchChan {
+			decryptedResp, err := service.decryptWatchResponse(watchResp)
+			if err != nil {
+				log.Printf("ending watch on %s: %v", key, err)
+
+				return
+			}
+
+			select {
+			case decryptedChan <- decryptedResp:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	closeChan := func() {
+		close(done)
+		closeWatch()
+	}
+
+	return decryptedChan, closeChan, nil
+}
+
+func (service *etcdService) decryptWatchResponse(watchResp clientv3.WatchResponse) (clientv3.WatchResponse, error) {
+	events := make([]*clientv3.Event, 0, len(watchResp.Events))
+
+	for _, event := range watchResp.Events {
+		decryptedEvent := *event
+
+		if event.Kv != nil && len(event.Kv.Value) > 0 {
+			kv := *event.Kv
+
+			value, err := service.fromStorage(string(kv.Key), kv.Value)
+			if err != nil {
+				return watchResp, err
+			}
+
+			kv.Value = value
+			decryptedEvent.Kv = &kv
+		}
+
+		events = append(events, &decryptedEvent)
+	}
+
+	watchResp.Events = events
+
+	return watchResp, nil
+}
+
+// Rewrite writes back every object of the encrypted resources that is not stored with the current write key,
+// after a key rotation this moves all the objects to the new key so the old key can be removed from the config.
+// service is the etcd service without encryption, the stored values are read as is
+func Rewrite(service etcd.EtcdService, transformers map[string]Transformer) error {
+	for prefix, transformer := range transformers {
+		values, _, err := service.ListResource(prefix + "/")
+		if err != nil {
+			return fmt.Errorf("error listing %s: %v", prefix, err)
+		}
+
+		rewritten := 0
+		for key, value := range values {
+			plain, stale, err := transformer.TransformFromStorage(value, key)
+			if err != nil {
+				return fmt.Errorf("error decrypting %s: %v", key, err)
+			}
+
+			if !stale {
+				continue
+			}
+
+			stored, err := transformer.TransformToStorage(plain, key)
+			if err != nil {
+				return fmt.Errorf("error encrypting %s: %v", key, err)
+			}
+
+			if err := service.PutResource(key, string(stored)); err != nil {
+				return fmt.Errorf("error rewriting %s: %v", key, err)
+			}
+
+			rewritten++
+		}
+
+		log.Printf("rewrote %d of %d objects of %s", rewritten, len(values), prefix)
+	}
+
+	return nil
+}
@@ -0,0 +1,186 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+	"io"
+)
+
+const (
+	aesGCMProviderName   = "aesgcm"
+	aesCBCProviderName   = "aescbc"
+	identityProviderName = "identity"
+
+	// same prefix as kubernetes, k8s:enc:<provider>:v1:<key name>:
+	encryptedValuePrefix = "k8s:enc:%s:v1:%s:"
+)
+
+// Transformer converts a value between its stored form and its plain form, key is the etcd key of the value
+type Transformer interface {
+	TransformToStorage(data []byte, key string) ([]byte, error)
+	// TransformFromStorage also returns stale when the value was not written with the current write key,
+	// so it should be rewritten
+	TransformFromStorage(data []byte, key string) ([]byte, bool, error)
+}
+
+type identityTransformer struct{}
+
+func (identityTransformer) TransformToStorage(data []byte, _ string) ([]byte, error) {
+	return data, nil
+}
+
+func (identityTransformer) TransformFromStorage(data []byte, _ string) ([]byte, bool, error) {
+	return data, false, nil
+}
+
+// aesGCMTransformer stores nonce + ciphertext, the etcd key is authenticated so a value can not be moved to another key
+type aesGCMTransformer struct {
+	aead cipher.AEAD
+}
+
+func newAESGCMTransformer(secret []byte) (Transformer, error) {
+	block, err := aes.NewCipher(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &aesGCMTransformer{aead: aead}, nil
+}
+
+func (transformer *aesGCMTransformer) TransformToStorage(data []byte, key string) ([]byte, error) {
+	nonce := make([]byte, transformer.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("error generating nonce: %v", err)
+	}
+
+	return transformer.aead.Seal(nonce, nonce, data, []byte(key)), nil
+}
+
+func (transformer *aesGCMTransformer) TransformFromStorage(data []byte, key string) ([]byte, bool, error) {
+	nonceSize := transformer.aead.NonceSize()
+	if len(data) < nonceSize {
+		return nil, false, fmt.Errorf("encrypted value is too short")
+	}
+
+	plain, err := transformer.aead.Open(nil, data[:nonceSize], data[nonceSize:], []byte(key))
+	if err != nil {
+		return nil, false, fmt.Errorf("error decrypting value: %v", err)
+	}
+
+	return plain, false, nil
+}
+
+// aesCBCTransformer stores iv + ciphertext with PKCS#7 padding
+type aesCBCTransformer struct {
+	block cipher.Block
+}
+
+func newAESCBCTransformer(secret []byte) (Transformer, error) {
+	block, err := aes.NewCipher(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &aesCBCTransformer{block: block}, nil
+}
+
+func (transformer *aesCBCTransformer) TransformToStorage(data []byte, _ string) ([]byte, error) {
+	blockSize := transformer.block.BlockSize()
+	paddingSize := blockSize - len(data)%blockSize
+
+	result := make([]byte, blockSize+len(data)+paddingSize)
+	iv := result[:blockSize]
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return nil, fmt.Errorf("error generating iv: %v", err)
+	}
+
+	plain := result[blockSize:]
+	copy(plain, data)
+	copy(plain[len(data):], bytes.Repeat([]byte{byte(paddingSize)}, paddingSize))
+
+	cipher.NewCBCEncrypter(transformer.block, iv).CryptBlocks(plain, plain)
+
+	return result, nil
+}
+
+func (transformer *aesCBCTransformer) TransformFromStorage(data []byte, _ string) ([]byte, bool, error) {
+	blockSize := transformer.block.BlockSize()
+	if len(data) < 2*blockSize || len(data)%blockSize != 0 {
+		return nil, false, fmt.Errorf("encrypted value has an invalid length")
+	}
+
+	plain := make([]byte, len(data)-blockSize)
+	cipher.NewCBCDecrypter(transformer.block, data[:blockSize]).CryptBlocks(plain, data[blockSize:])
+
+	paddingSize := int(plain[len(plain)-1])
+	if paddingSize == 0 || paddingSize > blockSize {
+		return nil, false, fmt.Errorf("error decrypting value: invalid padding")
+	}
+
+	for _, paddingByte := range plain[len(plain)-paddingSize:] {
+		if int(paddingByte) != paddingSize {
+			return nil, false, fmt.Errorf("error decrypting value: invalid padding")
+		}
+	}
+
+	return plain[:len(plain)-paddingSize], false, nil
+}
+
+type prefixTransformer struct {
+	prefix      []byte
+	transformer Transformer
+}
+
+// prefixTransformers writes with the first transformer and reads with the one matching the prefix of the value,
+// a value with no known prefix is read as plain text only when the identity provider is in the chain
+type prefixTransformers struct {
+	transformers []prefixTransformer
+}
+
+func (chain *prefixTransformers) TransformToStorage(data []byte, key string) ([]byte, error) {
+	first := chain.transformers[0]
+
+	stored, err := first.transformer.TransformToStorage(data, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(append([]byte{}, first.prefix...), stored...), nil
+}
+
+func (chain *prefixTransformers) TransformFromStorage(data []byte, key string) ([]byte, bool, error) {
+	identityIndex := -1
+
+	for index, current := range chain.transformers {
+		if len(current.prefix) == 0 {
+			identityIndex = index
+
+			continue
+		}
+
+		if !bytes.HasPrefix(data, current.prefix) {
+			continue
+		}
+
+		plain, stale, err := current.transformer.TransformFromStorage(data[len(current.prefix):], key)
+		if err != nil {
+			return nil, false, err
+		}
+
+		return plain, stale || index != 0, nil
+	}
+
+	if identityIndex == -1 {
+		return nil, false, fmt.Errorf("no provider matches the stored value of %s", key)
+	}
+
+	return data, identityIndex != 0, nil
+}
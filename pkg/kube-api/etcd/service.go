@@ -2,24 +2,43 @@ package etcd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/metrics"
 	clientv3 "go.etcd.io/etcd/client/v3"
 )
 
+// ErrRevisionConflict is returned by PutResourceIfRevision when the key was changed since it was read
+var ErrRevisionConflict = errors.New("resource was modified since it was read")
+
 type EtcdService interface {
 	GetResource(string) ([]byte, error)
+	GetResourceWithRevision(string) ([]byte, int64, error)
 	GetAllFromResource(string) ([][]byte, error)
 	PutResource(string, string) error
+	PutResourceIfRevision(string, string, int64) error
 	DeleteResource(string) error
-	GetWatchChannel(string) (clientv3.WatchChan, func(), error)
+	ListResource(string) (map[string][]byte, int64, error)
+	GetWatchChannel(string, int64) (clientv3.WatchChan, func(), error)
+	Ping(context.Context) error
+	CountResource(string) (int64, error)
+	Status(context.Context) (*clientv3.StatusResponse, error)
+	Snapshot(context.Context) (io.ReadCloser, error)
+	Close() error
 }
 
 type EtcdServiceApp struct {
 	endpoints string
+
+	clientMutex sync.Mutex
+	client      *clientv3.Client
 }
 
 func NewEtcdService(endpoint string) EtcdService {
@@ -28,24 +47,66 @@ func NewEtcdService(endpoint string) EtcdService {
 	}
 }
 
-func connect() (*clientv3.Client, error) {
+func observe(operation string, key string, start time.Time, err *error) {
+	requestErr := *err
+	if requestErr != nil && strings.Contains(requestErr.Error(), "key not found") {
+		// missing key is a valid answer from etcd and not a failed request
+		requestErr = nil
+	}
+
+	metrics.ObserveEtcdRequest(operation, key, start, requestErr)
+}
+
+// connect returns the etcd client shared by all the requests, the client is created on first use
+func (app *EtcdServiceApp) connect() (*clientv3.Client, error) {
+	app.clientMutex.Lock()
+	defer app.clientMutex.Unlock()
+
+	if app.client != nil {
+		return app.client, nil
+	}
+
+	endpoints := strings.Split(app.endpoints, ",")
+	if etcdEndpoint := os.Getenv("ETCD_ENDPOINT"); etcdEndpoint != "" {
+		endpoints = []string{fmt.Sprintf("%s:2379", etcdEndpoint)}
+	}
+
 	cli, err := clientv3.New(clientv3.Config{
-		Endpoints:   []string{fmt.Sprintf("%s:2379", os.Getenv("ETCD_ENDPOINT"))},
+		Endpoints:   endpoints,
 		DialTimeout: 5 * time.Second,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("error connecting to etcd: %v", err)
 	}
 
+	app.client = cli
+
 	return cli, nil
 }
 
-func (app *EtcdServiceApp) GetResource(key string) ([]byte, error) {
-	cli, err := connect()
+func (app *EtcdServiceApp) Close() error {
+	app.clientMutex.Lock()
+	defer app.clientMutex.Unlock()
+
+	if app.client == nil {
+		return nil
+	}
+
+	log.Println("closing etcd client")
+
+	err := app.client.Close()
+	app.client = nil
+
+	return err
+}
+
+func (app *EtcdServiceApp) GetResource(key string) (_ []byte, err error) {
+	defer observe("get", key, time.Now(), &err)
+
+	cli, err := app.connect()
 	if err != nil {
 		return nil, err
 	}
-	defer cli.Close()
 
 	// ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	resp, err := cli.Get(context.Background(), key)
@@ -61,12 +122,37 @@ func (app *EtcdServiceApp) GetResource(key string) ([]byte, error) {
 	return resp.Kvs[0].Value, nil
 }
 
-func (app *EtcdServiceApp) GetAllFromResource(key string) ([][]byte, error) {
-	cli, err := connect()
+// GetResourceWithRevision returns the value with the revision it was last modified at,
+// the revision is passed to PutResourceIfRevision to write the key only if it was not changed since
+func (app *EtcdServiceApp) GetResourceWithRevision(key string) (_ []byte, _ int64, err error) {
+	defer observe("get", key, time.Now(), &err)
+
+	cli, err := app.connect()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := cli.Get(ctx, key)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get: %v", err)
+	}
+
+	if len(resp.Kvs) == 0 {
+		return nil, 0, fmt.Errorf("key not found for: %s", key)
+	}
+
+	return resp.Kvs[0].Value, resp.Kvs[0].ModRevision, nil
+}
+
+func (app *EtcdServiceApp) GetAllFromResource(key string) (_ [][]byte, err error) {
+	defer observe("list", key, time.Now(), &err)
+	cli, err := app.connect()
 	if err != nil {
 		return nil, err
 	}
-	defer cli.Close()
 
 	// ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	resp, err := cli.Get(context.Background(), key, clientv3.WithPrefix())
@@ -87,12 +173,12 @@ func (app *EtcdServiceApp) GetAllFromResource(key string) ([][]byte, error) {
 	return values, nil
 }
 
-func (app *EtcdServiceApp) PutResource(key string, value string) error {
-	cli, err := connect()
+func (app *EtcdServiceApp) PutResource(key string, value string) (err error) {
+	defer observe("put", key, time.Now(), &err)
+	cli, err := app.connect()
 	if err != nil {
 		return err
 	}
-	defer cli.Close()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	_, err = cli.Put(ctx, key, value)
@@ -105,12 +191,39 @@ func (app *EtcdServiceApp) PutResource(key string, value string) error {
 	return nil
 }
 
-func (app *EtcdServiceApp) DeleteResource(key string) error {
-	cli, err := connect()
+// PutResourceIfRevision writes the key in a transaction that succeeds only when the key is still at the revision,
+// a revision of 0 writes the key only if it does not exist
+func (app *EtcdServiceApp) PutResourceIfRevision(key string, value string, revision int64) (err error) {
+	defer observe("put", key, time.Now(), &err)
+	cli, err := app.connect()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := cli.Txn(ctx).
+		If(clientv3.Compare(clientv3.ModRevision(key), "=", revision)).
+		Then(clientv3.OpPut(key, value)).
+		Commit()
+	if err != nil {
+		return fmt.Errorf("failed to put: %v", err)
+	}
+
+	if !resp.Succeeded {
+		return fmt.Errorf("failed to put %s: %w", key, ErrRevisionConflict)
+	}
+
+	return nil
+}
+
+func (app *EtcdServiceApp) DeleteResource(key string) (err error) {
+	defer observe("delete", key, time.Now(), &err)
+	cli, err := app.connect()
 	if err != nil {
 		return err
 	}
-	defer cli.Close()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	_, err = cli.Delete(ctx, key)
@@ -123,19 +236,123 @@ func (app *EtcdServiceApp) DeleteResource(key string) error {
 	return nil
 }
 
-func (app *EtcdServiceApp) GetWatchChannel(key string) (clientv3.WatchChan, func(), error) {
-	cli, err := connect()
+// ListResource returns all the keys and values under the prefix with the etcd revision they were read at,
+// unlike GetAllFromResource an empty prefix is not an error
+func (app *EtcdServiceApp) ListResource(key string) (_ map[string][]byte, _ int64, err error) {
+	defer observe("list", key, time.Now(), &err)
+	cli, err := app.connect()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := cli.Get(ctx, key, clientv3.WithPrefix())
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list: %v", err)
+	}
+
+	values := make(map[string][]byte, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		values[string(kv.Key)] = kv.Value
+	}
+
+	return values, resp.Header.Revision, nil
+}
+
+// GetWatchChannel watches all the keys under the prefix,
+// when revision is not 0 the watch starts from it instead of the current revision
+func (app *EtcdServiceApp) GetWatchChannel(key string, revision int64) (_ clientv3.WatchChan, _ func(), err error) {
+	defer observe("watch", key, time.Now(), &err)
+	cli, err := app.connect()
 	if err != nil {
 		return nil, nil, err
 	}
 
-	watchChan := cli.Watch(context.Background(), key, clientv3.WithPrefix())
+	options := []clientv3.OpOption{clientv3.WithPrefix()}
+	if revision != 0 {
+		options = append(options, clientv3.WithRev(revision))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	watchChan := cli.Watch(ctx, key, options...)
 
 	closeChan := func() {
 		log.Printf("closing watch channel %s", key)
 
-		cli.Close()
+		cancel()
 	}
 
 	return watchChan, closeChan, nil
 }
+
+func (app *EtcdServiceApp) Ping(ctx context.Context) (err error) {
+	defer observe("ping", "/health", time.Now(), &err)
+	cli, err := app.connect()
+	if err != nil {
+		return err
+	}
+
+	// same as etcd own health check, a get on the health key is enough to know the cluster has a leader
+	_, err = cli.Get(ctx, "health")
+	if err != nil {
+		return fmt.Errorf("etcd unreachable: %v", err)
+	}
+
+	return nil
+}
+
+func (app *EtcdServiceApp) CountResource(key string) (_ int64, err error) {
+	defer observe("count", key, time.Now(), &err)
+
+	cli, err := app.connect()
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := cli.Get(ctx, key, clientv3.WithPrefix(), clientv3.WithCountOnly())
+	if err != nil {
+		return 0, fmt.Errorf("failed to count: %v", err)
+	}
+
+	return resp.Count, nil
+}
+
+// Status returns the status of the first etcd endpoint, with its version and current revision
+func (app *EtcdServiceApp) Status(ctx context.Context) (_ *clientv3.StatusResponse, err error) {
+	defer observe("status", "/", time.Now(), &err)
+
+	cli, err := app.connect()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := cli.Status(ctx, cli.Endpoints()[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status: %v", err)
+	}
+
+	return resp, nil
+}
+
+// Snapshot streams a snapshot of the whole etcd database, the stream ends when the snapshot is complete or the
+// context is done
+func (app *EtcdServiceApp) Snapshot(ctx context.Context) (_ io.ReadCloser, err error) {
+	defer observe("snapshot", "/", time.Now(), &err)
+
+	cli, err := app.connect()
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot, err := cli.Snapshot(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to snapshot: %v", err)
+	}
+
+	return snapshot, nil
+}
@@ -0,0 +1,146 @@
+package flowcontrol
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	restful "github.com/emicklei/go-restful/v3"
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/metrics"
+)
+
+const (
+	DefaultMaxRequestsInflight         = 400
+	DefaultMaxMutatingRequestsInflight = 200
+
+	defaultQueueWait      = 15 * time.Second
+	defaultQueueLength    = 50
+	defaultRetryAfter     = 1
+	readOnlyRequestKind   = "readOnly"
+	mutatingRequestKind   = "mutating"
+	systemPriorityLevel   = "system"
+	workloadPriorityLevel = "workload"
+)
+
+var (
+	// systemUserAgents are the cluster components, they get a bigger share of the seats than users
+	systemUserAgents = []string{"kubelet", "kube-proxy", "kube-scheduler", "kube-controller-manager"}
+
+	// exemptPaths are never throttled so the server can always be probed and scraped
+	exemptPaths = []string{"/livez", "/readyz", "/health", "/metrics"}
+)
+
+type Options struct {
+	MaxRequestsInflight         int
+	MaxMutatingRequestsInflight int
+	// QueueWait is how long a request waits for a seat before it is rejected
+	QueueWait time.Duration
+	// QueueLength is the number of requests a single client can have waiting
+	QueueLength int
+}
+
+// Controller limits the number of requests served at once, requests over the limit wait in a queue per client
+// and are served round robin between the clients, so one busy client can not starve the others
+type Controller struct {
+	readOnly *pool
+	mutating *pool
+}
+
+func NewController(options Options) *Controller {
+	if options.QueueWait == 0 {
+		options.QueueWait = defaultQueueWait
+	}
+
+	if options.QueueLength == 0 {
+		options.QueueLength = defaultQueueLength
+	}
+
+	return &Controller{
+		readOnly: newPool(readOnlyRequestKind, options.MaxRequestsInflight, options.QueueLength, options.QueueWait),
+		mutating: newPool(mutatingRequestKind, options.MaxMutatingRequestsInflight, options.QueueLength, options.QueueWait),
+	}
+}
+
+// Filter is a container filter, it has to run before any other filter so rejected requests cost nothing
+func (controller *Controller) Filter(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
+	if isExempt(req.Request) {
+		chain.ProcessFilter(req, resp)
+
+		return
+	}
+
+	requestPool := controller.readOnly
+	if isMutating(req.Request) {
+		requestPool = controller.mutating
+	}
+
+	level := priorityLevel(req.Request)
+	flow := flowKey(req.Request)
+
+	if err := requestPool.acquire(level, flow, req.Request.Context().Done()); err != nil {
+		log.Printf("rejecting %s %s from %s: %v", req.Request.Method, req.Request.URL.Path, flow, err)
+
+		metrics.RequestDropped(requestPool.kind)
+
+		resp.Header().Set("Retry-After", strconv.Itoa(defaultRetryAfter))
+		err = resp.WriteErrorString(http.StatusTooManyRequests, fmt.Sprintf("too many requests, please try again later: %v", err))
+		if err != nil {
+			log.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+	defer requestPool.release()
+
+	chain.ProcessFilter(req, resp)
+}
+
+func isExempt(req *http.Request) bool {
+	// watches are long running and would hold a seat until the client disconnects
+	if req.Method == http.MethodGet && req.URL.Query().Get("watch") == "true" {
+		return true
+	}
+
+	for _, path := range exemptPaths {
+		if req.URL.Path == path || strings.HasPrefix(req.URL.Path, path+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isMutating(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+
+	return true
+}
+
+func priorityLevel(req *http.Request) string {
+	userAgent := strings.ToLower(req.UserAgent())
+
+	for _, systemUserAgent := range systemUserAgents {
+		if strings.HasPrefix(userAgent, systemUserAgent) {
+			return systemPriorityLevel
+		}
+	}
+
+	return workloadPriorityLevel
+}
+
+// flowKey identifies a client, there is no authentication so the user agent and the host are used instead of the user
+func flowKey(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+
+	return fmt.Sprintf("%s@%s", req.UserAgent(), host)
+}
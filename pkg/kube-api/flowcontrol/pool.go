@@ -0,0 +1,239 @@
+package flowcontrol
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/metrics"
+)
+
+// priorityShares is the weight of every priority level when both have waiting requests
+var priorityShares = map[string]int{
+	systemPriorityLevel:   2,
+	workloadPriorityLevel: 1,
+}
+
+type waiter struct {
+	ready      chan struct{}
+	dispatched bool
+}
+
+type queueSet struct {
+	name   string
+	shares int
+	// current is the weight of the level in the smooth weighted round robin between levels
+	current int
+
+	queues map[string][]*waiter
+	// flows are the flows with waiting requests, served round robin
+	flows []string
+}
+
+func (set *queueSet) waiting() int {
+	count := 0
+	for _, queue := range set.queues {
+		count += len(queue)
+	}
+
+	return count
+}
+
+func (set *queueSet) enqueue(flow string, w *waiter) {
+	if len(set.queues[flow]) == 0 {
+		set.flows = append(set.flows, flow)
+	}
+
+	set.queues[flow] = append(set.queues[flow], w)
+}
+
+func (set *queueSet) dequeue() *waiter {
+	flow := set.flows[0]
+	set.flows = set.flows[1:]
+
+	queue := set.queues[flow]
+	w := queue[0]
+
+	if len(queue) == 1 {
+		delete(set.queues, flow)
+	} else {
+		set.queues[flow] = queue[1:]
+		// the flow goes to the back of the line, so every flow gets one request in turn
+		set.flows = append(set.flows, flow)
+	}
+
+	return w
+}
+
+func (set *queueSet) remove(flow string, w *waiter) {
+	queue := set.queues[flow]
+	for index, current := range queue {
+		if current == w {
+			queue = append(queue[:index], queue[index+1:]...)
+
+			break
+		}
+	}
+
+	if len(queue) > 0 {
+		set.queues[flow] = queue
+
+		return
+	}
+
+	delete(set.queues, flow)
+	for index, current := range set.flows {
+		if current == flow {
+			set.flows = append(set.flows[:index], set.flows[index+1:]...)
+
+			break
+		}
+	}
+}
+
+// pool is the seats of one kind of requests, a freed seat is handed to the next waiting request
+type pool struct {
+	kind        string
+	limit       int
+	queueLength int
+	queueWait   time.Duration
+
+	mu       sync.Mutex
+	inflight int
+	levels   []*queueSet
+}
+
+func newPool(kind string, limit int, queueLength int, queueWait time.Duration) *pool {
+	return &pool{
+		kind:        kind,
+		limit:       limit,
+		queueLength: queueLength,
+		queueWait:   queueWait,
+		levels: []*queueSet{
+			{name: systemPriorityLevel, shares: priorityShares[systemPriorityLevel], queues: make(map[string][]*waiter)},
+			{name: workloadPriorityLevel, shares: priorityShares[workloadPriorityLevel], queues: make(map[string][]*waiter)},
+		},
+	}
+}
+
+func (p *pool) level(name string) *queueSet {
+	for _, level := range p.levels {
+		if level.name == name {
+			return level
+		}
+	}
+
+	return p.levels[len(p.levels)-1]
+}
+
+func (p *pool) waitingLocked() int {
+	count := 0
+	for _, level := range p.levels {
+		count += level.waiting()
+	}
+
+	return count
+}
+
+// acquire blocks until the request gets a seat, the wait times out or the client goes away
+func (p *pool) acquire(levelName string, flow string, cancel <-chan struct{}) error {
+	// a limit of zero or less disables the limit
+	if p.limit <= 0 {
+		return nil
+	}
+
+	p.mu.Lock()
+
+	if p.inflight < p.limit && p.waitingLocked() == 0 {
+		p.inflight++
+		p.mu.Unlock()
+
+		return nil
+	}
+
+	level := p.level(levelName)
+	if len(level.queues[flow]) >= p.queueLength {
+		p.mu.Unlock()
+
+		return fmt.Errorf("queue of %s is full", flow)
+	}
+
+	w := &waiter{ready: make(chan struct{})}
+	level.enqueue(flow, w)
+	metrics.RequestQueued(level.name, p.kind)
+	p.mu.Unlock()
+
+	timer := time.NewTimer(p.queueWait)
+	defer timer.Stop()
+
+	var reason string
+	select {
+	case <-w.ready:
+		return nil
+	case <-timer.C:
+		reason = fmt.Sprintf("waited %s in queue", p.queueWait)
+	case <-cancel:
+		reason = "client went away"
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// the seat may have been handed over right when the wait ended
+	if w.dispatched {
+		return nil
+	}
+
+	level.remove(flow, w)
+	metrics.RequestDequeued(level.name, p.kind)
+
+	return fmt.Errorf("%s", reason)
+}
+
+func (p *pool) release() {
+	if p.limit <= 0 {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	level := p.nextLevelLocked()
+	if level == nil {
+		p.inflight--
+
+		return
+	}
+
+	// the seat goes straight to the waiting request, inflight stays the same
+	w := level.dequeue()
+	w.dispatched = true
+	metrics.RequestDequeued(level.name, p.kind)
+	close(w.ready)
+}
+
+// nextLevelLocked picks the priority level to serve with smooth weighted round robin between levels
+// that have waiting requests, nil when no request is waiting
+func (p *pool) nextLevelLocked() *queueSet {
+	var chosen *queueSet
+	totalShares := 0
+
+	for _, level := range p.levels {
+		if level.waiting() == 0 {
+			continue
+		}
+
+		level.current += level.shares
+		totalShares += level.shares
+
+		if chosen == nil || level.current > chosen.current {
+			chosen = level
+		}
+	}
+
+	if chosen != nil {
+		chosen.current -= totalShares
+	}
+
+	return chosen
+}
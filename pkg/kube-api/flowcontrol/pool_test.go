@@ -0,0 +1,192 @@
+package flowcontrol
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type queuedRequest struct {
+	level string
+	flow  string
+}
+
+func TestPoolDispatchOrder(t *testing.T) {
+	tests := []struct {
+		name   string
+		queued []queuedRequest
+		want   []string
+	}{
+		{
+			name: "single flow is first in first out",
+			queued: []queuedRequest{
+				{workloadPriorityLevel, "a"}, {workloadPriorityLevel, "a"}, {workloadPriorityLevel, "a"},
+			},
+			want: []string{"workload/a#0", "workload/a#1", "workload/a#2"},
+		},
+		{
+			name: "flows of a level are served round robin",
+			queued: []queuedRequest{
+				{workloadPriorityLevel, "a"}, {workloadPriorityLevel, "a"}, {workloadPriorityLevel, "a"},
+				{workloadPriorityLevel, "b"}, {workloadPriorityLevel, "c"},
+			},
+			want: []string{"workload/a#0", "workload/b#0", "workload/c#0", "workload/a#1", "workload/a#2"},
+		},
+		{
+			name: "levels are served by their shares",
+			queued: []queuedRequest{
+				{systemPriorityLevel, "a"}, {systemPriorityLevel, "a"}, {systemPriorityLevel, "a"}, {systemPriorityLevel, "a"},
+				{workloadPriorityLevel, "b"}, {workloadPriorityLevel, "b"},
+			},
+			want: []string{"system/a#0", "workload/b#0", "system/a#1", "system/a#2", "workload/b#1", "system/a#3"},
+		},
+		{
+			name: "level left alone is served without its share",
+			queued: []queuedRequest{
+				{systemPriorityLevel, "a"},
+				{workloadPriorityLevel, "b"}, {workloadPriorityLevel, "b"}, {workloadPriorityLevel, "b"},
+			},
+			want: []string{"system/a#0", "workload/b#0", "workload/b#1", "workload/b#2"},
+		},
+		{
+			name:   "unknown level is served as workload",
+			queued: []queuedRequest{{"unknown", "a"}},
+			want:   []string{"workload/a#0"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := newPool(readOnlyRequestKind, 1, 10, time.Minute)
+			p.inflight = p.limit
+
+			names := map[*waiter]string{}
+			counts := map[string]int{}
+			for _, request := range test.queued {
+				level := p.level(request.level)
+				key := level.name + "/" + request.flow
+
+				w := &waiter{ready: make(chan struct{})}
+				names[w] = fmt.Sprintf("%s#%d", key, counts[key])
+				counts[key]++
+
+				level.enqueue(request.flow, w)
+			}
+
+			var got []string
+			for range test.queued {
+				level := p.nextLevelLocked()
+				if level == nil {
+					t.Fatalf("no level picked with %d requests waiting", p.waitingLocked())
+				}
+
+				got = append(got, names[level.dequeue()])
+			}
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("dispatch order = %v, want %v", got, test.want)
+			}
+
+			if level := p.nextLevelLocked(); level != nil {
+				t.Errorf("level %s picked with no request waiting", level.name)
+			}
+		})
+	}
+}
+
+func TestPoolInflightLimit(t *testing.T) {
+	tests := []struct {
+		name        string
+		limit       int
+		queueLength int
+		// acquired is the number of seats taken before the request
+		acquired int
+		// released is the number of seats freed while the request waits
+		released int
+		cancel   bool
+		wantErr  bool
+	}{
+		{name: "seat free", limit: 2, queueLength: 1, acquired: 1},
+		{name: "limit disabled", limit: 0, queueLength: 1, acquired: 5},
+		{name: "waits for a released seat", limit: 2, queueLength: 1, acquired: 2, released: 1},
+		{name: "times out waiting", limit: 2, queueLength: 1, acquired: 2, wantErr: true},
+		{name: "queue full", limit: 1, queueLength: 0, acquired: 1, wantErr: true},
+		{name: "client went away", limit: 1, queueLength: 1, acquired: 1, cancel: true, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := newPool(mutatingRequestKind, test.limit, test.queueLength, 200*time.Millisecond)
+
+			for i := 0; i < test.acquired; i++ {
+				if err := p.acquire(workloadPriorityLevel, "flow", nil); err != nil {
+					t.Fatalf("acquire %d error: %v", i, err)
+				}
+			}
+
+			cancel := make(chan struct{})
+			if test.cancel {
+				close(cancel)
+			}
+
+			result := make(chan error, 1)
+			go func() {
+				result <- p.acquire(workloadPriorityLevel, "flow", cancel)
+			}()
+
+			if test.released > 0 {
+				waitForQueued(t, p, workloadPriorityLevel, 1)
+			}
+
+			for i := 0; i < test.released; i++ {
+				p.release()
+			}
+
+			err := <-result
+			if (err != nil) != test.wantErr {
+				t.Fatalf("acquire error = %v, want error %v", err, test.wantErr)
+			}
+
+			p.mu.Lock()
+			defer p.mu.Unlock()
+
+			if waiting := p.waitingLocked(); waiting != 0 {
+				t.Errorf("%d requests left waiting", waiting)
+			}
+
+			wantInflight := test.acquired - test.released
+			if !test.wantErr {
+				wantInflight++
+			}
+
+			if test.limit <= 0 {
+				wantInflight = 0
+			}
+
+			if p.inflight != wantInflight {
+				t.Errorf("inflight = %d, want %d", p.inflight, wantInflight)
+			}
+		})
+	}
+}
+
+// waitForQueued waits until the level has the number of waiting requests
+func waitForQueued(t *testing.T, p *pool, levelName string, count int) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		p.mu.Lock()
+		waiting := p.level(levelName).waiting()
+		p.mu.Unlock()
+
+		if waiting == count {
+			return
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	t.Fatalf("level %s did not get %d waiting requests", levelName, count)
+}
@@ -0,0 +1,151 @@
+package health
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	restful "github.com/emicklei/go-restful/v3"
+)
+
+const (
+	verboseQuery = "verbose"
+	excludeQuery = "exclude"
+	mimeText     = "text/plain"
+)
+
+type Checker interface {
+	Name() string
+	Check() error
+}
+
+type namedCheck struct {
+	name  string
+	check func() error
+}
+
+func (c *namedCheck) Name() string {
+	return c.name
+}
+
+func (c *namedCheck) Check() error {
+	return c.check()
+}
+
+func NamedCheck(name string, check func() error) Checker {
+	return &namedCheck{
+		name:  name,
+		check: check,
+	}
+}
+
+// PingCheck always succeeds, it only proves the server is able to answer requests
+var PingCheck = NamedCheck("ping", func() error { return nil })
+
+// InstallHandler adds to the web service the route /{name} that runs all the checks,
+// and the route /{name}/{check} for running a single check
+func InstallHandler(ws *restful.WebService, name string, checks ...Checker) {
+	log.Printf("installing health checks endpoint /%s", name)
+
+	ws.Route(ws.GET(fmt.Sprintf("/%s", name)).To(handleRootCheck(name, checks)).
+		Produces(mimeText).
+		Param(ws.QueryParameter(verboseQuery, "print all the checks results").DataType("bool")).
+		Param(ws.QueryParameter(excludeQuery, "name of a check to exclude").DataType("string").AllowMultiple(true)))
+
+	ws.Route(ws.GET(fmt.Sprintf("/%s/{check}", name)).To(handleSingleCheck(checks)).
+		Produces(mimeText).
+		Param(ws.PathParameter("check", "name of the check").DataType("string")))
+}
+
+func handleRootCheck(name string, checks []Checker) restful.RouteFunction {
+	return func(req *restful.Request, resp *restful.Response) {
+		excluded := make(map[string]bool)
+		for _, exclude := range req.QueryParameters(excludeQuery) {
+			for _, checkName := range strings.Split(exclude, ",") {
+				if checkName = strings.TrimSpace(checkName); checkName != "" {
+					excluded[checkName] = true
+				}
+			}
+		}
+
+		var output bytes.Buffer
+		failed := false
+
+		for _, check := range checks {
+			if excluded[check.Name()] {
+				fmt.Fprintf(&output, "[+]%s excluded: ok\n", check.Name())
+				delete(excluded, check.Name())
+
+				continue
+			}
+
+			if err := check.Check(); err != nil {
+				log.Printf("%s check %s failed: %v", name, check.Name(), err)
+				fmt.Fprintf(&output, "[-]%s failed: %v\n", check.Name(), err)
+				failed = true
+
+				continue
+			}
+
+			fmt.Fprintf(&output, "[+]%s ok\n", check.Name())
+		}
+
+		for checkName := range excluded {
+			fmt.Fprintf(&output, "warn: some health checks cannot be excluded: no matches for %q\n", checkName)
+		}
+
+		_, verbose := req.Request.URL.Query()[verboseQuery]
+
+		if failed {
+			fmt.Fprintf(&output, "%s check failed\n", name)
+			writeText(resp, http.StatusInternalServerError, output.String())
+
+			return
+		}
+
+		if verbose {
+			fmt.Fprintf(&output, "%s check passed\n", name)
+			writeText(resp, http.StatusOK, output.String())
+
+			return
+		}
+
+		writeText(resp, http.StatusOK, "ok")
+	}
+}
+
+func handleSingleCheck(checks []Checker) restful.RouteFunction {
+	return func(req *restful.Request, resp *restful.Response) {
+		checkName := req.PathParameter("check")
+
+		for _, check := range checks {
+			if check.Name() != checkName {
+				continue
+			}
+
+			if err := check.Check(); err != nil {
+				writeText(resp, http.StatusInternalServerError, fmt.Sprintf("internal server error: %v", err))
+
+				return
+			}
+
+			writeText(resp, http.StatusOK, "ok")
+
+			return
+		}
+
+		writeText(resp, http.StatusNotFound, fmt.Sprintf("check %s not found", checkName))
+	}
+}
+
+func writeText(resp *restful.Response, status int, text string) {
+	resp.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	resp.Header().Set("X-Content-Type-Options", "nosniff")
+	resp.WriteHeader(status)
+
+	if _, err := fmt.Fprint(resp, text); err != nil {
+		log.Printf("error while writing health check response: %v", err)
+	}
+}
@@ -5,17 +5,23 @@ import (
 	"time"
 
 	restful "github.com/emicklei/go-restful/v3"
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/metrics"
 )
 
 func LoggerMiddleware(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
 	start := time.Now()
+	verb, resource := metrics.VerbAndResource(req)
 
 	// Log request information
 	log.Printf("Incoming request: %s %s", req.Request.Method, req.Request.URL.Path)
 
+	metrics.RequestStarted()
+
 	// Process the request
 	chain.ProcessFilter(req, resp)
 
+	metrics.RequestFinished(verb, resource, resp.StatusCode(), time.Since(start))
+
 	// Log response information
 	log.Printf("Outgoing response: %s %d %s", req.Request.Method, resp.StatusCode(), time.Since(start))
 }
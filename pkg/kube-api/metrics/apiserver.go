@@ -0,0 +1,223 @@
+package metrics
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	restful "github.com/emicklei/go-restful/v3"
+)
+
+const mimeText = "text/plain; version=0.0.4; charset=utf-8"
+
+var (
+	requestDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+	etcdDurationBuckets    = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+	DefaultRegistry = NewRegistry()
+
+	requestTotal = NewCounterVec(DefaultRegistry,
+		"apiserver_request_total",
+		"Counter of apiserver requests broken out by verb, resource and HTTP response code.",
+		"verb", "resource", "code")
+
+	requestDuration = NewHistogramVec(DefaultRegistry,
+		"apiserver_request_duration_seconds",
+		"Response latency distribution in seconds for each verb, resource and HTTP response code.",
+		requestDurationBuckets,
+		"verb", "resource", "code")
+
+	inflightRequests = NewGaugeVec(DefaultRegistry,
+		"apiserver_current_inflight_requests",
+		"Number of requests currently being served.")
+
+	registeredWatchers = NewGaugeVec(DefaultRegistry,
+		"apiserver_registered_watchers",
+		"Number of currently open watch streams for a given resource.",
+		"resource")
+
+	etcdRequestDuration = NewHistogramVec(DefaultRegistry,
+		"etcd_request_duration_seconds",
+		"Etcd request latency in seconds for each operation and object type.",
+		etcdDurationBuckets,
+		"operation", "type")
+
+	etcdRequestErrors = NewCounterVec(DefaultRegistry,
+		"etcd_request_errors_total",
+		"Etcd failed request counts for each operation and object type.",
+		"operation", "type")
+
+	storageObjects = NewGaugeVec(DefaultRegistry,
+		"apiserver_storage_objects",
+		"Number of stored objects at the time of last check split by kind.",
+		"resource")
+
+	droppedRequests = NewCounterVec(DefaultRegistry,
+		"apiserver_dropped_requests_total",
+		"Number of requests dropped with 'Try again later' response.",
+		"request_kind")
+
+	inqueueRequests = NewGaugeVec(DefaultRegistry,
+		"apiserver_flowcontrol_current_inqueue_requests",
+		"Number of requests currently pending in queues of a priority level.",
+		"priority_level", "request_kind")
+
+	scrapeHooksMutex sync.Mutex
+	scrapeHooks      []func()
+)
+
+// OnScrape registers a function that is called before every scrape of /metrics,
+// used for metrics that are cheaper to compute on demand
+func OnScrape(hook func()) {
+	scrapeHooksMutex.Lock()
+	defer scrapeHooksMutex.Unlock()
+
+	scrapeHooks = append(scrapeHooks, hook)
+}
+
+func InstallHandler(ws *restful.WebService) {
+	log.Println("installing metrics endpoint /metrics")
+
+	ws.Route(ws.GET("/").To(func(_ *restful.Request, resp *restful.Response) {
+		scrapeHooksMutex.Lock()
+		hooks := append([]func(){}, scrapeHooks...)
+		scrapeHooksMutex.Unlock()
+
+		for _, hook := range hooks {
+			hook()
+		}
+
+		var output bytes.Buffer
+		if err := DefaultRegistry.Write(&output); err != nil {
+			resp.WriteError(http.StatusInternalServerError, err)
+
+			return
+		}
+
+		resp.Header().Set("Content-Type", mimeText)
+		resp.WriteHeader(http.StatusOK)
+
+		if _, err := resp.Write(output.Bytes()); err != nil {
+			log.Printf("error while writing metrics: %v", err)
+		}
+	}).Produces("text/plain"))
+}
+
+func RequestStarted() {
+	inflightRequests.Inc()
+}
+
+func RequestFinished(verb string, resource string, code int, duration time.Duration) {
+	inflightRequests.Dec()
+
+	requestTotal.Inc(verb, resource, strconv.Itoa(code))
+	requestDuration.Observe(duration.Seconds(), verb, resource, strconv.Itoa(code))
+}
+
+func WatchStarted(resource string) {
+	registeredWatchers.Inc(resource)
+}
+
+func WatchStopped(resource string) {
+	registeredWatchers.Dec(resource)
+}
+
+func RequestDropped(requestKind string) {
+	droppedRequests.Inc(requestKind)
+}
+
+func RequestQueued(priorityLevel string, requestKind string) {
+	inqueueRequests.Inc(priorityLevel, requestKind)
+}
+
+func RequestDequeued(priorityLevel string, requestKind string) {
+	inqueueRequests.Dec(priorityLevel, requestKind)
+}
+
+func ObserveEtcdRequest(operation string, key string, start time.Time, err error) {
+	objectType := ResourceFromEtcdKey(key)
+
+	etcdRequestDuration.Observe(time.Since(start).Seconds(), operation, objectType)
+
+	if err != nil {
+		etcdRequestErrors.Inc(operation, objectType)
+	}
+}
+
+func SetStorageObjects(resource string, count int64) {
+	storageObjects.Set(float64(count), resource)
+}
+
+// ResourceFromEtcdKey returns the resource name of an etcd key,
+// keys are /{resource}/... except endpoints and services specs which are nested under /services
+func ResourceFromEtcdKey(key string) string {
+	parts := strings.Split(strings.TrimPrefix(key, "/"), "/")
+
+	if parts[0] == "services" && len(parts) > 1 && parts[1] == "endpoints" {
+		return "endpoints"
+	}
+
+	return parts[0]
+}
+
+// VerbAndResource returns the kubernetes like verb and the resource of a request from its selected route,
+// for example GET /namespaces/{namespace}/pods -> LIST pods
+func VerbAndResource(req *restful.Request) (string, string) {
+	routePath := req.SelectedRoutePath()
+	if routePath == "" {
+		routePath = req.Request.URL.Path
+	}
+
+	var segments []string
+	for _, segment := range strings.Split(strings.Trim(routePath, "/"), "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+
+	resource := ""
+	subresource := ""
+	single := false
+	for index, segment := range segments {
+		if strings.HasPrefix(segment, "{") {
+			single = segment != "{namespace}"
+
+			continue
+		}
+
+		if resource != "" && index > 0 && strings.HasPrefix(segments[index-1], "{") && segments[index-1] != "{namespace}" {
+			subresource = segment
+
+			continue
+		}
+
+		resource = segment
+		single = false
+	}
+
+	if subresource != "" {
+		resource = resource + "/" + subresource
+	}
+
+	verb := req.Request.Method
+	switch req.Request.Method {
+	case http.MethodGet:
+		if req.QueryParameter("watch") == "true" {
+			verb = "WATCH"
+		} else if single {
+			verb = "GET"
+		} else {
+			verb = "LIST"
+		}
+	case http.MethodPost:
+		verb = "CREATE"
+	case http.MethodPut:
+		verb = "UPDATE"
+	}
+
+	return verb, resource
+}
@@ -0,0 +1,297 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const labelValuesSeparator = "\xff"
+
+type collector interface {
+	write(w io.Writer) error
+}
+
+// Registry holds all the metrics that are exposed in prometheus text format
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (registry *Registry) register(c collector) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	registry.collectors = append(registry.collectors, c)
+}
+
+func (registry *Registry) Write(w io.Writer) error {
+	registry.mu.Lock()
+	collectors := append([]collector{}, registry.collectors...)
+	registry.mu.Unlock()
+
+	for _, c := range collectors {
+		if err := c.write(w); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type desc struct {
+	name       string
+	help       string
+	metricType string
+	labelNames []string
+}
+
+func (d *desc) writeHeader(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", d.name, d.help, d.name, d.metricType)
+
+	return err
+}
+
+func (d *desc) key(labelValues []string) string {
+	if len(labelValues) != len(d.labelNames) {
+		panic(fmt.Sprintf("metric %s expects %d label values, got %d", d.name, len(d.labelNames), len(labelValues)))
+	}
+
+	return strings.Join(labelValues, labelValuesSeparator)
+}
+
+func (d *desc) formatLabels(labelValues []string, extra ...string) string {
+	var pairs []string
+
+	for index, labelName := range d.labelNames {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", labelName, escapeLabelValue(labelValues[index])))
+	}
+
+	for index := 0; index+1 < len(extra); index += 2 {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", extra[index], escapeLabelValue(extra[index+1])))
+	}
+
+	if len(pairs) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("{%s}", strings.Join(pairs, ","))
+}
+
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func formatFloat(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// valueVec is the shared implementation of counters and gauges
+type valueVec struct {
+	desc
+
+	mu     sync.Mutex
+	values map[string]float64
+	labels map[string][]string
+}
+
+func newValueVec(registry *Registry, metricType string, name string, help string, labelNames []string) *valueVec {
+	vec := &valueVec{
+		desc: desc{
+			name:       name,
+			help:       help,
+			metricType: metricType,
+			labelNames: labelNames,
+		},
+		values: make(map[string]float64),
+		labels: make(map[string][]string),
+	}
+
+	registry.register(vec)
+
+	return vec
+}
+
+func (vec *valueVec) add(value float64, labelValues []string) {
+	key := vec.key(labelValues)
+
+	vec.mu.Lock()
+	defer vec.mu.Unlock()
+
+	vec.values[key] += value
+	vec.labels[key] = labelValues
+}
+
+func (vec *valueVec) set(value float64, labelValues []string) {
+	key := vec.key(labelValues)
+
+	vec.mu.Lock()
+	defer vec.mu.Unlock()
+
+	vec.values[key] = value
+	vec.labels[key] = labelValues
+}
+
+func (vec *valueVec) write(w io.Writer) error {
+	vec.mu.Lock()
+	defer vec.mu.Unlock()
+
+	if err := vec.writeHeader(w); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(vec.values))
+	for key := range vec.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if _, err := fmt.Fprintf(w, "%s%s %s\n", vec.name, vec.formatLabels(vec.labels[key]), formatFloat(vec.values[key])); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type CounterVec struct {
+	vec *valueVec
+}
+
+func NewCounterVec(registry *Registry, name string, help string, labelNames ...string) *CounterVec {
+	return &CounterVec{vec: newValueVec(registry, "counter", name, help, labelNames)}
+}
+
+func (counter *CounterVec) Inc(labelValues ...string) {
+	counter.vec.add(1, labelValues)
+}
+
+type GaugeVec struct {
+	vec *valueVec
+}
+
+func NewGaugeVec(registry *Registry, name string, help string, labelNames ...string) *GaugeVec {
+	return &GaugeVec{vec: newValueVec(registry, "gauge", name, help, labelNames)}
+}
+
+func (gauge *GaugeVec) Inc(labelValues ...string) {
+	gauge.vec.add(1, labelValues)
+}
+
+func (gauge *GaugeVec) Dec(labelValues ...string) {
+	gauge.vec.add(-1, labelValues)
+}
+
+func (gauge *GaugeVec) Set(value float64, labelValues ...string) {
+	gauge.vec.set(value, labelValues)
+}
+
+type histogramSeries struct {
+	labelValues []string
+	counts      []uint64
+	count       uint64
+	sum         float64
+}
+
+type HistogramVec struct {
+	desc
+
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*histogramSeries
+}
+
+func NewHistogramVec(registry *Registry, name string, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	histogram := &HistogramVec{
+		desc: desc{
+			name:       name,
+			help:       help,
+			metricType: "histogram",
+			labelNames: labelNames,
+		},
+		buckets: buckets,
+		series:  make(map[string]*histogramSeries),
+	}
+
+	registry.register(histogram)
+
+	return histogram
+}
+
+func (histogram *HistogramVec) Observe(value float64, labelValues ...string) {
+	key := histogram.key(labelValues)
+
+	histogram.mu.Lock()
+	defer histogram.mu.Unlock()
+
+	series, ok := histogram.series[key]
+	if !ok {
+		series = &histogramSeries{
+			labelValues: labelValues,
+			counts:      make([]uint64, len(histogram.buckets)),
+		}
+		histogram.series[key] = series
+	}
+
+	for index, upperBound := range histogram.buckets {
+		if value <= upperBound {
+			series.counts[index]++
+		}
+	}
+
+	series.count++
+	series.sum += value
+}
+
+func (histogram *HistogramVec) write(w io.Writer) error {
+	histogram.mu.Lock()
+	defer histogram.mu.Unlock()
+
+	if err := histogram.writeHeader(w); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(histogram.series))
+	for key := range histogram.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		series := histogram.series[key]
+
+		for index, upperBound := range histogram.buckets {
+			if _, err := fmt.Fprintf(w, "%s_bucket%s %d\n",
+				histogram.name,
+				histogram.formatLabels(series.labelValues, "le", formatFloat(upperBound)),
+				series.counts[index],
+			); err != nil {
+				return err
+			}
+		}
+
+		if _, err := fmt.Fprintf(w, "%s_bucket%s %d\n%s_sum%s %s\n%s_count%s %d\n",
+			histogram.name, histogram.formatLabels(series.labelValues, "le", "+Inf"), series.count,
+			histogram.name, histogram.formatLabels(series.labelValues), formatFloat(series.sum),
+			histogram.name, histogram.formatLabels(series.labelValues), series.count,
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
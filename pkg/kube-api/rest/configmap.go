@@ -0,0 +1,117 @@
+package rest
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	restful "github.com/emicklei/go-restful/v3"
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/etcd"
+	kubeapi_logger "github.com/jonatan5524/own-kubernetes/pkg/kube-api/logger"
+)
+
+const configMapEtcdKey = "/configmaps"
+
+var (
+	etcdServiceAppConfigMap etcd.EtcdService
+
+	// configMapKeyRegex are the allowed keys, every key becomes a file name in a configmap volume
+	configMapKeyRegex = regexp.MustCompile(`^[-._a-zA-Z0-9]+$`)
+)
+
+// ConfigMap holds configuration for pods, consumed as environment variables or files in a volume
+type ConfigMap struct {
+	Metadata ResourceMetadata `json:"metadata" yaml:"metadata"`
+
+	TypeMeta `json:",inline" yaml:",inline"`
+
+	Data map[string]string `json:"data" yaml:"data"`
+	// BinaryData values are base64 encoded in JSON, the keys can not be in Data as well
+	BinaryData map[string][]byte `json:"binaryData" yaml:"binaryData"`
+}
+
+func (configMap *ConfigMap) Register(container *restful.Container, etcdService etcd.EtcdService) {
+	log.Println("rest api configmap register")
+
+	etcdServiceAppConfigMap = etcdService
+
+	ws := new(restful.WebService)
+
+	ws.Filter(kubeapi_logger.LoggerMiddleware)
+
+	ws.Path("/configmaps").
+		Consumes(restful.MIME_JSON, MimeYAML).
+		Produces(restful.MIME_JSON, MimeYAML)
+
+	ws.Route(ws.GET("/").To(configMap.getAll).
+		Param(ws.QueryParameter("watch", "boolean for watching resource").DataType("bool").DefaultValue("false")).
+		Param(ws.QueryParameter("fieldSelector", "field selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("labelSelector", "label selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("resourceVersion", "resource version to start the watch from").DataType("string").DefaultValue("")))
+
+	container.Add(ws)
+}
+
+func (configMap *ConfigMap) getAll(req *restful.Request, resp *restful.Response) {
+	watchQuery := req.QueryParameter("watch")
+
+	if watchQuery == "true" {
+		watchFromWatchCache(req, resp, configMapEtcdKey, "")
+
+		return
+	}
+
+	listFromWatchCache(req, resp, configMapEtcdKey, "")
+}
+
+// Value returns the value of a key from Data or BinaryData
+func (configMap *ConfigMap) Value(key string) ([]byte, bool) {
+	if value, ok := configMap.Data[key]; ok {
+		return []byte(value), true
+	}
+
+	value, ok := configMap.BinaryData[key]
+
+	return value, ok
+}
+
+// Files returns all the keys of Data and BinaryData with their values
+func (configMap *ConfigMap) Files() map[string][]byte {
+	files := make(map[string][]byte, len(configMap.Data)+len(configMap.BinaryData))
+
+	for key, value := range configMap.Data {
+		files[key] = []byte(value)
+	}
+
+	for key, value := range configMap.BinaryData {
+		files[key] = value
+	}
+
+	return files
+}
+
+// isValidConfigMapKey also refuses keys starting with "..", the volume writer keeps its own files under that prefix
+func isValidConfigMapKey(key string) bool {
+	return configMapKeyRegex.MatchString(key) && key != "." && !strings.HasPrefix(key, "..")
+}
+
+func validateConfigMapKeys(configMap *ConfigMap) error {
+	for key := range configMap.Data {
+		if !isValidConfigMapKey(key) {
+			return fmt.Errorf("invalid configmap key %q, must consist of alphanumeric characters, '-', '_' or '.'", key)
+		}
+	}
+
+	for key := range configMap.BinaryData {
+		if !isValidConfigMapKey(key) {
+			return fmt.Errorf("invalid configmap key %q, must consist of alphanumeric characters, '-', '_' or '.'", key)
+		}
+
+		if _, ok := configMap.Data[key]; ok {
+			return fmt.Errorf("configmap key %q is in both data and binaryData", key)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package rest
+
+import (
+	"fmt"
+	"log"
+
+	restful "github.com/emicklei/go-restful/v3"
+	"github.com/jonatan5524/own-kubernetes/pkg/cron"
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/etcd"
+	kubeapi_logger "github.com/jonatan5524/own-kubernetes/pkg/kube-api/logger"
+)
+
+const (
+	cronJobEtcdKey = "/cronjobs"
+
+	// AllowConcurrent runs the jobs of a cronjob even when the previous ones are still running, ForbidConcurrent
+	// skips a run while a previous job is running and ReplaceConcurrent deletes the running jobs for the new one
+	AllowConcurrent   = "Allow"
+	ForbidConcurrent  = "Forbid"
+	ReplaceConcurrent = "Replace"
+
+	// same defaults as kubernetes
+	defaultSuccessfulJobsHistoryLimit = 3
+	defaultFailedJobsHistoryLimit     = 1
+)
+
+var etcdServiceAppCronJob etcd.EtcdService
+
+// CronJob creates a job of its template at every time of its schedule
+type CronJob struct {
+	Metadata ResourceMetadata `json:"metadata" yaml:"metadata"`
+
+	TypeMeta `json:",inline" yaml:",inline"`
+
+	Spec CronJobSpec `json:"spec" yaml:"spec"`
+
+	Status CronJobStatus `json:"status" yaml:"status"`
+}
+
+type CronJobSpec struct {
+	// Schedule is in the cron format, for example "*/5 * * * *" or "@hourly", in the time zone of the controller
+	Schedule string `json:"schedule" yaml:"schedule"`
+	// StartingDeadlineSeconds is how late a run may start after its scheduled time, a run later than that is missed
+	StartingDeadlineSeconds *int `json:"startingDeadlineSeconds,omitempty" yaml:"startingDeadlineSeconds,omitempty"`
+	// ConcurrencyPolicy is Allow, Forbid or Replace, Allow when not set
+	ConcurrencyPolicy string `json:"concurrencyPolicy" yaml:"concurrencyPolicy"`
+	// Suspend stops the next runs, the running jobs are not stopped
+	Suspend bool `json:"suspend" yaml:"suspend"`
+	// SuccessfulJobsHistoryLimit and FailedJobsHistoryLimit are how many finished jobs are kept, 3 and 1 when not set
+	SuccessfulJobsHistoryLimit *int `json:"successfulJobsHistoryLimit,omitempty" yaml:"successfulJobsHistoryLimit,omitempty"`
+	FailedJobsHistoryLimit     *int `json:"failedJobsHistoryLimit,omitempty" yaml:"failedJobsHistoryLimit,omitempty"`
+
+	JobTemplate JobTemplateSpec `json:"jobTemplate" yaml:"jobTemplate"`
+}
+
+// JobTemplateSpec is the job a cronjob creates at every run
+type JobTemplateSpec struct {
+	Metadata ResourceMetadata `json:"metadata" yaml:"metadata"`
+
+	Spec JobSpec `json:"spec" yaml:"spec"`
+}
+
+type CronJobStatus struct {
+	// Active are the jobs of the cronjob that did not finish
+	Active             []ObjectReference `json:"active" yaml:"active"`
+	LastScheduleTime   string            `json:"lastScheduleTime" yaml:"lastScheduleTime"`
+	LastSuccessfulTime string            `json:"lastSuccessfulTime" yaml:"lastSuccessfulTime"`
+}
+
+func (cronJob *CronJob) setDefaults() {
+	if cronJob.Spec.ConcurrencyPolicy == "" {
+		cronJob.Spec.ConcurrencyPolicy = AllowConcurrent
+	}
+
+	if cronJob.Spec.SuccessfulJobsHistoryLimit == nil {
+		successfulJobsHistoryLimit := defaultSuccessfulJobsHistoryLimit
+		cronJob.Spec.SuccessfulJobsHistoryLimit = &successfulJobsHistoryLimit
+	}
+
+	if cronJob.Spec.FailedJobsHistoryLimit == nil {
+		failedJobsHistoryLimit := defaultFailedJobsHistoryLimit
+		cronJob.Spec.FailedJobsHistoryLimit = &failedJobsHistoryLimit
+	}
+
+	cronJob.Spec.JobTemplate.Spec.setDefaults()
+}
+
+func (cronJob *CronJob) validate() error {
+	if cronJob.Metadata.Name == "" {
+		return fmt.Errorf("cronjob name is required")
+	}
+
+	if _, err := cron.Parse(cronJob.Spec.Schedule); err != nil {
+		return fmt.Errorf("spec.schedule: %v", err)
+	}
+
+	if cronJob.Spec.ConcurrencyPolicy != AllowConcurrent && cronJob.Spec.ConcurrencyPolicy != ForbidConcurrent &&
+		cronJob.Spec.ConcurrencyPolicy != ReplaceConcurrent {
+		return fmt.Errorf("spec.concurrencyPolicy must be %s, %s or %s", AllowConcurrent, ForbidConcurrent, ReplaceConcurrent)
+	}
+
+	if cronJob.Spec.StartingDeadlineSeconds != nil && *cronJob.Spec.StartingDeadlineSeconds < 0 {
+		return fmt.Errorf("spec.startingDeadlineSeconds must not be negative")
+	}
+
+	if *cronJob.Spec.SuccessfulJobsHistoryLimit < 0 || *cronJob.Spec.FailedJobsHistoryLimit < 0 {
+		return fmt.Errorf("spec.successfulJobsHistoryLimit and spec.failedJobsHistoryLimit must not be negative")
+	}
+
+	if err := cronJob.Spec.JobTemplate.Spec.validate(); err != nil {
+		return fmt.Errorf("spec.jobTemplate.%v", err)
+	}
+
+	return nil
+}
+
+func (cronJob *CronJob) Register(container *restful.Container, etcdService etcd.EtcdService) {
+	log.Println("rest api cronjob register")
+
+	etcdServiceAppCronJob = etcdService
+
+	ws := new(restful.WebService)
+
+	ws.Filter(kubeapi_logger.LoggerMiddleware)
+
+	ws.Path("/cronjobs").
+		Consumes(restful.MIME_JSON, MimeYAML).
+		Produces(restful.MIME_JSON, MimeYAML)
+
+	ws.Route(ws.GET("/").To(cronJob.getAll).
+		Param(ws.QueryParameter("watch", "boolean for watching resource").DataType("bool").DefaultValue("false")).
+		Param(ws.QueryParameter("fieldSelector", "field selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("labelSelector", "label selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("resourceVersion", "resource version to start the watch from").DataType("string").DefaultValue("")))
+
+	container.Add(ws)
+}
+
+func (cronJob *CronJob) getAll(req *restful.Request, resp *restful.Response) {
+	watchQuery := req.QueryParameter("watch")
+
+	if watchQuery == "true" {
+		watchFromWatchCache(req, resp, cronJobEtcdKey, "")
+
+		return
+	}
+
+	listFromWatchCache(req, resp, cronJobEtcdKey, "")
+}
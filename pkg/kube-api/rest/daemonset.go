@@ -0,0 +1,165 @@
+package rest
+
+import (
+	"fmt"
+	"log"
+
+	restful "github.com/emicklei/go-restful/v3"
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/etcd"
+	kubeapi_logger "github.com/jonatan5524/own-kubernetes/pkg/kube-api/logger"
+)
+
+const (
+	daemonSetEtcdKey = "/daemonsets"
+
+	RollingUpdateDaemonSetStrategyType = "RollingUpdate"
+	OnDeleteDaemonSetStrategyType      = "OnDelete"
+
+	// ControllerRevisionHashLabelKey is set on the pods of a daemonset to the hash of the template they were created
+	// from, a pod with another hash is of an older template
+	ControllerRevisionHashLabelKey = "controller-revision-hash"
+
+	defaultDaemonSetMaxUnavailable = "1"
+)
+
+var etcdServiceAppDaemonSet etcd.EtcdService
+
+// DaemonSet runs one pod of its template on every node matching the node selector, affinity and tolerations of the
+// template
+type DaemonSet struct {
+	Metadata ResourceMetadata `json:"metadata" yaml:"metadata"`
+
+	TypeMeta `json:",inline" yaml:",inline"`
+
+	Spec DaemonSetSpec `json:"spec" yaml:"spec"`
+
+	Status DaemonSetStatus `json:"status" yaml:"status"`
+}
+
+type DaemonSetSpec struct {
+	// Selector must match the labels of the template
+	Selector        LabelSelector           `json:"selector" yaml:"selector"`
+	Template        PodTemplateSpec         `json:"template" yaml:"template"`
+	UpdateStrategy  DaemonSetUpdateStrategy `json:"updateStrategy" yaml:"updateStrategy"`
+	MinReadySeconds int                     `json:"minReadySeconds" yaml:"minReadySeconds"`
+}
+
+type DaemonSetUpdateStrategy struct {
+	// Type is RollingUpdate, replacing the pods of an older template, or OnDelete, replacing them only
+	// after they are deleted
+	Type          string                  `json:"type" yaml:"type"`
+	RollingUpdate *RollingUpdateDaemonSet `json:"rollingUpdate,omitempty" yaml:"rollingUpdate,omitempty"`
+}
+
+type RollingUpdateDaemonSet struct {
+	// MaxUnavailable is how many nodes may be without an available pod during the update, a number or a
+	// percentage of the desired pods, 1 when not set
+	MaxUnavailable IntOrString `json:"maxUnavailable" yaml:"maxUnavailable"`
+}
+
+type DaemonSetStatus struct {
+	// DesiredNumberScheduled is the number of nodes that should run the pod
+	DesiredNumberScheduled int `json:"desiredNumberScheduled" yaml:"desiredNumberScheduled"`
+	// CurrentNumberScheduled is the number of nodes that run the pod and should
+	CurrentNumberScheduled int `json:"currentNumberScheduled" yaml:"currentNumberScheduled"`
+	// NumberMisscheduled is the number of nodes that run the pod but should not
+	NumberMisscheduled     int `json:"numberMisscheduled" yaml:"numberMisscheduled"`
+	NumberReady            int `json:"numberReady" yaml:"numberReady"`
+	NumberAvailable        int `json:"numberAvailable" yaml:"numberAvailable"`
+	NumberUnavailable      int `json:"numberUnavailable" yaml:"numberUnavailable"`
+	UpdatedNumberScheduled int `json:"updatedNumberScheduled" yaml:"updatedNumberScheduled"`
+}
+
+// MaxUnavailable returns the number of nodes that may be without an available pod during a rolling update,
+// at least 1 so the update always makes progress
+func (daemonSet *DaemonSet) MaxUnavailable(desired int) (int, error) {
+	if daemonSet.Spec.UpdateStrategy.RollingUpdate == nil {
+		return 1, nil
+	}
+
+	maxUnavailable, err := daemonSet.Spec.UpdateStrategy.RollingUpdate.MaxUnavailable.ScaledValue(desired, true)
+	if err != nil {
+		return 0, fmt.Errorf("invalid maxUnavailable: %v", err)
+	}
+
+	return max(maxUnavailable, 1), nil
+}
+
+func (daemonSet *DaemonSet) setDefaults() {
+	if daemonSet.Spec.UpdateStrategy.Type == "" {
+		daemonSet.Spec.UpdateStrategy.Type = RollingUpdateDaemonSetStrategyType
+	}
+
+	if daemonSet.Spec.UpdateStrategy.Type != RollingUpdateDaemonSetStrategyType {
+		daemonSet.Spec.UpdateStrategy.RollingUpdate = nil
+
+		return
+	}
+
+	if daemonSet.Spec.UpdateStrategy.RollingUpdate == nil {
+		daemonSet.Spec.UpdateStrategy.RollingUpdate = &RollingUpdateDaemonSet{}
+	}
+
+	if daemonSet.Spec.UpdateStrategy.RollingUpdate.MaxUnavailable == "" {
+		daemonSet.Spec.UpdateStrategy.RollingUpdate.MaxUnavailable = defaultDaemonSetMaxUnavailable
+	}
+}
+
+func (daemonSet *DaemonSet) validate() error {
+	if daemonSet.Metadata.Name == "" {
+		return fmt.Errorf("daemonset name is required")
+	}
+
+	if daemonSet.Spec.UpdateStrategy.Type != RollingUpdateDaemonSetStrategyType &&
+		daemonSet.Spec.UpdateStrategy.Type != OnDeleteDaemonSetStrategyType {
+		return fmt.Errorf("spec.updateStrategy.type must be %s or %s", RollingUpdateDaemonSetStrategyType, OnDeleteDaemonSetStrategyType)
+	}
+
+	if _, err := daemonSet.MaxUnavailable(0); err != nil {
+		return fmt.Errorf("spec.updateStrategy.rollingUpdate: %v", err)
+	}
+
+	if daemonSet.Spec.Template.Spec.NodeName != "" {
+		return fmt.Errorf("spec.template.spec.nodeName must not be set, the pods are placed on every node by the controller")
+	}
+
+	if _, ok := daemonSet.Spec.Template.Metadata.Labels[ControllerRevisionHashLabelKey]; ok {
+		return fmt.Errorf("spec.template.metadata.labels must not contain %s, it is set by the controller", ControllerRevisionHashLabelKey)
+	}
+
+	return validateTemplateSelector(daemonSet.Spec.Selector, daemonSet.Spec.Template)
+}
+
+func (daemonSet *DaemonSet) Register(container *restful.Container, etcdService etcd.EtcdService) {
+	log.Println("rest api daemonset register")
+
+	etcdServiceAppDaemonSet = etcdService
+
+	ws := new(restful.WebService)
+
+	ws.Filter(kubeapi_logger.LoggerMiddleware)
+
+	ws.Path("/daemonsets").
+		Consumes(restful.MIME_JSON, MimeYAML).
+		Produces(restful.MIME_JSON, MimeYAML)
+
+	ws.Route(ws.GET("/").To(daemonSet.getAll).
+		Param(ws.QueryParameter("watch", "boolean for watching resource").DataType("bool").DefaultValue("false")).
+		Param(ws.QueryParameter("fieldSelector", "field selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("labelSelector", "label selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("resourceVersion", "resource version to start the watch from").DataType("string").DefaultValue("")))
+
+	container.Add(ws)
+}
+
+func (daemonSet *DaemonSet) getAll(req *restful.Request, resp *restful.Response) {
+	watchQuery := req.QueryParameter("watch")
+
+	if watchQuery == "true" {
+		watchFromWatchCache(req, resp, daemonSetEtcdKey, "")
+
+		return
+	}
+
+	listFromWatchCache(req, resp, daemonSetEtcdKey, "")
+}
@@ -0,0 +1,259 @@
+package rest
+
+import (
+	"fmt"
+	"log"
+
+	restful "github.com/emicklei/go-restful/v3"
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/etcd"
+	kubeapi_logger "github.com/jonatan5524/own-kubernetes/pkg/kube-api/logger"
+)
+
+const (
+	deploymentEtcdKey = "/deployments"
+
+	RecreateDeploymentStrategyType      = "Recreate"
+	RollingUpdateDeploymentStrategyType = "RollingUpdate"
+
+	// DeploymentAvailable is true when at least the replicas minus maxUnavailable are available
+	DeploymentAvailable = "Available"
+	// DeploymentProgressing is true while the rollout makes progress or is complete, and false when it is stuck
+	// for longer than the progress deadline
+	DeploymentProgressing = "Progressing"
+
+	// PodTemplateHashLabelKey is added to the selector, template and pods of the replicasets of a deployment so
+	// the replicasets of different templates do not match each other pods
+	PodTemplateHashLabelKey = "pod-template-hash"
+	// RevisionAnnotation is the revision of a deployment and of its replicasets, the replicaset of the current
+	// template has the revision of the deployment
+	RevisionAnnotation = "deployment.kubernetes.io/revision"
+
+	// same defaults as kubernetes
+	defaultRevisionHistoryLimit    = 10
+	defaultProgressDeadlineSeconds = 600
+	defaultMaxSurge                = "25%"
+	defaultMaxUnavailable          = "25%"
+)
+
+var etcdServiceAppDeployment etcd.EtcdService
+
+// Deployment rolls out its pod template with a replicaset per template, the replicaset of the current template
+// is scaled up while the replicasets of the previous templates are scaled down
+type Deployment struct {
+	Metadata ResourceMetadata `json:"metadata" yaml:"metadata"`
+
+	TypeMeta `json:",inline" yaml:",inline"`
+
+	Spec DeploymentSpec `json:"spec" yaml:"spec"`
+
+	Status DeploymentStatus `json:"status" yaml:"status"`
+}
+
+type DeploymentSpec struct {
+	// Replicas is the number of pods to run, 1 when not set
+	Replicas        *int               `json:"replicas,omitempty" yaml:"replicas,omitempty"`
+	MinReadySeconds int                `json:"minReadySeconds" yaml:"minReadySeconds"`
+	Selector        LabelSelector      `json:"selector" yaml:"selector"`
+	Template        PodTemplateSpec    `json:"template" yaml:"template"`
+	Strategy        DeploymentStrategy `json:"strategy" yaml:"strategy"`
+	// RevisionHistoryLimit is how many scaled down replicasets are kept for rollbacks, 10 when not set
+	RevisionHistoryLimit *int `json:"revisionHistoryLimit,omitempty" yaml:"revisionHistoryLimit,omitempty"`
+	// ProgressDeadlineSeconds is how long a rollout may go without progress before it is reported as failed,
+	// 600 when not set
+	ProgressDeadlineSeconds *int `json:"progressDeadlineSeconds,omitempty" yaml:"progressDeadlineSeconds,omitempty"`
+	// RollbackTo is set by the rollback subresource, the controller replaces the template with the template of
+	// the revision and clears it
+	RollbackTo *RollbackConfig `json:"rollbackTo,omitempty" yaml:"rollbackTo,omitempty"`
+}
+
+type DeploymentStrategy struct {
+	// Type is RollingUpdate or Recreate, RollingUpdate when not set
+	Type          string                   `json:"type" yaml:"type"`
+	RollingUpdate *RollingUpdateDeployment `json:"rollingUpdate,omitempty" yaml:"rollingUpdate,omitempty"`
+}
+
+type RollingUpdateDeployment struct {
+	// MaxSurge is how many pods may run above the replicas during the rollout, a number or a percentage of
+	// the replicas rounded up
+	MaxSurge IntOrString `json:"maxSurge" yaml:"maxSurge"`
+	// MaxUnavailable is how many pods may be unavailable below the replicas during the rollout, a number or a
+	// percentage of the replicas rounded down
+	MaxUnavailable IntOrString `json:"maxUnavailable" yaml:"maxUnavailable"`
+}
+
+type RollbackConfig struct {
+	// Revision to roll back to, 0 rolls back to the previous revision
+	Revision int `json:"revision" yaml:"revision"`
+}
+
+// DeploymentRollback is posted to the rollback subresource of a deployment
+type DeploymentRollback struct {
+	TypeMeta `json:",inline" yaml:",inline"`
+
+	Name       string         `json:"name" yaml:"name"`
+	RollbackTo RollbackConfig `json:"rollbackTo" yaml:"rollbackTo"`
+}
+
+type DeploymentStatus struct {
+	// Replicas is the number of active pods of all the replicasets of the deployment
+	Replicas int `json:"replicas" yaml:"replicas"`
+	// UpdatedReplicas is the number of active pods of the current template
+	UpdatedReplicas     int                   `json:"updatedReplicas" yaml:"updatedReplicas"`
+	ReadyReplicas       int                   `json:"readyReplicas" yaml:"readyReplicas"`
+	AvailableReplicas   int                   `json:"availableReplicas" yaml:"availableReplicas"`
+	UnavailableReplicas int                   `json:"unavailableReplicas" yaml:"unavailableReplicas"`
+	Conditions          []DeploymentCondition `json:"conditions" yaml:"conditions"`
+}
+
+type DeploymentCondition struct {
+	Type string `json:"type" yaml:"type"`
+	// Status is True, False or Unknown
+	Status             string `json:"status" yaml:"status"`
+	LastUpdateTime     string `json:"lastUpdateTime" yaml:"lastUpdateTime"`
+	LastTransitionTime string `json:"lastTransitionTime" yaml:"lastTransitionTime"`
+	Reason             string `json:"reason" yaml:"reason"`
+	Message            string `json:"message" yaml:"message"`
+}
+
+// Condition returns the condition of the given type, nil when the deployment does not report it
+func (deployment *Deployment) Condition(conditionType string) *DeploymentCondition {
+	for index := range deployment.Status.Conditions {
+		if deployment.Status.Conditions[index].Type == conditionType {
+			return &deployment.Status.Conditions[index]
+		}
+	}
+
+	return nil
+}
+
+// MaxSurgeAndUnavailable returns the absolute maxSurge and maxUnavailable of a rolling update, they are never
+// both zero so the rollout can always make progress
+func (deployment *Deployment) MaxSurgeAndUnavailable() (int, int, error) {
+	if deployment.Spec.Strategy.Type != RollingUpdateDeploymentStrategyType || deployment.Spec.Strategy.RollingUpdate == nil {
+		return 0, 0, nil
+	}
+
+	replicas := *deployment.Spec.Replicas
+
+	maxSurge, err := deployment.Spec.Strategy.RollingUpdate.MaxSurge.ScaledValue(replicas, true)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid maxSurge: %v", err)
+	}
+
+	maxUnavailable, err := deployment.Spec.Strategy.RollingUpdate.MaxUnavailable.ScaledValue(replicas, false)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid maxUnavailable: %v", err)
+	}
+
+	if maxSurge == 0 && maxUnavailable == 0 {
+		maxUnavailable = 1
+	}
+
+	return maxSurge, min(maxUnavailable, replicas), nil
+}
+
+func (deployment *Deployment) setDefaults() {
+	if deployment.Spec.Replicas == nil {
+		replicas := 1
+		deployment.Spec.Replicas = &replicas
+	}
+
+	if deployment.Spec.RevisionHistoryLimit == nil {
+		revisionHistoryLimit := defaultRevisionHistoryLimit
+		deployment.Spec.RevisionHistoryLimit = &revisionHistoryLimit
+	}
+
+	if deployment.Spec.ProgressDeadlineSeconds == nil {
+		progressDeadlineSeconds := defaultProgressDeadlineSeconds
+		deployment.Spec.ProgressDeadlineSeconds = &progressDeadlineSeconds
+	}
+
+	if deployment.Spec.Strategy.Type == "" {
+		deployment.Spec.Strategy.Type = RollingUpdateDeploymentStrategyType
+	}
+
+	if deployment.Spec.Strategy.Type != RollingUpdateDeploymentStrategyType {
+		deployment.Spec.Strategy.RollingUpdate = nil
+
+		return
+	}
+
+	if deployment.Spec.Strategy.RollingUpdate == nil {
+		deployment.Spec.Strategy.RollingUpdate = &RollingUpdateDeployment{}
+	}
+
+	if deployment.Spec.Strategy.RollingUpdate.MaxSurge == "" {
+		deployment.Spec.Strategy.RollingUpdate.MaxSurge = defaultMaxSurge
+	}
+
+	if deployment.Spec.Strategy.RollingUpdate.MaxUnavailable == "" {
+		deployment.Spec.Strategy.RollingUpdate.MaxUnavailable = defaultMaxUnavailable
+	}
+}
+
+func (deployment *Deployment) validate() error {
+	if deployment.Metadata.Name == "" {
+		return fmt.Errorf("deployment name is required")
+	}
+
+	if *deployment.Spec.Replicas < 0 {
+		return fmt.Errorf("spec.replicas must not be negative")
+	}
+
+	if *deployment.Spec.RevisionHistoryLimit < 0 {
+		return fmt.Errorf("spec.revisionHistoryLimit must not be negative")
+	}
+
+	if *deployment.Spec.ProgressDeadlineSeconds <= deployment.Spec.MinReadySeconds {
+		return fmt.Errorf("spec.progressDeadlineSeconds must be greater than spec.minReadySeconds")
+	}
+
+	if deployment.Spec.Strategy.Type != RollingUpdateDeploymentStrategyType &&
+		deployment.Spec.Strategy.Type != RecreateDeploymentStrategyType {
+		return fmt.Errorf("spec.strategy.type must be %s or %s", RollingUpdateDeploymentStrategyType, RecreateDeploymentStrategyType)
+	}
+
+	if _, _, err := deployment.MaxSurgeAndUnavailable(); err != nil {
+		return fmt.Errorf("spec.strategy.rollingUpdate: %v", err)
+	}
+
+	if _, ok := deployment.Spec.Template.Metadata.Labels[PodTemplateHashLabelKey]; ok {
+		return fmt.Errorf("spec.template.metadata.labels must not contain %s, it is set by the controller", PodTemplateHashLabelKey)
+	}
+
+	return validateTemplateSelector(deployment.Spec.Selector, deployment.Spec.Template)
+}
+
+func (deployment *Deployment) Register(container *restful.Container, etcdService etcd.EtcdService) {
+	log.Println("rest api deployment register")
+
+	etcdServiceAppDeployment = etcdService
+
+	ws := new(restful.WebService)
+
+	ws.Filter(kubeapi_logger.LoggerMiddleware)
+
+	ws.Path("/deployments").
+		Consumes(restful.MIME_JSON, MimeYAML).
+		Produces(restful.MIME_JSON, MimeYAML)
+
+	ws.Route(ws.GET("/").To(deployment.getAll).
+		Param(ws.QueryParameter("watch", "boolean for watching resource").DataType("bool").DefaultValue("false")).
+		Param(ws.QueryParameter("fieldSelector", "field selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("labelSelector", "label selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("resourceVersion", "resource version to start the watch from").DataType("string").DefaultValue("")))
+
+	container.Add(ws)
+}
+
+func (deployment *Deployment) getAll(req *restful.Request, resp *restful.Response) {
+	watchQuery := req.QueryParameter("watch")
+
+	if watchQuery == "true" {
+		watchFromWatchCache(req, resp, deploymentEtcdKey, "")
+
+		return
+	}
+
+	listFromWatchCache(req, resp, deploymentEtcdKey, "")
+}
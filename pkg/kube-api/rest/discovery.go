@@ -0,0 +1,242 @@
+package rest
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	restful "github.com/emicklei/go-restful/v3"
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/etcd"
+	kubeapi_logger "github.com/jonatan5524/own-kubernetes/pkg/kube-api/logger"
+)
+
+const (
+	coreAPIPrefix  = "/api"
+	groupAPIPrefix = "/apis"
+)
+
+// Discovery serves the group versions of the api and their resources under /api and /apis
+type Discovery struct{}
+
+type APIVersions struct {
+	TypeMeta `json:",inline" yaml:",inline"`
+
+	Versions []string `json:"versions" yaml:"versions"`
+}
+
+type GroupVersionForDiscovery struct {
+	GroupVersion string `json:"groupVersion" yaml:"groupVersion"`
+	Version      string `json:"version" yaml:"version"`
+}
+
+type APIGroup struct {
+	Name             string                     `json:"name" yaml:"name"`
+	Versions         []GroupVersionForDiscovery `json:"versions" yaml:"versions"`
+	PreferredVersion GroupVersionForDiscovery   `json:"preferredVersion" yaml:"preferredVersion"`
+}
+
+type APIGroupList struct {
+	TypeMeta `json:",inline" yaml:",inline"`
+
+	Groups []APIGroup `json:"groups" yaml:"groups"`
+}
+
+type APIResourceList struct {
+	TypeMeta `json:",inline" yaml:",inline"`
+
+	GroupVersion string        `json:"groupVersion" yaml:"groupVersion"`
+	Resources    []APIResource `json:"resources" yaml:"resources"`
+}
+
+func (discovery *Discovery) Register(container *restful.Container, _ etcd.EtcdService) {
+	log.Println("rest api discovery register")
+
+	coreWS := new(restful.WebService)
+
+	coreWS.Filter(kubeapi_logger.LoggerMiddleware)
+
+	coreWS.Path(coreAPIPrefix).
+		Consumes(restful.MIME_JSON, MimeYAML).
+		Produces(restful.MIME_JSON, MimeYAML)
+
+	coreWS.Route(coreWS.GET("/").To(discovery.getCoreVersions))
+
+	coreWS.Route(coreWS.GET("/{version}").To(discovery.getCoreResources).
+		Param(coreWS.PathParameter("version", "version of the core group").DataType("string")))
+
+	container.Add(coreWS)
+
+	groupsWS := new(restful.WebService)
+
+	groupsWS.Filter(kubeapi_logger.LoggerMiddleware)
+
+	groupsWS.Path(groupAPIPrefix).
+		Consumes(restful.MIME_JSON, MimeYAML).
+		Produces(restful.MIME_JSON, MimeYAML)
+
+	groupsWS.Route(groupsWS.GET("/").To(discovery.getGroups))
+
+	groupsWS.Route(groupsWS.GET("/{group}/{version}").To(discovery.getGroupResources).
+		Param(groupsWS.PathParameter("group", "name of the group").DataType("string")).
+		Param(groupsWS.PathParameter("version", "version of the group").DataType("string")))
+
+	container.Add(groupsWS)
+}
+
+func (discovery *Discovery) getCoreVersions(_ *restful.Request, resp *restful.Response) {
+	err := resp.WriteEntity(APIVersions{
+		TypeMeta: TypeMeta{Kind: "APIVersions"},
+		Versions: []string{CoreV1},
+	})
+	if err != nil {
+		fmt.Printf("error while sending error: %v", err)
+	}
+}
+
+func (discovery *Discovery) getCoreResources(req *restful.Request, resp *restful.Response) {
+	discovery.writeResources(resp, req.PathParameter("version"))
+}
+
+func (discovery *Discovery) getGroupResources(req *restful.Request, resp *restful.Response) {
+	discovery.writeResources(resp, req.PathParameter("group")+"/"+req.PathParameter("version"))
+}
+
+func (discovery *Discovery) writeResources(resp *restful.Response, groupVersion string) {
+	resources := resourcesOf(groupVersion)
+	if len(resources) == 0 {
+		err := resp.WriteError(http.StatusNotFound, fmt.Errorf("group version %s not found", groupVersion))
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	err := resp.WriteEntity(APIResourceList{
+		TypeMeta:     TypeMeta{Kind: "APIResourceList"},
+		GroupVersion: groupVersion,
+		Resources:    resources,
+	})
+	if err != nil {
+		fmt.Printf("error while sending error: %v", err)
+	}
+}
+
+func (discovery *Discovery) getGroups(_ *restful.Request, resp *restful.Response) {
+	groups := []APIGroup{}
+
+	for _, resource := range APIResources {
+		group, version, found := strings.Cut(resource.groupVersion, "/")
+		if !found || (len(groups) > 0 && groups[len(groups)-1].Name == group) {
+			continue
+		}
+
+		// every group is served in a single version
+		groupVersion := GroupVersionForDiscovery{GroupVersion: resource.groupVersion, Version: version}
+		groups = append(groups, APIGroup{
+			Name:             group,
+			Versions:         []GroupVersionForDiscovery{groupVersion},
+			PreferredVersion: groupVersion,
+		})
+	}
+
+	err := resp.WriteEntity(APIGroupList{
+		TypeMeta: TypeMeta{Kind: "APIGroupList"},
+		Groups:   groups,
+	})
+	if err != nil {
+		fmt.Printf("error while sending error: %v", err)
+	}
+}
+
+func resourcesOf(groupVersion string) []APIResource {
+	resources := []APIResource{}
+
+	for _, resource := range APIResources {
+		if resource.groupVersion == groupVersion {
+			resources = append(resources, resource)
+		}
+	}
+
+	return resources
+}
+
+// VersionedPaths serves the resources under the paths of their group version as well, /api/v1/namespaces/default/pods
+// and /apis/apps/v1/namespaces/default/deployments are served by the routes of /namespaces/default/pods and
+// /namespaces/default/deployments. A resource requested under a group version it is not in is not found
+func VersionedPaths(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		groupVersion, path, versioned := splitVersionedPath(r.URL.Path)
+		if !versioned {
+			handler.ServeHTTP(w, r)
+
+			return
+		}
+
+		// the discovery of the group version itself
+		if strings.Trim(path, "/") == "" {
+			handler.ServeHTTP(w, r)
+
+			return
+		}
+
+		resource, found := resourceOfPath(path)
+		if !found || resource.groupVersion != groupVersion {
+			http.Error(w, fmt.Sprintf("the server could not find the requested resource %s", r.URL.Path), http.StatusNotFound)
+
+			return
+		}
+
+		versionedRequest := r.Clone(r.Context())
+		versionedRequest.URL.Path = path
+		versionedRequest.URL.RawPath = ""
+
+		handler.ServeHTTP(w, versionedRequest)
+	})
+}
+
+// splitVersionedPath returns the group version of a path under /api/<version> or /apis/<group>/<version> and the
+// rest of the path
+func splitVersionedPath(urlPath string) (string, string, bool) {
+	if rest, found := strings.CutPrefix(urlPath, coreAPIPrefix+"/"); found {
+		version, path, _ := strings.Cut(rest, "/")
+
+		return version, "/" + path, version != ""
+	}
+
+	if rest, found := strings.CutPrefix(urlPath, groupAPIPrefix+"/"); found {
+		segments := strings.SplitN(rest, "/", 3)
+		if len(segments) < 2 || segments[0] == "" || segments[1] == "" {
+			return "", "", false
+		}
+
+		path := "/"
+		if len(segments) == 3 {
+			path += segments[2]
+		}
+
+		return segments[0] + "/" + segments[1], path, true
+	}
+
+	return "", "", false
+}
+
+// resourceOfPath returns the resource of an unversioned path, namespaced resources are under
+// /namespaces/<namespace>/<resource> and all their objects are listed from /<resource>
+func resourceOfPath(path string) (APIResource, bool) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+
+	name := segments[0]
+	if name == "namespaces" && len(segments) > 2 {
+		name = segments[2]
+	}
+
+	for _, resource := range APIResources {
+		if resource.Name == name {
+			return resource, true
+		}
+	}
+
+	return APIResource{}, false
+}
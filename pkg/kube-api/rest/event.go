@@ -0,0 +1,88 @@
+package rest
+
+import (
+	"log"
+
+	restful "github.com/emicklei/go-restful/v3"
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/etcd"
+	kubeapi_logger "github.com/jonatan5524/own-kubernetes/pkg/kube-api/logger"
+)
+
+const (
+	eventEtcdKey = "/events"
+
+	EventTypeNormal  = "Normal"
+	EventTypeWarning = "Warning"
+)
+
+var etcdServiceAppEvent etcd.EtcdService
+
+// Event is a report of something that happened to an object, for example a failed image pull of a pod
+type Event struct {
+	Metadata ResourceMetadata `json:"metadata" yaml:"metadata"`
+
+	TypeMeta `json:",inline" yaml:",inline"`
+
+	InvolvedObject ObjectReference `json:"involvedObject" yaml:"involvedObject"`
+
+	// Reason is a short CamelCase reason of the event, for example Pulled or FailedCreatePodSandBox
+	Reason  string `json:"reason" yaml:"reason"`
+	Message string `json:"message" yaml:"message"`
+	// Type is Normal or Warning
+	Type string `json:"type" yaml:"type"`
+
+	// Count is the number of times the same event happened, repeated events update the existing one
+	Count          int    `json:"count" yaml:"count"`
+	FirstTimestamp string `json:"firstTimestamp" yaml:"firstTimestamp"`
+	LastTimestamp  string `json:"lastTimestamp" yaml:"lastTimestamp"`
+
+	Source EventSource `json:"source" yaml:"source"`
+}
+
+type ObjectReference struct {
+	Kind      string `json:"kind" yaml:"kind"`
+	Namespace string `json:"namespace" yaml:"namespace"`
+	Name      string `json:"name" yaml:"name"`
+	UID       string `json:"uid" yaml:"uid"`
+	// FieldPath points to a part of the object, for example spec.containers{nginx}
+	FieldPath string `json:"fieldPath" yaml:"fieldPath"`
+}
+
+type EventSource struct {
+	Component string `json:"component" yaml:"component"`
+	Host      string `json:"host" yaml:"host"`
+}
+
+func (event *Event) Register(container *restful.Container, etcdService etcd.EtcdService) {
+	log.Println("rest api event register")
+
+	etcdServiceAppEvent = etcdService
+
+	ws := new(restful.WebService)
+
+	ws.Filter(kubeapi_logger.LoggerMiddleware)
+
+	ws.Path("/events").
+		Consumes(restful.MIME_JSON, MimeYAML).
+		Produces(restful.MIME_JSON, MimeYAML)
+
+	ws.Route(ws.GET("/").To(event.getAll).
+		Param(ws.QueryParameter("watch", "boolean for watching resource").DataType("bool").DefaultValue("false")).
+		Param(ws.QueryParameter("fieldSelector", "field selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("labelSelector", "label selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("resourceVersion", "resource version to start the watch from").DataType("string").DefaultValue("")))
+
+	container.Add(ws)
+}
+
+func (event *Event) getAll(req *restful.Request, resp *restful.Response) {
+	watchQuery := req.QueryParameter("watch")
+
+	if watchQuery == "true" {
+		watchFromWatchCache(req, resp, eventEtcdKey, "")
+
+		return
+	}
+
+	listFromWatchCache(req, resp, eventEtcdKey, "")
+}
@@ -0,0 +1,292 @@
+package rest
+
+import (
+	"fmt"
+	"log"
+	"slices"
+
+	restful "github.com/emicklei/go-restful/v3"
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/etcd"
+	kubeapi_logger "github.com/jonatan5524/own-kubernetes/pkg/kube-api/logger"
+	"github.com/jonatan5524/own-kubernetes/pkg/utils"
+)
+
+const (
+	horizontalPodAutoscalerEtcdKey = "/horizontalpodautoscalers"
+
+	ResourceMetricSourceType = "Resource"
+
+	// UtilizationMetricType targets the average usage of the pods as a percentage of their requests,
+	// AverageValueMetricType targets the average usage of the pods as a quantity
+	UtilizationMetricType  = "Utilization"
+	AverageValueMetricType = "AverageValue"
+
+	// AbleToScale tells if the autoscaler can read and update the scale of its target, ScalingActive if it can
+	// compute the replicas from the metrics and ScalingLimited if the replicas were bounded by min or max replicas
+	AbleToScale    = "AbleToScale"
+	ScalingActive  = "ScalingActive"
+	ScalingLimited = "ScalingLimited"
+
+	// same defaults as kubernetes
+	defaultTargetCPUUtilization                = 80
+	defaultScaleDownStabilizationWindowSeconds = 300
+	maxStabilizationWindowSeconds              = 3600
+)
+
+// ScalableKinds are the kinds an autoscaler can target, the kinds with a scale subresource
+var ScalableKinds = []string{"Deployment", "ReplicaSet", "StatefulSet"}
+
+var etcdServiceAppHorizontalPodAutoscaler etcd.EtcdService
+
+// HorizontalPodAutoscaler changes the replicas of its target so the average usage of its pods is close to the
+// targets of its metrics
+type HorizontalPodAutoscaler struct {
+	Metadata ResourceMetadata `json:"metadata" yaml:"metadata"`
+
+	TypeMeta `json:",inline" yaml:",inline"`
+
+	Spec HorizontalPodAutoscalerSpec `json:"spec" yaml:"spec"`
+
+	Status HorizontalPodAutoscalerStatus `json:"status" yaml:"status"`
+}
+
+type HorizontalPodAutoscalerSpec struct {
+	// ScaleTargetRef is the Deployment, ReplicaSet or StatefulSet to scale, in the namespace of the autoscaler
+	ScaleTargetRef CrossVersionObjectReference `json:"scaleTargetRef" yaml:"scaleTargetRef"`
+	// MinReplicas is 1 when not set
+	MinReplicas *int `json:"minReplicas,omitempty" yaml:"minReplicas,omitempty"`
+	MaxReplicas int  `json:"maxReplicas" yaml:"maxReplicas"`
+	// Metrics are the metrics to scale by, the replicas are the most replicas any of them asks for.
+	// 80% cpu utilization when not set
+	Metrics  []MetricSpec                     `json:"metrics" yaml:"metrics"`
+	Behavior *HorizontalPodAutoscalerBehavior `json:"behavior,omitempty" yaml:"behavior,omitempty"`
+}
+
+type CrossVersionObjectReference struct {
+	Kind string `json:"kind" yaml:"kind"`
+	Name string `json:"name" yaml:"name"`
+}
+
+type MetricSpec struct {
+	// Type is Resource, the cpu or memory usage of the pods reported by the kubelets
+	Type     string                `json:"type" yaml:"type"`
+	Resource *ResourceMetricSource `json:"resource,omitempty" yaml:"resource,omitempty"`
+}
+
+type ResourceMetricSource struct {
+	// Name is cpu or memory
+	Name   string       `json:"name" yaml:"name"`
+	Target MetricTarget `json:"target" yaml:"target"`
+}
+
+type MetricTarget struct {
+	// Type is Utilization or AverageValue
+	Type string `json:"type" yaml:"type"`
+	// AverageUtilization is the percentage of the requests of the pods, for the Utilization type
+	AverageUtilization *int `json:"averageUtilization,omitempty" yaml:"averageUtilization,omitempty"`
+	// AverageValue is a quantity such as "500m" or "100Mi", for the AverageValue type
+	AverageValue string `json:"averageValue,omitempty" yaml:"averageValue,omitempty"`
+}
+
+// HorizontalPodAutoscalerBehavior bounds how fast the replicas change, a scaling direction without rules uses
+// its default stabilization window
+type HorizontalPodAutoscalerBehavior struct {
+	ScaleUp   *HPAScalingRules `json:"scaleUp,omitempty" yaml:"scaleUp,omitempty"`
+	ScaleDown *HPAScalingRules `json:"scaleDown,omitempty" yaml:"scaleDown,omitempty"`
+}
+
+type HPAScalingRules struct {
+	// StabilizationWindowSeconds is how far back the recommendations are looked at, scaling up takes the lowest
+	// recommendation of the window and scaling down the highest, so the replicas do not flap. 0 for scaling up
+	// and 300 for scaling down when not set
+	StabilizationWindowSeconds *int `json:"stabilizationWindowSeconds,omitempty" yaml:"stabilizationWindowSeconds,omitempty"`
+}
+
+type HorizontalPodAutoscalerStatus struct {
+	LastScaleTime   string                             `json:"lastScaleTime" yaml:"lastScaleTime"`
+	CurrentReplicas int                                `json:"currentReplicas" yaml:"currentReplicas"`
+	DesiredReplicas int                                `json:"desiredReplicas" yaml:"desiredReplicas"`
+	CurrentMetrics  []MetricStatus                     `json:"currentMetrics" yaml:"currentMetrics"`
+	Conditions      []HorizontalPodAutoscalerCondition `json:"conditions" yaml:"conditions"`
+}
+
+type MetricStatus struct {
+	Type     string                `json:"type" yaml:"type"`
+	Resource *ResourceMetricStatus `json:"resource,omitempty" yaml:"resource,omitempty"`
+}
+
+type ResourceMetricStatus struct {
+	Name    string            `json:"name" yaml:"name"`
+	Current MetricValueStatus `json:"current" yaml:"current"`
+}
+
+type MetricValueStatus struct {
+	AverageUtilization *int   `json:"averageUtilization,omitempty" yaml:"averageUtilization,omitempty"`
+	AverageValue       string `json:"averageValue,omitempty" yaml:"averageValue,omitempty"`
+}
+
+type HorizontalPodAutoscalerCondition struct {
+	Type string `json:"type" yaml:"type"`
+	// Status is True, False or Unknown
+	Status             string `json:"status" yaml:"status"`
+	LastTransitionTime string `json:"lastTransitionTime" yaml:"lastTransitionTime"`
+	Reason             string `json:"reason" yaml:"reason"`
+	Message            string `json:"message" yaml:"message"`
+}
+
+// ScaleUpStabilizationWindowSeconds returns the stabilization window of scaling up
+func (hpa *HorizontalPodAutoscaler) ScaleUpStabilizationWindowSeconds() int {
+	if hpa.Spec.Behavior == nil || hpa.Spec.Behavior.ScaleUp == nil || hpa.Spec.Behavior.ScaleUp.StabilizationWindowSeconds == nil {
+		return 0
+	}
+
+	return *hpa.Spec.Behavior.ScaleUp.StabilizationWindowSeconds
+}
+
+// ScaleDownStabilizationWindowSeconds returns the stabilization window of scaling down
+func (hpa *HorizontalPodAutoscaler) ScaleDownStabilizationWindowSeconds() int {
+	if hpa.Spec.Behavior == nil || hpa.Spec.Behavior.ScaleDown == nil || hpa.Spec.Behavior.ScaleDown.StabilizationWindowSeconds == nil {
+		return defaultScaleDownStabilizationWindowSeconds
+	}
+
+	return *hpa.Spec.Behavior.ScaleDown.StabilizationWindowSeconds
+}
+
+// SetCondition adds the condition or replaces the one of the same type, the transition time is kept
+// when the status did not change
+func (hpa *HorizontalPodAutoscaler) SetCondition(condition HorizontalPodAutoscalerCondition) {
+	for index := range hpa.Status.Conditions {
+		existing := &hpa.Status.Conditions[index]
+		if existing.Type != condition.Type {
+			continue
+		}
+
+		if existing.Status == condition.Status {
+			condition.LastTransitionTime = existing.LastTransitionTime
+		}
+
+		*existing = condition
+
+		return
+	}
+
+	hpa.Status.Conditions = append(hpa.Status.Conditions, condition)
+}
+
+func (hpa *HorizontalPodAutoscaler) setDefaults() {
+	if hpa.Spec.MinReplicas == nil {
+		minReplicas := 1
+		hpa.Spec.MinReplicas = &minReplicas
+	}
+
+	if len(hpa.Spec.Metrics) == 0 {
+		targetCPUUtilization := defaultTargetCPUUtilization
+		hpa.Spec.Metrics = []MetricSpec{{
+			Type: ResourceMetricSourceType,
+			Resource: &ResourceMetricSource{
+				Name: ResourceCPU,
+				Target: MetricTarget{
+					Type:               UtilizationMetricType,
+					AverageUtilization: &targetCPUUtilization,
+				},
+			},
+		}}
+	}
+}
+
+func (hpa *HorizontalPodAutoscaler) validate() error {
+	if hpa.Metadata.Name == "" {
+		return fmt.Errorf("horizontalpodautoscaler name is required")
+	}
+
+	if !slices.Contains(ScalableKinds, hpa.Spec.ScaleTargetRef.Kind) || hpa.Spec.ScaleTargetRef.Name == "" {
+		return fmt.Errorf("spec.scaleTargetRef must be one of %v with a name", ScalableKinds)
+	}
+
+	if *hpa.Spec.MinReplicas < 1 {
+		return fmt.Errorf("spec.minReplicas must be at least 1")
+	}
+
+	if hpa.Spec.MaxReplicas < *hpa.Spec.MinReplicas {
+		return fmt.Errorf("spec.maxReplicas must be at least spec.minReplicas")
+	}
+
+	for index, metric := range hpa.Spec.Metrics {
+		if err := metric.validate(); err != nil {
+			return fmt.Errorf("spec.metrics[%d].%v", index, err)
+		}
+	}
+
+	if hpa.Spec.Behavior != nil {
+		for _, rules := range []*HPAScalingRules{hpa.Spec.Behavior.ScaleUp, hpa.Spec.Behavior.ScaleDown} {
+			if rules != nil && rules.StabilizationWindowSeconds != nil &&
+				(*rules.StabilizationWindowSeconds < 0 || *rules.StabilizationWindowSeconds > maxStabilizationWindowSeconds) {
+				return fmt.Errorf("spec.behavior stabilizationWindowSeconds must be between 0 and %d", maxStabilizationWindowSeconds)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (metric MetricSpec) validate() error {
+	if metric.Type != ResourceMetricSourceType || metric.Resource == nil {
+		return fmt.Errorf("type must be %s with a resource", ResourceMetricSourceType)
+	}
+
+	if metric.Resource.Name != ResourceCPU && metric.Resource.Name != ResourceMemory {
+		return fmt.Errorf("resource.name must be %s or %s", ResourceCPU, ResourceMemory)
+	}
+
+	target := metric.Resource.Target
+
+	switch target.Type {
+	case UtilizationMetricType:
+		if target.AverageUtilization == nil || *target.AverageUtilization <= 0 {
+			return fmt.Errorf("resource.target.averageUtilization must be positive")
+		}
+	case AverageValueMetricType:
+		value, err := utils.ParseQuantity(target.AverageValue)
+		if err != nil || value <= 0 {
+			return fmt.Errorf("resource.target.averageValue must be a positive quantity")
+		}
+	default:
+		return fmt.Errorf("resource.target.type must be %s or %s", UtilizationMetricType, AverageValueMetricType)
+	}
+
+	return nil
+}
+
+func (hpa *HorizontalPodAutoscaler) Register(container *restful.Container, etcdService etcd.EtcdService) {
+	log.Println("rest api horizontalpodautoscaler register")
+
+	etcdServiceAppHorizontalPodAutoscaler = etcdService
+
+	ws := new(restful.WebService)
+
+	ws.Filter(kubeapi_logger.LoggerMiddleware)
+
+	ws.Path("/horizontalpodautoscalers").
+		Consumes(restful.MIME_JSON, MimeYAML).
+		Produces(restful.MIME_JSON, MimeYAML)
+
+	ws.Route(ws.GET("/").To(hpa.getAll).
+		Param(ws.QueryParameter("watch", "boolean for watching resource").DataType("bool").DefaultValue("false")).
+		Param(ws.QueryParameter("fieldSelector", "field selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("labelSelector", "label selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("resourceVersion", "resource version to start the watch from").DataType("string").DefaultValue("")))
+
+	container.Add(ws)
+}
+
+func (hpa *HorizontalPodAutoscaler) getAll(req *restful.Request, resp *restful.Response) {
+	watchQuery := req.QueryParameter("watch")
+
+	if watchQuery == "true" {
+		watchFromWatchCache(req, resp, horizontalPodAutoscalerEtcdKey, "")
+
+		return
+	}
+
+	listFromWatchCache(req, resp, horizontalPodAutoscalerEtcdKey, "")
+}
@@ -0,0 +1,123 @@
+package rest
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// maxIPRangeBits limits the bitmap of an ip range to 128KiB
+const maxIPRangeBits = 20
+
+// ipAllocator allocates the ips of an ipv4 cidr, without its network and broadcast addresses
+type ipAllocator struct {
+	rangeAllocator
+
+	network *net.IPNet
+	base    uint32
+}
+
+func newIPAllocator(etcdKey string, cidr string) (*ipAllocator, error) {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ip range %s: %v", cidr, err)
+	}
+
+	if network.IP.To4() == nil {
+		return nil, fmt.Errorf("invalid ip range %s: only ipv4 ranges are supported", cidr)
+	}
+
+	ones, bits := network.Mask.Size()
+	if bits-ones < 2 || bits-ones > maxIPRangeBits {
+		return nil, fmt.Errorf("invalid ip range %s: the prefix length must be between /%d and /%d",
+			cidr, bits-maxIPRangeBits, bits-2)
+	}
+
+	return &ipAllocator{
+		rangeAllocator: rangeAllocator{
+			etcdKey:   etcdKey,
+			rangeName: network.String(),
+			size:      1<<(bits-ones) - 2,
+		},
+		network: network,
+		base:    binary.BigEndian.Uint32(network.IP.To4()),
+	}, nil
+}
+
+// offset returns the offset of the ip in the range, false when the ip is not an allocatable ip of the range
+func (allocator *ipAllocator) offset(ip net.IP) (int, bool) {
+	if ip.To4() == nil || !allocator.network.Contains(ip) {
+		return 0, false
+	}
+
+	offset := int(binary.BigEndian.Uint32(ip.To4())-allocator.base) - 1
+	if offset < 0 || offset >= allocator.size {
+		return 0, false
+	}
+
+	return offset, true
+}
+
+func (allocator *ipAllocator) ip(offset int) string {
+	ip := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(ip, allocator.base+uint32(offset)+1)
+
+	return ip.String()
+}
+
+// allocate reserves the ip requested by the user
+func (allocator *ipAllocator) allocate(requestedIP string) error {
+	ip := net.ParseIP(requestedIP)
+	if ip == nil {
+		return &updateError{
+			statusCode: http.StatusUnprocessableEntity,
+			message:    fmt.Sprintf("spec.clusterIP: invalid value %q, must be a valid ip or %s", requestedIP, ClusterIPNone),
+		}
+	}
+
+	offset, ok := allocator.offset(ip)
+	if !ok {
+		return &updateError{
+			statusCode: http.StatusUnprocessableEntity,
+			message:    fmt.Sprintf("spec.clusterIP: invalid value %q, not in the service ip range %s", requestedIP, allocator.rangeName),
+		}
+	}
+
+	err := allocator.rangeAllocator.allocate(offset)
+	if errors.Is(err, errRangeItemAllocated) {
+		return &updateError{
+			statusCode: http.StatusUnprocessableEntity,
+			message:    fmt.Sprintf("spec.clusterIP: invalid value %q, the ip is already allocated", requestedIP),
+		}
+	}
+
+	return err
+}
+
+func (allocator *ipAllocator) allocateNext() (string, error) {
+	offset, err := allocator.rangeAllocator.allocateNext()
+	if errors.Is(err, errRangeFull) {
+		return "", &updateError{
+			statusCode: http.StatusInternalServerError,
+			message:    fmt.Sprintf("no clusterIP left in the service ip range %s", allocator.rangeName),
+		}
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return allocator.ip(offset), nil
+}
+
+// release frees the ip, ips outside of the range were never allocated from it and are ignored
+func (allocator *ipAllocator) release(releasedIP string) error {
+	offset, ok := allocator.offset(net.ParseIP(releasedIP))
+	if !ok {
+		return nil
+	}
+
+	return allocator.rangeAllocator.release(offset)
+}
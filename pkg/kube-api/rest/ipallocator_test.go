@@ -0,0 +1,178 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestNewIPAllocatorErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cidr string
+	}{
+		{name: "not a cidr", cidr: "10.96.0.0"},
+		{name: "ipv6", cidr: "fd00::/112"},
+		{name: "no allocatable ip", cidr: "10.96.0.0/31"},
+		{name: "too large", cidr: "10.0.0.0/8"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := newIPAllocator(serviceClusterIPsEtcdKey, test.cidr); err == nil {
+				t.Errorf("newIPAllocator(%q) expected an error", test.cidr)
+			}
+		})
+	}
+}
+
+func TestIPAllocator(t *testing.T) {
+	type step struct {
+		operation string
+		ip        string
+		// want is the ip allocated by allocateNext
+		want string
+		// wantStatusCode is the status code of the updateError returned, 0 when no error is returned
+		wantStatusCode int
+	}
+
+	tests := []struct {
+		name  string
+		cidr  string
+		steps []step
+	}{
+		{
+			name: "allocate next skips the network address",
+			cidr: "10.96.0.0/29",
+			steps: []step{
+				{operation: "allocateNext", want: "10.96.0.1"},
+				{operation: "allocateNext", want: "10.96.0.2"},
+			},
+		},
+		{
+			name: "claim a specific ip",
+			cidr: "10.96.0.0/29",
+			steps: []step{
+				{operation: "allocate", ip: "10.96.0.1"},
+				{operation: "allocate", ip: "10.96.0.6"},
+				{operation: "allocateNext", want: "10.96.0.2"},
+			},
+		},
+		{
+			name: "claim an allocated ip",
+			cidr: "10.96.0.0/29",
+			steps: []step{
+				{operation: "allocateNext", want: "10.96.0.1"},
+				{operation: "allocate", ip: "10.96.0.1", wantStatusCode: http.StatusUnprocessableEntity},
+			},
+		},
+		{
+			name: "claim an invalid ip",
+			cidr: "10.96.0.0/29",
+			steps: []step{
+				{operation: "allocate", ip: "10.96.0", wantStatusCode: http.StatusUnprocessableEntity},
+			},
+		},
+		{
+			name: "claim ips outside of the allocatable range",
+			cidr: "10.96.0.0/29",
+			steps: []step{
+				{operation: "allocate", ip: "10.97.0.1", wantStatusCode: http.StatusUnprocessableEntity},
+				{operation: "allocate", ip: "10.96.0.0", wantStatusCode: http.StatusUnprocessableEntity},
+				{operation: "allocate", ip: "10.96.0.7", wantStatusCode: http.StatusUnprocessableEntity},
+				{operation: "allocate", ip: "fd00::1", wantStatusCode: http.StatusUnprocessableEntity},
+			},
+		},
+		{
+			name: "released ip is allocated again",
+			cidr: "10.96.0.0/29",
+			steps: []step{
+				{operation: "allocateNext", want: "10.96.0.1"},
+				{operation: "allocateNext", want: "10.96.0.2"},
+				{operation: "release", ip: "10.96.0.1"},
+				{operation: "allocateNext", want: "10.96.0.1"},
+				{operation: "release", ip: "10.96.0.2"},
+				{operation: "allocate", ip: "10.96.0.2"},
+			},
+		},
+		{
+			name: "release ips that were never allocated from the range",
+			cidr: "10.96.0.0/29",
+			steps: []step{
+				{operation: "release", ip: "10.97.0.1"},
+				{operation: "release", ip: ClusterIPNone},
+				{operation: "release", ip: "10.96.0.3"},
+			},
+		},
+		{
+			name: "exhaust the range",
+			cidr: "10.96.0.0/30",
+			steps: []step{
+				{operation: "allocateNext", want: "10.96.0.1"},
+				{operation: "allocateNext", want: "10.96.0.2"},
+				{operation: "allocateNext", wantStatusCode: http.StatusInternalServerError},
+				{operation: "release", ip: "10.96.0.2"},
+				{operation: "allocateNext", want: "10.96.0.2"},
+			},
+		},
+		{
+			name: "range not aligned to the prefix",
+			cidr: "10.96.1.7/24",
+			steps: []step{
+				{operation: "allocateNext", want: "10.96.1.1"},
+				{operation: "allocate", ip: "10.96.1.254"},
+				{operation: "allocate", ip: "10.96.1.255", wantStatusCode: http.StatusUnprocessableEntity},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useFakeEtcdService(t)
+
+			allocator, err := newIPAllocator(serviceClusterIPsEtcdKey, test.cidr)
+			if err != nil {
+				t.Fatalf("newIPAllocator(%q) error: %v", test.cidr, err)
+			}
+
+			for index, current := range test.steps {
+				var allocated string
+
+				switch current.operation {
+				case "allocate":
+					err = allocator.allocate(current.ip)
+				case "allocateNext":
+					allocated, err = allocator.allocateNext()
+				case "release":
+					err = allocator.release(current.ip)
+				}
+
+				if statusCode := updateErrorStatusCode(t, err); statusCode != current.wantStatusCode {
+					t.Fatalf("step %d %s %s error = %v, want status code %d",
+						index, current.operation, current.ip, err, current.wantStatusCode)
+				}
+
+				if err == nil && current.operation == "allocateNext" && allocated != current.want {
+					t.Fatalf("step %d allocateNext = %s, want %s", index, allocated, current.want)
+				}
+			}
+		})
+	}
+}
+
+// updateErrorStatusCode returns the status code of the updateError, 0 for no error, and fails the test on any
+// other error
+func updateErrorStatusCode(t *testing.T, err error) int {
+	t.Helper()
+
+	if err == nil {
+		return 0
+	}
+
+	var updateErr *updateError
+	if !errors.As(err, &updateErr) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return updateErr.statusCode
+}
@@ -0,0 +1,200 @@
+package rest
+
+import (
+	"fmt"
+	"log"
+
+	restful "github.com/emicklei/go-restful/v3"
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/etcd"
+	kubeapi_logger "github.com/jonatan5524/own-kubernetes/pkg/kube-api/logger"
+)
+
+const (
+	jobEtcdKey = "/jobs"
+
+	// JobComplete is true once the job has its completions
+	JobComplete = "Complete"
+	// JobFailed is true once the job gave up, after backoffLimit failed pods or activeDeadlineSeconds
+	JobFailed = "Failed"
+
+	// ControllerUIDLabelKey and JobNameLabelKey are set on the generated selector and template of a job
+	// created without a selector
+	ControllerUIDLabelKey = "controller-uid"
+	JobNameLabelKey       = "job-name"
+
+	// same default as kubernetes
+	defaultBackoffLimit = 6
+)
+
+var etcdServiceAppJob etcd.EtcdService
+
+// Job runs pods of its template until completions of them succeeded, with at most parallelism pods at a time
+type Job struct {
+	Metadata ResourceMetadata `json:"metadata" yaml:"metadata"`
+
+	TypeMeta `json:",inline" yaml:",inline"`
+
+	Spec JobSpec `json:"spec" yaml:"spec"`
+
+	Status JobStatus `json:"status" yaml:"status"`
+}
+
+type JobSpec struct {
+	// Parallelism is the most pods running at a time, 1 when not set
+	Parallelism *int `json:"parallelism,omitempty" yaml:"parallelism,omitempty"`
+	// Completions is the number of pods that must succeed, 1 when not set
+	Completions *int `json:"completions,omitempty" yaml:"completions,omitempty"`
+	// BackoffLimit is the number of failed pods after which the job fails, 6 when not set
+	BackoffLimit *int `json:"backoffLimit,omitempty" yaml:"backoffLimit,omitempty"`
+	// ActiveDeadlineSeconds is how long the job may run from its start before it fails, no limit when not set
+	ActiveDeadlineSeconds *int `json:"activeDeadlineSeconds,omitempty" yaml:"activeDeadlineSeconds,omitempty"`
+	// Selector is generated from the UID of the job when not set
+	Selector LabelSelector   `json:"selector" yaml:"selector"`
+	Template PodTemplateSpec `json:"template" yaml:"template"`
+}
+
+type JobStatus struct {
+	Active    int `json:"active" yaml:"active"`
+	Succeeded int `json:"succeeded" yaml:"succeeded"`
+	Failed    int `json:"failed" yaml:"failed"`
+
+	StartTime      string `json:"startTime" yaml:"startTime"`
+	CompletionTime string `json:"completionTime" yaml:"completionTime"`
+
+	Conditions []JobCondition `json:"conditions" yaml:"conditions"`
+}
+
+type JobCondition struct {
+	Type string `json:"type" yaml:"type"`
+	// Status is True, False or Unknown
+	Status             string `json:"status" yaml:"status"`
+	LastProbeTime      string `json:"lastProbeTime" yaml:"lastProbeTime"`
+	LastTransitionTime string `json:"lastTransitionTime" yaml:"lastTransitionTime"`
+	Reason             string `json:"reason" yaml:"reason"`
+	Message            string `json:"message" yaml:"message"`
+}
+
+// IsFinished tells if the job completed or failed, a finished job does not run pods anymore
+func (job *Job) IsFinished() bool {
+	for _, condition := range job.Status.Conditions {
+		if (condition.Type == JobComplete || condition.Type == JobFailed) && condition.Status == ConditionTrue {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsComplete tells if the job finished with its completions
+func (job *Job) IsComplete() bool {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == JobComplete && condition.Status == ConditionTrue {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (spec *JobSpec) setDefaults() {
+	if spec.Parallelism == nil {
+		parallelism := 1
+		spec.Parallelism = &parallelism
+	}
+
+	if spec.Completions == nil {
+		completions := 1
+		spec.Completions = &completions
+	}
+
+	if spec.BackoffLimit == nil {
+		backoffLimit := defaultBackoffLimit
+		spec.BackoffLimit = &backoffLimit
+	}
+}
+
+// generateSelector selects the pods of the job by its UID, the template labels are copied so the template of
+// the request is not changed
+func (spec *JobSpec) generateSelector(uid string, name string) {
+	spec.Selector = LabelSelector{MatchLabels: map[string]string{ControllerUIDLabelKey: uid}}
+
+	labels := make(map[string]string, len(spec.Template.Metadata.Labels)+2)
+	for key, value := range spec.Template.Metadata.Labels {
+		labels[key] = value
+	}
+
+	labels[ControllerUIDLabelKey] = uid
+	labels[JobNameLabelKey] = name
+	spec.Template.Metadata.Labels = labels
+}
+
+func (spec *JobSpec) validate() error {
+	if *spec.Parallelism < 0 {
+		return fmt.Errorf("spec.parallelism must not be negative")
+	}
+
+	if *spec.Completions < 0 {
+		return fmt.Errorf("spec.completions must not be negative")
+	}
+
+	if *spec.BackoffLimit < 0 {
+		return fmt.Errorf("spec.backoffLimit must not be negative")
+	}
+
+	if spec.ActiveDeadlineSeconds != nil && *spec.ActiveDeadlineSeconds <= 0 {
+		return fmt.Errorf("spec.activeDeadlineSeconds must be positive")
+	}
+
+	// the selector is generated when it is not set
+	if len(spec.Selector.MatchLabels) == 0 {
+		if len(spec.Template.Spec.Containers) == 0 {
+			return fmt.Errorf("spec.template.spec.containers is required")
+		}
+
+		return nil
+	}
+
+	return validateTemplateSelector(spec.Selector, spec.Template)
+}
+
+func (job *Job) validate() error {
+	if job.Metadata.Name == "" {
+		return fmt.Errorf("job name is required")
+	}
+
+	return job.Spec.validate()
+}
+
+func (job *Job) Register(container *restful.Container, etcdService etcd.EtcdService) {
+	log.Println("rest api job register")
+
+	etcdServiceAppJob = etcdService
+
+	ws := new(restful.WebService)
+
+	ws.Filter(kubeapi_logger.LoggerMiddleware)
+
+	ws.Path("/jobs").
+		Consumes(restful.MIME_JSON, MimeYAML).
+		Produces(restful.MIME_JSON, MimeYAML)
+
+	ws.Route(ws.GET("/").To(job.getAll).
+		Param(ws.QueryParameter("watch", "boolean for watching resource").DataType("bool").DefaultValue("false")).
+		Param(ws.QueryParameter("fieldSelector", "field selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("labelSelector", "label selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("resourceVersion", "resource version to start the watch from").DataType("string").DefaultValue("")))
+
+	container.Add(ws)
+}
+
+func (job *Job) getAll(req *restful.Request, resp *restful.Response) {
+	watchQuery := req.QueryParameter("watch")
+
+	if watchQuery == "true" {
+		watchFromWatchCache(req, resp, jobEtcdKey, "")
+
+		return
+	}
+
+	listFromWatchCache(req, resp, jobEtcdKey, "")
+}
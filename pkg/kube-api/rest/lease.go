@@ -0,0 +1,69 @@
+package rest
+
+import (
+	"log"
+
+	restful "github.com/emicklei/go-restful/v3"
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/etcd"
+	kubeapi_logger "github.com/jonatan5524/own-kubernetes/pkg/kube-api/logger"
+)
+
+const (
+	leaseEtcdKey = "/leases"
+
+	// NodeLeaseNamespace holds a lease per node, renewing it is the heartbeat of the kubelet
+	NodeLeaseNamespace = "kube-node-lease"
+)
+
+var etcdServiceAppLease etcd.EtcdService
+
+// Lease is held by a single holder that renews it, the holder is gone when it is not renewed for the lease duration
+type Lease struct {
+	Metadata ResourceMetadata `json:"metadata" yaml:"metadata"`
+
+	TypeMeta `json:",inline" yaml:",inline"`
+
+	Spec LeaseSpec `json:"spec" yaml:"spec"`
+}
+
+type LeaseSpec struct {
+	HolderIdentity       string `json:"holderIdentity" yaml:"holderIdentity"`
+	LeaseDurationSeconds int    `json:"leaseDurationSeconds" yaml:"leaseDurationSeconds"`
+	AcquireTime          string `json:"acquireTime" yaml:"acquireTime"`
+	RenewTime            string `json:"renewTime" yaml:"renewTime"`
+	LeaseTransitions     int    `json:"leaseTransitions" yaml:"leaseTransitions"`
+}
+
+func (lease *Lease) Register(container *restful.Container, etcdService etcd.EtcdService) {
+	log.Println("rest api lease register")
+
+	etcdServiceAppLease = etcdService
+
+	ws := new(restful.WebService)
+
+	ws.Filter(kubeapi_logger.LoggerMiddleware)
+
+	ws.Path("/leases").
+		Consumes(restful.MIME_JSON, MimeYAML).
+		Produces(restful.MIME_JSON, MimeYAML)
+
+	ws.Route(ws.GET("/").To(lease.getAll).
+		Param(ws.QueryParameter("watch", "boolean for watching resource").DataType("bool").DefaultValue("false")).
+		Param(ws.QueryParameter("fieldSelector", "field selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("labelSelector", "label selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("resourceVersion", "resource version to start the watch from").DataType("string").DefaultValue("")))
+
+	container.Add(ws)
+}
+
+func (lease *Lease) getAll(req *restful.Request, resp *restful.Response) {
+	watchQuery := req.QueryParameter("watch")
+
+	if watchQuery == "true" {
+		watchFromWatchCache(req, resp, leaseEtcdKey, "")
+
+		return
+	}
+
+	listFromWatchCache(req, resp, leaseEtcdKey, "")
+}
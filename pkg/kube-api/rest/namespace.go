@@ -2,9 +2,12 @@ package rest
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"reflect"
+	"strconv"
 	"strings"
 	"time"
 
@@ -12,36 +15,36 @@ import (
 	"github.com/google/uuid"
 	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/etcd"
 	kubeapi_logger "github.com/jonatan5524/own-kubernetes/pkg/kube-api/logger"
-	"github.com/tidwall/gjson"
 )
 
 const namespaceEtcdKey = "/namespaces"
 
 var (
-	setupNamespaces         = [...]string{"default", "kube-system"}
+	setupNamespaces         = [...]string{"default", "kube-system", NodeLeaseNamespace}
 	etcdServiceAppNamespace etcd.EtcdService
 )
 
 type Namespace struct {
 	Metadata ResourceMetadata `json:"metadata" yaml:"metadata"`
 
-	Kind string `json:"kind" yaml:"kind"`
+	TypeMeta `json:",inline" yaml:",inline"`
 }
 
-func (namespace *Namespace) Register(etcdServersEndpoints string) {
+func (namespace *Namespace) Register(container *restful.Container, etcdService etcd.EtcdService) {
 	log.Println("rest api namespace register")
 
-	etcdServiceAppNamespace = etcd.NewEtcdService(etcdServersEndpoints)
+	etcdServiceAppNamespace = etcdService
 
 	ws := new(restful.WebService)
 
 	ws.Filter(kubeapi_logger.LoggerMiddleware)
 
 	ws.Path("/namespaces").
-		Consumes(restful.MIME_XML, restful.MIME_JSON).
-		Produces(restful.MIME_JSON, restful.MIME_XML)
+		Consumes(restful.MIME_JSON, MimeYAML).
+		Produces(restful.MIME_JSON, MimeYAML)
 
-	ws.Route(ws.GET("/").To(namespace.getNamespaces))
+	ws.Route(ws.GET("/").To(namespace.getNamespaces).
+		Param(ws.QueryParameter("labelSelector", "label selector for resource").DataType("string").DefaultValue("")))
 
 	ws.Route(ws.POST("/").To(namespace.createNamespace).
 		Param(ws.BodyParameter("Namespace", "a Namespace resource (JSON)").DataType("rest.Namespace")))
@@ -50,17 +53,107 @@ func (namespace *Namespace) Register(etcdServersEndpoints string) {
 	ws.Route(ws.GET("/{namespace}/pods").To(namespace.getPods).Filter(validateNamespaceExists).
 		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
 		Param(ws.QueryParameter("watch", "boolean for watching resource").DataType("bool").DefaultValue("false")).
-		Param(ws.QueryParameter("fieldSelector", "field selector for resource").DataType("string").DefaultValue("")))
+		Param(ws.QueryParameter("fieldSelector", "field selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("labelSelector", "label selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("resourceVersion", "resource version to start the watch from").DataType("string").DefaultValue("")))
 
 	ws.Route(ws.GET("/{namespace}/services").To(namespace.getServices).Filter(validateNamespaceExists).
 		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
 		Param(ws.QueryParameter("watch", "boolean for watching resource").DataType("bool").DefaultValue("false")).
-		Param(ws.QueryParameter("fieldSelector", "field selector for resource").DataType("string").DefaultValue("")))
+		Param(ws.QueryParameter("fieldSelector", "field selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("labelSelector", "label selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("resourceVersion", "resource version to start the watch from").DataType("string").DefaultValue("")))
 
 	ws.Route(ws.GET("/{namespace}/endpoints").To(namespace.getEndpoints).Filter(validateNamespaceExists).
 		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
 		Param(ws.QueryParameter("watch", "boolean for watching resource").DataType("bool").DefaultValue("false")).
-		Param(ws.QueryParameter("fieldSelector", "field selector for resource").DataType("string").DefaultValue("")))
+		Param(ws.QueryParameter("fieldSelector", "field selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("labelSelector", "label selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("resourceVersion", "resource version to start the watch from").DataType("string").DefaultValue("")))
+
+	ws.Route(ws.GET("/{namespace}/events").To(namespace.getEvents).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.QueryParameter("watch", "boolean for watching resource").DataType("bool").DefaultValue("false")).
+		Param(ws.QueryParameter("fieldSelector", "field selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("labelSelector", "label selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("resourceVersion", "resource version to start the watch from").DataType("string").DefaultValue("")))
+
+	ws.Route(ws.GET("/{namespace}/configmaps").To(namespace.getConfigMaps).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.QueryParameter("watch", "boolean for watching resource").DataType("bool").DefaultValue("false")).
+		Param(ws.QueryParameter("fieldSelector", "field selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("labelSelector", "label selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("resourceVersion", "resource version to start the watch from").DataType("string").DefaultValue("")))
+
+	ws.Route(ws.GET("/{namespace}/secrets").To(namespace.getSecrets).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.QueryParameter("watch", "boolean for watching resource").DataType("bool").DefaultValue("false")).
+		Param(ws.QueryParameter("fieldSelector", "field selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("labelSelector", "label selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("resourceVersion", "resource version to start the watch from").DataType("string").DefaultValue("")))
+
+	ws.Route(ws.GET("/{namespace}/leases").To(namespace.getLeases).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.QueryParameter("watch", "boolean for watching resource").DataType("bool").DefaultValue("false")).
+		Param(ws.QueryParameter("fieldSelector", "field selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("labelSelector", "label selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("resourceVersion", "resource version to start the watch from").DataType("string").DefaultValue("")))
+
+	ws.Route(ws.GET("/{namespace}/replicasets").To(namespace.getReplicaSets).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.QueryParameter("watch", "boolean for watching resource").DataType("bool").DefaultValue("false")).
+		Param(ws.QueryParameter("fieldSelector", "field selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("labelSelector", "label selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("resourceVersion", "resource version to start the watch from").DataType("string").DefaultValue("")))
+
+	ws.Route(ws.GET("/{namespace}/deployments").To(namespace.getDeployments).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.QueryParameter("watch", "boolean for watching resource").DataType("bool").DefaultValue("false")).
+		Param(ws.QueryParameter("fieldSelector", "field selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("labelSelector", "label selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("resourceVersion", "resource version to start the watch from").DataType("string").DefaultValue("")))
+
+	ws.Route(ws.GET("/{namespace}/daemonsets").To(namespace.getDaemonSets).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.QueryParameter("watch", "boolean for watching resource").DataType("bool").DefaultValue("false")).
+		Param(ws.QueryParameter("fieldSelector", "field selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("labelSelector", "label selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("resourceVersion", "resource version to start the watch from").DataType("string").DefaultValue("")))
+
+	ws.Route(ws.GET("/{namespace}/statefulsets").To(namespace.getStatefulSets).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.QueryParameter("watch", "boolean for watching resource").DataType("bool").DefaultValue("false")).
+		Param(ws.QueryParameter("fieldSelector", "field selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("labelSelector", "label selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("resourceVersion", "resource version to start the watch from").DataType("string").DefaultValue("")))
+
+	ws.Route(ws.GET("/{namespace}/horizontalpodautoscalers").To(namespace.getHorizontalPodAutoscalers).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.QueryParameter("watch", "boolean for watching resource").DataType("bool").DefaultValue("false")).
+		Param(ws.QueryParameter("fieldSelector", "field selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("labelSelector", "label selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("resourceVersion", "resource version to start the watch from").DataType("string").DefaultValue("")))
+
+	ws.Route(ws.GET("/{namespace}/podmetrics").To(namespace.getPodsMetrics).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.QueryParameter("watch", "boolean for watching resource").DataType("bool").DefaultValue("false")).
+		Param(ws.QueryParameter("fieldSelector", "field selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("labelSelector", "label selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("resourceVersion", "resource version to start the watch from").DataType("string").DefaultValue("")))
+
+	ws.Route(ws.GET("/{namespace}/jobs").To(namespace.getJobs).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.QueryParameter("watch", "boolean for watching resource").DataType("bool").DefaultValue("false")).
+		Param(ws.QueryParameter("fieldSelector", "field selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("labelSelector", "label selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("resourceVersion", "resource version to start the watch from").DataType("string").DefaultValue("")))
+
+	ws.Route(ws.GET("/{namespace}/cronjobs").To(namespace.getCronJobs).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.QueryParameter("watch", "boolean for watching resource").DataType("bool").DefaultValue("false")).
+		Param(ws.QueryParameter("fieldSelector", "field selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("labelSelector", "label selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("resourceVersion", "resource version to start the watch from").DataType("string").DefaultValue("")))
 
 	// --- GetSingleResource ----
 	ws.Route(ws.GET("/{namespace}/pods/{name}").To(namespace.getPod).Filter(validateNamespaceExists).
@@ -75,11 +168,76 @@ func (namespace *Namespace) Register(etcdServersEndpoints string) {
 		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
 		Param(ws.PathParameter("name", "name of the service").DataType("string")))
 
+	ws.Route(ws.GET("/{namespace}/events/{name}").To(namespace.getEvent).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.PathParameter("name", "name of the event").DataType("string")))
+
+	ws.Route(ws.GET("/{namespace}/configmaps/{name}").To(namespace.getConfigMap).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.PathParameter("name", "name of the configmap").DataType("string")))
+
+	ws.Route(ws.GET("/{namespace}/secrets/{name}").To(namespace.getSecret).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.PathParameter("name", "name of the secret").DataType("string")))
+
+	ws.Route(ws.GET("/{namespace}/leases/{name}").To(namespace.getLease).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.PathParameter("name", "name of the lease").DataType("string")))
+
+	ws.Route(ws.GET("/{namespace}/replicasets/{name}").To(namespace.getReplicaSet).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.PathParameter("name", "name of the replicaset").DataType("string")))
+
+	ws.Route(ws.GET("/{namespace}/deployments/{name}").To(namespace.getDeployment).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.PathParameter("name", "name of the deployment").DataType("string")))
+
+	ws.Route(ws.GET("/{namespace}/daemonsets/{name}").To(namespace.getDaemonSet).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.PathParameter("name", "name of the daemonset").DataType("string")))
+
+	ws.Route(ws.GET("/{namespace}/statefulsets/{name}").To(namespace.getStatefulSet).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.PathParameter("name", "name of the statefulset").DataType("string")))
+
+	ws.Route(ws.GET("/{namespace}/horizontalpodautoscalers/{name}").To(namespace.getHorizontalPodAutoscaler).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.PathParameter("name", "name of the horizontalpodautoscaler").DataType("string")))
+
+	ws.Route(ws.GET("/{namespace}/podmetrics/{name}").To(namespace.getPodMetrics).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.PathParameter("name", "name of the pod").DataType("string")))
+
+	ws.Route(ws.GET("/{namespace}/deployments/{name}/scale").To(namespace.getDeploymentScale).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.PathParameter("name", "name of the deployment").DataType("string")))
+
+	ws.Route(ws.GET("/{namespace}/replicasets/{name}/scale").To(namespace.getReplicaSetScale).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.PathParameter("name", "name of the replicaset").DataType("string")))
+
+	ws.Route(ws.GET("/{namespace}/statefulsets/{name}/scale").To(namespace.getStatefulSetScale).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.PathParameter("name", "name of the statefulset").DataType("string")))
+
+	ws.Route(ws.GET("/{namespace}/jobs/{name}").To(namespace.getJob).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.PathParameter("name", "name of the job").DataType("string")))
+
+	ws.Route(ws.GET("/{namespace}/cronjobs/{name}").To(namespace.getCronJob).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.PathParameter("name", "name of the cronjob").DataType("string")))
+
 	// --- Create ----
 	ws.Route(ws.POST("/{namespace}/pods").To(namespace.createPod).Filter(validateNamespaceExists).
 		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
 		Param(ws.BodyParameter("Pod", "a Pod resource (JSON)").DataType("rest.Pod")))
 
+	ws.Route(ws.POST("/{namespace}/pods/{name}/binding").To(namespace.bindPod).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.PathParameter("name", "name of the pod").DataType("string")).
+		Param(ws.BodyParameter("Binding", "a Binding resource (JSON)").DataType("rest.Binding")))
+
 	ws.Route(ws.POST("/{namespace}/services").To(namespace.createService).Filter(validateNamespaceExists).
 		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
 		Param(ws.BodyParameter("Service", "a Service resource (JSON)").DataType("rest.Service")))
@@ -88,6 +246,59 @@ func (namespace *Namespace) Register(etcdServersEndpoints string) {
 		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
 		Param(ws.BodyParameter("Service", "a Service resource (JSON)").DataType("rest.Endpoint")))
 
+	ws.Route(ws.POST("/{namespace}/events").To(namespace.createEvent).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.BodyParameter("Event", "a Event resource (JSON)").DataType("rest.Event")))
+
+	ws.Route(ws.POST("/{namespace}/configmaps").To(namespace.createConfigMap).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.BodyParameter("ConfigMap", "a ConfigMap resource (JSON)").DataType("rest.ConfigMap")))
+
+	ws.Route(ws.POST("/{namespace}/secrets").To(namespace.createSecret).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.BodyParameter("Secret", "a Secret resource (JSON)").DataType("rest.Secret")))
+
+	ws.Route(ws.POST("/{namespace}/leases").To(namespace.createLease).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.BodyParameter("Lease", "a Lease resource (JSON)").DataType("rest.Lease")))
+
+	ws.Route(ws.POST("/{namespace}/replicasets").To(namespace.createReplicaSet).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.BodyParameter("ReplicaSet", "a ReplicaSet resource (JSON)").DataType("rest.ReplicaSet")))
+
+	ws.Route(ws.POST("/{namespace}/deployments").To(namespace.createDeployment).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.BodyParameter("Deployment", "a Deployment resource (JSON)").DataType("rest.Deployment")))
+
+	ws.Route(ws.POST("/{namespace}/deployments/{name}/rollback").To(namespace.rollbackDeployment).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.PathParameter("name", "name of the deployment").DataType("string")).
+		Param(ws.BodyParameter("DeploymentRollback", "a DeploymentRollback resource (JSON)").DataType("rest.DeploymentRollback")))
+
+	ws.Route(ws.POST("/{namespace}/daemonsets").To(namespace.createDaemonSet).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.BodyParameter("DaemonSet", "a DaemonSet resource (JSON)").DataType("rest.DaemonSet")))
+
+	ws.Route(ws.POST("/{namespace}/statefulsets").To(namespace.createStatefulSet).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.BodyParameter("StatefulSet", "a StatefulSet resource (JSON)").DataType("rest.StatefulSet")))
+
+	ws.Route(ws.POST("/{namespace}/horizontalpodautoscalers").To(namespace.createHorizontalPodAutoscaler).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.BodyParameter("HorizontalPodAutoscaler", "a HorizontalPodAutoscaler resource (JSON)").DataType("rest.HorizontalPodAutoscaler")))
+
+	ws.Route(ws.POST("/{namespace}/podmetrics").To(namespace.createPodMetrics).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.BodyParameter("PodMetrics", "a PodMetrics resource (JSON)").DataType("rest.PodMetrics")))
+
+	ws.Route(ws.POST("/{namespace}/jobs").To(namespace.createJob).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.BodyParameter("Job", "a Job resource (JSON)").DataType("rest.Job")))
+
+	ws.Route(ws.POST("/{namespace}/cronjobs").To(namespace.createCronJob).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.BodyParameter("CronJob", "a CronJob resource (JSON)").DataType("rest.CronJob")))
+
 	// --- PATCH ----
 	ws.Route(ws.PATCH("/{namespace}/pods/{name}/status").To(namespace.updateStatus).Filter(validateNamespaceExists).
 		Param(ws.PathParameter("name", "name of the pod").DataType("string")).
@@ -104,6 +315,111 @@ func (namespace *Namespace) Register(etcdServersEndpoints string) {
 		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
 		Param(ws.BodyParameter("Endpoint", "a Endpoint resource (JSON)").DataType("rest.Endpoint")))
 
+	ws.Route(ws.PATCH("/{namespace}/events/{name}").To(namespace.createEvent).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("name", "name of the event").DataType("string")).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.BodyParameter("Event", "a Event resource (JSON)").DataType("rest.Event")))
+
+	ws.Route(ws.PATCH("/{namespace}/configmaps/{name}").To(namespace.createConfigMap).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("name", "name of the configmap").DataType("string")).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.BodyParameter("ConfigMap", "a ConfigMap resource (JSON)").DataType("rest.ConfigMap")))
+
+	ws.Route(ws.PATCH("/{namespace}/secrets/{name}").To(namespace.createSecret).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("name", "name of the secret").DataType("string")).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.BodyParameter("Secret", "a Secret resource (JSON)").DataType("rest.Secret")))
+
+	ws.Route(ws.PATCH("/{namespace}/leases/{name}").To(namespace.createLease).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("name", "name of the lease").DataType("string")).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.BodyParameter("Lease", "a Lease resource (JSON)").DataType("rest.Lease")))
+
+	ws.Route(ws.PATCH("/{namespace}/replicasets/{name}").To(namespace.createReplicaSet).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("name", "name of the replicaset").DataType("string")).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.BodyParameter("ReplicaSet", "a ReplicaSet resource (JSON)").DataType("rest.ReplicaSet")))
+
+	ws.Route(ws.PATCH("/{namespace}/replicasets/{name}/status").To(namespace.updateReplicaSetStatus).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("name", "name of the replicaset").DataType("string")).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.BodyParameter("ReplicaSetStatus", "a ReplicaSet status resource (JSON)").DataType("rest.ReplicaSetStatus")))
+
+	ws.Route(ws.PATCH("/{namespace}/deployments/{name}").To(namespace.createDeployment).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("name", "name of the deployment").DataType("string")).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.BodyParameter("Deployment", "a Deployment resource (JSON)").DataType("rest.Deployment")))
+
+	ws.Route(ws.PATCH("/{namespace}/deployments/{name}/status").To(namespace.updateDeploymentStatus).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("name", "name of the deployment").DataType("string")).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.BodyParameter("DeploymentStatus", "a Deployment status resource (JSON)").DataType("rest.DeploymentStatus")))
+
+	ws.Route(ws.PATCH("/{namespace}/daemonsets/{name}").To(namespace.createDaemonSet).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("name", "name of the daemonset").DataType("string")).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.BodyParameter("DaemonSet", "a DaemonSet resource (JSON)").DataType("rest.DaemonSet")))
+
+	ws.Route(ws.PATCH("/{namespace}/statefulsets/{name}").To(namespace.createStatefulSet).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("name", "name of the statefulset").DataType("string")).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.BodyParameter("StatefulSet", "a StatefulSet resource (JSON)").DataType("rest.StatefulSet")))
+
+	ws.Route(ws.PATCH("/{namespace}/jobs/{name}").To(namespace.createJob).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("name", "name of the job").DataType("string")).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.BodyParameter("Job", "a Job resource (JSON)").DataType("rest.Job")))
+
+	ws.Route(ws.PATCH("/{namespace}/cronjobs/{name}").To(namespace.createCronJob).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("name", "name of the cronjob").DataType("string")).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.BodyParameter("CronJob", "a CronJob resource (JSON)").DataType("rest.CronJob")))
+
+	ws.Route(ws.PATCH("/{namespace}/daemonsets/{name}/status").To(namespace.updateDaemonSetStatus).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("name", "name of the daemonset").DataType("string")).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.BodyParameter("DaemonSetStatus", "a DaemonSet status resource (JSON)").DataType("rest.DaemonSetStatus")))
+
+	ws.Route(ws.PATCH("/{namespace}/statefulsets/{name}/status").To(namespace.updateStatefulSetStatus).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("name", "name of the statefulset").DataType("string")).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.BodyParameter("StatefulSetStatus", "a StatefulSet status resource (JSON)").DataType("rest.StatefulSetStatus")))
+
+	ws.Route(ws.PATCH("/{namespace}/horizontalpodautoscalers/{name}").To(namespace.createHorizontalPodAutoscaler).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("name", "name of the horizontalpodautoscaler").DataType("string")).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.BodyParameter("HorizontalPodAutoscaler", "a HorizontalPodAutoscaler resource (JSON)").DataType("rest.HorizontalPodAutoscaler")))
+
+	ws.Route(ws.PATCH("/{namespace}/horizontalpodautoscalers/{name}/status").To(namespace.updateHorizontalPodAutoscalerStatus).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("name", "name of the horizontalpodautoscaler").DataType("string")).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.BodyParameter("HorizontalPodAutoscalerStatus", "a HorizontalPodAutoscaler status resource (JSON)").DataType("rest.HorizontalPodAutoscalerStatus")))
+
+	ws.Route(ws.PATCH("/{namespace}/deployments/{name}/scale").To(namespace.updateDeploymentScale).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("name", "name of the deployment").DataType("string")).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.BodyParameter("Scale", "a Scale resource (JSON)").DataType("rest.Scale")))
+
+	ws.Route(ws.PATCH("/{namespace}/replicasets/{name}/scale").To(namespace.updateReplicaSetScale).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("name", "name of the replicaset").DataType("string")).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.BodyParameter("Scale", "a Scale resource (JSON)").DataType("rest.Scale")))
+
+	ws.Route(ws.PATCH("/{namespace}/statefulsets/{name}/scale").To(namespace.updateStatefulSetScale).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("name", "name of the statefulset").DataType("string")).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.BodyParameter("Scale", "a Scale resource (JSON)").DataType("rest.Scale")))
+
+	ws.Route(ws.PATCH("/{namespace}/jobs/{name}/status").To(namespace.updateJobStatus).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("name", "name of the job").DataType("string")).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.BodyParameter("JobStatus", "a Job status resource (JSON)").DataType("rest.JobStatus")))
+
+	ws.Route(ws.PATCH("/{namespace}/cronjobs/{name}/status").To(namespace.updateCronJobStatus).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("name", "name of the cronjob").DataType("string")).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.BodyParameter("CronJobStatus", "a CronJob status resource (JSON)").DataType("rest.CronJobStatus")))
+
 	// -- DELETE --
 	ws.Route(ws.DELETE("/{namespace}/endpoints/{name}").To(namespace.deleteEndpoint).Filter(validateNamespaceExists).
 		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
@@ -117,7 +433,51 @@ func (namespace *Namespace) Register(etcdServersEndpoints string) {
 		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
 		Param(ws.PathParameter("name", "name of the pod").DataType("string")))
 
-	restful.Add(ws)
+	ws.Route(ws.DELETE("/{namespace}/configmaps/{name}").To(namespace.deleteConfigMap).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.PathParameter("name", "name of the configmap").DataType("string")))
+
+	ws.Route(ws.DELETE("/{namespace}/secrets/{name}").To(namespace.deleteSecret).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.PathParameter("name", "name of the secret").DataType("string")))
+
+	ws.Route(ws.DELETE("/{namespace}/leases/{name}").To(namespace.deleteLease).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.PathParameter("name", "name of the lease").DataType("string")))
+
+	ws.Route(ws.DELETE("/{namespace}/replicasets/{name}").To(namespace.deleteReplicaSet).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.PathParameter("name", "name of the replicaset").DataType("string")))
+
+	ws.Route(ws.DELETE("/{namespace}/deployments/{name}").To(namespace.deleteDeployment).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.PathParameter("name", "name of the deployment").DataType("string")))
+
+	ws.Route(ws.DELETE("/{namespace}/daemonsets/{name}").To(namespace.deleteDaemonSet).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.PathParameter("name", "name of the daemonset").DataType("string")))
+
+	ws.Route(ws.DELETE("/{namespace}/statefulsets/{name}").To(namespace.deleteStatefulSet).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.PathParameter("name", "name of the statefulset").DataType("string")))
+
+	ws.Route(ws.DELETE("/{namespace}/horizontalpodautoscalers/{name}").To(namespace.deleteHorizontalPodAutoscaler).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.PathParameter("name", "name of the horizontalpodautoscaler").DataType("string")))
+
+	ws.Route(ws.DELETE("/{namespace}/podmetrics/{name}").To(namespace.deletePodMetrics).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.PathParameter("name", "name of the pod").DataType("string")))
+
+	ws.Route(ws.DELETE("/{namespace}/jobs/{name}").To(namespace.deleteJob).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.PathParameter("name", "name of the job").DataType("string")))
+
+	ws.Route(ws.DELETE("/{namespace}/cronjobs/{name}").To(namespace.deleteCronJob).Filter(validateNamespaceExists).
+		Param(ws.PathParameter("namespace", "namespace").DataType("string")).
+		Param(ws.PathParameter("name", "name of the cronjob").DataType("string")))
+
+	container.Add(ws)
 
 	setupDefaultNamespaces()
 }
@@ -127,7 +487,7 @@ func setupDefaultNamespaces() {
 
 	for _, namespaceName := range setupNamespaces {
 		namespace := Namespace{
-			Kind: "Namespace",
+			TypeMeta: TypeMeta{Kind: "Namespace"},
 			Metadata: ResourceMetadata{
 				CreationTimestamp: time.Now().Format(time.RFC3339),
 				Name:              namespaceName,
@@ -147,6 +507,22 @@ func setupDefaultNamespaces() {
 	}
 }
 
+// CheckDefaultNamespaces returns an error if one of the setup namespaces is missing from etcd
+func CheckDefaultNamespaces() error {
+	if etcdServiceAppNamespace == nil {
+		return fmt.Errorf("namespaces are not registered yet")
+	}
+
+	for _, namespaceName := range setupNamespaces {
+		_, err := etcdServiceAppNamespace.GetResource(fmt.Sprintf("%s/%s", namespaceEtcdKey, namespaceName))
+		if err != nil {
+			return fmt.Errorf("namespace %s is not bootstrapped: %v", namespaceName, err)
+		}
+	}
+
+	return nil
+}
+
 func validateNamespaceExists(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
 	namespaceQuery := req.PathParameter("namespace")
 	log.Printf("validating namespace exists %s", namespaceQuery)
@@ -172,55 +548,15 @@ func validateNamespaceExists(req *restful.Request, resp *restful.Response, chain
 
 func (namespace *Namespace) getAllResourceInNamespace(req *restful.Request, resp *restful.Response, etcdKey string) {
 	watchQuery := req.QueryParameter("watch")
-
-	if watchQuery == "true" {
-		namespace.watcher(req, resp, etcdKey)
-
-		return
-	}
-
 	namespaceQuery := req.PathParameter("namespace")
-	resArr, err := etcdServiceAppNamespace.GetAllFromResource(fmt.Sprintf("%s/%s", etcdKey, namespaceQuery))
-	if err != nil {
-		if strings.Contains(err.Error(), "key not found") {
-			err = resp.WriteEntity([]Pod{})
-			if err != nil {
-				fmt.Printf("error while sending error: %v", err)
-			}
 
-			return
-		}
-
-		err = resp.WriteError(http.StatusBadRequest, err)
-		if err != nil {
-			fmt.Printf("error while sending error: %v", err)
-		}
+	if watchQuery == "true" {
+		watchFromWatchCache(req, resp, etcdKey, namespaceQuery)
 
 		return
 	}
 
-	fieldSelector := req.QueryParameter("fieldSelector")
-	resourcesArr := make([]interface{}, len(resArr))
-	for index, res := range resArr {
-		if fieldSelector == "" || (fieldSelector != "" && validateFieldSelector(fieldSelector, string(res))) {
-			var resource interface{}
-			if err = json.Unmarshal(res, &resource); err != nil {
-				err = resp.WriteError(http.StatusBadRequest, err)
-				if err != nil {
-					fmt.Printf("error while sending error: %v", err)
-				}
-
-				return
-			}
-
-			resourcesArr[index] = resource
-		}
-	}
-
-	err = resp.WriteEntity(resourcesArr)
-	if err != nil {
-		fmt.Printf("error while sending error: %v", err)
-	}
+	listFromWatchCache(req, resp, etcdKey, namespaceQuery)
 }
 
 func (namespace *Namespace) getSingleResourceInNamespace(req *restful.Request, resp *restful.Response, etcdKey string) {
@@ -253,60 +589,6 @@ func (namespace *Namespace) getSingleResourceInNamespace(req *restful.Request, r
 	}
 }
 
-func (namespace *Namespace) watcher(req *restful.Request, resp *restful.Response, etcdKey string) {
-	fieldSelector := req.QueryParameter("fieldSelector")
-	namespaceQuery := req.PathParameter("namespace")
-
-	resp.Header().Set("Access-Control-Allow-Origin", "*")
-	resp.Header().Set("Content-Type", "text/event-stream")
-	resp.Header().Set("Cache-Control", "no-cache")
-	resp.Header().Set("Connection", "keep-alive")
-
-	watchChan, closeChanFunc, err := etcdServiceAppNamespace.GetWatchChannel(fmt.Sprintf("%s/%s", etcdKey, namespaceQuery))
-	if err != nil {
-		err = resp.WriteErrorString(http.StatusBadRequest, err.Error())
-		if err != nil {
-			fmt.Printf("error while sending error: %v", err)
-		}
-
-		return
-	}
-	defer closeChanFunc()
-	defer resp.CloseNotify()
-
-	log.Println("Client pod watcher started")
-
-	for {
-		select {
-		case watchResp := <-watchChan:
-			if watchResp.Err() != nil {
-				fmt.Printf("error while sending error: %v", err)
-			}
-
-			for _, event := range watchResp.Events {
-				log.Printf("watch: %s executed on %s with value %s\n", event.Type, string(event.Kv.Key), string(event.Kv.Value))
-
-				if fieldSelector == "" {
-					fmt.Fprintf(resp, "Type: %s Value: %s\n", event.Type, string(event.Kv.Value))
-				} else {
-					splitedFieldSelector := strings.Split(fieldSelector, "=")
-					resGJSON := gjson.Get(string(event.Kv.Value), splitedFieldSelector[0])
-
-					if resGJSON.Exists() && resGJSON.Value() == splitedFieldSelector[1] {
-						fmt.Fprintf(resp, "Type: %s Value: %s\n", event.Type, string(event.Kv.Value))
-					}
-				}
-
-				resp.Flush()
-			}
-
-		case <-req.Request.Context().Done():
-			log.Println("Connection closed")
-			return
-		}
-	}
-}
-
 func (namespace *Namespace) createResourceInNamespace(
 	_ *restful.Request,
 	resp *restful.Response,
@@ -376,6 +658,8 @@ func (namespace *Namespace) createPod(req *restful.Request, resp *restful.Respon
 		newPod.Metadata.Namespace = namespaceQuery
 	}
 
+	requestUID := newPod.Metadata.UID
+
 	newPod.Metadata.CreationTimestamp = time.Now().Format(time.RFC3339)
 
 	if newPod.Metadata.UID == "" {
@@ -386,27 +670,56 @@ func (namespace *Namespace) createPod(req *restful.Request, resp *restful.Respon
 		newPod.Status.Phase = "Pending"
 	}
 
-	namespace.createResourceInNamespace(
-		req,
-		resp,
-		podEtcdKey,
-		newPod.Metadata.Namespace,
-		newPod.Metadata.Name,
-		newPod,
-	)
-}
+	// the kubelet posts its static pods with the status of their containers, everyone else posts a spec
+	statusFromKubelet := strings.HasPrefix(req.Request.UserAgent(), kubeletUserAgentPrefix)
 
-func (namespace *Namespace) deleteResourceInNamespace(req *restful.Request, resp *restful.Response, etcdKey string) {
-	name := req.PathParameter("name")
-	namespaceQuery := req.PathParameter("namespace")
+	err = guaranteedUpdatePod(newPod.Metadata.Namespace, newPod.Metadata.Name, true, func(storedPod *Pod) (*Pod, error) {
+		if storedPod == nil {
+			return newPod, nil
+		}
 
-	err := etcdServiceAppNamespace.DeleteResource(fmt.Sprintf("%s/%s/%s", etcdKey, namespaceQuery, name))
-	if err != nil {
-		err = resp.WriteError(http.StatusBadRequest, err)
-		if err != nil {
-			fmt.Printf("error while sending error: %v", err)
+		// a kubelet recreates the containers of its static pods with a new uid when it starts, the pod it posts
+		// replaces the stored one
+		recreatedByKubelet := statusFromKubelet && requestUID != storedPod.Metadata.UID
+
+		if requestUID != "" && requestUID != storedPod.Metadata.UID && !recreatedByKubelet {
+			return nil, &updateError{
+				statusCode: http.StatusConflict,
+				message: fmt.Sprintf("pod %s/%s has uid %s, the request is for uid %s",
+					storedPod.Metadata.Namespace, storedPod.Metadata.Name, storedPod.Metadata.UID, requestUID),
+			}
+		}
+
+		updatedPod := *newPod
+
+		// an update is the same object, its identity and the status reported by its kubelet are kept
+		if !recreatedByKubelet {
+			updatedPod.Metadata.UID = storedPod.Metadata.UID
+			updatedPod.Metadata.CreationTimestamp = storedPod.Metadata.CreationTimestamp
+		}
+
+		if !statusFromKubelet {
+			updatedPod.Status = storedPod.Status
+		}
+
+		// the node of a pod is only set by the binding, an update without a node keeps the node of the stored pod
+		if updatedPod.Spec.NodeName == "" {
+			updatedPod.Spec.NodeName = storedPod.Spec.NodeName
 		}
 
+		if updatedPod.Spec.NodeName != storedPod.Spec.NodeName {
+			return nil, &updateError{
+				statusCode: http.StatusUnprocessableEntity,
+				message: fmt.Sprintf("pod %s/%s spec.nodeName is immutable, use the binding subresource to assign a node",
+					storedPod.Metadata.Namespace, storedPod.Metadata.Name),
+			}
+		}
+
+		return &updatedPod, nil
+	})
+	if err != nil {
+		writeUpdateError(resp, err)
+
 		return
 	}
 
@@ -416,11 +729,14 @@ func (namespace *Namespace) deleteResourceInNamespace(req *restful.Request, resp
 	}
 }
 
-func (namespace *Namespace) updateStatus(req *restful.Request, resp *restful.Response) {
+// bindPod places a pod on a node, the kubelet of the node watches for the pods with its name and starts it.
+// The node is set only if the pod has none, a pod bound by someone else gets a Conflict
+func (namespace *Namespace) bindPod(req *restful.Request, resp *restful.Response) {
 	name := req.PathParameter("name")
 	namespaceQuery := req.PathParameter("namespace")
 
-	res, err := etcdServiceAppNamespace.GetResource(fmt.Sprintf("%s/%s/%s", podEtcdKey, namespaceQuery, name))
+	binding := new(Binding)
+	err := req.ReadEntity(binding)
 	if err != nil {
 		err = resp.WriteError(http.StatusBadRequest, err)
 		if err != nil {
@@ -430,9 +746,8 @@ func (namespace *Namespace) updateStatus(req *restful.Request, resp *restful.Res
 		return
 	}
 
-	var podRes Pod
-	if err = json.Unmarshal(res, &podRes); err != nil {
-		err = resp.WriteError(http.StatusBadRequest, err)
+	if binding.Target.Kind != "Node" || binding.Target.Name == "" {
+		err = resp.WriteErrorString(http.StatusBadRequest, "binding target must be a Node with a name")
 		if err != nil {
 			fmt.Printf("error while sending error: %v", err)
 		}
@@ -440,30 +755,116 @@ func (namespace *Namespace) updateStatus(req *restful.Request, resp *restful.Res
 		return
 	}
 
-	newPodStatus := new(PodStatus)
-	err = req.ReadEntity(newPodStatus)
-	if err != nil {
-		err = resp.WriteError(http.StatusBadRequest, err)
-		if err != nil {
-			fmt.Printf("error while sending error: %v", err)
+	err = guaranteedUpdatePod(namespaceQuery, name, false, func(storedPod *Pod) (*Pod, error) {
+		if storedPod.Spec.NodeName != "" {
+			return nil, &updateError{
+				statusCode: http.StatusConflict,
+				message:    fmt.Sprintf("pod %s/%s is already assigned to node %s", namespaceQuery, name, storedPod.Spec.NodeName),
+			}
+		}
+
+		if storedPod.Status.Phase == "Terminating" {
+			return nil, &updateError{
+				statusCode: http.StatusConflict,
+				message:    fmt.Sprintf("pod %s/%s is being deleted", namespaceQuery, name),
+			}
 		}
 
+		storedPod.Spec.NodeName = binding.Target.Name
+
+		return storedPod, nil
+	})
+	if err != nil {
+		writeUpdateError(resp, err)
+
 		return
 	}
 
-	podRes.Status = *newPodStatus
-
-	podResBytes, err := json.Marshal(podRes)
+	err = resp.WriteEntity("success")
 	if err != nil {
-		err = resp.WriteError(http.StatusBadRequest, err)
+		fmt.Printf("error while sending error: %v", err)
+	}
+}
+
+// updateError rejects an update of guaranteedUpdate with the status code
+type updateError struct {
+	statusCode int
+	message    string
+}
+
+func (err *updateError) Error() string {
+	return err.message
+}
+
+// guaranteedUpdate applies the update on the stored object and writes the result only if the object was not changed
+// since it was read, so a write based on an old object can not undo another write. When the object was changed it
+// is read again and the update is applied on the new object. The stored object is nil when it does not exist and
+// allowCreate is set
+func guaranteedUpdate[T any](key string, allowCreate bool, update func(stored *T) (*T, error)) error {
+	for {
+		var stored *T
+
+		res, revision, err := etcdServiceAppNamespace.GetResourceWithRevision(key)
 		if err != nil {
-			fmt.Printf("error while sending error: %v", err)
+			if !allowCreate || !strings.Contains(err.Error(), "key not found") {
+				return err
+			}
+		} else {
+			stored = new(T)
+			if err = json.Unmarshal(res, stored); err != nil {
+				return err
+			}
 		}
 
-		return
+		updated, err := update(stored)
+		if err != nil {
+			return err
+		}
+
+		resourceBytes, err := json.Marshal(updated)
+		if err != nil {
+			return err
+		}
+
+		err = etcdServiceAppNamespace.PutResourceIfRevision(key, string(resourceBytes), revision)
+		if errors.Is(err, etcd.ErrRevisionConflict) {
+			log.Printf("%s was modified while updating, retrying", key)
+
+			continue
+		}
+
+		return err
+	}
+}
+
+// guaranteedUpdatePod updates the pod with guaranteedUpdate, so a write based on an old pod can not undo a binding
+func guaranteedUpdatePod(namespaceName string, name string, allowCreate bool, update func(storedPod *Pod) (*Pod, error)) error {
+	return guaranteedUpdate(fmt.Sprintf("%s/%s/%s", podEtcdKey, namespaceName, name), allowCreate, update)
+}
+
+// writeUpdateError writes the status code of an updateError, not found when the updated object does not exist and
+// bad request otherwise
+func writeUpdateError(resp *restful.Response, err error) {
+	statusCode := http.StatusBadRequest
+
+	var updateErr *updateError
+	if errors.As(err, &updateErr) {
+		statusCode = updateErr.statusCode
+	} else if strings.Contains(err.Error(), "key not found") {
+		statusCode = http.StatusNotFound
+	}
+
+	err = resp.WriteError(statusCode, err)
+	if err != nil {
+		fmt.Printf("error while sending error: %v", err)
 	}
+}
+
+func (namespace *Namespace) deleteResourceInNamespace(req *restful.Request, resp *restful.Response, etcdKey string) {
+	name := req.PathParameter("name")
+	namespaceQuery := req.PathParameter("namespace")
 
-	err = etcdServiceAppNamespace.PutResource(fmt.Sprintf("%s/%s/%s", podEtcdKey, namespaceQuery, name), string(podResBytes))
+	err := etcdServiceAppNamespace.DeleteResource(fmt.Sprintf("%s/%s/%s", etcdKey, namespaceQuery, name))
 	if err != nil {
 		err = resp.WriteError(http.StatusBadRequest, err)
 		if err != nil {
@@ -479,8 +880,12 @@ func (namespace *Namespace) updateStatus(req *restful.Request, resp *restful.Res
 	}
 }
 
-func (namespace *Namespace) getNamespaces(_ *restful.Request, resp *restful.Response) {
-	resArr, err := etcdServiceAppNamespace.GetAllFromResource(namespaceEtcdKey)
+func (namespace *Namespace) updateStatus(req *restful.Request, resp *restful.Response) {
+	name := req.PathParameter("name")
+	namespaceQuery := req.PathParameter("namespace")
+
+	newPodStatus := new(PodStatus)
+	err := req.ReadEntity(newPodStatus)
 	if err != nil {
 		err = resp.WriteError(http.StatusBadRequest, err)
 		if err != nil {
@@ -490,27 +895,27 @@ func (namespace *Namespace) getNamespaces(_ *restful.Request, resp *restful.Resp
 		return
 	}
 
-	namespacesResArr := make([]Namespace, len(resArr))
-	for index, res := range resArr {
-		var namespaceRes Namespace
-		if err = json.Unmarshal(res, &namespaceRes); err != nil {
-			err = resp.WriteError(http.StatusBadRequest, err)
-			if err != nil {
-				fmt.Printf("error while sending error: %v", err)
-			}
+	err = guaranteedUpdatePod(namespaceQuery, name, false, func(storedPod *Pod) (*Pod, error) {
+		storedPod.Status = *newPodStatus
 
-			return
-		}
+		return storedPod, nil
+	})
+	if err != nil {
+		writeUpdateError(resp, err)
 
-		namespacesResArr[index] = namespaceRes
+		return
 	}
 
-	err = resp.WriteEntity(namespacesResArr)
+	err = resp.WriteEntity("success")
 	if err != nil {
 		fmt.Printf("error while sending error: %v", err)
 	}
 }
 
+func (namespace *Namespace) getNamespaces(req *restful.Request, resp *restful.Response) {
+	listFromWatchCache(req, resp, namespaceEtcdKey, "")
+}
+
 func (namespace *Namespace) createNamespace(req *restful.Request, resp *restful.Response) {
 	newNamespace := new(Namespace)
 	err := req.ReadEntity(newNamespace)
@@ -581,14 +986,46 @@ func (namespace *Namespace) createService(req *restful.Request, resp *restful.Re
 		newService.Metadata.UID = uuid.NewString()
 	}
 
-	namespace.createResourceInNamespace(
-		req,
-		resp,
-		serviceEtcdKey,
-		newService.Metadata.Namespace,
-		newService.Metadata.Name,
-		newService,
-	)
+	requestedService := *newService
+	requestedService.Spec.Ports = append([]ServicePorts{}, newService.Spec.Ports...)
+
+	serviceAllocationsMutex.RLock()
+	defer serviceAllocationsMutex.RUnlock()
+
+	allocations := serviceAllocations{}
+	released := serviceAllocations{}
+
+	key := fmt.Sprintf("%s/%s/%s", serviceEtcdKey, newService.Metadata.Namespace, newService.Metadata.Name)
+	err = guaranteedUpdate(key, true, func(storedService *Service) (*Service, error) {
+		// the allocations made for a stored service that changed since are released and made again for the new one
+		allocations.release()
+		allocations = serviceAllocations{}
+
+		newService.Spec.ClusterIP = requestedService.Spec.ClusterIP
+		newService.Spec.Ports = append([]ServicePorts{}, requestedService.Spec.Ports...)
+
+		if err := allocations.allocate(storedService, newService); err != nil {
+			return nil, err
+		}
+
+		released = releasedAllocations(storedService, newService)
+
+		return newService, nil
+	})
+	if err != nil {
+		allocations.release()
+		writeUpdateError(resp, err)
+
+		return
+	}
+
+	// the node ports of the stored service are released once the service is written without them
+	released.release()
+
+	err = resp.WriteEntity("success")
+	if err != nil {
+		fmt.Printf("error while sending error: %v", err)
+	}
 }
 
 func (namespace *Namespace) getServices(req *restful.Request, resp *restful.Response) {
@@ -646,8 +1083,41 @@ func (namespace *Namespace) deleteEndpoint(req *restful.Request, resp *restful.R
 }
 
 // TODO: add handler in kubelet
+// deleteService deletes the service and releases its clusterIP and node ports
 func (namespace *Namespace) deleteService(req *restful.Request, resp *restful.Response) {
-	namespace.deleteResourceInNamespace(req, resp, serviceEtcdKey)
+	name := req.PathParameter("name")
+	namespaceQuery := req.PathParameter("namespace")
+	key := fmt.Sprintf("%s/%s/%s", serviceEtcdKey, namespaceQuery, name)
+
+	storedService := Service{}
+
+	res, err := etcdServiceAppNamespace.GetResource(key)
+	if err == nil {
+		err = json.Unmarshal(res, &storedService)
+	} else if strings.Contains(err.Error(), "key not found") {
+		err = nil
+	}
+
+	if err == nil {
+		err = etcdServiceAppNamespace.DeleteResource(key)
+	}
+
+	if err != nil {
+		err = resp.WriteError(http.StatusBadRequest, err)
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	allocations := allocationsOf(&storedService)
+	allocations.release()
+
+	err = resp.WriteEntity("success")
+	if err != nil {
+		fmt.Printf("error while sending error: %v", err)
+	}
 }
 
 func (namespace *Namespace) deletePod(req *restful.Request, resp *restful.Response) {
@@ -664,8 +1134,8 @@ func (namespace *Namespace) deletePod(req *restful.Request, resp *restful.Respon
 		return
 	}
 
-	var newPod Pod
-	if err = json.Unmarshal(res, &newPod); err != nil {
+	var storedPod Pod
+	if err = json.Unmarshal(res, &storedPod); err != nil {
 		err = resp.WriteError(http.StatusBadRequest, err)
 		if err != nil {
 			fmt.Printf("error while sending error: %v", err)
@@ -674,17 +1144,1308 @@ func (namespace *Namespace) deletePod(req *restful.Request, resp *restful.Respon
 		return
 	}
 
-	if newPod.Status.Phase == "Terminating" {
+	if storedPod.Status.Phase == "Terminating" {
 		namespace.deleteResourceInNamespace(req, resp, podEtcdKey)
-	} else {
-		newPod.Status.Phase = "Terminating"
-		namespace.createResourceInNamespace(
-			req,
-			resp,
-			podEtcdKey,
-			newPod.Metadata.Namespace,
-			newPod.Metadata.Name,
-			newPod,
-		)
+
+		return
+	}
+
+	err = guaranteedUpdatePod(namespaceQuery, name, false, func(storedPod *Pod) (*Pod, error) {
+		storedPod.Status.Phase = "Terminating"
+
+		return storedPod, nil
+	})
+	if err != nil {
+		writeUpdateError(resp, err)
+
+		return
+	}
+
+	err = resp.WriteEntity("success")
+	if err != nil {
+		fmt.Printf("error while sending error: %v", err)
+	}
+}
+
+func (namespace *Namespace) getEvents(req *restful.Request, resp *restful.Response) {
+	namespace.getAllResourceInNamespace(req, resp, eventEtcdKey)
+}
+
+func (namespace *Namespace) getEvent(req *restful.Request, resp *restful.Response) {
+	namespace.getSingleResourceInNamespace(req, resp, eventEtcdKey)
+}
+
+func (namespace *Namespace) createEvent(req *restful.Request, resp *restful.Response) {
+	newEvent := new(Event)
+	err := req.ReadEntity(newEvent)
+	if err != nil {
+		err = resp.WriteError(http.StatusBadRequest, err)
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	if newEvent.Type == "" {
+		newEvent.Type = EventTypeNormal
+	}
+
+	if newEvent.Type != EventTypeNormal && newEvent.Type != EventTypeWarning {
+		err = resp.WriteErrorString(http.StatusBadRequest, fmt.Sprintf("event type must be %s or %s", EventTypeNormal, EventTypeWarning))
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	namespaceQuery := req.PathParameter("namespace")
+
+	if newEvent.Metadata.Namespace == "" {
+		newEvent.Metadata.Namespace = namespaceQuery
+	}
+
+	now := time.Now().Format(time.RFC3339)
+
+	if newEvent.Metadata.Name != "" {
+		// an update of a repeated event keeps the identity of the stored one
+		res, err := etcdServiceAppNamespace.GetResource(fmt.Sprintf("%s/%s/%s", eventEtcdKey, newEvent.Metadata.Namespace, newEvent.Metadata.Name))
+		if err == nil {
+			var storedEvent Event
+			if err = json.Unmarshal(res, &storedEvent); err == nil {
+				newEvent.Metadata.UID = storedEvent.Metadata.UID
+				newEvent.Metadata.CreationTimestamp = storedEvent.Metadata.CreationTimestamp
+				newEvent.FirstTimestamp = storedEvent.FirstTimestamp
+			}
+		}
+	}
+
+	if newEvent.Metadata.Name == "" {
+		// same as kubernetes, the name is the involved object with a unique suffix
+		newEvent.Metadata.Name = fmt.Sprintf("%s.%x", newEvent.InvolvedObject.Name, time.Now().UnixNano())
+	}
+
+	if newEvent.Metadata.CreationTimestamp == "" {
+		newEvent.Metadata.CreationTimestamp = now
+	}
+
+	if newEvent.Metadata.UID == "" {
+		newEvent.Metadata.UID = uuid.NewString()
+	}
+
+	if newEvent.FirstTimestamp == "" {
+		newEvent.FirstTimestamp = now
+	}
+
+	if newEvent.LastTimestamp == "" {
+		newEvent.LastTimestamp = newEvent.FirstTimestamp
+	}
+
+	if newEvent.Count == 0 {
+		newEvent.Count = 1
+	}
+
+	newEvent.Kind = "Event"
+
+	namespace.createResourceInNamespace(
+		req,
+		resp,
+		eventEtcdKey,
+		newEvent.Metadata.Namespace,
+		newEvent.Metadata.Name,
+		newEvent,
+	)
+}
+
+func (namespace *Namespace) getConfigMaps(req *restful.Request, resp *restful.Response) {
+	namespace.getAllResourceInNamespace(req, resp, configMapEtcdKey)
+}
+
+func (namespace *Namespace) getConfigMap(req *restful.Request, resp *restful.Response) {
+	namespace.getSingleResourceInNamespace(req, resp, configMapEtcdKey)
+}
+
+func (namespace *Namespace) createConfigMap(req *restful.Request, resp *restful.Response) {
+	newConfigMap := new(ConfigMap)
+	err := req.ReadEntity(newConfigMap)
+	if err != nil {
+		err = resp.WriteError(http.StatusBadRequest, err)
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
 	}
+
+	if err = validateConfigMapKeys(newConfigMap); err != nil {
+		err = resp.WriteError(http.StatusBadRequest, err)
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	namespaceQuery := req.PathParameter("namespace")
+
+	if newConfigMap.Metadata.Namespace == "" {
+		newConfigMap.Metadata.Namespace = namespaceQuery
+	}
+
+	if newConfigMap.Metadata.CreationTimestamp == "" {
+		newConfigMap.Metadata.CreationTimestamp = time.Now().Format(time.RFC3339)
+	}
+
+	if newConfigMap.Metadata.UID == "" {
+		newConfigMap.Metadata.UID = uuid.NewString()
+	}
+
+	newConfigMap.Kind = "ConfigMap"
+
+	namespace.createResourceInNamespace(
+		req,
+		resp,
+		configMapEtcdKey,
+		newConfigMap.Metadata.Namespace,
+		newConfigMap.Metadata.Name,
+		newConfigMap,
+	)
+}
+
+func (namespace *Namespace) deleteConfigMap(req *restful.Request, resp *restful.Response) {
+	namespace.deleteResourceInNamespace(req, resp, configMapEtcdKey)
+}
+
+func (namespace *Namespace) getSecrets(req *restful.Request, resp *restful.Response) {
+	namespace.getAllResourceInNamespace(req, resp, secretEtcdKey)
+}
+
+func (namespace *Namespace) getSecret(req *restful.Request, resp *restful.Response) {
+	namespace.getSingleResourceInNamespace(req, resp, secretEtcdKey)
+}
+
+func (namespace *Namespace) createSecret(req *restful.Request, resp *restful.Response) {
+	newSecret := new(Secret)
+	err := req.ReadEntity(newSecret)
+	if err != nil {
+		err = resp.WriteError(http.StatusBadRequest, err)
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	if err = normalizeSecret(newSecret); err != nil {
+		err = resp.WriteError(http.StatusBadRequest, err)
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	namespaceQuery := req.PathParameter("namespace")
+
+	if newSecret.Metadata.Namespace == "" {
+		newSecret.Metadata.Namespace = namespaceQuery
+	}
+
+	if newSecret.Metadata.CreationTimestamp == "" {
+		newSecret.Metadata.CreationTimestamp = time.Now().Format(time.RFC3339)
+	}
+
+	if newSecret.Metadata.UID == "" {
+		newSecret.Metadata.UID = uuid.NewString()
+	}
+
+	newSecret.Kind = "Secret"
+
+	namespace.createResourceInNamespace(
+		req,
+		resp,
+		secretEtcdKey,
+		newSecret.Metadata.Namespace,
+		newSecret.Metadata.Name,
+		newSecret,
+	)
+}
+
+func (namespace *Namespace) deleteSecret(req *restful.Request, resp *restful.Response) {
+	namespace.deleteResourceInNamespace(req, resp, secretEtcdKey)
+}
+
+func (namespace *Namespace) getLeases(req *restful.Request, resp *restful.Response) {
+	namespace.getAllResourceInNamespace(req, resp, leaseEtcdKey)
+}
+
+// getLease returns the lease with its resource version, the holders pass it back to update the lease
+// only if no one else did in the meantime
+func (namespace *Namespace) getLease(req *restful.Request, resp *restful.Response) {
+	name := req.PathParameter("name")
+	namespaceQuery := req.PathParameter("namespace")
+
+	res, revision, err := etcdServiceAppNamespace.GetResourceWithRevision(fmt.Sprintf("%s/%s/%s", leaseEtcdKey, namespaceQuery, name))
+	if err != nil {
+		err = resp.WriteError(http.StatusBadRequest, err)
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	var leaseRes Lease
+	if err = json.Unmarshal(res, &leaseRes); err != nil {
+		err = resp.WriteError(http.StatusBadRequest, err)
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	leaseRes.Metadata.ResourceVersion = strconv.FormatInt(revision, 10)
+
+	err = resp.WriteEntity(leaseRes)
+	if err != nil {
+		fmt.Printf("error while sending error: %v", err)
+	}
+}
+
+func (namespace *Namespace) createLease(req *restful.Request, resp *restful.Response) {
+	newLease := new(Lease)
+	err := req.ReadEntity(newLease)
+	if err != nil {
+		err = resp.WriteError(http.StatusBadRequest, err)
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	namespaceQuery := req.PathParameter("namespace")
+
+	if newLease.Metadata.Namespace == "" {
+		newLease.Metadata.Namespace = namespaceQuery
+	}
+
+	if newLease.Metadata.CreationTimestamp == "" {
+		newLease.Metadata.CreationTimestamp = time.Now().Format(time.RFC3339)
+	}
+
+	if newLease.Metadata.UID == "" {
+		newLease.Metadata.UID = uuid.NewString()
+	}
+
+	newLease.Kind = "Lease"
+
+	if newLease.Metadata.ResourceVersion == "" {
+		namespace.createResourceInNamespace(
+			req,
+			resp,
+			leaseEtcdKey,
+			newLease.Metadata.Namespace,
+			newLease.Metadata.Name,
+			newLease,
+		)
+
+		return
+	}
+
+	// a lease with a resource version is written only if it is still at that version,
+	// resource version 0 creates the lease only if it does not exist
+	revision, err := strconv.ParseInt(newLease.Metadata.ResourceVersion, 10, 64)
+	if err != nil {
+		err = resp.WriteErrorString(http.StatusBadRequest, fmt.Sprintf("invalid resourceVersion: %v", err))
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	newLease.Metadata.ResourceVersion = ""
+
+	leaseBytes, err := json.Marshal(newLease)
+	if err != nil {
+		err = resp.WriteError(http.StatusBadRequest, err)
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	err = etcdServiceAppNamespace.PutResourceIfRevision(
+		fmt.Sprintf("%s/%s/%s", leaseEtcdKey, newLease.Metadata.Namespace, newLease.Metadata.Name),
+		string(leaseBytes),
+		revision,
+	)
+	if err != nil {
+		statusCode := http.StatusBadRequest
+		if errors.Is(err, etcd.ErrRevisionConflict) {
+			statusCode = http.StatusConflict
+		}
+
+		err = resp.WriteError(statusCode, err)
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	err = resp.WriteEntity("success")
+	if err != nil {
+		fmt.Printf("error while sending error: %v", err)
+	}
+}
+
+func (namespace *Namespace) deleteLease(req *restful.Request, resp *restful.Response) {
+	namespace.deleteResourceInNamespace(req, resp, leaseEtcdKey)
+}
+
+func (namespace *Namespace) getReplicaSets(req *restful.Request, resp *restful.Response) {
+	namespace.getAllResourceInNamespace(req, resp, replicaSetEtcdKey)
+}
+
+func (namespace *Namespace) getReplicaSet(req *restful.Request, resp *restful.Response) {
+	namespace.getSingleResourceInNamespace(req, resp, replicaSetEtcdKey)
+}
+
+// createReplicaSet creates or replaces the spec of a replicaset, the status is only changed by its subresource
+func (namespace *Namespace) createReplicaSet(req *restful.Request, resp *restful.Response) {
+	newReplicaSet := new(ReplicaSet)
+	err := req.ReadEntity(newReplicaSet)
+	if err != nil {
+		err = resp.WriteError(http.StatusBadRequest, err)
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	newReplicaSet.setDefaults()
+
+	if err = newReplicaSet.validate(); err != nil {
+		err = resp.WriteError(http.StatusBadRequest, err)
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	namespaceQuery := req.PathParameter("namespace")
+
+	if newReplicaSet.Metadata.Namespace == "" {
+		newReplicaSet.Metadata.Namespace = namespaceQuery
+	}
+
+	newReplicaSet.Kind = "ReplicaSet"
+
+	err = guaranteedUpdate(
+		fmt.Sprintf("%s/%s/%s", replicaSetEtcdKey, newReplicaSet.Metadata.Namespace, newReplicaSet.Metadata.Name),
+		true,
+		func(storedReplicaSet *ReplicaSet) (*ReplicaSet, error) {
+			if storedReplicaSet == nil {
+				newReplicaSet.Metadata.CreationTimestamp = time.Now().Format(time.RFC3339)
+				newReplicaSet.Metadata.UID = uuid.NewString()
+				newReplicaSet.Status = ReplicaSetStatus{}
+
+				return newReplicaSet, nil
+			}
+
+			updatedReplicaSet := *newReplicaSet
+			updatedReplicaSet.Metadata.CreationTimestamp = storedReplicaSet.Metadata.CreationTimestamp
+			updatedReplicaSet.Metadata.UID = storedReplicaSet.Metadata.UID
+			updatedReplicaSet.Status = storedReplicaSet.Status
+
+			return &updatedReplicaSet, nil
+		},
+	)
+	if err != nil {
+		writeUpdateError(resp, err)
+
+		return
+	}
+
+	err = resp.WriteEntity("success")
+	if err != nil {
+		fmt.Printf("error while sending error: %v", err)
+	}
+}
+
+func (namespace *Namespace) updateReplicaSetStatus(req *restful.Request, resp *restful.Response) {
+	name := req.PathParameter("name")
+	namespaceQuery := req.PathParameter("namespace")
+
+	newReplicaSetStatus := new(ReplicaSetStatus)
+	err := req.ReadEntity(newReplicaSetStatus)
+	if err != nil {
+		err = resp.WriteError(http.StatusBadRequest, err)
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	err = guaranteedUpdate(
+		fmt.Sprintf("%s/%s/%s", replicaSetEtcdKey, namespaceQuery, name),
+		false,
+		func(storedReplicaSet *ReplicaSet) (*ReplicaSet, error) {
+			storedReplicaSet.Status = *newReplicaSetStatus
+
+			return storedReplicaSet, nil
+		},
+	)
+	if err != nil {
+		writeUpdateError(resp, err)
+
+		return
+	}
+
+	err = resp.WriteEntity("success")
+	if err != nil {
+		fmt.Printf("error while sending error: %v", err)
+	}
+}
+
+// deleteReplicaSet removes the replicaset, its pods are deleted by the replicaset controller
+func (namespace *Namespace) deleteReplicaSet(req *restful.Request, resp *restful.Response) {
+	namespace.deleteResourceInNamespace(req, resp, replicaSetEtcdKey)
+}
+
+func (namespace *Namespace) getDeployments(req *restful.Request, resp *restful.Response) {
+	namespace.getAllResourceInNamespace(req, resp, deploymentEtcdKey)
+}
+
+func (namespace *Namespace) getDeployment(req *restful.Request, resp *restful.Response) {
+	namespace.getSingleResourceInNamespace(req, resp, deploymentEtcdKey)
+}
+
+// createDeployment creates or replaces the spec of a deployment, the status is only changed by its subresource
+func (namespace *Namespace) createDeployment(req *restful.Request, resp *restful.Response) {
+	newDeployment := new(Deployment)
+	err := req.ReadEntity(newDeployment)
+	if err != nil {
+		err = resp.WriteError(http.StatusBadRequest, err)
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	newDeployment.setDefaults()
+
+	if err = newDeployment.validate(); err != nil {
+		err = resp.WriteError(http.StatusBadRequest, err)
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	namespaceQuery := req.PathParameter("namespace")
+
+	if newDeployment.Metadata.Namespace == "" {
+		newDeployment.Metadata.Namespace = namespaceQuery
+	}
+
+	newDeployment.Kind = "Deployment"
+
+	err = guaranteedUpdate(
+		fmt.Sprintf("%s/%s/%s", deploymentEtcdKey, newDeployment.Metadata.Namespace, newDeployment.Metadata.Name),
+		true,
+		func(storedDeployment *Deployment) (*Deployment, error) {
+			if storedDeployment == nil {
+				newDeployment.Metadata.CreationTimestamp = time.Now().Format(time.RFC3339)
+				newDeployment.Metadata.UID = uuid.NewString()
+				newDeployment.Status = DeploymentStatus{}
+
+				return newDeployment, nil
+			}
+
+			updatedDeployment := *newDeployment
+			updatedDeployment.Metadata.CreationTimestamp = storedDeployment.Metadata.CreationTimestamp
+			updatedDeployment.Metadata.UID = storedDeployment.Metadata.UID
+			updatedDeployment.Status = storedDeployment.Status
+
+			return &updatedDeployment, nil
+		},
+	)
+	if err != nil {
+		writeUpdateError(resp, err)
+
+		return
+	}
+
+	err = resp.WriteEntity("success")
+	if err != nil {
+		fmt.Printf("error while sending error: %v", err)
+	}
+}
+
+func (namespace *Namespace) updateDeploymentStatus(req *restful.Request, resp *restful.Response) {
+	name := req.PathParameter("name")
+	namespaceQuery := req.PathParameter("namespace")
+
+	newDeploymentStatus := new(DeploymentStatus)
+	err := req.ReadEntity(newDeploymentStatus)
+	if err != nil {
+		err = resp.WriteError(http.StatusBadRequest, err)
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	err = guaranteedUpdate(
+		fmt.Sprintf("%s/%s/%s", deploymentEtcdKey, namespaceQuery, name),
+		false,
+		func(storedDeployment *Deployment) (*Deployment, error) {
+			storedDeployment.Status = *newDeploymentStatus
+
+			return storedDeployment, nil
+		},
+	)
+	if err != nil {
+		writeUpdateError(resp, err)
+
+		return
+	}
+
+	err = resp.WriteEntity("success")
+	if err != nil {
+		fmt.Printf("error while sending error: %v", err)
+	}
+}
+
+// rollbackDeployment asks the deployment controller to roll the deployment back to the template of a revision
+func (namespace *Namespace) rollbackDeployment(req *restful.Request, resp *restful.Response) {
+	name := req.PathParameter("name")
+	namespaceQuery := req.PathParameter("namespace")
+
+	rollback := new(DeploymentRollback)
+	err := req.ReadEntity(rollback)
+	if err != nil {
+		err = resp.WriteError(http.StatusBadRequest, err)
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	if rollback.RollbackTo.Revision < 0 {
+		err = resp.WriteErrorString(http.StatusBadRequest, "rollbackTo.revision must not be negative")
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	err = guaranteedUpdate(
+		fmt.Sprintf("%s/%s/%s", deploymentEtcdKey, namespaceQuery, name),
+		false,
+		func(storedDeployment *Deployment) (*Deployment, error) {
+			storedDeployment.Spec.RollbackTo = &rollback.RollbackTo
+
+			return storedDeployment, nil
+		},
+	)
+	if err != nil {
+		writeUpdateError(resp, err)
+
+		return
+	}
+
+	err = resp.WriteEntity("success")
+	if err != nil {
+		fmt.Printf("error while sending error: %v", err)
+	}
+}
+
+// deleteDeployment removes the deployment, its replicasets are deleted by the deployment controller
+func (namespace *Namespace) deleteDeployment(req *restful.Request, resp *restful.Response) {
+	namespace.deleteResourceInNamespace(req, resp, deploymentEtcdKey)
+}
+
+func (namespace *Namespace) getDaemonSets(req *restful.Request, resp *restful.Response) {
+	namespace.getAllResourceInNamespace(req, resp, daemonSetEtcdKey)
+}
+
+func (namespace *Namespace) getDaemonSet(req *restful.Request, resp *restful.Response) {
+	namespace.getSingleResourceInNamespace(req, resp, daemonSetEtcdKey)
+}
+
+// createDaemonSet creates or replaces the spec of a daemonset, the status is only changed by its subresource
+func (namespace *Namespace) createDaemonSet(req *restful.Request, resp *restful.Response) {
+	newDaemonSet := new(DaemonSet)
+	err := req.ReadEntity(newDaemonSet)
+	if err != nil {
+		err = resp.WriteError(http.StatusBadRequest, err)
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	newDaemonSet.setDefaults()
+
+	if err = newDaemonSet.validate(); err != nil {
+		err = resp.WriteError(http.StatusBadRequest, err)
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	namespaceQuery := req.PathParameter("namespace")
+
+	if newDaemonSet.Metadata.Namespace == "" {
+		newDaemonSet.Metadata.Namespace = namespaceQuery
+	}
+
+	newDaemonSet.Kind = "DaemonSet"
+
+	err = guaranteedUpdate(
+		fmt.Sprintf("%s/%s/%s", daemonSetEtcdKey, newDaemonSet.Metadata.Namespace, newDaemonSet.Metadata.Name),
+		true,
+		func(storedDaemonSet *DaemonSet) (*DaemonSet, error) {
+			if storedDaemonSet == nil {
+				newDaemonSet.Metadata.CreationTimestamp = time.Now().Format(time.RFC3339)
+				newDaemonSet.Metadata.UID = uuid.NewString()
+				newDaemonSet.Status = DaemonSetStatus{}
+
+				return newDaemonSet, nil
+			}
+
+			updatedDaemonSet := *newDaemonSet
+			updatedDaemonSet.Metadata.CreationTimestamp = storedDaemonSet.Metadata.CreationTimestamp
+			updatedDaemonSet.Metadata.UID = storedDaemonSet.Metadata.UID
+			updatedDaemonSet.Status = storedDaemonSet.Status
+
+			return &updatedDaemonSet, nil
+		},
+	)
+	if err != nil {
+		writeUpdateError(resp, err)
+
+		return
+	}
+
+	err = resp.WriteEntity("success")
+	if err != nil {
+		fmt.Printf("error while sending error: %v", err)
+	}
+}
+
+func (namespace *Namespace) updateDaemonSetStatus(req *restful.Request, resp *restful.Response) {
+	name := req.PathParameter("name")
+	namespaceQuery := req.PathParameter("namespace")
+
+	newDaemonSetStatus := new(DaemonSetStatus)
+	err := req.ReadEntity(newDaemonSetStatus)
+	if err != nil {
+		err = resp.WriteError(http.StatusBadRequest, err)
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	err = guaranteedUpdate(
+		fmt.Sprintf("%s/%s/%s", daemonSetEtcdKey, namespaceQuery, name),
+		false,
+		func(storedDaemonSet *DaemonSet) (*DaemonSet, error) {
+			storedDaemonSet.Status = *newDaemonSetStatus
+
+			return storedDaemonSet, nil
+		},
+	)
+	if err != nil {
+		writeUpdateError(resp, err)
+
+		return
+	}
+
+	err = resp.WriteEntity("success")
+	if err != nil {
+		fmt.Printf("error while sending error: %v", err)
+	}
+}
+
+// deleteDaemonSet removes the daemonset, its pods are deleted by the daemonset controller
+func (namespace *Namespace) deleteDaemonSet(req *restful.Request, resp *restful.Response) {
+	namespace.deleteResourceInNamespace(req, resp, daemonSetEtcdKey)
+}
+
+func (namespace *Namespace) getStatefulSets(req *restful.Request, resp *restful.Response) {
+	namespace.getAllResourceInNamespace(req, resp, statefulSetEtcdKey)
+}
+
+func (namespace *Namespace) getStatefulSet(req *restful.Request, resp *restful.Response) {
+	namespace.getSingleResourceInNamespace(req, resp, statefulSetEtcdKey)
+}
+
+// createStatefulSet creates or replaces the spec of a statefulset, the status is only changed by its subresource.
+// Only the replicas, template and update strategy of an existing statefulset can change
+func (namespace *Namespace) createStatefulSet(req *restful.Request, resp *restful.Response) {
+	newStatefulSet := new(StatefulSet)
+	err := req.ReadEntity(newStatefulSet)
+	if err != nil {
+		err = resp.WriteError(http.StatusBadRequest, err)
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	newStatefulSet.setDefaults()
+
+	if err = newStatefulSet.validate(); err != nil {
+		err = resp.WriteError(http.StatusBadRequest, err)
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	namespaceQuery := req.PathParameter("namespace")
+
+	if newStatefulSet.Metadata.Namespace == "" {
+		newStatefulSet.Metadata.Namespace = namespaceQuery
+	}
+
+	newStatefulSet.Kind = "StatefulSet"
+
+	err = guaranteedUpdate(
+		fmt.Sprintf("%s/%s/%s", statefulSetEtcdKey, newStatefulSet.Metadata.Namespace, newStatefulSet.Metadata.Name),
+		true,
+		func(storedStatefulSet *StatefulSet) (*StatefulSet, error) {
+			if storedStatefulSet == nil {
+				newStatefulSet.Metadata.CreationTimestamp = time.Now().Format(time.RFC3339)
+				newStatefulSet.Metadata.UID = uuid.NewString()
+				newStatefulSet.Status = StatefulSetStatus{}
+
+				return newStatefulSet, nil
+			}
+
+			updatedStatefulSet := *newStatefulSet
+			updatedStatefulSet.Metadata.CreationTimestamp = storedStatefulSet.Metadata.CreationTimestamp
+			updatedStatefulSet.Metadata.UID = storedStatefulSet.Metadata.UID
+			updatedStatefulSet.Status = storedStatefulSet.Status
+
+			// the identities of the pods are derived from these, changing them would orphan the pods
+			if !reflect.DeepEqual(updatedStatefulSet.Spec.Selector, storedStatefulSet.Spec.Selector) ||
+				updatedStatefulSet.Spec.ServiceName != storedStatefulSet.Spec.ServiceName ||
+				updatedStatefulSet.Spec.PodManagementPolicy != storedStatefulSet.Spec.PodManagementPolicy {
+				return nil, &updateError{
+					statusCode: http.StatusUnprocessableEntity,
+					message: fmt.Sprintf("statefulset %s/%s spec.selector, spec.serviceName and spec.podManagementPolicy are immutable",
+						storedStatefulSet.Metadata.Namespace, storedStatefulSet.Metadata.Name),
+				}
+			}
+
+			return &updatedStatefulSet, nil
+		},
+	)
+	if err != nil {
+		writeUpdateError(resp, err)
+
+		return
+	}
+
+	err = resp.WriteEntity("success")
+	if err != nil {
+		fmt.Printf("error while sending error: %v", err)
+	}
+}
+
+func (namespace *Namespace) updateStatefulSetStatus(req *restful.Request, resp *restful.Response) {
+	name := req.PathParameter("name")
+	namespaceQuery := req.PathParameter("namespace")
+
+	newStatefulSetStatus := new(StatefulSetStatus)
+	err := req.ReadEntity(newStatefulSetStatus)
+	if err != nil {
+		err = resp.WriteError(http.StatusBadRequest, err)
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	err = guaranteedUpdate(
+		fmt.Sprintf("%s/%s/%s", statefulSetEtcdKey, namespaceQuery, name),
+		false,
+		func(storedStatefulSet *StatefulSet) (*StatefulSet, error) {
+			storedStatefulSet.Status = *newStatefulSetStatus
+
+			return storedStatefulSet, nil
+		},
+	)
+	if err != nil {
+		writeUpdateError(resp, err)
+
+		return
+	}
+
+	err = resp.WriteEntity("success")
+	if err != nil {
+		fmt.Printf("error while sending error: %v", err)
+	}
+}
+
+// deleteStatefulSet removes the statefulset, its pods are deleted by the statefulset controller
+func (namespace *Namespace) deleteStatefulSet(req *restful.Request, resp *restful.Response) {
+	namespace.deleteResourceInNamespace(req, resp, statefulSetEtcdKey)
+}
+
+func (namespace *Namespace) getHorizontalPodAutoscalers(req *restful.Request, resp *restful.Response) {
+	namespace.getAllResourceInNamespace(req, resp, horizontalPodAutoscalerEtcdKey)
+}
+
+func (namespace *Namespace) getHorizontalPodAutoscaler(req *restful.Request, resp *restful.Response) {
+	namespace.getSingleResourceInNamespace(req, resp, horizontalPodAutoscalerEtcdKey)
+}
+
+// createHorizontalPodAutoscaler creates or replaces the spec of a horizontalpodautoscaler, the status is only changed
+// by its subresource
+func (namespace *Namespace) createHorizontalPodAutoscaler(req *restful.Request, resp *restful.Response) {
+	newHorizontalPodAutoscaler := new(HorizontalPodAutoscaler)
+	err := req.ReadEntity(newHorizontalPodAutoscaler)
+	if err != nil {
+		err = resp.WriteError(http.StatusBadRequest, err)
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	newHorizontalPodAutoscaler.setDefaults()
+
+	if err = newHorizontalPodAutoscaler.validate(); err != nil {
+		err = resp.WriteError(http.StatusBadRequest, err)
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	namespaceQuery := req.PathParameter("namespace")
+
+	if newHorizontalPodAutoscaler.Metadata.Namespace == "" {
+		newHorizontalPodAutoscaler.Metadata.Namespace = namespaceQuery
+	}
+
+	newHorizontalPodAutoscaler.Kind = "HorizontalPodAutoscaler"
+
+	err = guaranteedUpdate(
+		fmt.Sprintf("%s/%s/%s", horizontalPodAutoscalerEtcdKey, newHorizontalPodAutoscaler.Metadata.Namespace, newHorizontalPodAutoscaler.Metadata.Name),
+		true,
+		func(storedHorizontalPodAutoscaler *HorizontalPodAutoscaler) (*HorizontalPodAutoscaler, error) {
+			if storedHorizontalPodAutoscaler == nil {
+				newHorizontalPodAutoscaler.Metadata.CreationTimestamp = time.Now().Format(time.RFC3339)
+				newHorizontalPodAutoscaler.Metadata.UID = uuid.NewString()
+				newHorizontalPodAutoscaler.Status = HorizontalPodAutoscalerStatus{}
+
+				return newHorizontalPodAutoscaler, nil
+			}
+
+			updatedHorizontalPodAutoscaler := *newHorizontalPodAutoscaler
+			updatedHorizontalPodAutoscaler.Metadata.CreationTimestamp = storedHorizontalPodAutoscaler.Metadata.CreationTimestamp
+			updatedHorizontalPodAutoscaler.Metadata.UID = storedHorizontalPodAutoscaler.Metadata.UID
+			updatedHorizontalPodAutoscaler.Status = storedHorizontalPodAutoscaler.Status
+
+			return &updatedHorizontalPodAutoscaler, nil
+		},
+	)
+	if err != nil {
+		writeUpdateError(resp, err)
+
+		return
+	}
+
+	err = resp.WriteEntity("success")
+	if err != nil {
+		fmt.Printf("error while sending error: %v", err)
+	}
+}
+
+func (namespace *Namespace) updateHorizontalPodAutoscalerStatus(req *restful.Request, resp *restful.Response) {
+	name := req.PathParameter("name")
+	namespaceQuery := req.PathParameter("namespace")
+
+	newHorizontalPodAutoscalerStatus := new(HorizontalPodAutoscalerStatus)
+	err := req.ReadEntity(newHorizontalPodAutoscalerStatus)
+	if err != nil {
+		err = resp.WriteError(http.StatusBadRequest, err)
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	err = guaranteedUpdate(
+		fmt.Sprintf("%s/%s/%s", horizontalPodAutoscalerEtcdKey, namespaceQuery, name),
+		false,
+		func(storedHorizontalPodAutoscaler *HorizontalPodAutoscaler) (*HorizontalPodAutoscaler, error) {
+			storedHorizontalPodAutoscaler.Status = *newHorizontalPodAutoscalerStatus
+
+			return storedHorizontalPodAutoscaler, nil
+		},
+	)
+	if err != nil {
+		writeUpdateError(resp, err)
+
+		return
+	}
+
+	err = resp.WriteEntity("success")
+	if err != nil {
+		fmt.Printf("error while sending error: %v", err)
+	}
+}
+
+// deleteHorizontalPodAutoscaler removes the horizontalpodautoscaler, the replicas of its target are left as they are
+func (namespace *Namespace) deleteHorizontalPodAutoscaler(req *restful.Request, resp *restful.Response) {
+	namespace.deleteResourceInNamespace(req, resp, horizontalPodAutoscalerEtcdKey)
+}
+
+func (namespace *Namespace) getDeploymentScale(req *restful.Request, resp *restful.Response) {
+	getScale[Deployment](req, resp, deploymentEtcdKey)
+}
+
+func (namespace *Namespace) updateDeploymentScale(req *restful.Request, resp *restful.Response) {
+	updateScale[Deployment](req, resp, deploymentEtcdKey)
+}
+
+func (namespace *Namespace) getReplicaSetScale(req *restful.Request, resp *restful.Response) {
+	getScale[ReplicaSet](req, resp, replicaSetEtcdKey)
+}
+
+func (namespace *Namespace) updateReplicaSetScale(req *restful.Request, resp *restful.Response) {
+	updateScale[ReplicaSet](req, resp, replicaSetEtcdKey)
+}
+
+func (namespace *Namespace) getStatefulSetScale(req *restful.Request, resp *restful.Response) {
+	getScale[StatefulSet](req, resp, statefulSetEtcdKey)
+}
+
+func (namespace *Namespace) updateStatefulSetScale(req *restful.Request, resp *restful.Response) {
+	updateScale[StatefulSet](req, resp, statefulSetEtcdKey)
+}
+
+func (namespace *Namespace) getPodsMetrics(req *restful.Request, resp *restful.Response) {
+	namespace.getAllResourceInNamespace(req, resp, podMetricsEtcdKey)
+}
+
+func (namespace *Namespace) getPodMetrics(req *restful.Request, resp *restful.Response) {
+	namespace.getSingleResourceInNamespace(req, resp, podMetricsEtcdKey)
+}
+
+// createPodMetrics creates or replaces the metrics of a pod, they are posted by the kubelet of the pod
+func (namespace *Namespace) createPodMetrics(req *restful.Request, resp *restful.Response) {
+	newPodMetrics := new(PodMetrics)
+	err := req.ReadEntity(newPodMetrics)
+	if err != nil {
+		err = resp.WriteError(http.StatusBadRequest, err)
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	namespaceQuery := req.PathParameter("namespace")
+
+	if newPodMetrics.Metadata.Namespace == "" {
+		newPodMetrics.Metadata.Namespace = namespaceQuery
+	}
+
+	if newPodMetrics.Metadata.CreationTimestamp == "" {
+		newPodMetrics.Metadata.CreationTimestamp = time.Now().Format(time.RFC3339)
+	}
+
+	newPodMetrics.Kind = "PodMetrics"
+
+	namespace.createResourceInNamespace(
+		req,
+		resp,
+		podMetricsEtcdKey,
+		newPodMetrics.Metadata.Namespace,
+		newPodMetrics.Metadata.Name,
+		newPodMetrics,
+	)
+}
+
+func (namespace *Namespace) deletePodMetrics(req *restful.Request, resp *restful.Response) {
+	namespace.deleteResourceInNamespace(req, resp, podMetricsEtcdKey)
+}
+
+func (namespace *Namespace) getJobs(req *restful.Request, resp *restful.Response) {
+	namespace.getAllResourceInNamespace(req, resp, jobEtcdKey)
+}
+
+func (namespace *Namespace) getJob(req *restful.Request, resp *restful.Response) {
+	namespace.getSingleResourceInNamespace(req, resp, jobEtcdKey)
+}
+
+// createJob creates a job or updates its parallelism, backoffLimit and activeDeadlineSeconds, the rest of the spec
+// can not change once the job runs. A job created without a selector gets one generated from its UID
+func (namespace *Namespace) createJob(req *restful.Request, resp *restful.Response) {
+	newJob := new(Job)
+	err := req.ReadEntity(newJob)
+	if err != nil {
+		err = resp.WriteError(http.StatusBadRequest, err)
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	newJob.Spec.setDefaults()
+
+	if err = newJob.validate(); err != nil {
+		err = resp.WriteError(http.StatusBadRequest, err)
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	namespaceQuery := req.PathParameter("namespace")
+
+	if newJob.Metadata.Namespace == "" {
+		newJob.Metadata.Namespace = namespaceQuery
+	}
+
+	newJob.Kind = "Job"
+	generateSelector := len(newJob.Spec.Selector.MatchLabels) == 0
+
+	err = guaranteedUpdate(
+		fmt.Sprintf("%s/%s/%s", jobEtcdKey, newJob.Metadata.Namespace, newJob.Metadata.Name),
+		true,
+		func(storedJob *Job) (*Job, error) {
+			if storedJob == nil {
+				createdJob := *newJob
+				createdJob.Metadata.CreationTimestamp = time.Now().Format(time.RFC3339)
+				createdJob.Metadata.UID = uuid.NewString()
+				createdJob.Status = JobStatus{}
+
+				if generateSelector {
+					createdJob.Spec.generateSelector(createdJob.Metadata.UID, createdJob.Metadata.Name)
+				}
+
+				return &createdJob, nil
+			}
+
+			updatedJob := *newJob
+			updatedJob.Metadata.CreationTimestamp = storedJob.Metadata.CreationTimestamp
+			updatedJob.Metadata.UID = storedJob.Metadata.UID
+			updatedJob.Status = storedJob.Status
+
+			if generateSelector {
+				updatedJob.Spec.generateSelector(storedJob.Metadata.UID, storedJob.Metadata.Name)
+			}
+
+			if !reflect.DeepEqual(updatedJob.Spec.Template, storedJob.Spec.Template) ||
+				!reflect.DeepEqual(updatedJob.Spec.Selector, storedJob.Spec.Selector) ||
+				*updatedJob.Spec.Completions != *storedJob.Spec.Completions {
+				return nil, &updateError{
+					statusCode: http.StatusUnprocessableEntity,
+					message: fmt.Sprintf("job %s/%s spec.template, spec.selector and spec.completions are immutable",
+						storedJob.Metadata.Namespace, storedJob.Metadata.Name),
+				}
+			}
+
+			return &updatedJob, nil
+		},
+	)
+	if err != nil {
+		writeUpdateError(resp, err)
+
+		return
+	}
+
+	err = resp.WriteEntity("success")
+	if err != nil {
+		fmt.Printf("error while sending error: %v", err)
+	}
+}
+
+func (namespace *Namespace) updateJobStatus(req *restful.Request, resp *restful.Response) {
+	name := req.PathParameter("name")
+	namespaceQuery := req.PathParameter("namespace")
+
+	newJobStatus := new(JobStatus)
+	err := req.ReadEntity(newJobStatus)
+	if err != nil {
+		err = resp.WriteError(http.StatusBadRequest, err)
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	err = guaranteedUpdate(
+		fmt.Sprintf("%s/%s/%s", jobEtcdKey, namespaceQuery, name),
+		false,
+		func(storedJob *Job) (*Job, error) {
+			storedJob.Status = *newJobStatus
+
+			return storedJob, nil
+		},
+	)
+	if err != nil {
+		writeUpdateError(resp, err)
+
+		return
+	}
+
+	err = resp.WriteEntity("success")
+	if err != nil {
+		fmt.Printf("error while sending error: %v", err)
+	}
+}
+
+// deleteJob removes the job, its pods are deleted by the job controller
+func (namespace *Namespace) deleteJob(req *restful.Request, resp *restful.Response) {
+	namespace.deleteResourceInNamespace(req, resp, jobEtcdKey)
+}
+
+func (namespace *Namespace) getCronJobs(req *restful.Request, resp *restful.Response) {
+	namespace.getAllResourceInNamespace(req, resp, cronJobEtcdKey)
+}
+
+func (namespace *Namespace) getCronJob(req *restful.Request, resp *restful.Response) {
+	namespace.getSingleResourceInNamespace(req, resp, cronJobEtcdKey)
+}
+
+// createCronJob creates or replaces the spec of a cronjob, the status is only changed by its subresource
+func (namespace *Namespace) createCronJob(req *restful.Request, resp *restful.Response) {
+	newCronJob := new(CronJob)
+	err := req.ReadEntity(newCronJob)
+	if err != nil {
+		err = resp.WriteError(http.StatusBadRequest, err)
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	newCronJob.setDefaults()
+
+	if err = newCronJob.validate(); err != nil {
+		err = resp.WriteError(http.StatusBadRequest, err)
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	namespaceQuery := req.PathParameter("namespace")
+
+	if newCronJob.Metadata.Namespace == "" {
+		newCronJob.Metadata.Namespace = namespaceQuery
+	}
+
+	newCronJob.Kind = "CronJob"
+
+	err = guaranteedUpdate(
+		fmt.Sprintf("%s/%s/%s", cronJobEtcdKey, newCronJob.Metadata.Namespace, newCronJob.Metadata.Name),
+		true,
+		func(storedCronJob *CronJob) (*CronJob, error) {
+			if storedCronJob == nil {
+				newCronJob.Metadata.CreationTimestamp = time.Now().Format(time.RFC3339)
+				newCronJob.Metadata.UID = uuid.NewString()
+				newCronJob.Status = CronJobStatus{}
+
+				return newCronJob, nil
+			}
+
+			updatedCronJob := *newCronJob
+			updatedCronJob.Metadata.CreationTimestamp = storedCronJob.Metadata.CreationTimestamp
+			updatedCronJob.Metadata.UID = storedCronJob.Metadata.UID
+			updatedCronJob.Status = storedCronJob.Status
+
+			return &updatedCronJob, nil
+		},
+	)
+	if err != nil {
+		writeUpdateError(resp, err)
+
+		return
+	}
+
+	err = resp.WriteEntity("success")
+	if err != nil {
+		fmt.Printf("error while sending error: %v", err)
+	}
+}
+
+func (namespace *Namespace) updateCronJobStatus(req *restful.Request, resp *restful.Response) {
+	name := req.PathParameter("name")
+	namespaceQuery := req.PathParameter("namespace")
+
+	newCronJobStatus := new(CronJobStatus)
+	err := req.ReadEntity(newCronJobStatus)
+	if err != nil {
+		err = resp.WriteError(http.StatusBadRequest, err)
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	err = guaranteedUpdate(
+		fmt.Sprintf("%s/%s/%s", cronJobEtcdKey, namespaceQuery, name),
+		false,
+		func(storedCronJob *CronJob) (*CronJob, error) {
+			storedCronJob.Status = *newCronJobStatus
+
+			return storedCronJob, nil
+		},
+	)
+	if err != nil {
+		writeUpdateError(resp, err)
+
+		return
+	}
+
+	err = resp.WriteEntity("success")
+	if err != nil {
+		fmt.Printf("error while sending error: %v", err)
+	}
+}
+
+// deleteCronJob removes the cronjob, its jobs are deleted by the cronjob controller
+func (namespace *Namespace) deleteCronJob(req *restful.Request, resp *restful.Response) {
+	namespace.deleteResourceInNamespace(req, resp, cronJobEtcdKey)
 }
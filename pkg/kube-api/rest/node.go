@@ -0,0 +1,316 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	restful "github.com/emicklei/go-restful/v3"
+	"github.com/google/uuid"
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/etcd"
+	kubeapi_logger "github.com/jonatan5524/own-kubernetes/pkg/kube-api/logger"
+)
+
+const (
+	nodeEtcdKey = "/nodes"
+
+	NodeReady          = "Ready"
+	NodeMemoryPressure = "MemoryPressure"
+	NodeDiskPressure   = "DiskPressure"
+	NodePIDPressure    = "PIDPressure"
+
+	ConditionTrue    = "True"
+	ConditionFalse   = "False"
+	ConditionUnknown = "Unknown"
+
+	NodeInternalIP = "InternalIP"
+	NodeHostName   = "Hostname"
+
+	ResourceCPU    = "cpu"
+	ResourceMemory = "memory"
+	ResourcePods   = "pods"
+)
+
+var etcdServiceAppNode etcd.EtcdService
+
+// Node is a machine running a kubelet, it is cluster scoped and its name is the hostname of the machine
+type Node struct {
+	Metadata ResourceMetadata `json:"metadata" yaml:"metadata"`
+
+	TypeMeta `json:",inline" yaml:",inline"`
+
+	Spec NodeSpec `json:"spec" yaml:"spec"`
+
+	Status NodeStatus `json:"status" yaml:"status"`
+}
+
+type NodeSpec struct {
+	PodCIDR string `json:"podCIDR" yaml:"podCIDR"`
+	// Unschedulable keeps new pods off the node, the pods already on it keep running
+	Unschedulable bool `json:"unschedulable" yaml:"unschedulable"`
+	// Taints keep off the pods that do not tolerate them
+	Taints []Taint `json:"taints" yaml:"taints"`
+}
+
+type NodeStatus struct {
+	// Capacity and Allocatable are quantities by resource name, for example cpu: "2", memory: "4028520Ki", pods: "110"
+	Capacity    map[string]string `json:"capacity" yaml:"capacity"`
+	Allocatable map[string]string `json:"allocatable" yaml:"allocatable"`
+
+	Conditions []NodeCondition `json:"conditions" yaml:"conditions"`
+	Addresses  []NodeAddress   `json:"addresses" yaml:"addresses"`
+	NodeInfo   NodeSystemInfo  `json:"nodeInfo" yaml:"nodeInfo"`
+}
+
+type NodeCondition struct {
+	Type string `json:"type" yaml:"type"`
+	// Status is True, False or Unknown
+	Status             string `json:"status" yaml:"status"`
+	LastHeartbeatTime  string `json:"lastHeartbeatTime" yaml:"lastHeartbeatTime"`
+	LastTransitionTime string `json:"lastTransitionTime" yaml:"lastTransitionTime"`
+	Reason             string `json:"reason" yaml:"reason"`
+	Message            string `json:"message" yaml:"message"`
+}
+
+type NodeAddress struct {
+	Type    string `json:"type" yaml:"type"`
+	Address string `json:"address" yaml:"address"`
+}
+
+type NodeSystemInfo struct {
+	KernelVersion           string `json:"kernelVersion" yaml:"kernelVersion"`
+	OSImage                 string `json:"osImage" yaml:"osImage"`
+	ContainerRuntimeVersion string `json:"containerRuntimeVersion" yaml:"containerRuntimeVersion"`
+	OperatingSystem         string `json:"operatingSystem" yaml:"operatingSystem"`
+	Architecture            string `json:"architecture" yaml:"architecture"`
+}
+
+// Condition returns the condition of the given type, nil when the node does not report it
+func (node *Node) Condition(conditionType string) *NodeCondition {
+	for index := range node.Status.Conditions {
+		if node.Status.Conditions[index].Type == conditionType {
+			return &node.Status.Conditions[index]
+		}
+	}
+
+	return nil
+}
+
+func (node *Node) Register(container *restful.Container, etcdService etcd.EtcdService) {
+	log.Println("rest api node register")
+
+	etcdServiceAppNode = etcdService
+
+	ws := new(restful.WebService)
+
+	ws.Filter(kubeapi_logger.LoggerMiddleware)
+
+	ws.Path("/nodes").
+		Consumes(restful.MIME_JSON, MimeYAML).
+		Produces(restful.MIME_JSON, MimeYAML)
+
+	ws.Route(ws.GET("/").To(node.getAll).
+		Param(ws.QueryParameter("watch", "boolean for watching resource").DataType("bool").DefaultValue("false")).
+		Param(ws.QueryParameter("fieldSelector", "field selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("labelSelector", "label selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("resourceVersion", "resource version to start the watch from").DataType("string").DefaultValue("")))
+
+	ws.Route(ws.GET("/{name}").To(node.getNode).
+		Param(ws.PathParameter("name", "name of the node").DataType("string")))
+
+	ws.Route(ws.POST("/").To(node.createNode).
+		Param(ws.BodyParameter("Node", "a Node resource (JSON)").DataType("rest.Node")))
+
+	ws.Route(ws.PATCH("/{name}/status").To(node.updateNodeStatus).
+		Param(ws.PathParameter("name", "name of the node").DataType("string")).
+		Param(ws.BodyParameter("NodeStatus", "a Node status resource (JSON)").DataType("rest.NodeStatus")))
+
+	ws.Route(ws.DELETE("/{name}").To(node.deleteNode).
+		Param(ws.PathParameter("name", "name of the node").DataType("string")))
+
+	container.Add(ws)
+}
+
+func (node *Node) getAll(req *restful.Request, resp *restful.Response) {
+	watchQuery := req.QueryParameter("watch")
+
+	if watchQuery == "true" {
+		watchFromWatchCache(req, resp, nodeEtcdKey, "")
+
+		return
+	}
+
+	listFromWatchCache(req, resp, nodeEtcdKey, "")
+}
+
+func (node *Node) getNode(req *restful.Request, resp *restful.Response) {
+	name := req.PathParameter("name")
+
+	res, err := etcdServiceAppNode.GetResource(fmt.Sprintf("%s/%s", nodeEtcdKey, name))
+	if err != nil {
+		err = resp.WriteError(http.StatusBadRequest, err)
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	var nodeRes Node
+	if err = json.Unmarshal(res, &nodeRes); err != nil {
+		err = resp.WriteError(http.StatusBadRequest, err)
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	err = resp.WriteEntity(nodeRes)
+	if err != nil {
+		fmt.Printf("error while sending error: %v", err)
+	}
+}
+
+func putNode(resp *restful.Response, nodeRes *Node) {
+	nodeBytes, err := json.Marshal(nodeRes)
+	if err != nil {
+		err = resp.WriteError(http.StatusBadRequest, err)
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	err = etcdServiceAppNode.PutResource(fmt.Sprintf("%s/%s", nodeEtcdKey, nodeRes.Metadata.Name), string(nodeBytes))
+	if err != nil {
+		err = resp.WriteError(http.StatusBadRequest, err)
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	err = resp.WriteEntity("success")
+	if err != nil {
+		fmt.Printf("error while sending error: %v", err)
+	}
+}
+
+// createNode creates or replaces a node, a kubelet registering again after a restart keeps the identity of its node
+func (node *Node) createNode(req *restful.Request, resp *restful.Response) {
+	newNode := new(Node)
+	err := req.ReadEntity(newNode)
+	if err != nil {
+		err = resp.WriteError(http.StatusBadRequest, err)
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	if newNode.Metadata.Name == "" {
+		err = resp.WriteErrorString(http.StatusBadRequest, "node name is required")
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	res, err := etcdServiceAppNode.GetResource(fmt.Sprintf("%s/%s", nodeEtcdKey, newNode.Metadata.Name))
+	if err == nil {
+		var storedNode Node
+		if err = json.Unmarshal(res, &storedNode); err == nil {
+			newNode.Metadata.UID = storedNode.Metadata.UID
+			newNode.Metadata.CreationTimestamp = storedNode.Metadata.CreationTimestamp
+
+			// a node manifest applied by a user changes the spec, the status stays the one the kubelet posted
+			if len(newNode.Status.Conditions) == 0 {
+				newNode.Status = storedNode.Status
+			}
+
+			if newNode.Spec.PodCIDR == "" {
+				newNode.Spec.PodCIDR = storedNode.Spec.PodCIDR
+			}
+		}
+	}
+
+	// nodes are cluster scoped
+	newNode.Metadata.Namespace = ""
+
+	if newNode.Metadata.CreationTimestamp == "" {
+		newNode.Metadata.CreationTimestamp = time.Now().Format(time.RFC3339)
+	}
+
+	if newNode.Metadata.UID == "" {
+		newNode.Metadata.UID = uuid.NewString()
+	}
+
+	newNode.Kind = "Node"
+
+	putNode(resp, newNode)
+}
+
+func (node *Node) updateNodeStatus(req *restful.Request, resp *restful.Response) {
+	name := req.PathParameter("name")
+
+	res, err := etcdServiceAppNode.GetResource(fmt.Sprintf("%s/%s", nodeEtcdKey, name))
+	if err != nil {
+		err = resp.WriteError(http.StatusBadRequest, err)
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	var nodeRes Node
+	if err = json.Unmarshal(res, &nodeRes); err != nil {
+		err = resp.WriteError(http.StatusBadRequest, err)
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	newNodeStatus := new(NodeStatus)
+	err = req.ReadEntity(newNodeStatus)
+	if err != nil {
+		err = resp.WriteError(http.StatusBadRequest, err)
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	nodeRes.Status = *newNodeStatus
+
+	putNode(resp, &nodeRes)
+}
+
+func (node *Node) deleteNode(req *restful.Request, resp *restful.Response) {
+	name := req.PathParameter("name")
+
+	err := etcdServiceAppNode.DeleteResource(fmt.Sprintf("%s/%s", nodeEtcdKey, name))
+	if err != nil {
+		err = resp.WriteError(http.StatusBadRequest, err)
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	err = resp.WriteEntity("success")
+	if err != nil {
+		fmt.Printf("error while sending error: %v", err)
+	}
+}
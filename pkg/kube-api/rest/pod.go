@@ -2,9 +2,8 @@ package rest
 
 import (
 	"encoding/json"
-	"fmt"
 	"log"
-	"net/http"
+	"time"
 
 	restful "github.com/emicklei/go-restful/v3"
 	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/etcd"
@@ -15,6 +14,17 @@ const (
 	podEtcdKey                            = "/pods"
 	defaultNamespace                      = "default"
 	LastAppliedConfigurationAnnotationKey = "last-applied-configuration"
+	// kubeletUserAgentPrefix is the start of the user agent of the kubelets, see utils.SetUserAgent
+	kubeletUserAgentPrefix = "kubelet/"
+
+	PodPendingPhase     = "Pending"
+	PodRunningPhase     = "Running"
+	PodSucceededPhase   = "Succeeded"
+	PodFailedPhase      = "Failed"
+	PodTerminatingPhase = "Terminating"
+
+	// PodReady tells if the pod can serve requests, only ready pods are added to endpoints
+	PodReady = "Ready"
 )
 
 var etcdServiceAppPod etcd.EtcdService
@@ -22,27 +32,70 @@ var etcdServiceAppPod etcd.EtcdService
 type Pod struct {
 	Metadata ResourceMetadata `json:"metadata" yaml:"metadata"`
 
-	Kind string `json:"kind" yaml:"kind"`
+	TypeMeta `json:",inline" yaml:",inline"`
 
 	Status PodStatus `json:"status" yaml:"status"`
 
-	Spec struct {
-		NodeName    string      `json:"nodeName" yaml:"nodeName"`
-		Containers  []Container `json:"containers" yaml:"containers"`
-		HostNetwork bool        `json:"hostNetwork" yaml:"hostNetwork"`
-	} `json:"spec" yaml:"spec"`
+	Spec PodSpec `json:"spec" yaml:"spec"`
+}
+
+type PodSpec struct {
+	NodeName    string      `json:"nodeName" yaml:"nodeName"`
+	Containers  []Container `json:"containers" yaml:"containers"`
+	HostNetwork bool        `json:"hostNetwork" yaml:"hostNetwork"`
+	Volumes     []Volume    `json:"volumes" yaml:"volumes"`
+
+	// NodeSelector, Affinity and Tolerations limit the nodes the scheduler may place the pod on
+	NodeSelector map[string]string `json:"nodeSelector" yaml:"nodeSelector"`
+	Affinity     *Affinity         `json:"affinity,omitempty" yaml:"affinity,omitempty"`
+	Tolerations  []Toleration      `json:"tolerations" yaml:"tolerations"`
+
+	// Hostname is the hostname of the containers, the pod name when not set. With a Subdomain the pod is also
+	// named <hostname>.<subdomain>.<namespace>.svc.cluster.local in its /etc/hosts
+	Hostname  string `json:"hostname" yaml:"hostname"`
+	Subdomain string `json:"subdomain" yaml:"subdomain"`
+}
+
+// PodTemplateSpec is the pod a workload controller creates its pods from
+type PodTemplateSpec struct {
+	Metadata ResourceMetadata `json:"metadata" yaml:"metadata"`
+
+	Spec PodSpec `json:"spec" yaml:"spec"`
 }
 
 type PodStatus struct {
 	PodIP             string            `json:"podIP" yaml:"podIP"`
 	Phase             string            `json:"phase" yaml:"phase"`
 	ContainerStatuses []ContainerStatus `json:"containerStatuses" yaml:"containerStatuses"`
+	Conditions        []PodCondition    `json:"conditions" yaml:"conditions"`
+}
+
+type PodCondition struct {
+	Type string `json:"type" yaml:"type"`
+	// Status is True, False or Unknown
+	Status             string `json:"status" yaml:"status"`
+	LastTransitionTime string `json:"lastTransitionTime" yaml:"lastTransitionTime"`
+	Reason             string `json:"reason" yaml:"reason"`
+	Message            string `json:"message" yaml:"message"`
 }
 
 type ContainerStatus struct {
-	ContainerID string `json:"containerID" yaml:"containerID"`
-	Image       string `json:"image" yaml:"image"`
-	Name        string `json:"name" yaml:"name"`
+	ContainerID string         `json:"containerID" yaml:"containerID"`
+	Image       string         `json:"image" yaml:"image"`
+	Name        string         `json:"name" yaml:"name"`
+	State       ContainerState `json:"state" yaml:"state"`
+}
+
+type ContainerState struct {
+	// Terminated is set once the container exited
+	Terminated *ContainerStateTerminated `json:"terminated,omitempty" yaml:"terminated,omitempty"`
+}
+
+type ContainerStateTerminated struct {
+	ExitCode int `json:"exitCode" yaml:"exitCode"`
+	// Reason is Completed for a zero exit code and Error otherwise
+	Reason     string `json:"reason" yaml:"reason"`
+	FinishedAt string `json:"finishedAt" yaml:"finishedAt"`
 }
 
 type Container struct {
@@ -53,37 +106,200 @@ type Container struct {
 	Args    []string `json:"args" yaml:"args"`
 
 	Ports []struct {
-		ContainerPort int `json:"containerPort" yaml:"containerPort"`
+		// Name can be referred to by the target port of a service
+		Name          string `json:"name,omitempty" yaml:"name,omitempty"`
+		ContainerPort int    `json:"containerPort" yaml:"containerPort"`
 	} `json:"ports" yaml:"ports"`
 
-	Env []struct {
-		Name  string `json:"name" yaml:"name"`
-		Value string `json:"value" yaml:"value"`
-	} `json:"env" yaml:"env"`
+	Env     []EnvVar        `json:"env" yaml:"env"`
+	EnvFrom []EnvFromSource `json:"envFrom" yaml:"envFrom"`
+
+	VolumeMounts []VolumeMount `json:"volumeMounts" yaml:"volumeMounts"`
+
+	Resources ResourceRequirements `json:"resources" yaml:"resources"`
 
 	SecurityContext struct {
 		Privileged bool `json:"privileged" yaml:"privileged"`
 	} `json:"securityContext" yaml:"securityContext"`
 }
 
-func (pod *Pod) Register(etcdServersEndpoints string) {
+// Binding assigns a pod to a node, it is posted to the binding subresource of the pod
+type Binding struct {
+	Metadata ResourceMetadata `json:"metadata" yaml:"metadata"`
+
+	TypeMeta `json:",inline" yaml:",inline"`
+
+	Target ObjectReference `json:"target" yaml:"target"`
+}
+
+// ResourceRequirements are quantities by resource name, for example cpu: "250m", memory: "64Mi",
+// the scheduler places the pod only on a node with enough allocatable resources left for the requests
+type ResourceRequirements struct {
+	Requests map[string]string `json:"requests" yaml:"requests"`
+	Limits   map[string]string `json:"limits" yaml:"limits"`
+}
+
+type EnvVar struct {
+	Name  string `json:"name" yaml:"name"`
+	Value string `json:"value" yaml:"value"`
+	// ValueFrom takes the value from another resource, Value is ignored when it is set
+	ValueFrom *EnvVarSource `json:"valueFrom,omitempty" yaml:"valueFrom,omitempty"`
+}
+
+type EnvVarSource struct {
+	ConfigMapKeyRef *ConfigMapKeySelector `json:"configMapKeyRef,omitempty" yaml:"configMapKeyRef,omitempty"`
+	SecretKeyRef    *SecretKeySelector    `json:"secretKeyRef,omitempty" yaml:"secretKeyRef,omitempty"`
+}
+
+type ConfigMapKeySelector struct {
+	Name string `json:"name" yaml:"name"`
+	Key  string `json:"key" yaml:"key"`
+	// Optional skips the variable when the configmap or the key does not exist instead of failing the container
+	Optional bool `json:"optional" yaml:"optional"`
+}
+
+type SecretKeySelector struct {
+	Name     string `json:"name" yaml:"name"`
+	Key      string `json:"key" yaml:"key"`
+	Optional bool   `json:"optional" yaml:"optional"`
+}
+
+// EnvFromSource adds all the keys of a resource as environment variables
+type EnvFromSource struct {
+	Prefix       string              `json:"prefix" yaml:"prefix"`
+	ConfigMapRef *ConfigMapEnvSource `json:"configMapRef,omitempty" yaml:"configMapRef,omitempty"`
+	SecretRef    *SecretEnvSource    `json:"secretRef,omitempty" yaml:"secretRef,omitempty"`
+}
+
+type ConfigMapEnvSource struct {
+	Name     string `json:"name" yaml:"name"`
+	Optional bool   `json:"optional" yaml:"optional"`
+}
+
+type SecretEnvSource struct {
+	Name     string `json:"name" yaml:"name"`
+	Optional bool   `json:"optional" yaml:"optional"`
+}
+
+type VolumeMount struct {
+	Name      string `json:"name" yaml:"name"`
+	MountPath string `json:"mountPath" yaml:"mountPath"`
+	ReadOnly  bool   `json:"readOnly" yaml:"readOnly"`
+}
+
+type Volume struct {
+	Name      string                 `json:"name" yaml:"name"`
+	ConfigMap *ConfigMapVolumeSource `json:"configMap,omitempty" yaml:"configMap,omitempty"`
+	Secret    *SecretVolumeSource    `json:"secret,omitempty" yaml:"secret,omitempty"`
+}
+
+// ConfigMapVolumeSource projects the keys of a configmap as files, all the keys when Items is empty
+type ConfigMapVolumeSource struct {
+	Name     string      `json:"name" yaml:"name"`
+	Items    []KeyToPath `json:"items" yaml:"items"`
+	Optional bool        `json:"optional" yaml:"optional"`
+}
+
+// SecretVolumeSource projects the keys of a secret as files on tmpfs, so they are never written to the node disk
+type SecretVolumeSource struct {
+	SecretName string      `json:"secretName" yaml:"secretName"`
+	Items      []KeyToPath `json:"items" yaml:"items"`
+	Optional   bool        `json:"optional" yaml:"optional"`
+}
+
+type KeyToPath struct {
+	Key  string `json:"key" yaml:"key"`
+	Path string `json:"path" yaml:"path"`
+}
+
+// Condition returns the condition of the given type, nil when the pod does not report it
+func (pod *Pod) Condition(conditionType string) *PodCondition {
+	for index := range pod.Status.Conditions {
+		if pod.Status.Conditions[index].Type == conditionType {
+			return &pod.Status.Conditions[index]
+		}
+	}
+
+	return nil
+}
+
+// SetCondition adds the condition or replaces the one of the same type, the transition time is kept
+// when the status did not change
+func (pod *Pod) SetCondition(condition PodCondition) {
+	existing := pod.Condition(condition.Type)
+	if existing == nil {
+		pod.Status.Conditions = append(pod.Status.Conditions, condition)
+
+		return
+	}
+
+	if existing.Status == condition.Status {
+		condition.LastTransitionTime = existing.LastTransitionTime
+	}
+
+	*existing = condition
+}
+
+// IsReady tells if the pod is running and was not marked as not ready, pods of older kubelets have no conditions
+func (pod *Pod) IsReady() bool {
+	if pod.Status.Phase != PodRunningPhase {
+		return false
+	}
+
+	ready := pod.Condition(PodReady)
+
+	return ready == nil || ready.Status == ConditionTrue
+}
+
+// IsActive tells if the pod is not deleted and did not finish, the workload controllers count only active pods
+func (pod *Pod) IsActive() bool {
+	return pod.Status.Phase != PodSucceededPhase && pod.Status.Phase != PodFailedPhase &&
+		pod.Status.Phase != PodTerminatingPhase
+}
+
+// IsAvailable tells if the pod is ready for at least minReadySeconds
+func (pod *Pod) IsAvailable(minReadySeconds int, now time.Time) bool {
+	if !pod.IsReady() {
+		return false
+	}
+
+	if minReadySeconds == 0 {
+		return true
+	}
+
+	ready := pod.Condition(PodReady)
+	if ready == nil {
+		return true
+	}
+
+	transitionTime, err := time.Parse(time.RFC3339, ready.LastTransitionTime)
+	if err != nil {
+		return false
+	}
+
+	return !transitionTime.Add(time.Duration(minReadySeconds) * time.Second).After(now)
+}
+
+func (pod *Pod) Register(container *restful.Container, etcdService etcd.EtcdService) {
 	log.Println("rest api pod register")
 
-	etcdServiceAppPod = etcd.NewEtcdService(etcdServersEndpoints)
+	etcdServiceAppPod = etcdService
 
 	ws := new(restful.WebService)
 
 	ws.Filter(kubeapi_logger.LoggerMiddleware)
 
 	ws.Path("/pods").
-		Consumes(restful.MIME_XML, restful.MIME_JSON).
-		Produces(restful.MIME_JSON, restful.MIME_XML)
+		Consumes(restful.MIME_JSON, MimeYAML).
+		Produces(restful.MIME_JSON, MimeYAML)
 
 	ws.Route(ws.GET("/").To(pod.getAll).
 		Param(ws.QueryParameter("watch", "boolean for watching resource").DataType("bool").DefaultValue("false")).
-		Param(ws.QueryParameter("fieldSelector", "field selector for resource").DataType("string").DefaultValue("")))
+		Param(ws.QueryParameter("fieldSelector", "field selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("labelSelector", "label selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("resourceVersion", "resource version to start the watch from").DataType("string").DefaultValue("")))
 
-	restful.Add(ws)
+	container.Add(ws)
 }
 
 func (pod *Pod) initLastAppliedConfigurations() error {
@@ -111,91 +327,10 @@ func (pod *Pod) getAll(req *restful.Request, resp *restful.Response) {
 	watchQuery := req.QueryParameter("watch")
 
 	if watchQuery == "true" {
-		pod.watcher(req, resp)
-
-		return
-	}
-
-	resArr, err := etcdServiceAppPod.GetAllFromResource(podEtcdKey)
-	if err != nil {
-		err = resp.WriteError(http.StatusBadRequest, err)
-		if err != nil {
-			resp.WriteError(http.StatusInternalServerError, err)
-		}
+		watchFromWatchCache(req, resp, podEtcdKey, "")
 
 		return
 	}
 
-	podsRes := make([]Pod, len(resArr))
-	for index, res := range resArr {
-		var pod Pod
-		if err = json.Unmarshal(res, &pod); err != nil {
-			err = resp.WriteError(http.StatusBadRequest, err)
-			if err != nil {
-				resp.WriteError(http.StatusInternalServerError, err)
-			}
-
-			return
-		}
-
-		podsRes[index] = pod
-	}
-
-	err = resp.WriteEntity(podsRes)
-	if err != nil {
-		resp.WriteError(http.StatusInternalServerError, err)
-	}
-}
-
-func (pod *Pod) watcher(req *restful.Request, resp *restful.Response) {
-	watchQuery := req.QueryParameter("watch")
-	fieldSelector := req.QueryParameter("fieldSelector")
-
-	if watchQuery != "true" {
-		return
-	}
-
-	resp.Header().Set("Access-Control-Allow-Origin", "*")
-	resp.Header().Set("Content-Type", "text/event-stream")
-	resp.Header().Set("Cache-Control", "no-cache")
-	resp.Header().Set("Connection", "keep-alive")
-
-	watchChan, closeChanFunc, err := etcdServiceAppPod.GetWatchChannel(podEtcdKey)
-	if err != nil {
-		err = resp.WriteErrorString(http.StatusBadRequest, err.Error())
-		if err != nil {
-			resp.WriteError(http.StatusInternalServerError, err)
-		}
-
-		return
-	}
-	defer closeChanFunc()
-	defer resp.CloseNotify()
-
-	log.Println("Client pod watcher started")
-
-	for {
-		select {
-		case watchResp := <-watchChan:
-			if watchResp.Err() != nil {
-				resp.WriteError(http.StatusInternalServerError, err)
-			}
-
-			for _, event := range watchResp.Events {
-				log.Printf("watch: %s executed on %s with value %s\n", event.Type, string(event.Kv.Key), string(event.Kv.Value))
-
-				if fieldSelector == "" {
-					fmt.Fprintf(resp, "Type: %s Value: %s\n", event.Type, string(event.Kv.Value))
-					} else if validateFieldSelector(fieldSelector, string(event.Kv.Value)) {
-						fmt.Fprintf(resp, "Type: %s Value: %s\n", event.Type, string(event.Kv.Value))
-				}
-
-				resp.Flush()
-			}
-
-		case <-req.Request.Context().Done():
-			log.Println("Connection closed")
-			return
-		}
-	}
+	listFromWatchCache(req, resp, podEtcdKey, "")
 }
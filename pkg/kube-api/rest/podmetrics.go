@@ -0,0 +1,66 @@
+package rest
+
+import (
+	"log"
+
+	restful "github.com/emicklei/go-restful/v3"
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/etcd"
+	kubeapi_logger "github.com/jonatan5524/own-kubernetes/pkg/kube-api/logger"
+)
+
+const podMetricsEtcdKey = "/podmetrics"
+
+var etcdServiceAppPodMetrics etcd.EtcdService
+
+// PodMetrics is the resource usage of the containers of a pod, reported by the kubelet of its node every
+// metrics resolution. It has the name and namespace of the pod
+type PodMetrics struct {
+	Metadata ResourceMetadata `json:"metadata" yaml:"metadata"`
+
+	TypeMeta `json:",inline" yaml:",inline"`
+
+	// Timestamp is when the usage was read and Window the duration the cpu usage rate was measured over
+	Timestamp  string             `json:"timestamp" yaml:"timestamp"`
+	Window     string             `json:"window" yaml:"window"`
+	Containers []ContainerMetrics `json:"containers" yaml:"containers"`
+}
+
+type ContainerMetrics struct {
+	Name string `json:"name" yaml:"name"`
+	// Usage are quantities by resource name, cpu in cores such as "250m" and memory in bytes such as "64Mi"
+	Usage map[string]string `json:"usage" yaml:"usage"`
+}
+
+func (podMetrics *PodMetrics) Register(container *restful.Container, etcdService etcd.EtcdService) {
+	log.Println("rest api podmetrics register")
+
+	etcdServiceAppPodMetrics = etcdService
+
+	ws := new(restful.WebService)
+
+	ws.Filter(kubeapi_logger.LoggerMiddleware)
+
+	ws.Path("/podmetrics").
+		Consumes(restful.MIME_JSON, MimeYAML).
+		Produces(restful.MIME_JSON, MimeYAML)
+
+	ws.Route(ws.GET("/").To(podMetrics.getAll).
+		Param(ws.QueryParameter("watch", "boolean for watching resource").DataType("bool").DefaultValue("false")).
+		Param(ws.QueryParameter("fieldSelector", "field selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("labelSelector", "label selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("resourceVersion", "resource version to start the watch from").DataType("string").DefaultValue("")))
+
+	container.Add(ws)
+}
+
+func (podMetrics *PodMetrics) getAll(req *restful.Request, resp *restful.Response) {
+	watchQuery := req.QueryParameter("watch")
+
+	if watchQuery == "true" {
+		watchFromWatchCache(req, resp, podMetricsEtcdKey, "")
+
+		return
+	}
+
+	listFromWatchCache(req, resp, podMetricsEtcdKey, "")
+}
@@ -0,0 +1,107 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const maxPort = 65535
+
+// portAllocator allocates the ports of an inclusive range of ports
+type portAllocator struct {
+	rangeAllocator
+
+	base int
+}
+
+// newPortAllocator returns the allocator of a range written as first-last, for example 30000-32767
+func newPortAllocator(etcdKey string, portRange string) (*portAllocator, error) {
+	firstString, lastString, ok := strings.Cut(portRange, "-")
+	if !ok {
+		return nil, fmt.Errorf("invalid port range %s: must be first-last", portRange)
+	}
+
+	first, err := strconv.Atoi(strings.TrimSpace(firstString))
+	if err != nil {
+		return nil, fmt.Errorf("invalid port range %s: %v", portRange, err)
+	}
+
+	last, err := strconv.Atoi(strings.TrimSpace(lastString))
+	if err != nil {
+		return nil, fmt.Errorf("invalid port range %s: %v", portRange, err)
+	}
+
+	if first < 1 || last > maxPort || first > last {
+		return nil, fmt.Errorf("invalid port range %s: must be between 1 and %d with the first port not after the last",
+			portRange, maxPort)
+	}
+
+	return &portAllocator{
+		rangeAllocator: rangeAllocator{
+			etcdKey:   etcdKey,
+			rangeName: fmt.Sprintf("%d-%d", first, last),
+			size:      last - first + 1,
+		},
+		base: first,
+	}, nil
+}
+
+// offset returns the offset of the port in the range, false when the port is not in the range
+func (allocator *portAllocator) offset(port int) (int, bool) {
+	offset := port - allocator.base
+	if offset < 0 || offset >= allocator.size {
+		return 0, false
+	}
+
+	return offset, true
+}
+
+// allocate reserves the port requested by the user for the field
+func (allocator *portAllocator) allocate(port int, field string) error {
+	offset, ok := allocator.offset(port)
+	if !ok {
+		return &updateError{
+			statusCode: http.StatusUnprocessableEntity,
+			message:    fmt.Sprintf("%s: invalid value %d, not in the port range %s", field, port, allocator.rangeName),
+		}
+	}
+
+	err := allocator.rangeAllocator.allocate(offset)
+	if errors.Is(err, errRangeItemAllocated) {
+		return &updateError{
+			statusCode: http.StatusUnprocessableEntity,
+			message:    fmt.Sprintf("%s: invalid value %d, the port is already allocated", field, port),
+		}
+	}
+
+	return err
+}
+
+func (allocator *portAllocator) allocateNext() (int, error) {
+	offset, err := allocator.rangeAllocator.allocateNext()
+	if errors.Is(err, errRangeFull) {
+		return 0, &updateError{
+			statusCode: http.StatusInternalServerError,
+			message:    fmt.Sprintf("no port left in the port range %s", allocator.rangeName),
+		}
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return allocator.base + offset, nil
+}
+
+// release frees the port, ports outside of the range were never allocated from it and are ignored
+func (allocator *portAllocator) release(port int) error {
+	offset, ok := allocator.offset(port)
+	if !ok {
+		return nil
+	}
+
+	return allocator.rangeAllocator.release(offset)
+}
@@ -0,0 +1,167 @@
+package rest
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestNewPortAllocatorErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		portRange string
+	}{
+		{name: "single port", portRange: "30000"},
+		{name: "not a number", portRange: "a-32767"},
+		{name: "first port zero", portRange: "0-100"},
+		{name: "last port too large", portRange: "30000-65536"},
+		{name: "first after last", portRange: "32767-30000"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := newPortAllocator(serviceNodePortsEtcdKey, test.portRange); err == nil {
+				t.Errorf("newPortAllocator(%q) expected an error", test.portRange)
+			}
+		})
+	}
+}
+
+func TestPortAllocator(t *testing.T) {
+	type step struct {
+		operation string
+		port      int
+		// want is the port allocated by allocateNext
+		want int
+		// wantStatusCode is the status code of the updateError returned, 0 when no error is returned
+		wantStatusCode int
+	}
+
+	tests := []struct {
+		name      string
+		portRange string
+		steps     []step
+	}{
+		{
+			name:      "allocate next from the first port",
+			portRange: "30000-30009",
+			steps: []step{
+				{operation: "allocateNext", want: 30000},
+				{operation: "allocateNext", want: 30001},
+			},
+		},
+		{
+			name:      "claim a specific port",
+			portRange: "30000-30009",
+			steps: []step{
+				{operation: "allocate", port: 30000},
+				{operation: "allocate", port: 30009},
+				{operation: "allocateNext", want: 30001},
+			},
+		},
+		{
+			name:      "claim an allocated port",
+			portRange: "30000-30009",
+			steps: []step{
+				{operation: "allocateNext", want: 30000},
+				{operation: "allocate", port: 30000, wantStatusCode: http.StatusUnprocessableEntity},
+			},
+		},
+		{
+			name:      "claim ports outside of the range",
+			portRange: "30000-30009",
+			steps: []step{
+				{operation: "allocate", port: 29999, wantStatusCode: http.StatusUnprocessableEntity},
+				{operation: "allocate", port: 30010, wantStatusCode: http.StatusUnprocessableEntity},
+			},
+		},
+		{
+			name:      "released port is allocated again",
+			portRange: "30000-30009",
+			steps: []step{
+				{operation: "allocateNext", want: 30000},
+				{operation: "allocateNext", want: 30001},
+				{operation: "release", port: 30000},
+				{operation: "allocateNext", want: 30000},
+				{operation: "release", port: 30001},
+				{operation: "allocate", port: 30001},
+			},
+		},
+		{
+			name:      "release ports that were never allocated from the range",
+			portRange: "30000-30009",
+			steps: []step{
+				{operation: "release", port: 80},
+				{operation: "release", port: 30005},
+			},
+		},
+		{
+			name:      "exhaust the range",
+			portRange: "30000-30001",
+			steps: []step{
+				{operation: "allocateNext", want: 30000},
+				{operation: "allocateNext", want: 30001},
+				{operation: "allocateNext", wantStatusCode: http.StatusInternalServerError},
+				{operation: "release", port: 30000},
+				{operation: "allocateNext", want: 30000},
+			},
+		},
+		{
+			name:      "range of a single port",
+			portRange: "30000-30000",
+			steps: []step{
+				{operation: "allocate", port: 30000},
+				{operation: "allocateNext", wantStatusCode: http.StatusInternalServerError},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useFakeEtcdService(t)
+
+			allocator, err := newPortAllocator(serviceNodePortsEtcdKey, test.portRange)
+			if err != nil {
+				t.Fatalf("newPortAllocator(%q) error: %v", test.portRange, err)
+			}
+
+			for index, current := range test.steps {
+				var allocated int
+
+				switch current.operation {
+				case "allocate":
+					err = allocator.allocate(current.port, "spec.ports[0].nodePort")
+				case "allocateNext":
+					allocated, err = allocator.allocateNext()
+				case "release":
+					err = allocator.release(current.port)
+				}
+
+				if statusCode := updateErrorStatusCode(t, err); statusCode != current.wantStatusCode {
+					t.Fatalf("step %d %s %d error = %v, want status code %d",
+						index, current.operation, current.port, err, current.wantStatusCode)
+				}
+
+				if err == nil && current.operation == "allocateNext" && allocated != current.want {
+					t.Fatalf("step %d allocateNext = %d, want %d", index, allocated, current.want)
+				}
+			}
+		})
+	}
+}
+
+func TestPortAllocatorErrorNamesField(t *testing.T) {
+	useFakeEtcdService(t)
+
+	allocator, err := newPortAllocator(serviceNodePortsEtcdKey, DefaultServiceNodePortRange)
+	if err != nil {
+		t.Fatalf("newPortAllocator error: %v", err)
+	}
+
+	field := "spec.ports[1].nodePort"
+
+	err = allocator.allocate(80, field)
+	if err == nil || !strings.HasPrefix(err.Error(), field+":") {
+		t.Errorf("allocate error = %v, want an error of %s", err, field)
+	}
+}
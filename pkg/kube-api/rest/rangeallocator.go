@@ -0,0 +1,126 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"log"
+)
+
+var (
+	errRangeItemAllocated = errors.New("already allocated")
+	errRangeFull          = errors.New("range is full")
+)
+
+// RangeAllocation is the bitmap of the allocated items of a range, it is kept in etcd so every allocation is
+// checked against all the previous ones and not only against the resources the allocating component has seen
+type RangeAllocation struct {
+	Range string `json:"range" yaml:"range"`
+	Data  []byte `json:"data" yaml:"data"`
+}
+
+// rangeAllocator allocates the offsets 0 to size-1 of a range from the bitmap stored under the etcd key. Every change
+// of the bitmap is written with guaranteedUpdate, so two concurrent allocations never get the same offset
+type rangeAllocator struct {
+	etcdKey   string
+	rangeName string
+	size      int
+}
+
+// bitmap returns a copy of the stored bitmap, an empty one when nothing is stored or the stored bitmap is of another
+// range, the allocations of the new range are rebuilt by repair
+func (allocator *rangeAllocator) bitmap(stored *RangeAllocation) []byte {
+	bitmap := make([]byte, (allocator.size+7)/8)
+
+	if stored != nil && stored.Range == allocator.rangeName {
+		copy(bitmap, stored.Data)
+	}
+
+	return bitmap
+}
+
+func (allocator *rangeAllocator) update(update func(bitmap []byte) error) error {
+	return guaranteedUpdate(allocator.etcdKey, true, func(stored *RangeAllocation) (*RangeAllocation, error) {
+		bitmap := allocator.bitmap(stored)
+		if err := update(bitmap); err != nil {
+			return nil, err
+		}
+
+		return &RangeAllocation{Range: allocator.rangeName, Data: bitmap}, nil
+	})
+}
+
+func isAllocated(bitmap []byte, offset int) bool {
+	return bitmap[offset/8]&(1<<(offset%8)) != 0
+}
+
+func setAllocated(bitmap []byte, offset int, allocated bool) {
+	if allocated {
+		bitmap[offset/8] |= 1 << (offset % 8)
+	} else {
+		bitmap[offset/8] &^= 1 << (offset % 8)
+	}
+}
+
+// allocate marks the offset as allocated, errRangeItemAllocated when it already is
+func (allocator *rangeAllocator) allocate(offset int) error {
+	return allocator.update(func(bitmap []byte) error {
+		if isAllocated(bitmap, offset) {
+			return errRangeItemAllocated
+		}
+
+		setAllocated(bitmap, offset, true)
+
+		return nil
+	})
+}
+
+// allocateNext allocates the lowest free offset, errRangeFull when every offset is allocated
+func (allocator *rangeAllocator) allocateNext() (int, error) {
+	var allocated int
+
+	err := allocator.update(func(bitmap []byte) error {
+		for offset := 0; offset < allocator.size; offset++ {
+			if !isAllocated(bitmap, offset) {
+				setAllocated(bitmap, offset, true)
+				allocated = offset
+
+				return nil
+			}
+		}
+
+		return errRangeFull
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return allocated, nil
+}
+
+func (allocator *rangeAllocator) release(offset int) error {
+	return allocator.update(func(bitmap []byte) error {
+		setAllocated(bitmap, offset, false)
+
+		return nil
+	})
+}
+
+// repair replaces the stored bitmap with the offsets used by the stored resources, so allocations leaked by a
+// failed request or made before the allocator existed are fixed when the api starts
+func (allocator *rangeAllocator) repair(offsets []int) error {
+	log.Printf("repairing allocations of range %s in %s", allocator.rangeName, allocator.etcdKey)
+
+	err := guaranteedUpdate(allocator.etcdKey, true, func(_ *RangeAllocation) (*RangeAllocation, error) {
+		bitmap := allocator.bitmap(nil)
+		for _, offset := range offsets {
+			setAllocated(bitmap, offset, true)
+		}
+
+		return &RangeAllocation{Range: allocator.rangeName, Data: bitmap}, nil
+	})
+	if err != nil {
+		return fmt.Errorf("error repairing range %s: %v", allocator.rangeName, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,379 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/etcd"
+)
+
+// fakeEtcdService keeps the resources in memory with the revisions etcd would give them, the methods the
+// allocators do not use are left to the embedded nil service
+type fakeEtcdService struct {
+	etcd.EtcdService
+
+	mu        sync.Mutex
+	revision  int64
+	values    map[string]string
+	revisions map[string]int64
+}
+
+func newFakeEtcdService() *fakeEtcdService {
+	return &fakeEtcdService{
+		values:    make(map[string]string),
+		revisions: make(map[string]int64),
+	}
+}
+
+func (service *fakeEtcdService) GetResource(key string) ([]byte, error) {
+	value, _, err := service.GetResourceWithRevision(key)
+
+	return value, err
+}
+
+func (service *fakeEtcdService) GetResourceWithRevision(key string) ([]byte, int64, error) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	value, ok := service.values[key]
+	if !ok {
+		return nil, 0, fmt.Errorf("key not found for: %s", key)
+	}
+
+	return []byte(value), service.revisions[key], nil
+}
+
+func (service *fakeEtcdService) PutResource(key string, value string) error {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	service.putLocked(key, value)
+
+	return nil
+}
+
+func (service *fakeEtcdService) PutResourceIfRevision(key string, value string, revision int64) error {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	// a missing key has the revision 0, like in etcd
+	if service.revisions[key] != revision {
+		return fmt.Errorf("failed to put %s: %w", key, etcd.ErrRevisionConflict)
+	}
+
+	service.putLocked(key, value)
+
+	return nil
+}
+
+func (service *fakeEtcdService) putLocked(key string, value string) {
+	service.revision++
+	service.values[key] = value
+	service.revisions[key] = service.revision
+}
+
+func (service *fakeEtcdService) ListResource(prefix string) (map[string][]byte, int64, error) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	values := map[string][]byte{}
+	for key, value := range service.values {
+		if strings.HasPrefix(key, prefix) {
+			values[key] = []byte(value)
+		}
+	}
+
+	return values, service.revision, nil
+}
+
+// useFakeEtcdService replaces the etcd service of the api with an empty fake one for the test
+func useFakeEtcdService(t *testing.T) *fakeEtcdService {
+	t.Helper()
+
+	service := newFakeEtcdService()
+
+	previous := etcdServiceAppNamespace
+	etcdServiceAppNamespace = service
+	t.Cleanup(func() {
+		etcdServiceAppNamespace = previous
+	})
+
+	return service
+}
+
+func newTestRangeAllocator(size int) *rangeAllocator {
+	return &rangeAllocator{
+		etcdKey:   "/ranges/test",
+		rangeName: fmt.Sprintf("test-%d", size),
+		size:      size,
+	}
+}
+
+// allocatedOffsets returns the offsets set in the stored bitmap of the allocator
+func allocatedOffsets(t *testing.T, allocator *rangeAllocator) []int {
+	t.Helper()
+
+	var stored *RangeAllocation
+
+	value, err := etcdServiceAppNamespace.GetResource(allocator.etcdKey)
+	if err == nil {
+		stored = &RangeAllocation{}
+		if err = json.Unmarshal(value, stored); err != nil {
+			t.Fatalf("error parsing the allocations: %v", err)
+		}
+	} else if !strings.Contains(err.Error(), "key not found") {
+		t.Fatalf("error reading the allocations: %v", err)
+	}
+
+	bitmap := allocator.bitmap(stored)
+
+	offsets := []int{}
+	for offset := 0; offset < allocator.size; offset++ {
+		if isAllocated(bitmap, offset) {
+			offsets = append(offsets, offset)
+		}
+	}
+
+	return offsets
+}
+
+func TestRangeAllocator(t *testing.T) {
+	type step struct {
+		operation string
+		offset    int
+		// want is the offset allocated by allocateNext
+		want    int
+		wantErr error
+	}
+
+	tests := []struct {
+		name          string
+		size          int
+		steps         []step
+		wantAllocated []int
+	}{
+		{
+			name: "allocate next in order",
+			size: 10,
+			steps: []step{
+				{operation: "allocateNext", want: 0},
+				{operation: "allocateNext", want: 1},
+				{operation: "allocateNext", want: 2},
+			},
+			wantAllocated: []int{0, 1, 2},
+		},
+		{
+			name: "allocate a specific offset",
+			size: 10,
+			steps: []step{
+				{operation: "allocate", offset: 7},
+				{operation: "allocateNext", want: 0},
+			},
+			wantAllocated: []int{0, 7},
+		},
+		{
+			name: "allocate next skips a specific offset",
+			size: 10,
+			steps: []step{
+				{operation: "allocate", offset: 0},
+				{operation: "allocateNext", want: 1},
+			},
+			wantAllocated: []int{0, 1},
+		},
+		{
+			name: "allocate an allocated offset",
+			size: 10,
+			steps: []step{
+				{operation: "allocate", offset: 3},
+				{operation: "allocate", offset: 3, wantErr: errRangeItemAllocated},
+			},
+			wantAllocated: []int{3},
+		},
+		{
+			name: "released offset is allocated again",
+			size: 10,
+			steps: []step{
+				{operation: "allocateNext", want: 0},
+				{operation: "allocateNext", want: 1},
+				{operation: "release", offset: 0},
+				{operation: "allocateNext", want: 0},
+				{operation: "release", offset: 1},
+				{operation: "allocate", offset: 1},
+			},
+			wantAllocated: []int{0, 1},
+		},
+		{
+			name: "release a free offset",
+			size: 10,
+			steps: []step{
+				{operation: "release", offset: 4},
+			},
+			wantAllocated: []int{},
+		},
+		{
+			name: "exhaust the range",
+			size: 3,
+			steps: []step{
+				{operation: "allocateNext", want: 0},
+				{operation: "allocateNext", want: 1},
+				{operation: "allocateNext", want: 2},
+				{operation: "allocateNext", wantErr: errRangeFull},
+				{operation: "release", offset: 1},
+				{operation: "allocateNext", want: 1},
+				{operation: "allocateNext", wantErr: errRangeFull},
+			},
+			wantAllocated: []int{0, 1, 2},
+		},
+		{
+			name: "offsets past the first byte",
+			size: 20,
+			steps: []step{
+				{operation: "allocate", offset: 8},
+				{operation: "allocate", offset: 19},
+				{operation: "release", offset: 8},
+			},
+			wantAllocated: []int{19},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useFakeEtcdService(t)
+			allocator := newTestRangeAllocator(test.size)
+
+			for index, current := range test.steps {
+				var (
+					allocated int
+					err       error
+				)
+
+				switch current.operation {
+				case "allocate":
+					err = allocator.allocate(current.offset)
+				case "allocateNext":
+					allocated, err = allocator.allocateNext()
+				case "release":
+					err = allocator.release(current.offset)
+				}
+
+				if !errors.Is(err, current.wantErr) {
+					t.Fatalf("step %d %s error = %v, want %v", index, current.operation, err, current.wantErr)
+				}
+
+				if err == nil && current.operation == "allocateNext" && allocated != current.want {
+					t.Fatalf("step %d allocateNext = %d, want %d", index, allocated, current.want)
+				}
+			}
+
+			if offsets := allocatedOffsets(t, allocator); fmt.Sprint(offsets) != fmt.Sprint(test.wantAllocated) {
+				t.Errorf("allocated offsets = %v, want %v", offsets, test.wantAllocated)
+			}
+		})
+	}
+}
+
+func TestRangeAllocatorRepair(t *testing.T) {
+	tests := []struct {
+		name          string
+		allocated     []int
+		repaired      []int
+		wantAllocated []int
+	}{
+		{name: "leaked offsets are freed", allocated: []int{0, 1, 2}, repaired: []int{1}, wantAllocated: []int{1}},
+		{name: "used offsets are allocated", allocated: []int{0}, repaired: []int{0, 5, 9}, wantAllocated: []int{0, 5, 9}},
+		{name: "nothing used", allocated: []int{3, 4}, repaired: nil, wantAllocated: []int{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useFakeEtcdService(t)
+			allocator := newTestRangeAllocator(10)
+
+			for _, offset := range test.allocated {
+				if err := allocator.allocate(offset); err != nil {
+					t.Fatalf("allocate %d error: %v", offset, err)
+				}
+			}
+
+			if err := allocator.repair(test.repaired); err != nil {
+				t.Fatalf("repair error: %v", err)
+			}
+
+			if offsets := allocatedOffsets(t, allocator); fmt.Sprint(offsets) != fmt.Sprint(test.wantAllocated) {
+				t.Errorf("allocated offsets = %v, want %v", offsets, test.wantAllocated)
+			}
+		})
+	}
+}
+
+func TestRangeAllocatorIgnoresBitmapOfAnotherRange(t *testing.T) {
+	useFakeEtcdService(t)
+
+	small := newTestRangeAllocator(10)
+	if err := small.allocate(0); err != nil {
+		t.Fatalf("allocate error: %v", err)
+	}
+
+	// the range changed, its allocations are rebuilt by repair and start empty until then
+	large := newTestRangeAllocator(20)
+
+	offset, err := large.allocateNext()
+	if err != nil {
+		t.Fatalf("allocateNext error: %v", err)
+	}
+
+	if offset != 0 {
+		t.Errorf("allocateNext = %d, want 0", offset)
+	}
+}
+
+func TestRangeAllocatorConcurrentAllocations(t *testing.T) {
+	useFakeEtcdService(t)
+
+	const size = 50
+	allocator := newTestRangeAllocator(size)
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		offsets []int
+	)
+
+	for i := 0; i < size; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			offset, err := allocator.allocateNext()
+			if err != nil {
+				t.Errorf("allocateNext error: %v", err)
+
+				return
+			}
+
+			mu.Lock()
+			offsets = append(offsets, offset)
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+
+	// every allocation retried on a conflict instead of overwriting another one
+	sort.Ints(offsets)
+	for index, offset := range offsets {
+		if offset != index {
+			t.Fatalf("allocated offsets = %v, want every offset of the range once", offsets)
+		}
+	}
+
+	if _, err := allocator.allocateNext(); !errors.Is(err, errRangeFull) {
+		t.Errorf("allocateNext on a full range error = %v, want %v", err, errRangeFull)
+	}
+}
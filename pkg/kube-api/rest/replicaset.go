@@ -0,0 +1,113 @@
+package rest
+
+import (
+	"fmt"
+	"log"
+
+	restful "github.com/emicklei/go-restful/v3"
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/etcd"
+	kubeapi_logger "github.com/jonatan5524/own-kubernetes/pkg/kube-api/logger"
+)
+
+const replicaSetEtcdKey = "/replicasets"
+
+var etcdServiceAppReplicaSet etcd.EtcdService
+
+// ReplicaSet keeps a number of pods created from its template running, the pods are owned by the replicaset
+type ReplicaSet struct {
+	Metadata ResourceMetadata `json:"metadata" yaml:"metadata"`
+
+	TypeMeta `json:",inline" yaml:",inline"`
+
+	Spec ReplicaSetSpec `json:"spec" yaml:"spec"`
+
+	Status ReplicaSetStatus `json:"status" yaml:"status"`
+}
+
+type ReplicaSetSpec struct {
+	// Replicas is the number of pods to run, 1 when not set
+	Replicas *int `json:"replicas,omitempty" yaml:"replicas,omitempty"`
+	// MinReadySeconds is how long a pod must be ready to be counted as available
+	MinReadySeconds int `json:"minReadySeconds" yaml:"minReadySeconds"`
+	// Selector must match the labels of the template
+	Selector LabelSelector   `json:"selector" yaml:"selector"`
+	Template PodTemplateSpec `json:"template" yaml:"template"`
+}
+
+type ReplicaSetStatus struct {
+	// Replicas is the number of active pods of the replicaset
+	Replicas          int `json:"replicas" yaml:"replicas"`
+	ReadyReplicas     int `json:"readyReplicas" yaml:"readyReplicas"`
+	AvailableReplicas int `json:"availableReplicas" yaml:"availableReplicas"`
+}
+
+func (replicaSet *ReplicaSet) setDefaults() {
+	if replicaSet.Spec.Replicas == nil {
+		replicas := 1
+		replicaSet.Spec.Replicas = &replicas
+	}
+}
+
+func (replicaSet *ReplicaSet) validate() error {
+	if replicaSet.Metadata.Name == "" {
+		return fmt.Errorf("replicaset name is required")
+	}
+
+	if *replicaSet.Spec.Replicas < 0 {
+		return fmt.Errorf("spec.replicas must not be negative")
+	}
+
+	return validateTemplateSelector(replicaSet.Spec.Selector, replicaSet.Spec.Template)
+}
+
+// validateTemplateSelector checks that the pods of the template are matched by the selector of their controller,
+// otherwise the controller would create pods forever
+func validateTemplateSelector(selector LabelSelector, template PodTemplateSpec) error {
+	if len(selector.MatchLabels) == 0 {
+		return fmt.Errorf("spec.selector.matchLabels is required")
+	}
+
+	if !selector.Matches(template.Metadata.Labels) {
+		return fmt.Errorf("spec.selector does not match the labels of spec.template")
+	}
+
+	if len(template.Spec.Containers) == 0 {
+		return fmt.Errorf("spec.template.spec.containers is required")
+	}
+
+	return nil
+}
+
+func (replicaSet *ReplicaSet) Register(container *restful.Container, etcdService etcd.EtcdService) {
+	log.Println("rest api replicaset register")
+
+	etcdServiceAppReplicaSet = etcdService
+
+	ws := new(restful.WebService)
+
+	ws.Filter(kubeapi_logger.LoggerMiddleware)
+
+	ws.Path("/replicasets").
+		Consumes(restful.MIME_JSON, MimeYAML).
+		Produces(restful.MIME_JSON, MimeYAML)
+
+	ws.Route(ws.GET("/").To(replicaSet.getAll).
+		Param(ws.QueryParameter("watch", "boolean for watching resource").DataType("bool").DefaultValue("false")).
+		Param(ws.QueryParameter("fieldSelector", "field selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("labelSelector", "label selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("resourceVersion", "resource version to start the watch from").DataType("string").DefaultValue("")))
+
+	container.Add(ws)
+}
+
+func (replicaSet *ReplicaSet) getAll(req *restful.Request, resp *restful.Response) {
+	watchQuery := req.QueryParameter("watch")
+
+	if watchQuery == "true" {
+		watchFromWatchCache(req, resp, replicaSetEtcdKey, "")
+
+		return
+	}
+
+	listFromWatchCache(req, resp, replicaSetEtcdKey, "")
+}
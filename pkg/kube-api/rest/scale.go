@@ -0,0 +1,167 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	restful "github.com/emicklei/go-restful/v3"
+)
+
+// Scale is the scale subresource of the resources that run a number of replicas, it lets the autoscaler and
+// clients read and change the replicas without knowing the kind of the resource
+type Scale struct {
+	Metadata ResourceMetadata `json:"metadata" yaml:"metadata"`
+
+	TypeMeta `json:",inline" yaml:",inline"`
+
+	Spec ScaleSpec `json:"spec" yaml:"spec"`
+
+	Status ScaleStatus `json:"status" yaml:"status"`
+}
+
+type ScaleSpec struct {
+	Replicas int `json:"replicas" yaml:"replicas"`
+}
+
+type ScaleStatus struct {
+	// Replicas is the number of active pods of the resource
+	Replicas int `json:"replicas" yaml:"replicas"`
+	// Selector selects the pods of the resource, in the labelSelector query format
+	Selector string `json:"selector" yaml:"selector"`
+}
+
+// scalable are the resources with a scale subresource
+type scalable interface {
+	scale() Scale
+	setReplicas(replicas int)
+}
+
+func newScale(metadata ResourceMetadata, replicas *int, statusReplicas int, selector LabelSelector) Scale {
+	scale := Scale{
+		TypeMeta: TypeMeta{Kind: "Scale"},
+		Metadata: ResourceMetadata{
+			Name:              metadata.Name,
+			Namespace:         metadata.Namespace,
+			UID:               metadata.UID,
+			CreationTimestamp: metadata.CreationTimestamp,
+		},
+		Status: ScaleStatus{
+			Replicas: statusReplicas,
+			Selector: selector.String(),
+		},
+	}
+
+	if replicas != nil {
+		scale.Spec.Replicas = *replicas
+	}
+
+	return scale
+}
+
+func (deployment *Deployment) scale() Scale {
+	return newScale(deployment.Metadata, deployment.Spec.Replicas, deployment.Status.Replicas, deployment.Spec.Selector)
+}
+
+func (deployment *Deployment) setReplicas(replicas int) {
+	deployment.Spec.Replicas = &replicas
+}
+
+func (replicaSet *ReplicaSet) scale() Scale {
+	return newScale(replicaSet.Metadata, replicaSet.Spec.Replicas, replicaSet.Status.Replicas, replicaSet.Spec.Selector)
+}
+
+func (replicaSet *ReplicaSet) setReplicas(replicas int) {
+	replicaSet.Spec.Replicas = &replicas
+}
+
+func (statefulSet *StatefulSet) scale() Scale {
+	return newScale(statefulSet.Metadata, statefulSet.Spec.Replicas, statefulSet.Status.Replicas, statefulSet.Spec.Selector)
+}
+
+func (statefulSet *StatefulSet) setReplicas(replicas int) {
+	statefulSet.Spec.Replicas = &replicas
+}
+
+// getScale writes the scale of the resource of the name in the namespace
+func getScale[T any, PT interface {
+	*T
+	scalable
+}](req *restful.Request, resp *restful.Response, etcdKey string) {
+	name := req.PathParameter("name")
+	namespaceQuery := req.PathParameter("namespace")
+
+	res, err := etcdServiceAppNamespace.GetResource(fmt.Sprintf("%s/%s/%s", etcdKey, namespaceQuery, name))
+	if err != nil {
+		err = resp.WriteError(http.StatusNotFound, err)
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	resource := PT(new(T))
+	if err = json.Unmarshal(res, resource); err != nil {
+		err = resp.WriteError(http.StatusInternalServerError, err)
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	err = resp.WriteEntity(resource.scale())
+	if err != nil {
+		fmt.Printf("error while sending error: %v", err)
+	}
+}
+
+// updateScale sets the replicas of the resource of the name in the namespace to the replicas of the scale spec
+func updateScale[T any, PT interface {
+	*T
+	scalable
+}](req *restful.Request, resp *restful.Response, etcdKey string) {
+	name := req.PathParameter("name")
+	namespaceQuery := req.PathParameter("namespace")
+
+	newScale := new(Scale)
+	err := req.ReadEntity(newScale)
+	if err != nil {
+		err = resp.WriteError(http.StatusBadRequest, err)
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	if newScale.Spec.Replicas < 0 {
+		err = resp.WriteErrorString(http.StatusBadRequest, "spec.replicas must not be negative")
+		if err != nil {
+			fmt.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	err = guaranteedUpdate(
+		fmt.Sprintf("%s/%s/%s", etcdKey, namespaceQuery, name),
+		false,
+		func(stored *T) (*T, error) {
+			PT(stored).setReplicas(newScale.Spec.Replicas)
+
+			return stored, nil
+		},
+	)
+	if err != nil {
+		writeUpdateError(resp, err)
+
+		return
+	}
+
+	err = resp.WriteEntity("success")
+	if err != nil {
+		fmt.Printf("error while sending error: %v", err)
+	}
+}
@@ -0,0 +1,196 @@
+package rest
+
+import (
+	"strconv"
+)
+
+const (
+	NodeSelectorOpIn           = "In"
+	NodeSelectorOpNotIn        = "NotIn"
+	NodeSelectorOpExists       = "Exists"
+	NodeSelectorOpDoesNotExist = "DoesNotExist"
+	NodeSelectorOpGt           = "Gt"
+	NodeSelectorOpLt           = "Lt"
+
+	TaintEffectNoSchedule       = "NoSchedule"
+	TaintEffectPreferNoSchedule = "PreferNoSchedule"
+	TaintEffectNoExecute        = "NoExecute"
+
+	TolerationOpEqual  = "Equal"
+	TolerationOpExists = "Exists"
+
+	// TaintNodeUnschedulable is tolerated by the pods that may run on a node marked as unschedulable
+	TaintNodeUnschedulable = "node.kubernetes.io/unschedulable"
+)
+
+type Affinity struct {
+	NodeAffinity *NodeAffinity `json:"nodeAffinity,omitempty" yaml:"nodeAffinity,omitempty"`
+}
+
+type NodeAffinity struct {
+	// RequiredDuringSchedulingIgnoredDuringExecution must match for the pod to be placed on the node
+	RequiredDuringSchedulingIgnoredDuringExecution *NodeSelector `json:"requiredDuringSchedulingIgnoredDuringExecution,omitempty" yaml:"requiredDuringSchedulingIgnoredDuringExecution,omitempty"`
+	// PreferredDuringSchedulingIgnoredDuringExecution adds the weight of every matching term to the score of the node
+	PreferredDuringSchedulingIgnoredDuringExecution []PreferredSchedulingTerm `json:"preferredDuringSchedulingIgnoredDuringExecution" yaml:"preferredDuringSchedulingIgnoredDuringExecution"`
+}
+
+// NodeSelector matches a node when one of its terms matches
+type NodeSelector struct {
+	NodeSelectorTerms []NodeSelectorTerm `json:"nodeSelectorTerms" yaml:"nodeSelectorTerms"`
+}
+
+// NodeSelectorTerm matches a node when all of its expressions match
+type NodeSelectorTerm struct {
+	MatchExpressions []NodeSelectorRequirement `json:"matchExpressions" yaml:"matchExpressions"`
+}
+
+type NodeSelectorRequirement struct {
+	Key string `json:"key" yaml:"key"`
+	// Operator is In, NotIn, Exists, DoesNotExist, Gt or Lt
+	Operator string   `json:"operator" yaml:"operator"`
+	Values   []string `json:"values" yaml:"values"`
+}
+
+type PreferredSchedulingTerm struct {
+	// Weight is between 1 and 100
+	Weight     int              `json:"weight" yaml:"weight"`
+	Preference NodeSelectorTerm `json:"preference" yaml:"preference"`
+}
+
+type Taint struct {
+	Key   string `json:"key" yaml:"key"`
+	Value string `json:"value" yaml:"value"`
+	// Effect is NoSchedule, PreferNoSchedule or NoExecute
+	Effect string `json:"effect" yaml:"effect"`
+}
+
+type Toleration struct {
+	// Key empty with the Exists operator tolerates every taint
+	Key string `json:"key" yaml:"key"`
+	// Operator is Equal or Exists, Equal when empty
+	Operator string `json:"operator" yaml:"operator"`
+	Value    string `json:"value" yaml:"value"`
+	// Effect empty tolerates every effect
+	Effect string `json:"effect" yaml:"effect"`
+}
+
+// Matches tells if the labels of a node match one of the terms, a selector without terms matches nothing
+func (selector *NodeSelector) Matches(labels map[string]string) bool {
+	for _, term := range selector.NodeSelectorTerms {
+		if term.Matches(labels) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Matches tells if the labels of a node match all the expressions, a term without expressions matches nothing
+func (term NodeSelectorTerm) Matches(labels map[string]string) bool {
+	if len(term.MatchExpressions) == 0 {
+		return false
+	}
+
+	for _, requirement := range term.MatchExpressions {
+		if !requirement.Matches(labels) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (requirement NodeSelectorRequirement) Matches(labels map[string]string) bool {
+	value, exists := labels[requirement.Key]
+
+	switch requirement.Operator {
+	case NodeSelectorOpIn:
+		return exists && containsString(requirement.Values, value)
+	case NodeSelectorOpNotIn:
+		return !exists || !containsString(requirement.Values, value)
+	case NodeSelectorOpExists:
+		return exists
+	case NodeSelectorOpDoesNotExist:
+		return !exists
+	case NodeSelectorOpGt, NodeSelectorOpLt:
+		if !exists || len(requirement.Values) != 1 {
+			return false
+		}
+
+		labelNumber, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return false
+		}
+
+		requirementNumber, err := strconv.ParseInt(requirement.Values[0], 10, 64)
+		if err != nil {
+			return false
+		}
+
+		if requirement.Operator == NodeSelectorOpGt {
+			return labelNumber > requirementNumber
+		}
+
+		return labelNumber < requirementNumber
+	}
+
+	return false
+}
+
+// ToleratesTaint tells if the toleration matches the key, value and effect of the taint
+func (toleration Toleration) ToleratesTaint(taint Taint) bool {
+	if toleration.Effect != "" && toleration.Effect != taint.Effect {
+		return false
+	}
+
+	if toleration.Key != "" && toleration.Key != taint.Key {
+		return false
+	}
+
+	switch toleration.Operator {
+	case TolerationOpExists:
+		return true
+	case "", TolerationOpEqual:
+		return toleration.Key != "" && toleration.Value == taint.Value
+	}
+
+	return false
+}
+
+// ToleratesTaint tells if one of the tolerations of the pod matches the taint
+func (pod *Pod) ToleratesTaint(taint Taint) bool {
+	for _, toleration := range pod.Spec.Tolerations {
+		if toleration.ToleratesTaint(taint) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MatchesNodeSelectorAndAffinity tells if the node has all the labels of the node selector of the pod
+// and matches its required node affinity
+func (pod *Pod) MatchesNodeSelectorAndAffinity(node *Node) bool {
+	for key, value := range pod.Spec.NodeSelector {
+		if nodeValue, ok := node.Metadata.Labels[key]; !ok || nodeValue != value {
+			return false
+		}
+	}
+
+	if pod.Spec.Affinity == nil || pod.Spec.Affinity.NodeAffinity == nil ||
+		pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return true
+	}
+
+	return pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.Matches(node.Metadata.Labels)
+}
+
+func containsString(values []string, value string) bool {
+	for _, current := range values {
+		if current == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,108 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/conversion"
+)
+
+// the group versions of the api, the core group is served under /api/v1 and the others under /apis/<group>/<version>
+const (
+	CoreV1         = "v1"
+	AppsV1         = "apps/v1"
+	BatchV1        = "batch/v1"
+	AutoscalingV2  = "autoscaling/v2"
+	CoordinationV1 = "coordination.k8s.io/v1"
+	MetricsV1beta1 = "metrics.k8s.io/v1beta1"
+)
+
+// APIResource is a kind of object served by the api, objects are stored with the group version they are served in
+type APIResource struct {
+	Name       string `json:"name" yaml:"name"`
+	Namespaced bool   `json:"namespaced" yaml:"namespaced"`
+	Kind       string `json:"kind" yaml:"kind"`
+
+	groupVersion string
+	etcdKey      string
+}
+
+var APIResources = []APIResource{
+	{Name: "namespaces", Kind: "Namespace", groupVersion: CoreV1, etcdKey: namespaceEtcdKey},
+	{Name: "pods", Namespaced: true, Kind: "Pod", groupVersion: CoreV1, etcdKey: podEtcdKey},
+	{Name: "services", Namespaced: true, Kind: "Service", groupVersion: CoreV1, etcdKey: serviceEtcdKey},
+	{Name: "endpoints", Namespaced: true, Kind: "Endpoint", groupVersion: CoreV1, etcdKey: endpointEtcdKey},
+	{Name: "events", Namespaced: true, Kind: "Event", groupVersion: CoreV1, etcdKey: eventEtcdKey},
+	{Name: "configmaps", Namespaced: true, Kind: "ConfigMap", groupVersion: CoreV1, etcdKey: configMapEtcdKey},
+	{Name: "secrets", Namespaced: true, Kind: "Secret", groupVersion: CoreV1, etcdKey: secretEtcdKey},
+	{Name: "nodes", Kind: "Node", groupVersion: CoreV1, etcdKey: nodeEtcdKey},
+	{Name: "replicasets", Namespaced: true, Kind: "ReplicaSet", groupVersion: AppsV1, etcdKey: replicaSetEtcdKey},
+	{Name: "deployments", Namespaced: true, Kind: "Deployment", groupVersion: AppsV1, etcdKey: deploymentEtcdKey},
+	{Name: "daemonsets", Namespaced: true, Kind: "DaemonSet", groupVersion: AppsV1, etcdKey: daemonSetEtcdKey},
+	{Name: "statefulsets", Namespaced: true, Kind: "StatefulSet", groupVersion: AppsV1, etcdKey: statefulSetEtcdKey},
+	{Name: "jobs", Namespaced: true, Kind: "Job", groupVersion: BatchV1, etcdKey: jobEtcdKey},
+	{Name: "cronjobs", Namespaced: true, Kind: "CronJob", groupVersion: BatchV1, etcdKey: cronJobEtcdKey},
+	{
+		Name: "horizontalpodautoscalers", Namespaced: true, Kind: "HorizontalPodAutoscaler",
+		groupVersion: AutoscalingV2, etcdKey: horizontalPodAutoscalerEtcdKey,
+	},
+	{Name: "leases", Namespaced: true, Kind: "Lease", groupVersion: CoordinationV1, etcdKey: leaseEtcdKey},
+	{Name: "podmetrics", Namespaced: true, Kind: "PodMetrics", groupVersion: MetricsV1beta1, etcdKey: podMetricsEtcdKey},
+}
+
+// Scheme has the storage version of every stored resource and the conversions of the objects stored with older
+// versions, the objects stored before the api had versions have no apiVersion
+var Scheme = newScheme()
+
+func newScheme() *conversion.Scheme {
+	scheme := conversion.NewScheme()
+
+	for _, resource := range APIResources {
+		scheme.AddResource(resource.etcdKey, resource.groupVersion, resource.Kind)
+	}
+
+	scheme.AddConversion(endpointEtcdKey, "", CoreV1, convertUnversionedEndpoint)
+
+	return scheme
+}
+
+// convertUnversionedEndpoint converts the ports of the endpoints written by the kube-proxies before the endpoints
+// controller, they were the ports of the service with the pod port in targetPort
+func convertUnversionedEndpoint(endpoint conversion.Object) error {
+	subsets, _ := endpoint["subsets"].([]interface{})
+
+	for _, subset := range subsets {
+		subset, ok := subset.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("invalid subset %v", subset)
+		}
+
+		ports, _ := subset["ports"].([]interface{})
+		for _, port := range ports {
+			port, ok := port.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("invalid port %v", port)
+			}
+
+			targetPort, ok := port["targetPort"].(json.Number)
+			if !ok {
+				// a named or missing target port was sent to the port of the service
+				if targetPortName, isName := port["targetPort"].(string); isName {
+					if _, err := strconv.Atoi(targetPortName); err == nil {
+						targetPort = json.Number(targetPortName)
+					}
+				}
+			}
+
+			if targetPort != "" {
+				port["port"] = targetPort
+			}
+
+			delete(port, "targetPort")
+			delete(port, "nodePort")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package rest
+
+import (
+	"fmt"
+	"log"
+
+	restful "github.com/emicklei/go-restful/v3"
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/etcd"
+	kubeapi_logger "github.com/jonatan5524/own-kubernetes/pkg/kube-api/logger"
+)
+
+const (
+	secretEtcdKey = "/secrets"
+
+	SecretTypeOpaque           = "Opaque"
+	SecretTypeDockerConfigJSON = "kubernetes.io/dockerconfigjson"
+	SecretTypeTLS              = "kubernetes.io/tls"
+
+	DockerConfigJSONKey = ".dockerconfigjson"
+	TLSCertKey          = "tls.crt"
+	TLSPrivateKeyKey    = "tls.key"
+)
+
+var etcdServiceAppSecret etcd.EtcdService
+
+// Secret holds sensitive data for pods, it is encrypted in etcd when the api has an encryption provider config
+type Secret struct {
+	Metadata ResourceMetadata `json:"metadata" yaml:"metadata"`
+
+	TypeMeta `json:",inline" yaml:",inline"`
+
+	// Type is Opaque, kubernetes.io/dockerconfigjson or kubernetes.io/tls, the last two require their keys in Data
+	Type string `json:"type" yaml:"type"`
+	// Data values are base64 encoded in JSON
+	Data map[string][]byte `json:"data" yaml:"data"`
+	// StringData is a write only convenience, it is merged into Data on create and never stored
+	StringData map[string]string `json:"stringData,omitempty" yaml:"stringData,omitempty"`
+}
+
+func (secret *Secret) Register(container *restful.Container, etcdService etcd.EtcdService) {
+	log.Println("rest api secret register")
+
+	etcdServiceAppSecret = etcdService
+
+	ws := new(restful.WebService)
+
+	ws.Filter(kubeapi_logger.LoggerMiddleware)
+
+	ws.Path("/secrets").
+		Consumes(restful.MIME_JSON, MimeYAML).
+		Produces(restful.MIME_JSON, MimeYAML)
+
+	ws.Route(ws.GET("/").To(secret.getAll).
+		Param(ws.QueryParameter("watch", "boolean for watching resource").DataType("bool").DefaultValue("false")).
+		Param(ws.QueryParameter("fieldSelector", "field selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("labelSelector", "label selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("resourceVersion", "resource version to start the watch from").DataType("string").DefaultValue("")))
+
+	container.Add(ws)
+}
+
+func (secret *Secret) getAll(req *restful.Request, resp *restful.Response) {
+	watchQuery := req.QueryParameter("watch")
+
+	if watchQuery == "true" {
+		watchFromWatchCache(req, resp, secretEtcdKey, "")
+
+		return
+	}
+
+	listFromWatchCache(req, resp, secretEtcdKey, "")
+}
+
+// Value returns the value of a key from Data
+func (secret *Secret) Value(key string) ([]byte, bool) {
+	value, ok := secret.Data[key]
+
+	return value, ok
+}
+
+// Files returns all the keys of Data with their values
+func (secret *Secret) Files() map[string][]byte {
+	files := make(map[string][]byte, len(secret.Data))
+
+	for key, value := range secret.Data {
+		files[key] = value
+	}
+
+	return files
+}
+
+// normalizeSecret merges StringData into Data, defaults the type and checks the keys the type requires
+func normalizeSecret(secret *Secret) error {
+	if secret.Type == "" {
+		secret.Type = SecretTypeOpaque
+	}
+
+	if secret.Data == nil {
+		secret.Data = make(map[string][]byte)
+	}
+
+	for key, value := range secret.StringData {
+		secret.Data[key] = []byte(value)
+	}
+	secret.StringData = nil
+
+	for key := range secret.Data {
+		if !isValidConfigMapKey(key) {
+			return fmt.Errorf("invalid secret key %q, must consist of alphanumeric characters, '-', '_' or '.'", key)
+		}
+	}
+
+	var requiredKeys []string
+	switch secret.Type {
+	case SecretTypeOpaque:
+	case SecretTypeDockerConfigJSON:
+		requiredKeys = []string{DockerConfigJSONKey}
+	case SecretTypeTLS:
+		requiredKeys = []string{TLSCertKey, TLSPrivateKeyKey}
+	default:
+		return fmt.Errorf("secret type must be %s, %s or %s", SecretTypeOpaque, SecretTypeDockerConfigJSON, SecretTypeTLS)
+	}
+
+	for _, key := range requiredKeys {
+		if _, ok := secret.Data[key]; !ok {
+			return fmt.Errorf("secret of type %s must have the key %s", secret.Type, key)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,346 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	serviceClusterIPsEtcdKey = "/ranges/serviceips"
+	serviceNodePortsEtcdKey  = "/ranges/servicenodeports"
+
+	DefaultServiceClusterIPRange = "10.96.0.0/16"
+	DefaultServiceNodePortRange  = "30000-32767"
+
+	serviceAllocationsRepairInterval = 10 * time.Second
+)
+
+var (
+	serviceIPAllocator       *ipAllocator
+	serviceNodePortAllocator *portAllocator
+
+	// serviceAllocationsMutex is held for reading from the allocation of a service until the service is written, and
+	// for writing by a repair, so a repair never frees what is allocated for a service that is not stored yet
+	serviceAllocationsMutex sync.RWMutex
+)
+
+// SetupServiceAllocators sets the ranges the clusterIPs and the node ports of the services are allocated from, and
+// rebuilds their allocations from the stored services
+func SetupServiceAllocators(clusterIPRange string, nodePortRange string) error {
+	log.Printf("setup service allocators for clusterIP range %s and node port range %s", clusterIPRange, nodePortRange)
+
+	ipAllocator, err := newIPAllocator(serviceClusterIPsEtcdKey, clusterIPRange)
+	if err != nil {
+		return err
+	}
+
+	nodePortAllocator, err := newPortAllocator(serviceNodePortsEtcdKey, nodePortRange)
+	if err != nil {
+		return err
+	}
+
+	if err = repairServiceAllocations(ipAllocator, nodePortAllocator); err != nil {
+		return err
+	}
+
+	serviceIPAllocator = ipAllocator
+	serviceNodePortAllocator = nodePortAllocator
+
+	return nil
+}
+
+// repairServiceAllocations rebuilds the allocations of the ranges from the clusterIPs and the node ports of the
+// stored services
+func repairServiceAllocations(ipAllocator *ipAllocator, nodePortAllocator *portAllocator) error {
+	services, _, err := etcdServiceAppNamespace.ListResource(serviceEtcdKey)
+	if err != nil {
+		return fmt.Errorf("error listing services: %v", err)
+	}
+
+	clusterIPs := map[int]string{}
+	nodePorts := map[int]string{}
+
+	for key, serviceBytes := range services {
+		service := Service{}
+		if err = json.Unmarshal(serviceBytes, &service); err != nil {
+			log.Printf("error parsing service %s: %v", key, err)
+
+			continue
+		}
+
+		serviceName := fmt.Sprintf("%s/%s", service.Metadata.Namespace, service.Metadata.Name)
+
+		if service.Spec.ClusterIP != "" && service.Spec.ClusterIP != ClusterIPNone {
+			offset, ok := ipAllocator.offset(net.ParseIP(service.Spec.ClusterIP))
+			if !ok {
+				log.Printf("clusterIP %s of service %s is not in the service ip range %s",
+					service.Spec.ClusterIP, serviceName, ipAllocator.rangeName)
+			} else if otherService, ok := clusterIPs[offset]; ok {
+				log.Printf("clusterIP %s is used by both service %s and service %s", service.Spec.ClusterIP, otherService, serviceName)
+			} else {
+				clusterIPs[offset] = serviceName
+			}
+		}
+
+		for _, port := range service.Spec.Ports {
+			if port.NodePort == 0 {
+				continue
+			}
+
+			offset, ok := nodePortAllocator.offset(port.NodePort)
+			if !ok {
+				log.Printf("node port %d of service %s is not in the node port range %s",
+					port.NodePort, serviceName, nodePortAllocator.rangeName)
+			} else if otherService, ok := nodePorts[offset]; ok {
+				log.Printf("node port %d is used by both service %s and service %s", port.NodePort, otherService, serviceName)
+			} else {
+				nodePorts[offset] = serviceName
+			}
+		}
+	}
+
+	if err = ipAllocator.repair(mapKeys(clusterIPs)); err != nil {
+		return err
+	}
+
+	return nodePortAllocator.repair(mapKeys(nodePorts))
+}
+
+// repairServiceAllocationsInBackground repairs the allocations until the repair succeeds, it is started when a
+// release failed and the released clusterIP or node ports would otherwise stay allocated
+func repairServiceAllocationsInBackground() {
+	go func() {
+		for {
+			serviceAllocationsMutex.Lock()
+			err := repairServiceAllocations(serviceIPAllocator, serviceNodePortAllocator)
+			serviceAllocationsMutex.Unlock()
+
+			if err == nil {
+				return
+			}
+
+			log.Printf("error repairing service allocations, retrying in %v: %v", serviceAllocationsRepairInterval, err)
+			time.Sleep(serviceAllocationsRepairInterval)
+		}
+	}()
+}
+
+func mapKeys(values map[int]string) []int {
+	keys := make([]int, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// serviceAllocations are the clusterIP and the node ports allocated from the ranges for a service
+type serviceAllocations struct {
+	clusterIP string
+	nodePorts []int
+}
+
+// allocationsOf returns the clusterIP and the node ports used by the service
+func allocationsOf(service *Service) serviceAllocations {
+	allocations := serviceAllocations{clusterIP: service.Spec.ClusterIP}
+
+	for _, port := range service.Spec.Ports {
+		if port.NodePort != 0 {
+			allocations.nodePorts = append(allocations.nodePorts, port.NodePort)
+		}
+	}
+
+	return allocations
+}
+
+// releasedAllocations returns the node ports of the stored service the updated service does not use anymore, the
+// clusterIP is immutable and is only released with the service
+func releasedAllocations(storedService *Service, service *Service) serviceAllocations {
+	released := serviceAllocations{}
+	if storedService == nil {
+		return released
+	}
+
+	used := map[int]bool{}
+	for _, nodePort := range allocationsOf(service).nodePorts {
+		used[nodePort] = true
+	}
+
+	for _, nodePort := range allocationsOf(storedService).nodePorts {
+		if !used[nodePort] {
+			released.nodePorts = append(released.nodePorts, nodePort)
+		}
+	}
+
+	return released
+}
+
+// release releases the clusterIP and the node ports, when a release fails the allocations are repaired in the
+// background from the stored services
+func (allocations *serviceAllocations) release() {
+	failed := false
+
+	if serviceIPAllocator != nil && allocations.clusterIP != "" && allocations.clusterIP != ClusterIPNone {
+		if err := serviceIPAllocator.release(allocations.clusterIP); err != nil {
+			log.Printf("error releasing clusterIP %s: %v", allocations.clusterIP, err)
+			failed = true
+		}
+	}
+
+	for _, nodePort := range allocations.nodePorts {
+		if serviceNodePortAllocator == nil {
+			break
+		}
+
+		if err := serviceNodePortAllocator.release(nodePort); err != nil {
+			log.Printf("error releasing node port %d: %v", nodePort, err)
+			failed = true
+		}
+	}
+
+	if failed {
+		repairServiceAllocationsInBackground()
+	}
+}
+
+// allocate allocates the clusterIP and the node ports of the service written over the stored service, nil when the
+// service is new, and sets them in the service
+func (allocations *serviceAllocations) allocate(storedService *Service, service *Service) error {
+	if serviceIPAllocator == nil || serviceNodePortAllocator == nil {
+		return fmt.Errorf("service allocators are not set up")
+	}
+
+	if err := allocations.allocateClusterIP(storedService, service); err != nil {
+		return err
+	}
+
+	return allocations.allocateNodePorts(storedService, service)
+}
+
+// allocateClusterIP allocates the requested clusterIP, or the next free ip when none is requested. The clusterIP of
+// a stored service is immutable, and a headless service keeps None with nothing allocated for it
+func (allocations *serviceAllocations) allocateClusterIP(storedService *Service, service *Service) error {
+	if storedService != nil && storedService.Spec.ClusterIP != "" {
+		if service.Spec.ClusterIP == "" {
+			service.Spec.ClusterIP = storedService.Spec.ClusterIP
+		}
+
+		if service.Spec.ClusterIP != storedService.Spec.ClusterIP {
+			return &updateError{
+				statusCode: http.StatusUnprocessableEntity,
+				message: fmt.Sprintf("service %s/%s spec.clusterIP is immutable",
+					storedService.Metadata.Namespace, storedService.Metadata.Name),
+			}
+		}
+
+		return nil
+	}
+
+	switch service.Spec.ClusterIP {
+	case ClusterIPNone:
+		return nil
+	case "":
+		clusterIP, err := serviceIPAllocator.allocateNext()
+		if err != nil {
+			return err
+		}
+
+		service.Spec.ClusterIP = clusterIP
+	default:
+		if err := serviceIPAllocator.allocate(service.Spec.ClusterIP); err != nil {
+			return err
+		}
+	}
+
+	allocations.clusterIP = service.Spec.ClusterIP
+
+	return nil
+}
+
+// storedPortKey identifies a port of a service across updates, by its name or by its port and protocol when it is
+// unnamed, names are not required so a service may have several unnamed ports
+type storedPortKey struct {
+	name     string
+	port     int
+	protocol string
+}
+
+func storedPortKeyOf(port ServicePorts) storedPortKey {
+	if port.Name != "" {
+		return storedPortKey{name: port.Name}
+	}
+
+	return storedPortKey{port: port.Port, protocol: port.Protocol}
+}
+
+// allocateNodePorts allocates the node ports of a NodePort service, a port without a node port keeps the node port
+// of the stored port with the same name, or the same port and protocol when unnamed, or gets the next free port.
+// Other types of services have no node ports
+func (allocations *serviceAllocations) allocateNodePorts(storedService *Service, service *Service) error {
+	storedNodePorts := map[int]bool{}
+	storedNodePortsByKey := map[storedPortKey]int{}
+
+	if storedService != nil {
+		for _, port := range storedService.Spec.Ports {
+			if port.NodePort != 0 {
+				storedNodePorts[port.NodePort] = true
+				storedNodePortsByKey[storedPortKeyOf(port)] = port.NodePort
+			}
+		}
+	}
+
+	used := map[int]bool{}
+
+	for index := range service.Spec.Ports {
+		port := &service.Spec.Ports[index]
+		field := fmt.Sprintf("spec.ports[%d].nodePort", index)
+
+		if service.Spec.Type != ServiceTypeNodePort {
+			if port.NodePort != 0 {
+				return &updateError{
+					statusCode: http.StatusUnprocessableEntity,
+					message:    fmt.Sprintf("%s: may only be set for services of type %s", field, ServiceTypeNodePort),
+				}
+			}
+
+			continue
+		}
+
+		if port.NodePort == 0 {
+			port.NodePort = storedNodePortsByKey[storedPortKeyOf(*port)]
+		}
+
+		if used[port.NodePort] {
+			return &updateError{
+				statusCode: http.StatusUnprocessableEntity,
+				message:    fmt.Sprintf("%s: duplicate value %d", field, port.NodePort),
+			}
+		}
+
+		switch {
+		case port.NodePort == 0:
+			nodePort, err := serviceNodePortAllocator.allocateNext()
+			if err != nil {
+				return err
+			}
+
+			port.NodePort = nodePort
+			allocations.nodePorts = append(allocations.nodePorts, nodePort)
+		case !storedNodePorts[port.NodePort]:
+			if err := serviceNodePortAllocator.allocate(port.NodePort, field); err != nil {
+				return err
+			}
+
+			allocations.nodePorts = append(allocations.nodePorts, port.NodePort)
+		}
+
+		used[port.NodePort] = true
+	}
+
+	return nil
+}
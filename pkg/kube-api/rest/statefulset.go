@@ -0,0 +1,174 @@
+package rest
+
+import (
+	"fmt"
+	"log"
+
+	restful "github.com/emicklei/go-restful/v3"
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/etcd"
+	kubeapi_logger "github.com/jonatan5524/own-kubernetes/pkg/kube-api/logger"
+)
+
+const (
+	statefulSetEtcdKey = "/statefulsets"
+
+	// OrderedReadyPodManagement creates the pods one by one from the first ordinal, each after the previous one is
+	// running and ready, and deletes them from the last ordinal. ParallelPodManagement creates and deletes them all
+	// at once
+	OrderedReadyPodManagement = "OrderedReady"
+	ParallelPodManagement     = "Parallel"
+
+	RollingUpdateStatefulSetStrategyType = "RollingUpdate"
+	OnDeleteStatefulSetStrategyType      = "OnDelete"
+
+	// StatefulSetPodNameLabelKey is set on every pod of a statefulset to its name, so a service can select a
+	// single pod
+	StatefulSetPodNameLabelKey = "statefulset.kubernetes.io/pod-name"
+)
+
+var etcdServiceAppStatefulSet etcd.EtcdService
+
+// StatefulSet runs replicas pods of its template with stable identities, the pod of ordinal i is named
+// <name>-i and has the same name and hostname whenever it is recreated
+type StatefulSet struct {
+	Metadata ResourceMetadata `json:"metadata" yaml:"metadata"`
+
+	TypeMeta `json:",inline" yaml:",inline"`
+
+	Spec StatefulSetSpec `json:"spec" yaml:"spec"`
+
+	Status StatefulSetStatus `json:"status" yaml:"status"`
+}
+
+type StatefulSetSpec struct {
+	// Replicas is the number of pods to run, 1 when not set
+	Replicas *int `json:"replicas,omitempty" yaml:"replicas,omitempty"`
+	// Selector must match the labels of the template
+	Selector LabelSelector   `json:"selector" yaml:"selector"`
+	Template PodTemplateSpec `json:"template" yaml:"template"`
+	// ServiceName is the service governing the pods, it is the subdomain of their hostname so the pod of
+	// ordinal i is named <name>-i.<serviceName>.<namespace>.svc.cluster.local
+	ServiceName string `json:"serviceName" yaml:"serviceName"`
+	// PodManagementPolicy is OrderedReady or Parallel, OrderedReady when not set
+	PodManagementPolicy string                    `json:"podManagementPolicy" yaml:"podManagementPolicy"`
+	UpdateStrategy      StatefulSetUpdateStrategy `json:"updateStrategy" yaml:"updateStrategy"`
+}
+
+type StatefulSetUpdateStrategy struct {
+	// Type is RollingUpdate, replacing the pods of an older template one by one from the last ordinal, or OnDelete,
+	// replacing them only after they are deleted
+	Type          string                            `json:"type" yaml:"type"`
+	RollingUpdate *RollingUpdateStatefulSetStrategy `json:"rollingUpdate,omitempty" yaml:"rollingUpdate,omitempty"`
+}
+
+type RollingUpdateStatefulSetStrategy struct {
+	// Partition is the first ordinal that is updated, the pods of lower ordinals keep their template.
+	// 0 when not set
+	Partition int `json:"partition" yaml:"partition"`
+}
+
+type StatefulSetStatus struct {
+	// Replicas is the number of active pods of the statefulset
+	Replicas      int `json:"replicas" yaml:"replicas"`
+	ReadyReplicas int `json:"readyReplicas" yaml:"readyReplicas"`
+	// CurrentReplicas are the pods of the current revision and UpdatedReplicas the pods of the update revision
+	CurrentReplicas int `json:"currentReplicas" yaml:"currentReplicas"`
+	UpdatedReplicas int `json:"updatedReplicas" yaml:"updatedReplicas"`
+	// CurrentRevision is the hash of the template all the pods had before the update, it becomes the
+	// UpdateRevision once every pod is updated
+	CurrentRevision string `json:"currentRevision" yaml:"currentRevision"`
+	UpdateRevision  string `json:"updateRevision" yaml:"updateRevision"`
+}
+
+func (statefulSet *StatefulSet) setDefaults() {
+	if statefulSet.Spec.Replicas == nil {
+		replicas := 1
+		statefulSet.Spec.Replicas = &replicas
+	}
+
+	if statefulSet.Spec.PodManagementPolicy == "" {
+		statefulSet.Spec.PodManagementPolicy = OrderedReadyPodManagement
+	}
+
+	if statefulSet.Spec.UpdateStrategy.Type == "" {
+		statefulSet.Spec.UpdateStrategy.Type = RollingUpdateStatefulSetStrategyType
+	}
+
+	if statefulSet.Spec.UpdateStrategy.Type != RollingUpdateStatefulSetStrategyType {
+		statefulSet.Spec.UpdateStrategy.RollingUpdate = nil
+
+		return
+	}
+
+	if statefulSet.Spec.UpdateStrategy.RollingUpdate == nil {
+		statefulSet.Spec.UpdateStrategy.RollingUpdate = &RollingUpdateStatefulSetStrategy{}
+	}
+}
+
+func (statefulSet *StatefulSet) validate() error {
+	if statefulSet.Metadata.Name == "" {
+		return fmt.Errorf("statefulset name is required")
+	}
+
+	if *statefulSet.Spec.Replicas < 0 {
+		return fmt.Errorf("spec.replicas must not be negative")
+	}
+
+	if statefulSet.Spec.PodManagementPolicy != OrderedReadyPodManagement &&
+		statefulSet.Spec.PodManagementPolicy != ParallelPodManagement {
+		return fmt.Errorf("spec.podManagementPolicy must be %s or %s", OrderedReadyPodManagement, ParallelPodManagement)
+	}
+
+	if statefulSet.Spec.UpdateStrategy.Type != RollingUpdateStatefulSetStrategyType &&
+		statefulSet.Spec.UpdateStrategy.Type != OnDeleteStatefulSetStrategyType {
+		return fmt.Errorf("spec.updateStrategy.type must be %s or %s", RollingUpdateStatefulSetStrategyType, OnDeleteStatefulSetStrategyType)
+	}
+
+	if statefulSet.Spec.UpdateStrategy.RollingUpdate != nil && statefulSet.Spec.UpdateStrategy.RollingUpdate.Partition < 0 {
+		return fmt.Errorf("spec.updateStrategy.rollingUpdate.partition must not be negative")
+	}
+
+	if statefulSet.Spec.Template.Spec.Hostname != "" || statefulSet.Spec.Template.Spec.Subdomain != "" {
+		return fmt.Errorf("spec.template.spec.hostname and subdomain must not be set, they are set by the controller")
+	}
+
+	if _, ok := statefulSet.Spec.Template.Metadata.Labels[ControllerRevisionHashLabelKey]; ok {
+		return fmt.Errorf("spec.template.metadata.labels must not contain %s, it is set by the controller", ControllerRevisionHashLabelKey)
+	}
+
+	return validateTemplateSelector(statefulSet.Spec.Selector, statefulSet.Spec.Template)
+}
+
+func (statefulSet *StatefulSet) Register(container *restful.Container, etcdService etcd.EtcdService) {
+	log.Println("rest api statefulset register")
+
+	etcdServiceAppStatefulSet = etcdService
+
+	ws := new(restful.WebService)
+
+	ws.Filter(kubeapi_logger.LoggerMiddleware)
+
+	ws.Path("/statefulsets").
+		Consumes(restful.MIME_JSON, MimeYAML).
+		Produces(restful.MIME_JSON, MimeYAML)
+
+	ws.Route(ws.GET("/").To(statefulSet.getAll).
+		Param(ws.QueryParameter("watch", "boolean for watching resource").DataType("bool").DefaultValue("false")).
+		Param(ws.QueryParameter("fieldSelector", "field selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("labelSelector", "label selector for resource").DataType("string").DefaultValue("")).
+		Param(ws.QueryParameter("resourceVersion", "resource version to start the watch from").DataType("string").DefaultValue("")))
+
+	container.Add(ws)
+}
+
+func (statefulSet *StatefulSet) getAll(req *restful.Request, resp *restful.Response) {
+	watchQuery := req.QueryParameter("watch")
+
+	if watchQuery == "true" {
+		watchFromWatchCache(req, resp, statefulSetEtcdKey, "")
+
+		return
+	}
+
+	listFromWatchCache(req, resp, statefulSetEtcdKey, "")
+}
@@ -1,5 +1,42 @@
 package rest
 
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// StoredResourcesEtcdKeys are the etcd prefixes of all the resources kinds kept by the api
+var StoredResourcesEtcdKeys = []string{
+	namespaceEtcdKey,
+	podEtcdKey,
+	serviceEtcdKey,
+	endpointEtcdKey,
+	eventEtcdKey,
+	configMapEtcdKey,
+	secretEtcdKey,
+	nodeEtcdKey,
+	leaseEtcdKey,
+	replicaSetEtcdKey,
+	deploymentEtcdKey,
+	daemonSetEtcdKey,
+	jobEtcdKey,
+	cronJobEtcdKey,
+	statefulSetEtcdKey,
+	podMetricsEtcdKey,
+	horizontalPodAutoscalerEtcdKey,
+}
+
+// TypeMeta is the kind of an object and the version of the api its fields are in, objects are stored with the
+// storage version of their resource and objects stored before versioning have no apiVersion
+type TypeMeta struct {
+	APIVersion string `json:"apiVersion,omitempty" yaml:"apiVersion,omitempty"`
+	Kind       string `json:"kind" yaml:"kind"`
+}
+
 type ResourceMetadata struct {
 	Annotations       map[string]string `json:"annotations" yaml:"annotations"`
 	Labels            map[string]string `json:"labels" yaml:"labels"`
@@ -7,6 +44,128 @@ type ResourceMetadata struct {
 	Namespace         string            `json:"namespace" yaml:"namespace"`
 	CreationTimestamp string            `json:"creationTimestamp" yaml:"creationTimestamp"`
 	UID               string            `json:"uid" yaml:"uid"`
+	// ResourceVersion is the etcd revision the object was read at, an update of a lease with a resource version
+	// only succeeds if the lease was not changed since
+	ResourceVersion string `json:"resourceVersion,omitempty" yaml:"resourceVersion,omitempty"`
+	// OwnerReferences are the objects this object belongs to, the one marked as controller manages it
+	OwnerReferences []OwnerReference `json:"ownerReferences,omitempty" yaml:"ownerReferences,omitempty"`
+}
+
+type OwnerReference struct {
+	Kind string `json:"kind" yaml:"kind"`
+	Name string `json:"name" yaml:"name"`
+	UID  string `json:"uid" yaml:"uid"`
+	// Controller is set on the single owner that manages the object, for example the replicaset of a pod
+	Controller bool `json:"controller" yaml:"controller"`
+}
+
+// ControllerRef returns the owner that manages the object, nil when it has none
+func (metadata ResourceMetadata) ControllerRef() *OwnerReference {
+	for index := range metadata.OwnerReferences {
+		if metadata.OwnerReferences[index].Controller {
+			return &metadata.OwnerReferences[index]
+		}
+	}
+
+	return nil
+}
+
+// LabelSelector matches the objects that have all the labels of MatchLabels, an empty selector matches nothing
+type LabelSelector struct {
+	MatchLabels map[string]string `json:"matchLabels" yaml:"matchLabels"`
+}
+
+func (selector LabelSelector) Matches(labels map[string]string) bool {
+	if len(selector.MatchLabels) == 0 {
+		return false
+	}
+
+	for key, value := range selector.MatchLabels {
+		if labelValue, ok := labels[key]; !ok || labelValue != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// String returns the selector in the labelSelector query format, for example "app=web,tier=db"
+func (selector LabelSelector) String() string {
+	requirements := make([]string, 0, len(selector.MatchLabels))
+	for key, value := range selector.MatchLabels {
+		requirements = append(requirements, fmt.Sprintf("%s=%s", key, value))
+	}
+
+	sort.Strings(requirements)
+
+	return strings.Join(requirements, ",")
+}
+
+// ParseLabelSelector parses a selector of the String format
+func ParseLabelSelector(selector string) (LabelSelector, error) {
+	parsed := LabelSelector{MatchLabels: make(map[string]string)}
+
+	for _, requirement := range strings.Split(selector, ",") {
+		if requirement == "" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(requirement, "=")
+		if !ok || key == "" {
+			return LabelSelector{}, fmt.Errorf("invalid label selector requirement %q", requirement)
+		}
+
+		parsed.MatchLabels[key] = value
+	}
+
+	return parsed, nil
+}
+
+// IntOrString is an absolute number, for example 1, or a percentage, for example "25%"
+type IntOrString string
+
+// UnmarshalJSON accepts both a JSON number and a JSON string
+func (value *IntOrString) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err == nil {
+		*value = IntOrString(str)
+
+		return nil
+	}
+
+	var number int
+	if err := json.Unmarshal(data, &number); err != nil {
+		return fmt.Errorf("value must be a number or a percentage: %v", err)
+	}
+
+	*value = IntOrString(strconv.Itoa(number))
+
+	return nil
+}
+
+// ScaledValue returns the number, or the percentage of total rounded up or down
+func (value IntOrString) ScaledValue(total int, roundUp bool) (int, error) {
+	str := string(value)
+
+	if !strings.HasSuffix(str, "%") {
+		number, err := strconv.Atoi(str)
+		if err != nil {
+			return 0, fmt.Errorf("invalid value %q: %v", str, err)
+		}
+
+		return number, nil
+	}
+
+	percent, err := strconv.Atoi(strings.TrimSuffix(str, "%"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid percentage %q: %v", str, err)
+	}
+
+	if roundUp {
+		return int(math.Ceil(float64(percent) * float64(total) / 100)), nil
+	}
+
+	return int(math.Floor(float64(percent) * float64(total) / 100)), nil
 }
 
 type TargetRef struct {
@@ -15,3 +174,17 @@ type TargetRef struct {
 	Namespace string `json:"namespace" yaml:"namespace"`
 	UID       string `json:"uid" yaml:"uid"`
 }
+
+const (
+	StatusSuccess = "Success"
+	StatusFailure = "Failure"
+)
+
+type Status struct {
+	TypeMeta `json:",inline" yaml:",inline"`
+
+	Status  string `json:"status" yaml:"status"`
+	Message string `json:"message" yaml:"message"`
+	Reason  string `json:"reason" yaml:"reason"`
+	Code    int    `json:"code" yaml:"code"`
+}
@@ -0,0 +1,304 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+
+	restful "github.com/emicklei/go-restful/v3"
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/cache"
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/etcd"
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/metrics"
+)
+
+const (
+	WatchErrorEventType = "ERROR"
+	// ResourceVersionHeader holds the revision a list was served at, a watch with this resourceVersion
+	// continues right after the list without missing events
+	ResourceVersionHeader = "X-Resource-Version"
+)
+
+var (
+	watchCachesMutex sync.RWMutex
+	watchCaches      = make(map[string]*cache.WatchCache)
+)
+
+// StartWatchCaches starts a watch cache for every stored resource, lists and watches of the api are served from them
+func StartWatchCaches(etcdService etcd.EtcdService, size int) {
+	watchCachesMutex.Lock()
+	defer watchCachesMutex.Unlock()
+
+	for _, etcdKey := range StoredResourcesEtcdKeys {
+		if _, ok := watchCaches[etcdKey]; ok {
+			continue
+		}
+
+		watchCache := cache.NewWatchCache(etcdService, etcdKey, size)
+		watchCache.Start()
+
+		watchCaches[etcdKey] = watchCache
+	}
+}
+
+func StopWatchCaches() {
+	watchCachesMutex.Lock()
+	defer watchCachesMutex.Unlock()
+
+	for etcdKey, watchCache := range watchCaches {
+		watchCache.Stop()
+
+		delete(watchCaches, etcdKey)
+	}
+}
+
+// CheckWatchCachesReady returns an error if one of the watch caches did not finish its initial list
+func CheckWatchCachesReady() error {
+	watchCachesMutex.RLock()
+	defer watchCachesMutex.RUnlock()
+
+	for etcdKey, watchCache := range watchCaches {
+		if !watchCache.Ready() {
+			return fmt.Errorf("watch cache for %s is not synced", etcdKey)
+		}
+	}
+
+	return nil
+}
+
+func getWatchCache(etcdKey string) (*cache.WatchCache, error) {
+	watchCachesMutex.RLock()
+	defer watchCachesMutex.RUnlock()
+
+	watchCache, ok := watchCaches[etcdKey]
+	if !ok {
+		return nil, fmt.Errorf("no watch cache for %s", etcdKey)
+	}
+
+	return watchCache, nil
+}
+
+func predicateFromRequest(req *restful.Request, namespace string) (cache.Predicate, error) {
+	labelSelector, err := cache.ParseLabelSelector(req.QueryParameter("labelSelector"))
+	if err != nil {
+		return cache.Predicate{}, err
+	}
+
+	fieldSelector, err := cache.ParseFieldSelector(req.QueryParameter("fieldSelector"))
+	if err != nil {
+		return cache.Predicate{}, err
+	}
+
+	return cache.Predicate{
+		Namespace: namespace,
+		Label:     labelSelector,
+		Field:     fieldSelector,
+	}, nil
+}
+
+// listFromWatchCache writes all the resources of etcdKey in the namespace (all namespaces when empty)
+// that match the selectors of the request
+func listFromWatchCache(req *restful.Request, resp *restful.Response, etcdKey string, namespace string) {
+	predicate, err := predicateFromRequest(req, namespace)
+	if err != nil {
+		err = resp.WriteError(http.StatusBadRequest, err)
+		if err != nil {
+			log.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	watchCache, err := getWatchCache(etcdKey)
+	if err != nil {
+		err = resp.WriteError(http.StatusInternalServerError, err)
+		if err != nil {
+			log.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	values, revision, err := watchCache.List(predicate)
+	if err != nil {
+		err = resp.WriteError(http.StatusServiceUnavailable, err)
+		if err != nil {
+			log.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	resources := make([]interface{}, len(values))
+	for index, value := range values {
+		if err = json.Unmarshal(value, &resources[index]); err != nil {
+			err = resp.WriteError(http.StatusInternalServerError, err)
+			if err != nil {
+				log.Printf("error while sending error: %v", err)
+			}
+
+			return
+		}
+	}
+
+	resp.Header().Set(ResourceVersionHeader, strconv.FormatInt(revision, 10))
+
+	err = resp.WriteEntity(resources)
+	if err != nil {
+		log.Printf("error while sending list: %v", err)
+	}
+}
+
+// watchFromWatchCache streams the events of etcdKey in the namespace (all namespaces when empty)
+// that match the selectors of the request, until the client disconnects or the server shuts down
+func watchFromWatchCache(req *restful.Request, resp *restful.Response, etcdKey string, namespace string) {
+	predicate, err := predicateFromRequest(req, namespace)
+	if err != nil {
+		err = resp.WriteError(http.StatusBadRequest, err)
+		if err != nil {
+			log.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	var resourceVersion int64
+	if resourceVersionQuery := req.QueryParameter("resourceVersion"); resourceVersionQuery != "" {
+		resourceVersion, err = strconv.ParseInt(resourceVersionQuery, 10, 64)
+		if err != nil {
+			err = resp.WriteErrorString(http.StatusBadRequest, fmt.Sprintf("invalid resourceVersion: %v", err))
+			if err != nil {
+				log.Printf("error while sending error: %v", err)
+			}
+
+			return
+		}
+	}
+
+	watchCache, err := getWatchCache(etcdKey)
+	if err != nil {
+		err = resp.WriteError(http.StatusInternalServerError, err)
+		if err != nil {
+			log.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+
+	resp.Header().Set("Access-Control-Allow-Origin", "*")
+	resp.Header().Set("Content-Type", "text/event-stream")
+	resp.Header().Set("Cache-Control", "no-cache")
+	resp.Header().Set("Connection", "keep-alive")
+
+	watcher, err := watchCache.Watch(predicate, resourceVersion)
+	if err != nil {
+		if errors.Is(err, cache.ErrResourceVersionTooOld) {
+			// same as kubernetes, the client gets a 410 in the stream and has to list again
+			writeWatchErrorEvent(resp, Status{
+				TypeMeta: TypeMeta{Kind: "Status"},
+				Status:   StatusFailure,
+				Message:  err.Error(),
+				Reason:   "Expired",
+				Code:     http.StatusGone,
+			})
+
+			return
+		}
+
+		err = resp.WriteError(http.StatusServiceUnavailable, err)
+		if err != nil {
+			log.Printf("error while sending error: %v", err)
+		}
+
+		return
+	}
+	defer watcher.Stop()
+
+	metrics.WatchStarted(metrics.ResourceFromEtcdKey(etcdKey))
+	defer metrics.WatchStopped(metrics.ResourceFromEtcdKey(etcdKey))
+
+	log.Printf("Client watcher on %s started", etcdKey)
+
+	// send the headers now, a watch with no events would otherwise leave the client waiting for them
+	resp.WriteHeader(http.StatusOK)
+	resp.Flush()
+
+	for {
+		select {
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				writeWatchErrorEvent(resp, Status{
+					TypeMeta: TypeMeta{Kind: "Status"},
+					Status:   StatusFailure,
+					Message:  "watch was terminated by the server, start a new one",
+					Reason:   "Expired",
+					Code:     http.StatusGone,
+				})
+
+				return
+			}
+
+			log.Printf("watch: %s executed on %s", event.Type, event.Key)
+
+			fmt.Fprintf(resp, "Type: %s Value: %s\n", event.Type, string(event.Value))
+			resp.Flush()
+
+		case <-watchStopChannel(req.Request.Context()):
+			writeWatchShutdownEvent(resp)
+
+			return
+
+		case <-req.Request.Context().Done():
+			log.Println("Connection closed")
+
+			return
+		}
+	}
+}
+
+type watchStopKey struct{}
+
+// WithWatchStop returns a context carrying a channel that is closed when the server is shutting down,
+// the server sets it as the base context of all its requests so open watch streams can be ended cleanly
+func WithWatchStop(ctx context.Context, stop <-chan struct{}) context.Context {
+	return context.WithValue(ctx, watchStopKey{}, stop)
+}
+
+// watchStopChannel returns nil when the request is not served by a stoppable server, receiving from it blocks forever
+func watchStopChannel(ctx context.Context) <-chan struct{} {
+	stop, ok := ctx.Value(watchStopKey{}).(<-chan struct{})
+	if !ok {
+		return nil
+	}
+
+	return stop
+}
+
+// writeWatchShutdownEvent sends the last event of a watch stream, so the client knows the stream ended on purpose
+func writeWatchShutdownEvent(resp *restful.Response) {
+	log.Println("server is shutting down, closing watch stream")
+
+	writeWatchErrorEvent(resp, Status{
+		TypeMeta: TypeMeta{Kind: "Status"},
+		Status:   StatusFailure,
+		Message:  "server is shutting down",
+		Reason:   "ServiceUnavailable",
+		Code:     http.StatusServiceUnavailable,
+	})
+}
+
+func writeWatchErrorEvent(resp *restful.Response, status Status) {
+	statusBytes, err := json.Marshal(status)
+	if err != nil {
+		log.Printf("error marshaling watch error event: %v", err)
+
+		return
+	}
+
+	fmt.Fprintf(resp, "Type: %s Value: %s\n", WatchErrorEventType, string(statusBytes))
+	resp.Flush()
+}
@@ -0,0 +1,65 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	restful "github.com/emicklei/go-restful/v3"
+	"github.com/jonatan5524/own-kubernetes/pkg/utils"
+)
+
+// MimeYAML is the content type of yaml manifests, requests can be sent and responses read as yaml with it
+const MimeYAML = "application/yaml"
+
+// entityAccessorYAML reads and writes the entities as yaml through their json, so they have the same fields as in
+// json and in etcd
+type entityAccessorYAML struct{}
+
+// RegisterEntityAccessorYAML registers the reader and writer of MimeYAML for the routes that consume or produce it
+func RegisterEntityAccessorYAML() {
+	restful.RegisterEntityAccessor(MimeYAML, entityAccessorYAML{})
+}
+
+func (accessor entityAccessorYAML) Read(req *restful.Request, v interface{}) error {
+	manifest, err := io.ReadAll(req.Request.Body)
+	if err != nil {
+		return err
+	}
+
+	value, err := utils.YAMLToJSON(manifest)
+	if err != nil {
+		return fmt.Errorf("invalid YAML, %v", err)
+	}
+
+	if string(value) == "null" {
+		return fmt.Errorf("empty YAML body")
+	}
+
+	return json.Unmarshal(value, v)
+}
+
+func (accessor entityAccessorYAML) Write(resp *restful.Response, status int, v interface{}) error {
+	if v == nil {
+		resp.WriteHeader(status)
+
+		return nil
+	}
+
+	value, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	manifest, err := utils.JSONToYAML(value)
+	if err != nil {
+		return err
+	}
+
+	resp.Header().Set(restful.HEADER_ContentType, MimeYAML)
+	resp.WriteHeader(status)
+
+	_, err = resp.Write(manifest)
+
+	return err
+}
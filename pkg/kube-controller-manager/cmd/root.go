@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jonatan5524/own-kubernetes/pkg/controller"
+	"github.com/jonatan5524/own-kubernetes/pkg/controller/cronjob"
+	"github.com/jonatan5524/own-kubernetes/pkg/controller/daemon"
+	"github.com/jonatan5524/own-kubernetes/pkg/controller/deployment"
+	"github.com/jonatan5524/own-kubernetes/pkg/controller/endpoint"
+	"github.com/jonatan5524/own-kubernetes/pkg/controller/job"
+	"github.com/jonatan5524/own-kubernetes/pkg/controller/nodelifecycle"
+	"github.com/jonatan5524/own-kubernetes/pkg/controller/podautoscaler"
+	"github.com/jonatan5524/own-kubernetes/pkg/controller/replicaset"
+	"github.com/jonatan5524/own-kubernetes/pkg/controller/statefulset"
+	kubecontrollermanager "github.com/jonatan5524/own-kubernetes/pkg/kube-controller-manager"
+	"github.com/jonatan5524/own-kubernetes/pkg/leaderelection"
+	"github.com/spf13/cobra"
+)
+
+var (
+	kubeAPIEndpoint string
+
+	controllers           []string
+	resyncPeriod          time.Duration
+	leaderElectionOptions leaderelection.Options
+	nodeLifecycleOptions  nodelifecycle.Options
+	replicaSetOptions     replicaset.Options
+	deploymentOptions     deployment.Options
+	daemonSetOptions      daemon.Options
+	jobOptions            job.Options
+	cronJobOptions        cronjob.Options
+	statefulSetOptions    statefulset.Options
+	hpaOptions            podautoscaler.Options
+	endpointOptions       endpoint.Options
+)
+
+var rootCmd = &cobra.Command{
+	Use:   "kube-controller-manager",
+	Short: "CLI util for running the kubernetes cluster controllers",
+	RunE: func(_ *cobra.Command, _ []string) error {
+		app := kubecontrollermanager.NewKubeControllerManager(kubeAPIEndpoint, kubecontrollermanager.Options{
+			Controllers:             controllers,
+			ResyncPeriod:            resyncPeriod,
+			LeaderElection:          leaderElectionOptions,
+			NodeLifecycle:           nodeLifecycleOptions,
+			ReplicaSet:              replicaSetOptions,
+			Deployment:              deploymentOptions,
+			DaemonSet:               daemonSetOptions,
+			Job:                     jobOptions,
+			CronJob:                 cronJobOptions,
+			StatefulSet:             statefulSetOptions,
+			HorizontalPodAutoscaler: hpaOptions,
+			Endpoint:                endpointOptions,
+		})
+		defer app.Stop()
+
+		if err := app.Setup(); err != nil {
+			return err
+		}
+
+		if err := app.Run(); err != nil {
+			return err
+		}
+
+		return nil
+	},
+}
+
+func Execute() {
+	err := rootCmd.Execute()
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+func init() {
+	rootCmd.Flags().StringVar(&kubeAPIEndpoint, "kubernetes-api-endpoint", "", "kubernetes api endpoint")
+	rootCmd.Flags().StringSliceVar(&controllers, "controllers", []string{"*"},
+		fmt.Sprintf("controllers to run, '*' runs all of them and '-foo' disables the controller foo, controllers: %v",
+			kubecontrollermanager.KnownControllers()))
+	rootCmd.Flags().DurationVar(&resyncPeriod, "resync-period", controller.DefaultResyncPeriod,
+		"how often the informers send all the objects to the controllers again")
+	rootCmd.Flags().BoolVar(&leaderElectionOptions.LeaderElect, "leader-elect", true,
+		"run the controllers only while holding the leader lease, so replicas can wait as hot standbys")
+	rootCmd.Flags().DurationVar(&leaderElectionOptions.LeaseDuration, "leader-elect-lease-duration", leaderelection.DefaultLeaseDuration,
+		"time the standbys wait after the last renewal of the leader before taking over")
+	rootCmd.Flags().DurationVar(&leaderElectionOptions.RenewDeadline, "leader-elect-renew-deadline", leaderelection.DefaultRenewDeadline,
+		"time the leader keeps trying to renew the lease before it stops leading")
+	rootCmd.Flags().DurationVar(&leaderElectionOptions.RetryPeriod, "leader-elect-retry-period", leaderelection.DefaultRetryPeriod,
+		"time between attempts to acquire or renew the lease")
+	rootCmd.Flags().StringVar(&leaderElectionOptions.ResourceName, "leader-elect-resource-name", "kube-controller-manager",
+		"name of the leader lease")
+	rootCmd.Flags().StringVar(&leaderElectionOptions.ResourceNamespace, "leader-elect-resource-namespace", leaderelection.DefaultLeaseNamespace,
+		"namespace of the leader lease")
+	rootCmd.Flags().DurationVar(&nodeLifecycleOptions.NodeMonitorPeriod, "node-monitor-period", nodelifecycle.DefaultNodeMonitorPeriod,
+		"how often the node heartbeats are checked")
+	rootCmd.Flags().DurationVar(&nodeLifecycleOptions.NodeMonitorGracePeriod, "node-monitor-grace-period", nodelifecycle.DefaultNodeMonitorGracePeriod,
+		"time a node may go without heartbeats before it is marked as not ready")
+	rootCmd.Flags().DurationVar(&nodeLifecycleOptions.PodEvictionTimeout, "pod-eviction-timeout", nodelifecycle.DefaultPodEvictionTimeout,
+		"time a node may be not ready before its pods are deleted")
+	rootCmd.Flags().Float64Var(&nodeLifecycleOptions.EvictionRate, "node-eviction-rate", nodelifecycle.DefaultEvictionRate,
+		"number of nodes per second whose pods are deleted")
+	rootCmd.Flags().Float64Var(&nodeLifecycleOptions.SecondaryEvictionRate, "secondary-node-eviction-rate", nodelifecycle.DefaultSecondaryEvictionRate,
+		"number of nodes per second whose pods are deleted when the cluster is unhealthy, zero for clusters smaller than --large-cluster-size-threshold")
+	rootCmd.Flags().Float64Var(&nodeLifecycleOptions.UnhealthyZoneThreshold, "unhealthy-zone-threshold", nodelifecycle.DefaultUnhealthyZoneThreshold,
+		"fraction of not ready nodes at which the cluster is unhealthy")
+	rootCmd.Flags().IntVar(&nodeLifecycleOptions.LargeClusterSizeThreshold, "large-cluster-size-threshold", nodelifecycle.DefaultLargeClusterSizeThreshold,
+		"number of nodes above which the secondary eviction rate is used when the cluster is unhealthy")
+	rootCmd.Flags().IntVar(&replicaSetOptions.ConcurrentSyncs, "concurrent-replicaset-syncs", replicaset.DefaultConcurrentSyncs,
+		"number of replicasets synced in parallel")
+	rootCmd.Flags().IntVar(&deploymentOptions.ConcurrentSyncs, "concurrent-deployment-syncs", deployment.DefaultConcurrentSyncs,
+		"number of deployments synced in parallel")
+	rootCmd.Flags().IntVar(&daemonSetOptions.ConcurrentSyncs, "concurrent-daemonset-syncs", daemon.DefaultConcurrentSyncs,
+		"number of daemonsets synced in parallel")
+	rootCmd.Flags().IntVar(&jobOptions.ConcurrentSyncs, "concurrent-job-syncs", job.DefaultConcurrentSyncs,
+		"number of jobs synced in parallel")
+	rootCmd.Flags().IntVar(&cronJobOptions.ConcurrentSyncs, "concurrent-cronjob-syncs", cronjob.DefaultConcurrentSyncs,
+		"number of cronjobs synced in parallel")
+	rootCmd.Flags().IntVar(&statefulSetOptions.ConcurrentSyncs, "concurrent-statefulset-syncs", statefulset.DefaultConcurrentSyncs,
+		"number of statefulsets synced in parallel")
+	rootCmd.Flags().IntVar(&endpointOptions.ConcurrentSyncs, "concurrent-endpoint-syncs", endpoint.DefaultConcurrentSyncs,
+		"number of services whose endpoints are synced in parallel")
+	rootCmd.Flags().IntVar(&hpaOptions.ConcurrentSyncs, "concurrent-horizontal-pod-autoscaler-syncs", podautoscaler.DefaultConcurrentSyncs,
+		"number of horizontal pod autoscalers synced in parallel")
+	rootCmd.Flags().DurationVar(&hpaOptions.SyncPeriod, "horizontal-pod-autoscaler-sync-period", podautoscaler.DefaultSyncPeriod,
+		"period for syncing the number of pods in horizontal pod autoscaler")
+	rootCmd.Flags().Float64Var(&hpaOptions.Tolerance, "horizontal-pod-autoscaler-tolerance", podautoscaler.DefaultTolerance,
+		"minimum change (from 1.0) in the desired-to-actual metrics ratio for the horizontal pod autoscaler to consider scaling")
+	err := rootCmd.MarkFlagRequired("kubernetes-api-endpoint")
+	if err != nil {
+		panic(err)
+	}
+}
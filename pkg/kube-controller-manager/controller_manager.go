@@ -0,0 +1,141 @@
+package kubecontrollermanager
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jonatan5524/own-kubernetes/pkg/controller"
+	"github.com/jonatan5524/own-kubernetes/pkg/controller/cronjob"
+	"github.com/jonatan5524/own-kubernetes/pkg/controller/daemon"
+	"github.com/jonatan5524/own-kubernetes/pkg/controller/deployment"
+	"github.com/jonatan5524/own-kubernetes/pkg/controller/endpoint"
+	"github.com/jonatan5524/own-kubernetes/pkg/controller/job"
+	"github.com/jonatan5524/own-kubernetes/pkg/controller/nodelifecycle"
+	"github.com/jonatan5524/own-kubernetes/pkg/controller/podautoscaler"
+	"github.com/jonatan5524/own-kubernetes/pkg/controller/replicaset"
+	"github.com/jonatan5524/own-kubernetes/pkg/controller/statefulset"
+	"github.com/jonatan5524/own-kubernetes/pkg/leaderelection"
+	"github.com/jonatan5524/own-kubernetes/pkg/utils"
+)
+
+type KubeControllerManager interface {
+	Run() error
+	Setup() error
+	Stop() error
+}
+
+type KubeControllerManagerApp struct {
+	kubeAPIEndpoint string
+	options         Options
+
+	informerFactory *controller.InformerFactory
+	controllers     map[string]controller.Controller
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+type Options struct {
+	// Controllers are the controllers to run, '*' runs all of them and '-foo' disables the controller foo
+	Controllers []string
+	// ResyncPeriod is how often the informers send all their objects again to the controllers
+	ResyncPeriod time.Duration
+
+	LeaderElection leaderelection.Options
+
+	NodeLifecycle           nodelifecycle.Options
+	ReplicaSet              replicaset.Options
+	Deployment              deployment.Options
+	DaemonSet               daemon.Options
+	Job                     job.Options
+	CronJob                 cronjob.Options
+	StatefulSet             statefulset.Options
+	Endpoint                endpoint.Options
+	HorizontalPodAutoscaler podautoscaler.Options
+}
+
+func NewKubeControllerManager(kubeAPIEndpoint string, options Options) KubeControllerManager {
+	return &KubeControllerManagerApp{
+		kubeAPIEndpoint: kubeAPIEndpoint,
+		options:         options,
+		controllers:     make(map[string]controller.Controller),
+		stopCh:          make(chan struct{}),
+	}
+}
+
+func (app *KubeControllerManagerApp) Setup() error {
+	log.Println("kube-controller-manager setup")
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return fmt.Errorf("hostname not found")
+	}
+
+	utils.SetUserAgent(fmt.Sprintf("kube-controller-manager/%s", hostname))
+
+	if err := validateControllers(app.options.Controllers); err != nil {
+		return err
+	}
+
+	app.informerFactory = controller.NewInformerFactory(app.kubeAPIEndpoint, app.options.ResyncPeriod)
+
+	ctx := controller.ControllerContext{
+		KubeAPIEndpoint: app.kubeAPIEndpoint,
+		InformerFactory: app.informerFactory,
+	}
+
+	for name, initFunc := range newControllerInitializers(app.options) {
+		if !isControllerEnabled(name, app.options.Controllers) {
+			log.Printf("controller %s is disabled", name)
+
+			continue
+		}
+
+		ctrl, err := initFunc(ctx)
+		if err != nil {
+			return fmt.Errorf("error creating controller %s: %v", name, err)
+		}
+
+		app.controllers[name] = ctrl
+	}
+
+	return nil
+}
+
+func (app *KubeControllerManagerApp) Run() error {
+	log.Println("kube-controller-manager running")
+
+	return leaderelection.RunOrDie(app.kubeAPIEndpoint, app.options.LeaderElection, app.runControllers, app.stopCh)
+}
+
+// runControllers starts the informers and the controllers, they run until the stop channel is closed
+func (app *KubeControllerManagerApp) runControllers(stopCh <-chan struct{}) {
+	app.informerFactory.Start(stopCh)
+
+	log.Println("waiting for informer caches to sync")
+
+	if !app.informerFactory.WaitForCacheSync(stopCh) {
+		log.Println("stopped before the informer caches synced")
+
+		return
+	}
+
+	for name, ctrl := range app.controllers {
+		log.Printf("starting controller %s", name)
+
+		go ctrl.Run(stopCh)
+	}
+
+	<-stopCh
+}
+
+func (app *KubeControllerManagerApp) Stop() error {
+	app.stopOnce.Do(func() {
+		close(app.stopCh)
+	})
+
+	return nil
+}
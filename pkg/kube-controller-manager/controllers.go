@@ -0,0 +1,105 @@
+package kubecontrollermanager
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/jonatan5524/own-kubernetes/pkg/controller"
+	"github.com/jonatan5524/own-kubernetes/pkg/controller/cronjob"
+	"github.com/jonatan5524/own-kubernetes/pkg/controller/daemon"
+	"github.com/jonatan5524/own-kubernetes/pkg/controller/deployment"
+	"github.com/jonatan5524/own-kubernetes/pkg/controller/endpoint"
+	"github.com/jonatan5524/own-kubernetes/pkg/controller/job"
+	"github.com/jonatan5524/own-kubernetes/pkg/controller/nodelifecycle"
+	"github.com/jonatan5524/own-kubernetes/pkg/controller/podautoscaler"
+	"github.com/jonatan5524/own-kubernetes/pkg/controller/replicaset"
+	"github.com/jonatan5524/own-kubernetes/pkg/controller/statefulset"
+)
+
+// newControllerInitializers returns all the controllers known to kube-controller-manager by name
+func newControllerInitializers(options Options) map[string]controller.InitFunc {
+	return map[string]controller.InitFunc{
+		"nodelifecycle": func(ctx controller.ControllerContext) (controller.Controller, error) {
+			return nodelifecycle.NewController(ctx, options.NodeLifecycle), nil
+		},
+		"replicaset": func(ctx controller.ControllerContext) (controller.Controller, error) {
+			return replicaset.NewController(ctx, options.ReplicaSet), nil
+		},
+		"deployment": func(ctx controller.ControllerContext) (controller.Controller, error) {
+			return deployment.NewController(ctx, options.Deployment), nil
+		},
+		"daemonset": func(ctx controller.ControllerContext) (controller.Controller, error) {
+			return daemon.NewController(ctx, options.DaemonSet), nil
+		},
+		"job": func(ctx controller.ControllerContext) (controller.Controller, error) {
+			return job.NewController(ctx, options.Job), nil
+		},
+		"cronjob": func(ctx controller.ControllerContext) (controller.Controller, error) {
+			return cronjob.NewController(ctx, options.CronJob), nil
+		},
+		"statefulset": func(ctx controller.ControllerContext) (controller.Controller, error) {
+			return statefulset.NewController(ctx, options.StatefulSet), nil
+		},
+		"endpoint": func(ctx controller.ControllerContext) (controller.Controller, error) {
+			return endpoint.NewController(ctx, options.Endpoint), nil
+		},
+		"horizontalpodautoscaling": func(ctx controller.ControllerContext) (controller.Controller, error) {
+			return podautoscaler.NewController(ctx, options.HorizontalPodAutoscaler), nil
+		},
+	}
+}
+
+// KnownControllers returns the names of the controllers that can be passed to --controllers
+func KnownControllers() []string {
+	names := []string{}
+	for name := range newControllerInitializers(Options{}) {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// isControllerEnabled follows the --controllers flag of kubernetes, '*' enables all the controllers,
+// 'foo' enables the controller foo and '-foo' disables it
+func isControllerEnabled(name string, controllers []string) bool {
+	hasStar := false
+
+	for _, ctrl := range controllers {
+		if ctrl == name {
+			return true
+		}
+
+		if ctrl == "-"+name {
+			return false
+		}
+
+		if ctrl == "*" {
+			hasStar = true
+		}
+	}
+
+	return hasStar
+}
+
+func validateControllers(controllers []string) error {
+	known := newControllerInitializers(Options{})
+
+	for _, ctrl := range controllers {
+		if ctrl == "*" {
+			continue
+		}
+
+		name := ctrl
+		if len(name) > 0 && name[0] == '-' {
+			name = name[1:]
+		}
+
+		if _, ok := known[name]; !ok {
+			return fmt.Errorf("unknown controller %q, the controllers are %v", ctrl, KnownControllers())
+		}
+	}
+
+	return nil
+}
@@ -1,16 +1,18 @@
 package kubeproxy
 
 import (
-	"fmt"
 	"log"
+	"time"
 
+	kubeapi_rest "github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
 	"github.com/jonatan5524/own-kubernetes/pkg/kube-proxy/iptables"
 	"github.com/jonatan5524/own-kubernetes/pkg/kube-proxy/service"
 	"github.com/jonatan5524/own-kubernetes/pkg/kube-proxy/service/endpoint"
+	"github.com/jonatan5524/own-kubernetes/pkg/record"
 )
 
 const (
-	serviceClusterIPCIDR = "10.96.0.0/16"
+	watchRetryInterval = 5 * time.Second
 )
 
 func Setup() error {
@@ -30,16 +32,35 @@ func Setup() error {
 func Run(kubeAPIEndpoint string, hostname string, podCIDR string) error {
 	log.Println("kube-proxy running")
 
-	go endpoint.ListenForEndpoint(kubeAPIEndpoint, hostname)
-	go service.ListenForPodRunning(kubeAPIEndpoint, hostname)
+	service.SetEventRecorder(record.NewRecorder(kubeAPIEndpoint, kubeapi_rest.EventSource{
+		Component: "kube-proxy",
+		Host:      hostname,
+	}))
 
-	if err := service.ListenForService(kubeAPIEndpoint, serviceClusterIPCIDR, podCIDR); err != nil {
-		return fmt.Errorf("%v", err)
-	}
+	// the endpoints are written by the endpoints controller, the proxy only writes their iptables rules
+	go watchForever("endpoints", func() error {
+		return endpoint.ListenForEndpoint(kubeAPIEndpoint)
+	})
+
+	watchForever("services", func() error {
+		return service.ListenForService(kubeAPIEndpoint, podCIDR)
+	})
 
 	return nil
 }
 
+// watchForever restarts a watch whenever the api ends it, for example when the api restarts
+func watchForever(resource string, listen func() error) {
+	for {
+		if err := listen(); err != nil {
+			log.Printf("watch on %s stopped: %v", resource, err)
+		}
+
+		log.Printf("restarting watch on %s in %s", resource, watchRetryInterval)
+		time.Sleep(watchRetryInterval)
+	}
+}
+
 func Stop() error {
 	return nil
 }
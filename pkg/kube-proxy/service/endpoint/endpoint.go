@@ -2,28 +2,61 @@ package endpoint
 
 import (
 	"bufio"
-	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
-	"math"
 	"net/http"
+	"sort"
 	"strings"
+	"sync"
 
 	kubeapi_rest "github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
 	"github.com/jonatan5524/own-kubernetes/pkg/kube-proxy/iptables"
 	clusterip "github.com/jonatan5524/own-kubernetes/pkg/kube-proxy/service/clusterIP"
+	"github.com/jonatan5524/own-kubernetes/pkg/record"
 	"github.com/jonatan5524/own-kubernetes/pkg/utils"
 	"gopkg.in/yaml.v3"
 )
 
-func ListenForEndpoint(kubeAPIEndpoint string, hostname string) error {
+var eventRecorder record.EventRecorder = record.NopRecorder{}
+
+var (
+	mu sync.Mutex
+	// syncedEndpoints are the endpoints the iptables rules were written for by namespace/name, so the rules of
+	// the addresses that left an endpoint are removed
+	syncedEndpoints = map[string]kubeapi_rest.Endpoint{}
+)
+
+func SetEventRecorder(recorder record.EventRecorder) {
+	eventRecorder = recorder
+}
+
+func endpointReference(endpoint kubeapi_rest.Endpoint) kubeapi_rest.ObjectReference {
+	return kubeapi_rest.ObjectReference{
+		Kind:      "Endpoints",
+		Namespace: endpoint.Metadata.Namespace,
+		Name:      endpoint.Metadata.Name,
+		UID:       endpoint.Metadata.UID,
+	}
+}
+
+// ListenForEndpoint writes the iptables rules of every endpoint and keeps them in sync with the endpoints, which
+// are written by the endpoints controller. The endpoints are listed first so the rules of the endpoints that
+// changed while the proxy was not watching are synced too
+func ListenForEndpoint(kubeAPIEndpoint string) error {
 	log.Printf("started watch on endpoints from kube API")
 
+	resourceVersion, err := syncAllEndpoints(kubeAPIEndpoint)
+	if err != nil {
+		return err
+	}
+
 	resp, err := http.Get(fmt.Sprintf(
-		"%s/endpoints/?watch=true",
+		"%s/endpoints/?watch=true&resourceVersion=%s",
 		kubeAPIEndpoint,
+		resourceVersion,
 	),
 	)
 	if err != nil {
@@ -45,6 +78,10 @@ func ListenForEndpoint(kubeAPIEndpoint string, hostname string) error {
 	for {
 		line, err := reader.ReadString('\n')
 		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return fmt.Errorf("watch stream closed by api")
+			}
+
 			log.Printf("error parsing response: %v", err)
 
 			continue
@@ -63,338 +100,217 @@ func ListenForEndpoint(kubeAPIEndpoint string, hostname string) error {
 			continue
 		}
 
+		if typeEvent == kubeapi_rest.WatchErrorEventType {
+			return fmt.Errorf("watch ended by api: %s", value)
+		}
+
 		log.Printf("endpoint event for endpoints: %s %s", typeEvent, value)
 
 		var endpoint kubeapi_rest.Endpoint
 		err = yaml.Unmarshal([]byte(value), &endpoint)
 		if err != nil {
-			log.Printf("error parsing pod from event: %v", err)
+			log.Printf("error parsing endpoint from event: %v", err)
 
 			continue
 		}
 
+		// the events are synced in order, a later event of an endpoint must not be overwritten by an earlier one
 		if typeEvent == "PUT" {
-			found := false
-			for _, subset := range endpoint.Subsets {
-				for _, address := range subset.Addresses {
-					if address.NodeName == hostname {
-						found = true
-						break
-					}
-				}
-
-				if found {
-					break
-				}
-			}
-
-			if found {
-				go createEndpointIPTable(kubeAPIEndpoint, endpoint)
-			}
+			syncEndpoint(endpoint)
 		} else {
-			go deleteEndpoint(endpoint)
+			removeEndpoint(endpoint)
 		}
 	}
 }
 
-func deleteEndpoint(endpoint kubeapi_rest.Endpoint) {
-	log.Printf("deleteing endpoint %s/%s", endpoint.Metadata.Namespace, endpoint.Metadata.Name)
-
-	for _, subset := range endpoint.Subsets {
-		for _, address := range subset.Addresses {
-			for _, port := range subset.Ports {
-				if err := iptables.DeleteEndpointChain(
-					address.TargetRef.Name,
-					endpoint.Metadata.Namespace,
-					port.Name,
-				); err != nil {
-					log.Printf("error deleting endpoint: %v", err)
-
-					return
-				}
-			}
+// SyncServiceEndpoint writes the iptables rules of the endpoint of the service, the endpoint may be written before
+// the proxy created the clusterIP rules of the service and then its addresses were skipped
+func SyncServiceEndpoint(kubeAPIEndpoint string, namespace string, name string) {
+	endpoint, err := getEndpoint(kubeAPIEndpoint, name, namespace)
+	if err != nil {
+		if err.Error() != "endpoint not found" {
+			log.Printf("error getting endpoint %s/%s: %v", namespace, name, err)
 		}
+
+		return
 	}
-}
 
-func createEndpointIPTable(kubeAPIEndpoint string, endpoint kubeapi_rest.Endpoint) {
-	log.Printf("creating iptable rules using endpoint")
+	mu.Lock()
+	// the rules are written again from scratch
+	delete(syncedEndpoints, endpointKey(endpoint))
+	mu.Unlock()
+
+	syncEndpoint(endpoint)
+}
 
-	service, err := getRelatableService(kubeAPIEndpoint, endpoint.Metadata.Name, endpoint.Metadata.Namespace)
+func syncAllEndpoints(kubeAPIEndpoint string) (string, error) {
+	endpoints, resourceVersion, err := getAllEndpoints(kubeAPIEndpoint)
 	if err != nil {
-		log.Printf("error in getting relatable service: %v", err)
+		return "", err
+	}
 
-		return
+	listed := map[string]bool{}
+	for _, endpoint := range endpoints {
+		listed[endpointKey(endpoint)] = true
+
+		syncEndpoint(endpoint)
 	}
 
-	for _, subset := range endpoint.Subsets {
-		for index, address := range subset.Addresses {
-			for _, port := range subset.Ports {
-				if !clusterip.CheckIfClusterIPServiceEndpointExists(service.Metadata.Namespace, address.TargetRef.Name, port.Name) {
-					log.Printf("len: %d", len(subset.Addresses))
-					log.Printf("index: %d", index)
-					log.Printf("probability: %f", float32(len(subset.Addresses)-index)/float32(len(subset.Addresses)))
-					err := iptables.CreateEndpointChain(
-						service.Metadata.Namespace,
-						service.Metadata.Name,
-						address.TargetRef.Name,
-						port.Name,
-						address.IP,
-						port.Port,
-					)
-					if err != nil {
-						log.Printf("error creating endpoint: %v", err)
-
-						return
-					}
-
-					err = clusterip.AddEndpointToClusterIP(
-						service.Metadata.Namespace,
-						service.Metadata.Name,
-						address.TargetRef.Name,
-						port.Name,
-						address.IP,
-						port.Port,
-						float32(len(subset.Addresses)-index)/float32(len(subset.Addresses)),
-					)
-					if err != nil {
-						log.Printf("error adding pod to clusterIP: %v", err)
-
-						return
-					}
-				}
-			}
+	mu.Lock()
+	removed := []kubeapi_rest.Endpoint{}
+	for key, endpoint := range syncedEndpoints {
+		if !listed[key] {
+			removed = append(removed, endpoint)
 		}
 	}
-}
+	mu.Unlock()
 
-func DeleteEndpointAddressIfExists(pod kubeapi_rest.Pod, kubeAPIEndpoint string) {
-	log.Printf("check if endpoint for pod %s/%s need to delete", pod.Metadata.Namespace, pod.Metadata.Name)
+	for _, endpoint := range removed {
+		removeEndpoint(endpoint)
+	}
 
-	endpoints, err := getAllEndpointsInNamespace(pod.Metadata.Namespace, kubeAPIEndpoint)
-	if err != nil {
-		log.Printf("error getting endpoints from namespace %v", err)
+	return resourceVersion, nil
+}
 
-		return
-	}
+func removeEndpoint(endpoint kubeapi_rest.Endpoint) {
+	log.Printf("deleteing endpoint %s/%s", endpoint.Metadata.Namespace, endpoint.Metadata.Name)
 
-	deleteEndpointIndex := math.MaxInt
-	deleteSubsetIndex := math.MaxInt
-	deleteAddressIndex := math.MaxInt
-	for indexEndpoint, endpoint := range endpoints {
-		for indexSubset, subset := range endpoint.Subsets {
-			for indexAddress, address := range subset.Addresses {
-				if address.TargetRef.UID == pod.Metadata.UID {
-					deleteEndpointIndex = indexEndpoint
-					deleteSubsetIndex = indexSubset
-					deleteAddressIndex = indexAddress
-
-					break
-				}
-			}
-		}
-	}
+	syncEndpoint(kubeapi_rest.Endpoint{Metadata: endpoint.Metadata})
 
-	if deleteEndpointIndex != math.MaxInt &&
-		deleteSubsetIndex != math.MaxInt &&
-		deleteAddressIndex != math.MaxInt {
-		log.Printf(
-			"found endpoint %s/%s to remove the address pod from",
-			endpoints[deleteEndpointIndex].Metadata.Namespace,
-			endpoints[deleteEndpointIndex].Metadata.Name,
-		)
-
-		service, err := getRelatableService(
-			kubeAPIEndpoint,
-			endpoints[deleteEndpointIndex].Metadata.Name,
-			endpoints[deleteEndpointIndex].Metadata.Namespace,
-		)
-		if err != nil {
-			log.Printf("error in getting relatable service: %v", err)
+	mu.Lock()
+	delete(syncedEndpoints, endpointKey(endpoint))
+	mu.Unlock()
+}
 
-			return
-		}
+// endpointTarget is where the rules of an address of a port send the traffic to
+type endpointTarget struct {
+	ip   string
+	port int
+}
 
-		for _, subset := range endpoints[deleteEndpointIndex].Subsets {
-			for range subset.Addresses {
-				for _, port := range subset.Ports {
-					if err := iptables.ClearClusterIPServiceFromEndpoints(
-						service.Metadata.Name,
-						service.Metadata.Namespace,
-						port.Name,
-					); err != nil {
-						log.Printf("error clearing clusterip chain: %v", err)
-					}
-				}
-			}
-		}
+// targetsByPort returns the ready addresses of the endpoint by port name and pod name
+func targetsByPort(endpoint kubeapi_rest.Endpoint) map[string]map[string]endpointTarget {
+	targets := map[string]map[string]endpointTarget{}
 
-		endpoints[deleteEndpointIndex].Subsets[deleteSubsetIndex].Addresses = append(
-			endpoints[deleteEndpointIndex].Subsets[deleteSubsetIndex].Addresses[:deleteAddressIndex],
-			endpoints[deleteEndpointIndex].Subsets[deleteSubsetIndex].Addresses[deleteAddressIndex+1:]...,
-		)
-
-		for _, subset := range endpoints[deleteEndpointIndex].Subsets {
-			for index, address := range subset.Addresses {
-				for _, port := range subset.Ports {
-					err = clusterip.AddEndpointToClusterIP(
-						service.Metadata.Namespace,
-						service.Metadata.Name,
-						address.TargetRef.Name,
-						port.Name,
-						address.IP,
-						port.Port,
-						float32(len(subset.Addresses)-index)/float32(len(subset.Addresses)),
-					)
-					if err != nil {
-						log.Printf("error adding pod to clusterIP: %v", err)
-
-						return
-					}
-				}
+	for _, subset := range endpoint.Subsets {
+		for _, port := range subset.Ports {
+			if _, ok := targets[port.Name]; !ok {
+				targets[port.Name] = map[string]endpointTarget{}
 			}
-		}
-
-		if err := updateEndpointToAPI(endpoints[deleteEndpointIndex], kubeAPIEndpoint); err != nil {
-			log.Printf("error updating endpoint %v", err)
 
-			return
+			for _, address := range subset.Addresses {
+				targets[port.Name][address.TargetRef.Name] = endpointTarget{ip: address.IP, port: port.Port}
+			}
 		}
 	}
+
+	return targets
 }
 
-func updateEndpointToAPI(endpoint kubeapi_rest.Endpoint, kubeAPIEndpoint string) error {
-	log.Printf("update endpoint %s for api", endpoint.Metadata.Name)
+// syncEndpoint writes the iptables rules of the ready addresses of the endpoint for every port of the service.
+// The endpoint chains of the addresses that left or moved are deleted, and the service chain is written again
+// so the traffic is spread evenly over the current addresses
+func syncEndpoint(endpoint kubeapi_rest.Endpoint) {
+	mu.Lock()
+	defer mu.Unlock()
 
-	endpointBytes, err := json.Marshal(endpoint)
-	if err != nil {
-		return fmt.Errorf("error parsing endpoint: %v", err)
-	}
+	key := endpointKey(endpoint)
+	namespace := endpoint.Metadata.Namespace
+	serviceName := endpoint.Metadata.Name
 
-	req, err := http.NewRequest(
-		http.MethodPatch,
-		fmt.Sprintf("%s/namespaces/%s/endpoints/%s", kubeAPIEndpoint, endpoint.Metadata.Namespace, endpoint.Metadata.Name),
-		bytes.NewBuffer(endpointBytes),
-	)
-	if err != nil {
-		return fmt.Errorf("error creating request for endpoint update: %v", err)
-	}
+	previousTargets := targetsByPort(syncedEndpoints[key])
+	currentTargets := targetsByPort(endpoint)
 
-	req.Header.Set("Content-Type", "application/json")
+	portNames := map[string]bool{}
+	for portName := range previousTargets {
+		portNames[portName] = true
+	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return fmt.Errorf("error sending endpoint update: %v", err)
+	for portName := range currentTargets {
+		portNames[portName] = true
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return fmt.Errorf("error reading response body: %v", err)
+	for portName := range portNames {
+		// the chain jumps to the endpoint chains from its second rule, they are deleted until none is left
+		for iptables.ClearClusterIPServiceFromEndpoints(serviceName, namespace, portName) == nil {
 		}
 
-		return fmt.Errorf("request failed with status code: %d %s", resp.StatusCode, string(body))
-	}
-
-	return nil
-}
+		for podName, previous := range previousTargets[portName] {
+			if current, ok := currentTargets[portName][podName]; ok && current == previous {
+				continue
+			}
 
-func CreateEndpoints(kubeAPIEndpoint string, service kubeapi_rest.Service, pods []kubeapi_rest.Pod) {
-	log.Printf("creating endpoints for service %s/%s", service.Metadata.Namespace, service.Metadata.Name)
+			if err := iptables.DeleteEndpointChain(podName, namespace, portName); err != nil {
+				log.Printf("error deleting endpoint chain of %s: %v", podName, err)
+			}
+		}
 
-	var method string
-	var url string
-	endpoint, err := getEndpoint(kubeAPIEndpoint, service.Metadata.Name, service.Metadata.Namespace)
-	if err != nil {
-		if err.Error() == "endpoint not found" {
-			method = http.MethodPost
-			endpoint = createNewEndpoint(service, pods)
-			url = fmt.Sprintf("%s/namespaces/%s/endpoints", kubeAPIEndpoint, endpoint.Metadata.Namespace)
-		} else {
-			log.Printf("error getting existing endpoint: %v", err)
+		if !clusterip.CheckIfClusterIPServiceExists(namespace, serviceName, portName) {
+			log.Printf("service %s/%s port %s has no clusterIP rules yet, skipping its endpoints", namespace, serviceName, portName)
 
-			return
+			continue
 		}
-	} else {
-		method = http.MethodPatch
-		url = fmt.Sprintf("%s/namespaces/%s/endpoints/%s", kubeAPIEndpoint, endpoint.Metadata.Namespace, endpoint.Metadata.Name)
-	}
 
-	for _, pod := range pods {
-		if pod.Status.PodIP != "" {
-			endpoint.Subsets[0].Addresses = append(endpoint.Subsets[0].Addresses,
-				kubeapi_rest.EndpointAddress{
-					IP:       pod.Status.PodIP,
-					NodeName: pod.Spec.NodeName,
-					TargetRef: kubeapi_rest.TargetRef{
-						Kind:      pod.Kind,
-						Name:      pod.Metadata.Name,
-						Namespace: pod.Metadata.Namespace,
-						UID:       pod.Metadata.UID,
-					},
-				},
-			)
+		podNames := make([]string, 0, len(currentTargets[portName]))
+		for podName := range currentTargets[portName] {
+			podNames = append(podNames, podName)
 		}
-	}
 
-	data, err := json.Marshal(endpoint)
-	if err != nil {
-		log.Printf("error marshaling endpoints: %v", err)
+		sort.Strings(podNames)
 
-		return
-	}
+		for index, podName := range podNames {
+			target := currentTargets[portName][podName]
 
-	req, err := http.NewRequest(
-		method,
-		url,
-		bytes.NewReader(data),
-	)
-	if err != nil {
-		log.Printf("error creating request for service update: %v", err)
+			previous, ok := previousTargets[portName][podName]
+			if !ok || previous != target || !clusterip.CheckIfClusterIPServiceEndpointExists(namespace, podName, portName) {
+				if !createEndpointChain(endpoint, portName, podName, target) {
+					continue
+				}
+			}
 
-		return
-	}
+			// every rule is inserted before the previous ones, the first rule takes 1/n of the traffic,
+			// the second 1/(n-1) of the rest and the last rule the rest
+			probability := float32(0)
+			if index > 0 {
+				probability = 1 / float32(index+1)
+			}
 
-	req.Header.Set("Content-Type", "application/json")
+			if err := clusterip.AddEndpointToClusterIP(namespace, serviceName, podName, portName, target.ip, target.port, probability); err != nil {
+				log.Printf("error adding pod to clusterIP: %v", err)
+				eventRecorder.Eventf(endpointReference(endpoint), kubeapi_rest.EventTypeWarning, "FailedToUpdateEndpoint",
+					"Failed to add %s:%d to the clusterIP iptables rules: %v", target.ip, target.port, err)
+			}
+		}
+	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Printf("error sending service update: %v", err)
+	syncedEndpoints[key] = endpoint
+}
 
-		return
-	}
-	defer resp.Body.Close()
+// createEndpointChain writes the endpoint chain of the address, a chain left from before the proxy started or
+// from the previous ip of the pod is replaced
+func createEndpointChain(endpoint kubeapi_rest.Endpoint, portName string, podName string, target endpointTarget) bool {
+	namespace := endpoint.Metadata.Namespace
 
-	if resp.StatusCode != http.StatusOK {
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			log.Fatal(err)
+	if clusterip.CheckIfClusterIPServiceEndpointExists(namespace, podName, portName) {
+		if err := iptables.DeleteEndpointChain(podName, namespace, portName); err != nil {
+			log.Printf("error deleting endpoint chain of %s: %v", podName, err)
 		}
+	}
 
-		log.Printf("error from api: %s %s", resp.Status, string(body))
+	err := iptables.CreateEndpointChain(namespace, endpoint.Metadata.Name, podName, portName, target.ip, target.port)
+	if err != nil {
+		log.Printf("error creating endpoint: %v", err)
+		eventRecorder.Eventf(endpointReference(endpoint), kubeapi_rest.EventTypeWarning, "FailedToUpdateEndpoint",
+			"Failed to create iptables rules for %s:%d: %v", target.ip, target.port, err)
 
-		return
+		return false
 	}
-}
 
-func createNewEndpoint(service kubeapi_rest.Service, pods []kubeapi_rest.Pod) kubeapi_rest.Endpoint {
-	endpoint := kubeapi_rest.Endpoint{
-		Metadata: kubeapi_rest.ResourceMetadata{
-			Name:      service.Metadata.Name,
-			Namespace: service.Metadata.Namespace,
-		},
-		Kind: "Endpoint",
-		Subsets: []kubeapi_rest.EndpointSubset{
-			{
-				Ports: service.Spec.Ports,
-			},
-		},
-	}
+	return true
+}
 
-	return endpoint
+func endpointKey(endpoint kubeapi_rest.Endpoint) string {
+	return fmt.Sprintf("%s/%s", endpoint.Metadata.Namespace, endpoint.Metadata.Name)
 }
 
 func getEndpoint(kubeAPIEndpoint string, name string, namespace string) (kubeapi_rest.Endpoint, error) {
@@ -437,81 +353,36 @@ func getEndpoint(kubeAPIEndpoint string, name string, namespace string) (kubeapi
 	return endpoint, nil
 }
 
-func getRelatableService(kubeAPIEndpoint string, name string, namespace string) (kubeapi_rest.Service, error) {
-	var service kubeapi_rest.Service
+// getAllEndpoints returns the endpoints of all the namespaces and the resource version they were listed at
+func getAllEndpoints(kubeAPIEndpoint string) ([]kubeapi_rest.Endpoint, string, error) {
+	var endpoints []kubeapi_rest.Endpoint
 	resp, err := http.Get(fmt.Sprintf(
-		"%s/namespaces/%s/services/%s",
+		"%s/endpoints",
 		kubeAPIEndpoint,
-		namespace,
-		name,
 	),
 	)
 	if err != nil {
-		return service, fmt.Errorf("error sending request: %v", err)
+		return endpoints, "", fmt.Errorf("error sending request: %v", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return service, fmt.Errorf("error reading response body: %v", err)
-		}
-
-		if strings.Contains(string(body), "key not found") {
-			return service, nil
-		}
-
-		return service, fmt.Errorf("request failed with status code: %d", resp.StatusCode)
-	}
-
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return service, fmt.Errorf("error reading response body: %v", err)
+		return endpoints, "", fmt.Errorf("error reading response body: %v", err)
 	}
 
-	err = json.Unmarshal(body, &service)
-	if err != nil {
-		return service, fmt.Errorf("error unmarshalling JSON: %v", err)
-	}
-
-	return service, nil
-}
-
-func getAllEndpointsInNamespace(namespace string, kubeAPIEndpoint string) ([]kubeapi_rest.Endpoint, error) {
-	var endpoints []kubeapi_rest.Endpoint
-	resp, err := http.Get(fmt.Sprintf(
-		"%s/namespaces/%s/endpoints",
-		kubeAPIEndpoint,
-		namespace,
-	),
-	)
-	if err != nil {
-		return endpoints, fmt.Errorf("error sending request: %v", err)
-	}
-	defer resp.Body.Close()
-
 	if resp.StatusCode != http.StatusOK {
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return endpoints, fmt.Errorf("error reading response body: %v", err)
-		}
-
 		if strings.Contains(string(body), "key not found") {
-			return endpoints, nil
+			return endpoints, resp.Header.Get(kubeapi_rest.ResourceVersionHeader), nil
 		}
 
-		return endpoints, fmt.Errorf("request failed with status code: %d", resp.StatusCode)
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return endpoints, fmt.Errorf("error reading response body: %v", err)
+		return endpoints, "", fmt.Errorf("request failed with status code: %d %s", resp.StatusCode, string(body))
 	}
 
 	err = json.Unmarshal(body, &endpoints)
 	if err != nil {
-		return endpoints, fmt.Errorf("error unmarshalling JSON: %v", err)
+		return endpoints, "", fmt.Errorf("error unmarshalling JSON: %v", err)
 	}
 
-	return endpoints, nil
+	return endpoints, resp.Header.Get(kubeapi_rest.ResourceVersionHeader), nil
 }
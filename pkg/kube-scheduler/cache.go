@@ -0,0 +1,222 @@
+package kubescheduler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	kubeapi_rest "github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
+	"github.com/jonatan5524/own-kubernetes/pkg/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// podCache holds all the pods of the cluster, the scheduled ones take resources of their nodes
+// and the unscheduled ones are waiting in the queue
+type podCache struct {
+	mu   sync.RWMutex
+	pods map[string]*kubeapi_rest.Pod
+}
+
+func newPodCache() *podCache {
+	return &podCache{
+		pods: make(map[string]*kubeapi_rest.Pod),
+	}
+}
+
+func podKey(pod *kubeapi_rest.Pod) string {
+	return fmt.Sprintf("%s/%s", pod.Metadata.Namespace, pod.Metadata.Name)
+}
+
+func (cache *podCache) get(key string) (*kubeapi_rest.Pod, bool) {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+
+	pod, ok := cache.pods[key]
+
+	return pod, ok
+}
+
+func (cache *podCache) set(pod *kubeapi_rest.Pod) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	cache.pods[podKey(pod)] = pod
+}
+
+func (cache *podCache) delete(key string) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	delete(cache.pods, key)
+}
+
+func (cache *podCache) replace(pods []kubeapi_rest.Pod) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	cache.pods = make(map[string]*kubeapi_rest.Pod, len(pods))
+	for index := range pods {
+		cache.pods[podKey(&pods[index])] = &pods[index]
+	}
+}
+
+// assume places the pod on the node in the cache right after the binding, so the next pods already count its
+// requests before the watch event of the binding arrives
+func (cache *podCache) assume(key string, nodeName string) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	pod, ok := cache.pods[key]
+	if !ok {
+		return
+	}
+
+	assumed := *pod
+	assumed.Spec.NodeName = nodeName
+	cache.pods[key] = &assumed
+}
+
+// podsByNode returns the pods taking resources of every node, the finished pods do not take any
+func (cache *podCache) podsByNode() map[string][]*kubeapi_rest.Pod {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+
+	podsByNode := make(map[string][]*kubeapi_rest.Pod)
+	for _, pod := range cache.pods {
+		if pod.Spec.NodeName == "" || pod.Status.Phase == "Succeeded" || pod.Status.Phase == "Failed" {
+			continue
+		}
+
+		podsByNode[pod.Spec.NodeName] = append(podsByNode[pod.Spec.NodeName], pod)
+	}
+
+	return podsByNode
+}
+
+// listAndWatchPods fills the cache with a list of the pods and keeps it up to date with a watch from the revision
+// of the list, every pod waiting for a node is added to the queue
+func (scheduler *Scheduler) listAndWatchPods(ctx context.Context) error {
+	pods, resourceVersion, err := listPods(scheduler.kubeAPIEndpoint)
+	if err != nil {
+		return err
+	}
+
+	scheduler.cache.replace(pods)
+
+	for index := range pods {
+		if needsScheduling(&pods[index]) {
+			scheduler.queue.Add(podKey(&pods[index]))
+		}
+	}
+
+	log.Printf("listed %d pods at revision %s, watching for changes", len(pods), resourceVersion)
+
+	// the watch request is cancelled with the context, so stopping the scheduler ends a watch waiting for events
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		fmt.Sprintf("%s/pods/?watch=true&resourceVersion=%s", scheduler.kubeAPIEndpoint, resourceVersion), nil)
+	if err != nil {
+		return fmt.Errorf("error creating request: %v", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("error reading response body: %v", err)
+		}
+
+		return fmt.Errorf("request failed with status code: %d %s", resp.StatusCode, string(body))
+	}
+
+	reader := bufio.NewReader(resp.Body)
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return fmt.Errorf("watch stream closed by api")
+			}
+
+			log.Printf("error parsing response: %v", err)
+
+			continue
+		}
+
+		line = strings.TrimSpace(line)
+
+		if len(line) == 0 {
+			continue
+		}
+
+		typeEvent, value, err := utils.GetTypeAndValueFromEvent(line)
+		if err != nil {
+			log.Printf("error getting type and value from event: %v", err)
+
+			continue
+		}
+
+		if typeEvent == kubeapi_rest.WatchErrorEventType {
+			return fmt.Errorf("watch ended by api: %s", value)
+		}
+
+		var pod kubeapi_rest.Pod
+		err = yaml.Unmarshal([]byte(value), &pod)
+		if err != nil {
+			log.Printf("error parsing pod from event: %v", err)
+
+			continue
+		}
+
+		if typeEvent == "PUT" {
+			scheduler.cache.set(&pod)
+
+			if needsScheduling(&pod) {
+				scheduler.queue.Add(podKey(&pod))
+			}
+		} else {
+			scheduler.cache.delete(podKey(&pod))
+			scheduler.queue.Forget(podKey(&pod))
+		}
+	}
+}
+
+func needsScheduling(pod *kubeapi_rest.Pod) bool {
+	return pod.Spec.NodeName == "" && pod.Status.Phase == "Pending"
+}
+
+func listPods(kubeAPIEndpoint string) ([]kubeapi_rest.Pod, string, error) {
+	var pods []kubeapi_rest.Pod
+
+	resp, err := http.Get(fmt.Sprintf("%s/pods", kubeAPIEndpoint))
+	if err != nil {
+		return pods, "", fmt.Errorf("error sending request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return pods, "", fmt.Errorf("error reading response body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return pods, "", fmt.Errorf("request failed with status code: %d %s", resp.StatusCode, string(body))
+	}
+
+	if err = json.Unmarshal(body, &pods); err != nil {
+		return pods, "", fmt.Errorf("error unmarshalling JSON: %v", err)
+	}
+
+	return pods, resp.Header.Get(kubeapi_rest.ResourceVersionHeader), nil
+}
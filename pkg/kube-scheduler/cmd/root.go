@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	kubescheduler "github.com/jonatan5524/own-kubernetes/pkg/kube-scheduler"
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-scheduler/plugins"
+	"github.com/jonatan5524/own-kubernetes/pkg/leaderelection"
+	"github.com/spf13/cobra"
+)
+
+var (
+	kubeAPIEndpoint string
+
+	schedulerOptions kubescheduler.Options
+)
+
+var rootCmd = &cobra.Command{
+	Use:   "kube-scheduler",
+	Short: "CLI util for running the kubernetes scheduler",
+	RunE: func(_ *cobra.Command, _ []string) error {
+		app := kubescheduler.NewKubeScheduler(kubeAPIEndpoint, schedulerOptions)
+		defer app.Stop()
+
+		if err := app.Setup(); err != nil {
+			return err
+		}
+
+		if err := app.Run(); err != nil {
+			return err
+		}
+
+		return nil
+	},
+}
+
+func Execute() {
+	err := rootCmd.Execute()
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+func init() {
+	rootCmd.Flags().StringVar(&kubeAPIEndpoint, "kubernetes-api-endpoint", "", "kubernetes api endpoint")
+	rootCmd.Flags().StringVar(&schedulerOptions.ScorePlugins, "score-plugins", plugins.DefaultScorePlugins,
+		fmt.Sprintf("comma separated list of score plugins and their weights as name=weight, plugins: %v", plugins.ScorePluginNames()))
+	rootCmd.Flags().BoolVar(&schedulerOptions.LeaderElection.LeaderElect, "leader-elect", true,
+		"schedule only while holding the leader lease, so replicas can wait as hot standbys")
+	rootCmd.Flags().DurationVar(&schedulerOptions.LeaderElection.LeaseDuration, "leader-elect-lease-duration", leaderelection.DefaultLeaseDuration,
+		"time the standbys wait after the last renewal of the leader before taking over")
+	rootCmd.Flags().DurationVar(&schedulerOptions.LeaderElection.RenewDeadline, "leader-elect-renew-deadline", leaderelection.DefaultRenewDeadline,
+		"time the leader keeps trying to renew the lease before it stops leading")
+	rootCmd.Flags().DurationVar(&schedulerOptions.LeaderElection.RetryPeriod, "leader-elect-retry-period", leaderelection.DefaultRetryPeriod,
+		"time between attempts to acquire or renew the lease")
+	rootCmd.Flags().StringVar(&schedulerOptions.LeaderElection.ResourceName, "leader-elect-resource-name", "kube-scheduler",
+		"name of the leader lease")
+	rootCmd.Flags().StringVar(&schedulerOptions.LeaderElection.ResourceNamespace, "leader-elect-resource-namespace", leaderelection.DefaultLeaseNamespace,
+		"namespace of the leader lease")
+	err := rootCmd.MarkFlagRequired("kubernetes-api-endpoint")
+	if err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,123 @@
+package framework
+
+import (
+	"fmt"
+	"math"
+
+	kubeapi_rest "github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
+	"github.com/jonatan5524/own-kubernetes/pkg/utils"
+)
+
+// MaxNodeScore is the highest score a score plugin gives a node
+const MaxNodeScore = 100
+
+type Resources struct {
+	MilliCPU int64
+	// Memory is in bytes
+	Memory int64
+	Pods   int64
+}
+
+func (resources *Resources) Add(other Resources) {
+	resources.MilliCPU += other.MilliCPU
+	resources.Memory += other.Memory
+	resources.Pods += other.Pods
+}
+
+// NodeInfo is a node with the pods placed on it and the resources they request
+type NodeInfo struct {
+	Node      *kubeapi_rest.Node
+	Pods      []*kubeapi_rest.Pod
+	Requested Resources
+	// Allocatable is what the node has for pods, the invalid quantities of the node are counted as zero
+	Allocatable Resources
+}
+
+func NewNodeInfo(node *kubeapi_rest.Node, pods []*kubeapi_rest.Pod) *NodeInfo {
+	allocatable, _ := ResourcesFromList(node.Status.Allocatable)
+
+	nodeInfo := &NodeInfo{
+		Node:        node,
+		Pods:        pods,
+		Allocatable: allocatable,
+	}
+
+	for _, pod := range pods {
+		// a pod request that can not be parsed was never scheduled, the kubelet started it from a manifest
+		requests, _ := PodRequests(pod)
+		nodeInfo.Requested.Add(requests)
+	}
+
+	return nodeInfo
+}
+
+// ResourcesFromList parses a list of quantities by resource name, such as the allocatable of a node
+func ResourcesFromList(list map[string]string) (Resources, error) {
+	var resources Resources
+
+	for name, quantity := range list {
+		value, err := utils.ParseQuantity(quantity)
+		if err != nil {
+			return resources, fmt.Errorf("invalid %s: %v", name, err)
+		}
+
+		switch name {
+		case kubeapi_rest.ResourceCPU:
+			resources.MilliCPU = int64(math.Ceil(value * 1000))
+		case kubeapi_rest.ResourceMemory:
+			resources.Memory = int64(math.Ceil(value))
+		case kubeapi_rest.ResourcePods:
+			resources.Pods = int64(value)
+		}
+	}
+
+	return resources, nil
+}
+
+// PodRequests sums the requests of the containers of the pod, a pod always takes one of the pods of a node
+func PodRequests(pod *kubeapi_rest.Pod) (Resources, error) {
+	requests := Resources{Pods: 1}
+
+	for _, container := range pod.Spec.Containers {
+		containerRequests, err := ResourcesFromList(container.Resources.Requests)
+		if err != nil {
+			return requests, fmt.Errorf("container %s: %v", container.Name, err)
+		}
+
+		containerRequests.Pods = 0
+		requests.Add(containerRequests)
+	}
+
+	return requests, nil
+}
+
+// Status is the result of a filter plugin, a nil status means the pod fits the node
+type Status struct {
+	Reasons []string
+}
+
+func NewStatus(reasons ...string) *Status {
+	return &Status{Reasons: reasons}
+}
+
+type Plugin interface {
+	Name() string
+}
+
+// FilterPlugin rules out the nodes the pod can not run on
+type FilterPlugin interface {
+	Plugin
+	Filter(pod *kubeapi_rest.Pod, nodeInfo *NodeInfo) *Status
+}
+
+// ScorePlugin ranks the nodes that passed the filters, it returns a score between 0 and MaxNodeScore
+// for every node in the order of nodeInfos
+type ScorePlugin interface {
+	Plugin
+	Score(pod *kubeapi_rest.Pod, nodeInfos []*NodeInfo) []int64
+}
+
+type WeightedScorePlugin struct {
+	ScorePlugin
+	Weight int64
+}
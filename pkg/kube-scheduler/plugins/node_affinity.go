@@ -0,0 +1,42 @@
+package plugins
+
+import (
+	kubeapi_rest "github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-scheduler/framework"
+)
+
+const NodeAffinityName = "node-affinity"
+
+// NodeAffinity keeps pods on the nodes matching their node selector and required node affinity,
+// and prefers the nodes matching more of their preferred node affinity
+type NodeAffinity struct{}
+
+func (NodeAffinity) Name() string {
+	return NodeAffinityName
+}
+
+func (NodeAffinity) Filter(pod *kubeapi_rest.Pod, nodeInfo *framework.NodeInfo) *framework.Status {
+	if !pod.MatchesNodeSelectorAndAffinity(nodeInfo.Node) {
+		return framework.NewStatus("node(s) didn't match Pod's node affinity/selector")
+	}
+
+	return nil
+}
+
+func (NodeAffinity) Score(pod *kubeapi_rest.Pod, nodeInfos []*framework.NodeInfo) []int64 {
+	scores := make([]int64, len(nodeInfos))
+
+	if pod.Spec.Affinity == nil || pod.Spec.Affinity.NodeAffinity == nil {
+		return scores
+	}
+
+	for index, nodeInfo := range nodeInfos {
+		for _, term := range pod.Spec.Affinity.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution {
+			if term.Preference.Matches(nodeInfo.Node.Metadata.Labels) {
+				scores[index] += int64(term.Weight)
+			}
+		}
+	}
+
+	return normalizeScores(scores, false)
+}
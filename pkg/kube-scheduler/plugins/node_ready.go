@@ -0,0 +1,31 @@
+package plugins
+
+import (
+	kubeapi_rest "github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-scheduler/framework"
+)
+
+const NodeReadyName = "node-ready"
+
+// NodeReady keeps pods off the nodes that are not ready or are marked as unschedulable
+type NodeReady struct{}
+
+func (NodeReady) Name() string {
+	return NodeReadyName
+}
+
+func (NodeReady) Filter(pod *kubeapi_rest.Pod, nodeInfo *framework.NodeInfo) *framework.Status {
+	ready := nodeInfo.Node.Condition(kubeapi_rest.NodeReady)
+	if ready == nil || ready.Status != kubeapi_rest.ConditionTrue {
+		return framework.NewStatus("node(s) were not ready")
+	}
+
+	if nodeInfo.Node.Spec.Unschedulable && !pod.ToleratesTaint(kubeapi_rest.Taint{
+		Key:    kubeapi_rest.TaintNodeUnschedulable,
+		Effect: kubeapi_rest.TaintEffectNoSchedule,
+	}) {
+		return framework.NewStatus("node(s) were unschedulable")
+	}
+
+	return nil
+}
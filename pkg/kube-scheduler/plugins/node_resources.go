@@ -0,0 +1,74 @@
+package plugins
+
+import (
+	kubeapi_rest "github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-scheduler/framework"
+)
+
+const (
+	NodeResourcesFitName = "node-resources-fit"
+	LeastAllocatedName   = "least-allocated"
+)
+
+// NodeResourcesFit keeps pods off the nodes without enough allocatable resources left for their requests
+type NodeResourcesFit struct{}
+
+func (NodeResourcesFit) Name() string {
+	return NodeResourcesFitName
+}
+
+func (NodeResourcesFit) Filter(pod *kubeapi_rest.Pod, nodeInfo *framework.NodeInfo) *framework.Status {
+	requests, err := framework.PodRequests(pod)
+	if err != nil {
+		return framework.NewStatus(err.Error())
+	}
+
+	var reasons []string
+
+	if nodeInfo.Requested.Pods+requests.Pods > nodeInfo.Allocatable.Pods {
+		reasons = append(reasons, "Too many pods")
+	}
+
+	if requests.MilliCPU > 0 && nodeInfo.Requested.MilliCPU+requests.MilliCPU > nodeInfo.Allocatable.MilliCPU {
+		reasons = append(reasons, "Insufficient cpu")
+	}
+
+	if requests.Memory > 0 && nodeInfo.Requested.Memory+requests.Memory > nodeInfo.Allocatable.Memory {
+		reasons = append(reasons, "Insufficient memory")
+	}
+
+	if len(reasons) > 0 {
+		return framework.NewStatus(reasons...)
+	}
+
+	return nil
+}
+
+// LeastAllocated prefers the nodes with the largest share of cpu and memory left after placing the pod
+type LeastAllocated struct{}
+
+func (LeastAllocated) Name() string {
+	return LeastAllocatedName
+}
+
+func (LeastAllocated) Score(pod *kubeapi_rest.Pod, nodeInfos []*framework.NodeInfo) []int64 {
+	requests, _ := framework.PodRequests(pod)
+
+	scores := make([]int64, len(nodeInfos))
+	for index, nodeInfo := range nodeInfos {
+		cpuScore := leastRequestedScore(nodeInfo.Requested.MilliCPU+requests.MilliCPU, nodeInfo.Allocatable.MilliCPU)
+		memoryScore := leastRequestedScore(nodeInfo.Requested.Memory+requests.Memory, nodeInfo.Allocatable.Memory)
+
+		scores[index] = (cpuScore + memoryScore) / 2
+	}
+
+	return scores
+}
+
+func leastRequestedScore(requested int64, allocatable int64) int64 {
+	if allocatable <= 0 || requested > allocatable {
+		return 0
+	}
+
+	return (allocatable - requested) * framework.MaxNodeScore / allocatable
+}
@@ -0,0 +1,102 @@
+package plugins
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-scheduler/framework"
+)
+
+// DefaultScorePlugins is the default value of the score plugins config
+const DefaultScorePlugins = "least-allocated=1,spreading=1,node-affinity=1,taint-toleration=1"
+
+var scorePluginRegistry = map[string]func() framework.ScorePlugin{
+	LeastAllocatedName:  func() framework.ScorePlugin { return LeastAllocated{} },
+	SpreadingName:       func() framework.ScorePlugin { return Spreading{} },
+	NodeAffinityName:    func() framework.ScorePlugin { return NodeAffinity{} },
+	TaintTolerationName: func() framework.ScorePlugin { return TaintToleration{} },
+}
+
+// FilterPlugins returns the filters every pod goes through, a node must pass all of them
+func FilterPlugins() []framework.FilterPlugin {
+	return []framework.FilterPlugin{
+		NodeReady{},
+		NodeResourcesFit{},
+		NodeAffinity{},
+		TaintToleration{},
+	}
+}
+
+// ScorePluginNames returns the names of the registered score plugins
+func ScorePluginNames() []string {
+	names := make([]string, 0, len(scorePluginRegistry))
+	for name := range scorePluginRegistry {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// ParseScorePlugins parses a comma separated list of name=weight, for example least-allocated=2,spreading=1
+func ParseScorePlugins(config string) ([]framework.WeightedScorePlugin, error) {
+	var scorePlugins []framework.WeightedScorePlugin
+
+	for _, entry := range strings.Split(config, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, weightString, found := strings.Cut(entry, "=")
+		weight := int64(1)
+
+		if found {
+			var err error
+
+			weight, err = strconv.ParseInt(weightString, 10, 64)
+			if err != nil || weight < 0 {
+				return nil, fmt.Errorf("invalid weight of score plugin %s: %q", name, weightString)
+			}
+		}
+
+		newPlugin, ok := scorePluginRegistry[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown score plugin %s, the plugins are %s", name, strings.Join(ScorePluginNames(), ", "))
+		}
+
+		scorePlugins = append(scorePlugins, framework.WeightedScorePlugin{
+			ScorePlugin: newPlugin(),
+			Weight:      weight,
+		})
+	}
+
+	return scorePlugins, nil
+}
+
+// normalizeScores scales the raw scores to 0 to MaxNodeScore by the highest raw score,
+// reverse gives the highest score to the lowest raw score
+func normalizeScores(scores []int64, reverse bool) []int64 {
+	var maxScore int64
+	for _, score := range scores {
+		maxScore = max(maxScore, score)
+	}
+
+	for index, score := range scores {
+		switch {
+		case maxScore == 0 && reverse:
+			scores[index] = framework.MaxNodeScore
+		case maxScore == 0:
+			scores[index] = 0
+		case reverse:
+			scores[index] = (maxScore - score) * framework.MaxNodeScore / maxScore
+		default:
+			scores[index] = score * framework.MaxNodeScore / maxScore
+		}
+	}
+
+	return scores
+}
@@ -0,0 +1,37 @@
+package plugins
+
+import (
+	"reflect"
+
+	kubeapi_rest "github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-scheduler/framework"
+)
+
+const SpreadingName = "spreading"
+
+// Spreading prefers the nodes running fewer pods of the same app, the pods in the same namespace
+// with the same labels, so a node going down takes as few of them as possible
+type Spreading struct{}
+
+func (Spreading) Name() string {
+	return SpreadingName
+}
+
+func (Spreading) Score(pod *kubeapi_rest.Pod, nodeInfos []*framework.NodeInfo) []int64 {
+	scores := make([]int64, len(nodeInfos))
+
+	if len(pod.Metadata.Labels) == 0 {
+		return scores
+	}
+
+	for index, nodeInfo := range nodeInfos {
+		for _, nodePod := range nodeInfo.Pods {
+			if nodePod.Metadata.Namespace == pod.Metadata.Namespace &&
+				reflect.DeepEqual(nodePod.Metadata.Labels, pod.Metadata.Labels) {
+				scores[index]++
+			}
+		}
+	}
+
+	return normalizeScores(scores, true)
+}
@@ -0,0 +1,46 @@
+package plugins
+
+import (
+	"fmt"
+
+	kubeapi_rest "github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-scheduler/framework"
+)
+
+const TaintTolerationName = "taint-toleration"
+
+// TaintToleration keeps pods off the nodes with NoSchedule and NoExecute taints they do not tolerate,
+// and prefers the nodes with fewer PreferNoSchedule taints they do not tolerate
+type TaintToleration struct{}
+
+func (TaintToleration) Name() string {
+	return TaintTolerationName
+}
+
+func (TaintToleration) Filter(pod *kubeapi_rest.Pod, nodeInfo *framework.NodeInfo) *framework.Status {
+	for _, taint := range nodeInfo.Node.Spec.Taints {
+		if taint.Effect == kubeapi_rest.TaintEffectPreferNoSchedule {
+			continue
+		}
+
+		if !pod.ToleratesTaint(taint) {
+			return framework.NewStatus(fmt.Sprintf("node(s) had untolerated taint {%s: %s}", taint.Key, taint.Value))
+		}
+	}
+
+	return nil
+}
+
+func (TaintToleration) Score(pod *kubeapi_rest.Pod, nodeInfos []*framework.NodeInfo) []int64 {
+	scores := make([]int64, len(nodeInfos))
+
+	for index, nodeInfo := range nodeInfos {
+		for _, taint := range nodeInfo.Node.Spec.Taints {
+			if taint.Effect == kubeapi_rest.TaintEffectPreferNoSchedule && !pod.ToleratesTaint(taint) {
+				scores[index]++
+			}
+		}
+	}
+
+	return normalizeScores(scores, true)
+}
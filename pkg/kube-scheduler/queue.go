@@ -0,0 +1,100 @@
+package kubescheduler
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	initialBackoff = time.Second
+	maxBackoff     = 30 * time.Second
+)
+
+// schedulingQueue holds the keys of the pods waiting to be scheduled, a key is in the queue at most once
+type schedulingQueue struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	active []string
+	queued map[string]bool
+	// backoffs are the next backoff of the pods that failed to schedule
+	backoffs map[string]time.Duration
+	// shuttingDown wakes up Pop, no keys are added after it is set
+	shuttingDown bool
+}
+
+func newSchedulingQueue() *schedulingQueue {
+	queue := &schedulingQueue{
+		queued:   make(map[string]bool),
+		backoffs: make(map[string]time.Duration),
+	}
+	queue.cond = sync.NewCond(&queue.mu)
+
+	return queue
+}
+
+func (queue *schedulingQueue) Add(key string) {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	if queue.queued[key] || queue.shuttingDown {
+		return
+	}
+
+	queue.queued[key] = true
+	queue.active = append(queue.active, key)
+	queue.cond.Signal()
+}
+
+// AddWithBackoff adds the key again after a backoff that doubles on every failure of the pod
+func (queue *schedulingQueue) AddWithBackoff(key string) time.Duration {
+	queue.mu.Lock()
+	backoff, ok := queue.backoffs[key]
+	if !ok {
+		backoff = initialBackoff
+	}
+	queue.backoffs[key] = min(backoff*2, maxBackoff)
+	queue.mu.Unlock()
+
+	time.AfterFunc(backoff, func() {
+		queue.Add(key)
+	})
+
+	return backoff
+}
+
+// Forget resets the backoff of a pod that was scheduled or deleted
+func (queue *schedulingQueue) Forget(key string) {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	delete(queue.backoffs, key)
+}
+
+// Pop blocks until a key is in the queue and removes it, it returns false once the queue is shut down
+func (queue *schedulingQueue) Pop() (string, bool) {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	for len(queue.active) == 0 && !queue.shuttingDown {
+		queue.cond.Wait()
+	}
+
+	if queue.shuttingDown {
+		return "", false
+	}
+
+	key := queue.active[0]
+	queue.active = queue.active[1:]
+	delete(queue.queued, key)
+
+	return key, true
+}
+
+// ShutDown wakes up the callers of Pop and makes them return, the keys left in the queue are dropped
+func (queue *schedulingQueue) ShutDown() {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	queue.shuttingDown = true
+	queue.cond.Broadcast()
+}
@@ -0,0 +1,361 @@
+package kubescheduler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	kubeapi_rest "github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-scheduler/framework"
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-scheduler/plugins"
+	"github.com/jonatan5524/own-kubernetes/pkg/leaderelection"
+	"github.com/jonatan5524/own-kubernetes/pkg/record"
+	"github.com/jonatan5524/own-kubernetes/pkg/utils"
+)
+
+const (
+	schedulerName      = "default-scheduler"
+	watchRetryInterval = 5 * time.Second
+)
+
+var errBindingConflict = errors.New("pod is already bound")
+
+type KubeScheduler interface {
+	Run() error
+	Setup() error
+	Stop() error
+}
+
+type Options struct {
+	// ScorePlugins is a comma separated list of score plugin name=weight
+	ScorePlugins string
+
+	LeaderElection leaderelection.Options
+}
+
+type Scheduler struct {
+	kubeAPIEndpoint string
+	options         Options
+
+	filterPlugins []framework.FilterPlugin
+	scorePlugins  []framework.WeightedScorePlugin
+
+	eventRecorder record.EventRecorder
+
+	cache *podCache
+	queue *schedulingQueue
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+func NewKubeScheduler(kubeAPIEndpoint string, options Options) KubeScheduler {
+	return &Scheduler{
+		kubeAPIEndpoint: kubeAPIEndpoint,
+		options:         options,
+		eventRecorder:   record.NopRecorder{},
+		cache:           newPodCache(),
+		queue:           newSchedulingQueue(),
+		stopCh:          make(chan struct{}),
+	}
+}
+
+func (scheduler *Scheduler) Setup() error {
+	log.Println("kube-scheduler setup")
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return fmt.Errorf("hostname not found")
+	}
+
+	utils.SetUserAgent(fmt.Sprintf("kube-scheduler/%s", hostname))
+
+	scheduler.filterPlugins = plugins.FilterPlugins()
+
+	scheduler.scorePlugins, err = plugins.ParseScorePlugins(scheduler.options.ScorePlugins)
+	if err != nil {
+		return err
+	}
+
+	scheduler.eventRecorder = record.NewRecorder(scheduler.kubeAPIEndpoint, kubeapi_rest.EventSource{
+		Component: schedulerName,
+	})
+
+	return nil
+}
+
+func (scheduler *Scheduler) Run() error {
+	log.Println("kube-scheduler running")
+
+	return leaderelection.RunOrDie(scheduler.kubeAPIEndpoint, scheduler.options.LeaderElection, scheduler.run, scheduler.stopCh)
+}
+
+// run watches the pods and schedules the pending ones until the stop channel is closed
+func (scheduler *Scheduler) run(stopCh <-chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		for {
+			if err := scheduler.listAndWatchPods(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("watch on pods stopped: %v", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(watchRetryInterval):
+				log.Printf("restarting watch on pods")
+			}
+		}
+	}()
+
+	scheduling := make(chan struct{})
+	go func() {
+		defer close(scheduling)
+
+		for {
+			key, ok := scheduler.queue.Pop()
+			if !ok {
+				return
+			}
+
+			scheduler.scheduleOne(key)
+		}
+	}()
+
+	<-stopCh
+
+	cancel()
+	scheduler.queue.ShutDown()
+
+	// the pod being scheduled is finished so its binding is not cut in the middle
+	<-scheduling
+}
+
+func (scheduler *Scheduler) Stop() error {
+	scheduler.stopOnce.Do(func() {
+		close(scheduler.stopCh)
+	})
+
+	return nil
+}
+
+// scheduleOne finds the best node for the pod and binds the pod to it, a pod that fits no node is tried again later
+func (scheduler *Scheduler) scheduleOne(key string) {
+	pod, ok := scheduler.cache.get(key)
+	if !ok || !needsScheduling(pod) {
+		return
+	}
+
+	nodes, err := getNodes(scheduler.kubeAPIEndpoint)
+	if err != nil {
+		log.Printf("error getting nodes for pod %s: %v", key, err)
+		scheduler.queue.AddWithBackoff(key)
+
+		return
+	}
+
+	nodeName, err := scheduler.findNode(pod, nodes)
+	if err != nil {
+		backoff := scheduler.queue.AddWithBackoff(key)
+
+		log.Printf("unable to schedule pod %s, retrying in %s: %v", key, backoff, err)
+		scheduler.eventRecorder.Eventf(podReference(pod), kubeapi_rest.EventTypeWarning, "FailedScheduling", "%v", err)
+
+		return
+	}
+
+	if err := bindPod(scheduler.kubeAPIEndpoint, pod, nodeName); err != nil {
+		if errors.Is(err, errBindingConflict) {
+			log.Printf("pod %s was bound by someone else", key)
+			scheduler.queue.Forget(key)
+
+			return
+		}
+
+		log.Printf("error binding pod %s to node %s: %v", key, nodeName, err)
+		scheduler.eventRecorder.Eventf(podReference(pod), kubeapi_rest.EventTypeWarning, "FailedScheduling",
+			"Binding rejected: %v", err)
+		scheduler.queue.AddWithBackoff(key)
+
+		return
+	}
+
+	scheduler.cache.assume(key, nodeName)
+	scheduler.queue.Forget(key)
+
+	log.Printf("pod %s scheduled to node %s", key, nodeName)
+	scheduler.eventRecorder.Eventf(podReference(pod), kubeapi_rest.EventTypeNormal, "Scheduled",
+		"Successfully assigned %s to %s", key, nodeName)
+}
+
+// findNode runs the filters on every node and returns the feasible node with the highest weighted score,
+// the error of a pod that fits no node tells how many nodes failed every filter reason
+func (scheduler *Scheduler) findNode(pod *kubeapi_rest.Pod, nodes []kubeapi_rest.Node) (string, error) {
+	if _, err := framework.PodRequests(pod); err != nil {
+		return "", fmt.Errorf("invalid resource requests: %v", err)
+	}
+
+	podsByNode := scheduler.cache.podsByNode()
+
+	var feasible []*framework.NodeInfo
+	reasonCounts := make(map[string]int)
+
+	for index := range nodes {
+		nodeInfo := framework.NewNodeInfo(&nodes[index], podsByNode[nodes[index].Metadata.Name])
+
+		if status := scheduler.runFilters(pod, nodeInfo); status != nil {
+			for _, reason := range status.Reasons {
+				reasonCounts[reason]++
+			}
+
+			continue
+		}
+
+		feasible = append(feasible, nodeInfo)
+	}
+
+	if len(feasible) == 0 {
+		return "", fmt.Errorf("0/%d nodes are available%s", len(nodes), formatReasons(reasonCounts))
+	}
+
+	totalScores := make([]int64, len(feasible))
+	for _, scorePlugin := range scheduler.scorePlugins {
+		for index, score := range scorePlugin.Score(pod, feasible) {
+			totalScores[index] += score * scorePlugin.Weight
+		}
+	}
+
+	// ties are broken at random so equal nodes get an even share of the pods
+	best := []int{0}
+	for index := 1; index < len(feasible); index++ {
+		switch {
+		case totalScores[index] > totalScores[best[0]]:
+			best = []int{index}
+		case totalScores[index] == totalScores[best[0]]:
+			best = append(best, index)
+		}
+	}
+
+	return feasible[best[rand.Intn(len(best))]].Node.Metadata.Name, nil
+}
+
+func (scheduler *Scheduler) runFilters(pod *kubeapi_rest.Pod, nodeInfo *framework.NodeInfo) *framework.Status {
+	for _, filterPlugin := range scheduler.filterPlugins {
+		if status := filterPlugin.Filter(pod, nodeInfo); status != nil {
+			return status
+		}
+	}
+
+	return nil
+}
+
+// formatReasons formats the reasons like kubernetes, for example ": 1 Insufficient cpu, 2 node(s) were not ready."
+func formatReasons(reasonCounts map[string]int) string {
+	if len(reasonCounts) == 0 {
+		return "."
+	}
+
+	reasons := make([]string, 0, len(reasonCounts))
+	for reason, count := range reasonCounts {
+		reasons = append(reasons, fmt.Sprintf("%d %s", count, reason))
+	}
+
+	sort.Strings(reasons)
+
+	return fmt.Sprintf(": %s.", strings.Join(reasons, ", "))
+}
+
+func podReference(pod *kubeapi_rest.Pod) kubeapi_rest.ObjectReference {
+	return kubeapi_rest.ObjectReference{
+		Kind:      "Pod",
+		Namespace: pod.Metadata.Namespace,
+		Name:      pod.Metadata.Name,
+		UID:       pod.Metadata.UID,
+	}
+}
+
+func getNodes(kubeAPIEndpoint string) ([]kubeapi_rest.Node, error) {
+	var nodes []kubeapi_rest.Node
+
+	resp, err := http.Get(fmt.Sprintf("%s/nodes", kubeAPIEndpoint))
+	if err != nil {
+		return nodes, fmt.Errorf("error sending request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nodes, fmt.Errorf("error reading response body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		if strings.Contains(string(body), "key not found") {
+			return nodes, nil
+		}
+
+		return nodes, fmt.Errorf("request failed with status code: %d %s", resp.StatusCode, string(body))
+	}
+
+	if err = json.Unmarshal(body, &nodes); err != nil {
+		return nodes, fmt.Errorf("error unmarshalling JSON: %v", err)
+	}
+
+	return nodes, nil
+}
+
+func bindPod(kubeAPIEndpoint string, pod *kubeapi_rest.Pod, nodeName string) error {
+	binding := kubeapi_rest.Binding{
+		TypeMeta: kubeapi_rest.TypeMeta{Kind: "Binding"},
+		Metadata: kubeapi_rest.ResourceMetadata{
+			Name:      pod.Metadata.Name,
+			Namespace: pod.Metadata.Namespace,
+		},
+		Target: kubeapi_rest.ObjectReference{
+			Kind: "Node",
+			Name: nodeName,
+		},
+	}
+
+	bindingBytes, err := json.Marshal(binding)
+	if err != nil {
+		return fmt.Errorf("error parsing binding: %v", err)
+	}
+
+	resp, err := http.Post(
+		fmt.Sprintf("%s/namespaces/%s/pods/%s/binding", kubeAPIEndpoint, pod.Metadata.Namespace, pod.Metadata.Name),
+		"application/json",
+		bytes.NewBuffer(bindingBytes),
+	)
+	if err != nil {
+		return fmt.Errorf("error sending binding: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("error reading response body: %v", err)
+		}
+
+		if resp.StatusCode == http.StatusConflict {
+			return fmt.Errorf("%w: %s", errBindingConflict, string(body))
+		}
+
+		return fmt.Errorf("request failed with status code: %d %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
@@ -4,9 +4,13 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sort"
+	"strings"
 	"syscall"
 	"time"
 
+	cgroup1_stats "github.com/containerd/cgroups/v3/cgroup1/stats"
+	cgroup2_stats "github.com/containerd/cgroups/v3/cgroup2/stats"
 	"github.com/containerd/containerd"
 	"github.com/containerd/containerd/cio"
 	"github.com/containerd/containerd/namespaces"
@@ -22,7 +26,7 @@ const (
 	containerdSocketPath = "/run/containerd/containerd.sock"
 	defaultNamespace     = "own-kube"
 	defaultDNSConfig     = "nameserver 8.8.8.8\nnameserver 8.8.4.4\n"
-	defaultEtcHosts      = "127.0.0.1 localhost\n" // TODO: add container ip and container name
+	defaultEtcHosts      = "127.0.0.1 localhost\n"
 )
 
 type CreateContainerSpec struct {
@@ -34,6 +38,14 @@ type CreateContainerSpec struct {
 	IPCNamespacePath     string
 	HostNetwork          bool
 	ContainerID          string
+	// Hostname is written to the hostname file of the container, the container name when not set
+	Hostname string
+	// ConfigMaps are the configmaps referenced by the container env by name, missing ones are not in the map
+	ConfigMaps map[string]kubeapi_rest.ConfigMap
+	// Secrets are the secrets referenced by the container env by name, missing ones are not in the map
+	Secrets map[string]kubeapi_rest.Secret
+	// VolumesLocation is the host directory of every pod volume by name
+	VolumesLocation map[string]string
 }
 
 func containerdConnection() (*containerd.Client, context.Context, error) {
@@ -109,6 +121,48 @@ func DeleteContainer(containerID string) error {
 	return nil
 }
 
+func IsImagePresent(image string) bool {
+	client, ctx, err := containerdConnection()
+	if err != nil {
+		return false
+	}
+	defer client.Close()
+
+	_, err = client.GetImage(ctx, image)
+
+	return err == nil
+}
+
+func PullImage(image string) error {
+	log.Printf("pulling image %s ", image)
+
+	client, ctx, err := containerdConnection()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	_, err = client.Pull(ctx, image, containerd.WithPullUnpack)
+
+	return err
+}
+
+// RuntimeVersion returns the containerd version in the kubernetes format, containerd://<version>
+func RuntimeVersion() (string, error) {
+	client, ctx, err := containerdConnection()
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+
+	version, err := client.Version(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("containerd://%s", version.Version), nil
+}
+
 func CreateContainer(
 	container *kubeapi_rest.Container,
 	createContainerSpec CreateContainerSpec,
@@ -121,10 +175,14 @@ func CreateContainer(
 	}
 	defer client.Close()
 
-	log.Printf("pulling image %s ", container.Image)
-	imageRef, err := client.Pull(ctx, container.Image, containerd.WithPullUnpack)
+	imageRef, err := client.GetImage(ctx, container.Image)
 	if err != nil {
-		return "", err
+		log.Printf("pulling image %s ", container.Image)
+
+		imageRef, err = client.Pull(ctx, container.Image, containerd.WithPullUnpack)
+		if err != nil {
+			return "", err
+		}
 	}
 
 	containerSpec, err := buildContainerSpec(
@@ -158,14 +216,134 @@ func CreateContainer(
 	return containerRef.ID(), nil
 }
 
-func convertEnvToStringSlice(container *kubeapi_rest.Container) []string {
-	env := make([]string, len(container.Env))
+func convertEnvToStringSlice(
+	container *kubeapi_rest.Container,
+	configMaps map[string]kubeapi_rest.ConfigMap,
+	secrets map[string]kubeapi_rest.Secret,
+) ([]string, error) {
+	var env []string
+
+	// same as kubernetes, envFrom comes first so env can override its keys
+	for _, envFrom := range container.EnvFrom {
+		var values map[string][]byte
+
+		switch {
+		case envFrom.ConfigMapRef != nil:
+			configMap, ok := configMaps[envFrom.ConfigMapRef.Name]
+			if !ok {
+				if envFrom.ConfigMapRef.Optional {
+					continue
+				}
+
+				return env, fmt.Errorf("configmap %s not found", envFrom.ConfigMapRef.Name)
+			}
+
+			// binaryData is not valid text for the environment, kubernetes leaves it out too
+			values = make(map[string][]byte, len(configMap.Data))
+			for key, value := range configMap.Data {
+				values[key] = []byte(value)
+			}
+		case envFrom.SecretRef != nil:
+			secret, ok := secrets[envFrom.SecretRef.Name]
+			if !ok {
+				if envFrom.SecretRef.Optional {
+					continue
+				}
+
+				return env, fmt.Errorf("secret %s not found", envFrom.SecretRef.Name)
+			}
+
+			values = secret.Files()
+		default:
+			continue
+		}
+
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			env = append(env, fmt.Sprintf("%s%s=%s", envFrom.Prefix, key, string(values[key])))
+		}
+	}
+
+	for _, envVar := range container.Env {
+		if envVar.ValueFrom == nil || (envVar.ValueFrom.ConfigMapKeyRef == nil && envVar.ValueFrom.SecretKeyRef == nil) {
+			env = append(env, fmt.Sprintf("%s=%s", envVar.Name, envVar.Value))
+
+			continue
+		}
+
+		var (
+			value []byte
+			found bool
+			err   error
+		)
+
+		if keyRef := envVar.ValueFrom.ConfigMapKeyRef; keyRef != nil {
+			value, found, err = configMapKeyValue(configMaps, keyRef)
+		} else {
+			value, found, err = secretKeyValue(secrets, envVar.ValueFrom.SecretKeyRef)
+		}
+
+		if err != nil {
+			return env, fmt.Errorf("%v for env %s", err, envVar.Name)
+		}
+
+		if found {
+			env = append(env, fmt.Sprintf("%s=%s", envVar.Name, string(value)))
+		}
+	}
+
+	return env, nil
+}
+
+// configMapKeyValue returns found false with no error when the key is missing and the selector is optional
+func configMapKeyValue(configMaps map[string]kubeapi_rest.ConfigMap, keyRef *kubeapi_rest.ConfigMapKeySelector) ([]byte, bool, error) {
+	configMap, ok := configMaps[keyRef.Name]
+	if !ok {
+		if keyRef.Optional {
+			return nil, false, nil
+		}
+
+		return nil, false, fmt.Errorf("configmap %s not found", keyRef.Name)
+	}
+
+	value, ok := configMap.Value(keyRef.Key)
+	if !ok {
+		if keyRef.Optional {
+			return nil, false, nil
+		}
+
+		return nil, false, fmt.Errorf("key %s not found in configmap %s", keyRef.Key, keyRef.Name)
+	}
+
+	return value, true, nil
+}
+
+// secretKeyValue returns found false with no error when the key is missing and the selector is optional
+func secretKeyValue(secrets map[string]kubeapi_rest.Secret, keyRef *kubeapi_rest.SecretKeySelector) ([]byte, bool, error) {
+	secret, ok := secrets[keyRef.Name]
+	if !ok {
+		if keyRef.Optional {
+			return nil, false, nil
+		}
 
-	for index, envStruct := range container.Env {
-		env[index] = fmt.Sprintf("%s=%s", envStruct.Name, envStruct.Value)
+		return nil, false, fmt.Errorf("secret %s not found", keyRef.Name)
 	}
 
-	return env
+	value, ok := secret.Value(keyRef.Key)
+	if !ok {
+		if keyRef.Optional {
+			return nil, false, nil
+		}
+
+		return nil, false, fmt.Errorf("key %s not found in secret %s", keyRef.Key, keyRef.Name)
+	}
+
+	return value, true, nil
 }
 
 func startContainer(ctx context.Context, container containerd.Container, logLocation string) error {
@@ -179,7 +357,7 @@ func startContainer(ctx context.Context, container containerd.Container, logLoca
 	return task.Start(ctx)
 }
 
-func getDefaultContainerMounts(container *kubeapi_rest.Container, resolvConfLocation string, hostnameLocation string, etcHostsLocation string) ([]specs.Mount, error) {
+func getDefaultContainerMounts(container *kubeapi_rest.Container, resolvConfLocation string, hostname string, hostnameLocation string, etcHostsLocation string) ([]specs.Mount, error) {
 	var mounts []specs.Mount
 
 	// TODO: currently public dns server and not coredns
@@ -193,7 +371,11 @@ func getDefaultContainerMounts(container *kubeapi_rest.Container, resolvConfLoca
 		Options:     []string{"rbind", "rw"},
 	})
 
-	if err := utils.CreateAndWriteToFile(hostnameLocation, container.Name, 0o644); err != nil {
+	if hostname == "" {
+		hostname = container.Name
+	}
+
+	if err := utils.CreateAndWriteToFile(hostnameLocation, hostname, 0o644); err != nil {
 		return mounts, err
 	}
 	mounts = append(mounts, specs.Mount{
@@ -216,6 +398,43 @@ func getDefaultContainerMounts(container *kubeapi_rest.Container, resolvConfLoca
 	return mounts, nil
 }
 
+// WriteEtcHosts adds the pod to the hosts file shared by its containers once the pod has an ip, the file is
+// rewritten in place so the mounts of the running containers see it
+func WriteEtcHosts(etcHostsLocation string, podIP string, hostnames ...string) error {
+	etcHosts := defaultEtcHosts
+	if podIP != "" {
+		etcHosts += fmt.Sprintf("%s %s\n", podIP, strings.Join(hostnames, " "))
+	}
+
+	return utils.CreateAndWriteToFile(etcHostsLocation, etcHosts, 0o644)
+}
+
+func getVolumeMounts(container *kubeapi_rest.Container, volumesLocation map[string]string) ([]specs.Mount, error) {
+	var mounts []specs.Mount
+
+	for _, volumeMount := range container.VolumeMounts {
+		volumeLocation, ok := volumesLocation[volumeMount.Name]
+		if !ok {
+			return mounts, fmt.Errorf("volume %s of mount %s not found in pod", volumeMount.Name, volumeMount.MountPath)
+		}
+
+		mode := "rw"
+		if volumeMount.ReadOnly {
+			mode = "ro"
+		}
+
+		// the whole directory is mounted and not the files, so updates of the volume are seen by the container
+		mounts = append(mounts, specs.Mount{
+			Source:      volumeLocation,
+			Destination: volumeMount.MountPath,
+			Type:        "bind",
+			Options:     []string{"rbind", mode},
+		})
+	}
+
+	return mounts, nil
+}
+
 func buildContainerSpec(
 	imageRef oci.Image,
 	container *kubeapi_rest.Container,
@@ -226,6 +445,7 @@ func buildContainerSpec(
 	mounts, err := getDefaultContainerMounts(
 		container,
 		createContainerSpec.ResolvConfLocation,
+		createContainerSpec.Hostname,
 		createContainerSpec.HostnameLocation,
 		createContainerSpec.EtcHostsLocation,
 	)
@@ -233,9 +453,20 @@ func buildContainerSpec(
 		return specsOpts, err
 	}
 
+	volumeMounts, err := getVolumeMounts(container, createContainerSpec.VolumesLocation)
+	if err != nil {
+		return specsOpts, err
+	}
+	mounts = append(mounts, volumeMounts...)
+
+	env, err := convertEnvToStringSlice(container, createContainerSpec.ConfigMaps, createContainerSpec.Secrets)
+	if err != nil {
+		return specsOpts, err
+	}
+
 	specsOpts = append(specsOpts,
 		oci.WithImageConfig(imageRef),
-		oci.WithEnv(convertEnvToStringSlice(container)),
+		oci.WithEnv(env),
 		oci.WithMounts(mounts),
 	)
 
@@ -269,6 +500,8 @@ func buildContainerSpec(
 
 	if createContainerSpec.HostNetwork {
 		specsOpts = append(specsOpts, oci.WithHostNamespace(specs.NetworkNamespace))
+	} else if createContainerSpec.Hostname != "" {
+		specsOpts = append(specsOpts, oci.WithHostname(createContainerSpec.Hostname))
 	}
 
 	return specsOpts, nil
@@ -300,29 +533,94 @@ func getContainerCurrentTask(containerID string) (containerd.Task, error) {
 	return containerRef.Task(ctx, cio.Load)
 }
 
-func IsContainerRunning(containerID string) (bool, error) {
-	log.Printf("checking if %s is running", containerID)
+// GetContainerStatus returns the status of the task of the container, the exit status and time are set once
+// the task stopped
+func GetContainerStatus(containerID string) (containerd.Status, error) {
+	log.Printf("checking status of %s", containerID)
 
 	client, ctx, err := containerdConnection()
 	if err != nil {
-		return false, err
+		return containerd.Status{}, err
 	}
 	defer client.Close()
 
 	containerRef, err := client.LoadContainer(ctx, containerID)
 	if err != nil {
-		return false, err
+		return containerd.Status{}, err
 	}
 
 	taskRef, err := containerRef.Task(ctx, cio.NewAttach())
 	if err != nil {
-		return false, err
+		return containerd.Status{}, err
 	}
 
-	status, err := taskRef.Status(ctx)
+	return taskRef.Status(ctx)
+}
+
+// ContainerUsage is the resource usage of a container read from the cgroup of its task
+type ContainerUsage struct {
+	// CPUUsageNanoseconds is the cpu time used since the container started, the cpu usage is its rate
+	CPUUsageNanoseconds uint64
+	// MemoryWorkingSetBytes is the memory used without the inactive page cache, the memory that can not be
+	// reclaimed under pressure
+	MemoryWorkingSetBytes uint64
+}
+
+// GetContainerUsage returns the cpu and memory usage of the container from the metrics of its task, for both
+// cgroup v1 and v2 hosts
+func GetContainerUsage(containerID string) (ContainerUsage, error) {
+	client, ctx, err := containerdConnection()
 	if err != nil {
-		return false, err
+		return ContainerUsage{}, err
+	}
+	defer client.Close()
+
+	containerRef, err := client.LoadContainer(ctx, containerID)
+	if err != nil {
+		return ContainerUsage{}, err
+	}
+
+	taskRef, err := containerRef.Task(ctx, nil)
+	if err != nil {
+		return ContainerUsage{}, err
+	}
+
+	metric, err := taskRef.Metrics(ctx)
+	if err != nil {
+		return ContainerUsage{}, fmt.Errorf("error getting metrics of task: %v", err)
+	}
+
+	cgroupV1Metrics := &cgroup1_stats.Metrics{}
+	if metric.Data.MessageIs(cgroupV1Metrics) {
+		if err := metric.Data.UnmarshalTo(cgroupV1Metrics); err != nil {
+			return ContainerUsage{}, fmt.Errorf("error reading cgroup v1 metrics: %v", err)
+		}
+
+		usage := ContainerUsage{CPUUsageNanoseconds: cgroupV1Metrics.GetCPU().GetUsage().GetTotal()}
+
+		memoryUsage := cgroupV1Metrics.GetMemory().GetUsage().GetUsage()
+		if inactiveFile := cgroupV1Metrics.GetMemory().GetTotalInactiveFile(); inactiveFile < memoryUsage {
+			usage.MemoryWorkingSetBytes = memoryUsage - inactiveFile
+		}
+
+		return usage, nil
+	}
+
+	cgroupV2Metrics := &cgroup2_stats.Metrics{}
+	if metric.Data.MessageIs(cgroupV2Metrics) {
+		if err := metric.Data.UnmarshalTo(cgroupV2Metrics); err != nil {
+			return ContainerUsage{}, fmt.Errorf("error reading cgroup v2 metrics: %v", err)
+		}
+
+		usage := ContainerUsage{CPUUsageNanoseconds: cgroupV2Metrics.GetCPU().GetUsageUsec() * uint64(time.Microsecond)}
+
+		memoryUsage := cgroupV2Metrics.GetMemory().GetUsage()
+		if inactiveFile := cgroupV2Metrics.GetMemory().GetInactiveFile(); inactiveFile < memoryUsage {
+			usage.MemoryWorkingSetBytes = memoryUsage - inactiveFile
+		}
+
+		return usage, nil
 	}
 
-	return status.Status == containerd.Running, nil
+	return ContainerUsage{}, fmt.Errorf("unknown metrics type %s", metric.Data.GetTypeUrl())
 }
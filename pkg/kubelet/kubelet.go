@@ -3,12 +3,17 @@ package kubelet
 import (
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"path/filepath"
 	"time"
 
+	kubeapi_rest "github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
 	kubeproxy "github.com/jonatan5524/own-kubernetes/pkg/kube-proxy"
+	"github.com/jonatan5524/own-kubernetes/pkg/kubelet/node"
 	"github.com/jonatan5524/own-kubernetes/pkg/kubelet/pod"
+	"github.com/jonatan5524/own-kubernetes/pkg/kubelet/stats"
+	"github.com/jonatan5524/own-kubernetes/pkg/record"
 	"github.com/jonatan5524/own-kubernetes/pkg/utils"
 )
 
@@ -27,10 +32,12 @@ type KubeletApp struct {
 }
 
 const (
-	defaultSystemManifestPath = "/home/user/kubernetes/manifests"
-	defaultLoggingLocation    = "/home/user/kubernetes/log/kubelet.log"
-	podCIDR                   = "10.244.0.0/16"
-	podBridgeName             = "br0"
+	defaultSystemManifestPath  = "/home/user/kubernetes/manifests"
+	defaultLoggingLocation     = "/home/user/kubernetes/log/kubelet.log"
+	podCIDR                    = "10.244.0.0/16"
+	podBridgeName              = "br0"
+	defaultKubeAPIReadyTimeout = 2 * time.Minute
+	kubeAPIReadyPollInterval   = time.Second
 )
 
 func NewKubelet(kubeAPIEndpoint string) Kubelet {
@@ -51,6 +58,13 @@ func (app *KubeletApp) Setup() error {
 	app.hostname = hostname
 	log.Printf("running on host %s", hostname)
 
+	utils.SetUserAgent(fmt.Sprintf("kubelet/%s", hostname))
+
+	pod.SetEventRecorder(record.NewRecorder(app.kubeAPIEndpoint, kubeapi_rest.EventSource{
+		Component: "kubelet",
+		Host:      hostname,
+	}))
+
 	if err = utils.CreateDirectory(filepath.Dir(app.loggingLocation), 0o644); err != nil {
 		return fmt.Errorf("unable to create log file location %v", err)
 	}
@@ -70,19 +84,28 @@ func (app *KubeletApp) Setup() error {
 		return err
 	}
 
+	nodeRes, err := node.NewNode(hostname, podCIDR)
+	if err != nil {
+		return fmt.Errorf("error collecting node info: %v", err)
+	}
+
+	// the kube api may be one of the system pods of this kubelet, registration waits for it in the background
+	go node.Run(app.kubeAPIEndpoint, nodeRes)
+
 	return nil
 }
 
 func (app *KubeletApp) Run() error {
 	log.Println("kubelet running")
 
-	pods, err := readAndStartSystemManifests(app.systemManifestPath, app.hostname)
+	pods, err := readAndStartSystemManifests(app.systemManifestPath, app.hostname, app.kubeAPIEndpoint)
 	if err != nil {
 		return fmt.Errorf("%v", err)
 	}
 
-	// TODO: switch later for wait for kube-api to be ready
-	time.Sleep(5 * time.Second)
+	if err := waitForKubeAPIReady(app.kubeAPIEndpoint, defaultKubeAPIReadyTimeout); err != nil {
+		return err
+	}
 
 	if len(pods) > 0 {
 		for _, podRes := range pods {
@@ -98,13 +121,64 @@ func (app *KubeletApp) Run() error {
 
 	go pod.Reconcile(app.kubeAPIEndpoint, app.hostname)
 
-	if err := pod.ListenForPod(app.kubeAPIEndpoint, app.hostname, podCIDR, podBridgeName); err != nil {
-		return fmt.Errorf("%v", err)
+	go app.listenForVolumeSources("configmaps", pod.ListenForConfigMaps)
+
+	go app.listenForVolumeSources("secrets", pod.ListenForSecrets)
+
+	go stats.Run(app.kubeAPIEndpoint, app.hostname)
+
+	for {
+		if err := pod.ListenForPod(app.kubeAPIEndpoint, app.hostname, podCIDR, podBridgeName); err != nil {
+			log.Printf("watch on pods stopped: %v", err)
+		}
+
+		// the api ends watches when it shuts down, wait for it to come back before watching again
+		if err := waitForKubeAPIReady(app.kubeAPIEndpoint, defaultKubeAPIReadyTimeout); err != nil {
+			return err
+		}
 	}
+}
 
-	return nil
+func (app *KubeletApp) listenForVolumeSources(kind string, listen func(string) error) {
+	for {
+		if err := listen(app.kubeAPIEndpoint); err != nil {
+			log.Printf("watch on %s stopped: %v", kind, err)
+		}
+
+		if err := waitForKubeAPIReady(app.kubeAPIEndpoint, defaultKubeAPIReadyTimeout); err != nil {
+			log.Printf("error waiting for kube api: %v", err)
+		}
+	}
 }
 
 func (app *KubeletApp) Stop() error {
 	return app.logFile.Close()
 }
+
+func waitForKubeAPIReady(kubeAPIEndpoint string, timeout time.Duration) error {
+	log.Printf("waiting for kube api %s to be ready", kubeAPIEndpoint)
+
+	client := &http.Client{Timeout: kubeAPIReadyPollInterval}
+	deadline := time.Now().Add(timeout)
+
+	for time.Now().Before(deadline) {
+		resp, err := client.Get(fmt.Sprintf("%s/readyz", kubeAPIEndpoint))
+		if err == nil {
+			resp.Body.Close()
+
+			if resp.StatusCode == http.StatusOK {
+				log.Printf("kube api is ready")
+
+				return nil
+			}
+
+			log.Printf("kube api is not ready yet, status code: %d", resp.StatusCode)
+		} else {
+			log.Printf("kube api is not reachable yet: %v", err)
+		}
+
+		time.Sleep(kubeAPIReadyPollInterval)
+	}
+
+	return fmt.Errorf("kube api was not ready after %s", timeout)
+}
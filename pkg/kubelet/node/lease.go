@@ -0,0 +1,141 @@
+package node
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	kubeapi_rest "github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
+)
+
+const (
+	// LeaseDurationSeconds is how long a node is considered alive after its last lease renewal
+	LeaseDurationSeconds = 40
+	leaseRenewInterval   = LeaseDurationSeconds * time.Second / 4
+)
+
+// renewLease is the heartbeat of the node, the lease is much cheaper to update than the whole node status
+func renewLease(kubeAPIEndpoint string, nodeName string) {
+	var lease *kubeapi_rest.Lease
+
+	ticker := time.NewTicker(leaseRenewInterval)
+	defer ticker.Stop()
+
+	for ; ; <-ticker.C {
+		if lease == nil {
+			var err error
+
+			lease, err = getOrCreateLease(kubeAPIEndpoint, nodeName)
+			if err != nil {
+				log.Printf("error getting lease of node %s: %v", nodeName, err)
+
+				continue
+			}
+		}
+
+		// the kubelet is the only writer of its lease, the renewal does not need to check the resource version
+		lease.Metadata.ResourceVersion = ""
+		lease.Spec.HolderIdentity = nodeName
+		lease.Spec.LeaseDurationSeconds = LeaseDurationSeconds
+		lease.Spec.RenewTime = time.Now().Format(time.RFC3339)
+
+		if err := sendLease(kubeAPIEndpoint, http.MethodPatch, lease); err != nil {
+			log.Printf("error renewing lease of node %s: %v", nodeName, err)
+
+			// the lease may have been deleted, it is looked up again on the next renewal
+			lease = nil
+		}
+	}
+}
+
+func getOrCreateLease(kubeAPIEndpoint string, nodeName string) (*kubeapi_rest.Lease, error) {
+	resp, err := http.Get(fmt.Sprintf("%s/namespaces/%s/leases/%s", kubeAPIEndpoint, kubeapi_rest.NodeLeaseNamespace, nodeName))
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %v", err)
+	}
+
+	if resp.StatusCode == http.StatusOK {
+		var lease kubeapi_rest.Lease
+		if err = json.Unmarshal(body, &lease); err != nil {
+			return nil, fmt.Errorf("error unmarshalling JSON: %v", err)
+		}
+
+		return &lease, nil
+	}
+
+	if !strings.Contains(string(body), "key not found") {
+		return nil, fmt.Errorf("request failed with status code: %d %s", resp.StatusCode, string(body))
+	}
+
+	now := time.Now().Format(time.RFC3339)
+
+	lease := &kubeapi_rest.Lease{
+		TypeMeta: kubeapi_rest.TypeMeta{Kind: "Lease"},
+		Metadata: kubeapi_rest.ResourceMetadata{
+			Name:      nodeName,
+			Namespace: kubeapi_rest.NodeLeaseNamespace,
+		},
+		Spec: kubeapi_rest.LeaseSpec{
+			HolderIdentity:       nodeName,
+			LeaseDurationSeconds: LeaseDurationSeconds,
+			AcquireTime:          now,
+			RenewTime:            now,
+		},
+	}
+
+	if err := sendLease(kubeAPIEndpoint, http.MethodPost, lease); err != nil {
+		return nil, err
+	}
+
+	log.Printf("created lease for node %s", nodeName)
+
+	return lease, nil
+}
+
+func sendLease(kubeAPIEndpoint string, method string, lease *kubeapi_rest.Lease) error {
+	leaseBytes, err := json.Marshal(lease)
+	if err != nil {
+		return fmt.Errorf("error parsing lease: %v", err)
+	}
+
+	url := fmt.Sprintf("%s/namespaces/%s/leases", kubeAPIEndpoint, lease.Metadata.Namespace)
+	if method == http.MethodPatch {
+		url = fmt.Sprintf("%s/%s", url, lease.Metadata.Name)
+	}
+
+	req, err := http.NewRequest(method, url, bytes.NewBuffer(leaseBytes))
+	if err != nil {
+		return fmt.Errorf("error creating request for lease: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending lease: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("error reading response body: %v", err)
+		}
+
+		return fmt.Errorf("request failed with status code: %d %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
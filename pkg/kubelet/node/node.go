@@ -0,0 +1,270 @@
+package node
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	kubeapi_rest "github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
+	kube_containerd "github.com/jonatan5524/own-kubernetes/pkg/kubelet/containerd"
+)
+
+const (
+	HostnameLabel = "kubernetes.io/hostname"
+	OSLabel       = "kubernetes.io/os"
+	ArchLabel     = "kubernetes.io/arch"
+
+	defaultMaxPods = 110
+	// reservedMemoryKi is kept out of the allocatable memory, it is the same as the memory pressure threshold
+	reservedMemoryKi = 100 * 1024
+
+	registerRetryInterval = 5 * time.Second
+
+	memInfoFile   = "/proc/meminfo"
+	osReleaseFile = "/etc/os-release"
+	kernelFile    = "/proc/sys/kernel/osrelease"
+)
+
+// NewNode collects the information of the machine the kubelet runs on
+func NewNode(hostname string, podCIDR string) (*kubeapi_rest.Node, error) {
+	memoryKi, err := readMemInfo("MemTotal")
+	if err != nil {
+		return nil, fmt.Errorf("error reading memory capacity: %v", err)
+	}
+
+	addresses := []kubeapi_rest.NodeAddress{}
+	if internalIP, err := getInternalIP(); err != nil {
+		log.Printf("error finding node internal ip: %v", err)
+	} else {
+		addresses = append(addresses, kubeapi_rest.NodeAddress{Type: kubeapi_rest.NodeInternalIP, Address: internalIP})
+	}
+	addresses = append(addresses, kubeapi_rest.NodeAddress{Type: kubeapi_rest.NodeHostName, Address: hostname})
+
+	runtimeVersion, err := kube_containerd.RuntimeVersion()
+	if err != nil {
+		log.Printf("error getting containerd version: %v", err)
+	}
+
+	kernelVersion, err := os.ReadFile(kernelFile)
+	if err != nil {
+		log.Printf("error reading kernel version: %v", err)
+	}
+
+	capacity := map[string]string{
+		kubeapi_rest.ResourceCPU:    strconv.Itoa(runtime.NumCPU()),
+		kubeapi_rest.ResourceMemory: fmt.Sprintf("%dKi", memoryKi),
+		kubeapi_rest.ResourcePods:   strconv.Itoa(defaultMaxPods),
+	}
+
+	allocatable := map[string]string{
+		kubeapi_rest.ResourceCPU:    capacity[kubeapi_rest.ResourceCPU],
+		kubeapi_rest.ResourceMemory: fmt.Sprintf("%dKi", max(memoryKi-reservedMemoryKi, 0)),
+		kubeapi_rest.ResourcePods:   capacity[kubeapi_rest.ResourcePods],
+	}
+
+	return &kubeapi_rest.Node{
+		TypeMeta: kubeapi_rest.TypeMeta{Kind: "Node"},
+		Metadata: kubeapi_rest.ResourceMetadata{
+			Name: hostname,
+			Labels: map[string]string{
+				HostnameLabel: hostname,
+				OSLabel:       runtime.GOOS,
+				ArchLabel:     runtime.GOARCH,
+			},
+		},
+		Spec: kubeapi_rest.NodeSpec{
+			PodCIDR: podCIDR,
+		},
+		Status: kubeapi_rest.NodeStatus{
+			Capacity:    capacity,
+			Allocatable: allocatable,
+			Addresses:   addresses,
+			NodeInfo: kubeapi_rest.NodeSystemInfo{
+				KernelVersion:           strings.TrimSpace(string(kernelVersion)),
+				OSImage:                 getOSImage(),
+				ContainerRuntimeVersion: runtimeVersion,
+				OperatingSystem:         runtime.GOOS,
+				Architecture:            runtime.GOARCH,
+			},
+		},
+	}, nil
+}
+
+// Run registers the node and then keeps its status and lease up to date, the api may not be up yet when
+// the kubelet starts so registration is retried until it works
+func Run(kubeAPIEndpoint string, node *kubeapi_rest.Node) {
+	for {
+		err := registerNode(kubeAPIEndpoint, node)
+		if err == nil {
+			break
+		}
+
+		log.Printf("error registering node %s, retrying in %s: %v", node.Metadata.Name, registerRetryInterval, err)
+		time.Sleep(registerRetryInterval)
+	}
+
+	log.Printf("node %s registered", node.Metadata.Name)
+
+	go renewLease(kubeAPIEndpoint, node.Metadata.Name)
+
+	updateStatus(kubeAPIEndpoint, node)
+}
+
+func registerNode(kubeAPIEndpoint string, node *kubeapi_rest.Node) error {
+	log.Printf("registering node %s", node.Metadata.Name)
+
+	node.Status.Conditions = nodeConditions(nil)
+
+	// the taints and labels set on the node by users are kept when the kubelet registers again
+	if storedNode, err := getNode(kubeAPIEndpoint, node.Metadata.Name); err == nil {
+		node.Spec.Taints = storedNode.Spec.Taints
+		node.Spec.Unschedulable = storedNode.Spec.Unschedulable
+
+		for key, value := range storedNode.Metadata.Labels {
+			if _, ok := node.Metadata.Labels[key]; !ok {
+				node.Metadata.Labels[key] = value
+			}
+		}
+	}
+
+	nodeBytes, err := json.Marshal(node)
+	if err != nil {
+		return fmt.Errorf("error parsing node: %v", err)
+	}
+
+	resp, err := http.Post(fmt.Sprintf("%s/nodes", kubeAPIEndpoint), "application/json", bytes.NewBuffer(nodeBytes))
+	if err != nil {
+		return fmt.Errorf("error sending node registration: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("error reading response body: %v", err)
+		}
+
+		return fmt.Errorf("request failed with status code: %d %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
+func getNode(kubeAPIEndpoint string, nodeName string) (*kubeapi_rest.Node, error) {
+	resp, err := http.Get(fmt.Sprintf("%s/nodes/%s", kubeAPIEndpoint, nodeName))
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("request failed with status code: %d %s", resp.StatusCode, string(body))
+	}
+
+	var node kubeapi_rest.Node
+	if err = json.Unmarshal(body, &node); err != nil {
+		return nil, fmt.Errorf("error unmarshalling JSON: %v", err)
+	}
+
+	return &node, nil
+}
+
+func updateNodeStatus(kubeAPIEndpoint string, nodeName string, nodeStatus kubeapi_rest.NodeStatus) error {
+	nodeStatusBytes, err := json.Marshal(nodeStatus)
+	if err != nil {
+		return fmt.Errorf("error parsing node status: %v", err)
+	}
+
+	req, err := http.NewRequest(
+		http.MethodPatch,
+		fmt.Sprintf("%s/nodes/%s/status", kubeAPIEndpoint, nodeName),
+		bytes.NewBuffer(nodeStatusBytes),
+	)
+	if err != nil {
+		return fmt.Errorf("error creating request for node status update: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending node status update: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("error reading response body: %v", err)
+		}
+
+		return fmt.Errorf("request failed with status code: %d %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
+// getInternalIP returns the source address of the default route, no packet is sent for a udp dial
+func getInternalIP() (string, error) {
+	conn, err := net.Dial("udp", "8.8.8.8:80")
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	return conn.LocalAddr().(*net.UDPAddr).IP.String(), nil
+}
+
+func getOSImage() string {
+	file, err := os.Open(osReleaseFile)
+	if err != nil {
+		return runtime.GOOS
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(scanner.Text(), "PRETTY_NAME="); ok {
+			return strings.Trim(value, `"`)
+		}
+	}
+
+	return runtime.GOOS
+}
+
+// readMemInfo returns a field of /proc/meminfo in Ki
+func readMemInfo(field string) (int64, error) {
+	file, err := os.Open(memInfoFile)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != field+":" {
+			continue
+		}
+
+		return strconv.ParseInt(fields[1], 10, 64)
+	}
+
+	return 0, fmt.Errorf("%s not found in %s", field, memInfoFile)
+}
@@ -0,0 +1,218 @@
+package node
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	kubeapi_rest "github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
+)
+
+const (
+	// nodeStatusUpdateFrequency is how often the conditions are checked, a changed status is posted right away
+	nodeStatusUpdateFrequency = 10 * time.Second
+	// nodeStatusReportFrequency is how often an unchanged status is posted, the lease is the heartbeat in between
+	nodeStatusReportFrequency = time.Minute
+
+	// same defaults as the kubelet hard eviction thresholds
+	memoryAvailableThresholdKi = 100 * 1024
+	diskAvailableThreshold     = 0.10
+	pidAvailableThreshold      = 0.10
+
+	pidMaxFile = "/proc/sys/kernel/pid_max"
+	procDir    = "/proc"
+	rootFSPath = "/"
+)
+
+type pressureCheck struct {
+	conditionType string
+	check         func() (bool, error)
+	// reasons are for the condition status False and True
+	falseReason  string
+	falseMessage string
+	trueReason   string
+	trueMessage  string
+}
+
+var pressureChecks = []pressureCheck{
+	{
+		conditionType: kubeapi_rest.NodeMemoryPressure,
+		check:         hasMemoryPressure,
+		falseReason:   "KubeletHasSufficientMemory",
+		falseMessage:  "kubelet has sufficient memory available",
+		trueReason:    "KubeletHasInsufficientMemory",
+		trueMessage:   "kubelet has insufficient memory available",
+	},
+	{
+		conditionType: kubeapi_rest.NodeDiskPressure,
+		check:         hasDiskPressure,
+		falseReason:   "KubeletHasNoDiskPressure",
+		falseMessage:  "kubelet has no disk pressure",
+		trueReason:    "KubeletHasDiskPressure",
+		trueMessage:   "kubelet has disk pressure",
+	},
+	{
+		conditionType: kubeapi_rest.NodePIDPressure,
+		check:         hasPIDPressure,
+		falseReason:   "KubeletHasSufficientPID",
+		falseMessage:  "kubelet has sufficient PID available",
+		trueReason:    "KubeletHasInsufficientPID",
+		trueMessage:   "kubelet has insufficient PID available",
+	},
+}
+
+// nodeConditions checks the node and returns its conditions, a condition keeps its transition time from previous
+// when its status did not change
+func nodeConditions(previous []kubeapi_rest.NodeCondition) []kubeapi_rest.NodeCondition {
+	now := time.Now().Format(time.RFC3339)
+
+	conditions := []kubeapi_rest.NodeCondition{
+		{
+			Type:    kubeapi_rest.NodeReady,
+			Status:  kubeapi_rest.ConditionTrue,
+			Reason:  "KubeletReady",
+			Message: "kubelet is posting ready status",
+		},
+	}
+
+	for _, pressure := range pressureChecks {
+		condition := kubeapi_rest.NodeCondition{
+			Type:    pressure.conditionType,
+			Status:  kubeapi_rest.ConditionFalse,
+			Reason:  pressure.falseReason,
+			Message: pressure.falseMessage,
+		}
+
+		underPressure, err := pressure.check()
+		switch {
+		case err != nil:
+			log.Printf("error checking %s: %v", pressure.conditionType, err)
+
+			condition.Status = kubeapi_rest.ConditionUnknown
+			condition.Reason = "NodeStatusUnknown"
+			condition.Message = err.Error()
+		case underPressure:
+			condition.Status = kubeapi_rest.ConditionTrue
+			condition.Reason = pressure.trueReason
+			condition.Message = pressure.trueMessage
+		}
+
+		conditions = append(conditions, condition)
+	}
+
+	for index := range conditions {
+		conditions[index].LastHeartbeatTime = now
+		conditions[index].LastTransitionTime = now
+
+		for _, previousCondition := range previous {
+			if previousCondition.Type == conditions[index].Type && previousCondition.Status == conditions[index].Status {
+				conditions[index].LastTransitionTime = previousCondition.LastTransitionTime
+			}
+		}
+	}
+
+	return conditions
+}
+
+// conditionsChanged compares the conditions without the heartbeat time, which changes on every check
+func conditionsChanged(previous []kubeapi_rest.NodeCondition, current []kubeapi_rest.NodeCondition) bool {
+	strip := func(conditions []kubeapi_rest.NodeCondition) []kubeapi_rest.NodeCondition {
+		stripped := make([]kubeapi_rest.NodeCondition, len(conditions))
+		for index, condition := range conditions {
+			condition.LastHeartbeatTime = ""
+			stripped[index] = condition
+		}
+
+		return stripped
+	}
+
+	return !reflect.DeepEqual(strip(previous), strip(current))
+}
+
+func updateStatus(kubeAPIEndpoint string, node *kubeapi_rest.Node) {
+	lastReport := time.Now()
+
+	ticker := time.NewTicker(nodeStatusUpdateFrequency)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		// the node lifecycle controller sets the conditions to Unknown when it misses heartbeats,
+		// comparing with the stored node posts the real conditions again right away
+		if storedNode, err := getNode(kubeAPIEndpoint, node.Metadata.Name); err != nil {
+			log.Printf("error getting node %s: %v", node.Metadata.Name, err)
+		} else {
+			node.Status.Conditions = storedNode.Status.Conditions
+		}
+
+		conditions := nodeConditions(node.Status.Conditions)
+
+		if !conditionsChanged(node.Status.Conditions, conditions) && time.Since(lastReport) < nodeStatusReportFrequency {
+			continue
+		}
+
+		nodeStatus := node.Status
+		nodeStatus.Conditions = conditions
+
+		if err := updateNodeStatus(kubeAPIEndpoint, node.Metadata.Name, nodeStatus); err != nil {
+			log.Printf("error updating node %s status: %v", node.Metadata.Name, err)
+
+			continue
+		}
+
+		node.Status = nodeStatus
+		lastReport = time.Now()
+	}
+}
+
+func hasMemoryPressure() (bool, error) {
+	availableKi, err := readMemInfo("MemAvailable")
+	if err != nil {
+		return false, err
+	}
+
+	return availableKi < memoryAvailableThresholdKi, nil
+}
+
+func hasDiskPressure() (bool, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(rootFSPath, &stat); err != nil {
+		return false, fmt.Errorf("error reading file system stats: %v", err)
+	}
+
+	if stat.Blocks == 0 {
+		return false, nil
+	}
+
+	return float64(stat.Bavail)/float64(stat.Blocks) < diskAvailableThreshold, nil
+}
+
+func hasPIDPressure() (bool, error) {
+	pidMaxBytes, err := os.ReadFile(pidMaxFile)
+	if err != nil {
+		return false, err
+	}
+
+	pidMax, err := strconv.Atoi(strings.TrimSpace(string(pidMaxBytes)))
+	if err != nil {
+		return false, fmt.Errorf("error parsing %s: %v", pidMaxFile, err)
+	}
+
+	entries, err := os.ReadDir(procDir)
+	if err != nil {
+		return false, err
+	}
+
+	processes := 0
+	for _, entry := range entries {
+		if _, err := strconv.Atoi(entry.Name()); err == nil {
+			processes++
+		}
+	}
+
+	return float64(pidMax-processes)/float64(pidMax) < pidAvailableThreshold, nil
+}
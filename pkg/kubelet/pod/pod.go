@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -13,6 +14,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/containerd/containerd"
 	"gopkg.in/yaml.v3"
 
 	"github.com/google/uuid"
@@ -20,6 +22,7 @@ import (
 	kubeapi_rest "github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
 	kube_containerd "github.com/jonatan5524/own-kubernetes/pkg/kubelet/containerd"
 	kubelet_net "github.com/jonatan5524/own-kubernetes/pkg/kubelet/net"
+	"github.com/jonatan5524/own-kubernetes/pkg/record"
 	"github.com/jonatan5524/own-kubernetes/pkg/utils"
 )
 
@@ -34,12 +37,37 @@ const (
 	defaultIPCNamespacePath    = "/proc/%d/ns/ipc"
 	podRunningPhase            = "Running"
 	podFailedPhase             = "Failed"
+	podSucceededPhase          = "Succeeded"
 	podPendingPhase            = "Pending"
 	podTerminatingPhase        = "Terminating"
 	podUnknownPhase            = "Unknown"
 	defaultReconcileTimeout    = 30
+	containerCompletedReason   = "Completed"
+	containerErrorReason       = "Error"
 )
 
+var eventRecorder record.EventRecorder = record.NopRecorder{}
+
+func SetEventRecorder(recorder record.EventRecorder) {
+	eventRecorder = recorder
+}
+
+func podReference(pod kubeapi_rest.Pod) kubeapi_rest.ObjectReference {
+	return kubeapi_rest.ObjectReference{
+		Kind:      "Pod",
+		Namespace: pod.Metadata.Namespace,
+		Name:      pod.Metadata.Name,
+		UID:       pod.Metadata.UID,
+	}
+}
+
+func containerReference(pod kubeapi_rest.Pod, containerName string) kubeapi_rest.ObjectReference {
+	reference := podReference(pod)
+	reference.FieldPath = fmt.Sprintf("spec.containers{%s}", containerName)
+
+	return reference
+}
+
 func UpdatePodStatus(kubeAPIEndpoint string, podName string, namespace string, podStatus kubeapi_rest.PodStatus) error {
 	log.Printf("update pod %s status for api", podName)
 
@@ -175,6 +203,10 @@ func ListenForPod(kubeAPIEndpoint string, hostname string, podCIDR string, podBr
 	for {
 		line, err := reader.ReadString('\n')
 		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return fmt.Errorf("watch stream closed by api")
+			}
+
 			log.Printf("error parsing response: %v", err)
 
 			continue
@@ -193,6 +225,10 @@ func ListenForPod(kubeAPIEndpoint string, hostname string, podCIDR string, podBr
 			continue
 		}
 
+		if typeEvent == kubeapi_rest.WatchErrorEventType {
+			return fmt.Errorf("watch ended by api: %s", value)
+		}
+
 		log.Printf("pod  event for pods: %s %s", typeEvent, value)
 
 		var pod kubeapi_rest.Pod
@@ -238,17 +274,34 @@ func Reconcile(kubeAPIEndpoint string, hostname string) {
 		if err != nil {
 			log.Printf("error getting pods from api %v", err)
 
+			// without waiting a failing api would be hammered by this loop
+			time.Sleep(time.Second * defaultReconcileTimeout)
+
 			continue
 		}
 
 		for _, pod := range pods {
-			newPhase, err := getStatus(pod)
+			newPhase, containerStatuses, err := getStatus(pod)
 			if err != nil {
 				log.Printf("error figuring out pod status %v", err)
 			}
 
-			if pod.Status.Phase != podTerminatingPhase && newPhase != pod.Status.Phase {
+			if pod.Status.Phase == podTerminatingPhase {
+				continue
+			}
+
+			// the node lifecycle controller marks the pods as not ready while the node is unreachable,
+			// so the ready condition is posted again even when the phase did not change
+			readyChanged := setReadyCondition(&pod, newPhase)
+			containersChanged := !reflect.DeepEqual(containerStatuses, pod.Status.ContainerStatuses)
+
+			if newPhase != pod.Status.Phase || readyChanged || containersChanged {
+				if newPhase == podFailedPhase && newPhase != pod.Status.Phase {
+					eventRecorder.Eventf(podReference(pod), kubeapi_rest.EventTypeWarning, "Failed", "Pod has a container that exited with an error")
+				}
+
 				pod.Status.Phase = newPhase
+				pod.Status.ContainerStatuses = containerStatuses
 
 				if err := UpdatePodStatus(kubeAPIEndpoint,
 					pod.Metadata.Name,
@@ -266,19 +319,83 @@ func Reconcile(kubeAPIEndpoint string, hostname string) {
 	}
 }
 
-func getStatus(pod rest.Pod) (string, error) {
+// setReadyCondition sets the ready condition of the pod by its phase and returns if the condition changed
+func setReadyCondition(pod *kubeapi_rest.Pod, phase string) bool {
+	condition := kubeapi_rest.PodCondition{
+		Type:               kubeapi_rest.PodReady,
+		Status:             kubeapi_rest.ConditionTrue,
+		LastTransitionTime: time.Now().Format(time.RFC3339),
+	}
+
+	if phase != podRunningPhase {
+		condition.Status = kubeapi_rest.ConditionFalse
+		condition.Reason = "ContainersNotReady"
+		condition.Message = fmt.Sprintf("pod phase is %s", phase)
+	}
+
+	previous := pod.Condition(kubeapi_rest.PodReady)
+	changed := previous == nil || previous.Status != condition.Status
+
+	pod.SetCondition(condition)
+
+	return changed
+}
+
+// getStatus returns the phase of the pod by the state of its containers and the container statuses with the
+// exit code of the containers that exited. The pod failed once a container exited with an error and succeeded
+// once all of them exited successfully, its phase is unknown while the state of a container is unknown
+func getStatus(pod rest.Pod) (string, []rest.ContainerStatus, error) {
+	containerStatuses := make([]rest.ContainerStatus, 0, len(pod.Status.ContainerStatuses))
+	failed := false
+	running := false
+	unknown := false
+
 	for _, containerStatus := range pod.Status.ContainerStatuses {
-		isRunning, err := kube_containerd.IsContainerRunning(containerStatus.ContainerID)
+		status, err := kube_containerd.GetContainerStatus(containerStatus.ContainerID)
 		if err != nil {
-			return podUnknownPhase, err
+			return podUnknownPhase, pod.Status.ContainerStatuses, err
 		}
 
-		if !isRunning {
-			return podFailedPhase, nil
+		containerStatus.State = rest.ContainerState{}
+
+		switch status.Status {
+		// a created task is about to be started, it is not reported as pending since a pending pod is created
+		// again by the kubelet. A paused task is still alive
+		case containerd.Running, containerd.Created, containerd.Paused, containerd.Pausing:
+			running = true
+		case containerd.Stopped:
+			terminated := &rest.ContainerStateTerminated{
+				ExitCode:   int(status.ExitStatus),
+				Reason:     containerCompletedReason,
+				FinishedAt: status.ExitTime.Format(time.RFC3339),
+			}
+
+			if status.ExitStatus != 0 {
+				terminated.Reason = containerErrorReason
+				failed = true
+			}
+
+			containerStatus.State.Terminated = terminated
+		default:
+			unknown = true
 		}
+
+		containerStatuses = append(containerStatuses, containerStatus)
+	}
+
+	if failed {
+		return podFailedPhase, containerStatuses, nil
+	}
+
+	if unknown {
+		return podUnknownPhase, containerStatuses, nil
 	}
 
-	return podRunningPhase, nil
+	if !running && len(containerStatuses) > 0 {
+		return podSucceededPhase, containerStatuses, nil
+	}
+
+	return podRunningPhase, containerStatuses, nil
 }
 
 func getPods(kubeAPIEndpoint string, hostname string) ([]rest.Pod, error) {
@@ -325,9 +442,17 @@ func getPods(kubeAPIEndpoint string, hostname string) ([]rest.Pod, error) {
 func deletePod(pod kubeapi_rest.Pod, kubeAPIEndpoint string) {
 	log.Printf("started deleting pod %s/%s", pod.Metadata.Namespace, pod.Metadata.Name)
 
-	for _, containerStatus := range pod.Status.ContainerStatuses {
+	for index, containerStatus := range pod.Status.ContainerStatuses {
+		containerName := containerStatus.Name
+		if index < len(pod.Spec.Containers) {
+			containerName = pod.Spec.Containers[index].Name
+		}
+
+		eventRecorder.Eventf(containerReference(pod, containerName), kubeapi_rest.EventTypeNormal, "Killing", "Stopping container %s", containerName)
+
 		if err := kube_containerd.DeleteContainer(containerStatus.ContainerID); err != nil {
 			log.Printf("error deleting container %v", err)
+			eventRecorder.Eventf(podReference(pod), kubeapi_rest.EventTypeWarning, "FailedKillPod", "error killing pod: %v", err)
 
 			return
 		}
@@ -335,11 +460,14 @@ func deletePod(pod kubeapi_rest.Pod, kubeAPIEndpoint string) {
 		// Pause container
 		if err := kube_containerd.DeleteContainer(pod.Metadata.UID); err != nil {
 			log.Printf("error deleting container %v", err)
+			eventRecorder.Eventf(podReference(pod), kubeapi_rest.EventTypeWarning, "FailedKillPod", "error killing pod sandbox: %v", err)
 
 			return
 		}
 	}
 
+	cleanupPodVolumes(pod.Metadata.UID)
+
 	if err := deletePodAPI(kubeAPIEndpoint, pod.Metadata.Namespace, pod.Metadata.Name); err != nil {
 		log.Printf("error sending delete pod to api %v", err)
 
@@ -350,7 +478,7 @@ func deletePod(pod kubeapi_rest.Pod, kubeAPIEndpoint string) {
 func createPod(pod kubeapi_rest.Pod, podCIDR string, podBridgeName string, kubeAPIEndpoint string) {
 	log.Printf("started creating pods %s/%s", pod.Metadata.Namespace, pod.Metadata.Name)
 
-	podRes, err := CreatePodContainers(pod, podCIDR, podBridgeName)
+	podRes, err := CreatePodContainers(pod, podCIDR, podBridgeName, kubeAPIEndpoint)
 	if err != nil {
 		log.Printf("error creating pod: %v", err)
 
@@ -382,11 +510,25 @@ func compareLastAppliedToCurrentPod(podRes kubeapi_rest.Pod) (bool, error) {
 	return reflect.DeepEqual(lastAppliedPodRes, podRes), nil
 }
 
-func CreatePodContainers(pod kubeapi_rest.Pod, podCIDR string, podBridgeName string) (*kubeapi_rest.Pod, error) {
+func CreatePodContainers(pod kubeapi_rest.Pod, podCIDR string, podBridgeName string, kubeAPIEndpoint string) (*kubeapi_rest.Pod, error) {
 	if pod.Metadata.UID == "" {
 		pod.Metadata.UID = uuid.NewString()
 	}
 
+	configMaps, secrets, err := getPodConfigMapsAndSecrets(kubeAPIEndpoint, pod)
+	if err != nil {
+		eventRecorder.Eventf(podReference(pod), kubeapi_rest.EventTypeWarning, "FailedMount", "Unable to get configmaps and secrets: %v", err)
+
+		return nil, fmt.Errorf("unable to get configmaps and secrets of pod %v", err)
+	}
+
+	volumesLocation, err := setupPodVolumes(pod, configMaps, secrets)
+	if err != nil {
+		eventRecorder.Eventf(podReference(pod), kubeapi_rest.EventTypeWarning, "FailedMount", "Unable to mount volumes: %v", err)
+
+		return nil, fmt.Errorf("unable to setup pod volumes %v", err)
+	}
+
 	pauseContainerPID, err := createPauseContainer(
 		pod.Metadata.UID,
 		pod.Metadata.Name,
@@ -394,28 +536,43 @@ func CreatePodContainers(pod kubeapi_rest.Pod, podCIDR string, podBridgeName str
 		pod.Spec.HostNetwork,
 	)
 	if err != nil {
+		eventRecorder.Eventf(podReference(pod), kubeapi_rest.EventTypeWarning, "FailedCreatePodSandBox", "Failed to create pod sandbox: %v", err)
+
 		return nil, fmt.Errorf("unable to create pause container, %v", err)
 	}
 
 	for _, container := range pod.Spec.Containers {
 		containerStatusName := fmt.Sprintf("own_k8s_%s_%s_%s_%s", container.Name, pod.Metadata.Name, pod.Metadata.Namespace, pod.Metadata.UID)
 
+		if err := pullImage(pod, container); err != nil {
+			return nil, fmt.Errorf("unable to pull image %s: %v", container.Image, err)
+		}
+
 		containerID, err := kube_containerd.CreateContainer(
 			&container,
 			kube_containerd.CreateContainerSpec{
 				LogLocation:          fmt.Sprintf(defaultPodLoggingLocation, containerStatusName),
 				ResolvConfLocation:   fmt.Sprintf(defaultPodResolvConfLocation, pod.Metadata.UID),
 				HostnameLocation:     fmt.Sprintf(defaultPodContainerHostnameLocation, pod.Metadata.UID, containerStatusName),
+				Hostname:             podHostname(pod),
 				EtcHostsLocation:     fmt.Sprintf(defaultPodContainerEtcdHostsLocation, pod.Metadata.UID),
 				HostNetwork:          pod.Spec.HostNetwork,
 				NetworkNamespacePath: fmt.Sprintf(defaultNetNamespacePath, pauseContainerPID),
 				IPCNamespacePath:     fmt.Sprintf(defaultIPCNamespacePath, pauseContainerPID),
+				ConfigMaps:           configMaps,
+				Secrets:              secrets,
+				VolumesLocation:      volumesLocation,
 			},
 		)
 		if err != nil {
+			eventRecorder.Eventf(containerReference(pod, container.Name), kubeapi_rest.EventTypeWarning, "Failed", "Error: %v", err)
+
 			return nil, fmt.Errorf("unable to create and start container %v", err)
 		}
 
+		eventRecorder.Eventf(containerReference(pod, container.Name), kubeapi_rest.EventTypeNormal, "Created", "Created container %s", container.Name)
+		eventRecorder.Eventf(containerReference(pod, container.Name), kubeapi_rest.EventTypeNormal, "Started", "Started container %s", container.Name)
+
 		pod.Status.ContainerStatuses = append(pod.Status.ContainerStatuses, kubeapi_rest.ContainerStatus{
 			ContainerID: containerID,
 			Image:       container.Image,
@@ -433,15 +590,73 @@ func CreatePodContainers(pod kubeapi_rest.Pod, podCIDR string, podBridgeName str
 		pod.Spec.HostNetwork,
 	)
 	if err != nil {
+		eventRecorder.Eventf(podReference(pod), kubeapi_rest.EventTypeWarning, "FailedCreatePodSandBox", "Failed to configure pod network: %v", err)
+
 		return nil, fmt.Errorf("unable to configure pod network %v", err)
 	}
 
+	if !pod.Spec.HostNetwork {
+		if err := kube_containerd.WriteEtcHosts(
+			fmt.Sprintf(defaultPodContainerEtcdHostsLocation, pod.Metadata.UID), ip, podHostnames(pod)...,
+		); err != nil {
+			return nil, fmt.Errorf("unable to write pod hosts file %v", err)
+		}
+	}
+
 	pod.Status.PodIP = ip
 	pod.Status.Phase = podRunningPhase
+	setReadyCondition(&pod, podRunningPhase)
 
 	return &pod, nil
 }
 
+// podHostname returns the hostname of the containers of the pod, the pod name unless the pod sets one
+func podHostname(pod kubeapi_rest.Pod) string {
+	if pod.Spec.Hostname != "" {
+		return pod.Spec.Hostname
+	}
+
+	return pod.Metadata.Name
+}
+
+// podHostnames returns the names of the pod in its hosts file, the fully qualified name first when the pod has
+// a subdomain
+func podHostnames(pod kubeapi_rest.Pod) []string {
+	hostname := podHostname(pod)
+
+	if pod.Spec.Subdomain == "" {
+		return []string{hostname}
+	}
+
+	return []string{
+		fmt.Sprintf("%s.%s.%s.svc.cluster.local", hostname, pod.Spec.Subdomain, pod.Metadata.Namespace),
+		hostname,
+	}
+}
+
+func pullImage(pod kubeapi_rest.Pod, container kubeapi_rest.Container) error {
+	reference := containerReference(pod, container.Name)
+
+	if kube_containerd.IsImagePresent(container.Image) {
+		eventRecorder.Eventf(reference, kubeapi_rest.EventTypeNormal, "Pulled", "Container image %q already present on machine", container.Image)
+
+		return nil
+	}
+
+	eventRecorder.Eventf(reference, kubeapi_rest.EventTypeNormal, "Pulling", "Pulling image %q", container.Image)
+
+	start := time.Now()
+	if err := kube_containerd.PullImage(container.Image); err != nil {
+		eventRecorder.Eventf(reference, kubeapi_rest.EventTypeWarning, "Failed", "Failed to pull image %q: %v", container.Image, err)
+
+		return err
+	}
+
+	eventRecorder.Eventf(reference, kubeapi_rest.EventTypeNormal, "Pulled", "Successfully pulled image %q in %s", container.Image, time.Since(start).Round(time.Millisecond))
+
+	return nil
+}
+
 func createPauseContainer(podID string, podName string, namespace string, isHostNetwork bool) (uint32, error) {
 	log.Printf("starting pause container for %s", podName)
 
@@ -0,0 +1,437 @@
+package pod
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	kubeapi_rest "github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
+	"github.com/jonatan5524/own-kubernetes/pkg/kubelet/volume"
+	"github.com/jonatan5524/own-kubernetes/pkg/utils"
+)
+
+const (
+	defaultPodVolumesLocation = "/home/user/kubernetes/kubelet/pod/%s/volumes"
+	defaultPodVolumeLocation  = "/home/user/kubernetes/kubelet/pod/%s/volumes/%s"
+	defaultVolumeFileMode     = 0o644
+
+	configMapVolumeKind = "configmaps"
+	secretVolumeKind    = "secrets"
+)
+
+// volumeSource is the part of a configmap and a secret a volume is built from
+type volumeSource interface {
+	Value(key string) ([]byte, bool)
+	Files() map[string][]byte
+}
+
+// podVolume is a configmap or secret volume of a running pod, kept to rewrite its files when the source changes
+type podVolume struct {
+	podName   string
+	namespace string
+	location  string
+	// kind is the api resource of the source, configmaps or secrets
+	kind       string
+	sourceName string
+	items      []kubeapi_rest.KeyToPath
+	optional   bool
+	tmpfs      bool
+}
+
+var (
+	podVolumesMutex sync.Mutex
+	// podVolumes are the configmap and secret volumes by pod uid
+	podVolumes = make(map[string][]podVolume)
+)
+
+// getNamespacedResource returns nil with no error when the resource does not exist
+func getNamespacedResource(kubeAPIEndpoint string, namespace string, kind string, name string) ([]byte, error) {
+	resp, err := http.Get(fmt.Sprintf("%s/namespaces/%s/%s/%s", kubeAPIEndpoint, namespace, kind, name))
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		if strings.Contains(string(body), "key not found") {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("request failed with status code: %d %s", resp.StatusCode, string(body))
+	}
+
+	return body, nil
+}
+
+func getVolumeSource(kubeAPIEndpoint string, namespace string, kind string, name string) (volumeSource, error) {
+	body, err := getNamespacedResource(kubeAPIEndpoint, namespace, kind, name)
+	if err != nil || body == nil {
+		return nil, err
+	}
+
+	return parseVolumeSource(kind, body)
+}
+
+func parseVolumeSource(kind string, body []byte) (volumeSource, error) {
+	if kind == secretVolumeKind {
+		var secret kubeapi_rest.Secret
+		if err := json.Unmarshal(body, &secret); err != nil {
+			return nil, fmt.Errorf("error unmarshalling JSON: %v", err)
+		}
+
+		return &secret, nil
+	}
+
+	var configMap kubeapi_rest.ConfigMap
+	if err := json.Unmarshal(body, &configMap); err != nil {
+		return nil, fmt.Errorf("error unmarshalling JSON: %v", err)
+	}
+
+	return &configMap, nil
+}
+
+// getPodConfigMapsAndSecrets gets all the configmaps and secrets the pod references by name,
+// the ones that do not exist are left out
+func getPodConfigMapsAndSecrets(kubeAPIEndpoint string, pod kubeapi_rest.Pod) (map[string]kubeapi_rest.ConfigMap, map[string]kubeapi_rest.Secret, error) {
+	configMapNames := make(map[string]bool)
+	secretNames := make(map[string]bool)
+
+	for _, container := range pod.Spec.Containers {
+		for _, envVar := range container.Env {
+			if envVar.ValueFrom != nil && envVar.ValueFrom.ConfigMapKeyRef != nil {
+				configMapNames[envVar.ValueFrom.ConfigMapKeyRef.Name] = true
+			}
+
+			if envVar.ValueFrom != nil && envVar.ValueFrom.SecretKeyRef != nil {
+				secretNames[envVar.ValueFrom.SecretKeyRef.Name] = true
+			}
+		}
+
+		for _, envFrom := range container.EnvFrom {
+			if envFrom.ConfigMapRef != nil {
+				configMapNames[envFrom.ConfigMapRef.Name] = true
+			}
+
+			if envFrom.SecretRef != nil {
+				secretNames[envFrom.SecretRef.Name] = true
+			}
+		}
+	}
+
+	for _, volumeSpec := range pod.Spec.Volumes {
+		if volumeSpec.ConfigMap != nil {
+			configMapNames[volumeSpec.ConfigMap.Name] = true
+		}
+
+		if volumeSpec.Secret != nil {
+			secretNames[volumeSpec.Secret.SecretName] = true
+		}
+	}
+
+	configMaps := make(map[string]kubeapi_rest.ConfigMap)
+	secrets := make(map[string]kubeapi_rest.Secret)
+
+	for name := range configMapNames {
+		source, err := getVolumeSource(kubeAPIEndpoint, pod.Metadata.Namespace, configMapVolumeKind, name)
+		if err != nil {
+			return configMaps, secrets, fmt.Errorf("error getting configmap %s: %v", name, err)
+		}
+
+		if source != nil {
+			configMaps[name] = *source.(*kubeapi_rest.ConfigMap)
+		}
+	}
+
+	for name := range secretNames {
+		source, err := getVolumeSource(kubeAPIEndpoint, pod.Metadata.Namespace, secretVolumeKind, name)
+		if err != nil {
+			return configMaps, secrets, fmt.Errorf("error getting secret %s: %v", name, err)
+		}
+
+		if source != nil {
+			secrets[name] = *source.(*kubeapi_rest.Secret)
+		}
+	}
+
+	return configMaps, secrets, nil
+}
+
+func volumeFiles(podVolumeRes podVolume, source volumeSource) (map[string][]byte, error) {
+	if source == nil {
+		if podVolumeRes.optional {
+			return map[string][]byte{}, nil
+		}
+
+		return nil, fmt.Errorf("%s %s not found", podVolumeRes.kind, podVolumeRes.sourceName)
+	}
+
+	if len(podVolumeRes.items) == 0 {
+		return source.Files(), nil
+	}
+
+	files := make(map[string][]byte, len(podVolumeRes.items))
+
+	for _, item := range podVolumeRes.items {
+		value, ok := source.Value(item.Key)
+		if !ok {
+			if podVolumeRes.optional {
+				continue
+			}
+
+			return nil, fmt.Errorf("key %s not found in %s %s", item.Key, podVolumeRes.kind, podVolumeRes.sourceName)
+		}
+
+		files[item.Path] = value
+	}
+
+	return files, nil
+}
+
+func writePodVolume(podVolumeRes podVolume, source volumeSource) error {
+	files, err := volumeFiles(podVolumeRes, source)
+	if err != nil {
+		return err
+	}
+
+	if podVolumeRes.tmpfs {
+		if err := volume.MountTmpfs(podVolumeRes.location); err != nil {
+			return err
+		}
+	}
+
+	return volume.WriteAtomically(podVolumeRes.location, files, defaultVolumeFileMode)
+}
+
+// setupPodVolumes writes the volumes of the pod under the pod directory and returns their locations by volume name
+func setupPodVolumes(pod kubeapi_rest.Pod, configMaps map[string]kubeapi_rest.ConfigMap, secrets map[string]kubeapi_rest.Secret) (map[string]string, error) {
+	volumesLocation := make(map[string]string)
+	var volumes []podVolume
+
+	for _, volumeSpec := range pod.Spec.Volumes {
+		podVolumeRes := podVolume{
+			podName:   pod.Metadata.Name,
+			namespace: pod.Metadata.Namespace,
+			location:  fmt.Sprintf(defaultPodVolumeLocation, pod.Metadata.UID, volumeSpec.Name),
+		}
+
+		var source volumeSource
+
+		switch {
+		case volumeSpec.ConfigMap != nil:
+			podVolumeRes.kind = configMapVolumeKind
+			podVolumeRes.sourceName = volumeSpec.ConfigMap.Name
+			podVolumeRes.items = volumeSpec.ConfigMap.Items
+			podVolumeRes.optional = volumeSpec.ConfigMap.Optional
+
+			if configMap, ok := configMaps[volumeSpec.ConfigMap.Name]; ok {
+				source = &configMap
+			}
+		case volumeSpec.Secret != nil:
+			podVolumeRes.kind = secretVolumeKind
+			podVolumeRes.sourceName = volumeSpec.Secret.SecretName
+			podVolumeRes.items = volumeSpec.Secret.Items
+			podVolumeRes.optional = volumeSpec.Secret.Optional
+			podVolumeRes.tmpfs = true
+
+			if secret, ok := secrets[volumeSpec.Secret.SecretName]; ok {
+				source = &secret
+			}
+		default:
+			return volumesLocation, fmt.Errorf("volume %s has no supported volume source", volumeSpec.Name)
+		}
+
+		if err := writePodVolume(podVolumeRes, source); err != nil {
+			return volumesLocation, fmt.Errorf("error writing volume %s: %v", volumeSpec.Name, err)
+		}
+
+		volumesLocation[volumeSpec.Name] = podVolumeRes.location
+		volumes = append(volumes, podVolumeRes)
+	}
+
+	if len(volumes) > 0 {
+		podVolumesMutex.Lock()
+		podVolumes[pod.Metadata.UID] = volumes
+		podVolumesMutex.Unlock()
+	}
+
+	return volumesLocation, nil
+}
+
+func cleanupPodVolumes(podUID string) {
+	podVolumesMutex.Lock()
+	volumes := podVolumes[podUID]
+	delete(podVolumes, podUID)
+	podVolumesMutex.Unlock()
+
+	for _, podVolumeRes := range volumes {
+		if !podVolumeRes.tmpfs {
+			continue
+		}
+
+		if err := volume.Unmount(podVolumeRes.location); err != nil {
+			log.Printf("error unmounting volume %s of pod %s: %v", podVolumeRes.location, podVolumeRes.podName, err)
+		}
+	}
+
+	if err := os.RemoveAll(fmt.Sprintf(defaultPodVolumesLocation, podUID)); err != nil {
+		log.Printf("error removing volumes of pod %s: %v", podUID, err)
+	}
+}
+
+// updatePodVolumes rewrites the files of every volume of the source, a nil source resyncs them from the api
+func updatePodVolumes(kubeAPIEndpoint string, kind string, namespace string, name string, source volumeSource) {
+	podVolumesMutex.Lock()
+	defer podVolumesMutex.Unlock()
+
+	for _, volumes := range podVolumes {
+		for _, podVolumeRes := range volumes {
+			if podVolumeRes.kind != kind || podVolumeRes.namespace != namespace || podVolumeRes.sourceName != name {
+				continue
+			}
+
+			current := source
+			if current == nil {
+				var err error
+				current, err = getVolumeSource(kubeAPIEndpoint, namespace, kind, name)
+				if err != nil {
+					log.Printf("error getting %s %s/%s: %v", kind, namespace, name, err)
+
+					continue
+				}
+
+				// a deleted source keeps the last files, same as kubernetes
+				if current == nil {
+					continue
+				}
+			}
+
+			if err := writePodVolume(podVolumeRes, current); err != nil {
+				log.Printf("error updating volume %s of pod %s: %v", podVolumeRes.location, podVolumeRes.podName, err)
+
+				continue
+			}
+
+			log.Printf("updated %s %s/%s volume of pod %s", kind, namespace, name, podVolumeRes.podName)
+		}
+	}
+}
+
+func resyncPodVolumes(kubeAPIEndpoint string, kind string) {
+	type sourceKey struct {
+		namespace string
+		name      string
+	}
+
+	podVolumesMutex.Lock()
+	referenced := make(map[sourceKey]bool)
+	for _, volumes := range podVolumes {
+		for _, podVolumeRes := range volumes {
+			if podVolumeRes.kind == kind {
+				referenced[sourceKey{namespace: podVolumeRes.namespace, name: podVolumeRes.sourceName}] = true
+			}
+		}
+	}
+	podVolumesMutex.Unlock()
+
+	for key := range referenced {
+		updatePodVolumes(kubeAPIEndpoint, kind, key.namespace, key.name, nil)
+	}
+}
+
+// ListenForConfigMaps keeps the configmap volumes of the pods up to date with the configmaps in the api
+func ListenForConfigMaps(kubeAPIEndpoint string) error {
+	return listenForVolumeSources(kubeAPIEndpoint, configMapVolumeKind)
+}
+
+// ListenForSecrets keeps the secret volumes of the pods up to date with the secrets in the api
+func ListenForSecrets(kubeAPIEndpoint string) error {
+	return listenForVolumeSources(kubeAPIEndpoint, secretVolumeKind)
+}
+
+func listenForVolumeSources(kubeAPIEndpoint string, kind string) error {
+	log.Printf("started watch on %s from kube API", kind)
+
+	// changes while the watch was down are missed, so every volume is synced first
+	resyncPodVolumes(kubeAPIEndpoint, kind)
+
+	resp, err := http.Get(fmt.Sprintf("%s/%s/?watch=true", kubeAPIEndpoint, kind))
+	if err != nil {
+		return fmt.Errorf("error sending request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("error reading response body: %v", err)
+		}
+
+		return fmt.Errorf("request failed with status code: %d %s", resp.StatusCode, string(body))
+	}
+
+	reader := bufio.NewReader(resp.Body)
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return fmt.Errorf("watch stream closed by api")
+			}
+
+			log.Printf("error parsing response: %v", err)
+
+			continue
+		}
+
+		line = strings.TrimSpace(line)
+
+		if len(line) == 0 {
+			continue
+		}
+
+		typeEvent, value, err := utils.GetTypeAndValueFromEvent(line)
+		if err != nil {
+			log.Printf("error getting type and value from event: %v", err)
+
+			continue
+		}
+
+		if typeEvent == kubeapi_rest.WatchErrorEventType {
+			return fmt.Errorf("watch ended by api: %s", value)
+		}
+
+		if typeEvent != "PUT" {
+			continue
+		}
+
+		var metadata struct {
+			Metadata kubeapi_rest.ResourceMetadata `json:"metadata"`
+		}
+		if err = json.Unmarshal([]byte(value), &metadata); err != nil {
+			log.Printf("error parsing %s from event: %v", kind, err)
+
+			continue
+		}
+
+		source, err := parseVolumeSource(kind, []byte(value))
+		if err != nil {
+			log.Printf("error parsing %s from event: %v", kind, err)
+
+			continue
+		}
+
+		updatePodVolumes(kubeAPIEndpoint, kind, metadata.Metadata.Namespace, metadata.Metadata.Name, source)
+	}
+}
@@ -13,7 +13,7 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
-func readAndStartSystemManifests(systemManifestPath string, hostname string) ([]*rest.Pod, error) {
+func readAndStartSystemManifests(systemManifestPath string, hostname string, kubeAPIEndpoint string) ([]*rest.Pod, error) {
 	log.Printf("Reading manifest in system %s", systemManifestPath)
 
 	files, err := os.ReadDir(systemManifestPath)
@@ -38,7 +38,7 @@ func readAndStartSystemManifests(systemManifestPath string, hostname string) ([]
 				return pods, fmt.Errorf("error parsing pod from event: %v", err)
 			}
 
-			podRes, err := pod.CreatePodContainers(podResManifest, podCIDR, podBridgeName)
+			podRes, err := pod.CreatePodContainers(podResManifest, podCIDR, podBridgeName, kubeAPIEndpoint)
 			if err != nil {
 				return pods, err
 			}
@@ -0,0 +1,251 @@
+package stats
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	kubeapi_rest "github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
+	kube_containerd "github.com/jonatan5524/own-kubernetes/pkg/kubelet/containerd"
+)
+
+const (
+	// metricsResolution is how often the usage of the containers is read and reported, same as the metrics server
+	metricsResolution = 15 * time.Second
+
+	podRunningPhase = "Running"
+)
+
+type cpuSample struct {
+	usageNanoseconds uint64
+	timestamp        time.Time
+}
+
+type collector struct {
+	kubeAPIEndpoint string
+	hostname        string
+	// previousSamples are the last cpu samples by container id, the cpu usage is the rate between two samples
+	previousSamples map[string]cpuSample
+	// reported are the namespace/name of the pods with reported metrics, so the metrics of removed pods are deleted
+	reported map[string]bool
+}
+
+// Run reports the cpu and memory usage of the containers of the running pods of the node every metrics resolution
+func Run(kubeAPIEndpoint string, hostname string) {
+	log.Printf("started reporting pod metrics to kube API")
+
+	collector := &collector{
+		kubeAPIEndpoint: kubeAPIEndpoint,
+		hostname:        hostname,
+		previousSamples: map[string]cpuSample{},
+		reported:        map[string]bool{},
+	}
+
+	ticker := time.NewTicker(metricsResolution)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := collector.collect(); err != nil {
+			log.Printf("error reporting pod metrics: %v", err)
+		}
+	}
+}
+
+func (collector *collector) collect() error {
+	pods, err := getPods(collector.kubeAPIEndpoint, collector.hostname)
+	if err != nil {
+		return err
+	}
+
+	samples := map[string]cpuSample{}
+	reported := map[string]bool{}
+
+	for _, pod := range pods {
+		if pod.Status.Phase != podRunningPhase {
+			continue
+		}
+
+		podMetrics, ok := collector.podMetrics(pod, samples)
+		if !ok {
+			continue
+		}
+
+		key := fmt.Sprintf("%s/%s", pod.Metadata.Namespace, pod.Metadata.Name)
+		if err := sendPodMetrics(collector.kubeAPIEndpoint, podMetrics); err != nil {
+			log.Printf("error reporting metrics of pod %s: %v", key, err)
+
+			continue
+		}
+
+		reported[key] = true
+	}
+
+	for key := range collector.reported {
+		if reported[key] {
+			continue
+		}
+
+		namespace, name, _ := strings.Cut(key, "/")
+		if err := deletePodMetrics(collector.kubeAPIEndpoint, namespace, name); err != nil {
+			log.Printf("error deleting metrics of pod %s: %v", key, err)
+
+			// the delete is retried on the next collection
+			reported[key] = true
+		}
+	}
+
+	collector.previousSamples = samples
+	collector.reported = reported
+
+	return nil
+}
+
+// podMetrics reads the usage of the containers of the pod, the pod is reported only once every container has a
+// previous cpu sample to compute the rate from
+func (collector *collector) podMetrics(pod kubeapi_rest.Pod, samples map[string]cpuSample) (kubeapi_rest.PodMetrics, bool) {
+	podMetrics := kubeapi_rest.PodMetrics{
+		Metadata: kubeapi_rest.ResourceMetadata{
+			Name:      pod.Metadata.Name,
+			Namespace: pod.Metadata.Namespace,
+			Labels:    pod.Metadata.Labels,
+		},
+		TypeMeta: kubeapi_rest.TypeMeta{Kind: "PodMetrics"},
+	}
+
+	complete := len(pod.Status.ContainerStatuses) > 0
+	var window time.Duration
+
+	for _, containerStatus := range pod.Status.ContainerStatuses {
+		if containerStatus.ContainerID == "" || containerStatus.State.Terminated != nil {
+			complete = false
+
+			continue
+		}
+
+		usage, err := kube_containerd.GetContainerUsage(containerStatus.ContainerID)
+		if err != nil {
+			log.Printf("error getting usage of container %s: %v", containerStatus.ContainerID, err)
+			complete = false
+
+			continue
+		}
+
+		now := time.Now()
+		samples[containerStatus.ContainerID] = cpuSample{usageNanoseconds: usage.CPUUsageNanoseconds, timestamp: now}
+
+		previous, ok := collector.previousSamples[containerStatus.ContainerID]
+		if !ok || usage.CPUUsageNanoseconds < previous.usageNanoseconds {
+			complete = false
+
+			continue
+		}
+
+		window = now.Sub(previous.timestamp)
+		milliCores := float64(usage.CPUUsageNanoseconds-previous.usageNanoseconds) / float64(window.Nanoseconds()) * 1000
+
+		podMetrics.Containers = append(podMetrics.Containers, kubeapi_rest.ContainerMetrics{
+			Name: containerStatus.Name,
+			Usage: map[string]string{
+				kubeapi_rest.ResourceCPU:    fmt.Sprintf("%dm", int64(milliCores)),
+				kubeapi_rest.ResourceMemory: fmt.Sprintf("%dKi", usage.MemoryWorkingSetBytes/1024),
+			},
+		})
+	}
+
+	podMetrics.Timestamp = time.Now().Format(time.RFC3339)
+	podMetrics.Window = window.Round(time.Second).String()
+
+	return podMetrics, complete
+}
+
+func getPods(kubeAPIEndpoint string, hostname string) ([]kubeapi_rest.Pod, error) {
+	var pods []kubeapi_rest.Pod
+	resp, err := http.Get(fmt.Sprintf(
+		"%s/pods?fieldSelector=%s",
+		kubeAPIEndpoint,
+		url.QueryEscape(fmt.Sprintf("spec.nodeName=%s", hostname)),
+	))
+	if err != nil {
+		return pods, fmt.Errorf("error sending request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return pods, fmt.Errorf("error reading response body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		if strings.Contains(string(body), "key not found") {
+			return pods, nil
+		}
+
+		return pods, fmt.Errorf("request failed with status code: %d", resp.StatusCode)
+	}
+
+	err = json.Unmarshal(body, &pods)
+	if err != nil {
+		return pods, fmt.Errorf("error unmarshalling JSON: %v", err)
+	}
+
+	return pods, nil
+}
+
+func sendPodMetrics(kubeAPIEndpoint string, podMetrics kubeapi_rest.PodMetrics) error {
+	podMetricsBytes, err := json.Marshal(podMetrics)
+	if err != nil {
+		return fmt.Errorf("error parsing pod metrics: %v", err)
+	}
+
+	return sendRequest(
+		http.MethodPost,
+		fmt.Sprintf("%s/namespaces/%s/podmetrics", kubeAPIEndpoint, podMetrics.Metadata.Namespace),
+		podMetricsBytes,
+	)
+}
+
+func deletePodMetrics(kubeAPIEndpoint string, namespace string, name string) error {
+	err := sendRequest(
+		http.MethodDelete,
+		fmt.Sprintf("%s/namespaces/%s/podmetrics/%s", kubeAPIEndpoint, namespace, name),
+		nil,
+	)
+	if err != nil && strings.Contains(err.Error(), "key not found") {
+		return nil
+	}
+
+	return err
+}
+
+func sendRequest(method string, url string, body []byte) error {
+	req, err := http.NewRequest(method, url, bytes.NewBuffer(body))
+	if err != nil {
+		return fmt.Errorf("error creating request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("error reading response body: %v", err)
+		}
+
+		return fmt.Errorf("request failed with status code: %d %s", resp.StatusCode, string(respBody))
+	}
+
+	return nil
+}
@@ -0,0 +1,153 @@
+package volume
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// dataDirName is a symlink to the current timestamped directory with the files, the user visible files
+	// are symlinks through it, so swapping it replaces all the files at once
+	dataDirName    = "..data"
+	newDataDirName = "..data_tmp"
+	timestampDir   = "..2006_01_02_15_04_05."
+)
+
+// WriteAtomically replaces the content of targetDir with files, a reader of the directory sees either all the old
+// files or all the new ones, the same layout as the kubernetes configmap and secret volumes
+func WriteAtomically(targetDir string, files map[string][]byte, mode os.FileMode) error {
+	for path := range files {
+		if err := validatePath(path); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(targetDir, 0o755); err != nil {
+		return fmt.Errorf("error creating volume directory %s: %v", targetDir, err)
+	}
+
+	dataDirPath := filepath.Join(targetDir, dataDirName)
+
+	oldTimestampDir, err := os.Readlink(dataDirPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error reading data directory link: %v", err)
+	}
+
+	newTimestampDirPath, err := os.MkdirTemp(targetDir, time.Now().Format(timestampDir))
+	if err != nil {
+		return fmt.Errorf("error creating timestamp directory: %v", err)
+	}
+
+	if err := writeFiles(newTimestampDirPath, files, mode); err != nil {
+		os.RemoveAll(newTimestampDirPath)
+
+		return err
+	}
+
+	newDataDirPath := filepath.Join(targetDir, newDataDirName)
+	os.Remove(newDataDirPath)
+
+	if err := os.Symlink(filepath.Base(newTimestampDirPath), newDataDirPath); err != nil {
+		os.RemoveAll(newTimestampDirPath)
+
+		return fmt.Errorf("error creating data directory link: %v", err)
+	}
+
+	// rename of a symlink is atomic, this is the moment the new files are visible
+	if err := os.Rename(newDataDirPath, dataDirPath); err != nil {
+		os.Remove(newDataDirPath)
+		os.RemoveAll(newTimestampDirPath)
+
+		return fmt.Errorf("error swapping data directory link: %v", err)
+	}
+
+	if err := updateUserVisibleLinks(targetDir, files); err != nil {
+		return err
+	}
+
+	if oldTimestampDir != "" && oldTimestampDir != filepath.Base(newTimestampDirPath) {
+		if err := os.RemoveAll(filepath.Join(targetDir, oldTimestampDir)); err != nil {
+			log.Printf("error removing old timestamp directory %s: %v", oldTimestampDir, err)
+		}
+	}
+
+	return nil
+}
+
+func validatePath(path string) error {
+	if path == "" {
+		return fmt.Errorf("volume file path is empty")
+	}
+
+	if filepath.IsAbs(path) {
+		return fmt.Errorf("volume file path %s must be relative", path)
+	}
+
+	for _, part := range strings.Split(path, string(os.PathSeparator)) {
+		if part == ".." {
+			return fmt.Errorf("volume file path %s must not contain '..'", path)
+		}
+	}
+
+	if strings.HasPrefix(path, "..") {
+		return fmt.Errorf("volume file path %s must not start with '..'", path)
+	}
+
+	return nil
+}
+
+func writeFiles(dir string, files map[string][]byte, mode os.FileMode) error {
+	for path, content := range files {
+		filePath := filepath.Join(dir, path)
+
+		if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+			return fmt.Errorf("error creating directory of %s: %v", path, err)
+		}
+
+		if err := os.WriteFile(filePath, content, mode); err != nil {
+			return fmt.Errorf("error writing %s: %v", path, err)
+		}
+	}
+
+	return nil
+}
+
+// updateUserVisibleLinks links every top level path to the data directory and removes links of paths that are gone
+func updateUserVisibleLinks(targetDir string, files map[string][]byte) error {
+	topLevelPaths := make(map[string]bool)
+	for path := range files {
+		topLevelPaths[strings.SplitN(path, string(os.PathSeparator), 2)[0]] = true
+	}
+
+	for path := range topLevelPaths {
+		linkPath := filepath.Join(targetDir, path)
+		if _, err := os.Lstat(linkPath); err == nil {
+			continue
+		}
+
+		if err := os.Symlink(filepath.Join(dataDirName, path), linkPath); err != nil {
+			return fmt.Errorf("error linking %s: %v", path, err)
+		}
+	}
+
+	entries, err := os.ReadDir(targetDir)
+	if err != nil {
+		return fmt.Errorf("error reading volume directory %s: %v", targetDir, err)
+	}
+
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), "..") || topLevelPaths[entry.Name()] {
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(targetDir, entry.Name())); err != nil {
+			return fmt.Errorf("error removing link of %s: %v", entry.Name(), err)
+		}
+	}
+
+	return nil
+}
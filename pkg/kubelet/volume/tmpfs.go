@@ -0,0 +1,81 @@
+package volume
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+const mountsFile = "/proc/mounts"
+
+// MountTmpfs mounts a memory backed file system on dir, files of secret volumes are written there so they never
+// reach the disk of the node
+func MountTmpfs(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("error creating tmpfs directory %s: %v", dir, err)
+	}
+
+	mounted, err := isMountPoint(dir)
+	if err != nil {
+		return err
+	}
+
+	if mounted {
+		return nil
+	}
+
+	log.Printf("mounting tmpfs on %s", dir)
+
+	if err := syscall.Mount("tmpfs", dir, "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV|syscall.MS_NOEXEC, "mode=0755"); err != nil {
+		return fmt.Errorf("error mounting tmpfs on %s: %v", dir, err)
+	}
+
+	return nil
+}
+
+// Unmount unmounts dir if something is mounted on it
+func Unmount(dir string) error {
+	mounted, err := isMountPoint(dir)
+	if err != nil {
+		return err
+	}
+
+	if !mounted {
+		return nil
+	}
+
+	log.Printf("unmounting %s", dir)
+
+	if err := syscall.Unmount(dir, 0); err != nil {
+		return fmt.Errorf("error unmounting %s: %v", dir, err)
+	}
+
+	return nil
+}
+
+func isMountPoint(dir string) (bool, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return false, err
+	}
+
+	file, err := os.Open(mountsFile)
+	if err != nil {
+		return false, fmt.Errorf("error reading mounts: %v", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 1 && fields[1] == absDir {
+			return true, nil
+		}
+	}
+
+	return false, scanner.Err()
+}
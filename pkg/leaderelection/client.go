@@ -0,0 +1,72 @@
+package leaderelection
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	kubeapi_rest "github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
+)
+
+func getLease(kubeAPIEndpoint string, namespace string, name string) (*kubeapi_rest.Lease, error) {
+	resp, err := http.Get(fmt.Sprintf("%s/namespaces/%s/leases/%s", kubeAPIEndpoint, namespace, name))
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("request failed with status code: %d %s", resp.StatusCode, string(body))
+	}
+
+	var lease kubeapi_rest.Lease
+	if err = json.Unmarshal(body, &lease); err != nil {
+		return nil, fmt.Errorf("error unmarshalling JSON: %v", err)
+	}
+
+	return &lease, nil
+}
+
+// updateLease writes the lease only if it is still at its resource version, the api answers Conflict otherwise
+func updateLease(kubeAPIEndpoint string, lease *kubeapi_rest.Lease) error {
+	leaseBytes, err := json.Marshal(lease)
+	if err != nil {
+		return fmt.Errorf("error parsing lease: %v", err)
+	}
+
+	req, err := http.NewRequest(
+		http.MethodPatch,
+		fmt.Sprintf("%s/namespaces/%s/leases/%s", kubeAPIEndpoint, lease.Metadata.Namespace, lease.Metadata.Name),
+		bytes.NewBuffer(leaseBytes),
+	)
+	if err != nil {
+		return fmt.Errorf("error creating request for lease: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending lease: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("error reading response body: %v", err)
+		}
+
+		return fmt.Errorf("request failed with status code: %d %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
@@ -0,0 +1,295 @@
+package leaderelection
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	kubeapi_rest "github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
+)
+
+const (
+	// same defaults as the kubernetes components
+	DefaultLeaseDuration = 15 * time.Second
+	DefaultRenewDeadline = 10 * time.Second
+	DefaultRetryPeriod   = 2 * time.Second
+
+	DefaultLeaseNamespace = "kube-system"
+)
+
+type LeaderCallbacks struct {
+	// OnStartedLeading runs in its own goroutine when the leadership is acquired,
+	// the stop channel is closed when it is lost
+	OnStartedLeading func(stopCh <-chan struct{})
+	// OnStoppedLeading is called when the leadership is lost or released
+	OnStoppedLeading func()
+	// OnNewLeader is called when another identity is seen holding the lease, optional
+	OnNewLeader func(identity string)
+}
+
+type LeaderElectionConfig struct {
+	KubeAPIEndpoint string
+
+	// LeaseNamespace and LeaseName are of the lease all the candidates compete on,
+	// its holder identity is the current leader
+	LeaseNamespace string
+	LeaseName      string
+	// Identity is unique per candidate
+	Identity string
+
+	// LeaseDuration is how long the candidates wait after the last renewal of the leader before taking over
+	LeaseDuration time.Duration
+	// RenewDeadline is how long the leader keeps trying to renew before it gives up the leadership
+	RenewDeadline time.Duration
+	// RetryPeriod is how often the candidates try to acquire or renew the lease
+	RetryPeriod time.Duration
+
+	// ReleaseOnCancel gives up the lease when the elector is stopped, so a standby takes over right away
+	ReleaseOnCancel bool
+
+	Callbacks LeaderCallbacks
+}
+
+// LeaderElector competes with the other candidates on a lease, a single candidate holds it and renews it.
+// The lease is updated with its resource version so two candidates can not acquire it together,
+// and its expiry is measured on the clock of the candidate so skewed clocks do not matter
+type LeaderElector struct {
+	config LeaderElectionConfig
+
+	mu sync.Mutex
+	// observedLease is the last lease seen and observedTime is when it was seen changing
+	observedLease  *kubeapi_rest.Lease
+	observedTime   time.Time
+	reportedLeader string
+}
+
+func NewLeaderElector(config LeaderElectionConfig) (*LeaderElector, error) {
+	if config.LeaseDuration <= config.RenewDeadline {
+		return nil, fmt.Errorf("lease duration must be greater than renew deadline")
+	}
+
+	if config.RenewDeadline <= time.Duration(1.2*float64(config.RetryPeriod)) {
+		return nil, fmt.Errorf("renew deadline must be greater than 1.2 times the retry period")
+	}
+
+	if config.RetryPeriod < 1 {
+		return nil, fmt.Errorf("retry period must be greater than zero")
+	}
+
+	if config.LeaseName == "" || config.Identity == "" {
+		return nil, fmt.Errorf("lease name and identity are required")
+	}
+
+	if config.Callbacks.OnStartedLeading == nil || config.Callbacks.OnStoppedLeading == nil {
+		return nil, fmt.Errorf("OnStartedLeading and OnStoppedLeading callbacks are required")
+	}
+
+	if config.LeaseNamespace == "" {
+		config.LeaseNamespace = DefaultLeaseNamespace
+	}
+
+	return &LeaderElector{config: config}, nil
+}
+
+// Run blocks until the leadership is acquired, then renews it until it is lost or the stop channel is closed
+func (elector *LeaderElector) Run(stopCh <-chan struct{}) {
+	defer elector.config.Callbacks.OnStoppedLeading()
+
+	if !elector.acquire(stopCh) {
+		return
+	}
+
+	leadingStopCh := make(chan struct{})
+	defer close(leadingStopCh)
+
+	go elector.config.Callbacks.OnStartedLeading(leadingStopCh)
+
+	elector.renew(stopCh)
+}
+
+// IsLeader tells if the elector holds the lease it last observed
+func (elector *LeaderElector) IsLeader() bool {
+	return elector.GetLeader() == elector.config.Identity
+}
+
+// GetLeader returns the identity of the holder of the lease it last observed
+func (elector *LeaderElector) GetLeader() string {
+	elector.mu.Lock()
+	defer elector.mu.Unlock()
+
+	if elector.observedLease == nil {
+		return ""
+	}
+
+	return elector.observedLease.Spec.HolderIdentity
+}
+
+func (elector *LeaderElector) acquire(stopCh <-chan struct{}) bool {
+	log.Printf("attempting to acquire leader lease %s/%s", elector.config.LeaseNamespace, elector.config.LeaseName)
+
+	ticker := time.NewTicker(elector.config.RetryPeriod)
+	defer ticker.Stop()
+
+	for {
+		if elector.tryAcquireOrRenew() {
+			log.Printf("successfully acquired lease %s/%s", elector.config.LeaseNamespace, elector.config.LeaseName)
+			elector.maybeReportTransition()
+
+			return true
+		}
+
+		elector.maybeReportTransition()
+
+		select {
+		case <-stopCh:
+			return false
+		case <-ticker.C:
+		}
+	}
+}
+
+// renew keeps renewing the lease every retry period, the leadership is lost when the lease was not renewed
+// for the renew deadline
+func (elector *LeaderElector) renew(stopCh <-chan struct{}) {
+	ticker := time.NewTicker(elector.config.RetryPeriod)
+	defer ticker.Stop()
+
+	lastRenew := time.Now()
+
+	for {
+		select {
+		case <-stopCh:
+			if elector.config.ReleaseOnCancel {
+				elector.release()
+			}
+
+			return
+		case <-ticker.C:
+		}
+
+		if elector.tryAcquireOrRenew() {
+			lastRenew = time.Now()
+
+			continue
+		}
+
+		elector.maybeReportTransition()
+
+		if time.Since(lastRenew) > elector.config.RenewDeadline || !elector.IsLeader() && elector.GetLeader() != "" {
+			log.Printf("failed to renew lease %s/%s, leadership lost", elector.config.LeaseNamespace, elector.config.LeaseName)
+
+			return
+		}
+	}
+}
+
+// tryAcquireOrRenew takes the lease when it is free or expired and renews it when it is already held
+func (elector *LeaderElector) tryAcquireOrRenew() bool {
+	now := time.Now()
+
+	lease, err := getLease(elector.config.KubeAPIEndpoint, elector.config.LeaseNamespace, elector.config.LeaseName)
+	if err != nil {
+		if !strings.Contains(err.Error(), "key not found") {
+			log.Printf("error getting lease %s/%s: %v", elector.config.LeaseNamespace, elector.config.LeaseName, err)
+
+			return false
+		}
+
+		lease = &kubeapi_rest.Lease{
+			TypeMeta: kubeapi_rest.TypeMeta{Kind: "Lease"},
+			Metadata: kubeapi_rest.ResourceMetadata{
+				Name:      elector.config.LeaseName,
+				Namespace: elector.config.LeaseNamespace,
+				// creates the lease only if no other candidate created it first
+				ResourceVersion: "0",
+			},
+		}
+	} else {
+		elector.observe(lease, now)
+
+		if lease.Spec.HolderIdentity != "" && lease.Spec.HolderIdentity != elector.config.Identity &&
+			elector.observedTime.Add(time.Duration(lease.Spec.LeaseDurationSeconds)*time.Second).After(now) {
+			return false
+		}
+	}
+
+	nowTime := now.Format(time.RFC3339)
+
+	if lease.Spec.HolderIdentity != elector.config.Identity {
+		lease.Spec.AcquireTime = nowTime
+		if lease.Metadata.ResourceVersion != "0" {
+			lease.Spec.LeaseTransitions++
+		}
+	}
+
+	lease.Spec.HolderIdentity = elector.config.Identity
+	lease.Spec.LeaseDurationSeconds = int(elector.config.LeaseDuration / time.Second)
+	lease.Spec.RenewTime = nowTime
+
+	if err := updateLease(elector.config.KubeAPIEndpoint, lease); err != nil {
+		log.Printf("error updating lease %s/%s: %v", elector.config.LeaseNamespace, elector.config.LeaseName, err)
+
+		return false
+	}
+
+	elector.observe(lease, now)
+
+	return true
+}
+
+// release gives up the lease so a standby does not wait for it to expire
+func (elector *LeaderElector) release() {
+	lease, err := getLease(elector.config.KubeAPIEndpoint, elector.config.LeaseNamespace, elector.config.LeaseName)
+	if err != nil || lease.Spec.HolderIdentity != elector.config.Identity {
+		return
+	}
+
+	lease.Spec.HolderIdentity = ""
+	lease.Spec.LeaseDurationSeconds = 1
+	lease.Spec.RenewTime = time.Now().Format(time.RFC3339)
+
+	if err := updateLease(elector.config.KubeAPIEndpoint, lease); err != nil {
+		log.Printf("error releasing lease %s/%s: %v", elector.config.LeaseNamespace, elector.config.LeaseName, err)
+
+		return
+	}
+
+	log.Printf("released lease %s/%s", elector.config.LeaseNamespace, elector.config.LeaseName)
+}
+
+// observe records the lease, the observed time moves only when the holder renewed the lease
+func (elector *LeaderElector) observe(lease *kubeapi_rest.Lease, now time.Time) {
+	elector.mu.Lock()
+	defer elector.mu.Unlock()
+
+	if elector.observedLease == nil || elector.observedLease.Spec != lease.Spec {
+		observed := *lease
+		elector.observedLease = &observed
+		elector.observedTime = now
+	}
+}
+
+func (elector *LeaderElector) maybeReportTransition() {
+	leader := elector.GetLeader()
+
+	elector.mu.Lock()
+	if leader == elector.reportedLeader {
+		elector.mu.Unlock()
+
+		return
+	}
+	elector.reportedLeader = leader
+	elector.mu.Unlock()
+
+	if leader == "" {
+		return
+	}
+
+	log.Printf("new leader of lease %s/%s is %s", elector.config.LeaseNamespace, elector.config.LeaseName, leader)
+
+	if elector.config.Callbacks.OnNewLeader != nil {
+		elector.config.Callbacks.OnNewLeader(leader)
+	}
+}
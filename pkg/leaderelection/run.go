@@ -0,0 +1,67 @@
+package leaderelection
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Options are the leader election settings of a component, set from its --leader-elect flags
+type Options struct {
+	// LeaderElect runs the component only while it holds the lease, so a second replica can wait as a hot standby
+	LeaderElect       bool
+	LeaseDuration     time.Duration
+	RenewDeadline     time.Duration
+	RetryPeriod       time.Duration
+	ResourceName      string
+	ResourceNamespace string
+}
+
+// RunOrDie runs the func while the component is the leader until the stop channel is closed, without leader
+// election the func runs right away. A component that loses the leadership exits, so it starts again as
+// a candidate with clean caches instead of racing the new leader
+func RunOrDie(kubeAPIEndpoint string, options Options, run func(stopCh <-chan struct{}), stopCh <-chan struct{}) error {
+	if !options.LeaderElect {
+		run(stopCh)
+
+		return nil
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return fmt.Errorf("hostname not found")
+	}
+
+	elector, err := NewLeaderElector(LeaderElectionConfig{
+		KubeAPIEndpoint: kubeAPIEndpoint,
+		LeaseNamespace:  options.ResourceNamespace,
+		LeaseName:       options.ResourceName,
+		// the host name alone is not unique when two replicas share the host network
+		Identity:        fmt.Sprintf("%s_%s", hostname, uuid.NewString()),
+		LeaseDuration:   options.LeaseDuration,
+		RenewDeadline:   options.RenewDeadline,
+		RetryPeriod:     options.RetryPeriod,
+		ReleaseOnCancel: true,
+		Callbacks: LeaderCallbacks{
+			OnStartedLeading: run,
+			OnStoppedLeading: func() {
+				select {
+				case <-stopCh:
+					log.Printf("requested to stop, leaving leader election of %s", options.ResourceName)
+				default:
+					log.Fatalf("leader election of %s lost", options.ResourceName)
+				}
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error creating leader elector: %v", err)
+	}
+
+	elector.Run(stopCh)
+
+	return nil
+}
@@ -89,6 +89,174 @@ var deleteEndpointsCmd = &cobra.Command{
 	},
 }
 
+var deleteReplicaSetsCmd = &cobra.Command{
+	Use:   "replicasets",
+	Short: "replicasets",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		namespace, err := cmd.Flags().GetString(namespaceDeleteFlag)
+		if err != nil {
+			return err
+		}
+
+		if len(args) == 0 {
+			return fmt.Errorf("replicaset name must be specify")
+		}
+
+		err = ownkubectl.DeleteResource(namespace, "replicasets", args[0])
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("success")
+
+		return nil
+	},
+}
+
+var deleteDeploymentsCmd = &cobra.Command{
+	Use:   "deployments",
+	Short: "deployments",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		namespace, err := cmd.Flags().GetString(namespaceDeleteFlag)
+		if err != nil {
+			return err
+		}
+
+		if len(args) == 0 {
+			return fmt.Errorf("deployment name must be specify")
+		}
+
+		err = ownkubectl.DeleteResource(namespace, "deployments", args[0])
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("success")
+
+		return nil
+	},
+}
+
+var deleteDaemonSetsCmd = &cobra.Command{
+	Use:   "daemonsets",
+	Short: "daemonsets",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		namespace, err := cmd.Flags().GetString(namespaceDeleteFlag)
+		if err != nil {
+			return err
+		}
+
+		if len(args) == 0 {
+			return fmt.Errorf("daemonset name must be specify")
+		}
+
+		err = ownkubectl.DeleteResource(namespace, "daemonsets", args[0])
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("success")
+
+		return nil
+	},
+}
+
+var deleteStatefulSetsCmd = &cobra.Command{
+	Use:   "statefulsets",
+	Short: "statefulsets",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		namespace, err := cmd.Flags().GetString(namespaceDeleteFlag)
+		if err != nil {
+			return err
+		}
+
+		if len(args) == 0 {
+			return fmt.Errorf("statefulset name must be specify")
+		}
+
+		err = ownkubectl.DeleteResource(namespace, "statefulsets", args[0])
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("success")
+
+		return nil
+	},
+}
+
+var deleteHorizontalPodAutoscalersCmd = &cobra.Command{
+	Use:   "horizontalpodautoscalers",
+	Short: "horizontalpodautoscalers",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		namespace, err := cmd.Flags().GetString(namespaceDeleteFlag)
+		if err != nil {
+			return err
+		}
+
+		if len(args) == 0 {
+			return fmt.Errorf("horizontalpodautoscaler name must be specify")
+		}
+
+		err = ownkubectl.DeleteResource(namespace, "horizontalpodautoscalers", args[0])
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("success")
+
+		return nil
+	},
+}
+
+var deleteJobsCmd = &cobra.Command{
+	Use:   "jobs",
+	Short: "jobs",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		namespace, err := cmd.Flags().GetString(namespaceDeleteFlag)
+		if err != nil {
+			return err
+		}
+
+		if len(args) == 0 {
+			return fmt.Errorf("job name must be specify")
+		}
+
+		err = ownkubectl.DeleteResource(namespace, "jobs", args[0])
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("success")
+
+		return nil
+	},
+}
+
+var deleteCronJobsCmd = &cobra.Command{
+	Use:   "cronjobs",
+	Short: "cronjobs",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		namespace, err := cmd.Flags().GetString(namespaceDeleteFlag)
+		if err != nil {
+			return err
+		}
+
+		if len(args) == 0 {
+			return fmt.Errorf("cronjob name must be specify")
+		}
+
+		err = ownkubectl.DeleteResource(namespace, "cronjobs", args[0])
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("success")
+
+		return nil
+	},
+}
+
 func init() {
 	rootCmd.AddCommand(deleteCmd)
 
@@ -100,4 +268,25 @@ func init() {
 
 	deleteCmd.AddCommand(deleteEndpointsCmd)
 	deleteEndpointsCmd.Flags().StringP(namespaceDeleteFlag, "n", defaultNamespaceDelete, "endpoint namespace")
+
+	deleteCmd.AddCommand(deleteReplicaSetsCmd)
+	deleteReplicaSetsCmd.Flags().StringP(namespaceDeleteFlag, "n", defaultNamespaceDelete, "replicaset namespace")
+
+	deleteCmd.AddCommand(deleteDeploymentsCmd)
+	deleteDeploymentsCmd.Flags().StringP(namespaceDeleteFlag, "n", defaultNamespaceDelete, "deployment namespace")
+
+	deleteCmd.AddCommand(deleteDaemonSetsCmd)
+	deleteDaemonSetsCmd.Flags().StringP(namespaceDeleteFlag, "n", defaultNamespaceDelete, "daemonset namespace")
+
+	deleteCmd.AddCommand(deleteStatefulSetsCmd)
+	deleteStatefulSetsCmd.Flags().StringP(namespaceDeleteFlag, "n", defaultNamespaceDelete, "statefulset namespace")
+
+	deleteCmd.AddCommand(deleteHorizontalPodAutoscalersCmd)
+	deleteHorizontalPodAutoscalersCmd.Flags().StringP(namespaceDeleteFlag, "n", defaultNamespaceDelete, "horizontalpodautoscaler namespace")
+
+	deleteCmd.AddCommand(deleteJobsCmd)
+	deleteJobsCmd.Flags().StringP(namespaceDeleteFlag, "n", defaultNamespaceDelete, "job namespace")
+
+	deleteCmd.AddCommand(deleteCronJobsCmd)
+	deleteCronJobsCmd.Flags().StringP(namespaceDeleteFlag, "n", defaultNamespaceDelete, "cronjob namespace")
 }
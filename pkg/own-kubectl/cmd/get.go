@@ -203,6 +203,565 @@ var getEndpointsCmd = &cobra.Command{
 	},
 }
 
+var getEventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "events",
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		namespace, err := cmd.Flags().GetString(namespaceFlag)
+		if err != nil {
+			return err
+		}
+
+		events, err := ownkubectl.GetEvents(namespace)
+		if err != nil {
+			return err
+		}
+
+		if len(events) == 0 {
+			fmt.Printf("No resource found in %s namespace\n", namespace)
+
+			return nil
+		}
+
+		outputFormat, err := cmd.Flags().GetString(outputFlag)
+		if err != nil {
+			return err
+		}
+
+		if outputFormat == ownkubectl.OutputFormatJSON {
+			eventsJSONBytes, err := json.MarshalIndent(events, "", " ")
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("%s\n", string(eventsJSONBytes))
+		} else if outputFormat == ownkubectl.OutputFormatYAML {
+			eventsYAMLBytes, err := yaml.Marshal(events)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("%s\n", string(eventsYAMLBytes))
+		} else {
+			ownkubectl.PrintEventsInTableFormat(events)
+		}
+
+		return nil
+	},
+}
+
+var getConfigMapsCmd = &cobra.Command{
+	Use:   "configmaps",
+	Short: "configmaps",
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		namespace, err := cmd.Flags().GetString(namespaceFlag)
+		if err != nil {
+			return err
+		}
+
+		configMaps, err := ownkubectl.GetConfigMaps(namespace)
+		if err != nil {
+			return err
+		}
+
+		if len(configMaps) == 0 {
+			fmt.Printf("No resource found in %s namespace\n", namespace)
+
+			return nil
+		}
+
+		outputFormat, err := cmd.Flags().GetString(outputFlag)
+		if err != nil {
+			return err
+		}
+
+		if outputFormat == ownkubectl.OutputFormatJSON {
+			configMapsJSONBytes, err := json.MarshalIndent(configMaps, "", " ")
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("%s\n", string(configMapsJSONBytes))
+		} else if outputFormat == ownkubectl.OutputFormatYAML {
+			configMapsYAMLBytes, err := yaml.Marshal(configMaps)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("%s\n", string(configMapsYAMLBytes))
+		} else {
+			ownkubectl.PrintConfigMapsInTableFormat(configMaps)
+		}
+
+		return nil
+	},
+}
+
+var getSecretsCmd = &cobra.Command{
+	Use:   "secrets",
+	Short: "secrets",
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		namespace, err := cmd.Flags().GetString(namespaceFlag)
+		if err != nil {
+			return err
+		}
+
+		secrets, err := ownkubectl.GetSecrets(namespace)
+		if err != nil {
+			return err
+		}
+
+		if len(secrets) == 0 {
+			fmt.Printf("No resource found in %s namespace\n", namespace)
+
+			return nil
+		}
+
+		outputFormat, err := cmd.Flags().GetString(outputFlag)
+		if err != nil {
+			return err
+		}
+
+		if outputFormat == ownkubectl.OutputFormatJSON {
+			secretsJSONBytes, err := json.MarshalIndent(secrets, "", " ")
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("%s\n", string(secretsJSONBytes))
+		} else if outputFormat == ownkubectl.OutputFormatYAML {
+			secretsYAMLBytes, err := yaml.Marshal(secrets)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("%s\n", string(secretsYAMLBytes))
+		} else {
+			ownkubectl.PrintSecretsInTableFormat(secrets)
+		}
+
+		return nil
+	},
+}
+
+var getLeasesCmd = &cobra.Command{
+	Use:   "leases",
+	Short: "leases",
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		namespace, err := cmd.Flags().GetString(namespaceFlag)
+		if err != nil {
+			return err
+		}
+
+		leases, err := ownkubectl.GetLeases(namespace)
+		if err != nil {
+			return err
+		}
+
+		if len(leases) == 0 {
+			fmt.Printf("No resource found in %s namespace\n", namespace)
+
+			return nil
+		}
+
+		outputFormat, err := cmd.Flags().GetString(outputFlag)
+		if err != nil {
+			return err
+		}
+
+		if outputFormat == ownkubectl.OutputFormatJSON {
+			leasesJSONBytes, err := json.MarshalIndent(leases, "", " ")
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("%s\n", string(leasesJSONBytes))
+		} else if outputFormat == ownkubectl.OutputFormatYAML {
+			leasesYAMLBytes, err := yaml.Marshal(leases)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("%s\n", string(leasesYAMLBytes))
+		} else {
+			ownkubectl.PrintLeasesInTableFormat(leases)
+		}
+
+		return nil
+	},
+}
+
+var getReplicaSetsCmd = &cobra.Command{
+	Use:   "replicasets",
+	Short: "replicasets",
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		namespace, err := cmd.Flags().GetString(namespaceFlag)
+		if err != nil {
+			return err
+		}
+
+		replicaSets, err := ownkubectl.GetReplicaSets(namespace)
+		if err != nil {
+			return err
+		}
+
+		if len(replicaSets) == 0 {
+			fmt.Printf("No resource found in %s namespace\n", namespace)
+
+			return nil
+		}
+
+		outputFormat, err := cmd.Flags().GetString(outputFlag)
+		if err != nil {
+			return err
+		}
+
+		if outputFormat == ownkubectl.OutputFormatJSON {
+			replicaSetsJSONBytes, err := json.MarshalIndent(replicaSets, "", " ")
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("%s\n", string(replicaSetsJSONBytes))
+		} else if outputFormat == ownkubectl.OutputFormatYAML {
+			replicaSetsYAMLBytes, err := yaml.Marshal(replicaSets)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("%s\n", string(replicaSetsYAMLBytes))
+		} else {
+			ownkubectl.PrintReplicaSetsInTableFormat(replicaSets)
+		}
+
+		return nil
+	},
+}
+
+var getDeploymentsCmd = &cobra.Command{
+	Use:   "deployments",
+	Short: "deployments",
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		namespace, err := cmd.Flags().GetString(namespaceFlag)
+		if err != nil {
+			return err
+		}
+
+		deployments, err := ownkubectl.GetDeployments(namespace)
+		if err != nil {
+			return err
+		}
+
+		if len(deployments) == 0 {
+			fmt.Printf("No resource found in %s namespace\n", namespace)
+
+			return nil
+		}
+
+		outputFormat, err := cmd.Flags().GetString(outputFlag)
+		if err != nil {
+			return err
+		}
+
+		if outputFormat == ownkubectl.OutputFormatJSON {
+			deploymentsJSONBytes, err := json.MarshalIndent(deployments, "", " ")
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("%s\n", string(deploymentsJSONBytes))
+		} else if outputFormat == ownkubectl.OutputFormatYAML {
+			deploymentsYAMLBytes, err := yaml.Marshal(deployments)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("%s\n", string(deploymentsYAMLBytes))
+		} else {
+			ownkubectl.PrintDeploymentsInTableFormat(deployments)
+		}
+
+		return nil
+	},
+}
+
+var getDaemonSetsCmd = &cobra.Command{
+	Use:   "daemonsets",
+	Short: "daemonsets",
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		namespace, err := cmd.Flags().GetString(namespaceFlag)
+		if err != nil {
+			return err
+		}
+
+		daemonSets, err := ownkubectl.GetDaemonSets(namespace)
+		if err != nil {
+			return err
+		}
+
+		if len(daemonSets) == 0 {
+			fmt.Printf("No resource found in %s namespace\n", namespace)
+
+			return nil
+		}
+
+		outputFormat, err := cmd.Flags().GetString(outputFlag)
+		if err != nil {
+			return err
+		}
+
+		if outputFormat == ownkubectl.OutputFormatJSON {
+			daemonSetsJSONBytes, err := json.MarshalIndent(daemonSets, "", " ")
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("%s\n", string(daemonSetsJSONBytes))
+		} else if outputFormat == ownkubectl.OutputFormatYAML {
+			daemonSetsYAMLBytes, err := yaml.Marshal(daemonSets)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("%s\n", string(daemonSetsYAMLBytes))
+		} else {
+			ownkubectl.PrintDaemonSetsInTableFormat(daemonSets)
+		}
+
+		return nil
+	},
+}
+
+var getStatefulSetsCmd = &cobra.Command{
+	Use:   "statefulsets",
+	Short: "statefulsets",
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		namespace, err := cmd.Flags().GetString(namespaceFlag)
+		if err != nil {
+			return err
+		}
+
+		statefulSets, err := ownkubectl.GetStatefulSets(namespace)
+		if err != nil {
+			return err
+		}
+
+		if len(statefulSets) == 0 {
+			fmt.Printf("No resource found in %s namespace\n", namespace)
+
+			return nil
+		}
+
+		outputFormat, err := cmd.Flags().GetString(outputFlag)
+		if err != nil {
+			return err
+		}
+
+		if outputFormat == ownkubectl.OutputFormatJSON {
+			statefulSetsJSONBytes, err := json.MarshalIndent(statefulSets, "", " ")
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("%s\n", string(statefulSetsJSONBytes))
+		} else if outputFormat == ownkubectl.OutputFormatYAML {
+			statefulSetsYAMLBytes, err := yaml.Marshal(statefulSets)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("%s\n", string(statefulSetsYAMLBytes))
+		} else {
+			ownkubectl.PrintStatefulSetsInTableFormat(statefulSets)
+		}
+
+		return nil
+	},
+}
+
+var getHorizontalPodAutoscalersCmd = &cobra.Command{
+	Use:   "horizontalpodautoscalers",
+	Short: "horizontalpodautoscalers",
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		namespace, err := cmd.Flags().GetString(namespaceFlag)
+		if err != nil {
+			return err
+		}
+
+		hpas, err := ownkubectl.GetHorizontalPodAutoscalers(namespace)
+		if err != nil {
+			return err
+		}
+
+		if len(hpas) == 0 {
+			fmt.Printf("No resource found in %s namespace\n", namespace)
+
+			return nil
+		}
+
+		outputFormat, err := cmd.Flags().GetString(outputFlag)
+		if err != nil {
+			return err
+		}
+
+		if outputFormat == ownkubectl.OutputFormatJSON {
+			hpasJSONBytes, err := json.MarshalIndent(hpas, "", " ")
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("%s\n", string(hpasJSONBytes))
+		} else if outputFormat == ownkubectl.OutputFormatYAML {
+			hpasYAMLBytes, err := yaml.Marshal(hpas)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("%s\n", string(hpasYAMLBytes))
+		} else {
+			ownkubectl.PrintHorizontalPodAutoscalersInTableFormat(hpas)
+		}
+
+		return nil
+	},
+}
+
+var getJobsCmd = &cobra.Command{
+	Use:   "jobs",
+	Short: "jobs",
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		namespace, err := cmd.Flags().GetString(namespaceFlag)
+		if err != nil {
+			return err
+		}
+
+		jobs, err := ownkubectl.GetJobs(namespace)
+		if err != nil {
+			return err
+		}
+
+		if len(jobs) == 0 {
+			fmt.Printf("No resource found in %s namespace\n", namespace)
+
+			return nil
+		}
+
+		outputFormat, err := cmd.Flags().GetString(outputFlag)
+		if err != nil {
+			return err
+		}
+
+		if outputFormat == ownkubectl.OutputFormatJSON {
+			jobsJSONBytes, err := json.MarshalIndent(jobs, "", " ")
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("%s\n", string(jobsJSONBytes))
+		} else if outputFormat == ownkubectl.OutputFormatYAML {
+			jobsYAMLBytes, err := yaml.Marshal(jobs)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("%s\n", string(jobsYAMLBytes))
+		} else {
+			ownkubectl.PrintJobsInTableFormat(jobs)
+		}
+
+		return nil
+	},
+}
+
+var getCronJobsCmd = &cobra.Command{
+	Use:   "cronjobs",
+	Short: "cronjobs",
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		namespace, err := cmd.Flags().GetString(namespaceFlag)
+		if err != nil {
+			return err
+		}
+
+		cronJobs, err := ownkubectl.GetCronJobs(namespace)
+		if err != nil {
+			return err
+		}
+
+		if len(cronJobs) == 0 {
+			fmt.Printf("No resource found in %s namespace\n", namespace)
+
+			return nil
+		}
+
+		outputFormat, err := cmd.Flags().GetString(outputFlag)
+		if err != nil {
+			return err
+		}
+
+		if outputFormat == ownkubectl.OutputFormatJSON {
+			cronJobsJSONBytes, err := json.MarshalIndent(cronJobs, "", " ")
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("%s\n", string(cronJobsJSONBytes))
+		} else if outputFormat == ownkubectl.OutputFormatYAML {
+			cronJobsYAMLBytes, err := yaml.Marshal(cronJobs)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("%s\n", string(cronJobsYAMLBytes))
+		} else {
+			ownkubectl.PrintCronJobsInTableFormat(cronJobs)
+		}
+
+		return nil
+	},
+}
+
+var getNodesCmd = &cobra.Command{
+	Use:   "nodes",
+	Short: "nodes",
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		nodes, err := ownkubectl.GetNodes()
+		if err != nil {
+			return err
+		}
+
+		if len(nodes) == 0 {
+			fmt.Println("No resource found")
+
+			return nil
+		}
+
+		outputFormat, err := cmd.Flags().GetString(outputFlag)
+		if err != nil {
+			return err
+		}
+
+		if outputFormat == ownkubectl.OutputFormatJSON {
+			nodesJSONBytes, err := json.MarshalIndent(nodes, "", " ")
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("%s\n", string(nodesJSONBytes))
+		} else if outputFormat == ownkubectl.OutputFormatYAML {
+			nodesYAMLBytes, err := yaml.Marshal(nodes)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("%s\n", string(nodesYAMLBytes))
+		} else {
+			ownkubectl.PrintNodesInTableFormat(nodes, outputFormat)
+		}
+
+		return nil
+	},
+}
+
 func init() {
 	rootCmd.AddCommand(getCmd)
 
@@ -224,4 +783,63 @@ func init() {
 	getEndpointsCmd.Flags().StringP(outputFlag, "o", "",
 		fmt.Sprintf("output format: %s, %s, %s", ownkubectl.OutputFormatWide, ownkubectl.OutputFormatYAML, ownkubectl.OutputFormatJSON))
 	getEndpointsCmd.Flags().StringP(namespaceFlag, "n", defaultNamespace, "endpoint namespace")
+
+	getCmd.AddCommand(getEventsCmd)
+	getEventsCmd.Flags().StringP(outputFlag, "o", "",
+		fmt.Sprintf("output format: %s, %s", ownkubectl.OutputFormatYAML, ownkubectl.OutputFormatJSON))
+	getEventsCmd.Flags().StringP(namespaceFlag, "n", defaultNamespace, "events namespace")
+
+	getCmd.AddCommand(getConfigMapsCmd)
+	getConfigMapsCmd.Flags().StringP(outputFlag, "o", "",
+		fmt.Sprintf("output format: %s, %s", ownkubectl.OutputFormatYAML, ownkubectl.OutputFormatJSON))
+	getConfigMapsCmd.Flags().StringP(namespaceFlag, "n", defaultNamespace, "configmap namespace")
+
+	getCmd.AddCommand(getSecretsCmd)
+	getSecretsCmd.Flags().StringP(outputFlag, "o", "",
+		fmt.Sprintf("output format: %s, %s", ownkubectl.OutputFormatYAML, ownkubectl.OutputFormatJSON))
+	getSecretsCmd.Flags().StringP(namespaceFlag, "n", defaultNamespace, "secret namespace")
+
+	getCmd.AddCommand(getLeasesCmd)
+	getLeasesCmd.Flags().StringP(outputFlag, "o", "",
+		fmt.Sprintf("output format: %s, %s", ownkubectl.OutputFormatYAML, ownkubectl.OutputFormatJSON))
+	getLeasesCmd.Flags().StringP(namespaceFlag, "n", defaultNamespace, "lease namespace")
+
+	getCmd.AddCommand(getReplicaSetsCmd)
+	getReplicaSetsCmd.Flags().StringP(outputFlag, "o", "",
+		fmt.Sprintf("output format: %s, %s", ownkubectl.OutputFormatYAML, ownkubectl.OutputFormatJSON))
+	getReplicaSetsCmd.Flags().StringP(namespaceFlag, "n", defaultNamespace, "replicaset namespace")
+
+	getCmd.AddCommand(getDeploymentsCmd)
+	getDeploymentsCmd.Flags().StringP(outputFlag, "o", "",
+		fmt.Sprintf("output format: %s, %s", ownkubectl.OutputFormatYAML, ownkubectl.OutputFormatJSON))
+	getDeploymentsCmd.Flags().StringP(namespaceFlag, "n", defaultNamespace, "deployment namespace")
+
+	getCmd.AddCommand(getDaemonSetsCmd)
+	getDaemonSetsCmd.Flags().StringP(outputFlag, "o", "",
+		fmt.Sprintf("output format: %s, %s", ownkubectl.OutputFormatYAML, ownkubectl.OutputFormatJSON))
+	getDaemonSetsCmd.Flags().StringP(namespaceFlag, "n", defaultNamespace, "daemonset namespace")
+
+	getCmd.AddCommand(getStatefulSetsCmd)
+	getStatefulSetsCmd.Flags().StringP(outputFlag, "o", "",
+		fmt.Sprintf("output format: %s, %s", ownkubectl.OutputFormatYAML, ownkubectl.OutputFormatJSON))
+	getStatefulSetsCmd.Flags().StringP(namespaceFlag, "n", defaultNamespace, "statefulset namespace")
+
+	getCmd.AddCommand(getHorizontalPodAutoscalersCmd)
+	getHorizontalPodAutoscalersCmd.Flags().StringP(outputFlag, "o", "",
+		fmt.Sprintf("output format: %s, %s", ownkubectl.OutputFormatYAML, ownkubectl.OutputFormatJSON))
+	getHorizontalPodAutoscalersCmd.Flags().StringP(namespaceFlag, "n", defaultNamespace, "horizontalpodautoscaler namespace")
+
+	getCmd.AddCommand(getJobsCmd)
+	getJobsCmd.Flags().StringP(outputFlag, "o", "",
+		fmt.Sprintf("output format: %s, %s", ownkubectl.OutputFormatYAML, ownkubectl.OutputFormatJSON))
+	getJobsCmd.Flags().StringP(namespaceFlag, "n", defaultNamespace, "job namespace")
+
+	getCmd.AddCommand(getCronJobsCmd)
+	getCronJobsCmd.Flags().StringP(outputFlag, "o", "",
+		fmt.Sprintf("output format: %s, %s", ownkubectl.OutputFormatYAML, ownkubectl.OutputFormatJSON))
+	getCronJobsCmd.Flags().StringP(namespaceFlag, "n", defaultNamespace, "cronjob namespace")
+
+	getCmd.AddCommand(getNodesCmd)
+	getNodesCmd.Flags().StringP(outputFlag, "o", "",
+		fmt.Sprintf("output format: %s, %s, %s", ownkubectl.OutputFormatWide, ownkubectl.OutputFormatYAML, ownkubectl.OutputFormatJSON))
 }
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+
+	ownkubectl "github.com/jonatan5524/own-kubernetes/pkg/own-kubectl"
+	"github.com/spf13/cobra"
+)
+
+const (
+	namespaceRolloutFlag = "namespace"
+	toRevisionFlag       = "to-revision"
+)
+
+var rolloutCmd = &cobra.Command{
+	Use:   "rollout",
+	Short: "manage the rollout of deployments",
+}
+
+var rolloutHistoryCmd = &cobra.Command{
+	Use:   "history",
+	Short: "revisions of a deployment",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		namespace, err := cmd.Flags().GetString(namespaceRolloutFlag)
+		if err != nil {
+			return err
+		}
+
+		if len(args) == 0 {
+			return fmt.Errorf("deployment name must be specify")
+		}
+
+		history, err := ownkubectl.GetDeploymentHistory(namespace, args[0])
+		if err != nil {
+			return err
+		}
+
+		if len(history) == 0 {
+			fmt.Printf("No revisions found for deployment %s in %s namespace\n", args[0], namespace)
+
+			return nil
+		}
+
+		ownkubectl.PrintDeploymentHistoryInTableFormat(history)
+
+		return nil
+	},
+}
+
+var rolloutUndoCmd = &cobra.Command{
+	Use:   "undo",
+	Short: "roll a deployment back to a previous revision",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		namespace, err := cmd.Flags().GetString(namespaceRolloutFlag)
+		if err != nil {
+			return err
+		}
+
+		toRevision, err := cmd.Flags().GetInt(toRevisionFlag)
+		if err != nil {
+			return err
+		}
+
+		if len(args) == 0 {
+			return fmt.Errorf("deployment name must be specify")
+		}
+
+		if err = ownkubectl.RollbackDeployment(namespace, args[0], toRevision); err != nil {
+			return err
+		}
+
+		fmt.Println("success")
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(rolloutCmd)
+
+	rolloutCmd.AddCommand(rolloutHistoryCmd)
+	rolloutHistoryCmd.Flags().StringP(namespaceRolloutFlag, "n", defaultNamespace, "deployment namespace")
+
+	rolloutCmd.AddCommand(rolloutUndoCmd)
+	rolloutUndoCmd.Flags().StringP(namespaceRolloutFlag, "n", defaultNamespace, "deployment namespace")
+	rolloutUndoCmd.Flags().Int(toRevisionFlag, 0, "revision to roll back to, 0 rolls back to the previous revision")
+}
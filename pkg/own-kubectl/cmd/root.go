@@ -3,6 +3,7 @@ package cmd
 import (
 	"os"
 
+	"github.com/jonatan5524/own-kubernetes/pkg/utils"
 	"github.com/spf13/cobra"
 )
 
@@ -12,6 +13,8 @@ var rootCmd = &cobra.Command{
 }
 
 func Execute() {
+	utils.SetUserAgent("own-kubectl")
+
 	err := rootCmd.Execute()
 	if err != nil {
 		os.Exit(1)
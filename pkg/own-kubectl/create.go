@@ -19,9 +19,9 @@ func CreateResource(file string) error {
 	}
 
 	var resp *http.Response
-	if kind == "Namespace" {
+	if kind == "Namespace" || kind == "Node" {
 		resp, err = http.Post(
-			fmt.Sprintf("%s/namespaces", os.Getenv("KUBE_API_ENDPOINT")),
+			fmt.Sprintf("%s/%ss", os.Getenv("KUBE_API_ENDPOINT"), strings.ToLower(kind)),
 			"application/json",
 			bytes.NewReader(data),
 		)
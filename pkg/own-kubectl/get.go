@@ -7,6 +7,7 @@ import (
 	"math"
 	"net/http"
 	"os"
+	"sort"
 	"strings"
 	"text/tabwriter"
 	"time"
@@ -18,6 +19,8 @@ const (
 	OutputFormatWide = "wide"
 	OutputFormatYAML = "yaml"
 	OutputFormatJSON = "json"
+
+	nodeRoleLabelPrefix = "node-role.kubernetes.io/"
 )
 
 func PrintPodsInTableFormat(pods []rest.Pod, outputFormat string) {
@@ -118,6 +121,393 @@ func PrintNamespacesInTableFormat(namespaces []rest.Namespace) {
 	w.Flush()
 }
 
+func PrintConfigMapsInTableFormat(configMaps []rest.ConfigMap) {
+	w := tabwriter.NewWriter(os.Stdout, 10, 1, 5, ' ', 0)
+	fmt.Fprintln(w, "NAME\tDATA\tAGE")
+
+	for _, configMap := range configMaps {
+		fmt.Fprintf(w, "%s\t%d\t%s\n",
+			configMap.Metadata.Name,
+			len(configMap.Data)+len(configMap.BinaryData),
+			getAge(configMap.Metadata.CreationTimestamp),
+		)
+	}
+
+	w.Flush()
+}
+
+func PrintSecretsInTableFormat(secrets []rest.Secret) {
+	w := tabwriter.NewWriter(os.Stdout, 10, 1, 5, ' ', 0)
+	fmt.Fprintln(w, "NAME\tTYPE\tDATA\tAGE")
+
+	for _, secret := range secrets {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n",
+			secret.Metadata.Name,
+			secret.Type,
+			len(secret.Data),
+			getAge(secret.Metadata.CreationTimestamp),
+		)
+	}
+
+	w.Flush()
+}
+
+func PrintLeasesInTableFormat(leases []rest.Lease) {
+	w := tabwriter.NewWriter(os.Stdout, 10, 1, 5, ' ', 0)
+	fmt.Fprintln(w, "NAME\tHOLDER\tAGE")
+
+	for _, lease := range leases {
+		fmt.Fprintf(w, "%s\t%s\t%s\n",
+			lease.Metadata.Name,
+			lease.Spec.HolderIdentity,
+			getAge(lease.Metadata.CreationTimestamp),
+		)
+	}
+
+	w.Flush()
+}
+
+func PrintReplicaSetsInTableFormat(replicaSets []rest.ReplicaSet) {
+	w := tabwriter.NewWriter(os.Stdout, 10, 1, 5, ' ', 0)
+	fmt.Fprintln(w, "NAME\tDESIRED\tCURRENT\tREADY\tAGE")
+
+	for _, replicaSet := range replicaSets {
+		desired := 0
+		if replicaSet.Spec.Replicas != nil {
+			desired = *replicaSet.Spec.Replicas
+		}
+
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%s\n",
+			replicaSet.Metadata.Name,
+			desired,
+			replicaSet.Status.Replicas,
+			replicaSet.Status.ReadyReplicas,
+			getAge(replicaSet.Metadata.CreationTimestamp),
+		)
+	}
+
+	w.Flush()
+}
+
+func PrintDeploymentsInTableFormat(deployments []rest.Deployment) {
+	w := tabwriter.NewWriter(os.Stdout, 10, 1, 5, ' ', 0)
+	fmt.Fprintln(w, "NAME\tREADY\tUP-TO-DATE\tAVAILABLE\tAGE")
+
+	for _, deployment := range deployments {
+		desired := 0
+		if deployment.Spec.Replicas != nil {
+			desired = *deployment.Spec.Replicas
+		}
+
+		fmt.Fprintf(w, "%s\t%d/%d\t%d\t%d\t%s\n",
+			deployment.Metadata.Name,
+			deployment.Status.ReadyReplicas,
+			desired,
+			deployment.Status.UpdatedReplicas,
+			deployment.Status.AvailableReplicas,
+			getAge(deployment.Metadata.CreationTimestamp),
+		)
+	}
+
+	w.Flush()
+}
+
+func PrintDaemonSetsInTableFormat(daemonSets []rest.DaemonSet) {
+	w := tabwriter.NewWriter(os.Stdout, 10, 1, 5, ' ', 0)
+	fmt.Fprintln(w, "NAME\tDESIRED\tCURRENT\tREADY\tUP-TO-DATE\tAVAILABLE\tNODE SELECTOR\tAGE")
+
+	for _, daemonSet := range daemonSets {
+		nodeSelector := "<none>"
+		if len(daemonSet.Spec.Template.Spec.NodeSelector) > 0 {
+			var selectors []string
+			for key, value := range daemonSet.Spec.Template.Spec.NodeSelector {
+				selectors = append(selectors, fmt.Sprintf("%s=%s", key, value))
+			}
+
+			sort.Strings(selectors)
+			nodeSelector = strings.Join(selectors, ",")
+		}
+
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\t%s\t%s\n",
+			daemonSet.Metadata.Name,
+			daemonSet.Status.DesiredNumberScheduled,
+			daemonSet.Status.CurrentNumberScheduled,
+			daemonSet.Status.NumberReady,
+			daemonSet.Status.UpdatedNumberScheduled,
+			daemonSet.Status.NumberAvailable,
+			nodeSelector,
+			getAge(daemonSet.Metadata.CreationTimestamp),
+		)
+	}
+
+	w.Flush()
+}
+
+func PrintStatefulSetsInTableFormat(statefulSets []rest.StatefulSet) {
+	w := tabwriter.NewWriter(os.Stdout, 10, 1, 5, ' ', 0)
+	fmt.Fprintln(w, "NAME\tREADY\tAGE")
+
+	for _, statefulSet := range statefulSets {
+		replicas := 0
+		if statefulSet.Spec.Replicas != nil {
+			replicas = *statefulSet.Spec.Replicas
+		}
+
+		fmt.Fprintf(w, "%s\t%d/%d\t%s\n",
+			statefulSet.Metadata.Name,
+			statefulSet.Status.ReadyReplicas,
+			replicas,
+			getAge(statefulSet.Metadata.CreationTimestamp),
+		)
+	}
+
+	w.Flush()
+}
+
+func PrintHorizontalPodAutoscalersInTableFormat(hpas []rest.HorizontalPodAutoscaler) {
+	w := tabwriter.NewWriter(os.Stdout, 10, 1, 5, ' ', 0)
+	fmt.Fprintln(w, "NAME\tREFERENCE\tTARGETS\tMINPODS\tMAXPODS\tREPLICAS\tAGE")
+
+	for _, hpa := range hpas {
+		minReplicas := 1
+		if hpa.Spec.MinReplicas != nil {
+			minReplicas = *hpa.Spec.MinReplicas
+		}
+
+		fmt.Fprintf(w, "%s\t%s/%s\t%s\t%d\t%d\t%d\t%s\n",
+			hpa.Metadata.Name,
+			hpa.Spec.ScaleTargetRef.Kind,
+			hpa.Spec.ScaleTargetRef.Name,
+			formatHorizontalPodAutoscalerTargets(hpa),
+			minReplicas,
+			hpa.Spec.MaxReplicas,
+			hpa.Status.CurrentReplicas,
+			getAge(hpa.Metadata.CreationTimestamp),
+		)
+	}
+
+	w.Flush()
+}
+
+// formatHorizontalPodAutoscalerTargets formats every metric as current/target, the current value is unknown
+// until the controller read the metrics of the pods
+func formatHorizontalPodAutoscalerTargets(hpa rest.HorizontalPodAutoscaler) string {
+	targets := []string{}
+
+	for _, metric := range hpa.Spec.Metrics {
+		if metric.Resource == nil {
+			continue
+		}
+
+		current := "<unknown>"
+		var currentStatus *rest.MetricValueStatus
+		for _, status := range hpa.Status.CurrentMetrics {
+			if status.Resource != nil && status.Resource.Name == metric.Resource.Name {
+				currentStatus = &status.Resource.Current
+			}
+		}
+
+		if metric.Resource.Target.Type == rest.UtilizationMetricType && metric.Resource.Target.AverageUtilization != nil {
+			if currentStatus != nil && currentStatus.AverageUtilization != nil {
+				current = fmt.Sprintf("%d%%", *currentStatus.AverageUtilization)
+			}
+
+			targets = append(targets, fmt.Sprintf("%s: %s/%d%%", metric.Resource.Name, current, *metric.Resource.Target.AverageUtilization))
+
+			continue
+		}
+
+		if currentStatus != nil && currentStatus.AverageValue != "" {
+			current = currentStatus.AverageValue
+		}
+
+		targets = append(targets, fmt.Sprintf("%s: %s/%s", metric.Resource.Name, current, metric.Resource.Target.AverageValue))
+	}
+
+	return strings.Join(targets, ", ")
+}
+
+func PrintJobsInTableFormat(jobs []rest.Job) {
+	w := tabwriter.NewWriter(os.Stdout, 10, 1, 5, ' ', 0)
+	fmt.Fprintln(w, "NAME\tCOMPLETIONS\tDURATION\tAGE")
+
+	for _, job := range jobs {
+		completions := 0
+		if job.Spec.Completions != nil {
+			completions = *job.Spec.Completions
+		}
+
+		duration := ""
+		if startTime, err := time.Parse(time.RFC3339, job.Status.StartTime); err == nil {
+			endTime := time.Now()
+			if completionTime, err := time.Parse(time.RFC3339, job.Status.CompletionTime); err == nil {
+				endTime = completionTime
+			}
+
+			duration = formatDuration(endTime.Sub(startTime))
+		}
+
+		fmt.Fprintf(w, "%s\t%d/%d\t%s\t%s\n",
+			job.Metadata.Name,
+			job.Status.Succeeded,
+			completions,
+			duration,
+			getAge(job.Metadata.CreationTimestamp),
+		)
+	}
+
+	w.Flush()
+}
+
+func PrintCronJobsInTableFormat(cronJobs []rest.CronJob) {
+	w := tabwriter.NewWriter(os.Stdout, 10, 1, 5, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSCHEDULE\tSUSPEND\tACTIVE\tLAST SCHEDULE\tAGE")
+
+	for _, cronJob := range cronJobs {
+		lastSchedule := "<none>"
+		if cronJob.Status.LastScheduleTime != "" {
+			lastSchedule = getAge(cronJob.Status.LastScheduleTime)
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%t\t%d\t%s\t%s\n",
+			cronJob.Metadata.Name,
+			cronJob.Spec.Schedule,
+			cronJob.Spec.Suspend,
+			len(cronJob.Status.Active),
+			lastSchedule,
+			getAge(cronJob.Metadata.CreationTimestamp),
+		)
+	}
+
+	w.Flush()
+}
+
+func PrintNodesInTableFormat(nodes []rest.Node, outputFormat string) {
+	w := tabwriter.NewWriter(os.Stdout, 10, 1, 5, ' ', 0)
+	if outputFormat == "" {
+		fmt.Fprintln(w, "NAME\tSTATUS\tROLES\tAGE")
+	} else if outputFormat == OutputFormatWide {
+		fmt.Fprintln(w, "NAME\tSTATUS\tROLES\tAGE\tINTERNAL-IP\tOS-IMAGE\tKERNEL-VERSION\tCONTAINER-RUNTIME")
+	}
+
+	for _, node := range nodes {
+		if outputFormat == "" {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+				node.Metadata.Name,
+				getNodeStatus(node),
+				getNodeRoles(node),
+				getAge(node.Metadata.CreationTimestamp),
+			)
+		} else if outputFormat == OutputFormatWide {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				node.Metadata.Name,
+				getNodeStatus(node),
+				getNodeRoles(node),
+				getAge(node.Metadata.CreationTimestamp),
+				getNodeInternalIP(node),
+				node.Status.NodeInfo.OSImage,
+				node.Status.NodeInfo.KernelVersion,
+				node.Status.NodeInfo.ContainerRuntimeVersion,
+			)
+		}
+	}
+
+	w.Flush()
+}
+
+func PrintEventsInTableFormat(events []rest.Event) {
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].LastTimestamp < events[j].LastTimestamp
+	})
+
+	w := tabwriter.NewWriter(os.Stdout, 10, 1, 5, ' ', 0)
+	fmt.Fprintln(w, "LAST SEEN\tTYPE\tREASON\tOBJECT\tMESSAGE")
+
+	for _, event := range events {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			getLastSeen(event.LastTimestamp, event.Count),
+			event.Type,
+			event.Reason,
+			fmt.Sprintf("%s/%s", strings.ToLower(event.InvolvedObject.Kind), event.InvolvedObject.Name),
+			event.Message,
+		)
+	}
+
+	w.Flush()
+}
+
+// getLastSeen is the age of the last occurrence, with the number of occurrences when the event repeated
+func getLastSeen(timeStampStr string, count int) string {
+	timeStamp, err := time.Parse(time.RFC3339, timeStampStr)
+	if err != nil {
+		return ""
+	}
+
+	duration := time.Since(timeStamp)
+
+	var lastSeen string
+	switch {
+	case duration < time.Minute:
+		lastSeen = fmt.Sprintf("%ds", int(duration.Seconds()))
+	case duration < time.Hour:
+		lastSeen = fmt.Sprintf("%dm", int(duration.Minutes()))
+	default:
+		lastSeen = getAge(timeStampStr)
+	}
+
+	if count > 1 {
+		return fmt.Sprintf("%s (x%d)", lastSeen, count)
+	}
+
+	return lastSeen
+}
+
+func getNodeStatus(node rest.Node) string {
+	status := "Unknown"
+
+	if ready := node.Condition(rest.NodeReady); ready != nil {
+		if ready.Status == rest.ConditionTrue {
+			status = "Ready"
+		} else {
+			status = "NotReady"
+		}
+	}
+
+	if node.Spec.Unschedulable {
+		status += ",SchedulingDisabled"
+	}
+
+	return status
+}
+
+func getNodeRoles(node rest.Node) string {
+	roles := []string{}
+
+	for label := range node.Metadata.Labels {
+		if role, ok := strings.CutPrefix(label, nodeRoleLabelPrefix); ok && role != "" {
+			roles = append(roles, role)
+		}
+	}
+
+	if len(roles) == 0 {
+		return "<none>"
+	}
+
+	sort.Strings(roles)
+
+	return strings.Join(roles, ",")
+}
+
+func getNodeInternalIP(node rest.Node) string {
+	for _, address := range node.Status.Addresses {
+		if address.Type == rest.NodeInternalIP {
+			return address.Address
+		}
+	}
+
+	return "<none>"
+}
+
 func getFormattedAddresses(endpoint rest.Endpoint) string {
 	endpoints := ""
 
@@ -129,6 +519,10 @@ func getFormattedAddresses(endpoint rest.Endpoint) string {
 		}
 	}
 
+	if endpoints == "" {
+		return "<none>"
+	}
+
 	return endpoints[:len(endpoints)-1]
 }
 
@@ -165,6 +559,18 @@ func getAge(timeStampStr string) string {
 	return fmt.Sprintf("%dh", roundTime(duration.Hours()))
 }
 
+// formatDuration formats short durations such as the run time of a job, which getAge rounds to hours
+func formatDuration(duration time.Duration) string {
+	switch {
+	case duration < time.Minute:
+		return fmt.Sprintf("%ds", int(duration.Seconds()))
+	case duration < time.Hour:
+		return fmt.Sprintf("%dm%ds", int(duration.Minutes()), int(duration.Seconds())%60)
+	default:
+		return fmt.Sprintf("%dh%dm", int(duration.Hours()), int(duration.Minutes())%60)
+	}
+}
+
 func roundTime(input float64) int {
 	var result float64
 	if input < 0 {
@@ -274,3 +680,207 @@ func GetEndpoints(namespace string) ([]rest.Endpoint, error) {
 
 	return endpoints, nil
 }
+
+func GetEvents(namespace string) ([]rest.Event, error) {
+	resources, err := getResource(
+		fmt.Sprintf("%s/namespaces/%s/events", os.Getenv("KUBE_API_ENDPOINT"), namespace),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []rest.Event
+	err = json.Unmarshal(resources, &events)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling JSON: %v", err)
+	}
+
+	return events, nil
+}
+
+func GetConfigMaps(namespace string) ([]rest.ConfigMap, error) {
+	resources, err := getResource(
+		fmt.Sprintf("%s/namespaces/%s/configmaps", os.Getenv("KUBE_API_ENDPOINT"), namespace),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var configMaps []rest.ConfigMap
+	err = json.Unmarshal(resources, &configMaps)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling JSON: %v", err)
+	}
+
+	return configMaps, nil
+}
+
+func GetSecrets(namespace string) ([]rest.Secret, error) {
+	resources, err := getResource(
+		fmt.Sprintf("%s/namespaces/%s/secrets", os.Getenv("KUBE_API_ENDPOINT"), namespace),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var secrets []rest.Secret
+	err = json.Unmarshal(resources, &secrets)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling JSON: %v", err)
+	}
+
+	return secrets, nil
+}
+
+func GetNodes() ([]rest.Node, error) {
+	resources, err := getResource(
+		fmt.Sprintf("%s/nodes", os.Getenv("KUBE_API_ENDPOINT")),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes []rest.Node
+	err = json.Unmarshal(resources, &nodes)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling JSON: %v", err)
+	}
+
+	return nodes, nil
+}
+
+func GetLeases(namespace string) ([]rest.Lease, error) {
+	resources, err := getResource(
+		fmt.Sprintf("%s/namespaces/%s/leases", os.Getenv("KUBE_API_ENDPOINT"), namespace),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var leases []rest.Lease
+	err = json.Unmarshal(resources, &leases)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling JSON: %v", err)
+	}
+
+	return leases, nil
+}
+
+func GetReplicaSets(namespace string) ([]rest.ReplicaSet, error) {
+	resources, err := getResource(
+		fmt.Sprintf("%s/namespaces/%s/replicasets", os.Getenv("KUBE_API_ENDPOINT"), namespace),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var replicaSets []rest.ReplicaSet
+	err = json.Unmarshal(resources, &replicaSets)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling JSON: %v", err)
+	}
+
+	return replicaSets, nil
+}
+
+func GetDeployments(namespace string) ([]rest.Deployment, error) {
+	resources, err := getResource(
+		fmt.Sprintf("%s/namespaces/%s/deployments", os.Getenv("KUBE_API_ENDPOINT"), namespace),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var deployments []rest.Deployment
+	err = json.Unmarshal(resources, &deployments)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling JSON: %v", err)
+	}
+
+	return deployments, nil
+}
+
+func GetDaemonSets(namespace string) ([]rest.DaemonSet, error) {
+	resources, err := getResource(
+		fmt.Sprintf("%s/namespaces/%s/daemonsets", os.Getenv("KUBE_API_ENDPOINT"), namespace),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var daemonSets []rest.DaemonSet
+	err = json.Unmarshal(resources, &daemonSets)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling JSON: %v", err)
+	}
+
+	return daemonSets, nil
+}
+
+func GetJobs(namespace string) ([]rest.Job, error) {
+	resources, err := getResource(
+		fmt.Sprintf("%s/namespaces/%s/jobs", os.Getenv("KUBE_API_ENDPOINT"), namespace),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []rest.Job
+	err = json.Unmarshal(resources, &jobs)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling JSON: %v", err)
+	}
+
+	return jobs, nil
+}
+
+func GetCronJobs(namespace string) ([]rest.CronJob, error) {
+	resources, err := getResource(
+		fmt.Sprintf("%s/namespaces/%s/cronjobs", os.Getenv("KUBE_API_ENDPOINT"), namespace),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var cronJobs []rest.CronJob
+	err = json.Unmarshal(resources, &cronJobs)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling JSON: %v", err)
+	}
+
+	return cronJobs, nil
+}
+
+func GetStatefulSets(namespace string) ([]rest.StatefulSet, error) {
+	resources, err := getResource(
+		fmt.Sprintf("%s/namespaces/%s/statefulsets", os.Getenv("KUBE_API_ENDPOINT"), namespace),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var statefulSets []rest.StatefulSet
+	err = json.Unmarshal(resources, &statefulSets)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling JSON: %v", err)
+	}
+
+	return statefulSets, nil
+}
+
+func GetHorizontalPodAutoscalers(namespace string) ([]rest.HorizontalPodAutoscaler, error) {
+	resources, err := getResource(
+		fmt.Sprintf("%s/namespaces/%s/horizontalpodautoscalers", os.Getenv("KUBE_API_ENDPOINT"), namespace),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var hpas []rest.HorizontalPodAutoscaler
+	err = json.Unmarshal(resources, &hpas)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling JSON: %v", err)
+	}
+
+	return hpas, nil
+}
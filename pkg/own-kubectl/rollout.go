@@ -0,0 +1,106 @@
+package ownkubectl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
+)
+
+// RollbackDeployment rolls the deployment back to the template of the revision, 0 is the previous revision
+func RollbackDeployment(namespace string, name string, revision int) error {
+	rollback := rest.DeploymentRollback{
+		TypeMeta:   rest.TypeMeta{Kind: "DeploymentRollback"},
+		Name:       name,
+		RollbackTo: rest.RollbackConfig{Revision: revision},
+	}
+
+	rollbackBytes, err := json.Marshal(rollback)
+	if err != nil {
+		return fmt.Errorf("error parsing request body: %v", err)
+	}
+
+	resp, err := http.Post(
+		fmt.Sprintf("%s/namespaces/%s/deployments/%s/rollback", os.Getenv("KUBE_API_ENDPOINT"), namespace, name),
+		"application/json",
+		bytes.NewReader(rollbackBytes),
+	)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("error reading response body: %v", err)
+		}
+
+		return fmt.Errorf("error from api: %s %s", resp.Status, string(body))
+	}
+
+	return nil
+}
+
+// GetDeploymentHistory returns the replicasets of the deployment ordered by their revision
+func GetDeploymentHistory(namespace string, name string) ([]rest.ReplicaSet, error) {
+	replicaSets, err := GetReplicaSets(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	var history []rest.ReplicaSet
+	for _, replicaSet := range replicaSets {
+		controllerRef := replicaSet.Metadata.ControllerRef()
+		if controllerRef != nil && controllerRef.Kind == "Deployment" && controllerRef.Name == name {
+			history = append(history, replicaSet)
+		}
+	}
+
+	sort.SliceStable(history, func(i, j int) bool {
+		return getRevision(history[i]) < getRevision(history[j])
+	})
+
+	return history, nil
+}
+
+func PrintDeploymentHistoryInTableFormat(history []rest.ReplicaSet) {
+	w := tabwriter.NewWriter(os.Stdout, 10, 1, 5, ' ', 0)
+	fmt.Fprintln(w, "REVISION\tREPLICASET\tIMAGES\tAGE")
+
+	for _, replicaSet := range history {
+		images := ""
+		for index, container := range replicaSet.Spec.Template.Spec.Containers {
+			if index > 0 {
+				images += ","
+			}
+
+			images += container.Image
+		}
+
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n",
+			getRevision(replicaSet),
+			replicaSet.Metadata.Name,
+			images,
+			getAge(replicaSet.Metadata.CreationTimestamp),
+		)
+	}
+
+	w.Flush()
+}
+
+func getRevision(replicaSet rest.ReplicaSet) int {
+	revision, err := strconv.Atoi(replicaSet.Metadata.Annotations[rest.RevisionAnnotation])
+	if err != nil {
+		return 0
+	}
+
+	return revision
+}
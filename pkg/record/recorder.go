@@ -0,0 +1,263 @@
+package record
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	kubeapi_rest "github.com/jonatan5524/own-kubernetes/pkg/kube-api/rest"
+	"github.com/jonatan5524/own-kubernetes/pkg/utils"
+)
+
+const (
+	// same defaults as the kubernetes event recorder, an object can burst 25 events and then one every 5 minutes
+	defaultObjectQPS   = 1.0 / 300
+	defaultObjectBurst = 25
+
+	defaultQueueSize     = 1000
+	defaultMaxCachedKeys = 4096
+
+	// the api may not be up yet when the kubelet records events of the system pods, so a failed event is queued
+	// again after a backoff that doubles up to the max interval, the other events are sent meanwhile
+	defaultMaxTries             = 12
+	defaultInitialRetryInterval = 2 * time.Second
+	defaultMaxRetryInterval     = 30 * time.Second
+)
+
+// EventRecorder records events of objects to the kube api
+type EventRecorder interface {
+	Event(object kubeapi_rest.ObjectReference, eventType string, reason string, message string)
+	Eventf(object kubeapi_rest.ObjectReference, eventType string, reason string, messageFmt string, args ...interface{})
+}
+
+// NopRecorder drops all the events, used until a real recorder is set up
+type NopRecorder struct{}
+
+func (NopRecorder) Event(kubeapi_rest.ObjectReference, string, string, string) {}
+
+func (NopRecorder) Eventf(kubeapi_rest.ObjectReference, string, string, string, ...interface{}) {}
+
+type recordedEvent struct {
+	name           string
+	count          int
+	firstTimestamp string
+}
+
+type queuedEvent struct {
+	event kubeapi_rest.Event
+	// tries is the number of times sending the event failed
+	tries int
+}
+
+type recorder struct {
+	kubeAPIEndpoint string
+	source          kubeapi_rest.EventSource
+
+	queue chan queuedEvent
+
+	mu sync.Mutex
+	// recorded are the events already sent, a repeated event updates the count of the recorded one
+	recorded map[string]*recordedEvent
+	// limiters rate limit every involved object on its own, so a crash looping pod does not hide other events
+	limiters map[string]*utils.TokenBucket
+}
+
+// NewRecorder returns a recorder that sends events in the background, recording never blocks the caller
+func NewRecorder(kubeAPIEndpoint string, source kubeapi_rest.EventSource) EventRecorder {
+	eventRecorder := &recorder{
+		kubeAPIEndpoint: kubeAPIEndpoint,
+		source:          source,
+		queue:           make(chan queuedEvent, defaultQueueSize),
+		recorded:        make(map[string]*recordedEvent),
+		limiters:        make(map[string]*utils.TokenBucket),
+	}
+
+	go eventRecorder.run()
+
+	return eventRecorder
+}
+
+func (eventRecorder *recorder) Event(object kubeapi_rest.ObjectReference, eventType string, reason string, message string) {
+	now := time.Now().Format(time.RFC3339)
+
+	event := kubeapi_rest.Event{
+		TypeMeta: kubeapi_rest.TypeMeta{Kind: "Event"},
+		Metadata: kubeapi_rest.ResourceMetadata{
+			Namespace: object.Namespace,
+		},
+		InvolvedObject: object,
+		Reason:         reason,
+		Message:        message,
+		Type:           eventType,
+		Count:          1,
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Source:         eventRecorder.source,
+	}
+
+	if event.Metadata.Namespace == "" {
+		event.Metadata.Namespace = "default"
+	}
+
+	eventRecorder.enqueue(queuedEvent{event: event})
+}
+
+func (eventRecorder *recorder) enqueue(queued queuedEvent) {
+	select {
+	case eventRecorder.queue <- queued:
+	default:
+		log.Printf("event queue is full, dropping event %s %s: %s",
+			queued.event.Reason, queued.event.InvolvedObject.Name, queued.event.Message)
+	}
+}
+
+func (eventRecorder *recorder) Eventf(object kubeapi_rest.ObjectReference, eventType string, reason string, messageFmt string, args ...interface{}) {
+	eventRecorder.Event(object, eventType, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+func (eventRecorder *recorder) run() {
+	for queued := range eventRecorder.queue {
+		if err := eventRecorder.record(queued.event, queued.tries == 0); err != nil {
+			eventRecorder.retry(queued, err)
+		}
+	}
+}
+
+// retry queues the event again after a backoff, so a failing event does not hold back the events behind it
+func (eventRecorder *recorder) retry(queued queuedEvent, err error) {
+	queued.tries++
+
+	if queued.tries >= defaultMaxTries {
+		log.Printf("error recording event %s for %s, dropping it after %d tries: %v",
+			queued.event.Reason, queued.event.InvolvedObject.Name, queued.tries, err)
+
+		return
+	}
+
+	backoff := min(defaultInitialRetryInterval<<(queued.tries-1), defaultMaxRetryInterval)
+
+	log.Printf("error sending event %s for %s, retrying in %s: %v", queued.event.Reason, queued.event.InvolvedObject.Name, backoff, err)
+
+	time.AfterFunc(backoff, func() {
+		eventRecorder.enqueue(queued)
+	})
+}
+
+func objectKey(object kubeapi_rest.ObjectReference) string {
+	return fmt.Sprintf("%s/%s/%s/%s", object.Kind, object.Namespace, object.Name, object.UID)
+}
+
+func eventKey(event kubeapi_rest.Event) string {
+	return fmt.Sprintf("%s/%s/%s/%s/%s/%s",
+		objectKey(event.InvolvedObject),
+		event.InvolvedObject.FieldPath,
+		event.Type,
+		event.Reason,
+		event.Message,
+		event.Source.Component,
+	)
+}
+
+func (eventRecorder *recorder) allow(event kubeapi_rest.Event) bool {
+	eventRecorder.mu.Lock()
+	defer eventRecorder.mu.Unlock()
+
+	key := objectKey(event.InvolvedObject)
+
+	limiter, ok := eventRecorder.limiters[key]
+	if !ok {
+		if len(eventRecorder.limiters) >= defaultMaxCachedKeys {
+			eventRecorder.limiters = make(map[string]*utils.TokenBucket)
+		}
+
+		limiter = utils.NewTokenBucket(defaultObjectQPS, defaultObjectBurst)
+		eventRecorder.limiters[key] = limiter
+	}
+
+	return limiter.TryAccept()
+}
+
+// record sends the event or updates the count of the recorded one, only the first try of an event is rate limited
+func (eventRecorder *recorder) record(event kubeapi_rest.Event, firstTry bool) error {
+	if firstTry && !eventRecorder.allow(event) {
+		log.Printf("rate limited event %s for %s: %s", event.Reason, event.InvolvedObject.Name, event.Message)
+
+		return nil
+	}
+
+	key := eventKey(event)
+
+	eventRecorder.mu.Lock()
+	previous, ok := eventRecorder.recorded[key]
+	eventRecorder.mu.Unlock()
+
+	method := http.MethodPost
+	url := fmt.Sprintf("%s/namespaces/%s/events", eventRecorder.kubeAPIEndpoint, event.Metadata.Namespace)
+
+	if ok {
+		event.Metadata.Name = previous.name
+		event.Count = previous.count + 1
+		event.FirstTimestamp = previous.firstTimestamp
+
+		method = http.MethodPatch
+		url = fmt.Sprintf("%s/%s", url, previous.name)
+	} else {
+		event.Metadata.Name = fmt.Sprintf("%s.%x", event.InvolvedObject.Name, time.Now().UnixNano())
+	}
+
+	if err := sendEvent(method, url, event); err != nil {
+		return err
+	}
+
+	eventRecorder.mu.Lock()
+	defer eventRecorder.mu.Unlock()
+
+	if !ok && len(eventRecorder.recorded) >= defaultMaxCachedKeys {
+		eventRecorder.recorded = make(map[string]*recordedEvent)
+	}
+
+	eventRecorder.recorded[key] = &recordedEvent{
+		name:           event.Metadata.Name,
+		count:          event.Count,
+		firstTimestamp: event.FirstTimestamp,
+	}
+
+	return nil
+}
+
+func sendEvent(method string, url string, event kubeapi_rest.Event) error {
+	eventBytes, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("error parsing event: %v", err)
+	}
+
+	req, err := http.NewRequest(method, url, bytes.NewBuffer(eventBytes))
+	if err != nil {
+		return fmt.Errorf("error creating request for event: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending event: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("error reading response body: %v", err)
+		}
+
+		return fmt.Errorf("request failed with status code: %d %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
@@ -0,0 +1,26 @@
+package utils
+
+import "net/http"
+
+type userAgentTransport struct {
+	userAgent string
+	next      http.RoundTripper
+}
+
+func (transport *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("User-Agent") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("User-Agent", transport.userAgent)
+	}
+
+	return transport.next.RoundTrip(req)
+}
+
+// SetUserAgent sets the user agent of all the requests sent with the default http transport,
+// the kube api uses it to tell the cluster components apart from users when it throttles requests
+func SetUserAgent(userAgent string) {
+	http.DefaultTransport = &userAgentTransport{
+		userAgent: userAgent,
+		next:      http.DefaultTransport,
+	}
+}
@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var quantitySuffixes = []struct {
+	suffix     string
+	multiplier float64
+}{
+	// the two letter suffixes are checked first so Mi is not read as M
+	{"Ki", 1 << 10},
+	{"Mi", 1 << 20},
+	{"Gi", 1 << 30},
+	{"Ti", 1 << 40},
+	{"Pi", 1 << 50},
+	{"m", 1e-3},
+	{"k", 1e3},
+	{"M", 1e6},
+	{"G", 1e9},
+	{"T", 1e12},
+	{"P", 1e15},
+}
+
+// ParseQuantity parses a resource quantity such as "250m", "2", "64Mi" or "1G" to its value in base units,
+// cores for cpu and bytes for memory
+func ParseQuantity(quantity string) (float64, error) {
+	quantity = strings.TrimSpace(quantity)
+	if quantity == "" {
+		return 0, fmt.Errorf("empty quantity")
+	}
+
+	multiplier := 1.0
+	number := quantity
+
+	for _, suffix := range quantitySuffixes {
+		if trimmed, ok := strings.CutSuffix(quantity, suffix.suffix); ok {
+			number = trimmed
+			multiplier = suffix.multiplier
+
+			break
+		}
+	}
+
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid quantity %q", quantity)
+	}
+
+	if value < 0 {
+		return 0, fmt.Errorf("negative quantity %q", quantity)
+	}
+
+	return value * multiplier, nil
+}
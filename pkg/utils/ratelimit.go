@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// TokenBucket allows qps events per second on average, with bursts of up to burst events
+type TokenBucket struct {
+	mu       sync.Mutex
+	qps      float64
+	burst    float64
+	tokens   float64
+	lastFill time.Time
+}
+
+func NewTokenBucket(qps float64, burst int) *TokenBucket {
+	return &TokenBucket{
+		qps:      qps,
+		burst:    float64(burst),
+		tokens:   float64(burst),
+		lastFill: time.Now(),
+	}
+}
+
+func (bucket *TokenBucket) refillLocked(now time.Time) {
+	bucket.tokens += now.Sub(bucket.lastFill).Seconds() * bucket.qps
+	if bucket.tokens > bucket.burst {
+		bucket.tokens = bucket.burst
+	}
+
+	bucket.lastFill = now
+}
+
+// TryAccept takes a token if there is one, it never blocks
+func (bucket *TokenBucket) TryAccept() bool {
+	bucket.mu.Lock()
+	defer bucket.mu.Unlock()
+
+	bucket.refillLocked(time.Now())
+
+	if bucket.tokens < 1 {
+		return false
+	}
+
+	bucket.tokens--
+
+	return true
+}
+
+// Accept blocks until a token is available and takes it
+func (bucket *TokenBucket) Accept() {
+	for {
+		bucket.mu.Lock()
+		bucket.refillLocked(time.Now())
+
+		if bucket.tokens >= 1 {
+			bucket.tokens--
+			bucket.mu.Unlock()
+
+			return
+		}
+
+		wait := time.Duration((1 - bucket.tokens) / bucket.qps * float64(time.Second))
+		bucket.mu.Unlock()
+
+		time.Sleep(wait)
+	}
+}
+
+// Reserve takes a token and returns how long to wait before using it, the tokens may go below zero
+// so the reservations that follow wait longer
+func (bucket *TokenBucket) Reserve() time.Duration {
+	bucket.mu.Lock()
+	defer bucket.mu.Unlock()
+
+	bucket.refillLocked(time.Now())
+	bucket.tokens--
+
+	if bucket.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-bucket.tokens / bucket.qps * float64(time.Second))
+}
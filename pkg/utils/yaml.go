@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"gopkg.in/yaml.v3"
+)
+
+// JSONToYAML converts the json of an object to yaml keeping the order of its fields, json is read as yaml and
+// written back in block style
+func JSONToYAML(value []byte) ([]byte, error) {
+	node := &yaml.Node{}
+	if err := yaml.Unmarshal(value, node); err != nil {
+		return nil, err
+	}
+
+	clearStyle(node)
+
+	manifest := &bytes.Buffer{}
+	encoder := yaml.NewEncoder(manifest)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(node); err != nil {
+		return nil, err
+	}
+
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	return manifest.Bytes(), nil
+}
+
+func clearStyle(node *yaml.Node) {
+	node.Style = 0
+
+	for _, child := range node.Content {
+		clearStyle(child)
+	}
+}
+
+// YAMLToJSON converts the first document of a yaml manifest to json
+func YAMLToJSON(manifest []byte) ([]byte, error) {
+	var object interface{}
+	if err := yaml.Unmarshal(manifest, &object); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(object)
+}